@@ -144,6 +144,8 @@ import (
 	"fmt"
 	"reflect"
 	"regexp"
+	"strconv"
+	"strings"
 
 	"github.com/btnguyen2k/consu/reddo"
 	"github.com/btnguyen2k/consu/semita"
@@ -160,7 +162,7 @@ import (
 //   - ToRow        : transform godal.IGenericBo "as-is" to map[string]interface{}.
 //   - ToBo         : expects input is a map[string]interface{}, or JSON data (string or array/slice of bytes), transforms input to godal.IGenericBo via JSON unmarshalling.
 //   - ColumnsList  : return []string{"*"} (CosmosDB is schema-free, hence column-list is not used).
-//   - ToDbColName  : return the input field name "as-is".
+//   - ToDbColName  : return the input field name "as-is", except that quoted path segments (e.g. `labels["example.com"]`) are written as Cosmos DB property accessors.
 //   - ToBoFieldName: return the input column name "as-is".
 //
 // Available: since v0.3.0
@@ -267,11 +269,35 @@ func (mapper *GenericRowMapperCosmosdb) ColumnsList(_ string) []string {
 
 // ToDbColName implements godal.IRowMapper.ToDbColName.
 //
-// This function returns the input field name "as-is".
+// This function returns the input field name "as-is". Since v0.7.0, paths with quoted segments are written as
+// Cosmos DB property accessors, e.g. `labels['example.com']` is mapped to `labels["example.com"]` so that filters and
+// sorting render `c.labels["example.com"]`.
 func (mapper *GenericRowMapperCosmosdb) ToDbColName(_, fieldName string) string {
-	return fieldName
+	if !strings.ContainsAny(fieldName, `"'`) {
+		return fieldName
+	}
+	p, err := godal.ParsePath(fieldName)
+	if err != nil {
+		return fieldName
+	}
+	var sb strings.Builder
+	for i, seg := range p.Segments() {
+		switch {
+		case seg.Index >= 0:
+			sb.WriteString("[" + strconv.Itoa(seg.Index) + "]")
+		case i == 0:
+			sb.WriteString(seg.Key)
+		case reCosmosIdentifier.MatchString(seg.Key):
+			sb.WriteString("." + seg.Key)
+		default:
+			sb.WriteString(`["` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(seg.Key) + `"]`)
+		}
+	}
+	return sb.String()
 }
 
+var reCosmosIdentifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
 // ToBoFieldName implements godal.IRowMapper.ToBoFieldName.
 //
 // This function returns the input column name "as-is".
@@ -309,6 +335,9 @@ var (
 //   - (y) GdaoUpdate(collectionName string, bo godal.IGenericBo) (int, error)
 //   - (y) GdaoSave(collectionName string, bo godal.IGenericBo) (int, error)
 //
+// Since v0.7.0, GenericDaoCosmosdb also implements godal.IGenericDaoWithContext; update, save and delete honor the
+// write condition carried by the context (see godal.WithWriteCondition).
+//
 // Available: since v0.3.0
 type GenericDaoCosmosdb struct {
 	godalsql.IGenericDaoSql
@@ -472,6 +501,20 @@ func (dao *GenericDaoCosmosdb) IsErrorDuplicatedEntry(err error) bool {
 	return err == gocosmos.ErrConflict
 }
 
+// IsErrorTransient checks if the error is transient and the operation can be retried.
+//
+// An error is transient if Azure Cosmos DB responded with status code 429 (request rate too large), 449 (retry with),
+// 408 (request timeout) or 503 (service unavailable).
+//
+// This function can be used as godal.TransientErrorClassifier.
+//
+// Available since v0.7.0
+func (dao *GenericDaoCosmosdb) IsErrorTransient(err error) bool {
+	return err != nil && reTransientStatusCode.FindString(err.Error()) != ""
+}
+
+var reTransientStatusCode = regexp.MustCompile(`StatusCode=(429|449|408|503)\b`)
+
 /*----------------------------------------------------------------------*/
 
 // cosmosdbDeleteBuilder is CosmosDB variant of sql.DeleteBuilder.
@@ -492,9 +535,16 @@ func (dao *GenericDaoCosmosdb) GdaoDelete(collectionName string, bo godal.IGener
 	return dao.GdaoDeleteWithTx(nil, nil, collectionName, bo)
 }
 
+// GdaoDeleteWithContext implements godal.IGenericDaoWithContext.GdaoDeleteWithContext.
+//
+// Available since v0.7.0
+func (dao *GenericDaoCosmosdb) GdaoDeleteWithContext(ctx context.Context, collectionName string, bo godal.IGenericBo) (int, error) {
+	return dao.GdaoDeleteWithTx(ctx, nil, collectionName, bo)
+}
+
 // GdaoDeleteWithTx is database/sql variant of GdaoDelete.
 func (dao *GenericDaoCosmosdb) GdaoDeleteWithTx(ctx context.Context, tx *gosql.Tx, collectionName string, bo godal.IGenericBo) (int, error) {
-	f, err := dao.BuildFilter(collectionName, dao.GdaoCreateFilter(collectionName, bo))
+	f, err := dao.BuildFilter(collectionName, godal.ApplyWriteCondition(ctx, dao.GdaoCreateFilter(collectionName, bo)))
 	if err != nil {
 		return 0, err
 	}
@@ -515,6 +565,13 @@ func (dao *GenericDaoCosmosdb) GdaoDeleteMany(collectionName string, filter goda
 	return dao.GdaoDeleteManyWithTx(nil, nil, collectionName, filter)
 }
 
+// GdaoDeleteManyWithContext implements godal.IGenericDaoWithContext.GdaoDeleteManyWithContext.
+//
+// Available since v0.7.0
+func (dao *GenericDaoCosmosdb) GdaoDeleteManyWithContext(ctx context.Context, collectionName string, filter godal.FilterOpt) (int, error) {
+	return dao.GdaoDeleteManyWithTx(ctx, nil, collectionName, filter)
+}
+
 // GdaoDeleteManyWithTx is database/sql variant of GdaoDeleteMany.
 //
 // Note: this function firstly fetches all matched documents and then delete them one by one.
@@ -551,6 +608,13 @@ func (dao *GenericDaoCosmosdb) GdaoFetchOne(collectionName string, filter godal.
 	return dao.GdaoFetchOneWithTx(nil, nil, collectionName, filter)
 }
 
+// GdaoFetchOneWithContext implements godal.IGenericDaoWithContext.GdaoFetchOneWithContext.
+//
+// Available since v0.7.0
+func (dao *GenericDaoCosmosdb) GdaoFetchOneWithContext(ctx context.Context, collectionName string, filter godal.FilterOpt) (godal.IGenericBo, error) {
+	return dao.GdaoFetchOneWithTx(ctx, nil, collectionName, filter)
+}
+
 // GdaoFetchOneWithTx is database/sql variant of GdaoFetchOne.
 func (dao *GenericDaoCosmosdb) GdaoFetchOneWithTx(ctx context.Context, tx *gosql.Tx, collectionName string, filter godal.FilterOpt) (godal.IGenericBo, error) {
 	f, err := dao.BuildFilter(collectionName, filter)
@@ -577,6 +641,13 @@ func (dao *GenericDaoCosmosdb) GdaoFetchMany(collectionName string, filter godal
 	return dao.GdaoFetchManyWithTx(nil, nil, collectionName, filter, sorting, fromOffset, numRows)
 }
 
+// GdaoFetchManyWithContext implements godal.IGenericDaoWithContext.GdaoFetchManyWithContext.
+//
+// Available since v0.7.0
+func (dao *GenericDaoCosmosdb) GdaoFetchManyWithContext(ctx context.Context, collectionName string, filter godal.FilterOpt, sorting *godal.SortingOpt, fromOffset, numRows int) ([]godal.IGenericBo, error) {
+	return dao.GdaoFetchManyWithTx(ctx, nil, collectionName, filter, sorting, fromOffset, numRows)
+}
+
 // GdaoFetchManyWithTx is database/sql variant of GdaoFetchMany.
 func (dao *GenericDaoCosmosdb) GdaoFetchManyWithTx(ctx context.Context, tx *gosql.Tx, collectionName string, filter godal.FilterOpt, sorting *godal.SortingOpt, fromOffset, numRows int) ([]godal.IGenericBo, error) {
 	f, err := dao.BuildFilter(collectionName, filter)
@@ -624,8 +695,18 @@ func (dao *GenericDaoCosmosdb) GdaoCreate(collectionName string, bo godal.IGener
 	return dao.GdaoCreateWithTx(nil, nil, collectionName, bo)
 }
 
+// GdaoCreateWithContext implements godal.IGenericDaoWithContext.GdaoCreateWithContext.
+//
+// Available since v0.7.0
+func (dao *GenericDaoCosmosdb) GdaoCreateWithContext(ctx context.Context, collectionName string, bo godal.IGenericBo) (int, error) {
+	return dao.GdaoCreateWithTx(ctx, nil, collectionName, bo)
+}
+
 // GdaoCreateWithTx is database/sql variant of GdaoCreate.
 func (dao *GenericDaoCosmosdb) GdaoCreateWithTx(ctx context.Context, tx *gosql.Tx, collectionName string, bo godal.IGenericBo) (int, error) {
+	if err := dao.PrepareBoForWrite(ctx, collectionName, bo, godal.WriteOpInsert); err != nil {
+		return 0, err
+	}
 	if row, err := dao.GetRowMapper().ToRow(collectionName, bo); err != nil {
 		return 0, err
 	} else if colsAndVals, err := reddo.ToMap(row, typeMap); err != nil {
@@ -652,15 +733,32 @@ func (dao *GenericDaoCosmosdb) GdaoSave(collectionName string, bo godal.IGeneric
 	return dao.GdaoSaveWithTx(nil, nil, collectionName, bo)
 }
 
+// GdaoSaveWithContext implements godal.IGenericDaoWithContext.GdaoSaveWithContext.
+//
+// Available since v0.7.0
+func (dao *GenericDaoCosmosdb) GdaoSaveWithContext(ctx context.Context, collectionName string, bo godal.IGenericBo) (int, error) {
+	return dao.GdaoSaveWithTx(ctx, nil, collectionName, bo)
+}
+
 // GdaoSaveWithTx is extended-implementation of godal.IGenericDao.GdaoSave.
 func (dao *GenericDaoCosmosdb) GdaoSaveWithTx(ctx context.Context, tx *gosql.Tx, collectionName string, bo godal.IGenericBo) (int, error) {
+	if err := dao.PrepareBoForWrite(ctx, collectionName, bo, godal.WriteOpUpsert); err != nil {
+		return 0, err
+	}
 	if row, err := dao.GetRowMapper().ToRow(collectionName, bo); err != nil {
 		return 0, err
 	} else if colsAndVals, err := reddo.ToMap(row, typeMap); err != nil {
 		return 0, err
 	} else {
+		if condition := godal.WriteConditionFromContext(ctx); condition != nil {
+			// UPSERT can not be conditioned: update the existing document if it satisfies the condition, otherwise insert
+			if numRows, err := dao.updateWithFilter(ctx, tx, collectionName, bo, colsAndVals.(map[string]interface{}),
+				godal.ApplyWriteCondition(ctx, dao.GdaoCreateFilter(collectionName, bo))); err != nil || numRows > 0 {
+				return numRows, err
+			}
+		}
 		builder := &cosmosdbInsertBuilder{
-			isUpsert:      true,
+			isUpsert:      godal.WriteConditionFromContext(ctx) == nil,
 			pkValue:       dao.CosmosGetPk(collectionName, bo),
 			InsertBuilder: godalsql.NewInsertBuilder().WithFlavor(dao.GetSqlFlavor()).WithTable(collectionName).WithValues(colsAndVals.(map[string]interface{})),
 		}
@@ -694,10 +792,16 @@ func (dao *GenericDaoCosmosdb) GdaoUpdate(collectionName string, bo godal.IGener
 	return dao.GdaoUpdateWithTx(nil, nil, collectionName, bo)
 }
 
+// GdaoUpdateWithContext implements godal.IGenericDaoWithContext.GdaoUpdateWithContext.
+//
+// Available since v0.7.0
+func (dao *GenericDaoCosmosdb) GdaoUpdateWithContext(ctx context.Context, collectionName string, bo godal.IGenericBo) (int, error) {
+	return dao.GdaoUpdateWithTx(ctx, nil, collectionName, bo)
+}
+
 // GdaoUpdateWithTx is database/sql variant of GdaoUpdate.
 func (dao *GenericDaoCosmosdb) GdaoUpdateWithTx(ctx context.Context, tx *gosql.Tx, collectionName string, bo godal.IGenericBo) (int, error) {
-	f, err := dao.BuildFilter(collectionName, dao.GdaoCreateFilter(collectionName, bo))
-	if err != nil {
+	if err := dao.PrepareBoForWrite(ctx, collectionName, bo, godal.WriteOpUpdate); err != nil {
 		return 0, err
 	}
 	row, err := dao.GetRowMapper().ToRow(collectionName, bo)
@@ -708,12 +812,22 @@ func (dao *GenericDaoCosmosdb) GdaoUpdateWithTx(ctx context.Context, tx *gosql.T
 	if err != nil {
 		return 0, err
 	}
+	return dao.updateWithFilter(ctx, tx, collectionName, bo, colsAndVals.(map[string]interface{}),
+		godal.ApplyWriteCondition(ctx, dao.GdaoCreateFilter(collectionName, bo)))
+}
+
+// updateWithFilter updates the document of a BO, matched by the specified filter.
+func (dao *GenericDaoCosmosdb) updateWithFilter(ctx context.Context, tx *gosql.Tx, collectionName string, bo godal.IGenericBo, colsAndVals map[string]interface{}, filter godal.FilterOpt) (int, error) {
+	f, err := dao.BuildFilter(collectionName, filter)
+	if err != nil {
+		return 0, err
+	}
 	builder := &cosmosdbUpdateBuilder{
 		pkValue: dao.CosmosGetPk(collectionName, bo),
 		UpdateBuilder: godalsql.NewUpdateBuilder().WithFlavor(dao.GetSqlFlavor()).WithTable(collectionName).
-			WithValues(colsAndVals.(map[string]interface{})).WithFilter(f),
+			WithValues(colsAndVals).WithFilter(f),
 	}
-	result, err := dao.SqlUpdateEx(ctx, builder, tx, collectionName, colsAndVals.(map[string]interface{}), f)
+	result, err := dao.SqlUpdateEx(ctx, builder, tx, collectionName, colsAndVals, f)
 	if err != nil {
 		if dao.IsErrorDuplicatedEntry(err) {
 			return 0, godal.ErrGdaoDuplicatedEntry
@@ -723,3 +837,29 @@ func (dao *GenericDaoCosmosdb) GdaoUpdateWithTx(ctx context.Context, tx *gosql.T
 	numRows, err := result.RowsAffected()
 	return int(numRows), err
 }
+
+// ApplyStorageDescriptors configures the DAO with the descriptors of backend godal.BackendCosmosdb: id_path and pk_path
+// are added to the mappings set by CosmosSetIdGboMapPath and CosmosSetPkGboMapPath, and schemas are attached to
+// collections.
+//
+// Available since v0.7.0
+func (dao *GenericDaoCosmosdb) ApplyStorageDescriptors(descs *godal.StorageDescriptors) error {
+	if schemaDao, ok := dao.IGenericDaoSql.(interface {
+		SetSchema(storageId string, schema *godal.Schema) error
+	}); ok {
+		if err := descs.ApplySchemas(godal.BackendCosmosdb, schemaDao); err != nil {
+			return err
+		}
+	}
+	idGboPathMap, pkGboPathMap := dao.CosmosGetIdGboMapPath(), dao.CosmosGetPkGboMapPath()
+	for _, s := range descs.ForBackend(godal.BackendCosmosdb) {
+		if s.IdPath != "" {
+			idGboPathMap[s.Id] = s.IdPath
+		}
+		if s.PkPath != "" {
+			pkGboPathMap[s.Id] = s.PkPath
+		}
+	}
+	dao.CosmosSetIdGboMapPath(idGboPathMap).CosmosSetPkGboMapPath(pkGboPathMap)
+	return nil
+}
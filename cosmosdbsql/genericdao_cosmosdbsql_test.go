@@ -1,6 +1,7 @@
 package cosmosdbsql
 
 import (
+	"context"
 	gosql "database/sql"
 	"encoding/json"
 	"fmt"
@@ -15,10 +16,11 @@ import (
 	"time"
 
 	"github.com/btnguyen2k/consu/reddo"
-	_ "github.com/btnguyen2k/gocosmos"
+	"github.com/btnguyen2k/gocosmos"
 	"github.com/btnguyen2k/prom/sql"
 
 	"github.com/btnguyen2k/godal"
+	godalsql "github.com/btnguyen2k/godal/sql"
 )
 
 func TestGenericRowMapperCosmosdb_ColumnsList(t *testing.T) {
@@ -246,6 +248,30 @@ func TestGenericRowMapperCosmosdb_ToDbColName_Intact(t *testing.T) {
 	}
 }
 
+func TestGenericRowMapperCosmosdb_ToDbColName_Quoted(t *testing.T) {
+	name := "TestGenericRowMapperCosmosdb_ToDbColName_Quoted"
+	rm := &GenericRowMapperCosmosdb{}
+	testCases := []struct {
+		fieldName, expected string
+	}{
+		{"a.b[0].c", "a.b[0].c"},
+		{`labels['example.com']`, `labels["example.com"]`},
+		{godal.Path("labels").Key("example.com").Index(1).Key("v").String(), `labels["example.com"][1].v`},
+		{godal.Path("a").Key(`x "y"`).String(), `a["x \"y\""]`},
+		{`a["b`, `a["b`},
+	}
+	for _, tc := range testCases {
+		if colName := rm.ToDbColName("table", tc.fieldName); colName != tc.expected {
+			t.Fatalf("%s failed: expected %#v but received %#v", name, tc.expected, colName)
+		}
+	}
+
+	f := &godalsql.FilterFieldValue{Field: rm.ToDbColName("table", godal.Path("labels", "example.com").String()), Operator: "=", Value: 1}
+	if clause, _ := f.Build(godalsql.NewPlaceholderGeneratorDollarN(), godalsql.OptTableAlias{TableAlias: "c"}); clause != `c.labels["example.com"] = $1` {
+		t.Fatalf("%s failed: %#v", name, clause)
+	}
+}
+
 func TestGenericRowMapperCosmosdb_ToBoFieldName_Intact(t *testing.T) {
 	name := "TestGenericRowMapperCosmosdb_ToBoFieldName_Intact"
 	fielda := "col1"
@@ -413,6 +439,25 @@ type UserBoSql struct {
 
 /*---------------------------------------------------------------*/
 
+func TestGenericDaoCosmosdb_IsErrorTransient(t *testing.T) {
+	testName := "TestGenericDaoCosmosdb_IsErrorTransient"
+	dao := &GenericDaoCosmosdb{}
+	if dao.IsErrorTransient(nil) {
+		t.Fatalf("%s failed: nil error should not be transient", testName)
+	}
+	for _, code := range []int{429, 449, 408, 503} {
+		err := fmt.Errorf("error executing Azure Cosmos DB command; StatusCode=%d;Body={}", code)
+		if !dao.IsErrorTransient(err) {
+			t.Fatalf("%s failed: status code %d should be transient", testName, code)
+		}
+	}
+	for _, err := range []error{gocosmos.ErrConflict, gocosmos.ErrNotFound, fmt.Errorf("StatusCode=4290")} {
+		if dao.IsErrorTransient(err) {
+			t.Fatalf("%s failed: error %s should not be transient", testName, err)
+		}
+	}
+}
+
 func TestGenericDaoCosmosdb_CosmosSetGetIdGboMapPath(t *testing.T) {
 	testName := "TestGenericDaoCosmosdb_CosmosSetGetIdGboMapPath"
 	dao := initDaoCosmosdb(os.Getenv(envCosmosdbDriver), os.Getenv(envCosmosdbUrl), testTableName, sql.FlavorCosmosDb)
@@ -1213,3 +1258,35 @@ func TestGenericDaoCosmosdb_FilterNotNull(t *testing.T) {
 	}
 	dotestGenericDaoSqlGdaoFilterNotNull(t, testName, dao)
 }
+
+func TestGenericDaoCosmosdb_ApplyStorageDescriptors(t *testing.T) {
+	name := "TestGenericDaoCosmosdb_ApplyStorageDescriptors"
+	descs, err := godal.ParseStorageDescriptors([]byte(`
+storages:
+  - id: profiles
+    backend: cosmosdb
+    id_path: id
+    pk_path: tenant
+    schema: {type: object, required: [tenant]}
+  - id: orders
+    backend: cosmosdb
+    pk_path: customer.id
+`))
+	if err != nil {
+		t.Fatalf("%s failed: %s", name, err)
+	}
+	dao := &GenericDaoCosmosdb{IGenericDaoSql: &godalsql.GenericDaoSql{AbstractGenericDao: godal.NewAbstractGenericDao(nil)}}
+	dao.CosmosSetPkGboMapPath(map[string]string{"*": "pk"})
+	if err := dao.ApplyStorageDescriptors(descs); err != nil {
+		t.Fatalf("%s failed: %s", name, err)
+	}
+	if m := dao.CosmosGetIdGboMapPath(); !reflect.DeepEqual(m, map[string]string{"profiles": "id"}) {
+		t.Fatalf("%s failed: %#v", name, m)
+	}
+	if m := dao.CosmosGetPkGboMapPath(); !reflect.DeepEqual(m, map[string]string{"*": "pk", "profiles": "tenant", "orders": "customer.id"}) {
+		t.Fatalf("%s failed: %#v", name, m)
+	}
+	if err := dao.PrepareBoForWrite(context.Background(), "profiles", godal.NewGenericBo(), godal.WriteOpInsert); err == nil {
+		t.Fatalf("%s failed: schema is not attached", name)
+	}
+}
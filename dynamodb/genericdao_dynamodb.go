@@ -126,6 +126,7 @@ To create AwsDynamodbConnect instances, see package github.com/btnguyen2k/prom/d
 package dynamodb
 
 import (
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"reflect"
@@ -134,7 +135,9 @@ import (
 	"strings"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	awsdynamodb "github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
 	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
 	"github.com/btnguyen2k/consu/reddo"
 	"github.com/btnguyen2k/godal"
@@ -144,10 +147,10 @@ import (
 // GenericRowMapperDynamodb is a generic implementation of godal.IRowMapper for AWS DynamoDB.
 //
 // Implementation rules:
-// 	 - ToRow        : transform godal.IGenericBo "as-is" to map[string]interface{}.
+// 	 - ToRow        : transform godal.IGenericBo "as-is" to map[string]interface{}. Since v0.7.0, arbitrary-precision numbers (see godal.ExactNumberString) are converted to dynamodbattribute.Number, i.e. written as exact numbers.
 // 	 - ToBo         : expect input is a map[string]interface{}, or JSON data (string or array/slice of bytes), transforms input to godal.IGenericBo via JSON unmarshalling.
 // 	 - ColumnsList  : look up column-list from a 'columns-list map' (AWS DynamoDB is schema-free but key-attributes are significant) and returns it.
-//   - ToDbColName  : return the input field name "as-is".
+//   - ToDbColName  : return the input field name "as-is", except that quoted path segments are unquoted (e.g. `labels["env"]` to "labels.env").
 //   - ToBoFieldName: return the input column name "as-is".
 //
 // Available: since v0.2.0
@@ -164,7 +167,21 @@ func (mapper *GenericRowMapperDynamodb) ToRow(_ string, bo godal.IGenericBo) (in
 		return nil, nil
 	}
 	result := make(map[string]interface{})
-	return result, bo.GboTransferViaJson(&result)
+	if err := bo.GboTransferViaJson(&result); err != nil {
+		return result, err
+	}
+	return result, godal.ReplaceExactNumbers(bo, result, func(value interface{}, _ string) (interface{}, error) {
+		return toDynamodbValue(value), nil
+	})
+}
+
+// toDynamodbValue converts arbitrary-precision numbers (see godal.ExactNumberString) to dynamodbattribute.Number, so
+// that they are written as DynamoDB numbers (which are transferred as strings) without going through float64.
+func toDynamodbValue(v interface{}) interface{} {
+	if number, ok := godal.ExactNumberString(v); ok {
+		return dynamodbattribute.Number(number)
+	}
+	return v
 }
 
 // ToBo implements godal.IRowMapper.ToBo.
@@ -262,11 +279,75 @@ func (mapper *GenericRowMapperDynamodb) ColumnsList(table string) []string {
 
 // ToDbColName implements godal.IRowMapper.ToDbColName.
 //
-// This function returns the input field name "as-is".
+// This function returns the input field name "as-is". Since v0.7.0, quoted path segments are unquoted to form a
+// DynamoDB document path, e.g. `labels["env"][0]` to "labels.env[0]".
 func (mapper *GenericRowMapperDynamodb) ToDbColName(_, fieldName string) string {
+	if result, err := toDocumentPath(fieldName, false); err == nil {
+		return result
+	}
 	return fieldName
 }
 
+// toDocumentPath converts a field path with quoted segments to a DynamoDB document path. An error is returned if a
+// member name is empty, or if it contains '.', '[' or ']' (which expression.Name can not represent) and escape is
+// false; if escape is true such member names are escaped by escapeMemberName.
+func toDocumentPath(fieldName string, escape bool) (string, error) {
+	if !strings.ContainsAny(fieldName, `"'`) {
+		return fieldName, nil
+	}
+	p, err := godal.ParsePath(fieldName)
+	if err != nil {
+		return fieldName, nil
+	}
+	var sb strings.Builder
+	for _, seg := range p.Segments() {
+		switch {
+		case seg.Index >= 0:
+			sb.WriteString("[" + strconv.Itoa(seg.Index) + "]")
+		case seg.Key == "" || (!escape && strings.ContainsAny(seg.Key, ".[]")):
+			return "", fmt.Errorf("field %q: member name %q is not supported in DynamoDB document paths", fieldName, seg.Key)
+		default:
+			if sb.Len() > 0 {
+				sb.WriteString(".")
+			}
+			if strings.ContainsAny(seg.Key, ".[]") {
+				sb.WriteString(escapeMemberName(seg.Key))
+			} else {
+				sb.WriteString(seg.Key)
+			}
+		}
+	}
+	return sb.String(), nil
+}
+
+// escapedMemberNamePrefix prefixes member names escaped by escapeMemberName.
+const escapedMemberNamePrefix = "__godal_x_"
+
+// escapeMemberName escapes a member name that expression.Name can not represent (e.g. "example.com") to a name it
+// accepts as a single member. The escaped name is restored by ResolveExpressionAttributeNames.
+func escapeMemberName(name string) string {
+	return escapedMemberNamePrefix + hex.EncodeToString([]byte(name))
+}
+
+// ResolveExpressionAttributeNames restores, in place, the member names escaped by BuildConditionBuilder in the
+// ExpressionAttributeNames of a built expression (or of an input built from it).
+//
+// Member names containing '.', '[' or ']' (e.g. `labels["example.com"]`) can not be represented by expression.Name;
+// BuildConditionBuilder escapes them and this function maps their placeholders back to the actual member names.
+//
+// Available since v0.7.0
+func ResolveExpressionAttributeNames(names map[string]*string) map[string]*string {
+	for placeholder, name := range names {
+		if name == nil || !strings.HasPrefix(*name, escapedMemberNamePrefix) {
+			continue
+		}
+		if decoded, err := hex.DecodeString(strings.TrimPrefix(*name, escapedMemberNamePrefix)); err == nil {
+			names[placeholder] = aws.String(string(decoded))
+		}
+	}
+	return names
+}
+
 // ToBoFieldName implements godal.IRowMapper.ToBoFieldName.
 //
 // This function returns the input column name "as-is".
@@ -302,6 +383,9 @@ func NewGenericDaoDynamodb(dynamodbConnect *dynamodb.AwsDynamodbConnect, agdao *
 // 	 - (y) GdaoUpdate(tableName string, bo godal.IGenericBo) (int, error)
 // 	 - (y) GdaoSave(tableName string, bo godal.IGenericBo) (int, error)
 //
+// Since v0.7.0, update, save and delete honor the write condition carried by the context (see godal.WithWriteCondition):
+// items that do not satisfy the condition are not touched, and the operation returns 0 without error.
+//
 // Available: since v0.2.0
 type GenericDaoDynamodb struct {
 	*godal.AbstractGenericDao
@@ -345,7 +429,7 @@ func toFilterMap(filter godal.FilterOpt) (map[string]interface{}, error) {
 		if f.Operator != godal.FilterOpEqual {
 			return nil, fmt.Errorf("invalid operator \"%#v\", only accept FilterOptFieldOpValue with operator FilterOpEqual", f.Operator)
 		}
-		return map[string]interface{}{f.FieldName: f.Value}, nil
+		return map[string]interface{}{f.FieldName: toDynamodbValue(f.Value)}, nil
 	case godal.FilterOptFieldIsNull:
 		f := filter.(godal.FilterOptFieldIsNull)
 		return toFilterMap(&f)
@@ -372,6 +456,32 @@ func toFilterMap(filter godal.FilterOpt) (map[string]interface{}, error) {
 	return nil, fmt.Errorf("cannot build filter map from %T", filter)
 }
 
+// transientErrorCodes holds AWS error codes that are considered transient.
+var transientErrorCodes = map[string]bool{
+	awsdynamodb.ErrCodeProvisionedThroughputExceededException: true,
+	awsdynamodb.ErrCodeRequestLimitExceeded:                   true,
+	awsdynamodb.ErrCodeInternalServerError:                    true,
+	awsdynamodb.ErrCodeTransactionConflictException:           true,
+	"ThrottlingException":                                     true,
+	"ServiceUnavailable":                                      true,
+}
+
+// IsErrorTransient checks if the error is transient and the operation can be retried.
+//
+// An error is transient if its AWS error code is one of ProvisionedThroughputExceededException, ThrottlingException,
+// RequestLimitExceeded, InternalServerError, TransactionConflictException or ServiceUnavailable.
+//
+// This function can be used as godal.TransientErrorClassifier.
+//
+// Available since v0.7.0
+func IsErrorTransient(err error) bool {
+	var awsErr awserr.Error
+	if errors.As(err, &awsErr) {
+		return transientErrorCodes[awsErr.Code()]
+	}
+	return false
+}
+
 /*----------------------------------------------------------------------*/
 
 // GdaoDelete implements godal.IGenericDao.GdaoDelete.
@@ -385,11 +495,17 @@ func (dao *GenericDaoDynamodb) GdaoDeleteWithContext(ctx aws.Context, table stri
 	if err != nil {
 		return 0, err
 	}
-	deleteInput, err := dao.dynamodbConnect.BuildDeleteItemInput(table, keyFilter, nil)
+	condition, err := dao.BuildConditionBuilder(table, godal.WriteConditionFromContext(ctx))
+	if err != nil {
+		return 0, err
+	}
+	deleteInput, err := dao.dynamodbConnect.BuildDeleteItemInput(table, keyFilter, condition)
 	if err != nil {
 		return 0, err
 	}
+	ResolveExpressionAttributeNames(deleteInput.ExpressionAttributeNames)
 	deleteResult, err := dao.dynamodbConnect.DeleteItemWithInput(ctx, deleteInput.SetReturnValues("ALL_OLD"))
+	err = dynamodb.AwsIgnoreErrorIfMatched(err, awsdynamodb.ErrCodeConditionalCheckFailedException)
 	numRows := 0
 	if deleteResult != nil && deleteResult.Attributes != nil {
 		numRows = 1
@@ -409,6 +525,11 @@ func (dao *GenericDaoDynamodb) GdaoDeleteMany(table string, filter godal.FilterO
 
 // BuildConditionBuilder transforms a godal.FilterOpt to expression.ConditionBuilder.
 //
+// Since v0.7.0, field names may have quoted segments (e.g. `labels["env"]`). Member names containing '.', '[' or ']'
+// (e.g. `labels["example.com"]`) can not be expressed by expression.Name, they are escaped instead: pass the
+// ExpressionAttributeNames of the built expression (or input) to ResolveExpressionAttributeNames before sending the
+// request. GenericDaoDynamodb's operations do this. Such fields are not translated by the row-mapper.
+//
 // Available since v0.5.1
 func (dao *GenericDaoDynamodb) BuildConditionBuilder(tableName string, filter godal.FilterOpt) (*expression.ConditionBuilder, error) {
 	if filter == nil {
@@ -425,25 +546,29 @@ func (dao *GenericDaoDynamodb) BuildConditionBuilder(tableName string, filter go
 		return dao.BuildConditionBuilder(tableName, &f)
 	case *godal.FilterOptFieldOpValue:
 		f := filter.(*godal.FilterOptFieldOpValue)
-		exp := expression.Name(rm.ToDbColName(tableName, f.FieldName))
+		exp, err := conditionName(rm, tableName, f.FieldName)
+		if err != nil {
+			return nil, err
+		}
+		value := expression.Value(toDynamodbValue(f.Value))
 		switch f.Operator {
 		case godal.FilterOpEqual:
-			t := exp.Equal(expression.Value(f.Value))
+			t := exp.Equal(value)
 			return &t, nil
 		case godal.FilterOpNotEqual:
-			t := exp.NotEqual(expression.Value(f.Value))
+			t := exp.NotEqual(value)
 			return &t, nil
 		case godal.FilterOpGreater:
-			t := exp.GreaterThan(expression.Value(f.Value))
+			t := exp.GreaterThan(value)
 			return &t, nil
 		case godal.FilterOpGreaterOrEqual:
-			t := exp.GreaterThanEqual(expression.Value(f.Value))
+			t := exp.GreaterThanEqual(value)
 			return &t, nil
 		case godal.FilterOpLess:
-			t := exp.LessThan(expression.Value(f.Value))
+			t := exp.LessThan(value)
 			return &t, nil
 		case godal.FilterOpLessOrEqual:
-			t := exp.LessThanEqual(expression.Value(f.Value))
+			t := exp.LessThanEqual(value)
 			return &t, nil
 		}
 		return nil, fmt.Errorf("unknown filter operator: %#v", f.Operator)
@@ -452,14 +577,22 @@ func (dao *GenericDaoDynamodb) BuildConditionBuilder(tableName string, filter go
 		return dao.BuildConditionBuilder(tableName, &f)
 	case *godal.FilterOptFieldIsNull:
 		f := filter.(*godal.FilterOptFieldIsNull)
-		t := expression.Name(rm.ToDbColName(tableName, f.FieldName)).AttributeNotExists()
+		name, err := conditionName(rm, tableName, f.FieldName)
+		if err != nil {
+			return nil, err
+		}
+		t := name.AttributeNotExists()
 		return &t, nil
 	case godal.FilterOptFieldIsNotNull:
 		f := filter.(godal.FilterOptFieldIsNotNull)
 		return dao.BuildConditionBuilder(tableName, &f)
 	case *godal.FilterOptFieldIsNotNull:
 		f := filter.(*godal.FilterOptFieldIsNotNull)
-		t := expression.Name(rm.ToDbColName(tableName, f.FieldName)).AttributeExists()
+		name, err := conditionName(rm, tableName, f.FieldName)
+		if err != nil {
+			return nil, err
+		}
+		t := name.AttributeExists()
 		return &t, nil
 	case godal.FilterOptAnd:
 		f := filter.(godal.FilterOptAnd)
@@ -503,6 +636,37 @@ func (dao *GenericDaoDynamodb) BuildConditionBuilder(tableName string, filter go
 	return nil, fmt.Errorf("cannot build filter from %T", filter)
 }
 
+// conditionName builds the expression.NameBuilder of a field used in conditions, escaping member names that
+// expression.Name can not represent.
+func conditionName(rm godal.IRowMapper, tableName, fieldName string) (expression.NameBuilder, error) {
+	if _, err := toDocumentPath(fieldName, false); err == nil {
+		return expression.Name(rm.ToDbColName(tableName, fieldName)), nil
+	}
+	path, err := toDocumentPath(fieldName, true)
+	return expression.Name(path), err
+}
+
+// scanOrQueryWithCallback scans (or queries if useQuery is true) items matching the filter.
+func (dao *GenericDaoDynamodb) scanOrQueryWithCallback(ctx aws.Context, tableName string, filter *expression.ConditionBuilder, indexName string, useQuery, queryBackward bool, callback dynamodb.AwsDynamodbItemCallback) error {
+	if !useQuery {
+		input, err := dao.dynamodbConnect.BuildScanInput(tableName, filter, indexName, nil)
+		if err != nil {
+			return err
+		}
+		ResolveExpressionAttributeNames(input.ExpressionAttributeNames)
+		return dao.dynamodbConnect.ScanWithInputCallback(ctx, input, callback)
+	}
+	input, err := dao.dynamodbConnect.BuildQueryInput(tableName, filter, nil, indexName, nil)
+	if err != nil {
+		return err
+	}
+	if queryBackward {
+		input.ScanIndexForward = aws.Bool(false)
+	}
+	ResolveExpressionAttributeNames(input.ExpressionAttributeNames)
+	return dao.dynamodbConnect.QueryWithInputCallback(ctx, input, callback)
+}
+
 // GdaoDeleteManyWithContext is is AWS DynamoDB variant of GdaoDeleteMany.
 func (dao *GenericDaoDynamodb) GdaoDeleteManyWithContext(ctx aws.Context, table string, filter godal.FilterOpt) (int, error) {
 	f, err := dao.BuildConditionBuilder(table, filter)
@@ -529,11 +693,7 @@ func (dao *GenericDaoDynamodb) GdaoDeleteManyWithContext(ctx aws.Context, table
 		}
 		return true, err
 	}
-	if useScan {
-		err = dao.dynamodbConnect.ScanItemsWithCallback(ctx, tableName, f, indexName, nil, callbackFunc)
-	} else {
-		err = dao.dynamodbConnect.QueryItemsWithCallback(ctx, tableName, f, nil, indexName, nil, callbackFunc)
-	}
+	err = dao.scanOrQueryWithCallback(ctx, tableName, f, indexName, !useScan, false, callbackFunc)
 	return count, err
 }
 
@@ -619,11 +779,7 @@ func (dao *GenericDaoDynamodb) GdaoFetchManyWithContext(ctx aws.Context, table s
 		}
 		return true, nil
 	}
-	if useQuery {
-		err = dao.dynamodbConnect.QueryItemsWithCallback(ctx, tableName, f, nil, indexName, nil, callbackFunc, dynamodb.AwsQueryOpt{ScanIndexBackward: aws.Bool(queryBackward)})
-	} else {
-		err = dao.dynamodbConnect.ScanItemsWithCallback(ctx, tableName, f, indexName, nil, callbackFunc)
-	}
+	err = dao.scanOrQueryWithCallback(ctx, tableName, f, indexName, useQuery, queryBackward, callbackFunc)
 
 	return result, err
 }
@@ -635,6 +791,9 @@ func (dao *GenericDaoDynamodb) GdaoCreate(table string, bo godal.IGenericBo) (in
 
 // GdaoCreateWithContext is is AWS DynamoDB variant of GdaoCreate.
 func (dao *GenericDaoDynamodb) GdaoCreateWithContext(ctx aws.Context, table string, bo godal.IGenericBo) (int, error) {
+	if err := dao.PrepareBoForWrite(ctx, table, bo, godal.WriteOpInsert); err != nil {
+		return 0, err
+	}
 	pkAttrs := dao.GetRowMapper().ColumnsList(table)
 	if pkAttrs == nil || len(pkAttrs) == 0 {
 		return 0, fmt.Errorf("cannot find primary-key attribute list for table [%s]", table)
@@ -659,6 +818,9 @@ func (dao *GenericDaoDynamodb) GdaoUpdate(table string, bo godal.IGenericBo) (in
 
 // GdaoUpdateWithContext is is AWS DynamoDB variant of GdaoUpdate.
 func (dao *GenericDaoDynamodb) GdaoUpdateWithContext(ctx aws.Context, table string, bo godal.IGenericBo) (int, error) {
+	if err := dao.PrepareBoForWrite(ctx, table, bo, godal.WriteOpUpdate); err != nil {
+		return 0, err
+	}
 	var keyFilter, itemMap map[string]interface{}
 	var err error
 	if keyFilter, err = toFilterMap(dao.GdaoCreateFilter(table, bo)); err != nil {
@@ -681,7 +843,18 @@ func (dao *GenericDaoDynamodb) GdaoUpdateWithContext(ctx aws.Context, table stri
 		delete(itemMap, pk)
 	}
 	condition := dynamodb.AwsDynamodbExistsAllBuilder(pkAttrs)
-	if _, err = dao.dynamodbConnect.UpdateItem(ctx, table, keyFilter, condition, nil, itemMap, nil, nil); err != nil {
+	if writeCondition, err := dao.BuildConditionBuilder(table, godal.WriteConditionFromContext(ctx)); err != nil {
+		return 0, err
+	} else if writeCondition != nil {
+		c := condition.And(*writeCondition)
+		condition = &c
+	}
+	updateInput, err := dao.dynamodbConnect.BuildUpdateItemInput(table, keyFilter, condition, nil, itemMap, nil, nil)
+	if err != nil {
+		return 0, err
+	}
+	ResolveExpressionAttributeNames(updateInput.ExpressionAttributeNames)
+	if _, err = dao.dynamodbConnect.UpdateItemWithInput(ctx, updateInput); err != nil {
 		err = dynamodb.AwsIgnoreErrorIfMatched(err, awsdynamodb.ErrCodeConditionalCheckFailedException)
 		return 0, err
 	}
@@ -697,6 +870,9 @@ func (dao *GenericDaoDynamodb) GdaoSave(table string, bo godal.IGenericBo) (int,
 
 // GdaoSaveWithContext is is AWS DynamoDB variant of GdaoSave.
 func (dao *GenericDaoDynamodb) GdaoSaveWithContext(ctx aws.Context, table string, bo godal.IGenericBo) (int, error) {
+	if err := dao.PrepareBoForWrite(ctx, table, bo, godal.WriteOpUpsert); err != nil {
+		return 0, err
+	}
 	pkAttrs := dao.GetRowMapper().ColumnsList(table)
 	if pkAttrs == nil || len(pkAttrs) == 0 {
 		return 0, fmt.Errorf("cannot find primary-key attribute list for table [%s]", table)
@@ -705,6 +881,70 @@ func (dao *GenericDaoDynamodb) GdaoSaveWithContext(ctx aws.Context, table string
 	if err != nil {
 		return 0, err
 	}
-	_, err = dao.dynamodbConnect.PutItem(ctx, table, item, nil)
-	return 1, err
+	// the existing item is replaced only if it satisfies the write condition
+	var condition *expression.ConditionBuilder
+	if writeCondition, err := dao.BuildConditionBuilder(table, godal.WriteConditionFromContext(ctx)); err != nil {
+		return 0, err
+	} else if writeCondition != nil {
+		c := dynamodb.AwsDynamodbNotExistsAllBuilder(pkAttrs).Or(*writeCondition)
+		condition = &c
+	}
+	av, err := dynamodbattribute.MarshalMap(item)
+	if err != nil {
+		return 0, err
+	}
+	putInput, err := dao.dynamodbConnect.BuildPutItemInput(table, av, condition)
+	if err != nil {
+		return 0, err
+	}
+	ResolveExpressionAttributeNames(putInput.ExpressionAttributeNames)
+	if _, err = dao.dynamodbConnect.PutItemWithInput(ctx, putInput); err != nil {
+		err = dynamodb.AwsIgnoreErrorIfMatched(err, awsdynamodb.ErrCodeConditionalCheckFailedException)
+		return 0, err
+	}
+	return 1, nil
+}
+
+/*----------------------------------------------------------------------*/
+
+// NewRowMapperFromDescriptors builds a GenericRowMapperDynamodb from the descriptors of backend godal.BackendDynamodb:
+// key fields become the tables' primary-key attribute lists.
+//
+// Available since v0.7.0
+func NewRowMapperFromDescriptors(descs *godal.StorageDescriptors) *GenericRowMapperDynamodb {
+	return mergeRowMapperDescriptors(&GenericRowMapperDynamodb{}, descs)
+}
+
+// mergeRowMapperDescriptors returns a copy of base with the primary-key attribute lists of the tables described for
+// backend godal.BackendDynamodb; lists of other tables are kept.
+func mergeRowMapperDescriptors(base *GenericRowMapperDynamodb, descs *godal.StorageDescriptors) *GenericRowMapperDynamodb {
+	mapper := &GenericRowMapperDynamodb{ColumnsListMap: make(map[string][]string)}
+	for k, v := range base.ColumnsListMap {
+		mapper.ColumnsListMap[k] = v
+	}
+	for _, s := range descs.ForBackend(godal.BackendDynamodb) {
+		mapper.ColumnsListMap[s.Id] = s.KeyColumns()
+	}
+	return mapper
+}
+
+// ApplyStorageDescriptors configures the DAO with the descriptors of backend godal.BackendDynamodb: the primary-key
+// attribute lists of described tables are merged into the current row mapper (a GenericRowMapperDynamodb; lists of
+// other tables are kept), and schemas are attached to tables. The row mapper is replaced by a merged copy, the current
+// one is not modified.
+//
+// Available since v0.7.0
+func (dao *GenericDaoDynamodb) ApplyStorageDescriptors(descs *godal.StorageDescriptors) error {
+	base := &GenericRowMapperDynamodb{}
+	if current := dao.GetRowMapper(); current != nil {
+		var ok bool
+		if base, ok = current.(*GenericRowMapperDynamodb); !ok {
+			return fmt.Errorf("row mapper %T can not be merged with storage descriptors", current)
+		}
+	}
+	if err := descs.ApplySchemas(godal.BackendDynamodb, dao.AbstractGenericDao); err != nil {
+		return err
+	}
+	dao.SetRowMapper(mergeRowMapperDescriptors(base, descs))
+	return nil
 }
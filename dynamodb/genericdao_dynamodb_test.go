@@ -1,6 +1,7 @@
 package dynamodb
 
 import (
+	"errors"
 	"fmt"
 	"math/rand"
 	"os"
@@ -11,11 +12,14 @@ import (
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/credentials"
 	awsdynamodb "github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
 	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
 	"github.com/btnguyen2k/consu/reddo"
 	"github.com/btnguyen2k/prom/dynamodb"
+	"github.com/shopspring/decimal"
 
 	"github.com/btnguyen2k/godal"
 )
@@ -365,6 +369,105 @@ func TestGenericRowMapperDynamodb_ToDbColName(t *testing.T) {
 	}
 }
 
+func TestGenericRowMapperDynamodb_ToDbColName_Path(t *testing.T) {
+	name := "TestGenericRowMapperDynamodb_ToDbColName_Path"
+	rowMapper := &GenericRowMapperDynamodb{}
+	testCases := []struct {
+		fieldName, expected string
+	}{
+		{"a.b[0]", "a.b[0]"},
+		{`labels["env"][0]`, "labels.env[0]"},
+		{godal.Path("a", "b").Index(1).String(), "a.b[1]"},
+		{`labels["example.com"]`, `labels["example.com"]`},
+	}
+	for _, tc := range testCases {
+		if fieldName := rowMapper.ToDbColName("table", tc.fieldName); fieldName != tc.expected {
+			t.Fatalf("%s failed, expect %#v but received %#v", name, tc.expected, fieldName)
+		}
+	}
+}
+
+func TestGenericDaoDynamodb_BuildConditionBuilder_QuotedPath(t *testing.T) {
+	name := "TestGenericDaoDynamodb_BuildConditionBuilder_QuotedPath"
+	dao := NewGenericDaoDynamodb(nil, godal.NewAbstractGenericDao(nil))
+	output, err := dao.BuildConditionBuilder("table", &godal.FilterOptFieldOpValue{FieldName: `labels["env"]`, Operator: godal.FilterOpEqual, Value: "prod"})
+	if expected := expression.Name("labels.env").Equal(expression.Value("prod")); err != nil || !reflect.DeepEqual(*output, expected) {
+		t.Fatalf("%s failed: expected %#v but received %#v / Error: %s", name, expected, output, err)
+	}
+	if _, err := dao.BuildConditionBuilder("table", godal.FilterOptFieldIsNull{FieldName: `labels[""]`}); err == nil {
+		t.Fatalf("%s failed: empty member name should be rejected", name)
+	}
+
+	// member names that expression.Name can not represent are passed via ExpressionAttributeNames
+	testCases := []struct {
+		filter godal.FilterOpt
+		names  []string
+	}{
+		{&godal.FilterOptFieldOpValue{FieldName: `labels["example.com"]`, Operator: godal.FilterOpEqual, Value: 1}, []string{"labels", "example.com"}},
+		{godal.FilterOptFieldIsNull{FieldName: `labels["a[0]"]`}, []string{"labels", "a[0]"}},
+		{godal.FilterOptFieldIsNotNull{FieldName: `labels["a.b"]`}, []string{"labels", "a.b"}},
+		{godal.FilterOptFieldIsNotNull{FieldName: `labels["a.b"][1].c`}, []string{"labels", "a.b", "c"}},
+	}
+	for _, tc := range testCases {
+		output, err := dao.BuildConditionBuilder("table", tc.filter)
+		if err != nil {
+			t.Fatalf("%s failed: %#v / Error: %s", name, tc.filter, err)
+		}
+		exp, err := expression.NewBuilder().WithCondition(*output).Build()
+		if err != nil {
+			t.Fatalf("%s failed: %#v / Error: %s", name, tc.filter, err)
+		}
+		names := ResolveExpressionAttributeNames(exp.Names())
+		resolved := make([]string, 0, len(names))
+		for i := 0; i < len(names); i++ {
+			resolved = append(resolved, aws.StringValue(names[fmt.Sprintf("#%d", i)]))
+		}
+		if !reflect.DeepEqual(resolved, tc.names) {
+			t.Fatalf("%s failed: expected %#v but received %#v (%s)", name, tc.names, resolved, *exp.Condition())
+		}
+	}
+}
+
+func TestGenericRowMapperDynamodb_ToRow_ExactNumbers(t *testing.T) {
+	name := "TestGenericRowMapperDynamodb_ToRow_ExactNumbers"
+	rowMapper := &GenericRowMapperDynamodb{}
+	bo := godal.NewGenericBo()
+	bo.(*godal.GenericBo).GboSetUseNumber(true).GboFromJson([]byte(`{"id":12345678901234567890,"items":[{"amount":0.10}]}`))
+	bo.GboSetAttr("dec", decimal.RequireFromString("-1.5"))
+	bo.GboSetAttr("float", 2.5)
+	row, err := rowMapper.ToRow("table", bo)
+	if err != nil {
+		t.Fatalf("%s failed: %s", name, err)
+	}
+	item, err := dynamodbattribute.MarshalMap(row)
+	if err != nil {
+		t.Fatalf("%s failed: %s", name, err)
+	}
+	if v := item["id"].N; v == nil || *v != "12345678901234567890" {
+		t.Fatalf("%s failed: %s", name, item["id"])
+	}
+	if v := item["items"].L[0].M["amount"].N; v == nil || *v != "0.10" {
+		t.Fatalf("%s failed: %s", name, item["items"])
+	}
+	if v := item["dec"].N; v == nil || *v != "-1.5" {
+		t.Fatalf("%s failed: %s", name, item["dec"])
+	}
+	if v := item["float"].N; v == nil || *v != "2.5" {
+		t.Fatalf("%s failed: %s", name, item["float"])
+	}
+
+	dao := NewGenericDaoDynamodb(nil, godal.NewAbstractGenericDao(nil))
+	id := bo.GboGetAttrUnsafe("id", nil)
+	output, err := dao.BuildConditionBuilder("table", &godal.FilterOptFieldOpValue{FieldName: "id", Operator: godal.FilterOpEqual, Value: id})
+	expected := expression.Name("id").Equal(expression.Value(dynamodbattribute.Number("12345678901234567890")))
+	if err != nil || !reflect.DeepEqual(*output, expected) {
+		t.Fatalf("%s failed: expected %#v but received %#v / Error: %s", name, expected, output, err)
+	}
+	if filter, err := toFilterMap(&godal.FilterOptFieldOpValue{FieldName: "id", Operator: godal.FilterOpEqual, Value: id}); err != nil || filter["id"] != dynamodbattribute.Number("12345678901234567890") {
+		t.Fatalf("%s failed: %#v / %s", name, filter, err)
+	}
+}
+
 func TestGenericRowMapperDynamodb_ToBoFieldName(t *testing.T) {
 	name := "TestGenericRowMapperDynamodb_ToBoFieldName"
 	table := "table"
@@ -416,6 +519,28 @@ func TestGenericDaoDynamodb_SetGetAwsDynamodbConnect(t *testing.T) {
 	}
 }
 
+func TestIsErrorTransient(t *testing.T) {
+	name := "TestIsErrorTransient"
+	if IsErrorTransient(nil) {
+		t.Fatalf("%s failed: nil error should not be transient", name)
+	}
+	if IsErrorTransient(errors.New("ThrottlingException")) {
+		t.Fatalf("%s failed: non-AWS error should not be transient", name)
+	}
+	for _, code := range []string{awsdynamodb.ErrCodeProvisionedThroughputExceededException, "ThrottlingException", awsdynamodb.ErrCodeRequestLimitExceeded} {
+		err := awserr.New(code, "dummy", nil)
+		if !IsErrorTransient(err) {
+			t.Fatalf("%s failed: error %s should be transient", name, code)
+		}
+		if !IsErrorTransient(fmt.Errorf("wrapped: %w", err)) {
+			t.Fatalf("%s failed: wrapped error %s should be transient", name, code)
+		}
+	}
+	if IsErrorTransient(awserr.New(awsdynamodb.ErrCodeConditionalCheckFailedException, "dummy", nil)) {
+		t.Fatalf("%s failed: error %s should not be transient", name, awsdynamodb.ErrCodeConditionalCheckFailedException)
+	}
+}
+
 func TestToFilterMap(t *testing.T) {
 	name := "TestToFilterMap"
 
@@ -1643,3 +1768,143 @@ func TestGenericDaoDynamodb_GdaoSaveShouldReplace(t *testing.T) {
 		}
 	}
 }
+
+func TestGenericDaoDynamodb_ApplyStorageDescriptors(t *testing.T) {
+	name := "TestGenericDaoDynamodb_ApplyStorageDescriptors"
+	descs, err := godal.ParseStorageDescriptors([]byte(`
+storages:
+  - id: sessions
+    backend: dynamodb
+    fields:
+      - {name: user, key: true}
+      - {name: created, key: true}
+      - {name: data}
+    schema: {type: object, required: [user, created]}
+  - id: users
+    backend: sql
+    fields: [{name: id}]
+`))
+	if err != nil {
+		t.Fatalf("%s failed: %s", name, err)
+	}
+	dao := NewGenericDaoDynamodb(nil, godal.NewAbstractGenericDao(nil))
+	if err := dao.ApplyStorageDescriptors(descs); err != nil {
+		t.Fatalf("%s failed: %s", name, err)
+	}
+	if cols := dao.GetRowMapper().ColumnsList("sessions"); !reflect.DeepEqual(cols, []string{"user", "created"}) {
+		t.Fatalf("%s failed: %#v", name, cols)
+	}
+	if cols := dao.GetRowMapper().ColumnsList("users"); cols != nil {
+		t.Fatalf("%s failed: %#v", name, cols)
+	}
+	if dao.GetSchema("sessions") == nil || dao.GetSchema("users") != nil {
+		t.Fatalf("%s failed: schemas are not attached", name)
+	}
+}
+
+func TestGenericDaoDynamodb_ApplyStorageDescriptors_Merge(t *testing.T) {
+	name := "TestGenericDaoDynamodb_ApplyStorageDescriptors_Merge"
+	descs, err := godal.ParseStorageDescriptors([]byte(`
+storages:
+  - id: sessions
+    backend: dynamodb
+    fields: [{name: user, key: true}]
+`))
+	if err != nil {
+		t.Fatalf("%s failed: %s", name, err)
+	}
+	current := &GenericRowMapperDynamodb{ColumnsListMap: map[string][]string{"orders": {"id"}, "sessions": {"sid"}}}
+	dao := NewGenericDaoDynamodb(nil, godal.NewAbstractGenericDao(nil))
+	dao.SetRowMapper(current)
+	if err := dao.ApplyStorageDescriptors(descs); err != nil {
+		t.Fatalf("%s failed: %s", name, err)
+	}
+	if cols := dao.GetRowMapper().ColumnsList("orders"); !reflect.DeepEqual(cols, []string{"id"}) {
+		t.Fatalf("%s failed: key attributes of other tables should be kept: %#v", name, cols)
+	}
+	if cols := dao.GetRowMapper().ColumnsList("sessions"); !reflect.DeepEqual(cols, []string{"user"}) {
+		t.Fatalf("%s failed: %#v", name, cols)
+	}
+	if cols := current.ColumnsList("sessions"); !reflect.DeepEqual(cols, []string{"sid"}) {
+		t.Fatalf("%s failed: current row mapper should not be modified: %#v", name, cols)
+	}
+}
+
+// keyMapRecordingDao records the key maps that GdaoFetchOne would send to GetItem.
+type keyMapRecordingDao struct {
+	*godal.AbstractGenericDao
+	items   map[string]godal.IGenericBo
+	keyMaps []map[string]interface{}
+}
+
+func (dao *keyMapRecordingDao) GdaoCreateFilter(_ string, bo godal.IGenericBo) godal.FilterOpt {
+	return godal.FilterOptFieldOpValue{FieldName: "id", Operator: godal.FilterOpEqual, Value: bo.GboGetAttrUnsafe("id", nil)}
+}
+
+func (dao *keyMapRecordingDao) GdaoDelete(_ string, _ godal.IGenericBo) (int, error) {
+	return 0, nil
+}
+
+func (dao *keyMapRecordingDao) GdaoDeleteMany(_ string, _ godal.FilterOpt) (int, error) {
+	return 0, nil
+}
+
+func (dao *keyMapRecordingDao) GdaoFetchOne(_ string, filter godal.FilterOpt) (godal.IGenericBo, error) {
+	keyMap, err := toFilterMap(filter)
+	if err != nil {
+		return nil, err
+	}
+	dao.keyMaps = append(dao.keyMaps, keyMap)
+	if bo, ok := dao.items[fmt.Sprint(keyMap["id"])]; ok {
+		return bo.(*godal.GenericBo).GboClone(), nil
+	}
+	return nil, nil
+}
+
+func (dao *keyMapRecordingDao) GdaoFetchMany(_ string, _ godal.FilterOpt, _ *godal.SortingOpt, _, _ int) ([]godal.IGenericBo, error) {
+	return nil, nil
+}
+
+func (dao *keyMapRecordingDao) GdaoCreate(storageId string, bo godal.IGenericBo) (int, error) {
+	return dao.GdaoSave(storageId, bo)
+}
+
+func (dao *keyMapRecordingDao) GdaoUpdate(storageId string, bo godal.IGenericBo) (int, error) {
+	return dao.GdaoSave(storageId, bo)
+}
+
+func (dao *keyMapRecordingDao) GdaoSave(_ string, bo godal.IGenericBo) (int, error) {
+	dao.items[fmt.Sprint(bo.GboGetAttrUnsafe("id", nil))] = bo.(*godal.GenericBo).GboClone()
+	return 1, nil
+}
+
+func TestSoftDeleteGenericDao_GdaoFetchOne_KeyOnly(t *testing.T) {
+	name := "TestSoftDeleteGenericDao_GdaoFetchOne_KeyOnly"
+	recorder := &keyMapRecordingDao{AbstractGenericDao: godal.NewAbstractGenericDao(nil), items: make(map[string]godal.IGenericBo)}
+	dao := godal.NewSoftDeleteGenericDao(recorder, "").SetRemoveWhenActive(true).SetKeyOnlyFetchOne(true)
+	for _, id := range []string{"1", "2"} {
+		bo := godal.NewGenericBo()
+		bo.GboSetAttr("id", id)
+		if _, err := dao.GdaoCreate("test", bo); err != nil {
+			t.Fatalf("%s failed: %s", name, err)
+		}
+	}
+	bo := godal.NewGenericBo()
+	bo.GboSetAttr("id", "2")
+	if _, err := dao.GdaoDelete("test", bo); err != nil {
+		t.Fatalf("%s failed: %s", name, err)
+	}
+
+	recorder.keyMaps = nil
+	if bo, err := dao.GdaoFetchOne("test", dao.GdaoCreateFilter("test", bo)); bo != nil || err != nil {
+		t.Fatalf("%s failed: soft-deleted BO should not be returned: %#v / %s", name, bo, err)
+	}
+	bo.GboSetAttr("id", "1")
+	if bo, err := dao.GdaoFetchOne("test", dao.GdaoCreateFilter("test", bo)); bo == nil || err != nil {
+		t.Fatalf("%s failed: active BO should be returned: %#v / %s", name, bo, err)
+	}
+	expected := []map[string]interface{}{{"id": "2"}, {"id": "1"}}
+	if !reflect.DeepEqual(recorder.keyMaps, expected) {
+		t.Fatalf("%s failed: GetItem should receive only key attributes, expected %#v but received %#v", name, expected, recorder.keyMaps)
+	}
+}
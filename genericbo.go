@@ -122,11 +122,20 @@ Sample usage:
 	year, err  := gbo.GboGetAttr("year", reddo.TypeString)    // year = "2019", because we want a string-result
 	month, err := gbo.GboGetAttr("month", reddo.TypeInt)      // month = 3, because we want an int-result and "3" is convertible to int
 	name, err := gbbo.GboGetAttr("name", nil)                 // name is nil because GboImportViaJson clear existing data upon importing
+
+Since v0.7.0, GenericBo can track changes: GboResetDirty takes a snapshot of the data and turns tracking on, see
+GboChangedPaths and GboDiff. Once tracking is on, loading data via GboFromJson, GboImportViaJson or GboImportViaMap
+retakes the snapshot. Tracking is off by default, so that BOs that do not use it do not pay for copying their data.
 */
 type GenericBo struct {
-	data interface{}
-	s    *semita.Semita
-	m    sync.RWMutex
+	data        interface{}
+	s           *semita.Semita
+	m           sync.RWMutex
+	snapshot    interface{} // deep copy of data taken by GboResetDirty
+	hasSnapshot bool        // change tracking is on, see GboResetDirty
+	strict      bool        // see GboSetStrict
+	useNumber   bool        // see GboSetUseNumber
+	shared      bool        // data is shared with FrozenGenericBo instances and must be copied before being modified
 }
 
 // Checksum returns checksum value of the BO.
@@ -146,12 +155,12 @@ func (bo *GenericBo) GboIterate(callback func(kind reflect.Kind, field interface
 	defer bo.m.RUnlock()
 	v := reflect.ValueOf(bo.data)
 	if v.Kind() == reflect.Map {
-		for iter := v.MapRange(); iter.Next(); callback(reflect.Map, iter.Key().Interface(), iter.Value().Interface()) {
+		for iter := v.MapRange(); iter.Next(); callback(reflect.Map, iter.Key().Interface(), bo.exposeValue(iter.Value().Interface())) {
 		}
 	}
 	if v.Kind() == reflect.Array || v.Kind() == reflect.Slice {
 		for i, n := 0, v.Len(); i < n; i++ {
-			callback(reflect.Slice, i, v.Index(i).Interface())
+			callback(reflect.Slice, i, bo.exposeValue(v.Index(i).Interface()))
 		}
 	}
 }
@@ -165,7 +174,20 @@ func (bo *GenericBo) GboGetAttr(path string, typ reflect.Type) (interface{}, err
 	if bo.s == nil {
 		return nil, nil
 	}
-	return bo.s.GetValueOfType(path, typ)
+	var v interface{}
+	var err error
+	if isQuotedPath(path) {
+		v, err = bo.getQuotedPath(path)
+	} else {
+		v, err = bo.s.GetValue(path)
+	}
+	if v == nil || err != nil {
+		return v, err
+	}
+	if v, err = bo.convertValue(v, typ); err != nil {
+		return nil, err
+	}
+	return bo.exposeValue(v), nil
 }
 
 // GboGetAttrUnsafe implements IGenericBo.GboGetAttrUnsafe.
@@ -205,6 +227,9 @@ func (bo *GenericBo) GboGetTimeWithLayout(path, layout string) (time.Time, error
 	if bo.s == nil {
 		return time.Time{}, nil
 	}
+	if isQuotedPath(path) {
+		return bo.getQuotedTime(path, layout)
+	}
 	return bo.s.GetTimeWithLayout(path, layout)
 }
 
@@ -214,6 +239,10 @@ func (bo *GenericBo) GboGetTimeWithLayout(path, layout string) (time.Time, error
 func (bo *GenericBo) GboSetAttr(path string, value interface{}) error {
 	bo.m.Lock()
 	defer bo.m.Unlock()
+	bo.copyOnWrite(path)
+	if isQuotedPath(path) {
+		return bo.setQuotedPath(path, value)
+	}
 	if bo.s == nil {
 		var data interface{}
 		if strings.HasPrefix(path, "[") {
@@ -243,19 +272,20 @@ func (bo *GenericBo) GboToJsonUnsafe() []byte {
 // GboFromJson implements IGenericBo.GboFromJson.
 //
 //   - If error occurs, existing BO data is intact.
-//   - If successful, existing data is replaced.
+//   - If successful, existing data is replaced, and the snapshot is retaken if change tracking is on (since v0.7.0).
+//   - Numbers are decoded to float64, or to json.Number if GboSetUseNumber(true) has been called (since v0.7.0).
 func (bo *GenericBo) GboFromJson(js []byte) error {
 	bo.m.Lock()
 	defer bo.m.Unlock()
 	var data interface{}
-	if err := json.Unmarshal(js, &data); err != nil {
+	if err := unmarshalJson(js, &data, bo.useNumber); err != nil {
 		return err
 	}
 	if data == nil {
 		data = map[string]interface{}{}
 	}
-	bo.data = data
-	bo.s = semita.NewSemita(bo.data)
+	bo.setData(data)
+	bo.refreshSnapshot()
 	return nil
 }
 
@@ -283,7 +313,7 @@ func (bo *GenericBo) GboImportViaJson(src interface{}) error {
 
 // GboImportViaMap implements IGenericBo.GboImportViaMap.
 //
-// Existing data is removed upon importing.
+// Existing data is removed upon importing, and the snapshot is retaken if change tracking is on (since v0.7.0).
 func (bo *GenericBo) GboImportViaMap(src map[string]interface{}) error {
 	bo.m.Lock()
 	defer bo.m.Unlock()
@@ -291,7 +321,7 @@ func (bo *GenericBo) GboImportViaMap(src map[string]interface{}) error {
 	for k, v := range src {
 		data[k] = v
 	}
-	bo.data = data
-	bo.s = semita.NewSemita(bo.data)
+	bo.setData(data)
+	bo.refreshSnapshot()
 	return nil
 }
@@ -0,0 +1,315 @@
+package godal
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/vmihailenco/msgpack/v5"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsonrw"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	// ErrBsonNotDocument is returned by the BSON codec (see NewBsonCodec) when the value to encode is not a document,
+	// e.g. BO data that is an array.
+	//
+	// Available since v0.7.0
+	ErrBsonNotDocument = errors.New("only documents (objects) can be encoded as BSON")
+)
+
+// IGboCodec serializes BO data to, and deserializes it from, a (binary) format other than JSON.
+//
+// When 'dest' is a *interface{}, Unmarshal must decode objects as map[string]interface{} and arrays as
+// []interface{} so that the result can be used as GenericBo data. Built-in codecs fall back to "json" struct tags
+// so that structs are (de)serialized the same way as with GboTransferViaJson/GboImportViaJson.
+//
+// Available since v0.7.0
+type IGboCodec interface {
+	// Name returns the codec's name, e.g. "msgpack".
+	Name() string
+
+	// Marshal serializes 'v' to bytes.
+	Marshal(v interface{}) ([]byte, error)
+
+	// Unmarshal deserializes 'data' to 'dest', which must be a pointer.
+	Unmarshal(data []byte, dest interface{}) error
+}
+
+// NewMsgpackCodec creates an IGboCodec that serializes BO data to MessagePack.
+//
+// time.Time values are preserved with nanosecond precision, []byte as binary data, integers are decoded as int64
+// (or uint64 if they do not fit). Map keys are sorted so that the output is deterministic.
+//
+// Available since v0.7.0
+func NewMsgpackCodec() IGboCodec {
+	return &msgpackCodec{}
+}
+
+type msgpackCodec struct{}
+
+// Name implements IGboCodec.Name.
+func (c *msgpackCodec) Name() string {
+	return "msgpack"
+}
+
+// Marshal implements IGboCodec.Marshal.
+func (c *msgpackCodec) Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := msgpack.NewEncoder(&buf)
+	enc.SetSortMapKeys(true)
+	enc.SetCustomStructTag("json")
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Unmarshal implements IGboCodec.Unmarshal.
+func (c *msgpackCodec) Unmarshal(data []byte, dest interface{}) error {
+	dec := msgpack.NewDecoder(bytes.NewReader(data))
+	dec.SetCustomStructTag("json")
+	if err := dec.Decode(dest); err != nil {
+		return err
+	}
+	if ptr, ok := dest.(*interface{}); ok {
+		*ptr = fromMsgpackValue(*ptr)
+	}
+	return nil
+}
+
+// fromMsgpackValue converts integers, which MessagePack decodes to the smallest fitting type, to int64 (or uint64 if
+// they do not fit).
+func fromMsgpackValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, e := range v {
+			v[k] = fromMsgpackValue(e)
+		}
+	case []interface{}:
+		for i, e := range v {
+			v[i] = fromMsgpackValue(e)
+		}
+	case int8:
+		return int64(v)
+	case int16:
+		return int64(v)
+	case int32:
+		return int64(v)
+	case uint8:
+		return int64(v)
+	case uint16:
+		return int64(v)
+	case uint32:
+		return int64(v)
+	case uint64:
+		if v <= math.MaxInt64 {
+			return int64(v)
+		}
+	}
+	return v
+}
+
+// NewCborCodec creates an IGboCodec that serializes BO data to CBOR (RFC 8949).
+//
+// time.Time values are preserved with nanosecond precision (tag 0, RFC 3339 strings), []byte as byte strings,
+// integers are decoded as int64 (or *big.Int if they do not fit). Map keys are sorted (core deterministic
+// encoding) so that the output is deterministic.
+//
+// Available since v0.7.0
+func NewCborCodec() IGboCodec {
+	em, _ := cbor.EncOptions{
+		Sort:    cbor.SortCoreDeterministic,
+		Time:    cbor.TimeRFC3339Nano,
+		TimeTag: cbor.EncTagRequired,
+	}.EncMode()
+	dm, _ := cbor.DecOptions{
+		DefaultMapType: reflect.TypeOf(map[string]interface{}{}),
+		IntDec:         cbor.IntDecConvertSignedOrBigInt,
+		BigIntDec:      cbor.BigIntDecodePointer,
+	}.DecMode()
+	return &cborCodec{em: em, dm: dm}
+}
+
+type cborCodec struct {
+	em cbor.EncMode
+	dm cbor.DecMode
+}
+
+// Name implements IGboCodec.Name.
+func (c *cborCodec) Name() string {
+	return "cbor"
+}
+
+// Marshal implements IGboCodec.Marshal.
+func (c *cborCodec) Marshal(v interface{}) ([]byte, error) {
+	return c.em.Marshal(v)
+}
+
+// Unmarshal implements IGboCodec.Unmarshal.
+func (c *cborCodec) Unmarshal(data []byte, dest interface{}) error {
+	return c.dm.Unmarshal(data, dest)
+}
+
+// NewBsonCodec creates an IGboCodec that serializes BO data to BSON.
+//
+// BSON documents must be objects, hence BOs whose data is an array can not be encoded (ErrBsonNotDocument). time.Time
+// values are preserved with millisecond precision (BSON datetime), []byte as binary data, integers are decoded as int64
+// (Go int values that fit are stored as BSON int32).
+//
+// Available since v0.7.0
+func NewBsonCodec() IGboCodec {
+	return &bsonCodec{}
+}
+
+type bsonCodec struct{}
+
+// Name implements IGboCodec.Name.
+func (c *bsonCodec) Name() string {
+	return "bson"
+}
+
+// Marshal implements IGboCodec.Marshal.
+func (c *bsonCodec) Marshal(v interface{}) ([]byte, error) {
+	if rv := reflect.Indirect(reflect.ValueOf(v)); (rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array) && rv.Type().Elem().Kind() != reflect.Uint8 {
+		return nil, ErrBsonNotDocument
+	}
+	var buf bytes.Buffer
+	vw, err := bsonrw.NewBSONValueWriter(&buf)
+	if err != nil {
+		return nil, err
+	}
+	enc, err := bson.NewEncoder(vw)
+	if err != nil {
+		return nil, err
+	}
+	enc.UseJSONStructTags()
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Unmarshal implements IGboCodec.Unmarshal.
+func (c *bsonCodec) Unmarshal(data []byte, dest interface{}) error {
+	dec, err := bson.NewDecoder(bsonrw.NewBSONDocumentReader(data))
+	if err != nil {
+		return err
+	}
+	dec.UseJSONStructTags()
+	ptr, ok := dest.(*interface{})
+	if !ok {
+		return dec.Decode(dest)
+	}
+	var doc bson.D
+	if err := dec.Decode(&doc); err != nil {
+		return err
+	}
+	*ptr = fromBsonValue(doc)
+	return nil
+}
+
+// fromBsonValue converts BSON-specific types to generic ones: documents to map[string]interface{}, arrays to
+// []interface{}, int32 to int64, datetimes to time.Time and generic binary data to []byte.
+func fromBsonValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case bson.D:
+		result := make(map[string]interface{}, len(v))
+		for _, e := range v {
+			result[e.Key] = fromBsonValue(e.Value)
+		}
+		return result
+	case bson.M:
+		result := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			result[k] = fromBsonValue(e)
+		}
+		return result
+	case bson.A:
+		result := make([]interface{}, len(v))
+		for i, e := range v {
+			result[i] = fromBsonValue(e)
+		}
+		return result
+	case int32:
+		return int64(v)
+	case primitive.DateTime:
+		return v.Time()
+	case primitive.Binary:
+		if v.Subtype == bson.TypeBinaryGeneric || v.Subtype == bson.TypeBinaryBinaryOld {
+			return v.Data
+		}
+	}
+	return v
+}
+
+/*----------------------------------------------------------------------*/
+
+// GboEncode serializes BO data using the specified codec.
+//
+// Available since v0.7.0
+func (bo *GenericBo) GboEncode(codec IGboCodec) ([]byte, error) {
+	bo.m.RLock()
+	defer bo.m.RUnlock()
+	data, err := codec.Marshal(bo.data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", codec.Name(), err)
+	}
+	return data, nil
+}
+
+// GboDecode imports BO data serialized by the specified codec (see GboEncode).
+//
+//   - If error occurs, existing BO data is intact.
+//   - If successful, existing data is replaced, and the snapshot is retaken if change tracking is on.
+//
+// Available since v0.7.0
+func (bo *GenericBo) GboDecode(codec IGboCodec, data []byte) error {
+	var v interface{}
+	if err := codec.Unmarshal(data, &v); err != nil {
+		return fmt.Errorf("%s: %w", codec.Name(), err)
+	}
+	if v == nil {
+		v = map[string]interface{}{}
+	}
+	bo.m.Lock()
+	defer bo.m.Unlock()
+	bo.setData(v)
+	bo.refreshSnapshot()
+	return nil
+}
+
+// GboTransferViaCodec copies BO data to the destination using the specified codec: BO data is serialized and
+// then deserialized to 'dest'. This is the codec-based equivalent of GboTransferViaJson.
+//
+// Passing by value won't work, so 'dest' must be a pointer.
+//
+// Available since v0.7.0
+func (bo *GenericBo) GboTransferViaCodec(codec IGboCodec, dest interface{}) error {
+	data, err := bo.GboEncode(codec)
+	if err != nil {
+		return err
+	}
+	if err := codec.Unmarshal(data, dest); err != nil {
+		return fmt.Errorf("%s: %w", codec.Name(), err)
+	}
+	return nil
+}
+
+// GboImportViaCodec imports BO data from an external source using the specified codec: 'src' is serialized and
+// then deserialized to BO's attributes. This is the codec-based equivalent of GboImportViaJson.
+//
+// Existing data is removed upon importing.
+//
+// Available since v0.7.0
+func (bo *GenericBo) GboImportViaCodec(codec IGboCodec, src interface{}) error {
+	data, err := codec.Marshal(src)
+	if err != nil {
+		return fmt.Errorf("%s: %w", codec.Name(), err)
+	}
+	return bo.GboDecode(codec, data)
+}
@@ -0,0 +1,146 @@
+package godal
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGenericBo_GboEncodeDecode(t *testing.T) {
+	name := "TestGenericBo_GboEncodeDecode"
+	now := time.Now()
+	for _, codec := range []IGboCodec{NewMsgpackCodec(), NewCborCodec(), NewBsonCodec()} {
+		precision := time.Nanosecond
+		if codec.Name() == "bson" {
+			precision = time.Millisecond
+		}
+		bo := NewGenericBo().(*GenericBo)
+		bo.GboImportViaMap(map[string]interface{}{
+			"id":      int64(1)<<60 + 1,
+			"created": now.Truncate(precision),
+			"avatar":  []byte{0, 1, 2, 255},
+			"rate":    1.5,
+			"tags":    []string{"a", "b"},
+			"owner":   map[string]interface{}{"name": "Thanh", "age": 30, "deleted": nil},
+		})
+		data, err := bo.GboEncode(codec)
+		if err != nil {
+			t.Fatalf("%s failed: %s: %s", name, codec.Name(), err)
+		}
+		bo2 := NewGenericBo().(*GenericBo)
+		bo2.GboResetDirty()
+		if err := bo2.GboDecode(codec, data); err != nil {
+			t.Fatalf("%s failed: %s: %s", name, codec.Name(), err)
+		}
+		if !bo.GboEquals(bo2) {
+			t.Fatalf("%s failed: %s: %s", name, codec.Name(), bo.GboDiff(bo2))
+		}
+		if v := bo2.GboGetAttrUnsafe("id", nil); v != int64(1)<<60+1 {
+			t.Fatalf("%s failed: %s: expected int64 value but received %#v", name, codec.Name(), v)
+		}
+		if v := bo2.GboGetAttrUnsafe("owner.age", nil); v != int64(30) {
+			t.Fatalf("%s failed: %s: expected int64 value but received %#v", name, codec.Name(), v)
+		}
+		if v, ok := bo2.GboGetAttrUnsafe("avatar", nil).([]byte); !ok || !bytes.Equal(v, []byte{0, 1, 2, 255}) {
+			t.Fatalf("%s failed: %s: expected []byte value but received %#v", name, codec.Name(), v)
+		}
+		if v, ok := bo2.GboGetAttrUnsafe("created", nil).(time.Time); !ok || !v.Equal(now.Truncate(precision)) {
+			t.Fatalf("%s failed: %s: expected time.Time value but received %#v", name, codec.Name(), v)
+		}
+		if _, ok := bo2.GboGetAttrUnsafe("owner", nil).(map[string]interface{}); !ok {
+			t.Fatalf("%s failed: %s: expected map[string]interface{} value", name, codec.Name())
+		}
+		if _, ok := bo2.GboGetAttrUnsafe("tags", nil).([]interface{}); !ok {
+			t.Fatalf("%s failed: %s: expected []interface{} value", name, codec.Name())
+		}
+		if paths := bo2.GboChangedPaths(); len(paths) != 0 {
+			t.Fatalf("%s failed: %s: decoded BO with change tracking on should not be dirty: %#v", name, codec.Name(), paths)
+		}
+
+		// encoding is deterministic
+		for i := 0; i < 10 && codec.Name() != "bson"; i++ {
+			if data2, _ := bo.GboEncode(codec); !bytes.Equal(data, data2) {
+				t.Fatalf("%s failed: %s: encoding is not deterministic", name, codec.Name())
+			}
+		}
+
+		// error leaves the BO intact
+		if err := bo2.GboDecode(codec, data[:len(data)/2]); err == nil || !strings.HasPrefix(err.Error(), codec.Name()+": ") {
+			t.Fatalf("%s failed: %s: expected error but received %v", name, codec.Name(), err)
+		}
+		if !bo.GboEquals(bo2) {
+			t.Fatalf("%s failed: %s: BO should be intact", name, codec.Name())
+		}
+	}
+
+	// JSON is lossy
+	bo := NewGenericBo().(*GenericBo)
+	bo.GboImportViaMap(map[string]interface{}{"id": int64(1)<<60 + 1})
+	bo2 := NewGenericBo().(*GenericBo)
+	bo2.GboFromJson(bo.GboToJsonUnsafe())
+	if bo.GboEquals(bo2) {
+		t.Fatalf("%s failed: JSON round-trip should lose int64 precision", name)
+	}
+}
+
+func TestGenericBo_GboEncode_Array(t *testing.T) {
+	name := "TestGenericBo_GboEncode_Array"
+	for _, codec := range []IGboCodec{NewMsgpackCodec(), NewCborCodec(), NewBsonCodec()} {
+		bo := NewGenericBo().(*GenericBo)
+		bo.GboFromJson([]byte(`["a"]`))
+		data, err := bo.GboEncode(codec)
+		if codec.Name() == "bson" {
+			if !errors.Is(err, ErrBsonNotDocument) {
+				t.Fatalf("%s failed: %s: expected ErrBsonNotDocument but received %v", name, codec.Name(), err)
+			}
+			if _, err := codec.Marshal(&[]string{"a"}); !errors.Is(err, ErrBsonNotDocument) {
+				t.Fatalf("%s failed: %s: expected ErrBsonNotDocument but received %v", name, codec.Name(), err)
+			}
+			continue
+		}
+		bo2 := NewGenericBo().(*GenericBo)
+		if err != nil || bo2.GboDecode(codec, data) != nil || !reflect.DeepEqual(bo2.data, []interface{}{"a"}) {
+			t.Fatalf("%s failed: %s: %s / %#v", name, codec.Name(), err, bo2.data)
+		}
+	}
+}
+
+type codecTestUser struct {
+	Id      int64             `json:"id"`
+	Name    string            `json:"name"`
+	Created time.Time         `json:"created"`
+	Avatar  []byte            `json:"avatar"`
+	Labels  map[string]string `json:"labels"`
+	Count   int               `json:"count"`
+}
+
+func TestGenericBo_GboTransferViaCodec(t *testing.T) {
+	name := "TestGenericBo_GboTransferViaCodec"
+	now := time.Now().Truncate(time.Millisecond)
+	user := codecTestUser{Id: int64(1)<<60 + 1, Name: "Thanh", Created: now, Avatar: []byte{1, 2}, Labels: map[string]string{"k": "v"}, Count: 7}
+	for _, codec := range []IGboCodec{NewMsgpackCodec(), NewCborCodec(), NewBsonCodec()} {
+		bo := NewGenericBo().(*GenericBo)
+		if err := bo.GboImportViaCodec(codec, user); err != nil {
+			t.Fatalf("%s failed: %s: %s", name, codec.Name(), err)
+		}
+		if v := bo.GboGetAttrUnsafe("id", nil); v != user.Id {
+			t.Fatalf("%s failed: %s: expected %#v but received %#v", name, codec.Name(), user.Id, v)
+		}
+		if v := bo.GboGetAttrUnsafe("count", nil); v != int64(7) {
+			t.Fatalf("%s failed: %s: expected %#v but received %#v", name, codec.Name(), int64(7), v)
+		}
+		if v := bo.GboGetAttrUnsafe("labels.k", nil); v != "v" {
+			t.Fatalf("%s failed: %s: expected %#v but received %#v", name, codec.Name(), "v", v)
+		}
+		var user2 codecTestUser
+		if err := bo.GboTransferViaCodec(codec, &user2); err != nil {
+			t.Fatalf("%s failed: %s: %s", name, codec.Name(), err)
+		}
+		if user2.Id != user.Id || user2.Name != user.Name || !user2.Created.Equal(now) || !bytes.Equal(user2.Avatar, user.Avatar) || !reflect.DeepEqual(user2.Labels, user.Labels) || user2.Count != user.Count {
+			t.Fatalf("%s failed: %s: expected %#v but received %#v", name, codec.Name(), user, user2)
+		}
+	}
+}
@@ -0,0 +1,260 @@
+package godal
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"reflect"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+var (
+	// TypeDecimal is the type of decimal.Decimal values (github.com/shopspring/decimal). Passing it to GboGetAttr
+	// converts the value to an arbitrary-precision decimal, see GboGetDecimal.
+	//
+	// Available since v0.7.0
+	TypeDecimal = reflect.TypeOf(decimal.Decimal{})
+
+	// TypeBigInt is the type of *big.Int values. Passing it to GboGetAttr converts the value to an arbitrary-precision
+	// integer, see GboGetBigInt.
+	//
+	// Available since v0.7.0
+	TypeBigInt = reflect.TypeOf((*big.Int)(nil))
+)
+
+// GboSetUseNumber turns json.Number decoding on or off.
+//
+// If on, GboFromJson (and GboImportViaJson) decodes numbers to json.Number instead of float64, so that big integers
+// and decimals (e.g. money amounts) keep their exact values. json.Number values are converted by GboGetAttr as usual,
+// written as-is to JSON, and passed to the databases as exact values by the row mappers.
+//
+// Available since v0.7.0
+func (bo *GenericBo) GboSetUseNumber(useNumber bool) *GenericBo {
+	bo.m.Lock()
+	defer bo.m.Unlock()
+	bo.useNumber = useNumber
+	return bo
+}
+
+// GboIsUseNumber returns true if json.Number decoding is on, see GboSetUseNumber.
+//
+// Available since v0.7.0
+func (bo *GenericBo) GboIsUseNumber() bool {
+	bo.m.RLock()
+	defer bo.m.RUnlock()
+	return bo.useNumber
+}
+
+// GboGetDecimal returns the value at 'path' converted to decimal.Decimal, or the zero decimal if the value does not
+// exist. Numbers, numeric strings, json.Number, *big.Int, *big.Float and MongoDB's decimals (e.g. {"$numberDecimal":
+// "1.10"}) are accepted. Floats are converted to the shortest decimal that represents them (e.g. 0.1 to "0.1").
+//
+// Available since v0.7.0
+func (bo *GenericBo) GboGetDecimal(path string) (decimal.Decimal, error) {
+	return getTyped[decimal.Decimal](bo, path, TypeDecimal)
+}
+
+// GboGetDecimalDefault is like GboGetDecimal, but returns 'def' if the value does not exist or can not be converted.
+//
+// Available since v0.7.0
+func (bo *GenericBo) GboGetDecimalDefault(path string, def decimal.Decimal) decimal.Decimal {
+	return getTypedDefault(bo, path, TypeDecimal, def)
+}
+
+// GboGetBigInt returns the value at 'path' converted to *big.Int, or nil if the value does not exist. Fractions are
+// truncated, unless strict mode is on (see GboSetStrict).
+//
+// Available since v0.7.0
+func (bo *GenericBo) GboGetBigInt(path string) (*big.Int, error) {
+	return getTyped[*big.Int](bo, path, TypeBigInt)
+}
+
+// GboGetBigIntDefault is like GboGetBigInt, but returns 'def' if the value does not exist or can not be converted.
+//
+// Available since v0.7.0
+func (bo *GenericBo) GboGetBigIntDefault(path string, def *big.Int) *big.Int {
+	return getTypedDefault(bo, path, TypeBigInt, def)
+}
+
+/*----------------------------------------------------------------------*/
+
+// ExactNumberString returns the exact decimal representation of 'v' if it is an arbitrary-precision number:
+// json.Number, decimal.Decimal, *big.Int, *big.Float or a MongoDB decimal in Extended JSON ({"$numberDecimal": "..."}).
+// Row mappers use it to pass such values to the databases without going through float64.
+//
+// Available since v0.7.0
+func ExactNumberString(v interface{}) (string, bool) {
+	switch t := v.(type) {
+	case json.Number:
+		return t.String(), true
+	case decimal.Decimal:
+		return t.String(), true
+	case *decimal.Decimal:
+		if t != nil {
+			return t.String(), true
+		}
+	case big.Int:
+		return t.String(), true
+	case *big.Int:
+		if t != nil {
+			return t.String(), true
+		}
+	case *big.Float:
+		if t != nil && !t.IsInf() {
+			return t.Text('g', -1), true
+		}
+	case map[string]interface{}:
+		if s, ok := t["$numberDecimal"].(string); ok && len(t) == 1 {
+			return s, true
+		}
+	}
+	return "", false
+}
+
+// ReplaceExactNumbers walks the BO's data and 'row', the BO converted to a map via JSON (see GboTransferViaJson), in
+// parallel: each arbitrary-precision number of the BO (see ExactNumberString) is replaced in 'row' (in place) by the
+// result of 'convert'. Values inside structs are not replaced.
+//
+// Row mappers use it to pass exact values to the databases, e.g. MongoDB's Decimal128.
+//
+// Available since v0.7.0
+func ReplaceExactNumbers(bo IGenericBo, row interface{}, convert func(value interface{}, number string) (interface{}, error)) error {
+	_, err := replaceExactNumbers(gboData(bo, false), row, convert)
+	return err
+}
+
+// replaceExactNumbers implements ReplaceExactNumbers, returning the (possibly replaced) 'row'.
+func replaceExactNumbers(data, row interface{}, convert func(value interface{}, number string) (interface{}, error)) (interface{}, error) {
+	if number, ok := ExactNumberString(data); ok {
+		return convert(data, number)
+	}
+	rv := reflect.ValueOf(data)
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		rv = rv.Elem()
+	}
+	switch rv.Kind() {
+	case reflect.Map:
+		obj, ok := row.(map[string]interface{})
+		if !ok || rv.Type().Key().Kind() != reflect.String {
+			break
+		}
+		for iter := rv.MapRange(); iter.Next(); {
+			k := iter.Key().String()
+			if e, exists := obj[k]; exists {
+				v, err := replaceExactNumbers(iter.Value().Interface(), e, convert)
+				if err != nil {
+					return row, fmt.Errorf("%s: %w", k, err)
+				}
+				obj[k] = v
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		arr, ok := row.([]interface{})
+		if !ok || len(arr) != rv.Len() {
+			break
+		}
+		for i := range arr {
+			v, err := replaceExactNumbers(rv.Index(i).Interface(), arr[i], convert)
+			if err != nil {
+				return row, fmt.Errorf("[%d]: %w", i, err)
+			}
+			arr[i] = v
+		}
+	}
+	return row, nil
+}
+
+// unmarshalJson is json.Unmarshal, decoding numbers to json.Number if 'useNumber' is true.
+func unmarshalJson(js []byte, v interface{}, useNumber bool) error {
+	if !useNumber {
+		return json.Unmarshal(js, v)
+	}
+	dec := json.NewDecoder(bytes.NewReader(js))
+	dec.UseNumber()
+	if err := dec.Decode(v); err != nil {
+		return err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return errors.New("invalid character after top-level value")
+	}
+	return nil
+}
+
+// isExactNumber returns true if 'v' is an arbitrary-precision number, see ExactNumberString.
+func isExactNumber(v interface{}) bool {
+	_, ok := ExactNumberString(v)
+	return ok
+}
+
+// exactNumbersEqual compares two numbers, at least one of them being an arbitrary-precision number, by value.
+func exactNumbersEqual(a, b interface{}) (equal, ok bool) {
+	if _, _, _, _, isNumber := toNumber(a); !isNumber && !isExactNumber(a) {
+		return false, false
+	}
+	if _, _, _, _, isNumber := toNumber(b); !isNumber && !isExactNumber(b) {
+		return false, false
+	}
+	da, errA := toDecimal(a)
+	db, errB := toDecimal(b)
+	if errA != nil || errB != nil {
+		return false, false
+	}
+	return da.Equal(db), true
+}
+
+// convertExactNumber implements GboGetAttr for TypeDecimal and TypeBigInt.
+func (bo *GenericBo) convertExactNumber(v interface{}, typ reflect.Type) (interface{}, error) {
+	if _, ok := v.(bool); ok && bo.strict {
+		return nil, fmt.Errorf("strict mode: converting %T(%v) to %s loses information", v, v, typ)
+	}
+	d, err := toDecimal(v)
+	if err != nil || typ == TypeDecimal {
+		return d, err
+	}
+	if bo.strict && !d.Equal(d.Truncate(0)) {
+		return nil, fmt.Errorf("strict mode: converting %T(%v) to %s loses information", v, v, typ)
+	}
+	return d.BigInt(), nil
+}
+
+// toDecimal converts 'v' to decimal.Decimal, see GboGetDecimal.
+func toDecimal(v interface{}) (decimal.Decimal, error) {
+	if number, ok := ExactNumberString(v); ok {
+		return decimal.NewFromString(number)
+	}
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	switch rv.Kind() {
+	case reflect.Bool:
+		if rv.Bool() {
+			return decimal.NewFromInt(1), nil
+		}
+		return decimal.Zero, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return decimal.NewFromInt(rv.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return decimal.NewFromBigInt(new(big.Int).SetUint64(rv.Uint()), 0), nil
+	case reflect.Float32, reflect.Float64:
+		if f := rv.Float(); !math.IsNaN(f) && !math.IsInf(f, 0) {
+			if rv.Kind() == reflect.Float32 {
+				return decimal.NewFromFloat32(float32(f)), nil
+			}
+			return decimal.NewFromFloat(f), nil
+		}
+	case reflect.String:
+		return decimal.NewFromString(strings.TrimSpace(rv.String()))
+	}
+	if s, ok := v.(fmt.Stringer); ok && rv.Kind() != reflect.Ptr {
+		// e.g. MongoDB's primitive.Decimal128
+		return decimal.NewFromString(s.String())
+	}
+	return decimal.Zero, fmt.Errorf("cannot convert %T(%v) to decimal", v, v)
+}
@@ -0,0 +1,171 @@
+package godal
+
+import (
+	"encoding/json"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/btnguyen2k/consu/reddo"
+	"github.com/shopspring/decimal"
+)
+
+const decimalTestDoc = `{"price":19.99,"big":12345678901234567890123,"qty":3,"amount":"0.10","rate":1.5,"flag":true,"name":"abc"}`
+
+func TestGenericBo_GboSetUseNumber(t *testing.T) {
+	name := "TestGenericBo_GboSetUseNumber"
+	bo := NewGenericBo().(*GenericBo)
+	if bo.GboIsUseNumber() {
+		t.Fatalf("%s failed: json.Number decoding should be off by default", name)
+	}
+	if err := bo.GboSetUseNumber(true).GboFromJson([]byte(decimalTestDoc)); err != nil {
+		t.Fatalf("%s failed: %s", name, err)
+	}
+	if v := bo.GboGetAttrUnsafe("big", nil); v != json.Number("12345678901234567890123") {
+		t.Fatalf("%s failed: %#v", name, v)
+	}
+	if v, err := bo.GboGetInt64("qty"); err != nil || v != 3 {
+		t.Fatalf("%s failed: %#v / %s", name, v, err)
+	}
+	if v, err := bo.GboGetFloat64("price"); err != nil || v != 19.99 {
+		t.Fatalf("%s failed: %#v / %s", name, v, err)
+	}
+	if js := string(bo.GboToJsonUnsafe()); js != `{"amount":"0.10","big":12345678901234567890123,"flag":true,"name":"abc","price":19.99,"qty":3,"rate":1.5}` {
+		t.Fatalf("%s failed: %s", name, js)
+	}
+	if clone := bo.GboClone().(*GenericBo); !clone.GboIsUseNumber() {
+		t.Fatalf("%s failed: json.Number decoding should be cloned", name)
+	}
+	if thawed := bo.GboFreeze().GboThaw(); !thawed.GboIsUseNumber() {
+		t.Fatalf("%s failed: json.Number decoding should be kept", name)
+	}
+
+	// numbers are compared by value, float64 can not hold "big"
+	other := NewGenericBo()
+	other.GboFromJson([]byte(decimalTestDoc))
+	if paths := bo.GboDiff(other).Paths(); !reflect.DeepEqual(paths, []string{"big"}) {
+		t.Fatalf("%s failed: %#v", name, paths)
+	}
+	other.GboSetAttr("big", decimal.RequireFromString("12345678901234567890123"))
+	if !bo.GboEquals(other) {
+		t.Fatalf("%s failed: %s should equal %s", name, bo.GboToJsonUnsafe(), other.GboToJsonUnsafe())
+	}
+
+	for _, js := range []string{`{"a":1} x`, `{"a":1}{}`, `{"a":`} {
+		if err := bo.GboFromJson([]byte(js)); err == nil {
+			t.Fatalf("%s failed: %q should be rejected", name, js)
+		}
+	}
+	if v := bo.GboGetAttrUnsafe("qty", nil); v != json.Number("3") {
+		t.Fatalf("%s failed: existing data should be intact, received %#v", name, v)
+	}
+}
+
+func TestGenericBo_GboGetDecimal(t *testing.T) {
+	name := "TestGenericBo_GboGetDecimal"
+	bo := NewGenericBo().(*GenericBo)
+	bo.GboSetUseNumber(true).GboFromJson([]byte(decimalTestDoc))
+	bo.GboSetAttr("dec", decimal.RequireFromString("1.23"))
+	bo.GboSetAttr("bigint", new(big.Int).Lsh(big.NewInt(1), 100))
+	bo.GboSetAttr("mongo", map[string]interface{}{"$numberDecimal": "9.99"})
+	bo.GboSetAttr("float", 0.1)
+	testCases := []struct {
+		path     string
+		expected string
+	}{
+		{"price", "19.99"},
+		{"big", "12345678901234567890123"},
+		{"amount", "0.1"},
+		{"qty", "3"},
+		{"dec", "1.23"},
+		{"bigint", "1267650600228229401496703205376"},
+		{"mongo", "9.99"},
+		{"float", "0.1"},
+		{"flag", "1"},
+	}
+	for _, tc := range testCases {
+		if v, err := bo.GboGetDecimal(tc.path); err != nil || v.String() != tc.expected {
+			t.Fatalf("%s failed: path %q, expected %s but received %s / %s", name, tc.path, tc.expected, v, err)
+		}
+	}
+	if _, err := bo.GboGetDecimal("name"); err == nil {
+		t.Fatalf("%s failed: %q should not be converted to decimal", name, "abc")
+	}
+	if v, err := bo.GboGetDecimal("notfound"); err != nil || !v.IsZero() {
+		t.Fatalf("%s failed: %s / %s", name, v, err)
+	}
+	def := decimal.NewFromInt(-1)
+	if v := bo.GboGetDecimalDefault("name", def); !v.Equal(def) {
+		t.Fatalf("%s failed: %s", name, v)
+	}
+	if v, err := bo.GboGetAttr("price", TypeDecimal); err != nil || v.(decimal.Decimal).String() != "19.99" {
+		t.Fatalf("%s failed: %#v / %s", name, v, err)
+	}
+
+	if v, err := bo.GboGetBigInt("big"); err != nil || v.String() != "12345678901234567890123" {
+		t.Fatalf("%s failed: %s / %s", name, v, err)
+	}
+	if v, err := bo.GboGetBigInt("rate"); err != nil || v.Int64() != 1 {
+		t.Fatalf("%s failed: non-strict mode should truncate, received %s / %s", name, v, err)
+	}
+	if v, err := bo.GboGetBigInt("notfound"); err != nil || v != nil {
+		t.Fatalf("%s failed: %s / %s", name, v, err)
+	}
+	if v := bo.GboGetBigIntDefault("name", big.NewInt(-1)); v.Int64() != -1 {
+		t.Fatalf("%s failed: %s", name, v)
+	}
+
+	bo.GboSetStrict(true)
+	for _, tc := range []struct {
+		path string
+		typ  reflect.Type
+	}{{"rate", TypeBigInt}, {"flag", TypeDecimal}, {"flag", TypeBigInt}} {
+		if v, err := bo.GboGetAttr(tc.path, tc.typ); err == nil {
+			t.Fatalf("%s failed: %s to %s should fail in strict mode, received %#v", name, tc.path, tc.typ, v)
+		}
+	}
+	if v, err := bo.GboGetAttr("big", reddo.TypeString); err != nil || v != "12345678901234567890123" {
+		t.Fatalf("%s failed: %#v / %s", name, v, err)
+	}
+}
+
+func TestExactNumberString(t *testing.T) {
+	name := "TestExactNumberString"
+	testCases := []struct {
+		value    interface{}
+		expected string
+		ok       bool
+	}{
+		{json.Number("1.10"), "1.10", true},
+		{decimal.RequireFromString("-0.5"), "-0.5", true},
+		{big.NewInt(42), "42", true},
+		{big.NewFloat(2.5), "2.5", true},
+		{map[string]interface{}{"$numberDecimal": "3.3"}, "3.3", true},
+		{(*big.Int)(nil), "", false},
+		{(*decimal.Decimal)(nil), "", false},
+		{1.5, "", false},
+		{"1.5", "", false},
+		{map[string]interface{}{"$numberDecimal": "3.3", "x": 1}, "", false},
+	}
+	for _, tc := range testCases {
+		if s, ok := ExactNumberString(tc.value); s != tc.expected || ok != tc.ok {
+			t.Fatalf("%s failed: %#v, expected %q/%v but received %q/%v", name, tc.value, tc.expected, tc.ok, s, ok)
+		}
+	}
+}
+
+func TestReplaceExactNumbers(t *testing.T) {
+	name := "TestReplaceExactNumbers"
+	bo := NewGenericBo().(*GenericBo)
+	bo.GboSetUseNumber(true).GboFromJson([]byte(`{"a":1.10,"b":{"c":[2,"x",{"d":3}]},"e":"s"}`))
+	bo.GboSetAttr("f", decimal.RequireFromString("4.5"))
+	row := make(map[string]interface{})
+	bo.GboTransferViaJson(&row)
+	err := ReplaceExactNumbers(bo, row, func(value interface{}, number string) (interface{}, error) {
+		return "#" + number, nil
+	})
+	expected := map[string]interface{}{"a": "#1.10", "b": map[string]interface{}{"c": []interface{}{"#2", "x", map[string]interface{}{"d": "#3"}}}, "e": "s", "f": "#4.5"}
+	if err != nil || !reflect.DeepEqual(row, expected) {
+		t.Fatalf("%s failed: expected %#v but received %#v / %s", name, expected, row, err)
+	}
+}
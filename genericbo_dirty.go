@@ -0,0 +1,532 @@
+package godal
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// IDirtyTrackingBo is implemented by BOs that track changes made since they were loaded (e.g. GenericBo).
+//
+// DAOs can use it to write only changed fields, see ChangedFieldsBo.
+//
+// Available since v0.7.0
+type IDirtyTrackingBo interface {
+	IGenericBo
+
+	// GboChangedPaths returns paths of attributes that have been added, removed or changed since the BO was loaded
+	// (or since the last call to GboResetDirty), sorted in ascending order.
+	GboChangedPaths() []string
+
+	// GboChanges returns changes made since the BO was loaded (or since the last call to GboResetDirty).
+	GboChanges() *GboDiffResult
+
+	// GboDiff compares the BO with another one, returning changes needed to turn this BO into 'other'.
+	GboDiff(other IGenericBo) *GboDiffResult
+
+	// GboResetDirty takes a snapshot of the BO's current data, marking the BO as "not modified". It also turns change
+	// tracking on if it is not yet.
+	GboResetDirty()
+}
+
+// GboChange describes a change of a BO's attribute.
+//
+// Available since v0.7.0
+type GboChange struct {
+	Path     string      // path of the attribute, e.g. "options.workhour[0].value"
+	OldValue interface{} // value before the change, nil if the attribute is added
+	NewValue interface{} // value after the change, nil if the attribute is removed
+}
+
+// GboDiffResult is the result of comparing two BOs.
+//
+// Changes are reported at the deepest path where the two BOs differ: an added (or removed) object or array is
+// reported as one change at its own path, not one change per leaf.
+//
+// Available since v0.7.0
+type GboDiffResult struct {
+	Added   []GboChange // attributes that exist in the new BO only
+	Removed []GboChange // attributes that exist in the old BO only
+	Changed []GboChange // attributes whose value differs
+}
+
+// IsEmpty returns true if there is no change.
+func (d *GboDiffResult) IsEmpty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// Paths returns paths of all changes, sorted in ascending order.
+func (d *GboDiffResult) Paths() []string {
+	result := make([]string, 0, len(d.Added)+len(d.Removed)+len(d.Changed))
+	for _, changes := range [][]GboChange{d.Added, d.Removed, d.Changed} {
+		for _, c := range changes {
+			result = append(result, c.Path)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// String returns a human-readable change log, one change per line: "+ path: new-value" for added attributes,
+// "- path: old-value" for removed attributes and "~ path: old-value -> new-value" for changed attributes.
+func (d *GboDiffResult) String() string {
+	lines := make([]string, 0, len(d.Added)+len(d.Removed)+len(d.Changed))
+	for _, c := range d.Added {
+		lines = append(lines, fmt.Sprintf("+ %s: %v", c.Path, c.NewValue))
+	}
+	for _, c := range d.Removed {
+		lines = append(lines, fmt.Sprintf("- %s: %v", c.Path, c.OldValue))
+	}
+	for _, c := range d.Changed {
+		lines = append(lines, fmt.Sprintf("~ %s: %v -> %v", c.Path, c.OldValue, c.NewValue))
+	}
+	return strings.Join(lines, "\n")
+}
+
+/*----------------------------------------------------------------------*/
+
+// GboChangedPaths implements IDirtyTrackingBo.GboChangedPaths.
+//
+// If change tracking is off (GboResetDirty has never been called), all of the BO's attributes are considered added.
+//
+// Available since v0.7.0
+func (bo *GenericBo) GboChangedPaths() []string {
+	return bo.GboChanges().Paths()
+}
+
+// GboChanges implements IDirtyTrackingBo.GboChanges.
+//
+// Available since v0.7.0
+func (bo *GenericBo) GboChanges() *GboDiffResult {
+	bo.m.RLock()
+	defer bo.m.RUnlock()
+	old := bo.snapshot
+	if !bo.hasSnapshot {
+		old = emptyContainerOf(bo.data)
+	}
+	return diffValues(old, bo.data)
+}
+
+// GboDiff implements IDirtyTrackingBo.GboDiff.
+//
+// Numbers are compared by value regardless of their Go types (e.g. int64(1) equals float64(1.0)).
+//
+// Available since v0.7.0
+func (bo *GenericBo) GboDiff(other IGenericBo) *GboDiffResult {
+	if other == IGenericBo(bo) {
+		return &GboDiffResult{}
+	}
+	otherData := gboData(other, false)
+	bo.m.RLock()
+	defer bo.m.RUnlock()
+	return diffValues(bo.data, otherData)
+}
+
+// GboResetDirty implements IDirtyTrackingBo.GboResetDirty.
+//
+// Available since v0.7.0
+func (bo *GenericBo) GboResetDirty() {
+	bo.m.Lock()
+	defer bo.m.Unlock()
+	bo.takeSnapshot()
+}
+
+// takeSnapshot must be called with the write-lock held.
+func (bo *GenericBo) takeSnapshot() {
+	bo.snapshot = deepCopyValue(bo.data)
+	bo.hasSnapshot = true
+}
+
+// refreshSnapshot retakes the snapshot after the BO's data has been replaced, if change tracking is on. It must be
+// called with the write-lock held.
+func (bo *GenericBo) refreshSnapshot() {
+	if bo.hasSnapshot {
+		bo.takeSnapshot()
+	}
+}
+
+// ChangedFieldsBo returns a new BO that contains only top-level fields of 'bo' that have been added or changed since
+// it was loaded. It returns nil if 'bo' does not track changes (see IDirtyTrackingBo) or if no field has been added or
+// changed.
+//
+// DAOs use it to write only changed fields, e.g. the row mapper transforms the returned BO instead of the original one
+// to build a minimal UPDATE statement.
+//
+// Available since v0.7.0
+func ChangedFieldsBo(bo IGenericBo) IGenericBo {
+	tracker, ok := bo.(IDirtyTrackingBo)
+	if !ok {
+		return nil
+	}
+	changes := tracker.GboChanges()
+	fields := make(map[string]bool)
+	for _, list := range [][]GboChange{changes.Added, changes.Changed} {
+		for _, c := range list {
+			if c.Path == "" {
+				return nil
+			}
+			fields[topLevelField(c.Path)] = true
+		}
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+	data := make(map[string]interface{})
+	bo.GboIterate(func(kind reflect.Kind, field interface{}, value interface{}) {
+		if name := fmt.Sprint(field); kind == reflect.Map && fields[name] {
+			data[name] = value
+		}
+	})
+	result := NewGenericBo()
+	result.GboImportViaMap(data)
+	return result
+}
+
+// topLevelField returns the first segment of a path.
+func topLevelField(path string) string {
+	if i := strings.IndexAny(path, ".["); i >= 0 {
+		return path[:i]
+	}
+	return path
+}
+
+/*----------------------------------------------------------------------*/
+
+// gboData returns the data of a BO that can be read without holding the BO's lock. GenericBo data is deep-copied while
+// holding the BO's read-lock, unless it is shared (see GboFreeze) and hence never modified in place; other
+// implementations are read via GboIterate. If 'own' is true, the result never shares maps or slices with the BO.
+func gboData(bo IGenericBo, own bool) interface{} {
+	if v := reflect.ValueOf(bo); bo == nil || (v.Kind() == reflect.Ptr && v.IsNil()) {
+		return nil
+	}
+	if gbo, ok := bo.(*GenericBo); ok {
+		gbo.m.RLock()
+		defer gbo.m.RUnlock()
+		if gbo.shared && !own {
+			return gbo.data
+		}
+		return deepCopyValue(gbo.data)
+	}
+	if frozen, ok := bo.(*FrozenGenericBo); ok {
+		if own {
+			return deepCopyValue(frozen.bo.data)
+		}
+		return frozen.bo.data
+	}
+	var m map[string]interface{}
+	var s []interface{}
+	bo.GboIterate(func(kind reflect.Kind, field interface{}, value interface{}) {
+		if kind == reflect.Map {
+			if m == nil {
+				m = make(map[string]interface{})
+			}
+			m[fmt.Sprint(field)] = value
+		} else {
+			s = append(s, value)
+		}
+	})
+	if m != nil {
+		return maybeDeepCopy(m, own)
+	}
+	if s != nil {
+		return maybeDeepCopy(s, own)
+	}
+	return nil
+}
+
+// maybeDeepCopy returns a deep copy of v if 'deep' is true, v itself otherwise.
+func maybeDeepCopy(v interface{}, deep bool) interface{} {
+	if deep {
+		return deepCopyValue(v)
+	}
+	return v
+}
+
+// asObject returns the entries of a map with string keys.
+func asObject(v interface{}) (map[string]interface{}, bool) {
+	if m, ok := v.(map[string]interface{}); ok {
+		return m, true
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Map || rv.Type().Key().Kind() != reflect.String {
+		return nil, false
+	}
+	result := make(map[string]interface{}, rv.Len())
+	for iter := rv.MapRange(); iter.Next(); {
+		result[iter.Key().String()] = iter.Value().Interface()
+	}
+	return result, true
+}
+
+// asArray returns the elements of a slice or array; []byte is not considered an array.
+func asArray(v interface{}) ([]interface{}, bool) {
+	if s, ok := v.([]interface{}); ok {
+		return s, true
+	}
+	rv := reflect.ValueOf(v)
+	if (rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array) || rv.Type().Elem().Kind() == reflect.Uint8 {
+		return nil, false
+	}
+	result := make([]interface{}, rv.Len())
+	for i := range result {
+		result[i] = rv.Index(i).Interface()
+	}
+	return result, true
+}
+
+// emptyContainerOf returns an empty map or slice matching the kind of v, nil otherwise.
+func emptyContainerOf(v interface{}) interface{} {
+	if _, ok := asObject(v); ok {
+		return map[string]interface{}{}
+	}
+	if _, ok := asArray(v); ok {
+		return []interface{}{}
+	}
+	return nil
+}
+
+// deepCopyValue returns a deep copy of maps, slices and arrays (including []byte); other values are returned as-is.
+// Types of maps and slices are preserved.
+func deepCopyValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case nil:
+		return nil
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(t))
+		for k, e := range t {
+			result[k] = deepCopyValue(e)
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(t))
+		for i, e := range t {
+			result[i] = deepCopyValue(e)
+		}
+		return result
+	case []byte:
+		return append([]byte(nil), t...)
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Map:
+		if rv.IsNil() {
+			return v
+		}
+		result := reflect.MakeMapWithSize(rv.Type(), rv.Len())
+		for iter := rv.MapRange(); iter.Next(); {
+			result.SetMapIndex(iter.Key(), deepCopyReflectValue(iter.Value(), rv.Type().Elem()))
+		}
+		return result.Interface()
+	case reflect.Slice:
+		if rv.IsNil() {
+			return v
+		}
+		result := reflect.MakeSlice(rv.Type(), rv.Len(), rv.Len())
+		for i, n := 0, rv.Len(); i < n; i++ {
+			result.Index(i).Set(deepCopyReflectValue(rv.Index(i), rv.Type().Elem()))
+		}
+		return result.Interface()
+	case reflect.Array:
+		result := reflect.New(rv.Type()).Elem()
+		for i, n := 0, rv.Len(); i < n; i++ {
+			result.Index(i).Set(deepCopyReflectValue(rv.Index(i), rv.Type().Elem()))
+		}
+		return result.Interface()
+	}
+	return v
+}
+
+func deepCopyReflectValue(v reflect.Value, typ reflect.Type) reflect.Value {
+	if (v.Kind() == reflect.Interface || v.Kind() == reflect.Ptr || v.Kind() == reflect.Map || v.Kind() == reflect.Slice) && v.IsNil() {
+		return reflect.Zero(typ)
+	}
+	return reflect.ValueOf(deepCopyValue(v.Interface())).Convert(typ)
+}
+
+// toNumber converts a numeric value to float64, also returning its int64/uint64 representation if it is an integer.
+func toNumber(v interface{}) (f float64, i int64, u uint64, kind reflect.Kind, ok bool) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), rv.Int(), 0, reflect.Int64, true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(rv.Uint()), 0, rv.Uint(), reflect.Uint64, true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), 0, 0, reflect.Float64, true
+	}
+	return 0, 0, 0, reflect.Invalid, false
+}
+
+// numbersEqual compares two numbers by value, regardless of their Go types.
+func numbersEqual(a, b interface{}) (equal, ok bool) {
+	if isExactNumber(a) || isExactNumber(b) {
+		return exactNumbersEqual(a, b)
+	}
+	fa, ia, ua, ka, okA := toNumber(a)
+	fb, ib, ub, kb, okB := toNumber(b)
+	if !okA || !okB {
+		return false, false
+	}
+	switch {
+	case ka == reflect.Int64 && kb == reflect.Int64:
+		return ia == ib, true
+	case ka == reflect.Uint64 && kb == reflect.Uint64:
+		return ua == ub, true
+	case ka == reflect.Int64 && kb == reflect.Uint64:
+		return ia >= 0 && uint64(ia) == ub, true
+	case ka == reflect.Uint64 && kb == reflect.Int64:
+		return ib >= 0 && uint64(ib) == ua, true
+	case ka == reflect.Float64 && kb == reflect.Float64:
+		return fa == fb || (math.IsNaN(fa) && math.IsNaN(fb)), true
+	case ka == reflect.Float64:
+		return floatEqualsInteger(fa, ib, ub, kb), true
+	}
+	return floatEqualsInteger(fb, ia, ua, ka), true
+}
+
+// floatEqualsInteger compares a float with an integer exactly (e.g. float64(1<<60) does not equal 1<<60+1).
+func floatEqualsInteger(f float64, i int64, u uint64, kind reflect.Kind) bool {
+	if f != math.Trunc(f) {
+		return false
+	}
+	if kind == reflect.Uint64 {
+		return f >= 0 && f < math.Exp2(64) && uint64(f) == u
+	}
+	return f >= -math.Exp2(63) && f < math.Exp2(63) && int64(f) == i
+}
+
+// leafValuesEqual compares two non-container values: numbers by value, time.Time via Equal, []byte by content.
+func leafValuesEqual(a, b interface{}) bool {
+	if equal, ok := numbersEqual(a, b); ok {
+		return equal
+	}
+	switch ta := a.(type) {
+	case time.Time:
+		tb, ok := b.(time.Time)
+		return ok && ta.Equal(tb)
+	case []byte:
+		tb, ok := b.([]byte)
+		return ok && bytes.Equal(ta, tb)
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// pathSegment is a segment of a path to a BO's attribute: a map key, or an array index if index >= 0.
+type pathSegment struct {
+	key   string
+	index int
+}
+
+// appendSegment returns a new slice, so that 'segs' can be shared by sibling calls.
+func appendSegment(segs []pathSegment, seg pathSegment) []pathSegment {
+	return append(segs[:len(segs):len(segs)], seg)
+}
+
+// formatPath formats path segments in semita's format, e.g. "options.workhour[0].value". Keys with special characters
+// are quoted, e.g. `labels["example.com"]`.
+func formatPath(segs []pathSegment) string {
+	var sb strings.Builder
+	for _, seg := range segs {
+		if seg.index >= 0 {
+			sb.WriteString("[" + strconv.Itoa(seg.index) + "]")
+			continue
+		}
+		if needsQuoting(seg.key) {
+			sb.WriteString(quotePathKey(seg.key))
+			continue
+		}
+		if sb.Len() > 0 {
+			sb.WriteString(".")
+		}
+		sb.WriteString(seg.key)
+	}
+	return sb.String()
+}
+
+// diffOp is the kind of a difference reported by diffWalk.
+type diffOp int
+
+const (
+	diffOpAdd diffOp = iota
+	diffOpRemove
+	diffOpReplace
+)
+
+// diffWalk compares 'old' and 'new' located at 'segs' and calls 'emit' for each difference. Object keys are visited in
+// sorted order; removed array elements are reported from the last one, so that differences can be applied in order.
+func diffWalk(segs []pathSegment, old, new interface{}, emit func(op diffOp, segs []pathSegment, old, new interface{})) {
+	oldObj, oldIsObj := asObject(old)
+	newObj, newIsObj := asObject(new)
+	if oldIsObj && newIsObj {
+		keys := make([]string, 0, len(oldObj)+len(newObj))
+		for k := range oldObj {
+			keys = append(keys, k)
+		}
+		for k := range newObj {
+			if _, ok := oldObj[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			oldV, inOld := oldObj[k]
+			newV, inNew := newObj[k]
+			child := appendSegment(segs, pathSegment{key: k, index: -1})
+			switch {
+			case !inOld:
+				emit(diffOpAdd, child, nil, newV)
+			case !inNew:
+				emit(diffOpRemove, child, oldV, nil)
+			default:
+				diffWalk(child, oldV, newV, emit)
+			}
+		}
+		return
+	}
+	oldArr, oldIsArr := asArray(old)
+	newArr, newIsArr := asArray(new)
+	if oldIsArr && newIsArr {
+		for i := 0; i < len(oldArr) && i < len(newArr); i++ {
+			diffWalk(appendSegment(segs, pathSegment{index: i}), oldArr[i], newArr[i], emit)
+		}
+		for i := len(oldArr); i < len(newArr); i++ {
+			emit(diffOpAdd, appendSegment(segs, pathSegment{index: i}), nil, newArr[i])
+		}
+		for i := len(oldArr) - 1; i >= len(newArr); i-- {
+			emit(diffOpRemove, appendSegment(segs, pathSegment{index: i}), oldArr[i], nil)
+		}
+		return
+	}
+	if oldIsObj || oldIsArr || newIsObj || newIsArr || !leafValuesEqual(old, new) {
+		emit(diffOpReplace, segs, old, new)
+	}
+}
+
+// diffValues compares 'old' and 'new', returning differences with paths in semita's format.
+func diffValues(old, new interface{}) *GboDiffResult {
+	result := &GboDiffResult{}
+	diffWalk(nil, old, new, func(op diffOp, segs []pathSegment, old, new interface{}) {
+		change := GboChange{Path: formatPath(segs), OldValue: old, NewValue: new}
+		switch op {
+		case diffOpAdd:
+			result.Added = append(result.Added, change)
+		case diffOpRemove:
+			result.Removed = append(result.Removed, change)
+		default:
+			result.Changed = append(result.Changed, change)
+		}
+	})
+	return result
+}
+
+// valuesEqual deeply compares two values, see GenericBo.GboDiff.
+func valuesEqual(a, b interface{}) bool {
+	equal := true
+	diffWalk(nil, a, b, func(diffOp, []pathSegment, interface{}, interface{}) { equal = false })
+	return equal
+}
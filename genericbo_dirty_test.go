@@ -0,0 +1,192 @@
+package godal
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGenericBo_GboChangedPaths(t *testing.T) {
+	name := "TestGenericBo_GboChangedPaths"
+	bo := NewGenericBo().(*GenericBo)
+	bo.GboSetAttr("name.first", "Thanh")
+	bo.GboSetAttr("age", 30)
+	if paths := bo.GboChangedPaths(); !reflect.DeepEqual(paths, []string{"age", "name"}) {
+		t.Fatalf("%s failed: not-loaded BO should have all attributes added, received %#v", name, paths)
+	}
+
+	// change tracking is off until GboResetDirty is called: loading data does not take a snapshot
+	bo.GboFromJson([]byte(`{"name":{"first":"Thanh","last":"Nguyen"},"age":30,"tags":["a","b"],"options":{"active":true}}`))
+	if paths := bo.GboChangedPaths(); !reflect.DeepEqual(paths, []string{"age", "name", "options", "tags"}) {
+		t.Fatalf("%s failed: BO without change tracking should have all attributes added, received %#v", name, paths)
+	}
+	bo.GboResetDirty()
+	if paths := bo.GboChangedPaths(); len(paths) != 0 {
+		t.Fatalf("%s failed: BO should not be dirty after GboResetDirty, received %#v", name, paths)
+	}
+	bo.GboSetAttr("name.last", "N.")
+	bo.GboSetAttr("age", int64(30)) // same value, different type
+	bo.GboSetAttr("tags[2]", "c")
+	bo.GboSetAttr("email", "thanh@example.com")
+	expected := []string{"email", "name.last", "tags[2]"}
+	if paths := bo.GboChangedPaths(); !reflect.DeepEqual(paths, expected) {
+		t.Fatalf("%s failed: expected %#v but received %#v", name, expected, paths)
+	}
+
+	bo.GboResetDirty()
+	if paths := bo.GboChangedPaths(); len(paths) != 0 {
+		t.Fatalf("%s failed: BO should not be dirty after GboResetDirty, received %#v", name, paths)
+	}
+
+	// nested maps modified directly must be detected as well
+	options := bo.GboGetAttrUnsafe("options", nil).(map[string]interface{})
+	options["active"] = false
+	if paths := bo.GboChangedPaths(); !reflect.DeepEqual(paths, []string{"options.active"}) {
+		t.Fatalf("%s failed: %#v", name, paths)
+	}
+
+	// once change tracking is on, loading data retakes the snapshot
+	bo.GboImportViaMap(map[string]interface{}{"id": "1"})
+	if paths := bo.GboChangedPaths(); len(paths) != 0 {
+		t.Fatalf("%s failed: imported BO should not be dirty, received %#v", name, paths)
+	}
+}
+
+func TestGenericBo_GboChanges(t *testing.T) {
+	name := "TestGenericBo_GboChanges"
+	bo := NewGenericBo()
+	bo.GboFromJson([]byte(`{"name":"Thanh","age":30,"address":{"city":"HCM"}}`))
+	bo.(IDirtyTrackingBo).GboResetDirty()
+	bo.GboSetAttr("name", "Thanh Nguyen")
+	bo.GboSetAttr("address", "HCM")
+	bo.GboSetAttr("email", "thanh@example.com")
+	changes := bo.(IDirtyTrackingBo).GboChanges()
+	expected := &GboDiffResult{
+		Added:   []GboChange{{Path: "email", NewValue: "thanh@example.com"}},
+		Changed: []GboChange{{Path: "address", OldValue: map[string]interface{}{"city": "HCM"}, NewValue: "HCM"}, {Path: "name", OldValue: "Thanh", NewValue: "Thanh Nguyen"}},
+	}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Fatalf("%s failed: expected %#v but received %#v", name, expected, changes)
+	}
+	log := changes.String()
+	for _, line := range []string{"+ email: thanh@example.com", "~ name: Thanh -> Thanh Nguyen", "~ address: map[city:HCM] -> HCM"} {
+		if !strings.Contains(log, line) {
+			t.Fatalf("%s failed: expected %#v in change log:\n%s", name, line, log)
+		}
+	}
+}
+
+func TestGenericBo_GboDiff(t *testing.T) {
+	name := "TestGenericBo_GboDiff"
+	now := time.Now()
+	bo1 := NewGenericBo().(*GenericBo)
+	bo1.GboImportViaMap(map[string]interface{}{
+		"id": "1", "count": int64(2), "ratio": 0.5, "created": now, "data": []byte("abc"),
+		"tags": []string{"a", "b", "c"}, "owner": map[string]interface{}{"name": "Thanh", "email": "thanh@example.com"},
+	})
+	bo2 := NewGenericBo()
+	bo2.GboImportViaMap(map[string]interface{}{
+		"id": "1", "count": 2.0, "ratio": 0.75, "created": now.UTC(), "data": []byte("abc"),
+		"tags": []interface{}{"a", "x"}, "owner": map[string]interface{}{"name": "Thanh", "phone": "123"}, "active": true,
+	})
+	diff := bo1.GboDiff(bo2)
+	expected := &GboDiffResult{
+		Added:   []GboChange{{Path: "active", NewValue: true}, {Path: "owner.phone", NewValue: "123"}},
+		Removed: []GboChange{{Path: "owner.email", OldValue: "thanh@example.com"}, {Path: "tags[2]", OldValue: "c"}},
+		Changed: []GboChange{{Path: "ratio", OldValue: 0.5, NewValue: 0.75}, {Path: "tags[1]", OldValue: "b", NewValue: "x"}},
+	}
+	if !reflect.DeepEqual(diff, expected) {
+		t.Fatalf("%s failed: expected %#v but received %#v", name, expected, diff)
+	}
+	if paths := diff.Paths(); !reflect.DeepEqual(paths, []string{"active", "owner.email", "owner.phone", "ratio", "tags[1]", "tags[2]"}) {
+		t.Fatalf("%s failed: %#v", name, paths)
+	}
+	if diff := bo1.GboDiff(bo1); !diff.IsEmpty() {
+		t.Fatalf("%s failed: %#v", name, diff)
+	}
+	if diff := bo1.GboDiff(nil); len(diff.Changed) != 1 || diff.Changed[0].Path != "" {
+		t.Fatalf("%s failed: %#v", name, diff)
+	}
+
+	// other IGenericBo implementations are read via GboIterate
+	mock := &iterateOnlyBo{IGenericBo: bo2}
+	if diff := bo1.GboDiff(mock); !reflect.DeepEqual(diff, expected) {
+		t.Fatalf("%s failed: expected %#v but received %#v", name, expected, diff)
+	}
+}
+
+type iterateOnlyBo struct {
+	IGenericBo
+}
+
+func TestChangedFieldsBo(t *testing.T) {
+	name := "TestChangedFieldsBo"
+	bo := NewGenericBo()
+	bo.GboImportViaMap(map[string]interface{}{"id": "1", "name": "Thanh", "address": map[string]interface{}{"city": "HCM"}, "age": 30})
+	bo.(IDirtyTrackingBo).GboResetDirty()
+	if changed := ChangedFieldsBo(bo); changed != nil {
+		t.Fatalf("%s failed: BO has no change, received %s", name, changed.GboToJsonUnsafe())
+	}
+	bo.GboSetAttr("address.city", "HN")
+	bo.GboSetAttr("email", "thanh@example.com")
+	changed := ChangedFieldsBo(bo)
+	if changed == nil || string(changed.GboToJsonUnsafe()) != `{"address":{"city":"HN"},"email":"thanh@example.com"}` {
+		t.Fatalf("%s failed: %#v", name, changed)
+	}
+	if changed := ChangedFieldsBo(&iterateOnlyBo{IGenericBo: bo}); changed != nil {
+		t.Fatalf("%s failed: BO does not track changes, received %s", name, changed.GboToJsonUnsafe())
+	}
+}
+
+func TestDeepCopyValue(t *testing.T) {
+	name := "TestDeepCopyValue"
+	src := map[string]interface{}{
+		"m": map[string]interface{}{"a": []interface{}{1, map[string]interface{}{"b": 2}}},
+		"s": []string{"x", "y"}, "bytes": []byte("abc"), "typed": map[string]int{"c": 3}, "nil": nil,
+	}
+	dst := deepCopyValue(src).(map[string]interface{})
+	if !reflect.DeepEqual(src, dst) {
+		t.Fatalf("%s failed: expected %#v but received %#v", name, src, dst)
+	}
+	dst["m"].(map[string]interface{})["a"].([]interface{})[1].(map[string]interface{})["b"] = 3
+	dst["s"].([]string)[0] = "z"
+	dst["bytes"].([]byte)[0] = 'z'
+	dst["typed"].(map[string]int)["c"] = 4
+	if src["m"].(map[string]interface{})["a"].([]interface{})[1].(map[string]interface{})["b"] != 2 ||
+		src["s"].([]string)[0] != "x" || src["bytes"].([]byte)[0] != 'a' || src["typed"].(map[string]int)["c"] != 3 {
+		t.Fatalf("%s failed: source is modified %#v", name, src)
+	}
+}
+
+func TestNumbersEqual(t *testing.T) {
+	name := "TestNumbersEqual"
+	testCases := []struct {
+		a, b  interface{}
+		equal bool
+	}{
+		{1, int64(1), true},
+		{uint8(1), 1.0, true},
+		{-1, uint64(1), false},
+		{int64(1)<<60 + 1, float64(int64(1) << 60), false},
+		{uint64(1)<<63 + 1, float64(uint64(1) << 63), false},
+		{uint64(1) << 63, float64(uint64(1) << 63), true},
+		{int64(-1) << 63, -math.Exp2(63), true},
+		{1, 1.5, false},
+		{float32(0.5), 0.5, true},
+		{math.NaN(), math.NaN(), true},
+		{math.Inf(1), int64(math.MaxInt64), false},
+	}
+	for i, tc := range testCases {
+		if equal, ok := numbersEqual(tc.a, tc.b); !ok || equal != tc.equal {
+			t.Fatalf("%s failed: case %d, expected %#v but received %#v", name, i, tc.equal, equal)
+		}
+		if equal, _ := numbersEqual(tc.b, tc.a); equal != tc.equal {
+			t.Fatalf("%s failed: case %d (swapped), expected %#v but received %#v", name, i, tc.equal, equal)
+		}
+	}
+	if _, ok := numbersEqual(1, "1"); ok {
+		t.Fatalf("%s failed: strings are not numbers", name)
+	}
+}
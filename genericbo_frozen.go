@@ -0,0 +1,212 @@
+package godal
+
+import (
+	"errors"
+	"reflect"
+	"time"
+
+	"github.com/btnguyen2k/consu/semita"
+)
+
+var (
+	// ErrGboFrozen is returned by FrozenGenericBo's modifying functions (e.g. GboSetAttr).
+	//
+	// Available since v0.7.0
+	ErrGboFrozen = errors.New("bo is frozen")
+)
+
+// GboFreeze returns a read-only view of the BO's current data, see FrozenGenericBo.
+//
+// No data is copied: the view and the BO share data, and the BO copies the parts it modifies afterwards (copy-on-write),
+// so that the view never changes. While data is shared, maps and slices returned by the BO's getters are deep copies.
+//
+// Available since v0.7.0
+func (bo *GenericBo) GboFreeze() *FrozenGenericBo {
+	bo.m.Lock()
+	defer bo.m.Unlock()
+	bo.shared = true
+	frozen := &GenericBo{strict: bo.strict, useNumber: bo.useNumber, shared: true}
+	frozen.data, frozen.s = bo.data, semita.NewSemita(bo.data)
+	return &FrozenGenericBo{bo: frozen}
+}
+
+// copyOnWrite prepares shared data for a modification at 'path': maps and slices along the path are copied, the rest
+// of the data is still shared. If the path can not be parsed (e.g. semita's "[]"), the whole data is copied.
+func (bo *GenericBo) copyOnWrite(path string) {
+	if !bo.shared {
+		return
+	}
+	segs, err := parsePath(path)
+	if err != nil {
+		bo.unshare()
+		return
+	}
+	bo.data = copyAlongPath(bo.data, segs)
+	bo.s = semita.NewSemita(bo.data)
+}
+
+// unshare deep-copies shared data, see copyOnWrite.
+func (bo *GenericBo) unshare() {
+	if bo.shared {
+		bo.setData(deepCopyValue(bo.data))
+	}
+}
+
+// exposeValue returns a value to be handed out by a getter: if data is shared, maps and slices are deep-copied so that
+// the caller can not modify shared data.
+func (bo *GenericBo) exposeValue(v interface{}) interface{} {
+	if bo.shared {
+		return deepCopyValue(v)
+	}
+	return v
+}
+
+// copyAlongPath returns 'v' with the maps and slices along 'segs' shallow-copied. Values inside pointers and structs
+// are not copied.
+func copyAlongPath(v interface{}, segs []pathSegment) interface{} {
+	rv := reflect.ValueOf(v)
+	switch {
+	case rv.Kind() == reflect.Map && !rv.IsNil():
+		result := reflect.MakeMapWithSize(rv.Type(), rv.Len())
+		for iter := rv.MapRange(); iter.Next(); {
+			result.SetMapIndex(iter.Key(), iter.Value())
+		}
+		if len(segs) > 0 && segs[0].index < 0 && rv.Type().Key().Kind() == reflect.String {
+			key := reflect.ValueOf(segs[0].key).Convert(rv.Type().Key())
+			if child := rv.MapIndex(key); child.IsValid() && child.Interface() != nil {
+				result.SetMapIndex(key, reflect.ValueOf(copyAlongPath(child.Interface(), segs[1:])))
+			}
+		}
+		return result.Interface()
+	case rv.Kind() == reflect.Slice && !rv.IsNil():
+		result := reflect.MakeSlice(rv.Type(), rv.Len(), rv.Len())
+		reflect.Copy(result, rv)
+		if len(segs) > 0 && segs[0].index >= 0 && segs[0].index < rv.Len() {
+			if child := rv.Index(segs[0].index); child.Interface() != nil {
+				result.Index(segs[0].index).Set(reflect.ValueOf(copyAlongPath(child.Interface(), segs[1:])))
+			}
+		}
+		return result.Interface()
+	}
+	return v
+}
+
+/*----------------------------------------------------------------------*/
+
+// FrozenGenericBo is a read-only implementation of IGenericBo, created by GenericBo.GboFreeze.
+//
+// Its data never changes: modifying functions (GboSetAttr, GboFromJson, GboImportViaJson and GboImportViaMap) return
+// ErrGboFrozen, and maps and slices returned by getters are deep copies. Hence, a FrozenGenericBo can be handed to many
+// goroutines, e.g. by a cache. Use GboThaw to get a mutable copy.
+//
+// Available since v0.7.0
+type FrozenGenericBo struct {
+	bo *GenericBo
+}
+
+// GboFreeze returns the FrozenGenericBo itself.
+func (f *FrozenGenericBo) GboFreeze() *FrozenGenericBo {
+	return f
+}
+
+// GboThaw returns a mutable copy of the BO. The copy shares data with the FrozenGenericBo and copies the parts it
+// modifies (copy-on-write), so that thawing is cheap. Change tracking of the copy starts from the frozen data, strict
+// mode and json.Number decoding are kept.
+func (f *FrozenGenericBo) GboThaw() *GenericBo {
+	bo := &GenericBo{strict: f.bo.strict, useNumber: f.bo.useNumber, shared: true, snapshot: f.bo.data, hasSnapshot: true}
+	bo.data, bo.s = f.bo.data, semita.NewSemita(f.bo.data)
+	return bo
+}
+
+// Checksum returns checksum value of the BO, see GenericBo.Checksum.
+func (f *FrozenGenericBo) Checksum() []byte {
+	return f.bo.Checksum()
+}
+
+// GboIterate implements IGenericBo.GboIterate.
+func (f *FrozenGenericBo) GboIterate(callback func(kind reflect.Kind, field interface{}, value interface{})) {
+	f.bo.GboIterate(callback)
+}
+
+// GboGetAttr implements IGenericBo.GboGetAttr.
+func (f *FrozenGenericBo) GboGetAttr(path string, typ reflect.Type) (interface{}, error) {
+	return f.bo.GboGetAttr(path, typ)
+}
+
+// GboGetAttrUnsafe implements IGenericBo.GboGetAttrUnsafe.
+func (f *FrozenGenericBo) GboGetAttrUnsafe(path string, typ reflect.Type) interface{} {
+	return f.bo.GboGetAttrUnsafe(path, typ)
+}
+
+// GboGetAttrUnmarshalJson implements IGenericBo.GboGetAttrUnmarshalJson.
+func (f *FrozenGenericBo) GboGetAttrUnmarshalJson(path string) (interface{}, error) {
+	return f.bo.GboGetAttrUnmarshalJson(path)
+}
+
+// GboGetTimeWithLayout implements IGenericBo.GboGetTimeWithLayout.
+func (f *FrozenGenericBo) GboGetTimeWithLayout(path, layout string) (time.Time, error) {
+	return f.bo.GboGetTimeWithLayout(path, layout)
+}
+
+// GboSetAttr implements IGenericBo.GboSetAttr, always returning ErrGboFrozen.
+func (f *FrozenGenericBo) GboSetAttr(_ string, _ interface{}) error {
+	return ErrGboFrozen
+}
+
+// GboToJson implements IGenericBo.GboToJson.
+func (f *FrozenGenericBo) GboToJson() ([]byte, error) {
+	return f.bo.GboToJson()
+}
+
+// GboToJsonUnsafe implements IGenericBo.GboToJsonUnsafe.
+func (f *FrozenGenericBo) GboToJsonUnsafe() []byte {
+	return f.bo.GboToJsonUnsafe()
+}
+
+// GboFromJson implements IGenericBo.GboFromJson, always returning ErrGboFrozen.
+func (f *FrozenGenericBo) GboFromJson(_ []byte) error {
+	return ErrGboFrozen
+}
+
+// GboTransferViaJson implements IGenericBo.GboTransferViaJson.
+func (f *FrozenGenericBo) GboTransferViaJson(dest interface{}) error {
+	return f.bo.GboTransferViaJson(dest)
+}
+
+// GboImportViaJson implements IGenericBo.GboImportViaJson, always returning ErrGboFrozen.
+func (f *FrozenGenericBo) GboImportViaJson(_ interface{}) error {
+	return ErrGboFrozen
+}
+
+// GboImportViaMap implements IGenericBo.GboImportViaMap, always returning ErrGboFrozen.
+func (f *FrozenGenericBo) GboImportViaMap(_ map[string]interface{}) error {
+	return ErrGboFrozen
+}
+
+// GboEquals deeply compares the BO with another one, see GenericBo.GboEquals.
+func (f *FrozenGenericBo) GboEquals(other IGenericBo) bool {
+	if other == IGenericBo(f) {
+		return true
+	}
+	return f.bo.GboEquals(other)
+}
+
+// GboQuery evaluates a JSONPath-style query, see GenericBo.GboQuery.
+func (f *FrozenGenericBo) GboQuery(expr string) ([]interface{}, error) {
+	return f.bo.GboQuery(expr)
+}
+
+// GboWalk visits every leaf of BO data, see GenericBo.GboWalk.
+func (f *FrozenGenericBo) GboWalk(callback func(path string, value interface{}) error) error {
+	return f.bo.GboWalk(callback)
+}
+
+// GboFlatten returns BO data as a flat map, see GenericBo.GboFlatten.
+func (f *FrozenGenericBo) GboFlatten(sep string) map[string]interface{} {
+	return f.bo.GboFlatten(sep)
+}
+
+// GboEncode serializes BO data with the specified codec, see GenericBo.GboEncode.
+func (f *FrozenGenericBo) GboEncode(codec IGboCodec) ([]byte, error) {
+	return f.bo.GboEncode(codec)
+}
@@ -0,0 +1,146 @@
+package godal
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+)
+
+const frozenTestDoc = `{"name":"Thanh","options":{"tags":["a","b"],"level":1},"items":[{"id":1},{"id":2}]}`
+
+func TestGenericBo_GboFreeze(t *testing.T) {
+	name := "TestGenericBo_GboFreeze"
+	bo := NewGenericBo().(*GenericBo)
+	bo.GboFromJson([]byte(frozenTestDoc))
+	frozen := bo.GboFreeze()
+	var _ IGenericBo = frozen
+	if frozen.GboFreeze() != frozen {
+		t.Fatalf("%s failed: freezing a frozen BO should return itself", name)
+	}
+
+	if err := frozen.GboSetAttr("name", "x"); err != ErrGboFrozen {
+		t.Fatalf("%s failed: expected ErrGboFrozen but received %v", name, err)
+	}
+	if err := frozen.GboFromJson([]byte(`{}`)); err != ErrGboFrozen {
+		t.Fatalf("%s failed: expected ErrGboFrozen but received %v", name, err)
+	}
+	if err := frozen.GboImportViaJson(map[string]interface{}{}); err != ErrGboFrozen {
+		t.Fatalf("%s failed: expected ErrGboFrozen but received %v", name, err)
+	}
+	if err := frozen.GboImportViaMap(map[string]interface{}{}); err != ErrGboFrozen {
+		t.Fatalf("%s failed: expected ErrGboFrozen but received %v", name, err)
+	}
+
+	// returned maps and slices are copies
+	options := frozen.GboGetAttrUnsafe("options", nil).(map[string]interface{})
+	options["level"] = 2
+	options["tags"].([]interface{})[0] = "z"
+	frozen.GboIterate(func(_ reflect.Kind, field interface{}, value interface{}) {
+		if field == "options" {
+			value.(map[string]interface{})["new"] = true
+		}
+	})
+	if v, _ := frozen.GboQuery("items[*]"); len(v) == 2 {
+		v[0].(map[string]interface{})["id"] = 0
+	}
+
+	// the source BO is still modifiable, without affecting the frozen BO
+	if err := bo.GboSetAttr("options.tags[1]", "y"); err != nil {
+		t.Fatalf("%s failed: %s", name, err)
+	}
+	if err := bo.GboSetAttr(`items[1]["id"]`, 3); err != nil {
+		t.Fatalf("%s failed: %s", name, err)
+	}
+	bo.GboGetAttrUnsafe("options", nil).(map[string]interface{})["level"] = 3
+	if js := string(frozen.GboToJsonUnsafe()); js != `{"items":[{"id":1},{"id":2}],"name":"Thanh","options":{"level":1,"tags":["a","b"]}}` {
+		t.Fatalf("%s failed: frozen BO has been modified: %s", name, js)
+	}
+	if js := string(bo.GboToJsonUnsafe()); js != `{"items":[{"id":1},{"id":3}],"name":"Thanh","options":{"level":1,"tags":["a","y"]}}` {
+		t.Fatalf("%s failed: %s", name, js)
+	}
+
+	// once data has been replaced, it is not shared anymore
+	bo.GboFromJson([]byte(`{"a":{"b":1}}`))
+	bo.GboGetAttrUnsafe("a", nil).(map[string]interface{})["b"] = 2
+	if v := bo.GboGetAttrUnsafe("a.b", nil); v != 2 {
+		t.Fatalf("%s failed: %#v", name, v)
+	}
+
+	other := NewGenericBo()
+	other.GboFromJson([]byte(frozenTestDoc))
+	if !frozen.GboEquals(other) || !other.(*GenericBo).GboEquals(frozen) || !frozen.GboEquals(frozen) {
+		t.Fatalf("%s failed: frozen BO should equal %s", name, other.GboToJsonUnsafe())
+	}
+}
+
+func TestFrozenGenericBo_GboThaw(t *testing.T) {
+	name := "TestFrozenGenericBo_GboThaw"
+	bo := NewGenericBo().(*GenericBo)
+	bo.GboFromJson([]byte(frozenTestDoc))
+	bo.GboSetStrict(true)
+	frozen := bo.GboFreeze()
+
+	thawed1, thawed2 := frozen.GboThaw(), frozen.GboThaw()
+	if !thawed1.GboIsStrict() {
+		t.Fatalf("%s failed: strict mode should be kept", name)
+	}
+	if paths := thawed1.GboChangedPaths(); len(paths) != 0 {
+		t.Fatalf("%s failed: thawed BO should not be dirty, received %#v", name, paths)
+	}
+	if err := thawed1.GboSetAttr("options.tags[2]", "c"); err != nil {
+		t.Fatalf("%s failed: %s", name, err)
+	}
+	if err := thawed1.GboSetAttr("items[0].id", 10); err != nil {
+		t.Fatalf("%s failed: %s", name, err)
+	}
+	if err := thawed1.GboSetAttr("options.level", nil); err != nil {
+		t.Fatalf("%s failed: %s", name, err)
+	}
+	if n, err := thawed2.GboSetAll("items[*].id", 0); err != nil || n != 2 {
+		t.Fatalf("%s failed: %d / %s", name, n, err)
+	}
+	if err := thawed2.GboApplyMergePatch([]byte(`{"name":null}`)); err != nil {
+		t.Fatalf("%s failed: %s", name, err)
+	}
+
+	expected := `{"items":[{"id":1},{"id":2}],"name":"Thanh","options":{"level":1,"tags":["a","b"]}}`
+	for _, b := range []IGenericBo{frozen, bo} {
+		if js := string(b.GboToJsonUnsafe()); js != expected {
+			t.Fatalf("%s failed: expected %s but received %s", name, expected, js)
+		}
+	}
+	if js := string(thawed1.GboToJsonUnsafe()); js != `{"items":[{"id":10},{"id":2}],"name":"Thanh","options":{"tags":["a","b","c"]}}` {
+		t.Fatalf("%s failed: %s", name, js)
+	}
+	if js := string(thawed2.GboToJsonUnsafe()); js != `{"items":[{"id":0},{"id":0}],"options":{"level":1,"tags":["a","b"]}}` {
+		t.Fatalf("%s failed: %s", name, js)
+	}
+	expectedPaths := []string{"items[0].id", "options.level", "options.tags[2]"}
+	if paths := thawed1.GboChangedPaths(); !reflect.DeepEqual(paths, expectedPaths) {
+		t.Fatalf("%s failed: expected %#v but received %#v", name, expectedPaths, paths)
+	}
+}
+
+func TestFrozenGenericBo_Concurrency(t *testing.T) {
+	name := "TestFrozenGenericBo_Concurrency"
+	bo := NewGenericBo().(*GenericBo)
+	bo.GboFromJson([]byte(frozenTestDoc))
+	frozen := bo.GboFreeze()
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				if v := frozen.GboGetAttrUnsafe("options.tags[0]", nil); v != "a" {
+					t.Errorf("%s failed: %#v", name, v)
+					return
+				}
+				thawed := frozen.GboThaw()
+				thawed.GboSetAttr("options.tags[0]", i)
+				bo.GboSetAttr("options.level", j)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
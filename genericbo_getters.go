@@ -0,0 +1,241 @@
+package godal
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"time"
+
+	"github.com/btnguyen2k/consu/reddo"
+)
+
+var (
+	typeSliceOfInterface = reflect.TypeOf([]interface{}{})
+	typeMapOfInterface   = reflect.TypeOf(map[string]interface{}{})
+)
+
+// GboSetStrict turns strict mode on or off.
+//
+// In strict mode, GboGetAttr (and the typed getters such as GboGetInt64) returns an error instead of a converted value
+// if the conversion would lose information, e.g. float 1.5 to int, number 2 to bool, -1 to uint, true to number, or
+// int64 values that float64 can not represent exactly. Slices and maps are checked element by element.
+//
+// Available since v0.7.0
+func (bo *GenericBo) GboSetStrict(strict bool) *GenericBo {
+	bo.m.Lock()
+	defer bo.m.Unlock()
+	bo.strict = strict
+	return bo
+}
+
+// GboIsStrict returns true if strict mode is on, see GboSetStrict.
+//
+// Available since v0.7.0
+func (bo *GenericBo) GboIsStrict() bool {
+	bo.m.RLock()
+	defer bo.m.RUnlock()
+	return bo.strict
+}
+
+// GboGetString returns the value at 'path' converted to string, or "" if the value does not exist.
+//
+// Available since v0.7.0
+func (bo *GenericBo) GboGetString(path string) (string, error) {
+	return getTyped[string](bo, path, reddo.TypeString)
+}
+
+// GboGetStringDefault is like GboGetString, but returns 'def' if the value does not exist or can not be converted.
+//
+// Available since v0.7.0
+func (bo *GenericBo) GboGetStringDefault(path string, def string) string {
+	return getTypedDefault(bo, path, reddo.TypeString, def)
+}
+
+// GboGetInt64 returns the value at 'path' converted to int64, or 0 if the value does not exist.
+//
+// Available since v0.7.0
+func (bo *GenericBo) GboGetInt64(path string) (int64, error) {
+	return getTyped[int64](bo, path, reddo.TypeInt)
+}
+
+// GboGetInt64Default is like GboGetInt64, but returns 'def' if the value does not exist or can not be converted.
+//
+// Available since v0.7.0
+func (bo *GenericBo) GboGetInt64Default(path string, def int64) int64 {
+	return getTypedDefault(bo, path, reddo.TypeInt, def)
+}
+
+// GboGetFloat64 returns the value at 'path' converted to float64, or 0 if the value does not exist.
+//
+// Available since v0.7.0
+func (bo *GenericBo) GboGetFloat64(path string) (float64, error) {
+	return getTyped[float64](bo, path, reddo.TypeFloat)
+}
+
+// GboGetFloat64Default is like GboGetFloat64, but returns 'def' if the value does not exist or can not be converted.
+//
+// Available since v0.7.0
+func (bo *GenericBo) GboGetFloat64Default(path string, def float64) float64 {
+	return getTypedDefault(bo, path, reddo.TypeFloat, def)
+}
+
+// GboGetBool returns the value at 'path' converted to bool, or false if the value does not exist.
+//
+// Available since v0.7.0
+func (bo *GenericBo) GboGetBool(path string) (bool, error) {
+	return getTyped[bool](bo, path, reddo.TypeBool)
+}
+
+// GboGetBoolDefault is like GboGetBool, but returns 'def' if the value does not exist or can not be converted.
+//
+// Available since v0.7.0
+func (bo *GenericBo) GboGetBoolDefault(path string, def bool) bool {
+	return getTypedDefault(bo, path, reddo.TypeBool, def)
+}
+
+// GboGetTime returns the value at 'path' converted to time.Time, or the zero time if the value does not exist.
+// Numbers and numeric strings are treated as UNIX timestamps (see reddo.ToTime), use GboGetTimeWithLayout to parse
+// other strings.
+//
+// Available since v0.7.0
+func (bo *GenericBo) GboGetTime(path string) (time.Time, error) {
+	return getTyped[time.Time](bo, path, reddo.TypeTime)
+}
+
+// GboGetTimeDefault is like GboGetTime, but returns 'def' if the value does not exist or can not be converted.
+//
+// Available since v0.7.0
+func (bo *GenericBo) GboGetTimeDefault(path string, def time.Time) time.Time {
+	return getTypedDefault(bo, path, reddo.TypeTime, def)
+}
+
+// GboGetSlice returns the array at 'path' as []interface{}, or nil if the value does not exist.
+//
+// Available since v0.7.0
+func (bo *GenericBo) GboGetSlice(path string) ([]interface{}, error) {
+	return getTyped[[]interface{}](bo, path, typeSliceOfInterface)
+}
+
+// GboGetSliceDefault is like GboGetSlice, but returns 'def' if the value does not exist or is not an array.
+//
+// Available since v0.7.0
+func (bo *GenericBo) GboGetSliceDefault(path string, def []interface{}) []interface{} {
+	return getTypedDefault(bo, path, typeSliceOfInterface, def)
+}
+
+// GboGetMap returns the object at 'path' as map[string]interface{}, or nil if the value does not exist.
+//
+// Available since v0.7.0
+func (bo *GenericBo) GboGetMap(path string) (map[string]interface{}, error) {
+	return getTyped[map[string]interface{}](bo, path, typeMapOfInterface)
+}
+
+// GboGetMapDefault is like GboGetMap, but returns 'def' if the value does not exist or is not an object.
+//
+// Available since v0.7.0
+func (bo *GenericBo) GboGetMapDefault(path string, def map[string]interface{}) map[string]interface{} {
+	return getTypedDefault(bo, path, typeMapOfInterface, def)
+}
+
+/*----------------------------------------------------------------------*/
+
+// getTyped returns the value at 'path' converted to 'typ', which must convert to T; missing values give T's zero value.
+func getTyped[T any](bo *GenericBo, path string, typ reflect.Type) (T, error) {
+	var zero T
+	v, err := bo.GboGetAttr(path, typ)
+	if v == nil || err != nil {
+		return zero, err
+	}
+	result, ok := v.(T)
+	if !ok {
+		return zero, fmt.Errorf("path %q: cannot convert %T to %T", path, v, zero)
+	}
+	return result, nil
+}
+
+// getTypedDefault is like getTyped, but returns 'def' instead of the zero value or an error.
+func getTypedDefault[T any](bo *GenericBo, path string, typ reflect.Type, def T) T {
+	v, err := bo.GboGetAttr(path, typ)
+	if result, ok := v.(T); ok && err == nil {
+		return result
+	}
+	return def
+}
+
+// convertValue converts 'v' to 'typ' with reddo, checking first that no information is lost if strict mode is on.
+func (bo *GenericBo) convertValue(v interface{}, typ reflect.Type) (interface{}, error) {
+	if v == nil || typ == nil {
+		return v, nil
+	}
+	if typ == TypeDecimal || typ == TypeBigInt {
+		return bo.convertExactNumber(v, typ)
+	}
+	if bo.strict {
+		if err := checkLossless(v, typ); err != nil {
+			return nil, err
+		}
+	}
+	return reddo.Convert(v, typ)
+}
+
+// checkLossless returns an error if converting 'v' to 'typ' with reddo would lose information.
+func checkLossless(v interface{}, typ reflect.Type) error {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	if !rv.IsValid() {
+		return nil
+	}
+	lossy := func() error {
+		return fmt.Errorf("strict mode: converting %T(%v) to %s loses information", v, v, typ)
+	}
+	if typ == reddo.TypeTime {
+		if rv.Type() == reddo.TypeTime {
+			return nil
+		}
+		typ = reddo.TypeInt // non-time values are converted to int64 UNIX timestamps first
+	}
+	f, i, u, kind, isNumber := toNumber(rv.Interface())
+	isBool := rv.Kind() == reflect.Bool
+	switch typ.Kind() {
+	case reflect.Bool:
+		if isNumber && f != 0 && f != 1 {
+			return lossy()
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if isBool || (kind == reflect.Uint64 && u > math.MaxInt64) ||
+			(kind == reflect.Float64 && !floatEqualsInteger(f, int64(f), 0, reflect.Int64)) {
+			return lossy()
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if isBool || (kind == reflect.Int64 && i < 0) ||
+			(kind == reflect.Float64 && !floatEqualsInteger(f, 0, uint64(f), reflect.Uint64)) {
+			return lossy()
+		}
+	case reflect.Float32, reflect.Float64:
+		if isBool || (kind != reflect.Float64 && isNumber && !floatEqualsInteger(f, i, u, kind)) {
+			return lossy()
+		}
+	case reflect.Array, reflect.Slice:
+		if rv.Kind() == reflect.Array || rv.Kind() == reflect.Slice {
+			for j, n := 0, rv.Len(); j < n; j++ {
+				if err := checkLossless(rv.Index(j).Interface(), typ.Elem()); err != nil {
+					return err
+				}
+			}
+		}
+	case reflect.Map:
+		if rv.Kind() == reflect.Map {
+			for iter := rv.MapRange(); iter.Next(); {
+				if err := checkLossless(iter.Value().Interface(), typ.Elem()); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
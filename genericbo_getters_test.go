@@ -0,0 +1,187 @@
+package godal
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/btnguyen2k/consu/reddo"
+)
+
+const gettersTestDoc = `{"name":"Thanh","age":30,"rate":1.5,"active":true,"flag":"true","count":"12","created":1547549353,
+"tags":["a","b"],"options":{"k":"v"},"nums":[1,2.5],"label":"abc"}`
+
+func TestGenericBo_TypedGetters(t *testing.T) {
+	name := "TestGenericBo_TypedGetters"
+	bo := NewGenericBo().(*GenericBo)
+	bo.GboFromJson([]byte(gettersTestDoc))
+
+	if v, err := bo.GboGetString("name"); err != nil || v != "Thanh" {
+		t.Fatalf("%s failed: %#v / %s", name, v, err)
+	}
+	if v, err := bo.GboGetString("age"); err != nil || v != "30" {
+		t.Fatalf("%s failed: %#v / %s", name, v, err)
+	}
+	if v, err := bo.GboGetInt64("age"); err != nil || v != 30 {
+		t.Fatalf("%s failed: %#v / %s", name, v, err)
+	}
+	if v, err := bo.GboGetInt64("count"); err != nil || v != 12 {
+		t.Fatalf("%s failed: %#v / %s", name, v, err)
+	}
+	if v, err := bo.GboGetInt64("rate"); err != nil || v != 1 {
+		t.Fatalf("%s failed: non-strict mode should truncate, received %#v / %s", name, v, err)
+	}
+	if _, err := bo.GboGetInt64("label"); err == nil {
+		t.Fatalf("%s failed: %q should not be converted to int64", name, "abc")
+	}
+	if v, err := bo.GboGetFloat64("rate"); err != nil || v != 1.5 {
+		t.Fatalf("%s failed: %#v / %s", name, v, err)
+	}
+	if v, err := bo.GboGetBool("active"); err != nil || !v {
+		t.Fatalf("%s failed: %#v / %s", name, v, err)
+	}
+	if v, err := bo.GboGetBool("flag"); err != nil || !v {
+		t.Fatalf("%s failed: %#v / %s", name, v, err)
+	}
+	if v, err := bo.GboGetTime("created"); err != nil || v.Unix() != 1547549353 {
+		t.Fatalf("%s failed: %#v / %s", name, v, err)
+	}
+	if v, err := bo.GboGetSlice("tags"); err != nil || !reflect.DeepEqual(v, []interface{}{"a", "b"}) {
+		t.Fatalf("%s failed: %#v / %s", name, v, err)
+	}
+	if v, err := bo.GboGetMap("options"); err != nil || !reflect.DeepEqual(v, map[string]interface{}{"k": "v"}) {
+		t.Fatalf("%s failed: %#v / %s", name, v, err)
+	}
+	if _, err := bo.GboGetMap("tags"); err == nil {
+		t.Fatalf("%s failed: array should not be converted to map", name)
+	}
+
+	// missing attributes give zero values
+	if v, err := bo.GboGetString("notfound"); err != nil || v != "" {
+		t.Fatalf("%s failed: %#v / %s", name, v, err)
+	}
+	if v, err := bo.GboGetInt64("notfound"); err != nil || v != 0 {
+		t.Fatalf("%s failed: %#v / %s", name, v, err)
+	}
+	if v, err := bo.GboGetTime("notfound"); err != nil || !v.IsZero() {
+		t.Fatalf("%s failed: %#v / %s", name, v, err)
+	}
+	if v, err := bo.GboGetSlice("notfound"); err != nil || v != nil {
+		t.Fatalf("%s failed: %#v / %s", name, v, err)
+	}
+
+	// typed containers
+	bo2 := NewGenericBo().(*GenericBo)
+	bo2.GboImportViaMap(map[string]interface{}{"m": map[string]int{"a": 1}, "s": []string{"x"}})
+	if v, err := bo2.GboGetMap("m"); err != nil || !reflect.DeepEqual(v, map[string]interface{}{"a": 1}) {
+		t.Fatalf("%s failed: %#v / %s", name, v, err)
+	}
+	if v, err := bo2.GboGetSlice("s"); err != nil || !reflect.DeepEqual(v, []interface{}{"x"}) {
+		t.Fatalf("%s failed: %#v / %s", name, v, err)
+	}
+}
+
+func TestGenericBo_TypedGettersDefault(t *testing.T) {
+	name := "TestGenericBo_TypedGettersDefault"
+	bo := NewGenericBo().(*GenericBo)
+	bo.GboFromJson([]byte(gettersTestDoc))
+	now := time.Now()
+
+	if v := bo.GboGetStringDefault("name", "x"); v != "Thanh" {
+		t.Fatalf("%s failed: %#v", name, v)
+	}
+	if v := bo.GboGetStringDefault("notfound", "x"); v != "x" {
+		t.Fatalf("%s failed: %#v", name, v)
+	}
+	if v := bo.GboGetInt64Default("age", -1); v != 30 {
+		t.Fatalf("%s failed: %#v", name, v)
+	}
+	if v := bo.GboGetInt64Default("label", -1); v != -1 {
+		t.Fatalf("%s failed: %#v", name, v)
+	}
+	if v := bo.GboGetFloat64Default("notfound", 2.5); v != 2.5 {
+		t.Fatalf("%s failed: %#v", name, v)
+	}
+	if v := bo.GboGetBoolDefault("label", true); v != true {
+		t.Fatalf("%s failed: %#v", name, v)
+	}
+	if v := bo.GboGetTimeDefault("notfound", now); !v.Equal(now) {
+		t.Fatalf("%s failed: %#v", name, v)
+	}
+	if v := bo.GboGetSliceDefault("name", []interface{}{}); !reflect.DeepEqual(v, []interface{}{}) {
+		t.Fatalf("%s failed: %#v", name, v)
+	}
+	if v := bo.GboGetMapDefault("options", nil); !reflect.DeepEqual(v, map[string]interface{}{"k": "v"}) {
+		t.Fatalf("%s failed: %#v", name, v)
+	}
+}
+
+func TestGenericBo_GboSetStrict(t *testing.T) {
+	name := "TestGenericBo_GboSetStrict"
+	bo := NewGenericBo().(*GenericBo)
+	bo.GboFromJson([]byte(gettersTestDoc))
+	if bo.GboIsStrict() {
+		t.Fatalf("%s failed: strict mode should be off by default", name)
+	}
+	if !bo.GboSetStrict(true).GboIsStrict() {
+		t.Fatalf("%s failed: strict mode should be on", name)
+	}
+	bo.GboImportViaMap(map[string]interface{}{
+		"int": 30, "float": 1.5, "whole": 2.0, "neg": -1, "big": int64(1)<<60 + 1, "bool": true, "str": "abc",
+		"one": 1, "two": 2, "floats": []interface{}{1.0, 2.5}, "ints": map[string]interface{}{"a": 1, "b": 1.2},
+		"ts": 1547549353.5,
+	})
+	okCases := []struct {
+		path     string
+		typ      reflect.Type
+		expected interface{}
+	}{
+		{"int", reddo.TypeInt, int64(30)},
+		{"whole", reddo.TypeInt, int64(2)},
+		{"int", reddo.TypeFloat, 30.0},
+		{"int", reddo.TypeUint, uint64(30)},
+		{"one", reddo.TypeBool, true},
+		{"bool", reddo.TypeString, "true"},
+		{"float", reddo.TypeString, "1.5"},
+		{"notfound", reddo.TypeInt, nil},
+	}
+	for _, tc := range okCases {
+		if v, err := bo.GboGetAttr(tc.path, tc.typ); err != nil || v != tc.expected {
+			t.Fatalf("%s failed: %s to %s, expected %#v but received %#v / %s", name, tc.path, tc.typ, tc.expected, v, err)
+		}
+	}
+	errorCases := []struct {
+		path string
+		typ  reflect.Type
+	}{
+		{"float", reddo.TypeInt},
+		{"neg", reddo.TypeUint},
+		{"big", reddo.TypeFloat},
+		{"bool", reddo.TypeInt},
+		{"two", reddo.TypeBool},
+		{"str", reddo.TypeInt},
+		{"floats", reflect.TypeOf([]int64{})},
+		{"ints", reflect.TypeOf(map[string]int64{})},
+		{"ts", reddo.TypeTime},
+	}
+	for _, tc := range errorCases {
+		if v, err := bo.GboGetAttr(tc.path, tc.typ); err == nil {
+			t.Fatalf("%s failed: %s to %s should fail in strict mode, received %#v", name, tc.path, tc.typ, v)
+		}
+	}
+	if _, err := bo.GboGetInt64("float"); err == nil || !strings.Contains(err.Error(), "strict mode") {
+		t.Fatalf("%s failed: expected error but received %v", name, err)
+	}
+	if v := bo.GboGetInt64Default("float", -1); v != -1 {
+		t.Fatalf("%s failed: %#v", name, v)
+	}
+	if clone := bo.GboClone().(*GenericBo); !clone.GboIsStrict() {
+		t.Fatalf("%s failed: strict mode should be cloned", name)
+	}
+
+	bo.GboSetStrict(false)
+	if v, err := bo.GboGetAttr("float", reddo.TypeInt); err != nil || v != int64(1) {
+		t.Fatalf("%s failed: %#v / %s", name, v, err)
+	}
+}
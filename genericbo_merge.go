@@ -0,0 +1,151 @@
+package godal
+
+import (
+	"sort"
+)
+
+// MergeObjectsMode specifies how GenericBo.GboMerge merges objects (maps) that exist in both BOs.
+//
+// Available since v0.7.0
+type MergeObjectsMode int
+
+const (
+	// MergeObjectsDeep merges objects recursively (default).
+	MergeObjectsDeep MergeObjectsMode = iota
+
+	// MergeObjectsReplace replaces the destination's top-level attributes with the source's ones.
+	MergeObjectsReplace
+)
+
+// MergeArraysMode specifies how GenericBo.GboMerge merges arrays that exist in both BOs.
+//
+// Available since v0.7.0
+type MergeArraysMode int
+
+const (
+	// MergeArraysReplace replaces the destination's array with the source's one (default).
+	MergeArraysReplace MergeArraysMode = iota
+
+	// MergeArraysConcat appends elements of the source's array to the destination's one.
+	MergeArraysConcat
+)
+
+// MergeConflictFunc resolves a conflict found by GenericBo.GboMerge: an attribute exists in both BOs with different
+// values that are not merged (e.g. two different strings, an object and a string, or two arrays with
+// MergeArraysReplace). It returns the value to keep; returning an error aborts the merge.
+//
+// Available since v0.7.0
+type MergeConflictFunc func(path string, dstValue, srcValue interface{}) (interface{}, error)
+
+// GboMergeStrategy specifies how GenericBo.GboMerge merges two BOs. The zero value merges objects recursively,
+// replaces arrays and lets the source win conflicts.
+//
+// Available since v0.7.0
+type GboMergeStrategy struct {
+	Objects    MergeObjectsMode  // how objects are merged
+	Arrays     MergeArraysMode   // how arrays are merged
+	OnConflict MergeConflictFunc // resolves conflicts, if nil the source's value wins
+}
+
+// GboClone returns a deep copy of the BO: the clone shares no map or slice with the original, so that both can be
+// modified independently (e.g. by different goroutines). The change-tracking snapshot, strict mode (see GboSetStrict)
+// and json.Number decoding (see GboSetUseNumber) are copied as well.
+//
+// Available since v0.7.0
+func (bo *GenericBo) GboClone() IGenericBo {
+	bo.m.RLock()
+	defer bo.m.RUnlock()
+	clone := &GenericBo{snapshot: deepCopyValue(bo.snapshot), hasSnapshot: bo.hasSnapshot, strict: bo.strict,
+		useNumber: bo.useNumber}
+	clone.setData(deepCopyValue(bo.data))
+	return clone
+}
+
+// GboEquals deeply compares the BO with another one.
+//
+// Numbers are compared by value regardless of their Go types (e.g. int64(1) equals float64(1.0)), time.Time values
+// are compared via time.Time.Equal.
+//
+// Available since v0.7.0
+func (bo *GenericBo) GboEquals(other IGenericBo) bool {
+	if other == IGenericBo(bo) {
+		return true
+	}
+	otherData := gboData(other, false)
+	bo.m.RLock()
+	defer bo.m.RUnlock()
+	return valuesEqual(bo.data, otherData)
+}
+
+// GboMerge merges attributes of 'src' into the BO according to 'strategy'. Values taken from 'src' are deep-copied.
+// Nothing is merged if 'src' is nil or empty.
+//
+// The merge is atomic: if the conflict callback returns an error, existing BO data is intact.
+//
+// Available since v0.7.0
+func (bo *GenericBo) GboMerge(src IGenericBo, strategy GboMergeStrategy) error {
+	if src == IGenericBo(bo) {
+		return nil
+	}
+	srcData := gboData(src, true)
+	if srcData == nil {
+		return nil
+	}
+	bo.m.Lock()
+	defer bo.m.Unlock()
+	dst := bo.data
+	if dst == nil {
+		dst = make(map[string]interface{})
+	}
+	result, err := mergeValues(nil, dst, srcData, strategy, true)
+	if err != nil {
+		return err
+	}
+	bo.replaceData(result)
+	return nil
+}
+
+// mergeValues merges 'src' (a deep copy owned by the merge) into 'dst' located at 'segs' and returns the result; 'dst'
+// is not modified, only the objects and arrays the merge descends into are copied (see shallowCopyContainer).
+func mergeValues(segs []pathSegment, dst, src interface{}, strategy GboMergeStrategy, root bool) (interface{}, error) {
+	dstObj, dstIsObj := asObject(dst)
+	srcObj, srcIsObj := asObject(src)
+	if dstIsObj && srcIsObj && (root || strategy.Objects == MergeObjectsDeep) {
+		result := make(map[string]interface{}, len(dstObj)+len(srcObj))
+		for k, v := range dstObj {
+			result[k] = v
+		}
+		keys := make([]string, 0, len(srcObj))
+		for k := range srcObj {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			srcV := srcObj[k]
+			dstV, ok := result[k]
+			if !ok {
+				result[k] = srcV
+				continue
+			}
+			merged, err := mergeValues(appendSegment(segs, pathSegment{key: k, index: -1}), dstV, srcV, strategy, false)
+			if err != nil {
+				return nil, err
+			}
+			result[k] = merged
+		}
+		return result, nil
+	}
+	dstArr, dstIsArr := asArray(dst)
+	srcArr, srcIsArr := asArray(src)
+	if dstIsArr && srcIsArr && strategy.Arrays == MergeArraysConcat {
+		result := make([]interface{}, 0, len(dstArr)+len(srcArr))
+		return append(append(result, dstArr...), srcArr...), nil
+	}
+	if valuesEqual(dst, src) {
+		return dst, nil
+	}
+	if strategy.OnConflict != nil {
+		return strategy.OnConflict(formatPath(segs), deepCopyValue(dst), src)
+	}
+	return src, nil
+}
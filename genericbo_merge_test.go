@@ -0,0 +1,209 @@
+package godal
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestGenericBo_GboClone(t *testing.T) {
+	name := "TestGenericBo_GboClone"
+	now := time.Now()
+	bo := NewGenericBo().(*GenericBo)
+	bo.GboImportViaMap(map[string]interface{}{
+		"name": "Thanh", "created": now, "tags": []string{"a", "b"},
+		"options": map[string]interface{}{"workhour": []interface{}{map[string]interface{}{"value": 8}}},
+	})
+	bo.GboResetDirty()
+	bo.GboSetAttr("email", "thanh@example.com")
+
+	clone := bo.GboClone().(*GenericBo)
+	if !bo.GboEquals(clone) || !reflect.DeepEqual(clone.GboChangedPaths(), []string{"email"}) {
+		t.Fatalf("%s failed: %s / %#v", name, clone.GboToJsonUnsafe(), clone.GboChangedPaths())
+	}
+	if v := clone.GboGetAttrUnsafe("created", nil); v != now {
+		t.Fatalf("%s failed: expected %#v but received %#v", name, now, v)
+	}
+
+	// nested maps and slices are not shared
+	clone.GboSetAttr("options.workhour[0].value", 9)
+	clone.GboGetAttrUnsafe("tags", nil).([]string)[0] = "x"
+	if v := bo.GboGetAttrUnsafe("options.workhour[0].value", nil); v != 8 {
+		t.Fatalf("%s failed: original BO is modified: %#v", name, v)
+	}
+	if v := bo.GboGetAttrUnsafe("tags", nil).([]string)[0]; v != "a" {
+		t.Fatalf("%s failed: original BO is modified: %#v", name, v)
+	}
+
+	// clones can be modified concurrently
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(c IGenericBo, i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				c.GboSetAttr("options.workhour[0].value", i*j)
+			}
+		}(bo.GboClone(), i)
+	}
+	wg.Wait()
+}
+
+func TestGenericBo_GboEquals(t *testing.T) {
+	name := "TestGenericBo_GboEquals"
+	now := time.Now()
+	bo1 := NewGenericBo().(*GenericBo)
+	bo1.GboImportViaMap(map[string]interface{}{"a": int64(1), "b": []int{1, 2}, "c": map[string]interface{}{"d": 1.0}, "t": now, "u": uint8(3)})
+	bo2 := NewGenericBo()
+	bo2.GboImportViaMap(map[string]interface{}{"a": 1.0, "b": []interface{}{1.0, int32(2)}, "c": map[string]int{"d": 1}, "t": now.UTC(), "u": -3 + 6})
+	if !bo1.GboEquals(bo2) || !bo1.GboEquals(bo1) {
+		t.Fatalf("%s failed: BOs should be equal", name)
+	}
+	for _, change := range []map[string]interface{}{{"a": 1.5}, {"b": []int{2, 1}}, {"c": map[string]int{"d": 1, "e": 2}}, {"t": now.Add(1)}, {"u": "3"}} {
+		bo3 := bo2.(*GenericBo).GboClone()
+		for k, v := range change {
+			bo3.GboSetAttr(k, v)
+		}
+		if bo1.GboEquals(bo3) {
+			t.Fatalf("%s failed: BOs should not be equal: %#v", name, change)
+		}
+	}
+	if bo1.GboEquals(nil) {
+		t.Fatalf("%s failed: BO should not be equal to nil", name)
+	}
+	if !NewGenericBo().(*GenericBo).GboEquals(nil) {
+		t.Fatalf("%s failed: empty BO should be equal to nil", name)
+	}
+}
+
+func TestGenericBo_GboMerge(t *testing.T) {
+	name := "TestGenericBo_GboMerge"
+	dstJson := `{"name":"Thanh","tags":["a"],"address":{"city":"HCM","street":"1 Main"},"age":30}`
+	src := NewGenericBo()
+	src.GboFromJson([]byte(`{"name":"Thanh Nguyen","tags":["b"],"address":{"city":"HN"},"email":"thanh@example.com","age":30}`))
+	testCases := []struct {
+		strategy GboMergeStrategy
+		expected string
+	}{
+		{GboMergeStrategy{},
+			`{"address":{"city":"HN","street":"1 Main"},"age":30,"email":"thanh@example.com","name":"Thanh Nguyen","tags":["b"]}`},
+		{GboMergeStrategy{Arrays: MergeArraysConcat},
+			`{"address":{"city":"HN","street":"1 Main"},"age":30,"email":"thanh@example.com","name":"Thanh Nguyen","tags":["a","b"]}`},
+		{GboMergeStrategy{Objects: MergeObjectsReplace},
+			`{"address":{"city":"HN"},"age":30,"email":"thanh@example.com","name":"Thanh Nguyen","tags":["b"]}`},
+	}
+	for i, tc := range testCases {
+		dst := NewGenericBo().(*GenericBo)
+		dst.GboFromJson([]byte(dstJson))
+		if err := dst.GboMerge(src, tc.strategy); err != nil {
+			t.Fatalf("%s failed: case %d: %s", name, i, err)
+		}
+		if js := string(dst.GboToJsonUnsafe()); js != tc.expected {
+			t.Fatalf("%s failed: case %d, expected %s but received %s", name, i, tc.expected, js)
+		}
+	}
+
+	// conflict callback: keep destination's values, except for "name"
+	var conflicts []string
+	strategy := GboMergeStrategy{OnConflict: func(path string, dstValue, srcValue interface{}) (interface{}, error) {
+		conflicts = append(conflicts, path)
+		if path == "name" {
+			return srcValue, nil
+		}
+		return dstValue, nil
+	}}
+	dst := NewGenericBo().(*GenericBo)
+	dst.GboFromJson([]byte(dstJson))
+	if err := dst.GboMerge(src, strategy); err != nil {
+		t.Fatalf("%s failed: %s", name, err)
+	}
+	expected := `{"address":{"city":"HCM","street":"1 Main"},"age":30,"email":"thanh@example.com","name":"Thanh Nguyen","tags":["a"]}`
+	if js := string(dst.GboToJsonUnsafe()); js != expected {
+		t.Fatalf("%s failed: expected %s but received %s", name, expected, js)
+	}
+	if !reflect.DeepEqual(conflicts, []string{"address.city", "name", "tags"}) {
+		t.Fatalf("%s failed: %#v", name, conflicts)
+	}
+
+	// merge is atomic
+	errConflict := errors.New("conflict")
+	dst = NewGenericBo().(*GenericBo)
+	dst.GboFromJson([]byte(dstJson))
+	err := dst.GboMerge(src, GboMergeStrategy{OnConflict: func(path string, _, _ interface{}) (interface{}, error) {
+		if path == "tags" {
+			return nil, errConflict
+		}
+		return nil, nil
+	}})
+	if err != errConflict {
+		t.Fatalf("%s failed: expected %#v but received %#v", name, errConflict, err)
+	}
+	if js := string(dst.GboToJsonUnsafe()); js != `{"address":{"city":"HCM","street":"1 Main"},"age":30,"name":"Thanh","tags":["a"]}` {
+		t.Fatalf("%s failed: BO should be intact, received %s", name, js)
+	}
+
+	// values taken from source are not shared
+	dst = NewGenericBo().(*GenericBo)
+	dst.GboMerge(src, GboMergeStrategy{})
+	dst.GboSetAttr("address.city", "DN")
+	if v := src.GboGetAttrUnsafe("address.city", nil); v != "HN" {
+		t.Fatalf("%s failed: source BO is modified: %#v", name, v)
+	}
+	if err := dst.GboMerge(nil, GboMergeStrategy{}); err != nil || dst.GboGetAttrUnsafe("address.city", nil) != "DN" {
+		t.Fatalf("%s failed: %s / %s", name, dst.GboToJsonUnsafe(), err)
+	}
+}
+
+func TestGenericBo_GboMerge_Types(t *testing.T) {
+	name := "TestGenericBo_GboMerge_Types"
+	dst := NewGenericBo().(*GenericBo)
+	dst.GboImportViaMap(map[string]interface{}{"tags": []string{"a"}, "address": map[string]string{"city": "HCM"}})
+	frozen := dst.GboFreeze()
+	src := NewGenericBo()
+	src.GboImportViaMap(map[string]interface{}{"address": map[string]interface{}{"street": "1 Main"}, "roles": []string{"admin"}})
+	if err := dst.GboMerge(src, GboMergeStrategy{}); err != nil {
+		t.Fatalf("%s failed: %s", name, err)
+	}
+	if _, ok := dst.GboGetAttrUnsafe("tags", nil).([]string); !ok {
+		t.Fatalf("%s failed: untouched attribute should keep its type, received %T", name, dst.GboGetAttrUnsafe("tags", nil))
+	}
+	if _, ok := dst.GboGetAttrUnsafe("roles", nil).([]string); !ok {
+		t.Fatalf("%s failed: merged attribute should keep its type, received %T", name, dst.GboGetAttrUnsafe("roles", nil))
+	}
+	if js := string(dst.GboToJsonUnsafe()); js != `{"address":{"city":"HCM","street":"1 Main"},"roles":["admin"],"tags":["a"]}` {
+		t.Fatalf("%s failed: received %s", name, js)
+	}
+	if js := string(frozen.GboToJsonUnsafe()); js != `{"address":{"city":"HCM"},"tags":["a"]}` {
+		t.Fatalf("%s failed: frozen view should not change, received %s", name, js)
+	}
+}
+
+func TestGenericBo_ConcurrentOther(t *testing.T) {
+	name := "TestGenericBo_ConcurrentOther"
+	src := NewGenericBo().(*GenericBo)
+	src.GboFromJson([]byte(`{"name":"Thanh","tags":["a"],"address":{"city":"HCM"}}`))
+	done := make(chan bool)
+	go func() {
+		defer close(done)
+		for i := 0; i < 1000; i++ {
+			src.GboSetAttr("address.street", strconv.Itoa(i))
+			src.GboSetAttr("tags[0]", strconv.Itoa(i))
+		}
+	}()
+	for i := 0; i < 200; i++ {
+		bo := NewGenericBo().(*GenericBo)
+		bo.GboFromJson([]byte(`{"name":"Thanh"}`))
+		bo.GboEquals(src)
+		bo.GboDiff(src)
+		if _, err := bo.GboCreateJsonPatch(src); err != nil {
+			t.Fatalf("%s failed: %s", name, err)
+		}
+		if err := bo.GboMerge(src, GboMergeStrategy{}); err != nil {
+			t.Fatalf("%s failed: %s", name, err)
+		}
+	}
+	<-done
+}
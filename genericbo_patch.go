@@ -0,0 +1,389 @@
+package godal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/btnguyen2k/consu/semita"
+)
+
+var (
+	// ErrGboPatchTestFailed is returned by GenericBo.GboApplyJsonPatch when a "test" operation fails.
+	//
+	// Available since v0.7.0
+	ErrGboPatchTestFailed = errors.New("test operation failed")
+)
+
+// JSON Patch operations, see RFC 6902.
+//
+// Available since v0.7.0
+const (
+	JsonPatchOpAdd     = "add"
+	JsonPatchOpRemove  = "remove"
+	JsonPatchOpReplace = "replace"
+	JsonPatchOpMove    = "move"
+	JsonPatchOpCopy    = "copy"
+	JsonPatchOpTest    = "test"
+)
+
+// JsonPatchOperation is an operation of a JSON Patch document (RFC 6902).
+//
+// Available since v0.7.0
+type JsonPatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// GboApplyJsonPatch applies a JSON Patch document (RFC 6902) to the BO.
+//
+// Paths are JSON Pointers (RFC 6901), e.g. "/options/workhour/0/value". The patch is applied atomically: if an
+// operation fails (including a "test" operation, see ErrGboPatchTestFailed), existing BO data is intact.
+//
+// Available since v0.7.0
+func (bo *GenericBo) GboApplyJsonPatch(patch []byte) error {
+	var ops []JsonPatchOperation
+	if err := json.Unmarshal(patch, &ops); err != nil {
+		return fmt.Errorf("json patch: %w", err)
+	}
+	bo.m.Lock()
+	defer bo.m.Unlock()
+	doc := bo.data
+	if doc == nil {
+		doc = make(map[string]interface{})
+	}
+	for i, op := range ops {
+		var err error
+		if doc, err = applyJsonPatchOperation(doc, op, bo.useNumber); err != nil {
+			return fmt.Errorf("json patch: operation #%d (%s %s): %w", i, op.Op, op.Path, err)
+		}
+	}
+	bo.replaceData(doc)
+	return nil
+}
+
+// GboApplyMergePatch applies a JSON Merge Patch document (RFC 7396) to the BO: members of the patch replace the BO's
+// attributes (objects are merged recursively), null members remove them.
+//
+// The patch is applied atomically: if it is not valid JSON, existing BO data is intact.
+//
+// Available since v0.7.0
+func (bo *GenericBo) GboApplyMergePatch(patch []byte) error {
+	bo.m.Lock()
+	defer bo.m.Unlock()
+	var p interface{}
+	if err := unmarshalJson(patch, &p, bo.useNumber); err != nil {
+		return fmt.Errorf("json merge patch: %w", err)
+	}
+	bo.replaceData(mergePatch(bo.data, p))
+	return nil
+}
+
+// GboCreateJsonPatch generates a JSON Patch document (RFC 6902) that turns this BO into 'other'.
+//
+// The result contains "add", "remove" and "replace" operations; applying it to this BO via GboApplyJsonPatch results
+// in a BO equal to 'other'.
+//
+// Available since v0.7.0
+func (bo *GenericBo) GboCreateJsonPatch(other IGenericBo) ([]byte, error) {
+	if other == IGenericBo(bo) {
+		return []byte("[]"), nil
+	}
+	otherData := gboData(other, false)
+	bo.m.RLock()
+	defer bo.m.RUnlock()
+	ops := make([]map[string]interface{}, 0)
+	diffWalk(nil, bo.data, otherData, func(op diffOp, segs []pathSegment, _, new interface{}) {
+		switch op {
+		case diffOpAdd:
+			ops = append(ops, map[string]interface{}{"op": JsonPatchOpAdd, "path": formatJsonPointer(segs), "value": new})
+		case diffOpRemove:
+			ops = append(ops, map[string]interface{}{"op": JsonPatchOpRemove, "path": formatJsonPointer(segs)})
+		default:
+			ops = append(ops, map[string]interface{}{"op": JsonPatchOpReplace, "path": formatJsonPointer(segs), "value": new})
+		}
+	})
+	return json.Marshal(ops)
+}
+
+// setData replaces the BO's data, must be called with the write-lock held.
+func (bo *GenericBo) setData(data interface{}) {
+	bo.data = data
+	bo.s = semita.NewSemita(bo.data)
+	bo.shared = false
+}
+
+// replaceData replaces the BO's data by a modified version of it, must be called with the write-lock held. Unlike
+// setData, unmodified parts of the new data may still be shared, see copyOnWrite.
+func (bo *GenericBo) replaceData(data interface{}) {
+	bo.data = data
+	bo.s = semita.NewSemita(bo.data)
+}
+
+/*----------------------------------------------------------------------*/
+
+// shallowCopyContainer returns a shallow copy of v as map[string]interface{} (maps with string keys) or []interface{}
+// (slices/arrays except []byte), the forms JSON Patch operations work on. Elements are neither copied nor converted,
+// so that values the operations do not touch keep their types.
+func shallowCopyContainer(v interface{}) (interface{}, bool) {
+	if obj, ok := asObject(v); ok {
+		result := make(map[string]interface{}, len(obj))
+		for k, e := range obj {
+			result[k] = e
+		}
+		return result, true
+	}
+	if arr, ok := asArray(v); ok {
+		result := make([]interface{}, len(arr))
+		copy(result, arr)
+		return result, true
+	}
+	return nil, false
+}
+
+// formatJsonPointer formats path segments as a JSON Pointer (RFC 6901), e.g. "/options/workhour/0/value".
+func formatJsonPointer(segs []pathSegment) string {
+	var sb strings.Builder
+	for _, seg := range segs {
+		sb.WriteString("/")
+		if seg.index >= 0 {
+			sb.WriteString(strconv.Itoa(seg.index))
+		} else {
+			sb.WriteString(strings.ReplaceAll(strings.ReplaceAll(seg.key, "~", "~0"), "/", "~1"))
+		}
+	}
+	return sb.String()
+}
+
+// parseJsonPointer splits a JSON Pointer (RFC 6901) into unescaped reference tokens.
+func parseJsonPointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("invalid JSON pointer %#v", pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+// parseArrayIndex parses an array index token; "-" (the end of the array) is accepted if allowEnd is true.
+func parseArrayIndex(token string, length int, allowEnd bool) (int, error) {
+	if token == "-" && allowEnd {
+		return length, nil
+	}
+	index, err := strconv.Atoi(token)
+	if err != nil || index < 0 || (len(token) > 1 && token[0] == '0') || token[0] == '+' {
+		return 0, fmt.Errorf("invalid array index %#v", token)
+	}
+	max := length - 1
+	if allowEnd {
+		max = length
+	}
+	if index > max {
+		return 0, fmt.Errorf("array index %d out of bounds", index)
+	}
+	return index, nil
+}
+
+// jsonPointerGet returns the value referenced by the tokens.
+func jsonPointerGet(doc interface{}, tokens []string) (interface{}, error) {
+	for _, token := range tokens {
+		if obj, ok := asObject(doc); ok {
+			v, ok := obj[token]
+			if !ok {
+				return nil, fmt.Errorf("member %#v does not exist", token)
+			}
+			doc = v
+		} else if arr, ok := asArray(doc); ok {
+			index, err := parseArrayIndex(token, len(arr), false)
+			if err != nil {
+				return nil, err
+			}
+			doc = arr[index]
+		} else {
+			return nil, fmt.Errorf("cannot reference %#v in a non-container value", token)
+		}
+	}
+	return doc, nil
+}
+
+// jsonPointerUpdate calls 'f' with a copy of the parent container of the referenced location and the last token, and
+// returns a copy of the document with the parent replaced by the result of 'f'. Only the containers along the path are
+// copied (see shallowCopyContainer), 'doc' itself is not modified.
+func jsonPointerUpdate(doc interface{}, tokens []string, f func(parent interface{}, token string) (interface{}, error)) (interface{}, error) {
+	if node, ok := shallowCopyContainer(doc); ok {
+		doc = node
+	}
+	if len(tokens) == 1 {
+		return f(doc, tokens[0])
+	}
+	child, err := jsonPointerGet(doc, tokens[:1])
+	if err != nil {
+		return nil, err
+	}
+	if child, err = jsonPointerUpdate(child, tokens[1:], f); err != nil {
+		return nil, err
+	}
+	switch node := doc.(type) {
+	case map[string]interface{}:
+		node[tokens[0]] = child
+	case []interface{}:
+		index, _ := parseArrayIndex(tokens[0], len(node), false)
+		node[index] = child
+	}
+	return doc, nil
+}
+
+func jsonPointerAdd(doc interface{}, tokens []string, value interface{}) (interface{}, error) {
+	if len(tokens) == 0 {
+		return value, nil
+	}
+	return jsonPointerUpdate(doc, tokens, func(parent interface{}, token string) (interface{}, error) {
+		switch node := parent.(type) {
+		case map[string]interface{}:
+			node[token] = value
+			return node, nil
+		case []interface{}:
+			index, err := parseArrayIndex(token, len(node), true)
+			if err != nil {
+				return nil, err
+			}
+			result := make([]interface{}, 0, len(node)+1)
+			result = append(append(append(result, node[:index]...), value), node[index:]...)
+			return result, nil
+		}
+		return nil, fmt.Errorf("cannot add %#v to a non-container value", token)
+	})
+}
+
+func jsonPointerRemove(doc interface{}, tokens []string) (interface{}, error) {
+	if len(tokens) == 0 {
+		return nil, errors.New("cannot remove the whole document")
+	}
+	return jsonPointerUpdate(doc, tokens, func(parent interface{}, token string) (interface{}, error) {
+		switch node := parent.(type) {
+		case map[string]interface{}:
+			if _, ok := node[token]; !ok {
+				return nil, fmt.Errorf("member %#v does not exist", token)
+			}
+			delete(node, token)
+			return node, nil
+		case []interface{}:
+			index, err := parseArrayIndex(token, len(node), false)
+			if err != nil {
+				return nil, err
+			}
+			return append(node[:index:index], node[index+1:]...), nil
+		}
+		return nil, fmt.Errorf("cannot remove %#v from a non-container value", token)
+	})
+}
+
+func jsonPointerReplace(doc interface{}, tokens []string, value interface{}) (interface{}, error) {
+	if len(tokens) == 0 {
+		return value, nil
+	}
+	return jsonPointerUpdate(doc, tokens, func(parent interface{}, token string) (interface{}, error) {
+		switch node := parent.(type) {
+		case map[string]interface{}:
+			if _, ok := node[token]; !ok {
+				return nil, fmt.Errorf("member %#v does not exist", token)
+			}
+			node[token] = value
+			return node, nil
+		case []interface{}:
+			index, err := parseArrayIndex(token, len(node), false)
+			if err != nil {
+				return nil, err
+			}
+			node[index] = value
+			return node, nil
+		}
+		return nil, fmt.Errorf("cannot replace %#v in a non-container value", token)
+	})
+}
+
+// applyJsonPatchOperation applies an operation to the document and returns the result; 'doc' is not modified.
+func applyJsonPatchOperation(doc interface{}, op JsonPatchOperation, useNumber bool) (interface{}, error) {
+	path, err := parseJsonPointer(op.Path)
+	if err != nil {
+		return nil, err
+	}
+	var value interface{}
+	switch op.Op {
+	case JsonPatchOpAdd, JsonPatchOpReplace, JsonPatchOpTest:
+		if len(op.Value) == 0 {
+			return nil, errors.New("missing value")
+		}
+		if err := unmarshalJson(op.Value, &value, useNumber); err != nil {
+			return nil, err
+		}
+	case JsonPatchOpMove, JsonPatchOpCopy:
+		from, err := parseJsonPointer(op.From)
+		if err != nil {
+			return nil, err
+		}
+		if value, err = jsonPointerGet(doc, from); err != nil {
+			return nil, fmt.Errorf("from: %w", err)
+		}
+		if op.Op == JsonPatchOpCopy {
+			value = deepCopyValue(value)
+			break
+		}
+		if len(path) > len(from) && strings.HasPrefix(op.Path, op.From+"/") {
+			return nil, errors.New("cannot move a value into one of its children")
+		}
+		if doc, err = jsonPointerRemove(doc, from); err != nil {
+			return nil, err
+		}
+	}
+	switch op.Op {
+	case JsonPatchOpAdd, JsonPatchOpMove, JsonPatchOpCopy:
+		return jsonPointerAdd(doc, path, value)
+	case JsonPatchOpRemove:
+		return jsonPointerRemove(doc, path)
+	case JsonPatchOpReplace:
+		return jsonPointerReplace(doc, path, value)
+	case JsonPatchOpTest:
+		current, err := jsonPointerGet(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		if !valuesEqual(current, value) {
+			return nil, ErrGboPatchTestFailed
+		}
+		return doc, nil
+	}
+	return nil, fmt.Errorf("unknown operation %#v", op.Op)
+}
+
+// mergePatch applies a JSON Merge Patch (RFC 7396) to the target and returns the result; 'target' is not modified,
+// only the objects the patch descends into are copied (see shallowCopyContainer).
+func mergePatch(target, patch interface{}) interface{} {
+	p, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	t := make(map[string]interface{})
+	if obj, ok := asObject(target); ok {
+		for k, v := range obj {
+			t[k] = v
+		}
+	}
+	for k, v := range p {
+		if v == nil {
+			delete(t, k)
+		} else {
+			t[k] = mergePatch(t[k], v)
+		}
+	}
+	return t
+}
@@ -0,0 +1,204 @@
+package godal
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestGenericBo_GboApplyJsonPatch(t *testing.T) {
+	name := "TestGenericBo_GboApplyJsonPatch"
+	// test cases from RFC 6902, appendix A
+	testCases := []struct {
+		doc, patch, expected string
+	}{
+		{`{"foo":"bar"}`, `[{"op":"add","path":"/baz","value":"qux"}]`, `{"baz":"qux","foo":"bar"}`},
+		{`{"foo":["bar","baz"]}`, `[{"op":"add","path":"/foo/1","value":"qux"}]`, `{"foo":["bar","qux","baz"]}`},
+		{`{"baz":"qux","foo":"bar"}`, `[{"op":"remove","path":"/baz"}]`, `{"foo":"bar"}`},
+		{`{"foo":["bar","qux","baz"]}`, `[{"op":"remove","path":"/foo/1"}]`, `{"foo":["bar","baz"]}`},
+		{`{"baz":"qux","foo":"bar"}`, `[{"op":"replace","path":"/baz","value":"boo"}]`, `{"baz":"boo","foo":"bar"}`},
+		{`{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"}}`, `[{"op":"move","from":"/foo/waldo","path":"/qux/thud"}]`,
+			`{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`},
+		{`{"foo":["all","grass","cows","eat"]}`, `[{"op":"move","from":"/foo/1","path":"/foo/3"}]`, `{"foo":["all","cows","eat","grass"]}`},
+		{`{"baz":"qux","foo":["a",2,"c"]}`, `[{"op":"test","path":"/baz","value":"qux"},{"op":"test","path":"/foo/1","value":2}]`, `{"baz":"qux","foo":["a",2,"c"]}`},
+		{`{"foo":"bar"}`, `[{"op":"add","path":"/child","value":{"grandchild":{}}}]`, `{"child":{"grandchild":{}},"foo":"bar"}`},
+		{`{"foo":["bar"]}`, `[{"op":"add","path":"/foo/-","value":["abc","def"]}]`, `{"foo":["bar",["abc","def"]]}`},
+		{`{"foo":null}`, `[{"op":"test","path":"/foo","value":null}]`, `{"foo":null}`},
+		{`{"/":9,"~1":10}`, `[{"op":"test","path":"/~01","value":10},{"op":"copy","from":"/~1","path":"/a~1b"}]`, `{"/":9,"a/b":9,"~1":10}`},
+		{`{"foo":"bar"}`, `[{"op":"replace","path":"","value":[1]}]`, `[1]`},
+	}
+	for i, tc := range testCases {
+		bo := NewGenericBo().(*GenericBo)
+		bo.GboFromJson([]byte(tc.doc))
+		if err := bo.GboApplyJsonPatch([]byte(tc.patch)); err != nil {
+			t.Fatalf("%s failed: case %d: %s", name, i, err)
+		}
+		if js := string(bo.GboToJsonUnsafe()); js != tc.expected {
+			t.Fatalf("%s failed: case %d, expected %s but received %s", name, i, tc.expected, js)
+		}
+	}
+
+	// empty BO
+	bo := NewGenericBo().(*GenericBo)
+	if err := bo.GboApplyJsonPatch([]byte(`[{"op":"add","path":"/a","value":1}]`)); err != nil || bo.GboGetAttrUnsafe("a", nil) != 1.0 {
+		t.Fatalf("%s failed: %s / %s", name, bo.GboToJsonUnsafe(), err)
+	}
+}
+
+func TestGenericBo_GboApplyJsonPatch_Errors(t *testing.T) {
+	name := "TestGenericBo_GboApplyJsonPatch_Errors"
+	doc := `{"baz":"qux","foo":["a",2,"c"],"obj":{"n":1}}`
+	testCases := []struct {
+		patch, message string
+	}{
+		{`{"op":"add"}`, "json patch: json: cannot unmarshal"},
+		{`[{"op":"add","path":"/x","value":1},{"op":"test","path":"/baz","value":"bar"}]`, "test operation failed"},
+		{`[{"op":"add","path":"/x","value":1},{"op":"remove","path":"/notfound"}]`, `member "notfound" does not exist`},
+		{`[{"op":"replace","path":"/foo/3","value":1}]`, "array index 3 out of bounds"},
+		{`[{"op":"add","path":"/foo/01","value":1}]`, `invalid array index "01"`},
+		{`[{"op":"add","path":"/baz/x","value":1}]`, `cannot add "x" to a non-container value`},
+		{`[{"op":"add","path":"baz","value":1}]`, `invalid JSON pointer "baz"`},
+		{`[{"op":"add","path":"/x"}]`, "missing value"},
+		{`[{"op":"move","from":"/obj","path":"/obj/child"}]`, "cannot move a value into one of its children"},
+		{`[{"op":"copy","from":"/notfound","path":"/x"}]`, `from: member "notfound" does not exist`},
+		{`[{"op":"remove","path":""}]`, "cannot remove the whole document"},
+		{`[{"op":"unknown","path":"/x"}]`, `unknown operation "unknown"`},
+	}
+	for i, tc := range testCases {
+		bo := NewGenericBo().(*GenericBo)
+		bo.GboFromJson([]byte(doc))
+		err := bo.GboApplyJsonPatch([]byte(tc.patch))
+		if err == nil || !strings.Contains(err.Error(), tc.message) {
+			t.Fatalf("%s failed: case %d, expected error %#v but received %v", name, i, tc.message, err)
+		}
+		if js := string(bo.GboToJsonUnsafe()); js != doc {
+			t.Fatalf("%s failed: case %d, BO should be intact, received %s", name, i, js)
+		}
+	}
+
+	bo := NewGenericBo().(*GenericBo)
+	bo.GboFromJson([]byte(doc))
+	if err := bo.GboApplyJsonPatch([]byte(`[{"op":"test","path":"/baz","value":"bar"}]`)); !errors.Is(err, ErrGboPatchTestFailed) {
+		t.Fatalf("%s failed: expected ErrGboPatchTestFailed but received %v", name, err)
+	}
+}
+
+func TestGenericBo_GboApplyMergePatch(t *testing.T) {
+	name := "TestGenericBo_GboApplyMergePatch"
+	// test cases from RFC 7396, appendix A
+	testCases := []struct {
+		doc, patch, expected string
+	}{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"e":null}`, `{"a":1}`, `{"a":1,"e":null}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+	}
+	for i, tc := range testCases {
+		bo := NewGenericBo().(*GenericBo)
+		bo.GboFromJson([]byte(tc.doc))
+		if err := bo.GboApplyMergePatch([]byte(tc.patch)); err != nil {
+			t.Fatalf("%s failed: case %d: %s", name, i, err)
+		}
+		if js := string(bo.GboToJsonUnsafe()); js != tc.expected {
+			t.Fatalf("%s failed: case %d, expected %s but received %s", name, i, tc.expected, js)
+		}
+	}
+
+	bo := NewGenericBo().(*GenericBo)
+	bo.GboImportViaMap(map[string]interface{}{"tags": []string{"a"}, "owner": map[string]interface{}{"name": "Thanh"}})
+	bo.GboResetDirty()
+	if err := bo.GboApplyMergePatch([]byte(`{"owner":`)); err == nil {
+		t.Fatalf("%s failed: invalid patch should be rejected", name)
+	}
+	if js := string(bo.GboToJsonUnsafe()); js != `{"owner":{"name":"Thanh"},"tags":["a"]}` {
+		t.Fatalf("%s failed: BO should be intact, received %s", name, js)
+	}
+	if err := bo.GboApplyMergePatch([]byte(`{"owner":{"email":"thanh@example.com"}}`)); err != nil {
+		t.Fatalf("%s failed: %s", name, err)
+	}
+	if paths := bo.GboChangedPaths(); len(paths) != 1 || paths[0] != "owner.email" {
+		t.Fatalf("%s failed: patched BO should be dirty, received %#v", name, paths)
+	}
+	if _, ok := bo.GboGetAttrUnsafe("tags", nil).([]string); !ok {
+		t.Fatalf("%s failed: untouched attribute should keep its type, received %T", name, bo.GboGetAttrUnsafe("tags", nil))
+	}
+
+	bo = NewGenericBo().(*GenericBo).GboSetUseNumber(true)
+	if err := bo.GboApplyMergePatch([]byte(`{"n":1.5}`)); err != nil {
+		t.Fatalf("%s failed: %s", name, err)
+	}
+	if v := bo.GboGetAttrUnsafe("n", nil); v != json.Number("1.5") {
+		t.Fatalf("%s failed: expected json.Number but received %#v", name, v)
+	}
+}
+
+func TestGenericBo_GboApplyJsonPatch_Types(t *testing.T) {
+	name := "TestGenericBo_GboApplyJsonPatch_Types"
+	bo := NewGenericBo().(*GenericBo)
+	bo.GboImportViaMap(map[string]interface{}{"tags": []string{"a", "b"}, "labels": map[string]string{"k": "v"}, "obj": map[string]interface{}{"n": 1}})
+	frozen := bo.GboFreeze()
+	if err := bo.GboApplyJsonPatch([]byte(`[{"op":"add","path":"/obj/m","value":2},{"op":"copy","from":"/labels","path":"/labels2"}]`)); err != nil {
+		t.Fatalf("%s failed: %s", name, err)
+	}
+	if _, ok := bo.GboGetAttrUnsafe("tags", nil).([]string); !ok {
+		t.Fatalf("%s failed: untouched attribute should keep its type, received %T", name, bo.GboGetAttrUnsafe("tags", nil))
+	}
+	if _, ok := bo.GboGetAttrUnsafe("labels2", nil).(map[string]string); !ok {
+		t.Fatalf("%s failed: copied attribute should keep its type, received %T", name, bo.GboGetAttrUnsafe("labels2", nil))
+	}
+	if js := string(frozen.GboToJsonUnsafe()); js != `{"labels":{"k":"v"},"obj":{"n":1},"tags":["a","b"]}` {
+		t.Fatalf("%s failed: frozen view should not change, received %s", name, js)
+	}
+	if err := bo.GboApplyJsonPatch([]byte(`[{"op":"replace","path":"/tags/1","value":"c"}]`)); err != nil {
+		t.Fatalf("%s failed: %s", name, err)
+	}
+	if js := string(bo.GboToJsonUnsafe()); js != `{"labels":{"k":"v"},"labels2":{"k":"v"},"obj":{"m":2,"n":1},"tags":["a","c"]}` {
+		t.Fatalf("%s failed: received %s", name, js)
+	}
+
+	bo = NewGenericBo().(*GenericBo).GboSetUseNumber(true)
+	if err := bo.GboApplyJsonPatch([]byte(`[{"op":"add","path":"/n","value":12345678901234567890}]`)); err != nil {
+		t.Fatalf("%s failed: %s", name, err)
+	}
+	if v := bo.GboGetAttrUnsafe("n", nil); v != json.Number("12345678901234567890") {
+		t.Fatalf("%s failed: expected json.Number but received %#v", name, v)
+	}
+}
+
+func TestGenericBo_GboCreateJsonPatch(t *testing.T) {
+	name := "TestGenericBo_GboCreateJsonPatch"
+	bo1 := NewGenericBo().(*GenericBo)
+	bo1.GboFromJson([]byte(`{"a":1,"b":{"c":[1,2,3],"d":"x"},"e/f":true,"g":[{"h":1}]}`))
+	bo2 := NewGenericBo()
+	bo2.GboFromJson([]byte(`{"a":2,"b":{"c":[1],"k":"y"},"e/f":true,"g":[{"h":1},{"h":2}],"n":null}`))
+	patch, err := bo1.GboCreateJsonPatch(bo2)
+	if err != nil {
+		t.Fatalf("%s failed: %s", name, err)
+	}
+	expected := `[{"op":"replace","path":"/a","value":2},{"op":"remove","path":"/b/c/2"},{"op":"remove","path":"/b/c/1"},` +
+		`{"op":"remove","path":"/b/d"},{"op":"add","path":"/b/k","value":"y"},{"op":"add","path":"/g/1","value":{"h":2}},` +
+		`{"op":"add","path":"/n","value":null}]`
+	if string(patch) != expected {
+		t.Fatalf("%s failed: expected %s but received %s", name, expected, patch)
+	}
+	if err := bo1.GboApplyJsonPatch(patch); err != nil {
+		t.Fatalf("%s failed: %s", name, err)
+	}
+	if diff := bo1.GboDiff(bo2); !diff.IsEmpty() {
+		t.Fatalf("%s failed: BOs should be equal after applying the patch: %s", name, diff)
+	}
+	if patch, _ := bo1.GboCreateJsonPatch(bo1); string(patch) != "[]" {
+		t.Fatalf("%s failed: %s", name, patch)
+	}
+}
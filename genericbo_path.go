@@ -0,0 +1,316 @@
+package godal
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/btnguyen2k/consu/reddo"
+	"github.com/btnguyen2k/consu/semita"
+)
+
+// PathSegment is a segment of a path to a BO's attribute: a member name if Index < 0, otherwise an array index.
+//
+// Available since v0.7.0
+type PathSegment struct {
+	Key   string
+	Index int
+}
+
+// GboPath is a path to a BO's attribute, built segment by segment so that member names may contain path's special
+// characters ('.', '[' and ']'), e.g. Path("labels").Key("example.com").
+//
+// GboPath.String() returns the path in GboGetAttr's format, member names with special characters being quoted, e.g.
+// `labels["example.com"]`. Such paths are accepted by GboGetAttr, GboSetAttr, filters and sorting of the DAOs.
+//
+// Available since v0.7.0
+type GboPath struct {
+	segs []pathSegment
+}
+
+// Path creates a new GboPath, each of 'keys' being a member name (used as-is, without parsing).
+//
+// Available since v0.7.0
+func Path(keys ...string) GboPath {
+	p := GboPath{}
+	for _, k := range keys {
+		p = p.Key(k)
+	}
+	return p
+}
+
+// ParsePath parses a path in GboGetAttr's format, e.g. `options.workhour[0].value` or `labels["example.com"].value`.
+//
+// Available since v0.7.0
+func ParsePath(path string) (GboPath, error) {
+	segs, err := parsePath(path)
+	return GboPath{segs: segs}, err
+}
+
+// Key returns a new path with member 'key' appended.
+func (p GboPath) Key(key string) GboPath {
+	return GboPath{segs: appendSegment(p.segs, pathSegment{key: key, index: -1})}
+}
+
+// Index returns a new path with array index 'index' appended.
+func (p GboPath) Index(index int) GboPath {
+	return GboPath{segs: appendSegment(p.segs, pathSegment{index: index})}
+}
+
+// Segments returns the path's segments.
+func (p GboPath) Segments() []PathSegment {
+	result := make([]PathSegment, len(p.segs))
+	for i, seg := range p.segs {
+		result[i] = PathSegment{Key: seg.key, Index: seg.index}
+	}
+	return result
+}
+
+// String returns the path in GboGetAttr's format.
+func (p GboPath) String() string {
+	return formatPath(p.segs)
+}
+
+/*----------------------------------------------------------------------*/
+
+// isQuotedPath returns true if 'path' has quoted segments, which semita does not support.
+func isQuotedPath(path string) bool {
+	return strings.ContainsAny(path, `"'`)
+}
+
+// needsQuoting returns true if 'key' must be quoted in a path.
+func needsQuoting(key string) bool {
+	return key == "" || strings.ContainsAny(key, `.[]"'\`)
+}
+
+// quotePathKey formats 'key' as a quoted path segment, e.g. `["example.com"]`.
+func quotePathKey(key string) string {
+	return `["` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(key) + `"]`
+}
+
+// parsePath parses a path in GboGetAttr's format (e.g. "options.workhour[0].value" or `labels["example.com"]`) to
+// segments.
+func parsePath(path string) ([]pathSegment, error) {
+	var segs []pathSegment
+	for pos := 0; pos < len(path); {
+		if pos > 0 && path[pos] == '.' {
+			if pos++; pos == len(path) {
+				return nil, fmt.Errorf("invalid path %q", path)
+			}
+		}
+		if path[pos] != '[' {
+			end := pos
+			for end < len(path) && path[end] != '.' && path[end] != '[' && path[end] != ']' {
+				end++
+			}
+			if end == pos || (end < len(path) && path[end] == ']') {
+				return nil, fmt.Errorf("invalid path %q", path)
+			}
+			segs = append(segs, pathSegment{key: path[pos:end], index: -1})
+			pos = end
+			continue
+		}
+		if pos++; pos < len(path) && (path[pos] == '"' || path[pos] == '\'') {
+			quote := path[pos]
+			var sb strings.Builder
+			for pos++; pos < len(path) && path[pos] != quote; pos++ {
+				if path[pos] == '\\' && pos+1 < len(path) {
+					pos++
+				}
+				sb.WriteByte(path[pos])
+			}
+			if pos+1 >= len(path) || path[pos+1] != ']' {
+				return nil, fmt.Errorf("invalid path %q", path)
+			}
+			segs = append(segs, pathSegment{key: sb.String(), index: -1})
+			pos += 2
+			continue
+		}
+		end := strings.IndexByte(path[pos:], ']')
+		if end < 0 {
+			return nil, fmt.Errorf("invalid path %q", path)
+		}
+		i, err := strconv.Atoi(path[pos : pos+end])
+		if err != nil || i < 0 {
+			return nil, fmt.Errorf("invalid index %q in path %q", path[pos:pos+end], path)
+		}
+		segs = append(segs, pathSegment{index: i})
+		pos += end + 1
+	}
+	if len(segs) == 0 {
+		return nil, fmt.Errorf("invalid path %q", path)
+	}
+	return segs, nil
+}
+
+// getPathValue returns the value located at 'segs' in 'data', or nil if not found.
+func getPathValue(data interface{}, segs []pathSegment) interface{} {
+	v := reflect.ValueOf(data)
+	for _, seg := range segs {
+		for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+			v = v.Elem()
+		}
+		switch {
+		case seg.index >= 0 && (v.Kind() == reflect.Slice || v.Kind() == reflect.Array):
+			if seg.index >= v.Len() {
+				return nil
+			}
+			v = v.Index(seg.index)
+		case seg.index < 0 && v.Kind() == reflect.Map && v.Type().Key().Kind() == reflect.String:
+			v = v.MapIndex(reflect.ValueOf(seg.key).Convert(v.Type().Key()))
+		case seg.index < 0 && v.Kind() == reflect.Struct && isExportedName(seg.key):
+			v = v.FieldByName(seg.key)
+		default:
+			return nil
+		}
+		if !v.IsValid() {
+			return nil
+		}
+	}
+	if !v.IsValid() || !v.CanInterface() {
+		return nil
+	}
+	return v.Interface()
+}
+
+// setPathValue sets 'value' at 'segs' in 'container', creating intermediate objects/arrays as needed, and returns the
+// (possibly new) container. As with semita, a nil value removes a map member or zeroes an array element.
+func setPathValue(container reflect.Value, segs []pathSegment, value reflect.Value, path string) (reflect.Value, error) {
+	result := container
+	for container.Kind() == reflect.Ptr || container.Kind() == reflect.Interface {
+		container = container.Elem()
+	}
+	seg := segs[0]
+	if !container.IsValid() {
+		if seg.index >= 0 {
+			container = reflect.ValueOf([]interface{}{})
+		} else {
+			container = reflect.ValueOf(map[string]interface{}{})
+		}
+		result = container
+	}
+	child := func(current reflect.Value, typ reflect.Type) (reflect.Value, error) {
+		if len(segs) > 1 {
+			v, err := setPathValue(current, segs[1:], value, path)
+			if err == nil && !v.Type().AssignableTo(typ) {
+				err = fmt.Errorf("path %q: cannot assign %s to %s", path, v.Type(), typ)
+			}
+			return v, err
+		}
+		if !value.IsValid() {
+			return reflect.Zero(typ), nil
+		}
+		if !value.Type().AssignableTo(typ) {
+			return value, fmt.Errorf("path %q: cannot assign %s to %s", path, value.Type(), typ)
+		}
+		return value, nil
+	}
+	switch {
+	case seg.index >= 0 && (container.Kind() == reflect.Slice || container.Kind() == reflect.Array):
+		n := container.Len()
+		if seg.index > n || (seg.index == n && container.Kind() != reflect.Slice) {
+			return result, fmt.Errorf("path %q: index %d out of bound", path, seg.index)
+		}
+		var current reflect.Value
+		if seg.index < n {
+			current = container.Index(seg.index)
+		}
+		v, err := child(current, container.Type().Elem())
+		if err != nil {
+			return result, err
+		}
+		if seg.index == n {
+			if container.Kind() == reflect.Slice && result.Kind() != reflect.Ptr {
+				return reflect.Append(container, v), nil
+			}
+			container.Set(reflect.Append(container, v))
+			return result, nil
+		}
+		if !current.CanSet() {
+			return result, fmt.Errorf("path %q: index %d is not settable", path, seg.index)
+		}
+		current.Set(v)
+	case seg.index < 0 && container.Kind() == reflect.Map && container.Type().Key().Kind() == reflect.String:
+		key := reflect.ValueOf(seg.key).Convert(container.Type().Key())
+		if !value.IsValid() && len(segs) == 1 {
+			container.SetMapIndex(key, reflect.Value{})
+			return result, nil
+		}
+		v, err := child(container.MapIndex(key), container.Type().Elem())
+		if err != nil {
+			return result, err
+		}
+		if container.IsNil() {
+			if !container.CanSet() {
+				container = reflect.MakeMap(container.Type())
+				result = container
+			} else {
+				container.Set(reflect.MakeMap(container.Type()))
+			}
+		}
+		container.SetMapIndex(key, v)
+	case seg.index < 0 && container.Kind() == reflect.Struct && isExportedName(seg.key):
+		f := container.FieldByName(seg.key)
+		if !f.IsValid() || !f.CanSet() {
+			return result, fmt.Errorf("path %q: field %s is not settable", path, seg.key)
+		}
+		v, err := child(f, f.Type())
+		if err != nil {
+			return result, err
+		}
+		f.Set(v)
+	case seg.index >= 0:
+		return result, fmt.Errorf("path %q: expecting array or slice, but it is %s", path, container.Type())
+	default:
+		return result, fmt.Errorf("path %q: expecting map or struct, but it is %s", path, container.Type())
+	}
+	return result, nil
+}
+
+// isExportedName returns true if 'name' starts with an upper-case letter.
+func isExportedName(name string) bool {
+	return name != "" && name[0] >= 'A' && name[0] <= 'Z'
+}
+
+// getQuotedPath implements GboGetAttr for paths with quoted segments, returning the value as-is.
+func (bo *GenericBo) getQuotedPath(path string) (interface{}, error) {
+	segs, err := parsePath(path)
+	if err != nil {
+		return nil, err
+	}
+	return getPathValue(bo.data, segs), nil
+}
+
+// getQuotedTime implements GboGetTimeWithLayout for paths with quoted segments, converting values the way semita does.
+func (bo *GenericBo) getQuotedTime(path, layout string) (time.Time, error) {
+	v, err := bo.getQuotedPath(path)
+	if v == nil || err != nil {
+		return time.Time{}, err
+	}
+	if t, err := reddo.ToTime(v); err == nil {
+		return t, nil
+	}
+	str, err := reddo.ToString(v)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Parse(layout, str)
+}
+
+// setQuotedPath implements GboSetAttr for paths with quoted segments.
+func (bo *GenericBo) setQuotedPath(path string, value interface{}) error {
+	segs, err := parsePath(path)
+	if err != nil {
+		return err
+	}
+	data, err := setPathValue(reflect.ValueOf(bo.data), segs, reflect.ValueOf(value), path)
+	if err != nil {
+		return err
+	}
+	// not setData: parts of the data may still be shared, see copyOnWrite
+	bo.data = data.Interface()
+	bo.s = semita.NewSemita(bo.data)
+	return nil
+}
@@ -0,0 +1,175 @@
+package godal
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParsePath(t *testing.T) {
+	name := "TestParsePath"
+	testCases := []struct {
+		path     string
+		expected []PathSegment
+	}{
+		{"a", []PathSegment{{"a", -1}}},
+		{"a.b[0].c", []PathSegment{{"a", -1}, {"b", -1}, {"", 0}, {"c", -1}}},
+		{"[1][2]", []PathSegment{{"", 1}, {"", 2}}},
+		{"a.[0]", []PathSegment{{"a", -1}, {"", 0}}},
+		{`labels["example.com"]`, []PathSegment{{"labels", -1}, {"example.com", -1}}},
+		{`labels['example.com'].value`, []PathSegment{{"labels", -1}, {"example.com", -1}, {"value", -1}}},
+		{`["a[0]"]["b\"c"]`, []PathSegment{{"a[0]", -1}, {`b"c`, -1}}},
+		{`a[""]`, []PathSegment{{"a", -1}, {"", -1}}},
+	}
+	for _, tc := range testCases {
+		p, err := ParsePath(tc.path)
+		if err != nil || !reflect.DeepEqual(p.Segments(), tc.expected) {
+			t.Fatalf("%s failed: path %q, expected %#v but received %#v / %s", name, tc.path, tc.expected, p.Segments(), err)
+		}
+	}
+
+	for _, path := range []string{"", "a..b", "a.", ".a", "a[0", "a[x]", "a[-1]", "a]", `a["b"`, `a["b]`, `a["b"c]`} {
+		if _, err := ParsePath(path); err == nil {
+			t.Fatalf("%s failed: path %q should be rejected", name, path)
+		}
+	}
+}
+
+func TestPath(t *testing.T) {
+	name := "TestPath"
+	testCases := []struct {
+		path     GboPath
+		expected string
+	}{
+		{Path("a", "b"), "a.b"},
+		{Path("a").Key("b.c").Index(2), `a["b.c"][2]`},
+		{Path().Index(0).Key("x"), "[0].x"},
+		{Path("example.com").Key("v"), `["example.com"].v`},
+		{Path("a").Key(`q"\`), `a["q\"\\"]`},
+		{Path("a").Key(""), `a[""]`},
+	}
+	for _, tc := range testCases {
+		if s := tc.path.String(); s != tc.expected {
+			t.Fatalf("%s failed: expected %q but received %q", name, tc.expected, s)
+		}
+		if p, err := ParsePath(tc.path.String()); err != nil || !reflect.DeepEqual(p.Segments(), tc.path.Segments()) {
+			t.Fatalf("%s failed: %q does not round-trip: %#v / %s", name, tc.expected, p.Segments(), err)
+		}
+	}
+
+	// paths are immutable
+	base := Path("a")
+	p1, p2 := base.Key("b"), base.Key("c")
+	if base.String() != "a" || p1.String() != "a.b" || p2.String() != "a.c" {
+		t.Fatalf("%s failed: %s / %s / %s", name, base, p1, p2)
+	}
+}
+
+func TestGenericBo_QuotedPath(t *testing.T) {
+	name := "TestGenericBo_QuotedPath"
+	bo := NewGenericBo().(*GenericBo)
+	bo.GboFromJson([]byte(`{"labels":{"example.com":"x","a":{"b":"y"}},"items":[{"k.1":1}]}`))
+	bo.GboResetDirty()
+	testCases := []struct {
+		path     string
+		expected interface{}
+	}{
+		{`labels["example.com"]`, "x"},
+		{`labels['example.com']`, "x"},
+		{`["labels"]["a"].b`, "y"},
+		{`items[0]["k.1"]`, 1.0},
+		{`labels["notfound"]`, nil},
+		{`labels["a"][0]`, nil},
+		{`items[5]["k.1"]`, nil},
+	}
+	for _, tc := range testCases {
+		if v, err := bo.GboGetAttr(tc.path, nil); err != nil || v != tc.expected {
+			t.Fatalf("%s failed: path %q, expected %#v but received %#v / %s", name, tc.path, tc.expected, v, err)
+		}
+	}
+	if v, err := bo.GboGetAttr(`items[0]["k.1"]`, reflect.TypeOf("")); err != nil || v != "1" {
+		t.Fatalf("%s failed: %#v / %s", name, v, err)
+	}
+	if _, err := bo.GboGetAttr(`labels["a`, nil); err == nil {
+		t.Fatalf("%s failed: invalid path should be rejected", name)
+	}
+
+	if err := bo.GboSetAttr(Path("labels").Key("example.org").String(), "z"); err != nil {
+		t.Fatalf("%s failed: %s", name, err)
+	}
+	if err := bo.GboSetAttr(`new["a.b"].list[0]["c.d"]`, true); err != nil {
+		t.Fatalf("%s failed: %s", name, err)
+	}
+	if err := bo.GboSetAttr(`items[1]["k.1"]`, 2); err != nil {
+		t.Fatalf("%s failed: %s", name, err)
+	}
+	if err := bo.GboSetAttr(`labels["example.com"]`, nil); err != nil {
+		t.Fatalf("%s failed: %s", name, err)
+	}
+	expected := `{"items":[{"k.1":1},{"k.1":2}],"labels":{"a":{"b":"y"},"example.org":"z"},"new":{"a.b":{"list":[{"c.d":true}]}}}`
+	if js := string(bo.GboToJsonUnsafe()); js != expected {
+		t.Fatalf("%s failed: expected %s but received %s", name, expected, js)
+	}
+	if v := bo.GboGetAttrUnsafe("labels.a.b", nil); v != "y" {
+		t.Fatalf("%s failed: plain paths should still work, received %#v", name, v)
+	}
+	expectedPaths := []string{`items[1]`, `labels["example.com"]`, `labels["example.org"]`, "new"}
+	if paths := bo.GboChangedPaths(); !reflect.DeepEqual(paths, expectedPaths) {
+		t.Fatalf("%s failed: expected %#v but received %#v", name, expectedPaths, paths)
+	}
+
+	for _, path := range []string{`items[5]["x"]`, `labels["a"][0]`, `labels["a`} {
+		if err := bo.GboSetAttr(path, 1); err == nil {
+			t.Fatalf("%s failed: path %q should be rejected", name, path)
+		}
+	}
+
+	// typed containers
+	bo2 := NewGenericBo().(*GenericBo)
+	now := time.Now()
+	bo2.GboImportViaMap(map[string]interface{}{"m": map[string]int{"a.b": 1}, "s": []string{"x"}, "t": map[string]interface{}{"t.0": now}})
+	if err := bo2.GboSetAttr(`m["c.d"]`, 2); err != nil || bo2.GboGetAttrUnsafe(`m["c.d"]`, nil) != 2 {
+		t.Fatalf("%s failed: %s", name, err)
+	}
+	if err := bo2.GboSetAttr(`m["c.d"]`, "s"); err == nil || !strings.Contains(err.Error(), "cannot assign string to int") {
+		t.Fatalf("%s failed: expected error but received %v", name, err)
+	}
+	if err := bo2.GboSetAttr(`["s"][1]`, "y"); err != nil || !reflect.DeepEqual(bo2.GboGetAttrUnsafe("s", nil), []string{"x", "y"}) {
+		t.Fatalf("%s failed: %s / %#v", name, err, bo2.GboGetAttrUnsafe("s", nil))
+	}
+	if v, err := bo2.GboGetTimeWithLayout(`t["t.0"]`, time.RFC3339); err != nil || !v.Equal(now) {
+		t.Fatalf("%s failed: %s / %s", name, v, err)
+	}
+	if v, err := bo2.GboGetTimeWithLayout(`t["t.1"]`, time.RFC3339); err != nil || !v.IsZero() {
+		t.Fatalf("%s failed: %s / %s", name, v, err)
+	}
+
+	// root array
+	bo3 := NewGenericBo()
+	if err := bo3.GboSetAttr(`[0]["a.b"]`, 1); err != nil || string(bo3.GboToJsonUnsafe()) != `[{"a.b":1}]` {
+		t.Fatalf("%s failed: %s / %s", name, err, bo3.GboToJsonUnsafe())
+	}
+}
+
+func TestGenericBo_QuotedPath_Flatten(t *testing.T) {
+	name := "TestGenericBo_QuotedPath_Flatten"
+	bo := NewGenericBo().(*GenericBo)
+	bo.GboFromJson([]byte(`{"labels":{"example.com":"x","a[0]":1},"k":{"":2}}`))
+	var paths []string
+	bo.GboWalk(func(path string, value interface{}) error {
+		paths = append(paths, path)
+		if v := bo.GboGetAttrUnsafe(path, nil); v != value {
+			t.Fatalf("%s failed: path %q, expected %#v but received %#v", name, path, value, v)
+		}
+		return nil
+	})
+	expected := []string{`k[""]`, `labels["a[0]"]`, `labels["example.com"]`}
+	if !reflect.DeepEqual(paths, expected) {
+		t.Fatalf("%s failed: expected %#v but received %#v", name, expected, paths)
+	}
+	bo2, err := NewGenericBoFromFlat(bo.GboFlatten(""), "")
+	if err != nil || !bo.GboEquals(bo2) {
+		t.Fatalf("%s failed: %s / %s", name, err, bo2.GboToJsonUnsafe())
+	}
+}
@@ -0,0 +1,727 @@
+package godal
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GboQuery evaluates a JSONPath-style query against BO data and returns all matched values, in document order (object
+// members are visited in sorted key order).
+//
+// The query language extends the path syntax of GboGetAttr:
+//
+//   - "$" is the root, it is optional: "items[0].price" is the same as "$.items[0].price"
+//   - ".name" or "['name']" selects an object member; "['a','b']" selects several members
+//   - "[2]", "[-1]" or "[0,2]" select array elements by index (negative indexes count from the end)
+//   - "[start:end:step]" selects a slice of an array, e.g. "[1:]", "[:-1]" or "[::2]"
+//   - ".*" or "[*]" selects all members of an object or all elements of an array
+//   - "..name", "..*" or "..[0]" apply the selector to the current value and all of its descendants (recursive descent)
+//   - "[?(filter)]" selects members/elements for which the filter holds, e.g. "items[?(@.qty > 2 && @.active)]"
+//
+// Filters compare operands with ==, !=, <, <=, > and >=, combine them with &&, || and !, and group with parentheses.
+// An operand is "@" (the current member/element), a relative path such as "@.price" or "@['unit price']", or a literal:
+// a number, a quoted string, true, false or null. An operand alone (e.g. "@.active") tests for existence. Numbers are
+// compared by value regardless of their Go types, strings lexicographically and time.Time values chronologically.
+//
+// Available since v0.7.0
+func (bo *GenericBo) GboQuery(expr string) ([]interface{}, error) {
+	steps, err := parseQuery(expr)
+	if err != nil {
+		return nil, err
+	}
+	bo.m.RLock()
+	defer bo.m.RUnlock()
+	matches := evalQuery(steps, bo.data, false)
+	result := make([]interface{}, 0, len(matches))
+	for _, node := range matches {
+		result = append(result, bo.exposeValue(node.value))
+	}
+	return result, nil
+}
+
+// GboSetAll sets 'value' to every location matched by the query 'expr' (see GboQuery) and returns the number of
+// updated locations. Each location receives its own deep copy of 'value'.
+//
+// If the last selector of the query is an object member (e.g. "items[*].discount"), the member is created in every
+// matched object that does not have it yet. Array elements are never created.
+//
+// The update is atomic: if 'value' can not be assigned to one of the locations (e.g. a string to a map[string]int),
+// an error is returned and existing BO data is intact.
+//
+// Available since v0.7.0
+func (bo *GenericBo) GboSetAll(expr string, value interface{}) (int, error) {
+	steps, err := parseQuery(expr)
+	if err != nil {
+		return 0, err
+	}
+	bo.m.Lock()
+	defer bo.m.Unlock()
+	bo.unshare()
+	matches := evalQuery(steps, bo.data, true)
+	values := make([]reflect.Value, len(matches))
+	for i, node := range matches {
+		if !node.parent.IsValid() {
+			continue
+		}
+		v := reflect.ValueOf(deepCopyValue(value))
+		elemType := node.parent.Type().Elem()
+		if !v.IsValid() {
+			switch elemType.Kind() {
+			case reflect.Interface, reflect.Map, reflect.Slice, reflect.Ptr:
+				v = reflect.Zero(elemType)
+			}
+		}
+		if !v.IsValid() || !v.Type().AssignableTo(elemType) {
+			return 0, fmt.Errorf("query %q: cannot assign %T to %s", expr, value, node.path())
+		}
+		if node.parent.Kind() == reflect.Array {
+			return 0, fmt.Errorf("query %q: cannot assign to %s, element of a Go array", expr, node.path())
+		}
+		values[i] = v
+	}
+	for i, node := range matches {
+		switch {
+		case !node.parent.IsValid():
+			bo.setData(deepCopyValue(value))
+		case node.parent.Kind() == reflect.Map:
+			node.parent.SetMapIndex(reflect.ValueOf(node.seg().key).Convert(node.parent.Type().Key()), values[i])
+		default:
+			node.parent.Index(node.seg().index).Set(values[i])
+		}
+	}
+	return len(matches), nil
+}
+
+/*----------------------------------------------------------------------*/
+
+type queryStepKind int
+
+const (
+	queryStepKeys queryStepKind = iota
+	queryStepIndexes
+	queryStepSlice
+	queryStepWildcard
+	queryStepFilter
+)
+
+// queryStep is a selector of a parsed query.
+type queryStep struct {
+	kind    queryStepKind
+	descend bool // recursive descent: the selector applies to the current value and all of its descendants
+	keys    []string
+	indexes []int
+	slice   [3]*int // start, end, step
+	filter  *queryFilter
+}
+
+// queryNode is a value matched by a query, along with its location.
+type queryNode struct {
+	value  interface{}
+	parent reflect.Value // the map or slice holding the value, invalid for the root
+	segs   []pathSegment // path from the root, the last segment is the value's key/index in 'parent'
+}
+
+func (n queryNode) child(value interface{}, parent reflect.Value, seg pathSegment) queryNode {
+	return queryNode{value: value, parent: parent, segs: appendSegment(n.segs, seg)}
+}
+
+func (n queryNode) seg() pathSegment {
+	return n.segs[len(n.segs)-1]
+}
+
+func (n queryNode) path() string {
+	if len(n.segs) == 0 {
+		return "$"
+	}
+	return formatPath(n.segs)
+}
+
+// queryContainer returns the map (with string keys) or slice/array (except []byte) 'v' holds, if any.
+func queryContainer(v interface{}) (reflect.Value, bool) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		rv = rv.Elem()
+	}
+	switch rv.Kind() {
+	case reflect.Map:
+		return rv, rv.Type().Key().Kind() == reflect.String
+	case reflect.Slice, reflect.Array:
+		return rv, rv.Type().Elem().Kind() != reflect.Uint8
+	}
+	return rv, false
+}
+
+// queryChildren returns all members (in sorted key order) or elements of the node's value.
+func queryChildren(node queryNode) []queryNode {
+	rv, ok := queryContainer(node.value)
+	if !ok {
+		return nil
+	}
+	var result []queryNode
+	if rv.Kind() == reflect.Map {
+		keys := make([]string, 0, rv.Len())
+		for _, k := range rv.MapKeys() {
+			keys = append(keys, k.String())
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			v := rv.MapIndex(reflect.ValueOf(k).Convert(rv.Type().Key()))
+			result = append(result, node.child(v.Interface(), rv, pathSegment{key: k, index: -1}))
+		}
+		return result
+	}
+	for i := 0; i < rv.Len(); i++ {
+		result = append(result, node.child(rv.Index(i).Interface(), rv, pathSegment{index: i}))
+	}
+	return result
+}
+
+// queryDescendants appends the node and all of its descendants (pre-order) to 'result'.
+func queryDescendants(node queryNode, result []queryNode) []queryNode {
+	result = append(result, node)
+	for _, child := range queryChildren(node) {
+		result = queryDescendants(child, result)
+	}
+	return result
+}
+
+// evalQuery evaluates parsed query steps against 'data'. If 'create' is true, missing object members selected by the
+// last step are returned as well (with nil values), so that they can be set.
+func evalQuery(steps []queryStep, data interface{}, create bool) []queryNode {
+	current := []queryNode{{value: data}}
+	for i, step := range steps {
+		var next []queryNode
+		for _, node := range current {
+			candidates := []queryNode{node}
+			if step.descend {
+				candidates = queryDescendants(node, nil)
+			}
+			for _, c := range candidates {
+				next = append(next, applyQueryStep(step, c, create && i == len(steps)-1 && !step.descend)...)
+			}
+		}
+		current = next
+	}
+	return current
+}
+
+func applyQueryStep(step queryStep, node queryNode, create bool) []queryNode {
+	switch step.kind {
+	case queryStepWildcard:
+		return queryChildren(node)
+	case queryStepFilter:
+		var result []queryNode
+		for _, child := range queryChildren(node) {
+			if step.filter.eval(child.value) {
+				result = append(result, child)
+			}
+		}
+		return result
+	}
+	rv, ok := queryContainer(node.value)
+	if !ok {
+		return nil
+	}
+	var result []queryNode
+	switch {
+	case step.kind == queryStepKeys && rv.Kind() == reflect.Map:
+		for _, k := range step.keys {
+			v := rv.MapIndex(reflect.ValueOf(k).Convert(rv.Type().Key()))
+			if v.IsValid() {
+				result = append(result, node.child(v.Interface(), rv, pathSegment{key: k, index: -1}))
+			} else if create {
+				result = append(result, node.child(nil, rv, pathSegment{key: k, index: -1}))
+			}
+		}
+	case step.kind == queryStepIndexes && rv.Kind() != reflect.Map:
+		for _, i := range step.indexes {
+			if i < 0 {
+				i += rv.Len()
+			}
+			if i >= 0 && i < rv.Len() {
+				result = append(result, node.child(rv.Index(i).Interface(), rv, pathSegment{index: i}))
+			}
+		}
+	case step.kind == queryStepSlice && rv.Kind() != reflect.Map:
+		for _, i := range sliceIndexes(step.slice, rv.Len()) {
+			result = append(result, node.child(rv.Index(i).Interface(), rv, pathSegment{index: i}))
+		}
+	}
+	return result
+}
+
+// sliceIndexes returns indexes selected by a [start:end:step] slice (Python semantics) on an array of length n.
+func sliceIndexes(slice [3]*int, n int) []int {
+	step := 1
+	if slice[2] != nil {
+		step = *slice[2]
+	}
+	if step == 0 {
+		return nil
+	}
+	bound := func(p *int, def int) int {
+		if p == nil {
+			return def
+		}
+		i := *p
+		if i < 0 {
+			i += n
+		}
+		if step > 0 {
+			return int(math.Max(0, math.Min(float64(i), float64(n))))
+		}
+		return int(math.Max(-1, math.Min(float64(i), float64(n-1))))
+	}
+	var result []int
+	if step > 0 {
+		for i, end := bound(slice[0], 0), bound(slice[1], n); i < end; i += step {
+			result = append(result, i)
+		}
+	} else {
+		for i, end := bound(slice[0], n-1), bound(slice[1], -1); i > end; i += step {
+			result = append(result, i)
+		}
+	}
+	return result
+}
+
+/*----------------------------------------------------------------------*/
+
+// queryFilter is a parsed filter expression.
+type queryFilter struct {
+	op          string // "&&", "||", "!", "exists" or a comparison operator
+	left, right *queryFilter
+	lhs, rhs    queryOperand
+}
+
+// queryOperand is either a path relative to the current value ("@...") or a literal.
+type queryOperand struct {
+	isPath  bool
+	path    []queryStep
+	literal interface{}
+}
+
+func (o queryOperand) resolve(current interface{}) (interface{}, bool) {
+	if !o.isPath {
+		return o.literal, true
+	}
+	matches := evalQuery(o.path, current, false)
+	if len(matches) == 0 {
+		return nil, false
+	}
+	return matches[0].value, true
+}
+
+func (f *queryFilter) eval(current interface{}) bool {
+	switch f.op {
+	case "&&":
+		return f.left.eval(current) && f.right.eval(current)
+	case "||":
+		return f.left.eval(current) || f.right.eval(current)
+	case "!":
+		return !f.left.eval(current)
+	case "exists":
+		_, ok := f.lhs.resolve(current)
+		return ok
+	}
+	a, okA := f.lhs.resolve(current)
+	b, okB := f.rhs.resolve(current)
+	switch f.op {
+	case "==":
+		return okA == okB && (!okA || valuesEqual(a, b))
+	case "!=":
+		return okA != okB || (okA && !valuesEqual(a, b))
+	}
+	if !okA || !okB {
+		return false
+	}
+	cmp, ok := compareQueryValues(a, b)
+	if !ok {
+		return false
+	}
+	switch f.op {
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	}
+	return cmp >= 0
+}
+
+// compareQueryValues compares two numbers, strings or time.Time values.
+func compareQueryValues(a, b interface{}) (int, bool) {
+	if equal, ok := numbersEqual(a, b); ok {
+		if equal {
+			return 0, true
+		}
+		fa, ia, ua, ka, _ := toNumber(a)
+		fb, ib, ub, kb, _ := toNumber(b)
+		switch {
+		case ka == reflect.Int64 && kb == reflect.Int64:
+			return compareOrdered(ia, ib), true
+		case ka == reflect.Uint64 && kb == reflect.Uint64:
+			return compareOrdered(ua, ub), true
+		}
+		return compareOrdered(fa, fb), !math.IsNaN(fa) && !math.IsNaN(fb)
+	}
+	if sa, ok := a.(string); ok {
+		if sb, ok := b.(string); ok {
+			return strings.Compare(sa, sb), true
+		}
+	}
+	if ta, ok := a.(time.Time); ok {
+		if tb, ok := b.(time.Time); ok {
+			return ta.Compare(tb), true
+		}
+	}
+	return 0, false
+}
+
+func compareOrdered[T int64 | uint64 | float64](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+/*----------------------------------------------------------------------*/
+
+// queryParser parses query expressions, see GenericBo.GboQuery.
+type queryParser struct {
+	expr string
+	pos  int
+}
+
+func parseQuery(expr string) ([]queryStep, error) {
+	p := &queryParser{expr: expr}
+	var steps []queryStep
+	var err error
+	if p.peek() == '$' {
+		p.pos++
+	} else if c := p.peek(); c != '.' && c != '[' && c != 0 {
+		// semita-style path: first member name without leading dot
+		name := p.parseName(false)
+		steps = append(steps, queryStep{kind: queryStepKeys, keys: []string{name}})
+	}
+	if steps, err = p.parseSteps(steps, false); err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.expr) {
+		return nil, p.errorf("unexpected character %q", p.expr[p.pos])
+	}
+	return steps, nil
+}
+
+func (p *queryParser) errorf(format string, a ...interface{}) error {
+	return fmt.Errorf("query %q: %s at position %d", p.expr, fmt.Sprintf(format, a...), p.pos)
+}
+
+func (p *queryParser) peek() byte {
+	if p.pos < len(p.expr) {
+		return p.expr[p.pos]
+	}
+	return 0
+}
+
+func (p *queryParser) skipSpaces() {
+	for p.pos < len(p.expr) && p.expr[p.pos] == ' ' {
+		p.pos++
+	}
+}
+
+// parseSteps parses selectors (".name", "..name", "[...]") until a character that does not start a selector.
+func (p *queryParser) parseSteps(steps []queryStep, inFilter bool) ([]queryStep, error) {
+	for {
+		switch p.peek() {
+		case '.':
+			p.pos++
+			descend := p.peek() == '.'
+			if descend {
+				p.pos++
+			}
+			var step queryStep
+			switch p.peek() {
+			case '*':
+				p.pos++
+				step = queryStep{kind: queryStepWildcard}
+			case '[':
+				if !descend {
+					return nil, p.errorf("unexpected character '['")
+				}
+				var err error
+				if step, err = p.parseBracket(); err != nil {
+					return nil, err
+				}
+			default:
+				name := p.parseName(inFilter)
+				if name == "" {
+					return nil, p.errorf("missing member name")
+				}
+				step = queryStep{kind: queryStepKeys, keys: []string{name}}
+			}
+			step.descend = descend
+			steps = append(steps, step)
+		case '[':
+			step, err := p.parseBracket()
+			if err != nil {
+				return nil, err
+			}
+			steps = append(steps, step)
+		default:
+			return steps, nil
+		}
+	}
+}
+
+// parseName parses a member name, which ends at '.' or '[' (and, inside filters, at spaces and operators).
+func (p *queryParser) parseName(inFilter bool) string {
+	stops := ".["
+	if inFilter {
+		stops = ".[]() =!<>&|"
+	}
+	start := p.pos
+	for p.pos < len(p.expr) && !strings.ContainsRune(stops, rune(p.expr[p.pos])) {
+		p.pos++
+	}
+	return p.expr[start:p.pos]
+}
+
+// parseBracket parses "[*]", "[?(filter)]", "['a','b']", "[0,1]" or "[start:end:step]".
+func (p *queryParser) parseBracket() (queryStep, error) {
+	p.pos++ // '['
+	p.skipSpaces()
+	var step queryStep
+	switch c := p.peek(); {
+	case c == '*':
+		p.pos++
+		step = queryStep{kind: queryStepWildcard}
+	case c == '?':
+		p.pos++
+		p.skipSpaces()
+		if p.peek() != '(' {
+			return step, p.errorf("expected '('")
+		}
+		p.pos++
+		filter, err := p.parseOr()
+		if err != nil {
+			return step, err
+		}
+		p.skipSpaces()
+		if p.peek() != ')' {
+			return step, p.errorf("expected ')'")
+		}
+		p.pos++
+		step = queryStep{kind: queryStepFilter, filter: filter}
+	case c == '\'' || c == '"':
+		step.kind = queryStepKeys
+		for {
+			key, err := p.parseQuoted()
+			if err != nil {
+				return step, err
+			}
+			step.keys = append(step.keys, key)
+			if p.skipSpaces(); p.peek() != ',' {
+				break
+			}
+			p.pos++
+			p.skipSpaces()
+		}
+	default:
+		var err error
+		if step, err = p.parseIndexesOrSlice(); err != nil {
+			return step, err
+		}
+	}
+	p.skipSpaces()
+	if p.peek() != ']' {
+		return step, p.errorf("expected ']'")
+	}
+	p.pos++
+	return step, nil
+}
+
+func (p *queryParser) parseIndexesOrSlice() (queryStep, error) {
+	step := queryStep{kind: queryStepIndexes}
+	var parts [3]*int
+	for n := 0; ; {
+		p.skipSpaces()
+		if c := p.peek(); c == '-' || (c >= '0' && c <= '9') {
+			start := p.pos
+			for p.pos++; p.peek() >= '0' && p.peek() <= '9'; p.pos++ {
+			}
+			i, err := strconv.Atoi(p.expr[start:p.pos])
+			if err != nil {
+				return step, p.errorf("invalid index %q", p.expr[start:p.pos])
+			}
+			parts[n] = &i
+			p.skipSpaces()
+		}
+		switch p.peek() {
+		case ':':
+			if n == 2 || (step.kind == queryStepIndexes && len(step.indexes) > 0) {
+				return step, p.errorf("unexpected ':'")
+			}
+			step.kind = queryStepSlice
+			n++
+			p.pos++
+			continue
+		case ',':
+			if step.kind == queryStepSlice || parts[0] == nil {
+				return step, p.errorf("unexpected ','")
+			}
+			step.indexes = append(step.indexes, *parts[0])
+			parts[0] = nil
+			p.pos++
+			continue
+		}
+		if step.kind == queryStepSlice {
+			step.slice = parts
+			return step, nil
+		}
+		if parts[0] == nil {
+			return step, p.errorf("expected index")
+		}
+		step.indexes = append(step.indexes, *parts[0])
+		return step, nil
+	}
+}
+
+// parseQuoted parses a single- or double-quoted string; backslash escapes the next character.
+func (p *queryParser) parseQuoted() (string, error) {
+	quote := p.peek()
+	p.pos++
+	var sb strings.Builder
+	for p.pos < len(p.expr) {
+		c := p.expr[p.pos]
+		p.pos++
+		switch {
+		case c == quote:
+			return sb.String(), nil
+		case c == '\\' && p.pos < len(p.expr):
+			sb.WriteByte(p.expr[p.pos])
+			p.pos++
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return "", p.errorf("unterminated string")
+}
+
+func (p *queryParser) parseOr() (*queryFilter, error) {
+	left, err := p.parseAnd()
+	for err == nil {
+		if p.skipSpaces(); !strings.HasPrefix(p.expr[p.pos:], "||") {
+			return left, nil
+		}
+		p.pos += 2
+		var right *queryFilter
+		if right, err = p.parseAnd(); err == nil {
+			left = &queryFilter{op: "||", left: left, right: right}
+		}
+	}
+	return nil, err
+}
+
+func (p *queryParser) parseAnd() (*queryFilter, error) {
+	left, err := p.parseUnary()
+	for err == nil {
+		if p.skipSpaces(); !strings.HasPrefix(p.expr[p.pos:], "&&") {
+			return left, nil
+		}
+		p.pos += 2
+		var right *queryFilter
+		if right, err = p.parseUnary(); err == nil {
+			left = &queryFilter{op: "&&", left: left, right: right}
+		}
+	}
+	return nil, err
+}
+
+func (p *queryParser) parseUnary() (*queryFilter, error) {
+	p.skipSpaces()
+	switch {
+	case p.peek() == '!' && !strings.HasPrefix(p.expr[p.pos:], "!="):
+		p.pos++
+		f, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &queryFilter{op: "!", left: f}, nil
+	case p.peek() == '(':
+		p.pos++
+		f, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.skipSpaces(); p.peek() != ')' {
+			return nil, p.errorf("expected ')'")
+		}
+		p.pos++
+		return f, nil
+	}
+	lhs, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpaces()
+	for _, op := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+		if strings.HasPrefix(p.expr[p.pos:], op) {
+			p.pos += len(op)
+			rhs, err := p.parseOperand()
+			if err != nil {
+				return nil, err
+			}
+			return &queryFilter{op: op, lhs: lhs, rhs: rhs}, nil
+		}
+	}
+	if !lhs.isPath {
+		return nil, p.errorf("expected comparison operator")
+	}
+	return &queryFilter{op: "exists", lhs: lhs}, nil
+}
+
+func (p *queryParser) parseOperand() (queryOperand, error) {
+	p.skipSpaces()
+	switch c := p.peek(); {
+	case c == '@':
+		p.pos++
+		path, err := p.parseSteps(nil, true)
+		return queryOperand{isPath: true, path: path}, err
+	case c == '\'' || c == '"':
+		s, err := p.parseQuoted()
+		return queryOperand{literal: s}, err
+	case c == '-' || c == '+' || (c >= '0' && c <= '9'):
+		start := p.pos
+		for p.pos++; p.pos < len(p.expr) && strings.ContainsRune("0123456789.eE+-", rune(p.expr[p.pos])); p.pos++ {
+		}
+		token := p.expr[start:p.pos]
+		if i, err := strconv.ParseInt(token, 10, 64); err == nil {
+			return queryOperand{literal: i}, nil
+		}
+		f, err := strconv.ParseFloat(token, 64)
+		if err != nil {
+			return queryOperand{}, p.errorf("invalid number %q", token)
+		}
+		return queryOperand{literal: f}, nil
+	}
+	for _, lit := range []struct {
+		token string
+		value interface{}
+	}{{"true", true}, {"false", false}, {"null", nil}} {
+		if strings.HasPrefix(p.expr[p.pos:], lit.token) {
+			p.pos += len(lit.token)
+			return queryOperand{literal: lit.value}, nil
+		}
+	}
+	return queryOperand{}, p.errorf("expected operand")
+}
@@ -0,0 +1,185 @@
+package godal
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const queryTestDoc = `{
+"store":{
+	"name":"Corner shop",
+	"items":[
+		{"sku":"a1","price":10,"qty":1,"tags":["new"]},
+		{"sku":"b2","price":25.5,"qty":3,"active":true},
+		{"sku":"c3","price":7,"qty":5,"active":false,"unit price":1.5},
+		{"sku":"d4","price":40,"qty":2,"active":true,"tags":["sale","new"]}
+	],
+	"owner":{"name":"Thanh","price":0}
+},
+"labels":{"example.com":"x"}
+}`
+
+func TestGenericBo_GboQuery(t *testing.T) {
+	name := "TestGenericBo_GboQuery"
+	bo := NewGenericBo().(*GenericBo)
+	if err := bo.GboFromJson([]byte(queryTestDoc)); err != nil {
+		t.Fatalf("%s failed: %s", name, err)
+	}
+	testCases := []struct {
+		expr     string
+		expected []interface{}
+	}{
+		{"store.name", []interface{}{"Corner shop"}},
+		{"$.store.items[0].sku", []interface{}{"a1"}},
+		{"store.items[*].price", []interface{}{10.0, 25.5, 7.0, 40.0}},
+		{"$.store.items.*.sku", []interface{}{"a1", "b2", "c3", "d4"}},
+		{"store.items[-1].sku", []interface{}{"d4"}},
+		{"store.items[0,2].sku", []interface{}{"a1", "c3"}},
+		{"store.items[1:3].sku", []interface{}{"b2", "c3"}},
+		{"store.items[:-2].sku", []interface{}{"a1", "b2"}},
+		{"store.items[::2].sku", []interface{}{"a1", "c3"}},
+		{"store.items[::-1].sku", []interface{}{"d4", "c3", "b2", "a1"}},
+		{"store.items[10:].sku", []interface{}{}},
+		{"$..price", []interface{}{10.0, 25.5, 7.0, 40.0, 0.0}},
+		{"$.store..tags[0]", []interface{}{"new", "sale"}},
+		{"$..[?(@.sku == 'b2')].qty", []interface{}{3.0}},
+		{"$.labels['example.com']", []interface{}{"x"}},
+		{`$.store.owner["name","price"]`, []interface{}{"Thanh", 0.0}},
+		{"store.items[?(@.qty > 2)].sku", []interface{}{"b2", "c3"}},
+		{"store.items[?(@.qty >= 2 && @.price < 30)].sku", []interface{}{"b2", "c3"}},
+		{"store.items[?(@.price == 10 || @.price == 40)].sku", []interface{}{"a1", "d4"}},
+		{"store.items[?(@.active)].sku", []interface{}{"b2", "c3", "d4"}},
+		{"store.items[?(!@.active)].sku", []interface{}{"a1"}},
+		{"store.items[?(@.active == true)].sku", []interface{}{"b2", "d4"}},
+		{"store.items[?(@.active != true)].sku", []interface{}{"a1", "c3"}},
+		{"store.items[?(@.sku > 'b9')].sku", []interface{}{"c3", "d4"}},
+		{"store.items[?(@['unit price'] < 2)].sku", []interface{}{"c3"}},
+		{"store.items[?(@.tags[0] == 'new')].sku", []interface{}{"a1"}},
+		{"store.items[?(2 < @.qty && !(@.price > 20))].sku", []interface{}{"c3"}},
+		{"store.items[?(@.price > @.qty * 2)].sku", nil},
+		{"store.items[0].tags[?(@ == 'new')]", []interface{}{"new"}},
+		{"store.notfound[*]", []interface{}{}},
+		{"store.name[0]", []interface{}{}},
+	}
+	for _, tc := range testCases {
+		result, err := bo.GboQuery(tc.expr)
+		if tc.expected == nil {
+			if err == nil {
+				t.Fatalf("%s failed: query %q should fail", name, tc.expr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s failed: query %q: %s", name, tc.expr, err)
+		}
+		if !reflect.DeepEqual(result, tc.expected) {
+			t.Fatalf("%s failed: query %q, expected %#v but received %#v", name, tc.expr, tc.expected, result)
+		}
+	}
+
+	// typed containers and values
+	now := time.Now()
+	bo2 := NewGenericBo().(*GenericBo)
+	bo2.GboImportViaMap(map[string]interface{}{
+		"scores": map[string]int{"a": 1, "b": 3},
+		"events": []map[string]interface{}{{"at": now.Add(-time.Hour)}, {"at": now.Add(time.Hour)}},
+		"avatar": []byte("abc"),
+	})
+	if result, _ := bo2.GboQuery("scores[?(@ > 1)]"); !reflect.DeepEqual(result, []interface{}{3}) {
+		t.Fatalf("%s failed: %#v", name, result)
+	}
+	if result, _ := bo2.GboQuery("$..at"); len(result) != 2 {
+		t.Fatalf("%s failed: %#v", name, result)
+	}
+	if result, _ := bo2.GboQuery("avatar[*]"); len(result) != 0 {
+		t.Fatalf("%s failed: []byte should not be queried as an array: %#v", name, result)
+	}
+}
+
+func TestGenericBo_GboQuery_Errors(t *testing.T) {
+	name := "TestGenericBo_GboQuery_Errors"
+	bo := NewGenericBo().(*GenericBo)
+	bo.GboFromJson([]byte(queryTestDoc))
+	testCases := []struct {
+		expr, message string
+	}{
+		{"store.", "missing member name"},
+		{"store.items[", "expected index"},
+		{"store.items[0", "expected ']'"},
+		{"store.items[1:2:3:4]", "unexpected ':'"},
+		{"store.items[0,1:2]", "unexpected ':'"},
+		{"store.items['a", "unterminated string"},
+		{"store.items[?(@.qty > )]", "expected operand"},
+		{"store.items[?(@.qty > 1]", "expected ')'"},
+		{"store.items[?@.qty]", "expected '('"},
+		{"store.items[?(1)]", "expected comparison operator"},
+		{"store.items[?(@.qty > 1-)]", `invalid number "1-"`},
+		{"$store", "unexpected character 's'"},
+		{"$.[0]", "unexpected character '['"},
+	}
+	for _, tc := range testCases {
+		if _, err := bo.GboQuery(tc.expr); err == nil || !strings.Contains(err.Error(), tc.message) {
+			t.Fatalf("%s failed: query %q, expected error %#v but received %v", name, tc.expr, tc.message, err)
+		}
+	}
+}
+
+func TestGenericBo_GboSetAll(t *testing.T) {
+	name := "TestGenericBo_GboSetAll"
+	bo := NewGenericBo().(*GenericBo)
+	bo.GboFromJson([]byte(queryTestDoc))
+	bo.GboResetDirty()
+
+	if n, err := bo.GboSetAll("store.items[?(@.qty > 2)].discount", map[string]interface{}{"rate": 0.1}); err != nil || n != 2 {
+		t.Fatalf("%s failed: %d / %s", name, n, err)
+	}
+	if result, _ := bo.GboQuery("store.items[*].discount.rate"); !reflect.DeepEqual(result, []interface{}{0.1, 0.1}) {
+		t.Fatalf("%s failed: %#v", name, result)
+	}
+	bo.GboSetAttr("store.items[1].discount.rate", 0.2)
+	if v := bo.GboGetAttrUnsafe("store.items[2].discount.rate", nil); v != 0.1 {
+		t.Fatalf("%s failed: matched locations should not share values, received %#v", name, v)
+	}
+
+	if n, err := bo.GboSetAll("$..price", 1); err != nil || n != 5 {
+		t.Fatalf("%s failed: %d / %s", name, n, err)
+	}
+	if result, _ := bo.GboQuery("$..price"); !reflect.DeepEqual(result, []interface{}{1, 1, 1, 1, 1}) {
+		t.Fatalf("%s failed: %#v", name, result)
+	}
+	if n, _ := bo.GboSetAll("store.items[10]", 1); n != 0 {
+		t.Fatalf("%s failed: array elements should not be created", name)
+	}
+	expected := []string{"store.items[0].price", "store.items[1].discount", "store.items[1].price", "store.items[2].discount",
+		"store.items[2].price", "store.items[3].price", "store.owner.price"}
+	if paths := bo.GboChangedPaths(); !reflect.DeepEqual(paths, expected) {
+		t.Fatalf("%s failed: expected %#v but received %#v", name, expected, paths)
+	}
+
+	if n, err := bo.GboSetAll("$", map[string]interface{}{"a": 1}); err != nil || n != 1 || string(bo.GboToJsonUnsafe()) != `{"a":1}` {
+		t.Fatalf("%s failed: %d / %s / %s", name, n, err, bo.GboToJsonUnsafe())
+	}
+
+	// typed containers: the update is atomic
+	bo.GboImportViaMap(map[string]interface{}{"a": map[string]interface{}{"n": 1}, "b": map[string]int{"n": 2}, "c": []string{"x"}})
+	if _, err := bo.GboSetAll("$..n", "s"); err == nil || !strings.Contains(err.Error(), "cannot assign string to b.n") {
+		t.Fatalf("%s failed: expected error but received %v", name, err)
+	}
+	if v := bo.GboGetAttrUnsafe("a.n", nil); v != 1 {
+		t.Fatalf("%s failed: BO should be intact, received %#v", name, v)
+	}
+	if n, err := bo.GboSetAll("$..n", 3); err != nil || n != 2 || bo.GboGetAttrUnsafe("b.n", nil) != 3 {
+		t.Fatalf("%s failed: %d / %s / %s", name, n, err, bo.GboToJsonUnsafe())
+	}
+	if n, err := bo.GboSetAll("c[*]", "y"); err != nil || n != 1 || bo.GboGetAttrUnsafe("c[0]", nil) != "y" {
+		t.Fatalf("%s failed: %d / %s / %s", name, n, err, bo.GboToJsonUnsafe())
+	}
+	if _, err := bo.GboSetAll("c[*]", nil); err == nil {
+		t.Fatalf("%s failed: nil should not be assigned to a string element", name)
+	}
+	if _, err := bo.GboSetAll("c[", nil); err == nil {
+		t.Fatalf("%s failed: invalid query should be rejected", name)
+	}
+}
@@ -0,0 +1,207 @@
+package godal
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// GboWalk visits every leaf of BO data, in document order (object members in sorted key order), calling 'callback'
+// with the leaf's full path in GboGetAttr's format (e.g. "options.workhour[0].value") and its value. Leaves are
+// non-container values, empty objects and empty arrays; []byte values are leaves.
+//
+// Walking stops at the first error returned by 'callback', and that error is returned. Leaves are collected before
+// 'callback' is called, so 'callback' may safely read or modify the BO.
+//
+// Available since v0.7.0
+func (bo *GenericBo) GboWalk(callback func(path string, value interface{}) error) error {
+	type leaf struct {
+		path  string
+		value interface{}
+	}
+	var leaves []leaf
+	bo.m.RLock()
+	walkLeaves(nil, bo.data, func(segs []pathSegment, value interface{}) {
+		leaves = append(leaves, leaf{path: formatPath(segs), value: bo.exposeValue(value)})
+	})
+	bo.m.RUnlock()
+	for _, l := range leaves {
+		if err := callback(l.path, l.value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GboFlatten returns BO data as a flat map of {leaf-path: leaf-value}, see GboWalk for what leaves are.
+//
+// If 'sep' is empty, keys are paths in GboGetAttr's format (e.g. "options.workhour[0].value"). Otherwise, keys are
+// path segments joined by 'sep', array indexes being written as numbers (e.g. "options_workhour_0_value" with
+// sep = "_"), which suits CSV headers or search-index fields.
+//
+// Available since v0.7.0
+func (bo *GenericBo) GboFlatten(sep string) map[string]interface{} {
+	result := make(map[string]interface{})
+	bo.m.RLock()
+	defer bo.m.RUnlock()
+	walkLeaves(nil, bo.data, func(segs []pathSegment, value interface{}) {
+		result[formatFlatKey(segs, sep)] = bo.exposeValue(value)
+	})
+	return result
+}
+
+// NewGenericBoFromFlat creates a new BO from a flat map produced by GboFlatten with the same 'sep'.
+//
+// If 'sep' is empty, keys are parsed as paths in GboGetAttr's format. Otherwise, keys are split by 'sep' and objects
+// whose members are exactly "0", "1"... "n-1" become arrays. An error is returned if keys conflict, e.g. "a" and "a.b"
+// (as "a" can not be both a leaf and an object).
+//
+// Available since v0.7.0
+func NewGenericBoFromFlat(flat map[string]interface{}, sep string) (IGenericBo, error) {
+	keys := make([]string, 0, len(flat))
+	for k := range flat {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	root := &flatNode{}
+	for _, k := range keys {
+		var segs []pathSegment
+		if sep == "" {
+			var err error
+			if segs, err = parsePath(k); err != nil {
+				return nil, err
+			}
+		} else {
+			for _, key := range strings.Split(k, sep) {
+				segs = append(segs, pathSegment{key: key, index: -1})
+			}
+		}
+		if err := root.insert(segs, flat[k]); err != nil {
+			return nil, fmt.Errorf("key %q: %w", k, err)
+		}
+	}
+	var data interface{} = map[string]interface{}{}
+	if len(keys) > 0 {
+		data = root.build(sep != "")
+	}
+	bo := &GenericBo{}
+	bo.setData(data)
+	return bo, nil
+}
+
+/*----------------------------------------------------------------------*/
+
+// walkLeaves calls 'emit' for every leaf of 'v' (see GenericBo.GboWalk), 'segs' being v's path.
+func walkLeaves(segs []pathSegment, v interface{}, emit func(segs []pathSegment, value interface{})) {
+	if obj, ok := asObject(v); ok && len(obj) > 0 {
+		keys := make([]string, 0, len(obj))
+		for k := range obj {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			walkLeaves(appendSegment(segs, pathSegment{key: k, index: -1}), obj[k], emit)
+		}
+		return
+	}
+	if arr, ok := asArray(v); ok && len(arr) > 0 {
+		for i, e := range arr {
+			walkLeaves(appendSegment(segs, pathSegment{index: i}), e, emit)
+		}
+		return
+	}
+	emit(segs, v)
+}
+
+// formatFlatKey formats path segments as a key of GenericBo.GboFlatten's result.
+func formatFlatKey(segs []pathSegment, sep string) string {
+	if sep == "" {
+		return formatPath(segs)
+	}
+	parts := make([]string, len(segs))
+	for i, seg := range segs {
+		if seg.index >= 0 {
+			parts[i] = strconv.Itoa(seg.index)
+		} else {
+			parts[i] = seg.key
+		}
+	}
+	return strings.Join(parts, sep)
+}
+
+// flatNode is an intermediate tree used to build BO data from flat keys.
+type flatNode struct {
+	isLeaf   bool
+	value    interface{}
+	isArray  bool
+	children map[string]*flatNode // keyed by member names, or by indexes for arrays
+	maxIndex int
+}
+
+func (n *flatNode) insert(segs []pathSegment, value interface{}) error {
+	if len(segs) == 0 {
+		if n.isLeaf || n.children != nil {
+			return fmt.Errorf("conflicting keys")
+		}
+		n.isLeaf, n.value = true, value
+		return nil
+	}
+	if n.isLeaf {
+		return fmt.Errorf("conflicting keys")
+	}
+	seg, isIndex := segs[0], segs[0].index >= 0
+	if n.children == nil {
+		n.isArray, n.children = isIndex, make(map[string]*flatNode)
+	} else if n.isArray != isIndex {
+		return fmt.Errorf("conflicting keys: array and object")
+	}
+	key := seg.key
+	if isIndex {
+		key = strconv.Itoa(seg.index)
+		if seg.index > n.maxIndex {
+			n.maxIndex = seg.index
+		}
+	}
+	child, ok := n.children[key]
+	if !ok {
+		child = &flatNode{}
+		n.children[key] = child
+	}
+	return child.insert(segs[1:], value)
+}
+
+// build converts the tree to BO data; if 'numericArrays' is true, objects with members "0".."n-1" become arrays.
+func (n *flatNode) build(numericArrays bool) interface{} {
+	if n.isLeaf {
+		return n.value
+	}
+	if n.isArray {
+		result := make([]interface{}, n.maxIndex+1)
+		for k, child := range n.children {
+			i, _ := strconv.Atoi(k)
+			result[i] = child.build(numericArrays)
+		}
+		return result
+	}
+	if numericArrays && len(n.children) > 0 {
+		result := make([]interface{}, len(n.children))
+		isArray := true
+		for i := range result {
+			child, ok := n.children[strconv.Itoa(i)]
+			if !ok {
+				isArray = false
+				break
+			}
+			result[i] = child.build(numericArrays)
+		}
+		if isArray {
+			return result
+		}
+	}
+	result := make(map[string]interface{}, len(n.children))
+	for k, child := range n.children {
+		result[k] = child.build(numericArrays)
+	}
+	return result
+}
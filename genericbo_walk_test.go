@@ -0,0 +1,131 @@
+package godal
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const walkTestDoc = `{"name":{"first":"Thanh","last":"Nguyen"},"age":30,"tags":["a","b"],"options":{"workhour":[{"value":8},{"value":4}],"empty":{}},"list":[]}`
+
+func TestGenericBo_GboWalk(t *testing.T) {
+	name := "TestGenericBo_GboWalk"
+	bo := NewGenericBo().(*GenericBo)
+	bo.GboFromJson([]byte(walkTestDoc))
+	var paths []string
+	err := bo.GboWalk(func(path string, value interface{}) error {
+		paths = append(paths, path)
+		if v := bo.GboGetAttrUnsafe(path, nil); !reflect.DeepEqual(v, value) {
+			t.Fatalf("%s failed: path %q, expected %#v but received %#v", name, path, v, value)
+		}
+		return nil
+	})
+	expected := []string{"age", "list", "name.first", "name.last", "options.empty", "options.workhour[0].value",
+		"options.workhour[1].value", "tags[0]", "tags[1]"}
+	if err != nil || !reflect.DeepEqual(paths, expected) {
+		t.Fatalf("%s failed: expected %#v but received %#v / %s", name, expected, paths, err)
+	}
+
+	// callback may modify the BO, error stops walking
+	errStop := errors.New("stop")
+	count := 0
+	err = bo.GboWalk(func(path string, value interface{}) error {
+		if count++; strings.HasPrefix(path, "name.") {
+			bo.GboSetAttr(path, strings.ToUpper(value.(string)))
+			return errStop
+		}
+		return nil
+	})
+	if err != errStop || count != 3 || bo.GboGetAttrUnsafe("name.first", nil) != "THANH" {
+		t.Fatalf("%s failed: %d / %s / %s", name, count, err, bo.GboToJsonUnsafe())
+	}
+
+	// typed containers
+	bo.GboImportViaMap(map[string]interface{}{"m": map[string]int{"x": 1}, "s": []string{"y"}, "b": []byte("z")})
+	paths = nil
+	bo.GboWalk(func(path string, _ interface{}) error {
+		paths = append(paths, path)
+		return nil
+	})
+	if !reflect.DeepEqual(paths, []string{"b", "m.x", "s[0]"}) {
+		t.Fatalf("%s failed: %#v", name, paths)
+	}
+}
+
+func TestGenericBo_GboFlatten(t *testing.T) {
+	name := "TestGenericBo_GboFlatten"
+	bo := NewGenericBo().(*GenericBo)
+	bo.GboFromJson([]byte(walkTestDoc))
+	for _, sep := range []string{"", ".", "_", "__"} {
+		flat := bo.GboFlatten(sep)
+		if len(flat) != 9 {
+			t.Fatalf("%s failed: sep %q, expected 9 keys but received %#v", name, sep, flat)
+		}
+		bo2, err := NewGenericBoFromFlat(flat, sep)
+		if err != nil {
+			t.Fatalf("%s failed: sep %q: %s", name, sep, err)
+		}
+		if !bo.GboEquals(bo2) {
+			t.Fatalf("%s failed: sep %q: %s", name, sep, bo.GboDiff(bo2))
+		}
+	}
+	flat := bo.GboFlatten("")
+	if flat["options.workhour[1].value"] != 4.0 || flat["name.first"] != "Thanh" {
+		t.Fatalf("%s failed: %#v", name, flat)
+	}
+	flat = bo.GboFlatten("_")
+	if flat["options_workhour_1_value"] != 4.0 || flat["tags_0"] != "a" {
+		t.Fatalf("%s failed: %#v", name, flat)
+	}
+
+	// root array
+	bo.GboFromJson([]byte(`[{"a":1},[2]]`))
+	for _, sep := range []string{"", "."} {
+		if bo2, err := NewGenericBoFromFlat(bo.GboFlatten(sep), sep); err != nil || !bo.GboEquals(bo2) {
+			t.Fatalf("%s failed: sep %q: %s / %s", name, sep, err, bo2.GboToJsonUnsafe())
+		}
+	}
+}
+
+func TestNewGenericBoFromFlat(t *testing.T) {
+	name := "TestNewGenericBoFromFlat"
+	testCases := []struct {
+		flat     map[string]interface{}
+		sep      string
+		expected string
+	}{
+		{map[string]interface{}{}, "", `{}`},
+		{map[string]interface{}{"a[2]": 1, "a[0]": 0}, "", `{"a":[0,null,1]}`},
+		{map[string]interface{}{"a.0": 1, "a.2": 2}, ".", `{"a":{"0":1,"2":2}}`},
+		{map[string]interface{}{"a.00": 1}, ".", `{"a":{"00":1}}`},
+		{map[string]interface{}{"a/b": 1, "a/c/0/d": true}, "/", `{"a":{"b":1,"c":[{"d":true}]}}`},
+	}
+	for i, tc := range testCases {
+		bo, err := NewGenericBoFromFlat(tc.flat, tc.sep)
+		if err != nil {
+			t.Fatalf("%s failed: case %d: %s", name, i, err)
+		}
+		if js := string(bo.GboToJsonUnsafe()); js != tc.expected {
+			t.Fatalf("%s failed: case %d, expected %s but received %s", name, i, tc.expected, js)
+		}
+	}
+
+	errorCases := []struct {
+		flat    map[string]interface{}
+		sep     string
+		message string
+	}{
+		{map[string]interface{}{"a": 1, "a.b": 2}, "", `key "a.b": conflicting keys`},
+		{map[string]interface{}{"a": 1, "a_b": 2}, "_", `key "a_b": conflicting keys`},
+		{map[string]interface{}{"a[0]": 1, "a.b": 2}, "", "array and object"},
+		{map[string]interface{}{"a[x]": 1}, "", `invalid index "x"`},
+		{map[string]interface{}{"a..b": 1}, "", `invalid path "a..b"`},
+		{map[string]interface{}{"a[0": 1}, "", `invalid path "a[0"`},
+	}
+	for i, tc := range errorCases {
+		if _, err := NewGenericBoFromFlat(tc.flat, tc.sep); err == nil || !strings.Contains(err.Error(), tc.message) {
+			t.Fatalf("%s failed: case %d, expected error %#v but received %v", name, i, tc.message, err)
+		}
+	}
+}
@@ -1,6 +1,11 @@
 package godal
 
-import "errors"
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
 
 // IRowMapper transforms a database row to IGenericBo and vice versa.
 //
@@ -31,6 +36,27 @@ type IRowMapper interface {
 var (
 	// ErrGdaoDuplicatedEntry indicates that the write operation failed because of data integrity violation: entry/key duplicated.
 	ErrGdaoDuplicatedEntry = errors.New("data integrity violation: duplicated entry/key")
+
+	// ErrGdaoCircuitOpen indicates that the operation was rejected without reaching the database store because the circuit breaker is open.
+	//
+	// Available since v0.7.0
+	ErrGdaoCircuitOpen = errors.New("circuit breaker is open")
+
+	// ErrGdaoShardKeyMissing indicates that the operation could not be routed to a shard because the shard key could not be determined.
+	//
+	// Available since v0.7.0
+	ErrGdaoShardKeyMissing = errors.New("shard key is missing")
+
+	// ErrGdaoTenantMissing indicates that the operation was rejected because the context does not carry a tenant id.
+	//
+	// Available since v0.7.0
+	ErrGdaoTenantMissing = errors.New("tenant id is missing from context")
+
+	// ErrGdaoTenantIdInvalid indicates that the operation was rejected because the tenant id carried by the context
+	// contains characters other than letters, digits, '_' and '-' (see IsValidTenantId).
+	//
+	// Available since v0.7.0
+	ErrGdaoTenantIdInvalid = errors.New("tenant id is invalid")
 )
 
 // IGenericDao defines API interface of a generic data-access-object.
@@ -101,7 +127,11 @@ func NewAbstractGenericDao(gdao IGenericDao) *AbstractGenericDao {
 //   - (n) GdaoSave(storageId string, bo IGenericBo) (int, error)
 type AbstractGenericDao struct {
 	IGenericDao
-	rowMapper IRowMapper
+	rowMapper    IRowMapper
+	autoFields   map[string]AutoFields
+	funcNow      func() time.Time
+	idGenerators map[string]storageIdGenerator
+	schemas      map[string]*Schema
 }
 
 // GetRowMapper implements IGenericDao.GetRowMapper.
@@ -118,3 +148,172 @@ func (dao *AbstractGenericDao) SetRowMapper(rowMapper IRowMapper) *AbstractGener
 	dao.rowMapper = rowMapper
 	return dao
 }
+
+/*----------------------------------------------------------------------*/
+
+// IGenericDaoWithContext is an optional API interface for DAOs that accept a context.Context along with each operation.
+//
+// GenericDaoMongo, GenericDaoDynamodb, GenericDaoSql and GenericDaoCosmosdb implement this interface. Use ToGenericDaoWithContext to obtain an
+// IGenericDaoWithContext from any IGenericDao.
+//
+// Available since v0.7.0
+type IGenericDaoWithContext interface {
+	IGenericDao
+
+	// GdaoDeleteWithContext is context-aware variant of GdaoDelete.
+	GdaoDeleteWithContext(ctx context.Context, storageId string, bo IGenericBo) (int, error)
+
+	// GdaoDeleteManyWithContext is context-aware variant of GdaoDeleteMany.
+	GdaoDeleteManyWithContext(ctx context.Context, storageId string, filter FilterOpt) (int, error)
+
+	// GdaoFetchOneWithContext is context-aware variant of GdaoFetchOne.
+	GdaoFetchOneWithContext(ctx context.Context, storageId string, filter FilterOpt) (IGenericBo, error)
+
+	// GdaoFetchManyWithContext is context-aware variant of GdaoFetchMany.
+	GdaoFetchManyWithContext(ctx context.Context, storageId string, filter FilterOpt, sorting *SortingOpt, startOffset, numItems int) ([]IGenericBo, error)
+
+	// GdaoCreateWithContext is context-aware variant of GdaoCreate.
+	GdaoCreateWithContext(ctx context.Context, storageId string, bo IGenericBo) (int, error)
+
+	// GdaoUpdateWithContext is context-aware variant of GdaoUpdate.
+	GdaoUpdateWithContext(ctx context.Context, storageId string, bo IGenericBo) (int, error)
+
+	// GdaoSaveWithContext is context-aware variant of GdaoSave.
+	GdaoSaveWithContext(ctx context.Context, storageId string, bo IGenericBo) (int, error)
+}
+
+// ToGenericDaoWithContext returns the input DAO as an IGenericDaoWithContext.
+//
+// If the input DAO does not implement IGenericDaoWithContext, it is wrapped by an adapter that ignores the context.
+//
+// Available since v0.7.0
+func ToGenericDaoWithContext(dao IGenericDao) IGenericDaoWithContext {
+	if dao == nil {
+		return nil
+	}
+	if ctxDao, ok := dao.(IGenericDaoWithContext); ok {
+		return ctxDao
+	}
+	return &genericDaoContextAdapter{IGenericDao: dao}
+}
+
+// genericDaoContextAdapter adapts an IGenericDao to IGenericDaoWithContext, the context is ignored.
+type genericDaoContextAdapter struct {
+	IGenericDao
+}
+
+// GdaoDeleteWithContext implements IGenericDaoWithContext.GdaoDeleteWithContext.
+func (a *genericDaoContextAdapter) GdaoDeleteWithContext(_ context.Context, storageId string, bo IGenericBo) (int, error) {
+	return a.GdaoDelete(storageId, bo)
+}
+
+// GdaoDeleteManyWithContext implements IGenericDaoWithContext.GdaoDeleteManyWithContext.
+func (a *genericDaoContextAdapter) GdaoDeleteManyWithContext(_ context.Context, storageId string, filter FilterOpt) (int, error) {
+	return a.GdaoDeleteMany(storageId, filter)
+}
+
+// GdaoFetchOneWithContext implements IGenericDaoWithContext.GdaoFetchOneWithContext.
+func (a *genericDaoContextAdapter) GdaoFetchOneWithContext(_ context.Context, storageId string, filter FilterOpt) (IGenericBo, error) {
+	return a.GdaoFetchOne(storageId, filter)
+}
+
+// GdaoFetchManyWithContext implements IGenericDaoWithContext.GdaoFetchManyWithContext.
+func (a *genericDaoContextAdapter) GdaoFetchManyWithContext(_ context.Context, storageId string, filter FilterOpt, sorting *SortingOpt, startOffset, numItems int) ([]IGenericBo, error) {
+	return a.GdaoFetchMany(storageId, filter, sorting, startOffset, numItems)
+}
+
+// GdaoCreateWithContext implements IGenericDaoWithContext.GdaoCreateWithContext.
+func (a *genericDaoContextAdapter) GdaoCreateWithContext(_ context.Context, storageId string, bo IGenericBo) (int, error) {
+	return a.GdaoCreate(storageId, bo)
+}
+
+// GdaoUpdateWithContext implements IGenericDaoWithContext.GdaoUpdateWithContext.
+func (a *genericDaoContextAdapter) GdaoUpdateWithContext(_ context.Context, storageId string, bo IGenericBo) (int, error) {
+	return a.GdaoUpdate(storageId, bo)
+}
+
+// GdaoSaveWithContext implements IGenericDaoWithContext.GdaoSaveWithContext.
+func (a *genericDaoContextAdapter) GdaoSaveWithContext(_ context.Context, storageId string, bo IGenericBo) (int, error) {
+	return a.GdaoSave(storageId, bo)
+}
+
+/*----------------------------------------------------------------------*/
+
+type ctxKeyWriteCondition struct{}
+
+// WithWriteCondition returns a copy of the parent context that carries an extra condition for write operations.
+// If the parent context already carries a condition, both conditions must be satisfied.
+//
+// Context-aware DAOs that honor the condition (sql.GenericDaoSql, mongo.GenericDaoMongo, dynamodb.GenericDaoDynamodb
+// and cosmosdbsql.GenericDaoCosmosdb) combine it with the filter built by GdaoCreateFilter, so that the condition is
+// checked by the same statement that writes the BO:
+//   - GdaoUpdateWithContext and GdaoDeleteWithContext do not touch the existing BO if it does not satisfy the condition.
+//   - GdaoSaveWithContext does not replace the existing BO if it does not satisfy the condition; the insert that
+//     follows fails (e.g. with ErrGdaoDuplicatedEntry) because the BO exists.
+//
+// Available since v0.7.0
+func WithWriteCondition(ctx context.Context, condition FilterOpt) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if existing := WriteConditionFromContext(ctx); existing != nil {
+		condition = (&FilterOptAnd{}).Add(existing).Add(condition)
+	}
+	return context.WithValue(ctx, ctxKeyWriteCondition{}, condition)
+}
+
+// WriteConditionFromContext returns the extra write condition carried by the context (see WithWriteCondition), or nil.
+//
+// Available since v0.7.0
+func WriteConditionFromContext(ctx context.Context) FilterOpt {
+	if ctx == nil {
+		return nil
+	}
+	condition, _ := ctx.Value(ctxKeyWriteCondition{}).(FilterOpt)
+	return condition
+}
+
+// ApplyWriteCondition combines the filter with the extra write condition carried by the context (see WithWriteCondition).
+// The filter is returned as-is if the context carries no condition.
+//
+// Available since v0.7.0
+func ApplyWriteCondition(ctx context.Context, filter FilterOpt) FilterOpt {
+	condition := WriteConditionFromContext(ctx)
+	if condition == nil {
+		return filter
+	}
+	return (&FilterOptAnd{}).Add(filter).Add(condition)
+}
+
+/*----------------------------------------------------------------------*/
+
+// IGenericDaoWithTx is an optional API interface for DAOs that can perform operations within a database/sql transaction.
+// If tx is nil, the operation is performed outside of any transaction.
+//
+// sql.GenericDaoSql and cosmosdbsql.GenericDaoCosmosdb implement this interface.
+//
+// Available since v0.7.0
+type IGenericDaoWithTx interface {
+	IGenericDao
+
+	// GdaoDeleteWithTx is database/sql variant of GdaoDelete.
+	GdaoDeleteWithTx(ctx context.Context, tx *sql.Tx, storageId string, bo IGenericBo) (int, error)
+
+	// GdaoDeleteManyWithTx is database/sql variant of GdaoDeleteMany.
+	GdaoDeleteManyWithTx(ctx context.Context, tx *sql.Tx, storageId string, filter FilterOpt) (int, error)
+
+	// GdaoFetchOneWithTx is database/sql variant of GdaoFetchOne.
+	GdaoFetchOneWithTx(ctx context.Context, tx *sql.Tx, storageId string, filter FilterOpt) (IGenericBo, error)
+
+	// GdaoFetchManyWithTx is database/sql variant of GdaoFetchMany.
+	GdaoFetchManyWithTx(ctx context.Context, tx *sql.Tx, storageId string, filter FilterOpt, sorting *SortingOpt, startOffset, numItems int) ([]IGenericBo, error)
+
+	// GdaoCreateWithTx is database/sql variant of GdaoCreate.
+	GdaoCreateWithTx(ctx context.Context, tx *sql.Tx, storageId string, bo IGenericBo) (int, error)
+
+	// GdaoUpdateWithTx is database/sql variant of GdaoUpdate.
+	GdaoUpdateWithTx(ctx context.Context, tx *sql.Tx, storageId string, bo IGenericBo) (int, error)
+
+	// GdaoSaveWithTx is database/sql variant of GdaoSave.
+	GdaoSaveWithTx(ctx context.Context, tx *sql.Tx, storageId string, bo IGenericBo) (int, error)
+}
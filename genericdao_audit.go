@@ -0,0 +1,580 @@
+package godal
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+)
+
+type ctxKeyActor struct{}
+
+// WithActor returns a copy of the parent context that carries the actor (e.g. id of the user performing the operation).
+//
+// Available since v0.7.0
+func WithActor(ctx context.Context, actor string) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithValue(ctx, ctxKeyActor{}, actor)
+}
+
+// ActorFromContext returns the actor carried by the context.
+//
+// Available since v0.7.0
+func ActorFromContext(ctx context.Context) (string, bool) {
+	if ctx == nil {
+		return "", false
+	}
+	actor, ok := ctx.Value(ctxKeyActor{}).(string)
+	return actor, ok && actor != ""
+}
+
+// ErrGdaoTxNotSupported is returned when a transactional operation is requested but the underlying DAO does not implement IGenericDaoWithTx.
+//
+// Available since v0.7.0
+var ErrGdaoTxNotSupported = errors.New("DAO does not support database/sql transactions")
+
+/*----------------------------------------------------------------------*/
+
+// daoExecutor performs operations on a DAO either with a context, or within a database/sql transaction.
+type daoExecutor interface {
+	fetchOne(storageId string, filter FilterOpt) (IGenericBo, error)
+	fetchMany(storageId string, filter FilterOpt) ([]IGenericBo, error)
+	create(storageId string, bo IGenericBo) (int, error)
+	update(storageId string, bo IGenericBo) (int, error)
+	save(storageId string, bo IGenericBo) (int, error)
+	delete(storageId string, bo IGenericBo) (int, error)
+	deleteMany(storageId string, filter FilterOpt) (int, error)
+}
+
+type ctxExecutor struct {
+	ctx context.Context
+	dao IGenericDaoWithContext
+}
+
+func (e *ctxExecutor) fetchOne(storageId string, filter FilterOpt) (IGenericBo, error) {
+	return e.dao.GdaoFetchOneWithContext(e.ctx, storageId, filter)
+}
+
+func (e *ctxExecutor) fetchMany(storageId string, filter FilterOpt) ([]IGenericBo, error) {
+	return e.dao.GdaoFetchManyWithContext(e.ctx, storageId, filter, nil, 0, 0)
+}
+
+func (e *ctxExecutor) create(storageId string, bo IGenericBo) (int, error) {
+	return e.dao.GdaoCreateWithContext(e.ctx, storageId, bo)
+}
+
+func (e *ctxExecutor) update(storageId string, bo IGenericBo) (int, error) {
+	return e.dao.GdaoUpdateWithContext(e.ctx, storageId, bo)
+}
+
+func (e *ctxExecutor) save(storageId string, bo IGenericBo) (int, error) {
+	return e.dao.GdaoSaveWithContext(e.ctx, storageId, bo)
+}
+
+func (e *ctxExecutor) delete(storageId string, bo IGenericBo) (int, error) {
+	return e.dao.GdaoDeleteWithContext(e.ctx, storageId, bo)
+}
+
+func (e *ctxExecutor) deleteMany(storageId string, filter FilterOpt) (int, error) {
+	return e.dao.GdaoDeleteManyWithContext(e.ctx, storageId, filter)
+}
+
+type txExecutor struct {
+	ctx context.Context
+	tx  *sql.Tx
+	dao IGenericDaoWithTx
+}
+
+func (e *txExecutor) fetchOne(storageId string, filter FilterOpt) (IGenericBo, error) {
+	return e.dao.GdaoFetchOneWithTx(e.ctx, e.tx, storageId, filter)
+}
+
+func (e *txExecutor) fetchMany(storageId string, filter FilterOpt) ([]IGenericBo, error) {
+	return e.dao.GdaoFetchManyWithTx(e.ctx, e.tx, storageId, filter, nil, 0, 0)
+}
+
+func (e *txExecutor) create(storageId string, bo IGenericBo) (int, error) {
+	return e.dao.GdaoCreateWithTx(e.ctx, e.tx, storageId, bo)
+}
+
+func (e *txExecutor) update(storageId string, bo IGenericBo) (int, error) {
+	return e.dao.GdaoUpdateWithTx(e.ctx, e.tx, storageId, bo)
+}
+
+func (e *txExecutor) save(storageId string, bo IGenericBo) (int, error) {
+	return e.dao.GdaoSaveWithTx(e.ctx, e.tx, storageId, bo)
+}
+
+func (e *txExecutor) delete(storageId string, bo IGenericBo) (int, error) {
+	return e.dao.GdaoDeleteWithTx(e.ctx, e.tx, storageId, bo)
+}
+
+func (e *txExecutor) deleteMany(storageId string, filter FilterOpt) (int, error) {
+	return e.dao.GdaoDeleteManyWithTx(e.ctx, e.tx, storageId, filter)
+}
+
+/*----------------------------------------------------------------------*/
+
+// AuditMode specifies what an audit record captures. Modes can be combined, e.g. AuditModeSnapshot | AuditModeDiff.
+//
+// Available since v0.7.0
+type AuditMode int
+
+const (
+	// AuditModeSnapshot captures the BO before and after the change.
+	AuditModeSnapshot AuditMode = 1 << iota
+
+	// AuditModeDiff captures the list of changed fields.
+	AuditModeDiff
+)
+
+// Field names of audit records.
+//
+// Available since v0.7.0
+const (
+	AuditFieldId        = "id"
+	AuditFieldStorageId = "storage_id"
+	AuditFieldOperation = "op"
+	AuditFieldKey       = "key"
+	AuditFieldBefore    = "before"
+	AuditFieldAfter     = "after"
+	AuditFieldDiff      = "diff"
+	AuditFieldActor     = "actor"
+	AuditFieldTimestamp = "timestamp"
+)
+
+// AuditFieldChange describes a changed field in an audit record's diff.
+//
+// Available since v0.7.0
+type AuditFieldChange struct {
+	Path   string      `json:"path"`
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// gboToData returns the BO's data as a JSON-compatible value.
+func gboToData(bo IGenericBo) (interface{}, error) {
+	if bo == nil {
+		return nil, nil
+	}
+	var data interface{}
+	err := bo.GboTransferViaJson(&data)
+	return data, err
+}
+
+// diffData compares two JSON-compatible values and appends changed paths (dot-notation) to the result.
+// Nested maps are compared field by field, other values (including arrays) are compared as a whole.
+func diffData(path string, before, after interface{}, result []AuditFieldChange) []AuditFieldChange {
+	mb, okb := before.(map[string]interface{})
+	ma, oka := after.(map[string]interface{})
+	if !okb || !oka {
+		if !reflect.DeepEqual(before, after) {
+			result = append(result, AuditFieldChange{Path: path, Before: before, After: after})
+		}
+		return result
+	}
+	keys := make([]string, 0, len(mb)+len(ma))
+	for k := range mb {
+		keys = append(keys, k)
+	}
+	for k := range ma {
+		if _, ok := mb[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		p := k
+		if path != "" {
+			p = path + "." + k
+		}
+		result = diffData(p, mb[k], ma[k], result)
+	}
+	return result
+}
+
+// filterToKey extracts a {field:value} map from the equality conditions of a key filter.
+// If the filter is not a combination of equality conditions, its string representation (see FilterToCacheKey) is returned.
+func filterToKey(filter FilterOpt) interface{} {
+	key := make(map[string]interface{})
+	var collect func(f FilterOpt) bool
+	collect = func(f FilterOpt) bool {
+		switch v := f.(type) {
+		case FilterOptFieldOpValue:
+			return collect(&v)
+		case *FilterOptFieldOpValue:
+			if v == nil || v.Operator != FilterOpEqual {
+				return false
+			}
+			key[v.FieldName] = v.Value
+			return true
+		case FilterOptAnd:
+			return collect(&v)
+		case *FilterOptAnd:
+			if v == nil {
+				return false
+			}
+			for _, inner := range v.Filters {
+				if !collect(inner) {
+					return false
+				}
+			}
+			return true
+		}
+		return false
+	}
+	if collect(filter) {
+		return key
+	}
+	return FilterToCacheKey(filter)
+}
+
+func defaultAuditIdGenerator() string {
+	buf := make([]byte, 8)
+	_, _ = rand.Read(buf)
+	return fmt.Sprintf("%016x%s", time.Now().UnixNano(), hex.EncodeToString(buf))
+}
+
+/*----------------------------------------------------------------------*/
+
+// NewAuditGenericDao constructs a new AuditGenericDao.
+//
+//   - dao: the DAO whose changes are audited.
+//   - auditDao: the DAO that stores audit records, can be the same as dao.
+//   - auditStorageId: storage that audit records are written to.
+//
+// Default settings: records capture before/after snapshots, record ids are generated as <timestamp-in-hex><random-hex>.
+//
+// Available since v0.7.0
+func NewAuditGenericDao(dao, auditDao IGenericDao, auditStorageId string) *AuditGenericDao {
+	return &AuditGenericDao{
+		origDao:        dao,
+		dao:            ToGenericDaoWithContext(dao),
+		origAuditDao:   auditDao,
+		auditDao:       ToGenericDaoWithContext(auditDao),
+		auditStorageId: auditStorageId,
+		mode:           AuditModeSnapshot,
+		idGenerator:    defaultAuditIdGenerator,
+		funcNow:        time.Now,
+	}
+}
+
+// AuditGenericDao is an IGenericDao wrapper that writes an audit record for every successful create, update, save and
+// delete operation. Each record is a BO with the following fields:
+//   - AuditFieldId: generated record id.
+//   - AuditFieldStorageId: storage of the changed BO.
+//   - AuditFieldOperation: "create", "update", "save" or "delete".
+//   - AuditFieldKey: key of the changed BO, as a {field:value} map built from GdaoCreateFilter.
+//   - AuditFieldBefore & AuditFieldAfter: snapshots of the BO before and after the change (AuditModeSnapshot).
+//   - AuditFieldDiff: list of AuditFieldChange (AuditModeDiff).
+//   - AuditFieldActor: actor carried by the context (see WithActor).
+//   - AuditFieldTimestamp: time of the change.
+//
+// GdaoUpdate, GdaoSave, GdaoDelete and GdaoDeleteMany read the existing BOs to capture "before" snapshots.
+//
+// The GdaoXxxWithTx methods perform the operation, the reads and the audit writes within the same database/sql
+// transaction; both dao and auditDao must implement IGenericDaoWithTx (e.g. sql.GenericDaoSql) and use the same database.
+// Otherwise, if writing the audit record fails, the change has already been persisted and the error is returned along
+// with the number of affected rows.
+//
+// Available since v0.7.0
+type AuditGenericDao struct {
+	origDao        IGenericDao
+	dao            IGenericDaoWithContext
+	origAuditDao   IGenericDao
+	auditDao       IGenericDaoWithContext
+	auditStorageId string
+	mode           AuditMode
+	idGenerator    func() string
+	funcNow        func() time.Time
+}
+
+// GetDao returns the underlying DAO.
+func (dao *AuditGenericDao) GetDao() IGenericDao {
+	return dao.origDao
+}
+
+// GetAuditDao returns the DAO that stores audit records.
+func (dao *AuditGenericDao) GetAuditDao() IGenericDao {
+	return dao.origAuditDao
+}
+
+// GetAuditStorageId returns the storage that audit records are written to.
+func (dao *AuditGenericDao) GetAuditStorageId() string {
+	return dao.auditStorageId
+}
+
+// GetAuditMode returns what audit records capture.
+func (dao *AuditGenericDao) GetAuditMode() AuditMode {
+	return dao.mode
+}
+
+// SetAuditMode sets what audit records capture. Default value is AuditModeSnapshot.
+func (dao *AuditGenericDao) SetAuditMode(mode AuditMode) *AuditGenericDao {
+	dao.mode = mode
+	return dao
+}
+
+// SetIdGenerator sets the function that generates audit record ids.
+func (dao *AuditGenericDao) SetIdGenerator(f func() string) *AuditGenericDao {
+	dao.idGenerator = f
+	return dao
+}
+
+func (dao *AuditGenericDao) ctxExecutors(ctx context.Context) (daoExecutor, daoExecutor) {
+	return &ctxExecutor{ctx: ctx, dao: dao.dao}, &ctxExecutor{ctx: ctx, dao: dao.auditDao}
+}
+
+func (dao *AuditGenericDao) txExecutors(ctx context.Context, tx *sql.Tx) (daoExecutor, daoExecutor, error) {
+	txDao, ok1 := dao.origDao.(IGenericDaoWithTx)
+	txAuditDao, ok2 := dao.origAuditDao.(IGenericDaoWithTx)
+	if !ok1 || !ok2 {
+		return nil, nil, ErrGdaoTxNotSupported
+	}
+	return &txExecutor{ctx: ctx, tx: tx, dao: txDao}, &txExecutor{ctx: ctx, tx: tx, dao: txAuditDao}, nil
+}
+
+// record writes an audit record.
+func (dao *AuditGenericDao) record(ctx context.Context, auditExec daoExecutor, op, storageId string, key interface{}, before, after IGenericBo) error {
+	dataBefore, err := gboToData(before)
+	if err != nil {
+		return err
+	}
+	dataAfter, err := gboToData(after)
+	if err != nil {
+		return err
+	}
+	rec := NewGenericBo()
+	_ = rec.GboSetAttr(AuditFieldId, dao.idGenerator())
+	_ = rec.GboSetAttr(AuditFieldStorageId, storageId)
+	_ = rec.GboSetAttr(AuditFieldOperation, op)
+	_ = rec.GboSetAttr(AuditFieldKey, key)
+	if dao.mode&AuditModeSnapshot != 0 {
+		_ = rec.GboSetAttr(AuditFieldBefore, dataBefore)
+		_ = rec.GboSetAttr(AuditFieldAfter, dataAfter)
+	}
+	if dao.mode&AuditModeDiff != 0 {
+		diff := make([]interface{}, 0)
+		for _, change := range diffData("", dataBefore, dataAfter, nil) {
+			var m interface{}
+			js, _ := json.Marshal(change)
+			_ = json.Unmarshal(js, &m)
+			diff = append(diff, m)
+		}
+		_ = rec.GboSetAttr(AuditFieldDiff, diff)
+	}
+	if actor, ok := ActorFromContext(ctx); ok {
+		_ = rec.GboSetAttr(AuditFieldActor, actor)
+	}
+	_ = rec.GboSetAttr(AuditFieldTimestamp, dao.funcNow())
+	_, err = auditExec.create(dao.auditStorageId, rec)
+	return err
+}
+
+// write performs a create/update/save operation and records it.
+func (dao *AuditGenericDao) write(ctx context.Context, exec, auditExec daoExecutor, op, storageId string, bo IGenericBo,
+	f func(storageId string, bo IGenericBo) (int, error)) (int, error) {
+	var before IGenericBo
+	if op != "create" {
+		var err error
+		if before, err = exec.fetchOne(storageId, dao.dao.GdaoCreateFilter(storageId, bo)); err != nil {
+			return 0, err
+		}
+	}
+	numRows, err := f(storageId, bo)
+	if err != nil || numRows == 0 {
+		return numRows, err
+	}
+	// the key is built after the write, which may populate key fields (e.g. generated ids)
+	keyFilter := dao.dao.GdaoCreateFilter(storageId, bo)
+	return numRows, dao.record(ctx, auditExec, op, storageId, filterToKey(keyFilter), before, bo)
+}
+
+func (dao *AuditGenericDao) deleteOne(ctx context.Context, exec, auditExec daoExecutor, storageId string, bo IGenericBo) (int, error) {
+	keyFilter := dao.dao.GdaoCreateFilter(storageId, bo)
+	before, err := exec.fetchOne(storageId, keyFilter)
+	if err != nil {
+		return 0, err
+	}
+	numRows, err := exec.delete(storageId, bo)
+	if err != nil || numRows == 0 {
+		return numRows, err
+	}
+	return numRows, dao.record(ctx, auditExec, "delete", storageId, filterToKey(keyFilter), before, nil)
+}
+
+func (dao *AuditGenericDao) deleteMany(ctx context.Context, exec, auditExec daoExecutor, storageId string, filter FilterOpt) (int, error) {
+	boList, err := exec.fetchMany(storageId, filter)
+	if err != nil {
+		return 0, err
+	}
+	numRows, err := exec.deleteMany(storageId, filter)
+	if err != nil || numRows == 0 {
+		return numRows, err
+	}
+	for _, before := range boList {
+		key := filterToKey(dao.dao.GdaoCreateFilter(storageId, before))
+		if err := dao.record(ctx, auditExec, "delete", storageId, key, before, nil); err != nil {
+			return numRows, err
+		}
+	}
+	return numRows, nil
+}
+
+// GdaoCreateFilter implements IGenericDao.GdaoCreateFilter.
+func (dao *AuditGenericDao) GdaoCreateFilter(storageId string, bo IGenericBo) FilterOpt {
+	return dao.dao.GdaoCreateFilter(storageId, bo)
+}
+
+// GetRowMapper implements IGenericDao.GetRowMapper.
+func (dao *AuditGenericDao) GetRowMapper() IRowMapper {
+	return dao.dao.GetRowMapper()
+}
+
+// GdaoDelete implements IGenericDao.GdaoDelete.
+func (dao *AuditGenericDao) GdaoDelete(storageId string, bo IGenericBo) (int, error) {
+	return dao.GdaoDeleteWithContext(nil, storageId, bo)
+}
+
+// GdaoDeleteWithContext implements IGenericDaoWithContext.GdaoDeleteWithContext.
+func (dao *AuditGenericDao) GdaoDeleteWithContext(ctx context.Context, storageId string, bo IGenericBo) (int, error) {
+	exec, auditExec := dao.ctxExecutors(ctx)
+	return dao.deleteOne(ctx, exec, auditExec, storageId, bo)
+}
+
+// GdaoDeleteWithTx implements IGenericDaoWithTx.GdaoDeleteWithTx.
+func (dao *AuditGenericDao) GdaoDeleteWithTx(ctx context.Context, tx *sql.Tx, storageId string, bo IGenericBo) (int, error) {
+	exec, auditExec, err := dao.txExecutors(ctx, tx)
+	if err != nil {
+		return 0, err
+	}
+	return dao.deleteOne(ctx, exec, auditExec, storageId, bo)
+}
+
+// GdaoDeleteMany implements IGenericDao.GdaoDeleteMany.
+//
+// Matched BOs are fetched before being deleted, and one audit record is written per deleted BO.
+func (dao *AuditGenericDao) GdaoDeleteMany(storageId string, filter FilterOpt) (int, error) {
+	return dao.GdaoDeleteManyWithContext(nil, storageId, filter)
+}
+
+// GdaoDeleteManyWithContext implements IGenericDaoWithContext.GdaoDeleteManyWithContext.
+func (dao *AuditGenericDao) GdaoDeleteManyWithContext(ctx context.Context, storageId string, filter FilterOpt) (int, error) {
+	exec, auditExec := dao.ctxExecutors(ctx)
+	return dao.deleteMany(ctx, exec, auditExec, storageId, filter)
+}
+
+// GdaoDeleteManyWithTx implements IGenericDaoWithTx.GdaoDeleteManyWithTx.
+func (dao *AuditGenericDao) GdaoDeleteManyWithTx(ctx context.Context, tx *sql.Tx, storageId string, filter FilterOpt) (int, error) {
+	exec, auditExec, err := dao.txExecutors(ctx, tx)
+	if err != nil {
+		return 0, err
+	}
+	return dao.deleteMany(ctx, exec, auditExec, storageId, filter)
+}
+
+// GdaoFetchOne implements IGenericDao.GdaoFetchOne.
+func (dao *AuditGenericDao) GdaoFetchOne(storageId string, filter FilterOpt) (IGenericBo, error) {
+	return dao.GdaoFetchOneWithContext(nil, storageId, filter)
+}
+
+// GdaoFetchOneWithContext implements IGenericDaoWithContext.GdaoFetchOneWithContext.
+func (dao *AuditGenericDao) GdaoFetchOneWithContext(ctx context.Context, storageId string, filter FilterOpt) (IGenericBo, error) {
+	return dao.dao.GdaoFetchOneWithContext(ctx, storageId, filter)
+}
+
+// GdaoFetchOneWithTx implements IGenericDaoWithTx.GdaoFetchOneWithTx.
+func (dao *AuditGenericDao) GdaoFetchOneWithTx(ctx context.Context, tx *sql.Tx, storageId string, filter FilterOpt) (IGenericBo, error) {
+	exec, _, err := dao.txExecutors(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+	return exec.fetchOne(storageId, filter)
+}
+
+// GdaoFetchMany implements IGenericDao.GdaoFetchMany.
+func (dao *AuditGenericDao) GdaoFetchMany(storageId string, filter FilterOpt, sorting *SortingOpt, startOffset, numItems int) ([]IGenericBo, error) {
+	return dao.GdaoFetchManyWithContext(nil, storageId, filter, sorting, startOffset, numItems)
+}
+
+// GdaoFetchManyWithContext implements IGenericDaoWithContext.GdaoFetchManyWithContext.
+func (dao *AuditGenericDao) GdaoFetchManyWithContext(ctx context.Context, storageId string, filter FilterOpt, sorting *SortingOpt, startOffset, numItems int) ([]IGenericBo, error) {
+	return dao.dao.GdaoFetchManyWithContext(ctx, storageId, filter, sorting, startOffset, numItems)
+}
+
+// GdaoFetchManyWithTx implements IGenericDaoWithTx.GdaoFetchManyWithTx.
+func (dao *AuditGenericDao) GdaoFetchManyWithTx(ctx context.Context, tx *sql.Tx, storageId string, filter FilterOpt, sorting *SortingOpt, startOffset, numItems int) ([]IGenericBo, error) {
+	txDao, ok := dao.origDao.(IGenericDaoWithTx)
+	if !ok {
+		return nil, ErrGdaoTxNotSupported
+	}
+	return txDao.GdaoFetchManyWithTx(ctx, tx, storageId, filter, sorting, startOffset, numItems)
+}
+
+// GdaoCreate implements IGenericDao.GdaoCreate.
+func (dao *AuditGenericDao) GdaoCreate(storageId string, bo IGenericBo) (int, error) {
+	return dao.GdaoCreateWithContext(nil, storageId, bo)
+}
+
+// GdaoCreateWithContext implements IGenericDaoWithContext.GdaoCreateWithContext.
+func (dao *AuditGenericDao) GdaoCreateWithContext(ctx context.Context, storageId string, bo IGenericBo) (int, error) {
+	exec, auditExec := dao.ctxExecutors(ctx)
+	return dao.write(ctx, exec, auditExec, "create", storageId, bo, exec.create)
+}
+
+// GdaoCreateWithTx implements IGenericDaoWithTx.GdaoCreateWithTx.
+func (dao *AuditGenericDao) GdaoCreateWithTx(ctx context.Context, tx *sql.Tx, storageId string, bo IGenericBo) (int, error) {
+	exec, auditExec, err := dao.txExecutors(ctx, tx)
+	if err != nil {
+		return 0, err
+	}
+	return dao.write(ctx, exec, auditExec, "create", storageId, bo, exec.create)
+}
+
+// GdaoUpdate implements IGenericDao.GdaoUpdate.
+func (dao *AuditGenericDao) GdaoUpdate(storageId string, bo IGenericBo) (int, error) {
+	return dao.GdaoUpdateWithContext(nil, storageId, bo)
+}
+
+// GdaoUpdateWithContext implements IGenericDaoWithContext.GdaoUpdateWithContext.
+func (dao *AuditGenericDao) GdaoUpdateWithContext(ctx context.Context, storageId string, bo IGenericBo) (int, error) {
+	exec, auditExec := dao.ctxExecutors(ctx)
+	return dao.write(ctx, exec, auditExec, "update", storageId, bo, exec.update)
+}
+
+// GdaoUpdateWithTx implements IGenericDaoWithTx.GdaoUpdateWithTx.
+func (dao *AuditGenericDao) GdaoUpdateWithTx(ctx context.Context, tx *sql.Tx, storageId string, bo IGenericBo) (int, error) {
+	exec, auditExec, err := dao.txExecutors(ctx, tx)
+	if err != nil {
+		return 0, err
+	}
+	return dao.write(ctx, exec, auditExec, "update", storageId, bo, exec.update)
+}
+
+// GdaoSave implements IGenericDao.GdaoSave.
+func (dao *AuditGenericDao) GdaoSave(storageId string, bo IGenericBo) (int, error) {
+	return dao.GdaoSaveWithContext(nil, storageId, bo)
+}
+
+// GdaoSaveWithContext implements IGenericDaoWithContext.GdaoSaveWithContext.
+func (dao *AuditGenericDao) GdaoSaveWithContext(ctx context.Context, storageId string, bo IGenericBo) (int, error) {
+	exec, auditExec := dao.ctxExecutors(ctx)
+	return dao.write(ctx, exec, auditExec, "save", storageId, bo, exec.save)
+}
+
+// GdaoSaveWithTx implements IGenericDaoWithTx.GdaoSaveWithTx.
+func (dao *AuditGenericDao) GdaoSaveWithTx(ctx context.Context, tx *sql.Tx, storageId string, bo IGenericBo) (int, error) {
+	exec, auditExec, err := dao.txExecutors(ctx, tx)
+	if err != nil {
+		return 0, err
+	}
+	return dao.write(ctx, exec, auditExec, "save", storageId, bo, exec.save)
+}
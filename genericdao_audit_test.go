@@ -0,0 +1,286 @@
+package godal
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/btnguyen2k/consu/reddo"
+)
+
+// mockGenericDaoWithTx is a mockGenericDao that implements IGenericDaoWithTx and records the transactions it receives.
+type mockGenericDaoWithTx struct {
+	*mockGenericDao
+	txList []*sql.Tx
+}
+
+func (dao *mockGenericDaoWithTx) track(tx *sql.Tx) {
+	dao.lock.Lock()
+	defer dao.lock.Unlock()
+	dao.txList = append(dao.txList, tx)
+}
+
+func (dao *mockGenericDaoWithTx) GdaoDeleteWithTx(ctx context.Context, tx *sql.Tx, storageId string, bo IGenericBo) (int, error) {
+	dao.track(tx)
+	return dao.GdaoDelete(storageId, bo)
+}
+
+func (dao *mockGenericDaoWithTx) GdaoDeleteManyWithTx(ctx context.Context, tx *sql.Tx, storageId string, filter FilterOpt) (int, error) {
+	dao.track(tx)
+	return dao.GdaoDeleteMany(storageId, filter)
+}
+
+func (dao *mockGenericDaoWithTx) GdaoFetchOneWithTx(ctx context.Context, tx *sql.Tx, storageId string, filter FilterOpt) (IGenericBo, error) {
+	dao.track(tx)
+	return dao.GdaoFetchOne(storageId, filter)
+}
+
+func (dao *mockGenericDaoWithTx) GdaoFetchManyWithTx(ctx context.Context, tx *sql.Tx, storageId string, filter FilterOpt, sorting *SortingOpt, startOffset, numItems int) ([]IGenericBo, error) {
+	dao.track(tx)
+	return dao.GdaoFetchMany(storageId, filter, sorting, startOffset, numItems)
+}
+
+func (dao *mockGenericDaoWithTx) GdaoCreateWithTx(ctx context.Context, tx *sql.Tx, storageId string, bo IGenericBo) (int, error) {
+	dao.track(tx)
+	return dao.GdaoCreate(storageId, bo)
+}
+
+func (dao *mockGenericDaoWithTx) GdaoUpdateWithTx(ctx context.Context, tx *sql.Tx, storageId string, bo IGenericBo) (int, error) {
+	dao.track(tx)
+	return dao.GdaoUpdate(storageId, bo)
+}
+
+func (dao *mockGenericDaoWithTx) GdaoSaveWithTx(ctx context.Context, tx *sql.Tx, storageId string, bo IGenericBo) (int, error) {
+	dao.track(tx)
+	return dao.GdaoSave(storageId, bo)
+}
+
+func fetchAuditRecords(mock *mockGenericDao) []IGenericBo {
+	boList, _ := mock.GdaoFetchMany("audit", nil, &SortingOpt{Fields: []*SortingField{{FieldName: "id"}}}, 0, 0)
+	return boList
+}
+
+func newAuditGenericDaoForTest(mock, auditMock IGenericDao) *AuditGenericDao {
+	seq := 0
+	return NewAuditGenericDao(mock, auditMock, "audit").SetIdGenerator(func() string {
+		seq++
+		return fmt.Sprintf("%04d", seq)
+	})
+}
+
+func TestActorFromContext(t *testing.T) {
+	name := "TestActorFromContext"
+	if _, ok := ActorFromContext(nil); ok {
+		t.Fatalf("%s failed: nil context should not carry actor", name)
+	}
+	if _, ok := ActorFromContext(WithActor(nil, "")); ok {
+		t.Fatalf("%s failed: empty actor should be treated as missing", name)
+	}
+	if actor, ok := ActorFromContext(WithActor(context.Background(), "alice")); !ok || actor != "alice" {
+		t.Fatalf("%s failed: %#v / %#v", name, actor, ok)
+	}
+}
+
+func TestAuditGenericDao_Snapshot(t *testing.T) {
+	name := "TestAuditGenericDao_Snapshot"
+	mock, auditMock := newMockGenericDao(), newMockGenericDao()
+	dao := newAuditGenericDaoForTest(mock, auditMock)
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	dao.funcNow = func() time.Time { return now }
+	ctx := WithActor(nil, "alice")
+
+	if numRows, err := dao.GdaoCreateWithContext(ctx, "test", newMockBo("1", 1)); numRows != 1 || err != nil {
+		t.Fatalf("%s failed: %#v / %#v", name, numRows, err)
+	}
+	if numRows, err := dao.GdaoUpdateWithContext(ctx, "test", newMockBo("1", 2)); numRows != 1 || err != nil {
+		t.Fatalf("%s failed: %#v / %#v", name, numRows, err)
+	}
+	if numRows, err := dao.GdaoSave("test", newMockBo("2", 3)); numRows != 1 || err != nil {
+		t.Fatalf("%s failed: %#v / %#v", name, numRows, err)
+	}
+	if numRows, err := dao.GdaoDeleteWithContext(ctx, "test", newMockBo("1", nil)); numRows != 1 || err != nil {
+		t.Fatalf("%s failed: %#v / %#v", name, numRows, err)
+	}
+
+	records := fetchAuditRecords(auditMock)
+	expected := []struct {
+		op, before, after, actor string
+	}{
+		{"create", "null", `{"id":"1","value":1}`, "alice"},
+		{"update", `{"id":"1","value":1}`, `{"id":"1","value":2}`, "alice"},
+		{"save", "null", `{"id":"2","value":3}`, ""},
+		{"delete", `{"id":"1","value":2}`, "null", "alice"},
+	}
+	if len(records) != len(expected) {
+		t.Fatalf("%s failed: expected %#v audit records but received %#v", name, len(expected), len(records))
+	}
+	for i, e := range expected {
+		rec := records[i]
+		if v := rec.GboGetAttrUnsafe(AuditFieldOperation, reddo.TypeString); v != e.op {
+			t.Fatalf("%s failed: expected op %#v but received %#v", name, e.op, v)
+		}
+		if v := rec.GboGetAttrUnsafe(AuditFieldStorageId, reddo.TypeString); v != "test" {
+			t.Fatalf("%s failed: expected storage %#v but received %#v", name, "test", v)
+		}
+		if v := rec.GboGetAttrUnsafe(AuditFieldKey+".id", reddo.TypeString); v == "" {
+			t.Fatalf("%s failed: record should carry key of the BO", name)
+		}
+		if js := toJsonString(rec.GboGetAttrUnsafe(AuditFieldBefore, nil)); js != e.before {
+			t.Fatalf("%s failed: expected before %s but received %s", name, e.before, js)
+		}
+		if js := toJsonString(rec.GboGetAttrUnsafe(AuditFieldAfter, nil)); js != e.after {
+			t.Fatalf("%s failed: expected after %s but received %s", name, e.after, js)
+		}
+		if v, _ := rec.GboGetAttr(AuditFieldActor, reddo.TypeString); (v == nil && e.actor != "") || (v != nil && v != e.actor) {
+			t.Fatalf("%s failed: expected actor %#v but received %#v", name, e.actor, v)
+		}
+		if v, _ := rec.GboGetTimeWithLayout(AuditFieldTimestamp, time.RFC3339); !v.Equal(now) {
+			t.Fatalf("%s failed: expected timestamp %#v but received %#v", name, now, v)
+		}
+		if rec.GboGetAttrUnsafe(AuditFieldDiff, nil) != nil {
+			t.Fatalf("%s failed: diff should not be recorded in snapshot mode", name)
+		}
+	}
+
+	// no record is written if nothing changes
+	dao.GdaoUpdate("test", newMockBo("100", 1))
+	dao.GdaoDelete("test", newMockBo("100", nil))
+	dao.GdaoCreate("test", newMockBo("2", 1))
+	if records := fetchAuditRecords(auditMock); len(records) != len(expected) {
+		t.Fatalf("%s failed: expected %#v audit records but received %#v", name, len(expected), len(records))
+	}
+}
+
+func TestAuditGenericDao_Diff(t *testing.T) {
+	name := "TestAuditGenericDao_Diff"
+	mock := newMockGenericDao()
+	dao := newAuditGenericDaoForTest(mock, mock).SetAuditMode(AuditModeDiff)
+	if dao.GetAuditMode() != AuditModeDiff {
+		t.Fatalf("%s failed: expected mode %#v but received %#v", name, AuditModeDiff, dao.GetAuditMode())
+	}
+	bo := newMockBo("1", map[string]interface{}{"a": 1, "b": "x"})
+	dao.GdaoCreate("test", bo)
+	bo = newMockBo("1", map[string]interface{}{"a": 2, "c": true})
+	dao.GdaoUpdate("test", bo)
+
+	records, _ := mock.GdaoFetchMany("audit", &FilterOptFieldOpValue{FieldName: AuditFieldOperation, Operator: FilterOpEqual, Value: "update"}, nil, 0, 0)
+	if len(records) != 1 {
+		t.Fatalf("%s failed: expected %#v audit record but received %#v", name, 1, len(records))
+	}
+	rec := records[0]
+	if rec.GboGetAttrUnsafe(AuditFieldBefore, nil) != nil || rec.GboGetAttrUnsafe(AuditFieldAfter, nil) != nil {
+		t.Fatalf("%s failed: snapshots should not be recorded in diff mode", name)
+	}
+	expected := `[{"after":2,"before":1,"path":"value.a"},{"after":null,"before":"x","path":"value.b"},{"after":true,"before":null,"path":"value.c"}]`
+	if js := toJsonString(rec.GboGetAttrUnsafe(AuditFieldDiff, nil)); js != expected {
+		t.Fatalf("%s failed: expected diff %s but received %s", name, expected, js)
+	}
+}
+
+func TestAuditGenericDao_GdaoDeleteMany(t *testing.T) {
+	name := "TestAuditGenericDao_GdaoDeleteMany"
+	mock, auditMock := newMockGenericDao(), newMockGenericDao()
+	dao := newAuditGenericDaoForTest(mock, auditMock)
+	for i := 0; i < 5; i++ {
+		mock.GdaoCreate("test", newMockBo(string(rune('0'+i)), i))
+	}
+	filter := &FilterOptFieldOpValue{FieldName: "value", Operator: FilterOpGreaterOrEqual, Value: 2}
+	if numRows, err := dao.GdaoDeleteMany("test", filter); numRows != 3 || err != nil {
+		t.Fatalf("%s failed: %#v / %#v", name, numRows, err)
+	}
+	records := fetchAuditRecords(auditMock)
+	if len(records) != 3 {
+		t.Fatalf("%s failed: expected %#v audit records but received %#v", name, 3, len(records))
+	}
+	for i, rec := range records {
+		if v := rec.GboGetAttrUnsafe(AuditFieldKey+".id", reddo.TypeString); v != string(rune('2'+i)) {
+			t.Fatalf("%s failed: unexpected key %#v", name, v)
+		}
+	}
+}
+
+// idAssigningDao is a mockGenericDao that assigns ids to new BOs upon creation.
+type idAssigningDao struct {
+	*mockGenericDao
+}
+
+func (dao *idAssigningDao) GdaoCreate(storageId string, bo IGenericBo) (int, error) {
+	if bo.GboGetAttrUnsafe("id", reddo.TypeString) == "" {
+		_ = bo.GboSetAttr("id", "generated")
+	}
+	return dao.mockGenericDao.GdaoCreate(storageId, bo)
+}
+
+func TestAuditGenericDao_KeyAfterWrite(t *testing.T) {
+	name := "TestAuditGenericDao_KeyAfterWrite"
+	auditMock := newMockGenericDao()
+	dao := newAuditGenericDaoForTest(&idAssigningDao{newMockGenericDao()}, auditMock)
+	if numRows, err := dao.GdaoCreate("test", newMockBo("", 1)); numRows != 1 || err != nil {
+		t.Fatalf("%s failed: %#v / %#v", name, numRows, err)
+	}
+	records := fetchAuditRecords(auditMock)
+	if len(records) != 1 {
+		t.Fatalf("%s failed: expected %#v audit records but received %#v", name, 1, len(records))
+	}
+	if v := records[0].GboGetAttrUnsafe(AuditFieldKey+".id", reddo.TypeString); v != "generated" {
+		t.Fatalf("%s failed: expected key %#v but received %#v", name, "generated", v)
+	}
+}
+
+func TestFilterToKey(t *testing.T) {
+	name := "TestFilterToKey"
+	var nilAnd *FilterOptAnd
+	if key := filterToKey(nilAnd); key != FilterToCacheKey(nilAnd) {
+		t.Fatalf("%s failed: nil filter should not be treated as a key, received %#v", name, key)
+	}
+	filter := (&FilterOptAnd{}).Add(&FilterOptFieldOpValue{FieldName: "id", Operator: FilterOpEqual, Value: "1"}).
+		Add(&FilterOptFieldOpValue{FieldName: "type", Operator: FilterOpEqual, Value: "a"})
+	if js := toJsonString(filterToKey(filter)); js != `{"id":"1","type":"a"}` {
+		t.Fatalf("%s failed: received %s", name, js)
+	}
+}
+
+func TestAuditGenericDao_WithTx(t *testing.T) {
+	name := "TestAuditGenericDao_WithTx"
+	mock := &mockGenericDaoWithTx{mockGenericDao: newMockGenericDao()}
+	auditMock := &mockGenericDaoWithTx{mockGenericDao: newMockGenericDao()}
+	dao := newAuditGenericDaoForTest(mock, auditMock)
+	tx := new(sql.Tx)
+
+	if numRows, err := dao.GdaoCreateWithTx(nil, tx, "test", newMockBo("1", 1)); numRows != 1 || err != nil {
+		t.Fatalf("%s failed: %#v / %#v", name, numRows, err)
+	}
+	if numRows, err := dao.GdaoSaveWithTx(nil, tx, "test", newMockBo("1", 2)); numRows != 1 || err != nil {
+		t.Fatalf("%s failed: %#v / %#v", name, numRows, err)
+	}
+	if numRows, err := dao.GdaoDeleteManyWithTx(nil, tx, "test", nil); numRows != 1 || err != nil {
+		t.Fatalf("%s failed: %#v / %#v", name, numRows, err)
+	}
+	if len(fetchAuditRecords(auditMock.mockGenericDao)) != 3 {
+		t.Fatalf("%s failed: expected %#v audit records", name, 3)
+	}
+	for _, m := range []*mockGenericDaoWithTx{mock, auditMock} {
+		if len(m.txList) == 0 {
+			t.Fatalf("%s failed: transaction should have been passed to the DAO", name)
+		}
+		for _, v := range m.txList {
+			if v != tx {
+				t.Fatalf("%s failed: all operations should run within the same transaction", name)
+			}
+		}
+	}
+
+	// underlying DAOs do not support transactions
+	dao = newAuditGenericDaoForTest(newMockGenericDao(), auditMock)
+	if _, err := dao.GdaoCreateWithTx(nil, tx, "test", newMockBo("1", 1)); err != ErrGdaoTxNotSupported {
+		t.Fatalf("%s failed: expected error %#v but received %#v", name, ErrGdaoTxNotSupported, err)
+	}
+}
+
+func toJsonString(v interface{}) string {
+	js, _ := json.Marshal(v)
+	return string(js)
+}
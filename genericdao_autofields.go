@@ -0,0 +1,208 @@
+package godal
+
+import (
+	"context"
+	"time"
+)
+
+// WriteOp identifies the kind of write operation a BO is being prepared for.
+//
+// Available since v0.7.0
+type WriteOp int
+
+const (
+	// WriteOpInsert means the BO is inserted as a new record (GdaoCreate, or the insert branch of GdaoSave).
+	WriteOpInsert WriteOp = iota
+
+	// WriteOpUpdate means the BO updates an existing record (GdaoUpdate).
+	WriteOpUpdate
+
+	// WriteOpUpsert means the BO either replaces an existing record or is inserted as a new one, and the DAO can not
+	// tell which in advance (GdaoSave on backends that perform an atomic upsert).
+	WriteOpUpsert
+
+	// WriteOpUpdateThenInsert means the BO updates an existing record, and is inserted if no record is updated (GdaoSave
+	// on backends that do not perform an atomic upsert). The BO is prepared as for WriteOpUpdate, except that fields
+	// filled by PrepareBoForInsert are not yet required by the schema.
+	WriteOpUpdateThenInsert
+)
+
+// isUpdate returns 'true' if the BO is prepared for updating an existing record.
+func (op WriteOp) isUpdate() bool {
+	return op == WriteOpUpdate || op == WriteOpUpdateThenInsert
+}
+
+// AutoFields specifies the fields that a DAO fills automatically on write. Empty field paths are ignored.
+//
+// Available since v0.7.0
+type AutoFields struct {
+	CreatedAt string // path of the field that receives the creation time
+	UpdatedAt string // path of the field that receives the last modification time
+	CreatedBy string // path of the field that receives the actor (see WithActor) creating the BO
+	UpdatedBy string // path of the field that receives the actor (see WithActor) last modifying the BO
+
+	// TimeValue converts the time to the value stored in the BO, e.g. func(t time.Time) interface{} { return t.UnixMilli() }.
+	// If nil, time.Time is stored as-is.
+	TimeValue func(t time.Time) interface{}
+}
+
+func (af AutoFields) timeValue(t time.Time) interface{} {
+	if af.TimeValue != nil {
+		return af.TimeValue(t)
+	}
+	return t
+}
+
+// GetAutoFields returns the auto-fields configured for a storage.
+//
+// Available since v0.7.0
+func (dao *AbstractGenericDao) GetAutoFields(storageId string) (AutoFields, bool) {
+	af, ok := dao.autoFields[storageId]
+	return af, ok
+}
+
+// SetAutoFields configures the fields that are filled automatically when BOs are written to a storage:
+//   - CreatedAt and CreatedBy are set when the BO is inserted (GdaoCreate, and GdaoSave if the record does not exist).
+//   - UpdatedAt and UpdatedBy are set on every write (GdaoCreate, GdaoUpdate and GdaoSave).
+//   - CreatedBy and UpdatedBy are taken from the context (see WithActor), and are left untouched if the context
+//     does not carry an actor.
+//
+// On backends whose GdaoSave is an atomic upsert (MongoDB, AWS DynamoDB, Azure Cosmos DB), CreatedAt and CreatedBy are
+// set by GdaoSave only if the BO does not carry them yet.
+//
+// Auto-fields should be configured once when the DAO is initialized; this function is not safe to call concurrently
+// with write operations.
+//
+// Available since v0.7.0
+func (dao *AbstractGenericDao) SetAutoFields(storageId string, fields AutoFields) *AbstractGenericDao {
+	if dao.autoFields == nil {
+		dao.autoFields = make(map[string]AutoFields)
+	}
+	dao.autoFields[storageId] = fields
+	return dao
+}
+
+// RemoveAutoFields removes the auto-fields configuration of a storage.
+//
+// Available since v0.7.0
+func (dao *AbstractGenericDao) RemoveAutoFields(storageId string) *AbstractGenericDao {
+	delete(dao.autoFields, storageId)
+	return dao
+}
+
+// GetClock returns the function that supplies the current time to auto-fields.
+//
+// Available since v0.7.0
+func (dao *AbstractGenericDao) GetClock() func() time.Time {
+	if dao.funcNow == nil {
+		return func() time.Time { return time.Now().UTC() }
+	}
+	return dao.funcNow
+}
+
+// SetClock sets the function that supplies the current time to auto-fields. Default clock returns the current time in UTC.
+//
+// Available since v0.7.0
+func (dao *AbstractGenericDao) SetClock(funcNow func() time.Time) *AbstractGenericDao {
+	dao.funcNow = funcNow
+	return dao
+}
+
+// PrepareBoForWrite prepares a BO before it is written to a storage: it generates the BO's id if absent (see
+// SetIdGenerator), fills the storage's auto-fields (see SetAutoFields) and validates the result against the storage's
+// schema (see SetSchema). DAO implementations call this function at the beginning of their write operations.
+//
+// Available since v0.7.0
+func (dao *AbstractGenericDao) PrepareBoForWrite(ctx context.Context, storageId string, bo IGenericBo, op WriteOp) error {
+	if dao == nil || bo == nil {
+		return nil
+	}
+	if err := dao.applyIdGenerator(ctx, storageId, bo, op); err != nil {
+		return err
+	}
+	if err := dao.applyAutoFields(ctx, storageId, bo, op); err != nil {
+		return err
+	}
+	if op == WriteOpUpdateThenInsert {
+		return dao.applySchema(storageId, bo, dao.insertOnlyFields(storageId)...)
+	}
+	return dao.applySchema(storageId, bo)
+}
+
+// PrepareBoForInsert fills the fields that are written only when a BO is inserted: the BO's id if absent (see
+// SetIdGenerator) and the CreatedAt/CreatedBy auto-fields (see SetAutoFields), then validates the result against the
+// storage's schema.
+//
+// DAO implementations whose GdaoSave tries to update the record first call this function after
+// PrepareBoForWrite(..., WriteOpUpdateThenInsert) and before falling back to inserting the BO, so that the BO is not
+// stamped twice.
+//
+// Available since v0.7.0
+func (dao *AbstractGenericDao) PrepareBoForInsert(ctx context.Context, storageId string, bo IGenericBo) error {
+	if dao == nil || bo == nil {
+		return nil
+	}
+	if err := dao.applyIdGenerator(ctx, storageId, bo, WriteOpInsert); err != nil {
+		return err
+	}
+	if af, ok := dao.autoFields[storageId]; ok {
+		// the creation time is the modification time stamped by PrepareBoForWrite
+		now := bo.GboGetAttrUnsafe(af.UpdatedAt, nil)
+		if af.UpdatedAt == "" || now == nil {
+			now = af.timeValue(dao.GetClock()())
+		}
+		if err := dao.applyCreatedFields(ctx, af, bo, now, false); err != nil {
+			return err
+		}
+	}
+	return dao.applySchema(storageId, bo)
+}
+
+// insertOnlyFields returns paths of the fields filled by PrepareBoForInsert.
+func (dao *AbstractGenericDao) insertOnlyFields(storageId string) []string {
+	var fields []string
+	if gen, ok := dao.idGenerators[storageId]; ok {
+		fields = append(fields, gen.fieldPath)
+	}
+	if af, ok := dao.autoFields[storageId]; ok {
+		fields = append(fields, af.CreatedAt, af.CreatedBy)
+	}
+	return fields
+}
+
+func (dao *AbstractGenericDao) applyAutoFields(ctx context.Context, storageId string, bo IGenericBo, op WriteOp) error {
+	af, ok := dao.autoFields[storageId]
+	if !ok {
+		return nil
+	}
+	now := af.timeValue(dao.GetClock()())
+	if !op.isUpdate() {
+		if err := dao.applyCreatedFields(ctx, af, bo, now, op == WriteOpUpsert); err != nil {
+			return err
+		}
+	}
+	if err := setAutoField(bo, af.UpdatedAt, now, false); err != nil {
+		return err
+	}
+	if actor, ok := ActorFromContext(ctx); ok {
+		return setAutoField(bo, af.UpdatedBy, actor, false)
+	}
+	return nil
+}
+
+func (dao *AbstractGenericDao) applyCreatedFields(ctx context.Context, af AutoFields, bo IGenericBo, now interface{}, onlyIfAbsent bool) error {
+	if err := setAutoField(bo, af.CreatedAt, now, onlyIfAbsent); err != nil {
+		return err
+	}
+	if actor, ok := ActorFromContext(ctx); ok {
+		return setAutoField(bo, af.CreatedBy, actor, onlyIfAbsent)
+	}
+	return nil
+}
+
+func setAutoField(bo IGenericBo, path string, value interface{}, onlyIfAbsent bool) error {
+	if path == "" || (onlyIfAbsent && bo.GboGetAttrUnsafe(path, nil) != nil) {
+		return nil
+	}
+	return bo.GboSetAttr(path, value)
+}
@@ -0,0 +1,150 @@
+package godal
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestAbstractGenericDao_SetGetAutoFields(t *testing.T) {
+	name := "TestAbstractGenericDao_SetGetAutoFields"
+	dao := NewAbstractGenericDao(nil)
+	if _, ok := dao.GetAutoFields("test"); ok {
+		t.Fatalf("%s failed: no auto-fields should be configured", name)
+	}
+	dao.SetAutoFields("test", AutoFields{CreatedAt: "created_at"})
+	if af, ok := dao.GetAutoFields("test"); !ok || af.CreatedAt != "created_at" {
+		t.Fatalf("%s failed: %#v / %#v", name, af, ok)
+	}
+	dao.RemoveAutoFields("test")
+	if _, ok := dao.GetAutoFields("test"); ok {
+		t.Fatalf("%s failed: auto-fields should have been removed", name)
+	}
+	if now := dao.GetClock()(); now.Location() != time.UTC {
+		t.Fatalf("%s failed: default clock should return UTC time, received %#v", name, now)
+	}
+}
+
+func TestAbstractGenericDao_PrepareBoForWrite(t *testing.T) {
+	name := "TestAbstractGenericDao_PrepareBoForWrite"
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	dao := NewAbstractGenericDao(nil).SetClock(func() time.Time { return now })
+	dao.SetAutoFields("test", AutoFields{CreatedAt: "created.at", UpdatedAt: "updated.at", CreatedBy: "created.by", UpdatedBy: "updated.by"})
+	ctx := WithActor(nil, "alice")
+
+	bo := newMockBo("1", 1)
+	if err := dao.PrepareBoForWrite(ctx, "test", bo, WriteOpInsert); err != nil {
+		t.Fatalf("%s failed: %e", name, err)
+	}
+	for path, expected := range map[string]interface{}{"created.at": now, "updated.at": now, "created.by": "alice", "updated.by": "alice"} {
+		if v := bo.GboGetAttrUnsafe(path, nil); v != expected {
+			t.Fatalf("%s failed: expected %#v at %s but received %#v", name, expected, path, v)
+		}
+	}
+
+	// update: only updated-fields are touched
+	later := now.Add(time.Hour)
+	dao.SetClock(func() time.Time { return later })
+	if err := dao.PrepareBoForWrite(WithActor(nil, "bob"), "test", bo, WriteOpUpdate); err != nil {
+		t.Fatalf("%s failed: %e", name, err)
+	}
+	for path, expected := range map[string]interface{}{"created.at": now, "updated.at": later, "created.by": "alice", "updated.by": "bob"} {
+		if v := bo.GboGetAttrUnsafe(path, nil); v != expected {
+			t.Fatalf("%s failed: expected %#v at %s but received %#v", name, expected, path, v)
+		}
+	}
+
+	// upsert: created-fields are set only if absent
+	dao.PrepareBoForWrite(WithActor(nil, "carol"), "test", bo, WriteOpUpsert)
+	if v := bo.GboGetAttrUnsafe("created.by", nil); v != "alice" {
+		t.Fatalf("%s failed: existing created-by should be kept, received %#v", name, v)
+	}
+	bo = newMockBo("2", 2)
+	dao.PrepareBoForWrite(WithActor(nil, "carol"), "test", bo, WriteOpUpsert)
+	if v := bo.GboGetAttrUnsafe("created.by", nil); v != "carol" {
+		t.Fatalf("%s failed: expected created-by %#v but received %#v", name, "carol", v)
+	}
+
+	// no actor in context: actor fields are left untouched
+	bo = newMockBo("3", 3)
+	dao.PrepareBoForWrite(nil, "test", bo, WriteOpInsert)
+	if bo.GboGetAttrUnsafe("created.by", nil) != nil || bo.GboGetAttrUnsafe("created.at", nil) != later {
+		t.Fatalf("%s failed: unexpected BO %s", name, bo.GboToJsonUnsafe())
+	}
+
+	// storage without auto-fields
+	bo = newMockBo("4", 4)
+	dao.PrepareBoForWrite(ctx, "other", bo, WriteOpInsert)
+	if js := string(bo.GboToJsonUnsafe()); js != `{"id":"4","value":4}` {
+		t.Fatalf("%s failed: BO should not be modified: %s", name, js)
+	}
+
+	// custom time value
+	dao.SetAutoFields("test", AutoFields{UpdatedAt: "updated_at", TimeValue: func(t time.Time) interface{} { return t.UnixMilli() }})
+	dao.PrepareBoForWrite(nil, "test", bo, WriteOpUpdate)
+	if v := bo.GboGetAttrUnsafe("updated_at", nil); v != later.UnixMilli() {
+		t.Fatalf("%s failed: expected %#v but received %#v", name, later.UnixMilli(), v)
+	}
+
+	var nilDao *AbstractGenericDao
+	if err := nilDao.PrepareBoForWrite(ctx, "test", bo, WriteOpInsert); err != nil {
+		t.Fatalf("%s failed: %e", name, err)
+	}
+}
+
+func TestAbstractGenericDao_PrepareBoForInsert(t *testing.T) {
+	name := "TestAbstractGenericDao_PrepareBoForInsert"
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	dao := NewAbstractGenericDao(nil).SetClock(func() time.Time { return now })
+	dao.SetAutoFields("test", AutoFields{CreatedAt: "created.at", UpdatedAt: "updated.at", CreatedBy: "created.by", UpdatedBy: "updated.by"})
+	numIds := 0
+	dao.SetIdGenerator("test", "id", IdGeneratorFunc(func(context.Context, string) (interface{}, error) {
+		numIds++
+		return "generated", nil
+	}))
+	ctx := WithActor(nil, "alice")
+
+	// save: the BO is prepared for update, then only insert-only fields are filled
+	bo := NewGenericBo()
+	if err := dao.PrepareBoForWrite(ctx, "test", bo, WriteOpUpdateThenInsert); err != nil {
+		t.Fatalf("%s failed: %e", name, err)
+	}
+	dao.SetClock(func() time.Time { return now.Add(time.Second) })
+	if err := dao.PrepareBoForInsert(ctx, "test", bo); err != nil {
+		t.Fatalf("%s failed: %e", name, err)
+	}
+	for path, expected := range map[string]interface{}{"id": "generated", "created.at": now, "updated.at": now, "created.by": "alice", "updated.by": "alice"} {
+		if v := bo.GboGetAttrUnsafe(path, nil); v != expected {
+			t.Fatalf("%s failed: expected %#v at %s but received %#v", name, expected, path, v)
+		}
+	}
+	if numIds != 1 {
+		t.Fatalf("%s failed: expected %#v generated ids but received %#v", name, 1, numIds)
+	}
+
+	// insert-only fields are required only once they are filled
+	dao.SetSchema("test", &Schema{Type: SchemaTypeObject, Required: []string{"id", "created", "updated"},
+		Properties: map[string]*Schema{"created": {Type: SchemaTypeObject, Required: []string{"at", "by"}}}})
+	bo = NewGenericBo()
+	bo.GboSetAttr("created", map[string]interface{}{})
+	if err := dao.PrepareBoForWrite(nil, "test", bo, WriteOpUpdateThenInsert); err != nil {
+		t.Fatalf("%s failed: %e", name, err)
+	}
+	var verr *ValidationError
+	if err := dao.PrepareBoForWrite(nil, "test", bo.(*GenericBo).GboClone(), WriteOpUpdate); !errors.As(err, &verr) || !reflect.DeepEqual(verr.Paths(), []string{"id", "created.at", "created.by"}) {
+		t.Fatalf("%s failed: update should require all fields, received %#v", name, err)
+	}
+	if err := dao.PrepareBoForInsert(nil, "test", bo); !errors.As(err, &verr) || !reflect.DeepEqual(verr.Paths(), []string{"created.by"}) {
+		t.Fatalf("%s failed: insert should validate filled BO, received %#v", name, err)
+	}
+	if err := dao.PrepareBoForInsert(ctx, "test", bo); err != nil {
+		t.Fatalf("%s failed: %e", name, err)
+	}
+
+	var nilDao *AbstractGenericDao
+	if err := nilDao.PrepareBoForInsert(ctx, "test", bo); err != nil {
+		t.Fatalf("%s failed: %e", name, err)
+	}
+}
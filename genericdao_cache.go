@@ -0,0 +1,588 @@
+package godal
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ICacheStore defines API interface of a key-value store used by CachingGenericDao to cache BOs.
+//
+// Values are opaque byte arrays (BOs are serialized via an IGboCodec, see CachingGenericDao.SetCodec), so that the
+// store can be an in-process map (see LruCacheStore) or an external one (e.g. Redis or Memcached).
+//
+// Available since v0.7.0
+type ICacheStore interface {
+	// Get retrieves a cached value. found is false if the entry does not exist or has expired.
+	Get(key string) (value []byte, found bool, err error)
+
+	// Set stores a value with a time-to-live. ttl <= 0 means the entry never expires.
+	Set(key string, value []byte, ttl time.Duration) error
+
+	// Delete removes an entry from the store.
+	Delete(key string) error
+
+	// DeleteByPrefix removes all entries whose keys start with the specified prefix.
+	DeleteByPrefix(prefix string) error
+}
+
+/*----------------------------------------------------------------------*/
+
+// NewLruCacheStore constructs a new LruCacheStore instance.
+//
+// maxEntries is the maximum number of entries the store holds before evicting the least recently used ones, maxEntries <= 0 means no limit.
+//
+// Available since v0.7.0
+func NewLruCacheStore(maxEntries int) *LruCacheStore {
+	return &LruCacheStore{
+		maxEntries: maxEntries,
+		entries:    make(map[string]*list.Element),
+		lruList:    list.New(),
+		funcNow:    time.Now,
+	}
+}
+
+type lruCacheEntry struct {
+	key      string
+	value    []byte
+	expireAt time.Time
+}
+
+// LruCacheStore is an in-process implementation of ICacheStore with TTL and LRU-size bound.
+//
+// Available since v0.7.0
+type LruCacheStore struct {
+	lock       sync.Mutex
+	maxEntries int
+	entries    map[string]*list.Element
+	lruList    *list.List
+	funcNow    func() time.Time
+}
+
+// Size returns the current number of entries (including expired ones that have not been removed yet).
+func (s *LruCacheStore) Size() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.lruList.Len()
+}
+
+// Get implements ICacheStore.Get.
+func (s *LruCacheStore) Get(key string) ([]byte, bool, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	el, ok := s.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+	entry := el.Value.(*lruCacheEntry)
+	if !entry.expireAt.IsZero() && !s.funcNow().Before(entry.expireAt) {
+		s.removeElement(el)
+		return nil, false, nil
+	}
+	s.lruList.MoveToFront(el)
+	return entry.value, true, nil
+}
+
+// Set implements ICacheStore.Set.
+func (s *LruCacheStore) Set(key string, value []byte, ttl time.Duration) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	entry := &lruCacheEntry{key: key, value: value}
+	if ttl > 0 {
+		entry.expireAt = s.funcNow().Add(ttl)
+	}
+	if el, ok := s.entries[key]; ok {
+		el.Value = entry
+		s.lruList.MoveToFront(el)
+		return nil
+	}
+	s.entries[key] = s.lruList.PushFront(entry)
+	for s.maxEntries > 0 && s.lruList.Len() > s.maxEntries {
+		s.removeElement(s.lruList.Back())
+	}
+	return nil
+}
+
+// Delete implements ICacheStore.Delete.
+func (s *LruCacheStore) Delete(key string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if el, ok := s.entries[key]; ok {
+		s.removeElement(el)
+	}
+	return nil
+}
+
+// DeleteByPrefix implements ICacheStore.DeleteByPrefix.
+func (s *LruCacheStore) DeleteByPrefix(prefix string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for key, el := range s.entries {
+		if strings.HasPrefix(key, prefix) {
+			s.removeElement(el)
+		}
+	}
+	return nil
+}
+
+func (s *LruCacheStore) removeElement(el *list.Element) {
+	s.lruList.Remove(el)
+	delete(s.entries, el.Value.(*lruCacheEntry).key)
+}
+
+/*----------------------------------------------------------------------*/
+
+// FilterToCacheKey builds a deterministic string representation of a FilterOpt, suitable to be used as cache key.
+//
+// Numbers are represented by value regardless of their Go types (e.g. int64(1), float64(1.0) and json.Number("1")
+// result in the same key), so that filters built from BOs fetched from the database and from BOs decoded from JSON
+// match.
+//
+// Available since v0.7.0
+func FilterToCacheKey(filter FilterOpt) string {
+	if filter == nil {
+		return "<nil>"
+	}
+	v := reflect.ValueOf(filter)
+	for ; v.Kind() == reflect.Ptr; v = v.Elem() {
+		if v.IsNil() {
+			return "<nil>"
+		}
+	}
+	switch f := v.Interface().(type) {
+	case FilterOptAnd:
+		return "AND(" + filtersToCacheKey(f.Filters) + ")"
+	case FilterOptOr:
+		return "OR(" + filtersToCacheKey(f.Filters) + ")"
+	case FilterOptFieldOpValue:
+		return fmt.Sprintf("FV(%q,%d,%s)", f.FieldName, f.Operator, cacheKeyValue(f.Value))
+	case FilterOptFieldOpField:
+		return fmt.Sprintf("FF(%q,%d,%q)", f.FieldNameLeft, f.Operator, f.FieldNameRight)
+	case FilterOptFieldIsNull:
+		return fmt.Sprintf("NULL(%q)", f.FieldName)
+	case FilterOptFieldIsNotNull:
+		return fmt.Sprintf("NOTNULL(%q)", f.FieldName)
+	}
+	return fmt.Sprintf("%T%#v", v.Interface(), v.Interface())
+}
+
+// cacheKeyValue represents a filter value in a cache key: numbers as "num:<value>", other values as "<type>:<value>".
+func cacheKeyValue(v interface{}) string {
+	if n, ok := v.(json.Number); ok {
+		if i, err := n.Int64(); err == nil {
+			return "num:" + strconv.FormatInt(i, 10)
+		}
+		if f, err := n.Float64(); err == nil {
+			v = f
+		}
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return "num:" + strconv.FormatInt(rv.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return "num:" + strconv.FormatUint(rv.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		f := rv.Float()
+		if f == math.Trunc(f) && f >= math.MinInt64 && f < math.MaxInt64 {
+			return "num:" + strconv.FormatInt(int64(f), 10)
+		}
+		return "num:" + strconv.FormatFloat(f, 'g', -1, 64)
+	}
+	return fmt.Sprintf("%T:%v", v, v)
+}
+
+func filtersToCacheKey(filters []FilterOpt) string {
+	keys := make([]string, len(filters))
+	for i, f := range filters {
+		keys[i] = FilterToCacheKey(f)
+	}
+	return strings.Join(keys, ",")
+}
+
+/*----------------------------------------------------------------------*/
+
+// cacheFill is a fetch whose result is about to be put into cache. A fill is marked stale if its entry is
+// invalidated while the fetch is in progress, so that the (possibly outdated) result is not cached.
+type cacheFill struct {
+	key   string
+	stale bool
+}
+
+// cacheSingleFlightCall is an in-flight or completed fetch shared by concurrent cache misses on the same key.
+type cacheSingleFlightCall struct {
+	wg    sync.WaitGroup
+	value []byte
+	err   error
+}
+
+// NewCachingGenericDao constructs a new CachingGenericDao that wraps the specified DAO.
+//
+// If store is nil, a LruCacheStore with no size limit is used. Default TTL is 5 minutes, negative caching is disabled,
+// BOs are serialized with the MessagePack codec.
+//
+// Available since v0.7.0
+func NewCachingGenericDao(dao IGenericDao, store ICacheStore) *CachingGenericDao {
+	if store == nil {
+		store = NewLruCacheStore(0)
+	}
+	return &CachingGenericDao{
+		origDao:      dao,
+		dao:          ToGenericDaoWithContext(dao),
+		store:        store,
+		codec:        NewMsgpackCodec(),
+		ttl:          5 * time.Minute,
+		singleFlight: true,
+		inFlight:     make(map[string]*cacheSingleFlightCall),
+		fills:        make(map[*cacheFill]bool),
+	}
+}
+
+// CachingGenericDao is a read-through cache wrapper of IGenericDao.
+//
+// Caching rules:
+//   - GdaoFetchOne: results are cached, keyed by storage-id and the filter. A fetched BO is cached only if the filter
+//     is equivalent to the one built by GdaoCreateFilter for that BO, so that the entry can be invalidated on write.
+//   - If negative caching is enabled, "not found" results are also cached (with a separate TTL), but only for filters
+//     that GdaoCreateFilter would build for the missing BO, so that the entry is invalidated when the BO is created.
+//   - Concurrent misses on the same key are de-duplicated (single-flight) so that only one fetch hits the underlying DAO.
+//   - GdaoCreate, GdaoUpdate, GdaoSave and GdaoDelete invalidate the entry keyed by GdaoCreateFilter of the BO.
+//   - GdaoDeleteMany purges all cached entries of the storage.
+//   - GdaoFetchMany is not cached.
+//   - A fetch that is in progress when its entry is invalidated does not put its result into cache.
+//
+// Each cache hit returns a new BO instance, hence callers are free to modify the returned BO. BOs are cached in a
+// serialized form (see SetCodec); the default MessagePack codec preserves time.Time, []byte and integer values.
+//
+// Available since v0.7.0
+type CachingGenericDao struct {
+	origDao      IGenericDao
+	dao          IGenericDaoWithContext
+	store        ICacheStore
+	codec        IGboCodec
+	ttl          time.Duration
+	negativeTtl  time.Duration
+	singleFlight bool
+	lock         sync.Mutex
+	inFlight     map[string]*cacheSingleFlightCall
+	fills        map[*cacheFill]bool
+}
+
+// GetDao returns the underlying DAO.
+func (dao *CachingGenericDao) GetDao() IGenericDao {
+	return dao.origDao
+}
+
+// GetCacheStore returns the cache store being used.
+func (dao *CachingGenericDao) GetCacheStore() ICacheStore {
+	return dao.store
+}
+
+// GetCodec returns the codec used to serialize cached BOs.
+func (dao *CachingGenericDao) GetCodec() IGboCodec {
+	return dao.codec
+}
+
+// SetCodec sets the codec used to serialize cached BOs. Default codec is NewMsgpackCodec().
+//
+// The codec should preserve the value types returned by the underlying DAO, otherwise cached reads return values of
+// different types than uncached ones (e.g. JSON turns time.Time into string).
+func (dao *CachingGenericDao) SetCodec(codec IGboCodec) *CachingGenericDao {
+	dao.codec = codec
+	return dao
+}
+
+// GetTtl returns the time-to-live of cached BOs.
+func (dao *CachingGenericDao) GetTtl() time.Duration {
+	return dao.ttl
+}
+
+// SetTtl sets the time-to-live of cached BOs. ttl <= 0 means cached BOs never expire.
+func (dao *CachingGenericDao) SetTtl(ttl time.Duration) *CachingGenericDao {
+	dao.ttl = ttl
+	return dao
+}
+
+// GetNegativeTtl returns the time-to-live of "not found" entries. Value <= 0 means negative caching is disabled.
+func (dao *CachingGenericDao) GetNegativeTtl() time.Duration {
+	return dao.negativeTtl
+}
+
+// SetNegativeTtl enables negative caching with the specified time-to-live. ttl <= 0 disables negative caching.
+func (dao *CachingGenericDao) SetNegativeTtl(ttl time.Duration) *CachingGenericDao {
+	dao.negativeTtl = ttl
+	return dao
+}
+
+// GetSingleFlight returns 'true' if concurrent misses on the same key are de-duplicated, 'false' otherwise.
+func (dao *CachingGenericDao) GetSingleFlight() bool {
+	return dao.singleFlight
+}
+
+// SetSingleFlight enables/disables de-duplication of concurrent misses on the same key. Default value is 'true'.
+func (dao *CachingGenericDao) SetSingleFlight(enabled bool) *CachingGenericDao {
+	dao.singleFlight = enabled
+	return dao
+}
+
+// cacheKeyPrefix returns prefix of all cache keys of a storage.
+func (dao *CachingGenericDao) cacheKeyPrefix(storageId string) string {
+	return storageId + "\x00"
+}
+
+// cacheKey builds the cache key of a filter.
+func (dao *CachingGenericDao) cacheKey(storageId string, filter FilterOpt) string {
+	return dao.cacheKeyPrefix(storageId) + FilterToCacheKey(filter)
+}
+
+// Invalidate removes the cached entry of a BO. Fetches of the entry that are in progress do not cache their results.
+func (dao *CachingGenericDao) Invalidate(storageId string, bo IGenericBo) error {
+	if bo == nil {
+		return nil
+	}
+	key := dao.cacheKey(storageId, dao.dao.GdaoCreateFilter(storageId, bo))
+	dao.lock.Lock()
+	defer dao.lock.Unlock()
+	for fill := range dao.fills {
+		if fill.key == key {
+			fill.stale = true
+		}
+	}
+	return dao.store.Delete(key)
+}
+
+// Purge removes all cached entries of a storage. Fetches of the storage that are in progress do not cache their results.
+func (dao *CachingGenericDao) Purge(storageId string) error {
+	prefix := dao.cacheKeyPrefix(storageId)
+	dao.lock.Lock()
+	defer dao.lock.Unlock()
+	for fill := range dao.fills {
+		if strings.HasPrefix(fill.key, prefix) {
+			fill.stale = true
+		}
+	}
+	return dao.store.DeleteByPrefix(prefix)
+}
+
+// decodeCachedValue transforms a cached value to BO. Empty value represents a "not found" entry.
+func (dao *CachingGenericDao) decodeCachedValue(value []byte) (IGenericBo, error) {
+	if len(value) == 0 {
+		return nil, nil
+	}
+	bo := NewGenericBo().(*GenericBo)
+	if err := bo.GboDecode(dao.codec, value); err != nil {
+		return nil, err
+	}
+	return bo, nil
+}
+
+// GdaoCreateFilter implements IGenericDao.GdaoCreateFilter.
+func (dao *CachingGenericDao) GdaoCreateFilter(storageId string, bo IGenericBo) FilterOpt {
+	return dao.dao.GdaoCreateFilter(storageId, bo)
+}
+
+// GetRowMapper implements IGenericDao.GetRowMapper.
+func (dao *CachingGenericDao) GetRowMapper() IRowMapper {
+	return dao.dao.GetRowMapper()
+}
+
+// GdaoDelete implements IGenericDao.GdaoDelete.
+func (dao *CachingGenericDao) GdaoDelete(storageId string, bo IGenericBo) (int, error) {
+	return dao.GdaoDeleteWithContext(nil, storageId, bo)
+}
+
+// GdaoDeleteWithContext implements IGenericDaoWithContext.GdaoDeleteWithContext.
+func (dao *CachingGenericDao) GdaoDeleteWithContext(ctx context.Context, storageId string, bo IGenericBo) (int, error) {
+	defer dao.Invalidate(storageId, bo)
+	return dao.dao.GdaoDeleteWithContext(ctx, storageId, bo)
+}
+
+// GdaoDeleteMany implements IGenericDao.GdaoDeleteMany.
+func (dao *CachingGenericDao) GdaoDeleteMany(storageId string, filter FilterOpt) (int, error) {
+	return dao.GdaoDeleteManyWithContext(nil, storageId, filter)
+}
+
+// GdaoDeleteManyWithContext implements IGenericDaoWithContext.GdaoDeleteManyWithContext.
+//
+// All cached entries of the storage are purged.
+func (dao *CachingGenericDao) GdaoDeleteManyWithContext(ctx context.Context, storageId string, filter FilterOpt) (int, error) {
+	defer dao.Purge(storageId)
+	return dao.dao.GdaoDeleteManyWithContext(ctx, storageId, filter)
+}
+
+// GdaoFetchOne implements IGenericDao.GdaoFetchOne.
+func (dao *CachingGenericDao) GdaoFetchOne(storageId string, filter FilterOpt) (IGenericBo, error) {
+	return dao.GdaoFetchOneWithContext(nil, storageId, filter)
+}
+
+// GdaoFetchOneWithContext implements IGenericDaoWithContext.GdaoFetchOneWithContext.
+func (dao *CachingGenericDao) GdaoFetchOneWithContext(ctx context.Context, storageId string, filter FilterOpt) (IGenericBo, error) {
+	key := dao.cacheKey(storageId, filter)
+	if value, found, err := dao.store.Get(key); err == nil && found {
+		return dao.decodeCachedValue(value)
+	}
+	if !dao.singleFlight {
+		value, err := dao.fetchAndCache(ctx, key, storageId, filter)
+		if err != nil {
+			return nil, err
+		}
+		return dao.decodeCachedValue(value)
+	}
+
+	dao.lock.Lock()
+	if call, ok := dao.inFlight[key]; ok {
+		dao.lock.Unlock()
+		call.wg.Wait()
+		if call.err != nil {
+			return nil, call.err
+		}
+		return dao.decodeCachedValue(call.value)
+	}
+	call := &cacheSingleFlightCall{}
+	call.wg.Add(1)
+	dao.inFlight[key] = call
+	dao.lock.Unlock()
+
+	call.value, call.err = dao.fetchAndCache(ctx, key, storageId, filter)
+	call.wg.Done()
+	dao.lock.Lock()
+	delete(dao.inFlight, key)
+	dao.lock.Unlock()
+
+	if call.err != nil {
+		return nil, call.err
+	}
+	return dao.decodeCachedValue(call.value)
+}
+
+// fetchAndCache fetches a BO from the underlying DAO and puts it into cache. The serialized BO is returned.
+//
+// The result is not cached if the entry is invalidated while the fetch is in progress.
+func (dao *CachingGenericDao) fetchAndCache(ctx context.Context, key, storageId string, filter FilterOpt) ([]byte, error) {
+	fill := &cacheFill{key: key}
+	dao.lock.Lock()
+	dao.fills[fill] = true
+	dao.lock.Unlock()
+	defer func() {
+		dao.lock.Lock()
+		delete(dao.fills, fill)
+		dao.lock.Unlock()
+	}()
+
+	bo, err := dao.dao.GdaoFetchOneWithContext(ctx, storageId, filter)
+	if err != nil {
+		return nil, err
+	}
+	if bo == nil {
+		if dao.negativeTtl > 0 && dao.isKeyFilter(storageId, key, filter) {
+			dao.storeFill(fill, []byte{}, dao.negativeTtl)
+		}
+		return nil, nil
+	}
+	value, err := dao.encode(bo)
+	if err != nil {
+		return nil, err
+	}
+	if key == dao.cacheKey(storageId, dao.dao.GdaoCreateFilter(storageId, bo)) {
+		dao.storeFill(fill, value, dao.ttl)
+	}
+	return value, nil
+}
+
+// isKeyFilter returns 'true' if the filter matches a BO by key, i.e. GdaoCreateFilter of a BO built from the filter's
+// equality conditions results in the same cache key. Only entries of such filters are removed by Invalidate, hence
+// "not found" results of other filters are not cached.
+func (dao *CachingGenericDao) isKeyFilter(storageId, key string, filter FilterOpt) bool {
+	fields, ok := filterToKey(filter).(map[string]interface{})
+	if !ok || len(fields) == 0 {
+		return false
+	}
+	bo := NewGenericBo()
+	for field, value := range fields {
+		if bo.GboSetAttr(field, value) != nil {
+			return false
+		}
+	}
+	return key == dao.cacheKey(storageId, dao.dao.GdaoCreateFilter(storageId, bo))
+}
+
+// storeFill puts the result of a fill into cache, unless the fill has become stale.
+func (dao *CachingGenericDao) storeFill(fill *cacheFill, value []byte, ttl time.Duration) {
+	dao.lock.Lock()
+	defer dao.lock.Unlock()
+	if !fill.stale {
+		_ = dao.store.Set(fill.key, value, ttl)
+	}
+}
+
+// encode serializes a BO using the cache's codec.
+func (dao *CachingGenericDao) encode(bo IGenericBo) ([]byte, error) {
+	if gbo, ok := bo.(interface {
+		GboEncode(IGboCodec) ([]byte, error)
+	}); ok {
+		return gbo.GboEncode(dao.codec)
+	}
+	var data interface{}
+	if err := bo.GboTransferViaJson(&data); err != nil {
+		return nil, err
+	}
+	value, err := dao.codec.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", dao.codec.Name(), err)
+	}
+	return value, nil
+}
+
+// GdaoFetchMany implements IGenericDao.GdaoFetchMany.
+func (dao *CachingGenericDao) GdaoFetchMany(storageId string, filter FilterOpt, sorting *SortingOpt, startOffset, numItems int) ([]IGenericBo, error) {
+	return dao.GdaoFetchManyWithContext(nil, storageId, filter, sorting, startOffset, numItems)
+}
+
+// GdaoFetchManyWithContext implements IGenericDaoWithContext.GdaoFetchManyWithContext.
+//
+// Results of this function are not cached.
+func (dao *CachingGenericDao) GdaoFetchManyWithContext(ctx context.Context, storageId string, filter FilterOpt, sorting *SortingOpt, startOffset, numItems int) ([]IGenericBo, error) {
+	return dao.dao.GdaoFetchManyWithContext(ctx, storageId, filter, sorting, startOffset, numItems)
+}
+
+// GdaoCreate implements IGenericDao.GdaoCreate.
+func (dao *CachingGenericDao) GdaoCreate(storageId string, bo IGenericBo) (int, error) {
+	return dao.GdaoCreateWithContext(nil, storageId, bo)
+}
+
+// GdaoCreateWithContext implements IGenericDaoWithContext.GdaoCreateWithContext.
+//
+// The "not found" entry of the BO (if any) is invalidated.
+func (dao *CachingGenericDao) GdaoCreateWithContext(ctx context.Context, storageId string, bo IGenericBo) (int, error) {
+	defer dao.Invalidate(storageId, bo)
+	return dao.dao.GdaoCreateWithContext(ctx, storageId, bo)
+}
+
+// GdaoUpdate implements IGenericDao.GdaoUpdate.
+func (dao *CachingGenericDao) GdaoUpdate(storageId string, bo IGenericBo) (int, error) {
+	return dao.GdaoUpdateWithContext(nil, storageId, bo)
+}
+
+// GdaoUpdateWithContext implements IGenericDaoWithContext.GdaoUpdateWithContext.
+func (dao *CachingGenericDao) GdaoUpdateWithContext(ctx context.Context, storageId string, bo IGenericBo) (int, error) {
+	defer dao.Invalidate(storageId, bo)
+	return dao.dao.GdaoUpdateWithContext(ctx, storageId, bo)
+}
+
+// GdaoSave implements IGenericDao.GdaoSave.
+func (dao *CachingGenericDao) GdaoSave(storageId string, bo IGenericBo) (int, error) {
+	return dao.GdaoSaveWithContext(nil, storageId, bo)
+}
+
+// GdaoSaveWithContext implements IGenericDaoWithContext.GdaoSaveWithContext.
+func (dao *CachingGenericDao) GdaoSaveWithContext(ctx context.Context, storageId string, bo IGenericBo) (int, error) {
+	defer dao.Invalidate(storageId, bo)
+	return dao.dao.GdaoSaveWithContext(ctx, storageId, bo)
+}
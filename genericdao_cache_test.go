@@ -0,0 +1,365 @@
+package godal
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/btnguyen2k/consu/reddo"
+)
+
+func TestLruCacheStore_GetSetDelete(t *testing.T) {
+	name := "TestLruCacheStore_GetSetDelete"
+	store := NewLruCacheStore(0)
+	if _, found, err := store.Get("key"); found || err != nil {
+		t.Fatalf("%s failed: %#v / %#v", name, found, err)
+	}
+	store.Set("key", []byte("value"), 0)
+	if v, found, err := store.Get("key"); !found || err != nil || string(v) != "value" {
+		t.Fatalf("%s failed: %#v / %#v / %#v", name, v, found, err)
+	}
+	store.Delete("key")
+	if _, found, _ := store.Get("key"); found {
+		t.Fatalf("%s failed: entry should have been deleted", name)
+	}
+}
+
+func TestLruCacheStore_Ttl(t *testing.T) {
+	name := "TestLruCacheStore_Ttl"
+	store := NewLruCacheStore(0)
+	now := time.Now()
+	store.funcNow = func() time.Time { return now }
+	store.Set("key", []byte("value"), time.Second)
+	if _, found, _ := store.Get("key"); !found {
+		t.Fatalf("%s failed: entry should exist", name)
+	}
+	now = now.Add(time.Second)
+	if _, found, _ := store.Get("key"); found {
+		t.Fatalf("%s failed: entry should have expired", name)
+	}
+	if store.Size() != 0 {
+		t.Fatalf("%s failed: expired entry should have been removed", name)
+	}
+}
+
+func TestLruCacheStore_Lru(t *testing.T) {
+	name := "TestLruCacheStore_Lru"
+	store := NewLruCacheStore(2)
+	store.Set("k1", []byte("1"), 0)
+	store.Set("k2", []byte("2"), 0)
+	store.Get("k1") // k2 becomes the least recently used entry
+	store.Set("k3", []byte("3"), 0)
+	if store.Size() != 2 {
+		t.Fatalf("%s failed: expected size %#v but received %#v", name, 2, store.Size())
+	}
+	if _, found, _ := store.Get("k2"); found {
+		t.Fatalf("%s failed: k2 should have been evicted", name)
+	}
+	for _, k := range []string{"k1", "k3"} {
+		if _, found, _ := store.Get(k); !found {
+			t.Fatalf("%s failed: %s should exist", name, k)
+		}
+	}
+}
+
+func TestLruCacheStore_DeleteByPrefix(t *testing.T) {
+	name := "TestLruCacheStore_DeleteByPrefix"
+	store := NewLruCacheStore(0)
+	store.Set("a:1", []byte("1"), 0)
+	store.Set("a:2", []byte("2"), 0)
+	store.Set("b:1", []byte("3"), 0)
+	store.DeleteByPrefix("a:")
+	if store.Size() != 1 {
+		t.Fatalf("%s failed: expected size %#v but received %#v", name, 1, store.Size())
+	}
+	if _, found, _ := store.Get("b:1"); !found {
+		t.Fatalf("%s failed: b:1 should exist", name)
+	}
+}
+
+func TestFilterToCacheKey(t *testing.T) {
+	name := "TestFilterToCacheKey"
+	f1 := &FilterOptFieldOpValue{FieldName: "id", Operator: FilterOpEqual, Value: "1"}
+	f2 := FilterOptFieldOpValue{FieldName: "id", Operator: FilterOpEqual, Value: "1"}
+	if FilterToCacheKey(f1) != FilterToCacheKey(f2) {
+		t.Fatalf("%s failed: pointer and value filters should produce the same key", name)
+	}
+	f3 := &FilterOptFieldOpValue{FieldName: "id", Operator: FilterOpEqual, Value: 1}
+	if FilterToCacheKey(f1) == FilterToCacheKey(f3) {
+		t.Fatalf("%s failed: filters with different value types should produce different keys", name)
+	}
+	for _, v := range []interface{}{int64(1), 1.0, float32(1), uint8(1), json.Number("1")} {
+		f := &FilterOptFieldOpValue{FieldName: "id", Operator: FilterOpEqual, Value: v}
+		if FilterToCacheKey(f) != FilterToCacheKey(f3) {
+			t.Fatalf("%s failed: numbers should produce the same key regardless of their types: %#v", name, v)
+		}
+	}
+	if FilterToCacheKey(&FilterOptFieldOpValue{FieldName: "id", Operator: FilterOpEqual, Value: 1.5}) == FilterToCacheKey(f3) {
+		t.Fatalf("%s failed: different numbers should produce different keys", name)
+	}
+	if FilterToCacheKey(&FilterOptFieldIsNull{FieldName: "a"}) == FilterToCacheKey(&FilterOptFieldIsNotNull{FieldName: "a"}) {
+		t.Fatalf("%s failed: IS NULL and IS NOT NULL should produce different keys", name)
+	}
+	and1 := (&FilterOptAnd{}).Add(f1).Add(&FilterOptFieldIsNull{FieldName: "b"})
+	and2 := (&FilterOptAnd{}).Add(f2).Add(FilterOptFieldIsNull{FieldName: "b"})
+	if FilterToCacheKey(and1) != FilterToCacheKey(and2) {
+		t.Fatalf("%s failed: equivalent AND filters should produce the same key", name)
+	}
+	or := (&FilterOptOr{}).Add(f1).Add(&FilterOptFieldIsNull{FieldName: "b"})
+	if FilterToCacheKey(and1) == FilterToCacheKey(or) {
+		t.Fatalf("%s failed: AND and OR filters should produce different keys", name)
+	}
+}
+
+func TestCachingGenericDao_GdaoFetchOne(t *testing.T) {
+	name := "TestCachingGenericDao_GdaoFetchOne"
+	mock := newMockGenericDao()
+	dao := NewCachingGenericDao(mock, nil)
+	mock.GdaoCreate("test", newMockBo("1", 1))
+
+	filter := dao.GdaoCreateFilter("test", newMockBo("1", nil))
+	for i := 0; i < 3; i++ {
+		bo, err := dao.GdaoFetchOne("test", filter)
+		if err != nil || bo == nil {
+			t.Fatalf("%s failed: %#v / %#v", name, bo, err)
+		}
+		if v := bo.GboGetAttrUnsafe("value", reddo.TypeInt); v != int64(1) {
+			t.Fatalf("%s failed: expected %#v but received %#v", name, 1, v)
+		}
+		bo.GboSetAttr("value", 100) // modifying returned BO must not affect cached one
+	}
+	if c := mock.count("fetchOne"); c != 1 {
+		t.Fatalf("%s failed: expected %#v fetch but received %#v", name, 1, c)
+	}
+
+	// filter not built by GdaoCreateFilter: not cached
+	other := (&FilterOptAnd{}).Add(filter)
+	dao.GdaoFetchOne("test", other)
+	dao.GdaoFetchOne("test", other)
+	if c := mock.count("fetchOne"); c != 3 {
+		t.Fatalf("%s failed: expected %#v fetches but received %#v", name, 3, c)
+	}
+}
+
+func TestCachingGenericDao_NegativeCaching(t *testing.T) {
+	name := "TestCachingGenericDao_NegativeCaching"
+	mock := newMockGenericDao()
+	dao := NewCachingGenericDao(mock, nil)
+	filter := dao.GdaoCreateFilter("test", newMockBo("1", nil))
+
+	dao.GdaoFetchOne("test", filter)
+	dao.GdaoFetchOne("test", filter)
+	if c := mock.count("fetchOne"); c != 2 {
+		t.Fatalf("%s failed: negative caching is disabled, expected %#v fetches but received %#v", name, 2, c)
+	}
+
+	dao.SetNegativeTtl(time.Minute)
+	for i := 0; i < 2; i++ {
+		if bo, err := dao.GdaoFetchOne("test", filter); bo != nil || err != nil {
+			t.Fatalf("%s failed: %#v / %#v", name, bo, err)
+		}
+	}
+	if c := mock.count("fetchOne"); c != 3 {
+		t.Fatalf("%s failed: expected %#v fetches but received %#v", name, 3, c)
+	}
+
+	// create must invalidate the "not found" entry
+	dao.GdaoCreate("test", newMockBo("1", 1))
+	if bo, err := dao.GdaoFetchOne("test", filter); bo == nil || err != nil {
+		t.Fatalf("%s failed: %#v / %#v", name, bo, err)
+	}
+}
+
+func TestCachingGenericDao_NegativeCaching_NonKeyFilter(t *testing.T) {
+	name := "TestCachingGenericDao_NegativeCaching_NonKeyFilter"
+	mock := newMockGenericDao()
+	dao := NewCachingGenericDao(mock, nil).SetNegativeTtl(time.Minute)
+	filter := &FilterOptFieldOpValue{FieldName: "value", Operator: FilterOpEqual, Value: 1}
+	if bo, err := dao.GdaoFetchOne("test", filter); bo != nil || err != nil {
+		t.Fatalf("%s failed: %#v / %#v", name, bo, err)
+	}
+	dao.GdaoCreate("test", newMockBo("1", 1))
+	if bo, err := dao.GdaoFetchOne("test", filter); bo == nil || err != nil {
+		t.Fatalf("%s failed: \"not found\" result of a non-key filter should not be cached: %#v / %#v", name, bo, err)
+	}
+}
+
+func TestCachingGenericDao_Invalidate_NumberTypes(t *testing.T) {
+	name := "TestCachingGenericDao_Invalidate_NumberTypes"
+	mock := newMockGenericDao()
+	dao := NewCachingGenericDao(mock, nil)
+	bo := NewGenericBo()
+	bo.GboImportViaMap(map[string]interface{}{"id": int64(1), "value": 1})
+	dao.GdaoCreate("test", bo)
+	filter := dao.GdaoCreateFilter("test", bo)
+	dao.GdaoFetchOne("test", filter)
+
+	// BO decoded from JSON has float64 id
+	bo = NewGenericBo()
+	bo.GboFromJson([]byte(`{"id":1,"value":2}`))
+	dao.GdaoUpdate("test", bo)
+	if bo, _ := dao.GdaoFetchOne("test", filter); bo.GboGetAttrUnsafe("value", reddo.TypeInt) != int64(2) {
+		t.Fatalf("%s failed: cache should have been invalidated on update", name)
+	}
+}
+
+func TestCachingGenericDao_Invalidate(t *testing.T) {
+	name := "TestCachingGenericDao_Invalidate"
+	mock := newMockGenericDao()
+	dao := NewCachingGenericDao(mock, nil)
+	dao.GdaoCreate("test", newMockBo("1", 1))
+	filter := dao.GdaoCreateFilter("test", newMockBo("1", nil))
+
+	dao.GdaoFetchOne("test", filter)
+	dao.GdaoUpdate("test", newMockBo("1", 2))
+	if bo, _ := dao.GdaoFetchOne("test", filter); bo.GboGetAttrUnsafe("value", reddo.TypeInt) != int64(2) {
+		t.Fatalf("%s failed: cache should have been invalidated on update", name)
+	}
+	dao.GdaoSave("test", newMockBo("1", 3))
+	if bo, _ := dao.GdaoFetchOne("test", filter); bo.GboGetAttrUnsafe("value", reddo.TypeInt) != int64(3) {
+		t.Fatalf("%s failed: cache should have been invalidated on save", name)
+	}
+	dao.GdaoDelete("test", newMockBo("1", nil))
+	if bo, _ := dao.GdaoFetchOne("test", filter); bo != nil {
+		t.Fatalf("%s failed: cache should have been invalidated on delete", name)
+	}
+}
+
+func TestCachingGenericDao_GdaoDeleteMany(t *testing.T) {
+	name := "TestCachingGenericDao_GdaoDeleteMany"
+	mock := newMockGenericDao()
+	store := NewLruCacheStore(0)
+	dao := NewCachingGenericDao(mock, store)
+	for i := 0; i < 3; i++ {
+		id := strconv.Itoa(i)
+		dao.GdaoCreate("test", newMockBo(id, i))
+		dao.GdaoCreate("other", newMockBo(id, i))
+		dao.GdaoFetchOne("test", dao.GdaoCreateFilter("test", newMockBo(id, nil)))
+		dao.GdaoFetchOne("other", dao.GdaoCreateFilter("other", newMockBo(id, nil)))
+	}
+	if store.Size() != 6 {
+		t.Fatalf("%s failed: expected %#v entries but received %#v", name, 6, store.Size())
+	}
+	if numRows, err := dao.GdaoDeleteMany("test", nil); numRows != 3 || err != nil {
+		t.Fatalf("%s failed: %#v / %#v", name, numRows, err)
+	}
+	if store.Size() != 3 {
+		t.Fatalf("%s failed: expected %#v entries but received %#v", name, 3, store.Size())
+	}
+}
+
+func TestCachingGenericDao_SingleFlight(t *testing.T) {
+	name := "TestCachingGenericDao_SingleFlight"
+	mock := newMockGenericDao()
+	mock.GdaoCreate("test", newMockBo("1", 1))
+	dao := NewCachingGenericDao(mock, nil)
+	filter := dao.GdaoCreateFilter("test", newMockBo("1", nil))
+
+	// hold the mock's lock so that all goroutines pile up on the same miss
+	mock.lock.Lock()
+	numGoroutines := 10
+	var wg sync.WaitGroup
+	wg.Add(numGoroutines)
+	results := make([]IGenericBo, numGoroutines)
+	for i := 0; i < numGoroutines; i++ {
+		go func(i int) {
+			defer wg.Done()
+			results[i], _ = dao.GdaoFetchOne("test", filter)
+		}(i)
+	}
+	time.Sleep(100 * time.Millisecond)
+	mock.lock.Unlock()
+	wg.Wait()
+
+	if c := mock.count("fetchOne"); c != 1 {
+		t.Fatalf("%s failed: expected %#v fetch but received %#v", name, 1, c)
+	}
+	for i, bo := range results {
+		if bo == nil {
+			t.Fatalf("%s failed: nil result #%d", name, i)
+		}
+		for j := 0; j < i; j++ {
+			if results[j] == bo {
+				t.Fatalf("%s failed: results #%d and #%d should be different instances", name, i, j)
+			}
+		}
+	}
+}
+
+// hookedFetchDao wraps mockGenericDao to modify fetched BOs and to run a callback after each fetch.
+type hookedFetchDao struct {
+	*mockGenericDao
+	afterFetch func(bo IGenericBo)
+}
+
+func (dao *hookedFetchDao) GdaoFetchOne(storageId string, filter FilterOpt) (IGenericBo, error) {
+	bo, err := dao.mockGenericDao.GdaoFetchOne(storageId, filter)
+	if dao.afterFetch != nil {
+		dao.afterFetch(bo)
+	}
+	return bo, err
+}
+
+func TestCachingGenericDao_ValueTypes(t *testing.T) {
+	name := "TestCachingGenericDao_ValueTypes"
+	now := time.Now().Round(0)
+	mock := &hookedFetchDao{mockGenericDao: newMockGenericDao(), afterFetch: func(bo IGenericBo) {
+		if bo != nil {
+			bo.GboSetAttr("time", now)
+			bo.GboSetAttr("big", int64(1)<<60+1)
+		}
+	}}
+	mock.GdaoCreate("test", newMockBo("1", 1))
+	dao := NewCachingGenericDao(mock, nil)
+	filter := dao.GdaoCreateFilter("test", newMockBo("1", nil))
+	uncached, _ := dao.GdaoFetchOne("test", filter)
+	cached, _ := dao.GdaoFetchOne("test", filter)
+	if c := mock.count("fetchOne"); c != 1 {
+		t.Fatalf("%s failed: expected %#v fetch but received %#v", name, 1, c)
+	}
+	for _, path := range []string{"time", "big"} {
+		v1, v2 := uncached.GboGetAttrUnsafe(path, nil), cached.GboGetAttrUnsafe(path, nil)
+		if reflect.TypeOf(v1) != reflect.TypeOf(v2) {
+			t.Fatalf("%s failed: %s expected type %T but received %T", name, path, v1, v2)
+		}
+	}
+	if v := cached.GboGetAttrUnsafe("big", nil); v != int64(1)<<60+1 {
+		t.Fatalf("%s failed: expected %#v but received %#v", name, int64(1)<<60+1, v)
+	}
+	if v := cached.GboGetAttrUnsafe("time", nil).(time.Time); !v.Equal(now) {
+		t.Fatalf("%s failed: expected %s but received %s", name, now, v)
+	}
+}
+
+func TestCachingGenericDao_StaleFill(t *testing.T) {
+	name := "TestCachingGenericDao_StaleFill"
+	mock := &hookedFetchDao{mockGenericDao: newMockGenericDao()}
+	mock.GdaoCreate("test", newMockBo("1", 1))
+	dao := NewCachingGenericDao(mock, nil)
+	filter := dao.GdaoCreateFilter("test", newMockBo("1", nil))
+
+	// a write that commits (and invalidates) after the fetch has read the old value
+	mock.afterFetch = func(bo IGenericBo) {
+		mock.afterFetch = nil
+		dao.GdaoUpdate("test", newMockBo("1", 2))
+	}
+	if bo, _ := dao.GdaoFetchOne("test", filter); bo.GboGetAttrUnsafe("value", reddo.TypeInt) != int64(1) {
+		t.Fatalf("%s failed: the in-progress fetch should return the value it read", name)
+	}
+	if bo, _ := dao.GdaoFetchOne("test", filter); bo.GboGetAttrUnsafe("value", reddo.TypeInt) != int64(2) {
+		t.Fatalf("%s failed: stale value should not have been cached", name)
+	}
+
+	mock.afterFetch = func(bo IGenericBo) {
+		mock.afterFetch = nil
+		dao.GdaoDeleteMany("test", nil)
+	}
+	dao.Purge("test")
+	dao.GdaoFetchOne("test", filter)
+	if bo, _ := dao.GdaoFetchOne("test", filter); bo != nil {
+		t.Fatalf("%s failed: stale value should not have been cached", name)
+	}
+}
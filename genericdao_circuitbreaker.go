@@ -0,0 +1,452 @@
+package godal
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// CircuitState represents state of a circuit breaker.
+//
+// Available since v0.7.0
+type CircuitState int
+
+const (
+	// CircuitClosed is the normal state: operations are passed through to the underlying DAO.
+	CircuitClosed CircuitState = iota
+
+	// CircuitOpen is the tripped state: operations fail fast with ErrGdaoCircuitOpen.
+	CircuitOpen
+
+	// CircuitHalfOpen is the probing state after cool-down: a limited number of operations are let through to test the database store.
+	CircuitHalfOpen
+)
+
+// String implements fmt.Stringer.
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+// CircuitStateChangeCallback is called when a circuit breaker changes its state.
+// storageId is empty if the circuit breaker is global (not per-storage).
+//
+// Available since v0.7.0
+type CircuitStateChangeCallback func(storageId string, from, to CircuitState)
+
+// circuitBreaker holds state of one circuit.
+//
+// generation is increased on every state change, so that outcomes of operations admitted under an earlier state
+// can be ignored.
+type circuitBreaker struct {
+	state         CircuitState
+	generation    uint64
+	failures      int
+	openedAt      time.Time
+	halfOpenCalls int
+}
+
+// setState changes state of the circuit and starts a new generation.
+func (cb *circuitBreaker) setState(state CircuitState) {
+	if cb.state != state {
+		cb.state = state
+		cb.generation++
+	}
+}
+
+// circuitAdmission identifies the circuit and generation under which an operation was allowed to pass through.
+type circuitAdmission struct {
+	cb         *circuitBreaker
+	generation uint64
+}
+
+/*----------------------------------------------------------------------*/
+
+// NewCircuitBreakerGenericDao constructs a new CircuitBreakerGenericDao that wraps the specified DAO.
+//
+// Default settings: global circuit (not per-storage), trips after 5 consecutive failures, cool-down period is 30 seconds,
+// one probe is allowed in half-open state, every error other than ErrGdaoDuplicatedEntry counts as failure.
+// Operations canceled by the caller's own context (context.Canceled) count as neither failure nor success.
+//
+// Available since v0.7.0
+func NewCircuitBreakerGenericDao(dao IGenericDao) *CircuitBreakerGenericDao {
+	return &CircuitBreakerGenericDao{
+		origDao:          dao,
+		dao:              ToGenericDaoWithContext(dao),
+		failureThreshold: 5,
+		coolDown:         30 * time.Second,
+		halfOpenMaxCalls: 1,
+		breakers:         make(map[string]*circuitBreaker),
+		funcNow:          time.Now,
+	}
+}
+
+// CircuitBreakerGenericDao is a circuit-breaker wrapper of IGenericDao.
+//
+// State transitions:
+//   - closed -> open: the number of consecutive failures reaches the failure threshold.
+//   - open -> half-open: the cool-down period has passed. While open, operations fail fast with ErrGdaoCircuitOpen.
+//   - half-open -> closed: a probe operation succeeds.
+//   - half-open -> open: a probe operation fails.
+//
+// Outcomes of operations that were allowed under an earlier state are ignored, e.g. a slow operation admitted before
+// the circuit opened does not close the circuit when it eventually succeeds.
+//
+// Circuits can be global (one circuit for all storages) or per-storage (one circuit for each storage-id).
+// GdaoCreateFilter and GetRowMapper do not touch the database store and hence are not guarded.
+//
+// Available since v0.7.0
+type CircuitBreakerGenericDao struct {
+	origDao           IGenericDao
+	dao               IGenericDaoWithContext
+	perStorage        bool
+	failureThreshold  int
+	coolDown          time.Duration
+	halfOpenMaxCalls  int
+	failureClassifier TransientErrorClassifier
+	callback          CircuitStateChangeCallback
+	lock              sync.Mutex
+	breakers          map[string]*circuitBreaker
+	funcNow           func() time.Time
+}
+
+// GetDao returns the underlying DAO.
+func (dao *CircuitBreakerGenericDao) GetDao() IGenericDao {
+	return dao.origDao
+}
+
+// GetPerStorage returns 'true' if each storage-id has its own circuit, 'false' if one global circuit is used.
+func (dao *CircuitBreakerGenericDao) GetPerStorage() bool {
+	return dao.perStorage
+}
+
+// SetPerStorage enables/disables per-storage circuits. Default value is 'false' (global circuit).
+//
+// Existing circuit states are reset.
+func (dao *CircuitBreakerGenericDao) SetPerStorage(enabled bool) *CircuitBreakerGenericDao {
+	dao.lock.Lock()
+	defer dao.lock.Unlock()
+	dao.perStorage = enabled
+	dao.breakers = make(map[string]*circuitBreaker)
+	return dao
+}
+
+// GetFailureThreshold returns the number of consecutive failures that trips the circuit.
+func (dao *CircuitBreakerGenericDao) GetFailureThreshold() int {
+	return dao.failureThreshold
+}
+
+// SetFailureThreshold sets the number of consecutive failures that trips the circuit. Value < 1 is treated as 1.
+func (dao *CircuitBreakerGenericDao) SetFailureThreshold(threshold int) *CircuitBreakerGenericDao {
+	dao.failureThreshold = threshold
+	return dao
+}
+
+// GetCoolDown returns the period the circuit stays open before switching to half-open.
+func (dao *CircuitBreakerGenericDao) GetCoolDown() time.Duration {
+	return dao.coolDown
+}
+
+// SetCoolDown sets the period the circuit stays open before switching to half-open.
+func (dao *CircuitBreakerGenericDao) SetCoolDown(coolDown time.Duration) *CircuitBreakerGenericDao {
+	dao.coolDown = coolDown
+	return dao
+}
+
+// GetHalfOpenMaxCalls returns the maximum number of concurrent probe operations allowed in half-open state.
+func (dao *CircuitBreakerGenericDao) GetHalfOpenMaxCalls() int {
+	return dao.halfOpenMaxCalls
+}
+
+// SetHalfOpenMaxCalls sets the maximum number of concurrent probe operations allowed in half-open state. Value < 1 is treated as 1.
+func (dao *CircuitBreakerGenericDao) SetHalfOpenMaxCalls(maxCalls int) *CircuitBreakerGenericDao {
+	dao.halfOpenMaxCalls = maxCalls
+	return dao
+}
+
+// GetFailureClassifier returns the function that decides if an error counts as failure.
+func (dao *CircuitBreakerGenericDao) GetFailureClassifier() TransientErrorClassifier {
+	return dao.failureClassifier
+}
+
+// SetFailureClassifier sets the function that decides if an error counts as failure (e.g. dynamodb.IsErrorTransient).
+// If nil, every error other than ErrGdaoDuplicatedEntry counts as failure.
+func (dao *CircuitBreakerGenericDao) SetFailureClassifier(classifier TransientErrorClassifier) *CircuitBreakerGenericDao {
+	dao.failureClassifier = classifier
+	return dao
+}
+
+// GetStateChangeCallback returns the callback function that is called when a circuit changes its state.
+func (dao *CircuitBreakerGenericDao) GetStateChangeCallback() CircuitStateChangeCallback {
+	return dao.callback
+}
+
+// SetStateChangeCallback sets the callback function that is called when a circuit changes its state, e.g. for alerting.
+func (dao *CircuitBreakerGenericDao) SetStateChangeCallback(callback CircuitStateChangeCallback) *CircuitBreakerGenericDao {
+	dao.callback = callback
+	return dao
+}
+
+// circuitKey returns key of the circuit that guards the storage.
+func (dao *CircuitBreakerGenericDao) circuitKey(storageId string) string {
+	if dao.perStorage {
+		return storageId
+	}
+	return ""
+}
+
+// GetState returns the current state of the circuit that guards the storage.
+func (dao *CircuitBreakerGenericDao) GetState(storageId string) CircuitState {
+	dao.lock.Lock()
+	defer dao.lock.Unlock()
+	if cb, ok := dao.breakers[dao.circuitKey(storageId)]; ok {
+		if cb.state == CircuitOpen && !dao.funcNow().Before(cb.openedAt.Add(dao.coolDown)) {
+			return CircuitHalfOpen
+		}
+		return cb.state
+	}
+	return CircuitClosed
+}
+
+// Reset forces the circuit that guards the storage to closed state.
+func (dao *CircuitBreakerGenericDao) Reset(storageId string) {
+	key := dao.circuitKey(storageId)
+	dao.lock.Lock()
+	from := CircuitClosed
+	if cb, ok := dao.breakers[key]; ok {
+		from = cb.state
+		delete(dao.breakers, key)
+	}
+	dao.lock.Unlock()
+	dao.notify(key, from, CircuitClosed)
+}
+
+func (dao *CircuitBreakerGenericDao) notify(key string, from, to CircuitState) {
+	if from != to && dao.callback != nil {
+		dao.callback(key, from, to)
+	}
+}
+
+func (dao *CircuitBreakerGenericDao) isFailure(err error) bool {
+	if err == nil || errors.Is(err, ErrGdaoDuplicatedEntry) {
+		return false
+	}
+	if dao.failureClassifier != nil {
+		return dao.failureClassifier(err)
+	}
+	return true
+}
+
+// beforeCall checks if the operation is allowed to pass through.
+func (dao *CircuitBreakerGenericDao) beforeCall(key string) (circuitAdmission, error) {
+	dao.lock.Lock()
+	cb, ok := dao.breakers[key]
+	if !ok {
+		cb = &circuitBreaker{state: CircuitClosed}
+		dao.breakers[key] = cb
+	}
+	from := cb.state
+	if cb.state == CircuitOpen && !dao.funcNow().Before(cb.openedAt.Add(dao.coolDown)) {
+		cb.setState(CircuitHalfOpen)
+		cb.halfOpenCalls = 0
+	}
+	var err error
+	switch cb.state {
+	case CircuitOpen:
+		err = ErrGdaoCircuitOpen
+	case CircuitHalfOpen:
+		maxCalls := dao.halfOpenMaxCalls
+		if maxCalls < 1 {
+			maxCalls = 1
+		}
+		if cb.halfOpenCalls >= maxCalls {
+			err = ErrGdaoCircuitOpen
+		} else {
+			cb.halfOpenCalls++
+		}
+	}
+	to := cb.state
+	admission := circuitAdmission{cb: cb, generation: cb.generation}
+	dao.lock.Unlock()
+	dao.notify(key, from, to)
+	return admission, err
+}
+
+// isCanceledByCaller returns 'true' if the operation failed because the caller's context was canceled.
+func isCanceledByCaller(ctx context.Context, err error) bool {
+	return ctx != nil && ctx.Err() != nil && errors.Is(err, context.Canceled)
+}
+
+// afterCall records the outcome of the operation.
+//
+// The outcome is ignored if the circuit has been reset or has changed its state since the operation was admitted, or if
+// the operation was canceled by the caller.
+func (dao *CircuitBreakerGenericDao) afterCall(ctx context.Context, key string, admission circuitAdmission, err error) {
+	ignored := isCanceledByCaller(ctx, err)
+	failure := dao.isFailure(err)
+	dao.lock.Lock()
+	cb, ok := dao.breakers[key]
+	if !ok || cb != admission.cb || cb.generation != admission.generation {
+		dao.lock.Unlock()
+		return
+	}
+	from := cb.state
+	switch {
+	case ignored:
+	case cb.state == CircuitHalfOpen && !failure:
+		cb.setState(CircuitClosed)
+		cb.failures = 0
+	case cb.state == CircuitHalfOpen:
+		cb.setState(CircuitOpen)
+		cb.openedAt = dao.funcNow()
+	case cb.state == CircuitClosed && !failure:
+		cb.failures = 0
+	case cb.state == CircuitClosed:
+		cb.failures++
+		if cb.failures >= dao.failureThreshold {
+			cb.setState(CircuitOpen)
+			cb.openedAt = dao.funcNow()
+		}
+	}
+	if cb.state == CircuitHalfOpen && cb.halfOpenCalls > 0 {
+		cb.halfOpenCalls--
+	}
+	to := cb.state
+	dao.lock.Unlock()
+	dao.notify(key, from, to)
+}
+
+// execute calls the function if the circuit allows.
+func (dao *CircuitBreakerGenericDao) execute(ctx context.Context, storageId string, f func() error) error {
+	key := dao.circuitKey(storageId)
+	admission, err := dao.beforeCall(key)
+	if err != nil {
+		return err
+	}
+	err = f()
+	dao.afterCall(ctx, key, admission, err)
+	return err
+}
+
+// GdaoCreateFilter implements IGenericDao.GdaoCreateFilter.
+func (dao *CircuitBreakerGenericDao) GdaoCreateFilter(storageId string, bo IGenericBo) FilterOpt {
+	return dao.dao.GdaoCreateFilter(storageId, bo)
+}
+
+// GetRowMapper implements IGenericDao.GetRowMapper.
+func (dao *CircuitBreakerGenericDao) GetRowMapper() IRowMapper {
+	return dao.dao.GetRowMapper()
+}
+
+// GdaoDelete implements IGenericDao.GdaoDelete.
+func (dao *CircuitBreakerGenericDao) GdaoDelete(storageId string, bo IGenericBo) (int, error) {
+	return dao.GdaoDeleteWithContext(nil, storageId, bo)
+}
+
+// GdaoDeleteWithContext implements IGenericDaoWithContext.GdaoDeleteWithContext.
+func (dao *CircuitBreakerGenericDao) GdaoDeleteWithContext(ctx context.Context, storageId string, bo IGenericBo) (int, error) {
+	var numRows int
+	err := dao.execute(ctx, storageId, func() (err error) {
+		numRows, err = dao.dao.GdaoDeleteWithContext(ctx, storageId, bo)
+		return err
+	})
+	return numRows, err
+}
+
+// GdaoDeleteMany implements IGenericDao.GdaoDeleteMany.
+func (dao *CircuitBreakerGenericDao) GdaoDeleteMany(storageId string, filter FilterOpt) (int, error) {
+	return dao.GdaoDeleteManyWithContext(nil, storageId, filter)
+}
+
+// GdaoDeleteManyWithContext implements IGenericDaoWithContext.GdaoDeleteManyWithContext.
+func (dao *CircuitBreakerGenericDao) GdaoDeleteManyWithContext(ctx context.Context, storageId string, filter FilterOpt) (int, error) {
+	var numRows int
+	err := dao.execute(ctx, storageId, func() (err error) {
+		numRows, err = dao.dao.GdaoDeleteManyWithContext(ctx, storageId, filter)
+		return err
+	})
+	return numRows, err
+}
+
+// GdaoFetchOne implements IGenericDao.GdaoFetchOne.
+func (dao *CircuitBreakerGenericDao) GdaoFetchOne(storageId string, filter FilterOpt) (IGenericBo, error) {
+	return dao.GdaoFetchOneWithContext(nil, storageId, filter)
+}
+
+// GdaoFetchOneWithContext implements IGenericDaoWithContext.GdaoFetchOneWithContext.
+func (dao *CircuitBreakerGenericDao) GdaoFetchOneWithContext(ctx context.Context, storageId string, filter FilterOpt) (IGenericBo, error) {
+	var bo IGenericBo
+	err := dao.execute(ctx, storageId, func() (err error) {
+		bo, err = dao.dao.GdaoFetchOneWithContext(ctx, storageId, filter)
+		return err
+	})
+	return bo, err
+}
+
+// GdaoFetchMany implements IGenericDao.GdaoFetchMany.
+func (dao *CircuitBreakerGenericDao) GdaoFetchMany(storageId string, filter FilterOpt, sorting *SortingOpt, startOffset, numItems int) ([]IGenericBo, error) {
+	return dao.GdaoFetchManyWithContext(nil, storageId, filter, sorting, startOffset, numItems)
+}
+
+// GdaoFetchManyWithContext implements IGenericDaoWithContext.GdaoFetchManyWithContext.
+func (dao *CircuitBreakerGenericDao) GdaoFetchManyWithContext(ctx context.Context, storageId string, filter FilterOpt, sorting *SortingOpt, startOffset, numItems int) ([]IGenericBo, error) {
+	var boList []IGenericBo
+	err := dao.execute(ctx, storageId, func() (err error) {
+		boList, err = dao.dao.GdaoFetchManyWithContext(ctx, storageId, filter, sorting, startOffset, numItems)
+		return err
+	})
+	return boList, err
+}
+
+// GdaoCreate implements IGenericDao.GdaoCreate.
+func (dao *CircuitBreakerGenericDao) GdaoCreate(storageId string, bo IGenericBo) (int, error) {
+	return dao.GdaoCreateWithContext(nil, storageId, bo)
+}
+
+// GdaoCreateWithContext implements IGenericDaoWithContext.GdaoCreateWithContext.
+func (dao *CircuitBreakerGenericDao) GdaoCreateWithContext(ctx context.Context, storageId string, bo IGenericBo) (int, error) {
+	var numRows int
+	err := dao.execute(ctx, storageId, func() (err error) {
+		numRows, err = dao.dao.GdaoCreateWithContext(ctx, storageId, bo)
+		return err
+	})
+	return numRows, err
+}
+
+// GdaoUpdate implements IGenericDao.GdaoUpdate.
+func (dao *CircuitBreakerGenericDao) GdaoUpdate(storageId string, bo IGenericBo) (int, error) {
+	return dao.GdaoUpdateWithContext(nil, storageId, bo)
+}
+
+// GdaoUpdateWithContext implements IGenericDaoWithContext.GdaoUpdateWithContext.
+func (dao *CircuitBreakerGenericDao) GdaoUpdateWithContext(ctx context.Context, storageId string, bo IGenericBo) (int, error) {
+	var numRows int
+	err := dao.execute(ctx, storageId, func() (err error) {
+		numRows, err = dao.dao.GdaoUpdateWithContext(ctx, storageId, bo)
+		return err
+	})
+	return numRows, err
+}
+
+// GdaoSave implements IGenericDao.GdaoSave.
+func (dao *CircuitBreakerGenericDao) GdaoSave(storageId string, bo IGenericBo) (int, error) {
+	return dao.GdaoSaveWithContext(nil, storageId, bo)
+}
+
+// GdaoSaveWithContext implements IGenericDaoWithContext.GdaoSaveWithContext.
+func (dao *CircuitBreakerGenericDao) GdaoSaveWithContext(ctx context.Context, storageId string, bo IGenericBo) (int, error) {
+	var numRows int
+	err := dao.execute(ctx, storageId, func() (err error) {
+		numRows, err = dao.dao.GdaoSaveWithContext(ctx, storageId, bo)
+		return err
+	})
+	return numRows, err
+}
@@ -0,0 +1,313 @@
+package godal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+type circuitStateChangeForTest struct {
+	storageId string
+	from, to  CircuitState
+}
+
+func newCircuitBreakerGenericDaoForTest(mock *mockGenericDao) (*CircuitBreakerGenericDao, *time.Time, *[]circuitStateChangeForTest) {
+	now := time.Now()
+	changes := make([]circuitStateChangeForTest, 0)
+	dao := NewCircuitBreakerGenericDao(mock).SetFailureThreshold(3).SetCoolDown(time.Minute)
+	dao.funcNow = func() time.Time { return now }
+	dao.SetStateChangeCallback(func(storageId string, from, to CircuitState) {
+		changes = append(changes, circuitStateChangeForTest{storageId, from, to})
+	})
+	return dao, &now, &changes
+}
+
+func TestCircuitState_String(t *testing.T) {
+	name := "TestCircuitState_String"
+	expected := map[CircuitState]string{CircuitClosed: "closed", CircuitOpen: "open", CircuitHalfOpen: "half-open", CircuitState(-1): "unknown"}
+	for s, str := range expected {
+		if s.String() != str {
+			t.Fatalf("%s failed: expected %#v but received %#v", name, str, s.String())
+		}
+	}
+}
+
+func TestCircuitBreakerGenericDao_Trip(t *testing.T) {
+	name := "TestCircuitBreakerGenericDao_Trip"
+	mock := newMockGenericDao()
+	dao, now, changes := newCircuitBreakerGenericDaoForTest(mock)
+	mock.errToThrow = errors.New("connection refused")
+
+	for i := 0; i < 3; i++ {
+		if _, err := dao.GdaoFetchOne("test", nil); err != mock.errToThrow {
+			t.Fatalf("%s failed: expected error %#v but received %#v", name, mock.errToThrow, err)
+		}
+	}
+	if s := dao.GetState("test"); s != CircuitOpen {
+		t.Fatalf("%s failed: expected state %s but received %s", name, CircuitOpen, s)
+	}
+	if _, err := dao.GdaoFetchOne("test", nil); err != ErrGdaoCircuitOpen {
+		t.Fatalf("%s failed: expected error %#v but received %#v", name, ErrGdaoCircuitOpen, err)
+	}
+	if _, err := dao.GdaoCreate("other", newMockBo("1", 1)); err != ErrGdaoCircuitOpen {
+		t.Fatalf("%s failed: global circuit should reject all storages, received %#v", name, err)
+	}
+	if c := mock.count("fetchOne"); c != 3 {
+		t.Fatalf("%s failed: expected %#v calls but received %#v", name, 3, c)
+	}
+	if len(*changes) != 1 || (*changes)[0] != (circuitStateChangeForTest{"", CircuitClosed, CircuitOpen}) {
+		t.Fatalf("%s failed: unexpected state changes %#v", name, *changes)
+	}
+
+	*now = now.Add(time.Minute)
+	if s := dao.GetState("test"); s != CircuitHalfOpen {
+		t.Fatalf("%s failed: expected state %s but received %s", name, CircuitHalfOpen, s)
+	}
+}
+
+func TestCircuitBreakerGenericDao_SuccessResetsFailures(t *testing.T) {
+	name := "TestCircuitBreakerGenericDao_SuccessResetsFailures"
+	mock := newMockGenericDao()
+	dao, _, _ := newCircuitBreakerGenericDaoForTest(mock)
+	for i := 0; i < 5; i++ {
+		mock.errToThrow = errors.New("connection refused")
+		dao.GdaoFetchOne("test", nil)
+		dao.GdaoFetchOne("test", nil)
+		mock.errToThrow = nil
+		dao.GdaoFetchOne("test", nil)
+	}
+	if s := dao.GetState("test"); s != CircuitClosed {
+		t.Fatalf("%s failed: expected state %s but received %s", name, CircuitClosed, s)
+	}
+}
+
+func TestCircuitBreakerGenericDao_NonFailure(t *testing.T) {
+	name := "TestCircuitBreakerGenericDao_NonFailure"
+	mock := newMockGenericDao()
+	dao, _, _ := newCircuitBreakerGenericDaoForTest(mock)
+	mock.errToThrow = ErrGdaoDuplicatedEntry
+	for i := 0; i < 5; i++ {
+		dao.GdaoCreate("test", newMockBo("1", 1))
+	}
+	if s := dao.GetState("test"); s != CircuitClosed {
+		t.Fatalf("%s failed: duplicated entry should not count as failure, state is %s", name, s)
+	}
+
+	dao.SetFailureClassifier(isErrorTransientForTest)
+	mock.errToThrow = errors.New("permanent error")
+	for i := 0; i < 5; i++ {
+		dao.GdaoFetchOne("test", nil)
+	}
+	if s := dao.GetState("test"); s != CircuitClosed {
+		t.Fatalf("%s failed: non-transient error should not count as failure, state is %s", name, s)
+	}
+	mock.errToThrow = errTransientForTest
+	for i := 0; i < 3; i++ {
+		dao.GdaoFetchOne("test", nil)
+	}
+	if s := dao.GetState("test"); s != CircuitOpen {
+		t.Fatalf("%s failed: expected state %s but received %s", name, CircuitOpen, s)
+	}
+}
+
+func TestCircuitBreakerGenericDao_WrappedAndCanceled(t *testing.T) {
+	name := "TestCircuitBreakerGenericDao_WrappedAndCanceled"
+	mock := newMockGenericDao()
+	dao, now, _ := newCircuitBreakerGenericDaoForTest(mock)
+	mock.errToThrow = fmt.Errorf("insert: %w", ErrGdaoDuplicatedEntry)
+	for i := 0; i < 5; i++ {
+		dao.GdaoCreate("test", newMockBo("1", 1))
+	}
+	if s := dao.GetState("test"); s != CircuitClosed {
+		t.Fatalf("%s failed: wrapped duplicated entry should not count as failure, state is %s", name, s)
+	}
+
+	// operations canceled by the caller count as neither failure nor success
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	mock.errToThrow = fmt.Errorf("query: %w", context.Canceled)
+	for i := 0; i < 5; i++ {
+		dao.GdaoFetchOneWithContext(ctx, "test", nil)
+	}
+	if s := dao.GetState("test"); s != CircuitClosed {
+		t.Fatalf("%s failed: caller's cancellation should not count as failure, state is %s", name, s)
+	}
+	mock.errToThrow = errors.New("connection refused")
+	for i := 0; i < 3; i++ {
+		dao.GdaoFetchOne("test", nil)
+	}
+	*now = now.Add(time.Minute)
+	mock.errToThrow = context.Canceled
+	dao.GdaoFetchOneWithContext(ctx, "test", nil)
+	if s := dao.GetState("test"); s != CircuitHalfOpen {
+		t.Fatalf("%s failed: canceled probe should keep the circuit half-open, state is %s", name, s)
+	}
+	mock.errToThrow = nil
+	if _, err := dao.GdaoFetchOne("test", nil); err != nil {
+		t.Fatalf("%s failed: canceled probe should release its slot, received %#v", name, err)
+	}
+	if s := dao.GetState("test"); s != CircuitClosed {
+		t.Fatalf("%s failed: expected state %s but received %s", name, CircuitClosed, s)
+	}
+
+	// context.Canceled not caused by the caller's context counts as failure
+	mock.errToThrow = context.Canceled
+	for i := 0; i < 3; i++ {
+		dao.GdaoFetchOneWithContext(context.Background(), "test", nil)
+	}
+	if s := dao.GetState("test"); s != CircuitOpen {
+		t.Fatalf("%s failed: expected state %s but received %s", name, CircuitOpen, s)
+	}
+}
+
+func TestCircuitBreakerGenericDao_HalfOpen(t *testing.T) {
+	name := "TestCircuitBreakerGenericDao_HalfOpen"
+	mock := newMockGenericDao()
+	dao, now, changes := newCircuitBreakerGenericDaoForTest(mock)
+	mock.errToThrow = errors.New("connection refused")
+	for i := 0; i < 3; i++ {
+		dao.GdaoFetchOne("test", nil)
+	}
+
+	// failed probe re-opens the circuit
+	*now = now.Add(time.Minute)
+	if _, err := dao.GdaoFetchOne("test", nil); err != mock.errToThrow {
+		t.Fatalf("%s failed: expected error %#v but received %#v", name, mock.errToThrow, err)
+	}
+	if s := dao.GetState("test"); s != CircuitOpen {
+		t.Fatalf("%s failed: expected state %s but received %s", name, CircuitOpen, s)
+	}
+	if _, err := dao.GdaoFetchOne("test", nil); err != ErrGdaoCircuitOpen {
+		t.Fatalf("%s failed: expected error %#v but received %#v", name, ErrGdaoCircuitOpen, err)
+	}
+
+	// successful probe closes the circuit
+	*now = now.Add(time.Minute)
+	mock.errToThrow = nil
+	if _, err := dao.GdaoFetchOne("test", nil); err != nil {
+		t.Fatalf("%s failed: %#v", name, err)
+	}
+	if s := dao.GetState("test"); s != CircuitClosed {
+		t.Fatalf("%s failed: expected state %s but received %s", name, CircuitClosed, s)
+	}
+
+	expected := []circuitStateChangeForTest{
+		{"", CircuitClosed, CircuitOpen},
+		{"", CircuitOpen, CircuitHalfOpen},
+		{"", CircuitHalfOpen, CircuitOpen},
+		{"", CircuitOpen, CircuitHalfOpen},
+		{"", CircuitHalfOpen, CircuitClosed},
+	}
+	if len(*changes) != len(expected) {
+		t.Fatalf("%s failed: expected state changes %#v but received %#v", name, expected, *changes)
+	}
+	for i, c := range expected {
+		if (*changes)[i] != c {
+			t.Fatalf("%s failed: expected state changes %#v but received %#v", name, expected, *changes)
+		}
+	}
+}
+
+func TestCircuitBreakerGenericDao_HalfOpenMaxCalls(t *testing.T) {
+	name := "TestCircuitBreakerGenericDao_HalfOpenMaxCalls"
+	mock := newMockGenericDao()
+	dao, now, _ := newCircuitBreakerGenericDaoForTest(mock)
+	mock.errToThrow = errors.New("connection refused")
+	for i := 0; i < 3; i++ {
+		dao.GdaoFetchOne("test", nil)
+	}
+	*now = now.Add(time.Minute)
+
+	// the first probe is in-flight: the second call must be rejected
+	key := dao.circuitKey("test")
+	admission, err := dao.beforeCall(key)
+	if err != nil {
+		t.Fatalf("%s failed: %#v", name, err)
+	}
+	if _, err := dao.beforeCall(key); err != ErrGdaoCircuitOpen {
+		t.Fatalf("%s failed: expected error %#v but received %#v", name, ErrGdaoCircuitOpen, err)
+	}
+	dao.afterCall(nil, key, admission, nil)
+	if s := dao.GetState("test"); s != CircuitClosed {
+		t.Fatalf("%s failed: expected state %s but received %s", name, CircuitClosed, s)
+	}
+}
+
+func TestCircuitBreakerGenericDao_StaleOutcome(t *testing.T) {
+	name := "TestCircuitBreakerGenericDao_StaleOutcome"
+	mock := newMockGenericDao()
+	dao, now, _ := newCircuitBreakerGenericDaoForTest(mock)
+	key := dao.circuitKey("test")
+
+	// a slow call admitted while closed succeeds after the circuit has opened
+	slow, _ := dao.beforeCall(key)
+	mock.errToThrow = errors.New("connection refused")
+	for i := 0; i < 3; i++ {
+		dao.GdaoFetchOne("test", nil)
+	}
+	dao.afterCall(nil, key, slow, nil)
+	if s := dao.GetState("test"); s != CircuitOpen {
+		t.Fatalf("%s failed: expected state %s but received %s", name, CircuitOpen, s)
+	}
+
+	// a slow call admitted while half-open succeeds after the probe has re-opened the circuit
+	*now = now.Add(time.Minute)
+	probe, _ := dao.beforeCall(key)
+	dao.afterCall(nil, key, probe, mock.errToThrow)
+	*now = now.Add(time.Minute)
+	if s := dao.GetState("test"); s != CircuitHalfOpen {
+		t.Fatalf("%s failed: expected state %s but received %s", name, CircuitHalfOpen, s)
+	}
+	dao.afterCall(nil, key, probe, nil)
+	if s := dao.GetState("test"); s != CircuitHalfOpen {
+		t.Fatalf("%s failed: expected state %s but received %s", name, CircuitHalfOpen, s)
+	}
+
+	// successes while closed reset the consecutive-failure counter
+	dao.Reset("test")
+	mock.errToThrow = errors.New("connection refused")
+	dao.GdaoFetchOne("test", nil)
+	dao.GdaoFetchOne("test", nil)
+	mock.errToThrow = nil
+	dao.GdaoFetchOne("test", nil)
+	mock.errToThrow = errors.New("connection refused")
+	dao.GdaoFetchOne("test", nil)
+	dao.GdaoFetchOne("test", nil)
+	if s := dao.GetState("test"); s != CircuitClosed {
+		t.Fatalf("%s failed: expected state %s but received %s", name, CircuitClosed, s)
+	}
+}
+
+func TestCircuitBreakerGenericDao_PerStorage(t *testing.T) {
+	name := "TestCircuitBreakerGenericDao_PerStorage"
+	mock := newMockGenericDao()
+	dao, _, changes := newCircuitBreakerGenericDaoForTest(mock)
+	dao.SetPerStorage(true)
+	mock.errToThrow = errors.New("connection refused")
+	for i := 0; i < 3; i++ {
+		dao.GdaoFetchOne("test", nil)
+	}
+	if s := dao.GetState("test"); s != CircuitOpen {
+		t.Fatalf("%s failed: expected state %s but received %s", name, CircuitOpen, s)
+	}
+	if s := dao.GetState("other"); s != CircuitClosed {
+		t.Fatalf("%s failed: expected state %s but received %s", name, CircuitClosed, s)
+	}
+	mock.errToThrow = nil
+	if _, err := dao.GdaoCreate("other", newMockBo("1", 1)); err != nil {
+		t.Fatalf("%s failed: %#v", name, err)
+	}
+	if len(*changes) != 1 || (*changes)[0].storageId != "test" {
+		t.Fatalf("%s failed: unexpected state changes %#v", name, *changes)
+	}
+
+	dao.Reset("test")
+	if s := dao.GetState("test"); s != CircuitClosed {
+		t.Fatalf("%s failed: expected state %s but received %s", name, CircuitClosed, s)
+	}
+	if len(*changes) != 2 || (*changes)[1] != (circuitStateChangeForTest{"test", CircuitOpen, CircuitClosed}) {
+		t.Fatalf("%s failed: unexpected state changes %#v", name, *changes)
+	}
+}
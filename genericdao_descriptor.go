@@ -0,0 +1,306 @@
+package godal
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Backends supported by storage descriptors.
+//
+// Available since v0.7.0
+const (
+	BackendSql      = "sql"
+	BackendMongo    = "mongo"
+	BackendDynamodb = "dynamodb"
+	BackendCosmosdb = "cosmosdb"
+)
+
+// Name transformations supported by storage descriptors, see package sql's NameTransformation.
+//
+// Available since v0.7.0
+const (
+	NameTransformationIntact = "intact"
+	NameTransformationUpper  = "upper"
+	NameTransformationLower  = "lower"
+)
+
+// FieldDescriptor describes a BO field and the database column it is stored to.
+//
+// Available since v0.7.0
+type FieldDescriptor struct {
+	Name   string `json:"name"`             // name of the BO field
+	Column string `json:"column,omitempty"` // name of the database column, default to the BO field name
+	Key    bool   `json:"key,omitempty"`    // the column is part of the primary key
+}
+
+// GetColumn returns the database column of the field.
+func (f *FieldDescriptor) GetColumn() string {
+	if f.Column != "" {
+		return f.Column
+	}
+	return f.Name
+}
+
+// StorageDescriptor describes a storage (table/collection) and how BOs are mapped to it.
+//
+// Available since v0.7.0
+type StorageDescriptor struct {
+	Id                 string             `json:"id"`                            // storage id (table/collection name)
+	Backend            string             `json:"backend"`                       // one of the BackendXXX constants
+	NameTransformation string             `json:"name_transformation,omitempty"` // (database/sql only) one of the NameTransformationXXX constants
+	Fields             []*FieldDescriptor `json:"fields,omitempty"`              // fields of the storage
+	IdPath             string             `json:"id_path,omitempty"`             // (Azure Cosmos DB only) path to fetch id value from BO
+	PkPath             string             `json:"pk_path,omitempty"`             // (Azure Cosmos DB only) path to fetch partition key value from BO
+	Schema             *Schema            `json:"schema,omitempty"`              // schema to validate BOs before writes, see AbstractGenericDao.SetSchema
+}
+
+// ColumnsList returns the database columns of the storage.
+func (d *StorageDescriptor) ColumnsList() []string {
+	result := make([]string, 0, len(d.Fields))
+	for _, f := range d.Fields {
+		result = append(result, f.GetColumn())
+	}
+	return result
+}
+
+// KeyColumns returns the database columns of the storage's primary key.
+func (d *StorageDescriptor) KeyColumns() []string {
+	result := make([]string, 0)
+	for _, f := range d.Fields {
+		if f.Key {
+			result = append(result, f.GetColumn())
+		}
+	}
+	return result
+}
+
+// FieldToColumnMap returns the mappings {field-name:column-name}.
+func (d *StorageDescriptor) FieldToColumnMap() map[string]string {
+	result := make(map[string]string, len(d.Fields))
+	for _, f := range d.Fields {
+		result[f.Name] = f.GetColumn()
+	}
+	return result
+}
+
+// StorageDescriptors is a set of storage descriptors, usually loaded from a YAML or JSON file:
+//
+//	storages:
+//	  - id: users
+//	    backend: sql
+//	    name_transformation: lower
+//	    fields:
+//	      - {name: id, column: uid, key: true}
+//	      - {name: email, column: uemail}
+//	    schema:
+//	      type: object
+//	      required: [id, email]
+//	  - id: sessions
+//	    backend: dynamodb
+//	    fields:
+//	      - {name: user, key: true}
+//	      - {name: created, key: true}
+//	  - id: profiles
+//	    backend: cosmosdb
+//	    id_path: id
+//	    pk_path: tenant
+//
+// Each backend package applies the descriptors of its backend to its DAO, e.g. sql.GenericDaoSql.ApplyStorageDescriptors.
+//
+// Available since v0.7.0
+type StorageDescriptors struct {
+	Storages []*StorageDescriptor `json:"storages"`
+}
+
+// ParseStorageDescriptors parses storage descriptors from YAML or JSON data, and validates them.
+//
+// Available since v0.7.0
+func ParseStorageDescriptors(data []byte) (*StorageDescriptors, error) {
+	// YAML is a superset of JSON: parse as YAML, then decode strictly via JSON so that unknown keys are reported
+	var doc interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid storage descriptors: %w", err)
+	}
+	js, err := json.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("invalid storage descriptors: %w", err)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(js))
+	decoder.DisallowUnknownFields()
+	descs := &StorageDescriptors{}
+	if err := decoder.Decode(descs); err != nil {
+		return nil, fmt.Errorf("invalid storage descriptors: %w", err)
+	}
+	return descs, descs.Validate()
+}
+
+// LoadStorageDescriptors loads storage descriptors from a YAML or JSON file, and validates them.
+//
+// Available since v0.7.0
+func LoadStorageDescriptors(filename string) (*StorageDescriptors, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	descs, err := ParseStorageDescriptors(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	return descs, nil
+}
+
+// Validate checks the descriptors and their references. All problems found are reported in the returned error.
+func (d *StorageDescriptors) Validate() error {
+	var errs []error
+	report := func(path, format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf("%s: %s", path, fmt.Sprintf(format, args...)))
+	}
+	if len(d.Storages) == 0 {
+		report("storages", "no storage is defined")
+	}
+	storageIds := make(map[string]int)
+	for i, s := range d.Storages {
+		path := fmt.Sprintf("storages[%d]", i)
+		if s == nil {
+			report(path, "storage descriptor is empty")
+			continue
+		}
+		if s.Id == "" {
+			report(path+".id", "is required")
+		} else if j, ok := storageIds[s.Id]; ok {
+			report(path+".id", "storage %#v is already defined at storages[%d]", s.Id, j)
+		} else {
+			storageIds[s.Id] = i
+			path = fmt.Sprintf("storages[%d](%s)", i, s.Id)
+		}
+		switch s.Backend {
+		case BackendSql, BackendMongo, BackendDynamodb, BackendCosmosdb:
+		case "":
+			report(path+".backend", "is required")
+		default:
+			report(path+".backend", "unknown backend %#v, expected one of %s, %s, %s, %s", s.Backend, BackendSql, BackendMongo, BackendDynamodb, BackendCosmosdb)
+		}
+		switch s.NameTransformation {
+		case "", NameTransformationIntact, NameTransformationUpper, NameTransformationLower:
+			if s.NameTransformation != "" && s.Backend != BackendSql {
+				report(path+".name_transformation", "is supported by backend %s only", BackendSql)
+			}
+		default:
+			report(path+".name_transformation", "unknown name transformation %#v, expected one of %s, %s, %s", s.NameTransformation, NameTransformationIntact, NameTransformationUpper, NameTransformationLower)
+		}
+
+		fieldNames := make(map[string]bool)
+		columns := make(map[string]string)
+		for j, f := range s.Fields {
+			fpath := fmt.Sprintf("%s.fields[%d]", path, j)
+			if f == nil || f.Name == "" {
+				report(fpath+".name", "is required")
+				continue
+			}
+			if fieldNames[f.Name] {
+				report(fpath+".name", "field %#v is already defined", f.Name)
+			}
+			fieldNames[f.Name] = true
+			if other, ok := columns[f.GetColumn()]; ok {
+				report(fpath+".column", "column %#v is already mapped to field %#v", f.GetColumn(), other)
+			}
+			columns[f.GetColumn()] = f.Name
+			if f.Column != "" && f.Column != f.Name && s.Backend != BackendSql {
+				report(fpath+".column", "column renaming is supported by backend %s only (use StructRowMapper instead)", BackendSql)
+			}
+		}
+		checkRef := func(key, fieldPath string) {
+			if len(fieldNames) == 0 || fieldPath == "" {
+				return
+			}
+			if name := strings.SplitN(strings.SplitN(fieldPath, ".", 2)[0], "[", 2)[0]; !fieldNames[name] {
+				report(path+"."+key, "field %#v is not defined in fields", name)
+			}
+		}
+		switch s.Backend {
+		case BackendSql:
+			if len(s.Fields) == 0 {
+				report(path+".fields", "is required for backend %s", s.Backend)
+			}
+		case BackendDynamodb:
+			if n := len(s.KeyColumns()); n < 1 || n > 2 {
+				report(path+".fields", "backend %s requires 1 or 2 key fields (partition key and optional sort key), found %d", s.Backend, n)
+			}
+		case BackendCosmosdb:
+			if s.PkPath == "" {
+				report(path+".pk_path", "is required for backend %s", s.Backend)
+			}
+			checkRef("id_path", s.IdPath)
+			checkRef("pk_path", s.PkPath)
+		}
+		if s.Backend != BackendCosmosdb {
+			if s.IdPath != "" {
+				report(path+".id_path", "is supported by backend %s only", BackendCosmosdb)
+			}
+			if s.PkPath != "" {
+				report(path+".pk_path", "is supported by backend %s only", BackendCosmosdb)
+			}
+		}
+		if s.Schema != nil {
+			if err := s.Schema.Compile(); err != nil {
+				report(path+".schema", "%s", err)
+			}
+			if len(fieldNames) > 0 {
+				refs := append([]string{}, s.Schema.Required...)
+				for name := range s.Schema.Properties {
+					refs = append(refs, name)
+				}
+				sort.Strings(refs)
+				for _, name := range refs {
+					if !fieldNames[name] {
+						report(path+".schema", "field %#v is not defined in fields", name)
+					}
+				}
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Get returns the descriptor of a storage, nil if not found.
+func (d *StorageDescriptors) Get(storageId string) *StorageDescriptor {
+	for _, s := range d.Storages {
+		if s.Id == storageId {
+			return s
+		}
+	}
+	return nil
+}
+
+// ForBackend returns the descriptors of a backend.
+func (d *StorageDescriptors) ForBackend(backend string) []*StorageDescriptor {
+	var result []*StorageDescriptor
+	for _, s := range d.Storages {
+		if s.Backend == backend {
+			result = append(result, s)
+		}
+	}
+	return result
+}
+
+// ApplySchemas attaches the schemas of a backend's storages to a DAO (usually the DAO's AbstractGenericDao).
+func (d *StorageDescriptors) ApplySchemas(backend string, dao interface {
+	SetSchema(storageId string, schema *Schema) error
+}) error {
+	for _, s := range d.ForBackend(backend) {
+		if s.Schema == nil {
+			continue
+		}
+		if err := dao.SetSchema(s.Id, s.Schema); err != nil {
+			return fmt.Errorf("storage %s: %w", s.Id, err)
+		}
+	}
+	return nil
+}
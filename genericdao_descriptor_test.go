@@ -0,0 +1,137 @@
+package godal
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testDescriptorsYaml = `
+storages:
+  - id: users
+    backend: sql
+    name_transformation: lower
+    fields:
+      - {name: id, column: uid, key: true}
+      - {name: email, column: uemail}
+      - name: age
+    schema:
+      type: object
+      required: [id, email]
+      properties:
+        age: {type: integer, minimum: 0}
+  - id: sessions
+    backend: dynamodb
+    fields:
+      - {name: user, key: true}
+      - {name: created, key: true}
+  - id: profiles
+    backend: cosmosdb
+    id_path: id
+    pk_path: tenant
+  - id: logs
+    backend: mongo
+`
+
+func TestParseStorageDescriptors(t *testing.T) {
+	name := "TestParseStorageDescriptors"
+	descs, err := ParseStorageDescriptors([]byte(testDescriptorsYaml))
+	if err != nil {
+		t.Fatalf("%s failed: %s", name, err)
+	}
+	users := descs.Get("users")
+	if users == nil || users.Backend != BackendSql || users.Schema == nil || users.Schema.Properties["age"].Type != SchemaTypeInteger {
+		t.Fatalf("%s failed: %#v", name, users)
+	}
+	if cols := users.ColumnsList(); !reflect.DeepEqual(cols, []string{"uid", "uemail", "age"}) {
+		t.Fatalf("%s failed: %#v", name, cols)
+	}
+	if m := users.FieldToColumnMap(); !reflect.DeepEqual(m, map[string]string{"id": "uid", "email": "uemail", "age": "age"}) {
+		t.Fatalf("%s failed: %#v", name, m)
+	}
+	if keys := descs.Get("sessions").KeyColumns(); !reflect.DeepEqual(keys, []string{"user", "created"}) {
+		t.Fatalf("%s failed: %#v", name, keys)
+	}
+	if list := descs.ForBackend(BackendCosmosdb); len(list) != 1 || list[0].PkPath != "tenant" {
+		t.Fatalf("%s failed: %#v", name, list)
+	}
+	if descs.Get("unknown") != nil {
+		t.Fatalf("%s failed: unknown storage should not be found", name)
+	}
+
+	// JSON is accepted as well
+	js := `{"storages":[{"id":"users","backend":"mongo","fields":[{"name":"id"}]}]}`
+	if descs, err := ParseStorageDescriptors([]byte(js)); err != nil || descs.Get("users") == nil {
+		t.Fatalf("%s failed: %#v / %s", name, descs, err)
+	}
+}
+
+func TestParseStorageDescriptors_Errors(t *testing.T) {
+	name := "TestParseStorageDescriptors_Errors"
+	testCases := []struct {
+		yaml     string
+		messages []string
+	}{
+		{"storages: [", []string{"invalid storage descriptors"}},
+		{"storages:\n  - id: a\n    backend: sql\n    colums: []", []string{`unknown field "colums"`}},
+		{"storages: []", []string{"storages: no storage is defined"}},
+		{"storages:\n  - backend: oracle", []string{"storages[0].id: is required", `storages[0].backend: unknown backend "oracle"`}},
+		{"storages:\n  - {id: a, backend: mongo}\n  - {id: a, backend: mongo}", []string{`storages[1].id: storage "a" is already defined at storages[0]`}},
+		{"storages:\n  - {id: a, backend: sql, name_transformation: title, fields: [{name: x}]}", []string{`storages[0](a).name_transformation: unknown name transformation "title"`}},
+		{"storages:\n  - {id: a, backend: mongo, name_transformation: lower}", []string{"storages[0](a).name_transformation: is supported by backend sql only"}},
+		{"storages:\n  - {id: a, backend: sql}", []string{"storages[0](a).fields: is required for backend sql"}},
+		{"storages:\n  - {id: a, backend: sql, fields: [{name: x}, {name: x, column: y}, {name: z, column: y}, {column: w}]}",
+			[]string{`storages[0](a).fields[1].name: field "x" is already defined`, `storages[0](a).fields[2].column: column "y" is already mapped to field "x"`, "storages[0](a).fields[3].name: is required"}},
+		{"storages:\n  - {id: a, backend: dynamodb, fields: [{name: x, column: y, key: true}]}", []string{"storages[0](a).fields[0].column: column renaming is supported by backend sql only"}},
+		{"storages:\n  - {id: a, backend: dynamodb, fields: [{name: x}]}", []string{"storages[0](a).fields: backend dynamodb requires 1 or 2 key fields"}},
+		{"storages:\n  - {id: a, backend: cosmosdb, id_path: id}", []string{"storages[0](a).pk_path: is required for backend cosmosdb"}},
+		{"storages:\n  - {id: a, backend: cosmosdb, pk_path: tenant.id, fields: [{name: id}]}", []string{`storages[0](a).pk_path: field "tenant" is not defined in fields`}},
+		{"storages:\n  - {id: a, backend: mongo, pk_path: tenant}", []string{"storages[0](a).pk_path: is supported by backend cosmosdb only"}},
+		{"storages:\n  - {id: a, backend: mongo, schema: {pattern: '('}}", []string{"storages[0](a).schema: schema at []: invalid pattern"}},
+		{"storages:\n  - {id: a, backend: mongo, fields: [{name: x}], schema: {required: [y], properties: {z: {}}}}",
+			[]string{`storages[0](a).schema: field "y" is not defined in fields`, `storages[0](a).schema: field "z" is not defined in fields`}},
+	}
+	for i, tc := range testCases {
+		_, err := ParseStorageDescriptors([]byte(tc.yaml))
+		if err == nil {
+			t.Fatalf("%s failed: case %d should fail", name, i)
+		}
+		for _, msg := range tc.messages {
+			if !strings.Contains(err.Error(), msg) {
+				t.Fatalf("%s failed: case %d, expected %#v in error:\n%s", name, i, msg, err)
+			}
+		}
+	}
+}
+
+func TestLoadStorageDescriptors(t *testing.T) {
+	name := "TestLoadStorageDescriptors"
+	filename := filepath.Join(t.TempDir(), "storages.yaml")
+	if err := os.WriteFile(filename, []byte(testDescriptorsYaml), 0600); err != nil {
+		t.Fatalf("%s failed: %e", name, err)
+	}
+	if descs, err := LoadStorageDescriptors(filename); err != nil || len(descs.Storages) != 4 {
+		t.Fatalf("%s failed: %#v / %s", name, descs, err)
+	}
+	if _, err := LoadStorageDescriptors(filename + ".notfound"); err == nil {
+		t.Fatalf("%s failed: missing file should be reported", name)
+	}
+	os.WriteFile(filename, []byte("storages: []"), 0600)
+	if _, err := LoadStorageDescriptors(filename); err == nil || !strings.Contains(err.Error(), filename) {
+		t.Fatalf("%s failed: error should mention the file name: %s", name, err)
+	}
+}
+
+func TestStorageDescriptors_ApplySchemas(t *testing.T) {
+	name := "TestStorageDescriptors_ApplySchemas"
+	descs, _ := ParseStorageDescriptors([]byte(testDescriptorsYaml))
+	dao := NewAbstractGenericDao(nil)
+	if err := descs.ApplySchemas(BackendSql, dao); err != nil {
+		t.Fatalf("%s failed: %s", name, err)
+	}
+	if dao.GetSchema("users") != descs.Get("users").Schema || dao.GetSchema("sessions") != nil {
+		t.Fatalf("%s failed: schemas are not attached", name)
+	}
+}
@@ -0,0 +1,239 @@
+package godal
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// IIdGenerator generates ids for BOs being inserted into a storage.
+//
+// Available since v0.7.0
+type IIdGenerator interface {
+	// NextId returns a new id for a BO being inserted into the storage.
+	//
+	// A generator may return a nil id to signal that the id is generated by the database when the BO is inserted
+	// (e.g. auto-increment/IDENTITY columns). Such generators are only supported by DAO implementations that
+	// recognize them.
+	NextId(ctx context.Context, storageId string) (interface{}, error)
+}
+
+// IdGeneratorFunc is a function that implements IIdGenerator.
+//
+// Available since v0.7.0
+type IdGeneratorFunc func(ctx context.Context, storageId string) (interface{}, error)
+
+// NextId implements IIdGenerator.NextId.
+func (f IdGeneratorFunc) NextId(ctx context.Context, storageId string) (interface{}, error) {
+	return f(ctx, storageId)
+}
+
+// storageIdGenerator is an IIdGenerator attached to a storage, along with the path of the BO field receiving the id.
+type storageIdGenerator struct {
+	fieldPath string
+	generator IIdGenerator
+}
+
+// GetIdGenerator returns the id generator attached to a storage, along with the path of the BO field receiving the id.
+//
+// Available since v0.7.0
+func (dao *AbstractGenericDao) GetIdGenerator(storageId string) (string, IIdGenerator) {
+	if gen, ok := dao.idGenerators[storageId]; ok {
+		return gen.fieldPath, gen.generator
+	}
+	return "", nil
+}
+
+// SetIdGenerator attaches an id generator to a storage. When a BO is inserted into the storage (GdaoCreate, and
+// GdaoSave), the generated id is set to the BO field at fieldPath if the field is absent.
+//
+// Supply a nil generator to detach the current one. Id generators should be attached once when the DAO is
+// initialized; this function is not safe to call concurrently with write operations.
+//
+// Available since v0.7.0
+func (dao *AbstractGenericDao) SetIdGenerator(storageId, fieldPath string, generator IIdGenerator) *AbstractGenericDao {
+	if generator == nil {
+		delete(dao.idGenerators, storageId)
+		return dao
+	}
+	if dao.idGenerators == nil {
+		dao.idGenerators = make(map[string]storageIdGenerator)
+	}
+	dao.idGenerators[storageId] = storageIdGenerator{fieldPath: fieldPath, generator: generator}
+	return dao
+}
+
+func (dao *AbstractGenericDao) applyIdGenerator(ctx context.Context, storageId string, bo IGenericBo, op WriteOp) error {
+	gen, ok := dao.idGenerators[storageId]
+	if !ok || op.isUpdate() || bo.GboGetAttrUnsafe(gen.fieldPath, nil) != nil {
+		return nil
+	}
+	id, err := gen.generator.NextId(ctx, storageId)
+	if err != nil || id == nil {
+		return err
+	}
+	return bo.GboSetAttr(gen.fieldPath, id)
+}
+
+/*----------------------------------------------------------------------*/
+
+func randomBytes(n int) []byte {
+	buf := make([]byte, n)
+	_, _ = rand.Read(buf)
+	return buf
+}
+
+func formatUuid(b []byte) string {
+	h := hex.EncodeToString(b)
+	return h[0:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:32]
+}
+
+// NewUuidV4 returns a new random (version 4) UUID in its canonical string form.
+//
+// Available since v0.7.0
+func NewUuidV4() string {
+	b := randomBytes(16)
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return formatUuid(b)
+}
+
+// NewUuidV7 returns a new time-ordered (version 7, RFC 9562) UUID in its canonical string form.
+//
+// Available since v0.7.0
+func NewUuidV7() string {
+	return newUuidV7(time.Now())
+}
+
+func newUuidV7(t time.Time) string {
+	b := randomBytes(16)
+	ms := uint64(t.UnixMilli())
+	b[0], b[1], b[2], b[3], b[4], b[5] = byte(ms>>40), byte(ms>>32), byte(ms>>24), byte(ms>>16), byte(ms>>8), byte(ms)
+	b[6] = (b[6] & 0x0f) | 0x70
+	b[8] = (b[8] & 0x3f) | 0x80
+	return formatUuid(b)
+}
+
+const crockfordBase32 = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// NewUlid returns a new ULID (26-character, lexicographically sortable identifier).
+//
+// Available since v0.7.0
+func NewUlid() string {
+	return newUlid(time.Now())
+}
+
+func newUlid(t time.Time) string {
+	// 48-bit timestamp followed by 80 bits of randomness, encoded as 26 base32 characters (5 bits each, 130 bits in total)
+	b := make([]byte, 16)
+	ms := uint64(t.UnixMilli())
+	b[0], b[1], b[2], b[3], b[4], b[5] = byte(ms>>40), byte(ms>>32), byte(ms>>24), byte(ms>>16), byte(ms>>8), byte(ms)
+	copy(b[6:], randomBytes(10))
+	hi, lo := binary.BigEndian.Uint64(b[0:8]), binary.BigEndian.Uint64(b[8:16])
+	result := make([]byte, 26)
+	for i := 25; i >= 0; i-- {
+		result[i] = crockfordBase32[lo&0x1f]
+		lo = (lo >> 5) | (hi << 59)
+		hi >>= 5
+	}
+	return string(result)
+}
+
+var (
+	// IdGeneratorUuidV4 generates random (version 4) UUIDs.
+	//
+	// Available since v0.7.0
+	IdGeneratorUuidV4 IIdGenerator = IdGeneratorFunc(func(context.Context, string) (interface{}, error) { return NewUuidV4(), nil })
+
+	// IdGeneratorUuidV7 generates time-ordered (version 7) UUIDs.
+	//
+	// Available since v0.7.0
+	IdGeneratorUuidV7 IIdGenerator = IdGeneratorFunc(func(context.Context, string) (interface{}, error) { return NewUuidV7(), nil })
+
+	// IdGeneratorUlid generates ULIDs.
+	//
+	// Available since v0.7.0
+	IdGeneratorUlid IIdGenerator = IdGeneratorFunc(func(context.Context, string) (interface{}, error) { return NewUlid(), nil })
+)
+
+/*----------------------------------------------------------------------*/
+
+// DefaultSnowflakeEpoch is the default epoch of SnowflakeIdGenerator (2020-01-01T00:00:00Z).
+//
+// Available since v0.7.0
+var DefaultSnowflakeEpoch = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// NewSnowflakeIdGenerator constructs a new SnowflakeIdGenerator.
+//
+//   - nodeId: id of the node generating ids, must fit in nodeBits bits.
+//   - nodeBits: number of bits reserved for node id, from 1 to 21. The remaining 22-nodeBits bits are used for the
+//     per-millisecond sequence (e.g. nodeBits=10 allows 1024 nodes, each generating up to 4096 ids per millisecond).
+//
+// Available since v0.7.0
+func NewSnowflakeIdGenerator(nodeId int64, nodeBits uint) (*SnowflakeIdGenerator, error) {
+	if nodeBits < 1 || nodeBits > 21 {
+		return nil, fmt.Errorf("nodeBits must be between 1 and 21, received %d", nodeBits)
+	}
+	if nodeId < 0 || nodeId >= 1<<nodeBits {
+		return nil, fmt.Errorf("nodeId must be between 0 and %d, received %d", int64(1)<<nodeBits-1, nodeId)
+	}
+	return &SnowflakeIdGenerator{
+		nodeId:   nodeId,
+		nodeBits: nodeBits,
+		seqBits:  22 - nodeBits,
+		epoch:    DefaultSnowflakeEpoch,
+		funcNow:  time.Now,
+	}, nil
+}
+
+// SnowflakeIdGenerator generates Snowflake-style 64-bit ids: 41 bits of milliseconds since epoch, followed by
+// node id and per-millisecond sequence. Generated ids are positive int64 values, increasing over time on each node.
+//
+// Available since v0.7.0
+type SnowflakeIdGenerator struct {
+	lock     sync.Mutex
+	nodeId   int64
+	nodeBits uint
+	seqBits  uint
+	epoch    time.Time
+	funcNow  func() time.Time
+	lastMs   int64
+	seq      int64
+}
+
+// SetEpoch sets the epoch that timestamps are counted from. Default value is DefaultSnowflakeEpoch.
+func (g *SnowflakeIdGenerator) SetEpoch(epoch time.Time) *SnowflakeIdGenerator {
+	g.epoch = epoch
+	return g
+}
+
+// Next returns a new id.
+func (g *SnowflakeIdGenerator) Next() int64 {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	ms := g.funcNow().Sub(g.epoch).Milliseconds()
+	if ms < g.lastMs {
+		// clock moved backwards: keep issuing ids from the last known timestamp
+		ms = g.lastMs
+	}
+	if ms == g.lastMs {
+		g.seq = (g.seq + 1) & (1<<g.seqBits - 1)
+		if g.seq == 0 {
+			// sequence exhausted: borrow the next millisecond
+			ms++
+		}
+	} else {
+		g.seq = 0
+	}
+	g.lastMs = ms
+	return ms<<22 | g.nodeId<<g.seqBits | g.seq
+}
+
+// NextId implements IIdGenerator.NextId.
+func (g *SnowflakeIdGenerator) NextId(context.Context, string) (interface{}, error) {
+	return g.Next(), nil
+}
@@ -0,0 +1,157 @@
+package godal
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+var reUuid = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-([0-9a-f])[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+func TestNewUuidV4(t *testing.T) {
+	name := "TestNewUuidV4"
+	seen := make(map[string]bool)
+	for i := 0; i < 1000; i++ {
+		id := NewUuidV4()
+		m := reUuid.FindStringSubmatch(id)
+		if m == nil || m[1] != "4" {
+			t.Fatalf("%s failed: invalid UUIDv4 %s", name, id)
+		}
+		if seen[id] {
+			t.Fatalf("%s failed: duplicated id %s", name, id)
+		}
+		seen[id] = true
+	}
+}
+
+func TestNewUuidV7(t *testing.T) {
+	name := "TestNewUuidV7"
+	t1 := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	id1, id2 := newUuidV7(t1), newUuidV7(t1.Add(time.Millisecond))
+	for _, id := range []string{id1, id2, NewUuidV7()} {
+		if m := reUuid.FindStringSubmatch(id); m == nil || m[1] != "7" {
+			t.Fatalf("%s failed: invalid UUIDv7 %s", name, id)
+		}
+	}
+	if id1 >= id2 {
+		t.Fatalf("%s failed: UUIDv7 should be time-ordered: %s >= %s", name, id1, id2)
+	}
+	if prefix := strings.ReplaceAll(id1, "-", "")[:12]; prefix != "018cc820d888" {
+		t.Fatalf("%s failed: unexpected timestamp prefix %s", name, prefix)
+	}
+}
+
+func TestNewUlid(t *testing.T) {
+	name := "TestNewUlid"
+	reUlid := regexp.MustCompile(`^[0-7][0-9A-HJKMNP-TV-Z]{25}$`)
+	t1 := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	id1, id2 := newUlid(t1), newUlid(t1.Add(time.Millisecond))
+	for _, id := range []string{id1, id2, NewUlid()} {
+		if !reUlid.MatchString(id) {
+			t.Fatalf("%s failed: invalid ULID %s", name, id)
+		}
+	}
+	if id1 >= id2 {
+		t.Fatalf("%s failed: ULID should be lexicographically sortable by time: %s >= %s", name, id1, id2)
+	}
+	if prefix := id1[:10]; prefix != "01HK421P48" {
+		t.Fatalf("%s failed: unexpected timestamp prefix %s", name, prefix)
+	}
+}
+
+func TestNewSnowflakeIdGenerator(t *testing.T) {
+	name := "TestNewSnowflakeIdGenerator"
+	for _, args := range [][2]int64{{0, 0}, {0, 22}, {-1, 10}, {1024, 10}} {
+		if _, err := NewSnowflakeIdGenerator(args[0], uint(args[1])); err == nil {
+			t.Fatalf("%s failed: nodeId=%d / nodeBits=%d should be rejected", name, args[0], args[1])
+		}
+	}
+	if _, err := NewSnowflakeIdGenerator(1023, 10); err != nil {
+		t.Fatalf("%s failed: %e", name, err)
+	}
+}
+
+func TestSnowflakeIdGenerator_Next(t *testing.T) {
+	name := "TestSnowflakeIdGenerator_Next"
+	gen, _ := NewSnowflakeIdGenerator(5, 4)
+	now := DefaultSnowflakeEpoch.Add(time.Hour)
+	gen.funcNow = func() time.Time { return now }
+
+	id := gen.Next()
+	if ms := id >> 22; ms != time.Hour.Milliseconds() {
+		t.Fatalf("%s failed: expected timestamp %d but received %d", name, time.Hour.Milliseconds(), ms)
+	}
+	if node := (id >> 18) & 0xf; node != 5 {
+		t.Fatalf("%s failed: expected node %d but received %d", name, 5, node)
+	}
+
+	// sequence of 18 bits is exhausted after 2^18 ids within the same millisecond
+	last := id
+	for i := 1; i < 1<<18+10; i++ {
+		next := gen.Next()
+		if next <= last {
+			t.Fatalf("%s failed: ids should be increasing: %d <= %d", name, next, last)
+		}
+		last = next
+	}
+	if ms := last >> 22; ms != time.Hour.Milliseconds()+1 {
+		t.Fatalf("%s failed: next millisecond should be borrowed, received %d", name, ms)
+	}
+
+	// clock moves backwards
+	now = now.Add(-time.Second)
+	if next := gen.Next(); next <= last {
+		t.Fatalf("%s failed: ids should be increasing: %d <= %d", name, next, last)
+	}
+}
+
+func TestAbstractGenericDao_SetIdGenerator(t *testing.T) {
+	name := "TestAbstractGenericDao_SetIdGenerator"
+	dao := NewAbstractGenericDao(nil)
+	counter := 0
+	gen := IdGeneratorFunc(func(_ context.Context, storageId string) (interface{}, error) {
+		counter++
+		if storageId == "error" {
+			return nil, errors.New("dummy")
+		}
+		return counter, nil
+	})
+	dao.SetIdGenerator("test", "key.id", gen).SetIdGenerator("error", "id", gen)
+	if path, g := dao.GetIdGenerator("test"); path != "key.id" || g == nil {
+		t.Fatalf("%s failed: %#v / %#v", name, path, g)
+	}
+
+	bo := NewGenericBo()
+	if err := dao.PrepareBoForWrite(nil, "test", bo, WriteOpInsert); err != nil || bo.GboGetAttrUnsafe("key.id", nil) != 1 {
+		t.Fatalf("%s failed: %s / %#v", name, bo.GboToJsonUnsafe(), err)
+	}
+	dao.PrepareBoForWrite(nil, "test", bo, WriteOpUpsert)
+	if bo.GboGetAttrUnsafe("key.id", nil) != 1 {
+		t.Fatalf("%s failed: existing id should be kept, received %s", name, bo.GboToJsonUnsafe())
+	}
+	bo = NewGenericBo()
+	dao.PrepareBoForWrite(nil, "test", bo, WriteOpUpdate)
+	if bo.GboGetAttrUnsafe("key.id", nil) != nil {
+		t.Fatalf("%s failed: id should not be generated on update, received %s", name, bo.GboToJsonUnsafe())
+	}
+	dao.PrepareBoForWrite(nil, "test", bo, WriteOpUpsert)
+	if bo.GboGetAttrUnsafe("key.id", nil) != 2 {
+		t.Fatalf("%s failed: %s", name, bo.GboToJsonUnsafe())
+	}
+	if err := dao.PrepareBoForWrite(nil, "error", NewGenericBo(), WriteOpInsert); err == nil {
+		t.Fatalf("%s failed: generator error should be returned", name)
+	}
+
+	dao.SetIdGenerator("test", "", nil)
+	if _, g := dao.GetIdGenerator("test"); g != nil {
+		t.Fatalf("%s failed: id generator should have been detached", name)
+	}
+	for _, g := range []IIdGenerator{IdGeneratorUuidV4, IdGeneratorUuidV7, IdGeneratorUlid} {
+		if id, err := g.NextId(nil, "test"); err != nil || id == "" {
+			t.Fatalf("%s failed: %#v / %#v", name, id, err)
+		}
+	}
+}
@@ -0,0 +1,430 @@
+package godal
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// ReplicaSelection specifies how a replica is chosen to serve a read operation.
+//
+// Available since v0.7.0
+type ReplicaSelection int
+
+const (
+	// ReplicaSelectionRoundRobin chooses replicas in turn.
+	ReplicaSelectionRoundRobin ReplicaSelection = iota
+
+	// ReplicaSelectionLeastLatency chooses the replica with the lowest (moving-average) read latency.
+	ReplicaSelectionLeastLatency
+)
+
+type ctxKeyForcePrimary struct{}
+
+// WithForcePrimary returns a copy of the parent context that instructs ReplicatedGenericDao to serve reads from the primary.
+//
+// Available since v0.7.0
+func WithForcePrimary(ctx context.Context) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithValue(ctx, ctxKeyForcePrimary{}, true)
+}
+
+// IsForcePrimary returns 'true' if the context was created by WithForcePrimary.
+//
+// Available since v0.7.0
+func IsForcePrimary(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+	v, ok := ctx.Value(ctxKeyForcePrimary{}).(bool)
+	return ok && v
+}
+
+type ctxKeyReadYourWritesSession struct{}
+
+// NewReadYourWritesSession constructs a new ReadYourWritesSession.
+//
+// Available since v0.7.0
+func NewReadYourWritesSession() *ReadYourWritesSession {
+	return &ReadYourWritesSession{lastWrites: make(map[string]time.Time)}
+}
+
+// ReadYourWritesSession records the write operations of one caller (e.g. one end-user session), so that the
+// read-your-writes window of ReplicatedGenericDao only applies to that caller's reads. A session can be shared by
+// several goroutines and kept across requests.
+//
+// Available since v0.7.0
+type ReadYourWritesSession struct {
+	lock       sync.Mutex
+	lastWrites map[string]time.Time
+}
+
+// WithReadYourWritesSession returns a copy of the parent context that carries the session: ReplicatedGenericDao records
+// writes made with the context in the session, and serves reads made with the context from the primary within the
+// read-your-writes window after the session's own writes.
+//
+// Available since v0.7.0
+func WithReadYourWritesSession(ctx context.Context, session *ReadYourWritesSession) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithValue(ctx, ctxKeyReadYourWritesSession{}, session)
+}
+
+// readYourWritesSessionFromContext returns the session carried by the context, nil if none.
+func readYourWritesSessionFromContext(ctx context.Context) *ReadYourWritesSession {
+	if ctx == nil {
+		return nil
+	}
+	session, _ := ctx.Value(ctxKeyReadYourWritesSession{}).(*ReadYourWritesSession)
+	return session
+}
+
+// latencyEwmaAlpha is the smoothing factor of the replica latency moving average.
+const latencyEwmaAlpha = 0.2
+
+// replicaStats holds read statistics of one replica.
+type replicaStats struct {
+	latency  float64 // moving average, in nanoseconds
+	samples  int64
+	lastUsed time.Time // last time the replica was chosen or sampled
+}
+
+/*----------------------------------------------------------------------*/
+
+// NewReplicatedGenericDao constructs a new ReplicatedGenericDao with one primary and zero or more replica DAOs.
+//
+// Default settings: round-robin replica selection, no read-your-writes window, fall back to primary if a replica read fails,
+// failed replica reads are penalized by 1 second, idle replicas are probed every 10 seconds (least-latency selection).
+//
+// Available since v0.7.0
+func NewReplicatedGenericDao(primary IGenericDao, replicas ...IGenericDao) *ReplicatedGenericDao {
+	dao := &ReplicatedGenericDao{
+		origPrimary:       primary,
+		primary:           ToGenericDaoWithContext(primary),
+		origReplicas:      replicas,
+		replicas:          make([]IGenericDaoWithContext, len(replicas)),
+		stats:             make([]replicaStats, len(replicas)),
+		fallbackToPrimary: true,
+		failurePenalty:    time.Second,
+		probeInterval:     10 * time.Second,
+		lastWrites:        make(map[string]time.Time),
+		funcNow:           time.Now,
+	}
+	for i, r := range replicas {
+		dao.replicas[i] = ToGenericDaoWithContext(r)
+	}
+	return dao
+}
+
+// ReplicatedGenericDao is an IGenericDao that splits reads and writes between one primary and several replica DAOs,
+// which are usually of the same backend type (e.g. several GenericDaoSql instances over different SqlConnect).
+//
+//   - Write operations (GdaoCreate, GdaoUpdate, GdaoSave, GdaoDelete and GdaoDeleteMany) are sent to the primary.
+//   - Read operations (GdaoFetchOne and GdaoFetchMany) are sent to a replica, except when:
+//     the context is created by WithForcePrimary; or there is no replica; or the storage has been written to within the
+//     read-your-writes window.
+//
+// The read-your-writes window is scoped to the caller if the context carries a ReadYourWritesSession (see
+// WithReadYourWritesSession): only the session's own writes send the session's reads to the primary. Otherwise, it is
+// scoped to the storage: a write made without session sends all reads without session of that storage to the primary,
+// which can shift much read traffic to the primary for frequently written storages.
+//
+// With least-latency selection, a failed replica read is sampled as its latency plus a penalty, so that a replica that
+// fails fast does not attract traffic. Replicas that have not been chosen for a while are probed with a read, so that
+// their moving averages can recover.
+//
+// GdaoCreateFilter and GetRowMapper are delegated to the primary.
+//
+// Available since v0.7.0
+type ReplicatedGenericDao struct {
+	origPrimary       IGenericDao
+	primary           IGenericDaoWithContext
+	origReplicas      []IGenericDao
+	replicas          []IGenericDaoWithContext
+	selection         ReplicaSelection
+	readYourWrites    time.Duration
+	fallbackToPrimary bool
+	failurePenalty    time.Duration
+	probeInterval     time.Duration
+	counter           uint64
+	lock              sync.Mutex
+	stats             []replicaStats
+	lastWrites        map[string]time.Time
+	funcNow           func() time.Time
+}
+
+// GetPrimary returns the primary DAO.
+func (dao *ReplicatedGenericDao) GetPrimary() IGenericDao {
+	return dao.origPrimary
+}
+
+// GetReplicas returns the replica DAOs.
+func (dao *ReplicatedGenericDao) GetReplicas() []IGenericDao {
+	return dao.origReplicas
+}
+
+// GetReplicaSelection returns the replica selection strategy.
+func (dao *ReplicatedGenericDao) GetReplicaSelection() ReplicaSelection {
+	return dao.selection
+}
+
+// SetReplicaSelection sets the replica selection strategy. Default value is ReplicaSelectionRoundRobin.
+func (dao *ReplicatedGenericDao) SetReplicaSelection(selection ReplicaSelection) *ReplicatedGenericDao {
+	dao.selection = selection
+	return dao
+}
+
+// GetReadYourWritesWindow returns the read-your-writes window.
+func (dao *ReplicatedGenericDao) GetReadYourWritesWindow() time.Duration {
+	return dao.readYourWrites
+}
+
+// SetReadYourWritesWindow sets the read-your-writes window: within this period after a write operation on a storage,
+// reads from that storage are served by the primary so that they do not miss the write because of replication lag.
+// The window is scoped to the caller's ReadYourWritesSession if any, to the storage otherwise.
+// Value <= 0 disables the window (default).
+func (dao *ReplicatedGenericDao) SetReadYourWritesWindow(window time.Duration) *ReplicatedGenericDao {
+	dao.readYourWrites = window
+	return dao
+}
+
+// GetFallbackToPrimary returns 'true' if a failed replica read is retried on the primary.
+func (dao *ReplicatedGenericDao) GetFallbackToPrimary() bool {
+	return dao.fallbackToPrimary
+}
+
+// SetFallbackToPrimary enables/disables retrying a failed replica read on the primary. Default value is 'true'.
+func (dao *ReplicatedGenericDao) SetFallbackToPrimary(enabled bool) *ReplicatedGenericDao {
+	dao.fallbackToPrimary = enabled
+	return dao
+}
+
+// GetFailurePenalty returns the duration added to the latency sample of a failed replica read.
+func (dao *ReplicatedGenericDao) GetFailurePenalty() time.Duration {
+	return dao.failurePenalty
+}
+
+// SetFailurePenalty sets the duration added to the latency sample of a failed replica read. Default value is 1 second.
+func (dao *ReplicatedGenericDao) SetFailurePenalty(penalty time.Duration) *ReplicatedGenericDao {
+	dao.failurePenalty = penalty
+	return dao
+}
+
+// GetProbeInterval returns the period after which a replica that has not been chosen is probed.
+func (dao *ReplicatedGenericDao) GetProbeInterval() time.Duration {
+	return dao.probeInterval
+}
+
+// SetProbeInterval sets the period after which a replica that has not been chosen is probed with a read, so that its
+// moving-average latency is refreshed (least-latency selection only). Value <= 0 disables probing. Default value is 10 seconds.
+func (dao *ReplicatedGenericDao) SetProbeInterval(interval time.Duration) *ReplicatedGenericDao {
+	dao.probeInterval = interval
+	return dao
+}
+
+// GetReplicaLatency returns the moving-average read latency of the replica at the specified index.
+func (dao *ReplicatedGenericDao) GetReplicaLatency(index int) time.Duration {
+	dao.lock.Lock()
+	defer dao.lock.Unlock()
+	return time.Duration(dao.stats[index].latency)
+}
+
+// markWrite records the time of the last write operation on the storage, in the caller's session if any.
+func (dao *ReplicatedGenericDao) markWrite(ctx context.Context, storageId string) {
+	if dao.readYourWrites <= 0 {
+		return
+	}
+	if session := readYourWritesSessionFromContext(ctx); session != nil {
+		session.lock.Lock()
+		defer session.lock.Unlock()
+		session.lastWrites[storageId] = dao.funcNow()
+		return
+	}
+	dao.lock.Lock()
+	defer dao.lock.Unlock()
+	dao.lastWrites[storageId] = dao.funcNow()
+}
+
+// withinReadYourWrites returns 'true' if the storage has been written to within the read-your-writes window, by the
+// caller's session if any. Expired entries are removed from lastWrites, which must be guarded by lock.
+func (dao *ReplicatedGenericDao) withinReadYourWrites(lock sync.Locker, lastWrites map[string]time.Time, storageId string) bool {
+	lock.Lock()
+	defer lock.Unlock()
+	if t, ok := lastWrites[storageId]; ok {
+		if dao.funcNow().Before(t.Add(dao.readYourWrites)) {
+			return true
+		}
+		delete(lastWrites, storageId)
+	}
+	return false
+}
+
+// pickReplica returns index of the replica to serve a read operation, or -1 if the read should go to the primary.
+func (dao *ReplicatedGenericDao) pickReplica(ctx context.Context, storageId string) int {
+	if len(dao.replicas) == 0 || IsForcePrimary(ctx) {
+		return -1
+	}
+	if dao.readYourWrites > 0 {
+		if session := readYourWritesSessionFromContext(ctx); session != nil {
+			if dao.withinReadYourWrites(&session.lock, session.lastWrites, storageId) {
+				return -1
+			}
+		} else if dao.withinReadYourWrites(&dao.lock, dao.lastWrites, storageId) {
+			return -1
+		}
+	}
+	dao.lock.Lock()
+	defer dao.lock.Unlock()
+	if dao.selection == ReplicaSelectionLeastLatency {
+		now := dao.funcNow()
+		best, idle := 0, -1
+		for i := range dao.stats {
+			if dao.stats[i].latency < dao.stats[best].latency {
+				best = i
+			}
+			if dao.probeInterval > 0 && !now.Before(dao.stats[i].lastUsed.Add(dao.probeInterval)) &&
+				(idle < 0 || dao.stats[i].lastUsed.Before(dao.stats[idle].lastUsed)) {
+				idle = i
+			}
+		}
+		if idle >= 0 {
+			best = idle
+		}
+		dao.stats[best].lastUsed = now
+		return best
+	}
+	index := int(dao.counter % uint64(len(dao.replicas)))
+	dao.counter++
+	return index
+}
+
+// recordLatency updates the moving-average read latency of a replica.
+func (dao *ReplicatedGenericDao) recordLatency(index int, d time.Duration) {
+	dao.lock.Lock()
+	defer dao.lock.Unlock()
+	s := &dao.stats[index]
+	if s.samples == 0 {
+		s.latency = float64(d)
+	} else {
+		s.latency = latencyEwmaAlpha*float64(d) + (1-latencyEwmaAlpha)*s.latency
+	}
+	s.samples++
+	s.lastUsed = dao.funcNow()
+}
+
+// read executes a read operation on the chosen replica, or on the primary.
+func (dao *ReplicatedGenericDao) read(ctx context.Context, storageId string, f func(d IGenericDaoWithContext) error) error {
+	index := dao.pickReplica(ctx, storageId)
+	if index < 0 {
+		return f(dao.primary)
+	}
+	start := dao.funcNow()
+	err := f(dao.replicas[index])
+	d := dao.funcNow().Sub(start)
+	if err != nil {
+		d += dao.failurePenalty
+	}
+	dao.recordLatency(index, d)
+	if err != nil && dao.fallbackToPrimary {
+		return f(dao.primary)
+	}
+	return err
+}
+
+// GdaoCreateFilter implements IGenericDao.GdaoCreateFilter.
+func (dao *ReplicatedGenericDao) GdaoCreateFilter(storageId string, bo IGenericBo) FilterOpt {
+	return dao.primary.GdaoCreateFilter(storageId, bo)
+}
+
+// GetRowMapper implements IGenericDao.GetRowMapper.
+func (dao *ReplicatedGenericDao) GetRowMapper() IRowMapper {
+	return dao.primary.GetRowMapper()
+}
+
+// GdaoDelete implements IGenericDao.GdaoDelete.
+func (dao *ReplicatedGenericDao) GdaoDelete(storageId string, bo IGenericBo) (int, error) {
+	return dao.GdaoDeleteWithContext(nil, storageId, bo)
+}
+
+// GdaoDeleteWithContext implements IGenericDaoWithContext.GdaoDeleteWithContext.
+func (dao *ReplicatedGenericDao) GdaoDeleteWithContext(ctx context.Context, storageId string, bo IGenericBo) (int, error) {
+	defer dao.markWrite(ctx, storageId)
+	return dao.primary.GdaoDeleteWithContext(ctx, storageId, bo)
+}
+
+// GdaoDeleteMany implements IGenericDao.GdaoDeleteMany.
+func (dao *ReplicatedGenericDao) GdaoDeleteMany(storageId string, filter FilterOpt) (int, error) {
+	return dao.GdaoDeleteManyWithContext(nil, storageId, filter)
+}
+
+// GdaoDeleteManyWithContext implements IGenericDaoWithContext.GdaoDeleteManyWithContext.
+func (dao *ReplicatedGenericDao) GdaoDeleteManyWithContext(ctx context.Context, storageId string, filter FilterOpt) (int, error) {
+	defer dao.markWrite(ctx, storageId)
+	return dao.primary.GdaoDeleteManyWithContext(ctx, storageId, filter)
+}
+
+// GdaoFetchOne implements IGenericDao.GdaoFetchOne.
+func (dao *ReplicatedGenericDao) GdaoFetchOne(storageId string, filter FilterOpt) (IGenericBo, error) {
+	return dao.GdaoFetchOneWithContext(nil, storageId, filter)
+}
+
+// GdaoFetchOneWithContext implements IGenericDaoWithContext.GdaoFetchOneWithContext.
+func (dao *ReplicatedGenericDao) GdaoFetchOneWithContext(ctx context.Context, storageId string, filter FilterOpt) (IGenericBo, error) {
+	var bo IGenericBo
+	err := dao.read(ctx, storageId, func(d IGenericDaoWithContext) (err error) {
+		bo, err = d.GdaoFetchOneWithContext(ctx, storageId, filter)
+		return err
+	})
+	return bo, err
+}
+
+// GdaoFetchMany implements IGenericDao.GdaoFetchMany.
+func (dao *ReplicatedGenericDao) GdaoFetchMany(storageId string, filter FilterOpt, sorting *SortingOpt, startOffset, numItems int) ([]IGenericBo, error) {
+	return dao.GdaoFetchManyWithContext(nil, storageId, filter, sorting, startOffset, numItems)
+}
+
+// GdaoFetchManyWithContext implements IGenericDaoWithContext.GdaoFetchManyWithContext.
+func (dao *ReplicatedGenericDao) GdaoFetchManyWithContext(ctx context.Context, storageId string, filter FilterOpt, sorting *SortingOpt, startOffset, numItems int) ([]IGenericBo, error) {
+	var boList []IGenericBo
+	err := dao.read(ctx, storageId, func(d IGenericDaoWithContext) (err error) {
+		boList, err = d.GdaoFetchManyWithContext(ctx, storageId, filter, sorting, startOffset, numItems)
+		return err
+	})
+	return boList, err
+}
+
+// GdaoCreate implements IGenericDao.GdaoCreate.
+func (dao *ReplicatedGenericDao) GdaoCreate(storageId string, bo IGenericBo) (int, error) {
+	return dao.GdaoCreateWithContext(nil, storageId, bo)
+}
+
+// GdaoCreateWithContext implements IGenericDaoWithContext.GdaoCreateWithContext.
+func (dao *ReplicatedGenericDao) GdaoCreateWithContext(ctx context.Context, storageId string, bo IGenericBo) (int, error) {
+	defer dao.markWrite(ctx, storageId)
+	return dao.primary.GdaoCreateWithContext(ctx, storageId, bo)
+}
+
+// GdaoUpdate implements IGenericDao.GdaoUpdate.
+func (dao *ReplicatedGenericDao) GdaoUpdate(storageId string, bo IGenericBo) (int, error) {
+	return dao.GdaoUpdateWithContext(nil, storageId, bo)
+}
+
+// GdaoUpdateWithContext implements IGenericDaoWithContext.GdaoUpdateWithContext.
+func (dao *ReplicatedGenericDao) GdaoUpdateWithContext(ctx context.Context, storageId string, bo IGenericBo) (int, error) {
+	defer dao.markWrite(ctx, storageId)
+	return dao.primary.GdaoUpdateWithContext(ctx, storageId, bo)
+}
+
+// GdaoSave implements IGenericDao.GdaoSave.
+func (dao *ReplicatedGenericDao) GdaoSave(storageId string, bo IGenericBo) (int, error) {
+	return dao.GdaoSaveWithContext(nil, storageId, bo)
+}
+
+// GdaoSaveWithContext implements IGenericDaoWithContext.GdaoSaveWithContext.
+func (dao *ReplicatedGenericDao) GdaoSaveWithContext(ctx context.Context, storageId string, bo IGenericBo) (int, error) {
+	defer dao.markWrite(ctx, storageId)
+	return dao.primary.GdaoSaveWithContext(ctx, storageId, bo)
+}
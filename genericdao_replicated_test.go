@@ -0,0 +1,216 @@
+package godal
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWithForcePrimary(t *testing.T) {
+	name := "TestWithForcePrimary"
+	if IsForcePrimary(nil) {
+		t.Fatalf("%s failed: nil context should not force primary", name)
+	}
+	ctx := WithForcePrimary(nil)
+	if !IsForcePrimary(ctx) {
+		t.Fatalf("%s failed: context should force primary", name)
+	}
+}
+
+func TestReplicatedGenericDao_Writes(t *testing.T) {
+	name := "TestReplicatedGenericDao_Writes"
+	primary, replica := newMockGenericDao(), newMockGenericDao()
+	dao := NewReplicatedGenericDao(primary, replica)
+	dao.GdaoCreate("test", newMockBo("1", 1))
+	dao.GdaoUpdate("test", newMockBo("1", 2))
+	dao.GdaoSave("test", newMockBo("1", 3))
+	dao.GdaoDelete("test", newMockBo("1", nil))
+	dao.GdaoDeleteMany("test", nil)
+	for _, op := range []string{"create", "update", "save", "delete", "deleteMany"} {
+		if c := primary.count(op); c != 1 {
+			t.Fatalf("%s failed: expected %#v call of %s on primary but received %#v", name, 1, op, c)
+		}
+		if c := replica.count(op); c != 0 {
+			t.Fatalf("%s failed: expected %#v call of %s on replica but received %#v", name, 0, op, c)
+		}
+	}
+}
+
+func TestReplicatedGenericDao_RoundRobin(t *testing.T) {
+	name := "TestReplicatedGenericDao_RoundRobin"
+	primary, r1, r2 := newMockGenericDao(), newMockGenericDao(), newMockGenericDao()
+	dao := NewReplicatedGenericDao(primary, r1, r2)
+	for i := 0; i < 4; i++ {
+		dao.GdaoFetchOne("test", nil)
+		dao.GdaoFetchMany("test", nil, nil, 0, 0)
+	}
+	for i, r := range []*mockGenericDao{r1, r2} {
+		if c := r.count("fetchOne") + r.count("fetchMany"); c != 4 {
+			t.Fatalf("%s failed: expected %#v reads on replica #%d but received %#v", name, 4, i, c)
+		}
+	}
+	if c := primary.count("fetchOne") + primary.count("fetchMany"); c != 0 {
+		t.Fatalf("%s failed: expected %#v reads on primary but received %#v", name, 0, c)
+	}
+}
+
+func TestReplicatedGenericDao_NoReplica(t *testing.T) {
+	name := "TestReplicatedGenericDao_NoReplica"
+	primary := newMockGenericDao()
+	dao := NewReplicatedGenericDao(primary)
+	dao.GdaoFetchOne("test", nil)
+	if c := primary.count("fetchOne"); c != 1 {
+		t.Fatalf("%s failed: expected %#v read on primary but received %#v", name, 1, c)
+	}
+}
+
+func TestReplicatedGenericDao_ForcePrimary(t *testing.T) {
+	name := "TestReplicatedGenericDao_ForcePrimary"
+	primary, replica := newMockGenericDao(), newMockGenericDao()
+	dao := NewReplicatedGenericDao(primary, replica)
+	ctx := WithForcePrimary(nil)
+	dao.GdaoFetchOneWithContext(ctx, "test", nil)
+	dao.GdaoFetchManyWithContext(ctx, "test", nil, nil, 0, 0)
+	if c := primary.count("fetchOne") + primary.count("fetchMany"); c != 2 {
+		t.Fatalf("%s failed: expected %#v reads on primary but received %#v", name, 2, c)
+	}
+	if c := replica.count("fetchOne") + replica.count("fetchMany"); c != 0 {
+		t.Fatalf("%s failed: expected %#v reads on replica but received %#v", name, 0, c)
+	}
+}
+
+func TestReplicatedGenericDao_ReadYourWrites(t *testing.T) {
+	name := "TestReplicatedGenericDao_ReadYourWrites"
+	primary, replica := newMockGenericDao(), newMockGenericDao()
+	now := time.Now()
+	dao := NewReplicatedGenericDao(primary, replica).SetReadYourWritesWindow(time.Second)
+	dao.funcNow = func() time.Time { return now }
+
+	dao.GdaoCreate("test", newMockBo("1", 1))
+	if bo, _ := dao.GdaoFetchOne("test", dao.GdaoCreateFilter("test", newMockBo("1", nil))); bo == nil {
+		t.Fatalf("%s failed: read within the window should be served by primary", name)
+	}
+	dao.GdaoFetchOne("other", nil)
+	if c := replica.count("fetchOne"); c != 1 {
+		t.Fatalf("%s failed: read of other storage should be served by replica", name)
+	}
+
+	now = now.Add(time.Second)
+	dao.GdaoFetchOne("test", nil)
+	if c := replica.count("fetchOne"); c != 2 {
+		t.Fatalf("%s failed: read after the window should be served by replica", name)
+	}
+	if c := primary.count("fetchOne"); c != 1 {
+		t.Fatalf("%s failed: expected %#v read on primary but received %#v", name, 1, c)
+	}
+}
+
+func TestReplicatedGenericDao_ReadYourWritesSession(t *testing.T) {
+	name := "TestReplicatedGenericDao_ReadYourWritesSession"
+	primary, replica := newMockGenericDao(), newMockGenericDao()
+	now := time.Now()
+	dao := NewReplicatedGenericDao(primary, replica).SetReadYourWritesWindow(time.Second)
+	dao.funcNow = func() time.Time { return now }
+	alice := WithReadYourWritesSession(nil, NewReadYourWritesSession())
+	bob := WithReadYourWritesSession(nil, NewReadYourWritesSession())
+
+	dao.GdaoCreateWithContext(alice, "test", newMockBo("1", 1))
+	if bo, _ := dao.GdaoFetchOneWithContext(alice, "test", dao.GdaoCreateFilter("test", newMockBo("1", nil))); bo == nil {
+		t.Fatalf("%s failed: session's read within the window should be served by primary", name)
+	}
+	dao.GdaoFetchOneWithContext(bob, "test", nil)
+	dao.GdaoFetchOne("test", nil)
+	if c := replica.count("fetchOne"); c != 2 {
+		t.Fatalf("%s failed: reads of other callers should be served by replica, received %#v", name, c)
+	}
+
+	now = now.Add(time.Second)
+	dao.GdaoFetchOneWithContext(alice, "test", nil)
+	if c := replica.count("fetchOne"); c != 3 {
+		t.Fatalf("%s failed: read after the window should be served by replica", name)
+	}
+	if c := primary.count("fetchOne"); c != 1 {
+		t.Fatalf("%s failed: expected %#v read on primary but received %#v", name, 1, c)
+	}
+}
+
+func TestReplicatedGenericDao_LeastLatency(t *testing.T) {
+	name := "TestReplicatedGenericDao_LeastLatency"
+	primary, r1, r2 := newMockGenericDao(), newMockGenericDao(), newMockGenericDao()
+	dao := NewReplicatedGenericDao(primary, r1, r2).SetReplicaSelection(ReplicaSelectionLeastLatency)
+	now := time.Now()
+	dao.funcNow = func() time.Time { return now }
+	dao.recordLatency(0, 50*time.Millisecond)
+	dao.recordLatency(1, 10*time.Millisecond)
+	dao.GdaoFetchOne("test", nil)
+	if c := r2.count("fetchOne"); c != 1 {
+		t.Fatalf("%s failed: expected %#v read on fastest replica but received %#v", name, 1, c)
+	}
+
+	dao.recordLatency(0, 50*time.Millisecond)
+	dao.recordLatency(1, 300*time.Millisecond) // moving average: 0.2*300 + 0.8*(0.8*10) = 66.4ms
+	if l := dao.GetReplicaLatency(1); l != 66400*time.Microsecond {
+		t.Fatalf("%s failed: expected latency %s but received %s", name, 66400*time.Microsecond, l)
+	}
+	dao.GdaoFetchOne("test", nil)
+	if c := r1.count("fetchOne"); c != 1 {
+		t.Fatalf("%s failed: expected %#v read on fastest replica but received %#v", name, 1, c)
+	}
+}
+
+func TestReplicatedGenericDao_LeastLatencyFailure(t *testing.T) {
+	name := "TestReplicatedGenericDao_LeastLatencyFailure"
+	primary, r1, r2 := newMockGenericDao(), newMockGenericDao(), newMockGenericDao()
+	dao := NewReplicatedGenericDao(primary, r1, r2).SetReplicaSelection(ReplicaSelectionLeastLatency)
+	now := time.Now()
+	dao.funcNow = func() time.Time { return now }
+	dao.recordLatency(0, 10*time.Millisecond)
+	dao.recordLatency(1, 50*time.Millisecond)
+
+	// a replica that fails fast must not become the fastest one
+	r1.errToThrow = errors.New("replica down")
+	dao.GdaoFetchOne("test", nil)
+	if l := dao.GetReplicaLatency(0); l <= dao.GetReplicaLatency(1) {
+		t.Fatalf("%s failed: failed read should be penalized, latency %s", name, l)
+	}
+	dao.GdaoFetchOne("test", nil)
+	if c := r2.count("fetchOne"); c != 1 {
+		t.Fatalf("%s failed: expected %#v read on healthy replica but received %#v", name, 1, c)
+	}
+
+	// the idle replica is probed after the probe interval, and recovers
+	r1.errToThrow = nil
+	now = now.Add(dao.GetProbeInterval())
+	dao.GdaoFetchOne("test", nil)
+	if c := r1.count("fetchOne"); c != 2 {
+		t.Fatalf("%s failed: expected %#v reads on idle replica but received %#v", name, 2, c)
+	}
+	dao.GdaoFetchOne("test", nil)
+	if c := r2.count("fetchOne"); c != 2 {
+		t.Fatalf("%s failed: expected %#v reads on fastest replica but received %#v", name, 2, c)
+	}
+
+	dao.SetProbeInterval(0)
+	now = now.Add(time.Hour)
+	dao.GdaoFetchOne("test", nil)
+	if c := r2.count("fetchOne"); c != 3 {
+		t.Fatalf("%s failed: probing is disabled, expected %#v reads on fastest replica but received %#v", name, 3, c)
+	}
+}
+
+func TestReplicatedGenericDao_FallbackToPrimary(t *testing.T) {
+	name := "TestReplicatedGenericDao_FallbackToPrimary"
+	primary, replica := newMockGenericDao(), newMockGenericDao()
+	primary.GdaoCreate("test", newMockBo("1", 1))
+	replica.errToThrow = errors.New("replica down")
+	dao := NewReplicatedGenericDao(primary, replica)
+	filter := dao.GdaoCreateFilter("test", newMockBo("1", nil))
+	if bo, err := dao.GdaoFetchOne("test", filter); bo == nil || err != nil {
+		t.Fatalf("%s failed: %#v / %#v", name, bo, err)
+	}
+
+	dao.SetFallbackToPrimary(false)
+	if _, err := dao.GdaoFetchOne("test", filter); err != replica.errToThrow {
+		t.Fatalf("%s failed: expected error %#v but received %#v", name, replica.errToThrow, err)
+	}
+}
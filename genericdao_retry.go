@@ -0,0 +1,299 @@
+package godal
+
+import (
+	"context"
+	"math/rand"
+	"time"
+)
+
+// TransientErrorClassifier checks if an error is transient, i.e. the failed operation can be retried.
+//
+// Backend packages provide ready-to-use classifiers, e.g. GenericDaoSql.IsErrorTransient, mongo.IsErrorTransient,
+// dynamodb.IsErrorTransient and GenericDaoCosmosdb.IsErrorTransient.
+//
+// Available since v0.7.0
+type TransientErrorClassifier func(err error) bool
+
+// RetryPolicy specifies how a failed operation is retried.
+//
+// The n-th retry (n starts from 1) waits for min(InitialBackoff * Multiplier^(n-1), MaxBackoff), then the wait time is
+// randomized by +/- Jitter (a ratio in range [0, 1]).
+//
+// Available since v0.7.0
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts, including the first one. Value <= 1 means no retry.
+	MaxAttempts int
+
+	// InitialBackoff is the wait time before the first retry.
+	InitialBackoff time.Duration
+
+	// MaxBackoff caps the wait time between retries. Value <= 0 means no cap.
+	MaxBackoff time.Duration
+
+	// Multiplier is the factor by which the wait time increases after each retry. Value < 1 is treated as 1.
+	Multiplier float64
+
+	// Jitter randomizes the wait time by +/- Jitter ratio. Value is capped in range [0, 1].
+	Jitter float64
+}
+
+// DefaultRetryPolicy is the retry policy used by NewRetryingGenericDao: 3 attempts, exponential backoff starting
+// from 100ms, capped at 2s, with 20% jitter.
+//
+// Available since v0.7.0
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    3,
+	InitialBackoff: 100 * time.Millisecond,
+	MaxBackoff:     2 * time.Second,
+	Multiplier:     2.0,
+	Jitter:         0.2,
+}
+
+// Backoff calculates the wait time before the n-th retry (n starts from 1).
+func (p RetryPolicy) Backoff(n int) time.Duration {
+	if n < 1 || p.InitialBackoff <= 0 {
+		return 0
+	}
+	multiplier := p.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+	backoff := float64(p.InitialBackoff)
+	for i := 1; i < n && (p.MaxBackoff <= 0 || backoff < float64(p.MaxBackoff)); i++ {
+		backoff *= multiplier
+	}
+	if p.MaxBackoff > 0 && backoff > float64(p.MaxBackoff) {
+		backoff = float64(p.MaxBackoff)
+	}
+	jitter := p.Jitter
+	if jitter < 0 {
+		jitter = 0
+	} else if jitter > 1 {
+		jitter = 1
+	}
+	if jitter > 0 {
+		backoff += backoff * jitter * (2*rand.Float64() - 1)
+	}
+	return time.Duration(backoff)
+}
+
+/*----------------------------------------------------------------------*/
+
+// NewRetryingGenericDao constructs a new RetryingGenericDao that wraps the specified DAO, using DefaultRetryPolicy.
+//
+// If classifier is nil, no error is considered transient (hence no retry).
+//
+// Available since v0.7.0
+func NewRetryingGenericDao(dao IGenericDao, classifier TransientErrorClassifier) *RetryingGenericDao {
+	return &RetryingGenericDao{
+		origDao:    dao,
+		dao:        ToGenericDaoWithContext(dao),
+		classifier: classifier,
+		policy:     DefaultRetryPolicy,
+		funcSleep:  sleepWithContext,
+	}
+}
+
+// RetryingGenericDao is a wrapper of IGenericDao that retries failed operations with exponential backoff and jitter.
+//
+// An operation is retried only if the error is classified as transient by the TransientErrorClassifier.
+// By default, only idempotent operations are retried: GdaoFetchOne, GdaoFetchMany, GdaoSave and GdaoDelete.
+// Call SetRetryNonIdempotent(true) to also retry GdaoCreate, GdaoUpdate and GdaoDeleteMany.
+//
+// If the context passed to GdaoXXXWithContext functions is cancelled, no further retry is made.
+//
+// Available since v0.7.0
+type RetryingGenericDao struct {
+	origDao            IGenericDao
+	dao                IGenericDaoWithContext
+	classifier         TransientErrorClassifier
+	policy             RetryPolicy
+	retryNonIdempotent bool
+	funcSleep          func(ctx context.Context, d time.Duration) error
+}
+
+// sleepWithContext waits for the specified duration, or until the context is done.
+func sleepWithContext(ctx context.Context, d time.Duration) error {
+	if ctx == nil {
+		time.Sleep(d)
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// GetDao returns the underlying DAO.
+func (dao *RetryingGenericDao) GetDao() IGenericDao {
+	return dao.origDao
+}
+
+// GetTransientErrorClassifier returns the classifier used to check if an error is transient.
+func (dao *RetryingGenericDao) GetTransientErrorClassifier() TransientErrorClassifier {
+	return dao.classifier
+}
+
+// SetTransientErrorClassifier sets the classifier used to check if an error is transient.
+func (dao *RetryingGenericDao) SetTransientErrorClassifier(classifier TransientErrorClassifier) *RetryingGenericDao {
+	dao.classifier = classifier
+	return dao
+}
+
+// GetRetryPolicy returns the retry policy being used.
+func (dao *RetryingGenericDao) GetRetryPolicy() RetryPolicy {
+	return dao.policy
+}
+
+// SetRetryPolicy sets the retry policy.
+func (dao *RetryingGenericDao) SetRetryPolicy(policy RetryPolicy) *RetryingGenericDao {
+	dao.policy = policy
+	return dao
+}
+
+// GetRetryNonIdempotent returns 'true' if non-idempotent operations (GdaoCreate, GdaoUpdate and GdaoDeleteMany) are
+// also retried, 'false' otherwise.
+func (dao *RetryingGenericDao) GetRetryNonIdempotent() bool {
+	return dao.retryNonIdempotent
+}
+
+// SetRetryNonIdempotent enables/disables retrying non-idempotent operations (GdaoCreate, GdaoUpdate and GdaoDeleteMany).
+// Default value is 'false'.
+func (dao *RetryingGenericDao) SetRetryNonIdempotent(enabled bool) *RetryingGenericDao {
+	dao.retryNonIdempotent = enabled
+	return dao
+}
+
+// execute calls the function, retrying it according to the retry policy.
+func (dao *RetryingGenericDao) execute(ctx context.Context, idempotent bool, f func() error) error {
+	err := f()
+	if !idempotent && !dao.retryNonIdempotent {
+		return err
+	}
+	for attempt := 1; attempt < dao.policy.MaxAttempts && err != nil && dao.classifier != nil && dao.classifier(err); attempt++ {
+		if e := dao.funcSleep(ctx, dao.policy.Backoff(attempt)); e != nil {
+			return err
+		}
+		err = f()
+	}
+	return err
+}
+
+// GdaoCreateFilter implements IGenericDao.GdaoCreateFilter.
+func (dao *RetryingGenericDao) GdaoCreateFilter(storageId string, bo IGenericBo) FilterOpt {
+	return dao.dao.GdaoCreateFilter(storageId, bo)
+}
+
+// GetRowMapper implements IGenericDao.GetRowMapper.
+func (dao *RetryingGenericDao) GetRowMapper() IRowMapper {
+	return dao.dao.GetRowMapper()
+}
+
+// GdaoDelete implements IGenericDao.GdaoDelete.
+func (dao *RetryingGenericDao) GdaoDelete(storageId string, bo IGenericBo) (int, error) {
+	return dao.GdaoDeleteWithContext(nil, storageId, bo)
+}
+
+// GdaoDeleteWithContext implements IGenericDaoWithContext.GdaoDeleteWithContext.
+func (dao *RetryingGenericDao) GdaoDeleteWithContext(ctx context.Context, storageId string, bo IGenericBo) (int, error) {
+	var numRows int
+	err := dao.execute(ctx, true, func() (err error) {
+		numRows, err = dao.dao.GdaoDeleteWithContext(ctx, storageId, bo)
+		return err
+	})
+	return numRows, err
+}
+
+// GdaoDeleteMany implements IGenericDao.GdaoDeleteMany.
+func (dao *RetryingGenericDao) GdaoDeleteMany(storageId string, filter FilterOpt) (int, error) {
+	return dao.GdaoDeleteManyWithContext(nil, storageId, filter)
+}
+
+// GdaoDeleteManyWithContext implements IGenericDaoWithContext.GdaoDeleteManyWithContext.
+func (dao *RetryingGenericDao) GdaoDeleteManyWithContext(ctx context.Context, storageId string, filter FilterOpt) (int, error) {
+	var numRows int
+	err := dao.execute(ctx, false, func() (err error) {
+		numRows, err = dao.dao.GdaoDeleteManyWithContext(ctx, storageId, filter)
+		return err
+	})
+	return numRows, err
+}
+
+// GdaoFetchOne implements IGenericDao.GdaoFetchOne.
+func (dao *RetryingGenericDao) GdaoFetchOne(storageId string, filter FilterOpt) (IGenericBo, error) {
+	return dao.GdaoFetchOneWithContext(nil, storageId, filter)
+}
+
+// GdaoFetchOneWithContext implements IGenericDaoWithContext.GdaoFetchOneWithContext.
+func (dao *RetryingGenericDao) GdaoFetchOneWithContext(ctx context.Context, storageId string, filter FilterOpt) (IGenericBo, error) {
+	var bo IGenericBo
+	err := dao.execute(ctx, true, func() (err error) {
+		bo, err = dao.dao.GdaoFetchOneWithContext(ctx, storageId, filter)
+		return err
+	})
+	return bo, err
+}
+
+// GdaoFetchMany implements IGenericDao.GdaoFetchMany.
+func (dao *RetryingGenericDao) GdaoFetchMany(storageId string, filter FilterOpt, sorting *SortingOpt, startOffset, numItems int) ([]IGenericBo, error) {
+	return dao.GdaoFetchManyWithContext(nil, storageId, filter, sorting, startOffset, numItems)
+}
+
+// GdaoFetchManyWithContext implements IGenericDaoWithContext.GdaoFetchManyWithContext.
+func (dao *RetryingGenericDao) GdaoFetchManyWithContext(ctx context.Context, storageId string, filter FilterOpt, sorting *SortingOpt, startOffset, numItems int) ([]IGenericBo, error) {
+	var boList []IGenericBo
+	err := dao.execute(ctx, true, func() (err error) {
+		boList, err = dao.dao.GdaoFetchManyWithContext(ctx, storageId, filter, sorting, startOffset, numItems)
+		return err
+	})
+	return boList, err
+}
+
+// GdaoCreate implements IGenericDao.GdaoCreate.
+func (dao *RetryingGenericDao) GdaoCreate(storageId string, bo IGenericBo) (int, error) {
+	return dao.GdaoCreateWithContext(nil, storageId, bo)
+}
+
+// GdaoCreateWithContext implements IGenericDaoWithContext.GdaoCreateWithContext.
+func (dao *RetryingGenericDao) GdaoCreateWithContext(ctx context.Context, storageId string, bo IGenericBo) (int, error) {
+	var numRows int
+	err := dao.execute(ctx, false, func() (err error) {
+		numRows, err = dao.dao.GdaoCreateWithContext(ctx, storageId, bo)
+		return err
+	})
+	return numRows, err
+}
+
+// GdaoUpdate implements IGenericDao.GdaoUpdate.
+func (dao *RetryingGenericDao) GdaoUpdate(storageId string, bo IGenericBo) (int, error) {
+	return dao.GdaoUpdateWithContext(nil, storageId, bo)
+}
+
+// GdaoUpdateWithContext implements IGenericDaoWithContext.GdaoUpdateWithContext.
+func (dao *RetryingGenericDao) GdaoUpdateWithContext(ctx context.Context, storageId string, bo IGenericBo) (int, error) {
+	var numRows int
+	err := dao.execute(ctx, false, func() (err error) {
+		numRows, err = dao.dao.GdaoUpdateWithContext(ctx, storageId, bo)
+		return err
+	})
+	return numRows, err
+}
+
+// GdaoSave implements IGenericDao.GdaoSave.
+func (dao *RetryingGenericDao) GdaoSave(storageId string, bo IGenericBo) (int, error) {
+	return dao.GdaoSaveWithContext(nil, storageId, bo)
+}
+
+// GdaoSaveWithContext implements IGenericDaoWithContext.GdaoSaveWithContext.
+func (dao *RetryingGenericDao) GdaoSaveWithContext(ctx context.Context, storageId string, bo IGenericBo) (int, error) {
+	var numRows int
+	err := dao.execute(ctx, true, func() (err error) {
+		numRows, err = dao.dao.GdaoSaveWithContext(ctx, storageId, bo)
+		return err
+	})
+	return numRows, err
+}
@@ -0,0 +1,156 @@
+package godal
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+var errTransientForTest = errors.New("transient error")
+
+func isErrorTransientForTest(err error) bool {
+	return err == errTransientForTest
+}
+
+func newRetryingGenericDaoForTest(mock *mockGenericDao) (*RetryingGenericDao, *[]time.Duration) {
+	sleeps := make([]time.Duration, 0)
+	dao := NewRetryingGenericDao(mock, isErrorTransientForTest)
+	dao.funcSleep = func(ctx context.Context, d time.Duration) error {
+		sleeps = append(sleeps, d)
+		if ctx != nil {
+			return ctx.Err()
+		}
+		return nil
+	}
+	return dao, &sleeps
+}
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	name := "TestRetryPolicy_Backoff"
+	p := RetryPolicy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: 500 * time.Millisecond, Multiplier: 2}
+	expected := []time.Duration{0, 100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 500 * time.Millisecond, 500 * time.Millisecond}
+	for n, d := range expected {
+		if v := p.Backoff(n); v != d {
+			t.Fatalf("%s failed: expected backoff #%d to be %s but received %s", name, n, d, v)
+		}
+	}
+
+	p.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		if v := p.Backoff(2); v < 100*time.Millisecond || v > 300*time.Millisecond {
+			t.Fatalf("%s failed: backoff %s out of jitter range", name, v)
+		}
+	}
+}
+
+func TestRetryingGenericDao_Idempotent(t *testing.T) {
+	name := "TestRetryingGenericDao_Idempotent"
+	mock := newMockGenericDao()
+	dao, sleeps := newRetryingGenericDaoForTest(mock)
+	mock.GdaoCreate("test", newMockBo("1", 1))
+
+	mock.errToThrow = errTransientForTest
+	if _, err := dao.GdaoFetchOne("test", nil); err != errTransientForTest {
+		t.Fatalf("%s failed: expected error %#v but received %#v", name, errTransientForTest, err)
+	}
+	if c := mock.count("fetchOne"); c != DefaultRetryPolicy.MaxAttempts {
+		t.Fatalf("%s failed: expected %#v attempts but received %#v", name, DefaultRetryPolicy.MaxAttempts, c)
+	}
+	if len(*sleeps) != DefaultRetryPolicy.MaxAttempts-1 {
+		t.Fatalf("%s failed: expected %#v sleeps but received %#v", name, DefaultRetryPolicy.MaxAttempts-1, len(*sleeps))
+	}
+
+	dao.GdaoFetchMany("test", nil, nil, 0, 0)
+	dao.GdaoSave("test", newMockBo("1", 2))
+	dao.GdaoDelete("test", newMockBo("1", nil))
+	for _, op := range []string{"fetchMany", "save", "delete"} {
+		if c := mock.count(op); c != DefaultRetryPolicy.MaxAttempts {
+			t.Fatalf("%s failed: expected %#v attempts of %s but received %#v", name, DefaultRetryPolicy.MaxAttempts, op, c)
+		}
+	}
+}
+
+func TestRetryingGenericDao_NonIdempotent(t *testing.T) {
+	name := "TestRetryingGenericDao_NonIdempotent"
+	mock := newMockGenericDao()
+	dao, _ := newRetryingGenericDaoForTest(mock)
+	mock.errToThrow = errTransientForTest
+
+	dao.GdaoCreate("test", newMockBo("1", 1))
+	dao.GdaoUpdate("test", newMockBo("1", 1))
+	dao.GdaoDeleteMany("test", nil)
+	for _, op := range []string{"create", "update", "deleteMany"} {
+		if c := mock.count(op); c != 1 {
+			t.Fatalf("%s failed: expected %#v attempt of %s but received %#v", name, 1, op, c)
+		}
+	}
+
+	dao.SetRetryNonIdempotent(true)
+	dao.GdaoCreate("test", newMockBo("1", 1))
+	dao.GdaoUpdate("test", newMockBo("1", 1))
+	dao.GdaoDeleteMany("test", nil)
+	for _, op := range []string{"create", "update", "deleteMany"} {
+		if c := mock.count(op); c != 1+DefaultRetryPolicy.MaxAttempts {
+			t.Fatalf("%s failed: expected %#v attempts of %s but received %#v", name, 1+DefaultRetryPolicy.MaxAttempts, op, c)
+		}
+	}
+}
+
+func TestRetryingGenericDao_NonTransient(t *testing.T) {
+	name := "TestRetryingGenericDao_NonTransient"
+	mock := newMockGenericDao()
+	dao, _ := newRetryingGenericDaoForTest(mock)
+	mock.errToThrow = errors.New("permanent error")
+	if _, err := dao.GdaoFetchOne("test", nil); err != mock.errToThrow {
+		t.Fatalf("%s failed: expected error %#v but received %#v", name, mock.errToThrow, err)
+	}
+	if c := mock.count("fetchOne"); c != 1 {
+		t.Fatalf("%s failed: expected %#v attempt but received %#v", name, 1, c)
+	}
+}
+
+func TestRetryingGenericDao_Recover(t *testing.T) {
+	name := "TestRetryingGenericDao_Recover"
+	mock := newMockGenericDao()
+	mock.GdaoCreate("test", newMockBo("1", 1))
+	dao, _ := newRetryingGenericDaoForTest(mock)
+	mock.errToThrow = errTransientForTest
+	dao.funcSleep = func(ctx context.Context, d time.Duration) error {
+		mock.errToThrow = nil
+		return nil
+	}
+	if bo, err := dao.GdaoFetchOne("test", mock.GdaoCreateFilter("test", newMockBo("1", nil))); bo == nil || err != nil {
+		t.Fatalf("%s failed: %#v / %#v", name, bo, err)
+	}
+	if c := mock.count("fetchOne"); c != 2 {
+		t.Fatalf("%s failed: expected %#v attempts but received %#v", name, 2, c)
+	}
+}
+
+func TestRetryingGenericDao_ContextCancelled(t *testing.T) {
+	name := "TestRetryingGenericDao_ContextCancelled"
+	mock := newMockGenericDao()
+	dao, _ := newRetryingGenericDaoForTest(mock)
+	mock.errToThrow = errTransientForTest
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := dao.GdaoFetchOneWithContext(ctx, "test", nil); err != errTransientForTest {
+		t.Fatalf("%s failed: expected error %#v but received %#v", name, errTransientForTest, err)
+	}
+	if c := mock.count("fetchOne"); c != 1 {
+		t.Fatalf("%s failed: expected %#v attempt but received %#v", name, 1, c)
+	}
+}
+
+func TestSleepWithContext(t *testing.T) {
+	name := "TestSleepWithContext"
+	if err := sleepWithContext(nil, time.Millisecond); err != nil {
+		t.Fatalf("%s failed: %#v", name, err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := sleepWithContext(ctx, time.Hour); err != context.Canceled {
+		t.Fatalf("%s failed: expected %#v but received %#v", name, context.Canceled, err)
+	}
+}
@@ -0,0 +1,336 @@
+package godal
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/btnguyen2k/consu/reddo"
+)
+
+// ColumnsListMode specifies what StructRowMapper.ColumnsList returns.
+//
+// Available since v0.7.0
+type ColumnsListMode int
+
+const (
+	// ColumnsListAll returns all mapped columns (database/sql).
+	ColumnsListAll ColumnsListMode = iota
+
+	// ColumnsListKeys returns the primary key columns, i.e. fields tagged with "pk" (AWS DynamoDB).
+	ColumnsListKeys
+
+	// ColumnsListWildcard returns []string{"*"} (MongoDB, Azure Cosmos DB).
+	ColumnsListWildcard
+)
+
+// NewStructRowMapper constructs a new StructRowMapper.
+//
+// Use ColumnsListAll with GenericDaoSql, ColumnsListKeys with GenericDaoDynamodb, and ColumnsListWildcard with
+// GenericDaoMongo and GenericDaoCosmosdb.
+//
+// Available since v0.7.0
+func NewStructRowMapper(mode ColumnsListMode) *StructRowMapper {
+	return &StructRowMapper{mode: mode, storages: make(map[string]*structMapping)}
+}
+
+// StructRowMapper is an implementation of IRowMapper driven by struct tags: each storage is registered with a Go
+// struct that describes its columns.
+//
+// BO field names follow the rules of TypedDao (the "json" tag, or the Go field name). Columns are specified via the
+// "godal" tag, in the format `godal:"column_name,pk,omitempty,json"`:
+//   - column_name: name of the database column, default to the BO field name.
+//   - pk: the column is part of the primary key (see ColumnsListKeys).
+//   - omitempty: ToRow omits the column if its value is empty.
+//   - json: the value is stored as a JSON string, e.g. nested structs stored in a TEXT column.
+//   - `godal:"-"`: the field is not mapped.
+//
+// Implementation rules:
+//   - ToRow: transforms a BO to map[string]interface{}. Only mapped fields present in the BO are converted, values are
+//     converted to the fields' Go types first (e.g. a float64 becomes int64 for an int field).
+//   - ToBo: expects input to be a map[string]interface{}, or JSON data (string or array/slice of bytes). Only mapped
+//     columns are converted (column names are matched case-insensitively if there is no exact match), values are
+//     converted to the fields' Go types first (e.g. an int64 returned by SQLite becomes bool for a bool field).
+//   - ColumnsList: see ColumnsListMode. Returns []string{"*"} for storages that are not registered.
+//   - ToDbColName/ToBoFieldName: translate names of mapped fields/columns; the first segment of a nested path
+//     (e.g. "address.city") is also translated. Other names are returned as-is.
+//
+// Storages should be registered once when the row mapper is initialized; Register is not safe to call concurrently
+// with other functions.
+//
+// Available since v0.7.0
+type StructRowMapper struct {
+	mode     ColumnsListMode
+	storages map[string]*structMapping
+}
+
+type structColumn struct {
+	field     string
+	column    string
+	typ       reflect.Type
+	pk        bool
+	omitEmpty bool
+	json      bool
+}
+
+type structMapping struct {
+	columns      []*structColumn
+	byField      map[string]*structColumn
+	byColumn     map[string]*structColumn
+	byColumnFold map[string]*structColumn // lower-cased column names, for databases that change the case of column names
+	colNames     []string
+	keyNames     []string
+}
+
+// Register registers the struct that describes a storage. prototype is a struct or a pointer to struct.
+func (mapper *StructRowMapper) Register(storageId string, prototype interface{}) error {
+	typ := reflect.TypeOf(prototype)
+	for typ != nil && typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ == nil || typ.Kind() != reflect.Struct {
+		return fmt.Errorf("cannot register storage [%s]: struct expected, received %T", storageId, prototype)
+	}
+	mapping := &structMapping{
+		byField:      make(map[string]*structColumn),
+		byColumn:     make(map[string]*structColumn),
+		byColumnFold: make(map[string]*structColumn),
+	}
+	for _, f := range structFields(typ) {
+		sf := typ.FieldByIndex(f.index)
+		tag := sf.Tag.Get("godal")
+		if tag == "-" {
+			continue
+		}
+		opts := strings.Split(tag, ",")
+		col := &structColumn{field: f.name, column: strings.TrimSpace(opts[0]), typ: sf.Type}
+		if col.column == "" {
+			col.column = f.name
+		}
+		for _, opt := range opts[1:] {
+			switch strings.TrimSpace(opt) {
+			case "pk":
+				col.pk = true
+			case "omitempty":
+				col.omitEmpty = true
+			case "json":
+				col.json = true
+			case "":
+			default:
+				return fmt.Errorf("cannot register storage [%s]: unknown option %#v of field %s", storageId, opt, sf.Name)
+			}
+		}
+		if _, ok := mapping.byColumn[col.column]; ok {
+			return fmt.Errorf("cannot register storage [%s]: column %#v is mapped more than once", storageId, col.column)
+		}
+		mapping.columns = append(mapping.columns, col)
+		mapping.byField[col.field] = col
+		mapping.byColumn[col.column] = col
+		mapping.byColumnFold[strings.ToLower(col.column)] = col
+		mapping.colNames = append(mapping.colNames, col.column)
+		if col.pk {
+			mapping.keyNames = append(mapping.keyNames, col.column)
+		}
+	}
+	mapper.storages[storageId] = mapping
+	return nil
+}
+
+func (mapper *StructRowMapper) mapping(storageId string) (*structMapping, error) {
+	if mapping, ok := mapper.storages[storageId]; ok {
+		return mapping, nil
+	}
+	return nil, fmt.Errorf("storage [%s] is not registered", storageId)
+}
+
+// ToRow implements IRowMapper.ToRow.
+func (mapper *StructRowMapper) ToRow(storageId string, bo IGenericBo) (interface{}, error) {
+	if bo == nil {
+		return nil, nil
+	}
+	mapping, err := mapper.mapping(storageId)
+	if err != nil {
+		return nil, err
+	}
+	data := make(map[string]interface{})
+	bo.GboIterate(func(kind reflect.Kind, field interface{}, value interface{}) {
+		if kind == reflect.Map {
+			data[fmt.Sprint(field)] = value
+		}
+	})
+	row := make(map[string]interface{})
+	for _, col := range mapping.columns {
+		raw, ok := data[col.field]
+		if !ok {
+			continue
+		}
+		fv := reflect.New(col.typ).Elem()
+		if err := fromGboValue(raw, fv); err != nil {
+			return nil, fmt.Errorf("storage [%s], field %s: %w", storageId, col.field, err)
+		}
+		if col.omitEmpty && (raw == nil || isEmptyValue(fv)) {
+			continue
+		}
+		if raw == nil {
+			row[col.column] = nil
+			continue
+		}
+		var value interface{}
+		if col.json {
+			var js []byte
+			if js, err = json.Marshal(fv.Interface()); err == nil {
+				value = string(js)
+			}
+		} else {
+			value, err = toGboValue(fv)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("storage [%s], field %s: %w", storageId, col.field, err)
+		}
+		row[col.column] = value
+	}
+	return row, nil
+}
+
+// rowToMap normalizes a database row (a map, or JSON data) to map[string]interface{}.
+func rowToMap(row interface{}) (map[string]interface{}, error) {
+	var js []byte
+	switch r := row.(type) {
+	case nil:
+		return nil, nil
+	case map[string]interface{}:
+		return r, nil
+	case string:
+		js = []byte(r)
+	case []byte:
+		js = r
+	default:
+		v := reflect.ValueOf(row)
+		for ; v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface; v = v.Elem() {
+			if v.IsNil() {
+				return nil, nil
+			}
+		}
+		switch {
+		case v.Kind() == reflect.Map:
+			if v.IsNil() {
+				return nil, nil
+			}
+			result := make(map[string]interface{}, v.Len())
+			for iter := v.MapRange(); iter.Next(); {
+				key, _ := reddo.ToString(iter.Key().Interface())
+				result[key] = iter.Value().Interface()
+			}
+			return result, nil
+		case v.Kind() == reflect.String:
+			js = []byte(v.String())
+		case (v.Kind() == reflect.Slice || v.Kind() == reflect.Array) && v.Type().Elem().Kind() == reflect.Uint8:
+			js = make([]byte, v.Len())
+			reflect.Copy(reflect.ValueOf(js), v)
+		default:
+			return nil, fmt.Errorf("cannot construct godal.IGenericBo from input %v", row)
+		}
+	}
+	if len(js) == 0 {
+		return nil, nil
+	}
+	var result map[string]interface{}
+	return result, json.Unmarshal(js, &result)
+}
+
+// ToBo implements IRowMapper.ToBo.
+func (mapper *StructRowMapper) ToBo(storageId string, row interface{}) (IGenericBo, error) {
+	mapping, err := mapper.mapping(storageId)
+	if err != nil {
+		return nil, err
+	}
+	data, err := rowToMap(row)
+	if err != nil || data == nil {
+		return nil, err
+	}
+	result := make(map[string]interface{})
+	for colName, raw := range data {
+		col, ok := mapping.byColumn[colName]
+		if !ok {
+			if col, ok = mapping.byColumnFold[strings.ToLower(colName)]; !ok {
+				continue
+			}
+		}
+		if raw == nil {
+			result[col.field] = nil
+			continue
+		}
+		fv := reflect.New(col.typ).Elem()
+		if js, isJson := raw.([]byte); col.json && isJson {
+			err = json.Unmarshal(js, fv.Addr().Interface())
+		} else if js, isJson := raw.(string); col.json && isJson {
+			err = json.Unmarshal([]byte(js), fv.Addr().Interface())
+		} else {
+			err = fromGboValue(raw, fv)
+		}
+		if err == nil {
+			result[col.field], err = toGboValue(fv)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("storage [%s], column %s: %w", storageId, colName, err)
+		}
+	}
+	bo := NewGenericBo()
+	return bo, bo.GboImportViaMap(result)
+}
+
+// ColumnsList implements IRowMapper.ColumnsList.
+func (mapper *StructRowMapper) ColumnsList(storageId string) []string {
+	mapping, ok := mapper.storages[storageId]
+	switch {
+	case mapper.mode == ColumnsListWildcard || !ok:
+		return []string{"*"}
+	case mapper.mode == ColumnsListKeys:
+		return append([]string{}, mapping.keyNames...)
+	}
+	return append([]string{}, mapping.colNames...)
+}
+
+// translatePath translates the first segment of a path using the lookup function.
+func translatePath(path string, lookup func(name string) (string, bool)) string {
+	if name, ok := lookup(path); ok {
+		return name
+	}
+	if i := strings.IndexAny(path, ".["); i > 0 {
+		if name, ok := lookup(path[:i]); ok {
+			return name + path[i:]
+		}
+	}
+	return path
+}
+
+// ToDbColName implements IRowMapper.ToDbColName.
+func (mapper *StructRowMapper) ToDbColName(storageId, fieldName string) string {
+	mapping, ok := mapper.storages[storageId]
+	if !ok {
+		return fieldName
+	}
+	return translatePath(fieldName, func(name string) (string, bool) {
+		col, ok := mapping.byField[name]
+		if ok {
+			return col.column, true
+		}
+		return "", false
+	})
+}
+
+// ToBoFieldName implements IRowMapper.ToBoFieldName.
+func (mapper *StructRowMapper) ToBoFieldName(storageId, colName string) string {
+	mapping, ok := mapper.storages[storageId]
+	if !ok {
+		return colName
+	}
+	return translatePath(colName, func(name string) (string, bool) {
+		col, ok := mapping.byColumn[name]
+		if ok {
+			return col.field, true
+		}
+		return "", false
+	})
+}
@@ -0,0 +1,149 @@
+package godal
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+type rowMapperAddress struct {
+	City string `json:"city"`
+}
+
+type rowMapperUser struct {
+	Id      string            `json:"id" godal:"uid,pk"`
+	Group   string            `json:"group" godal:"ugroup,pk"`
+	Name    string            `json:"name"`
+	Active  bool              `json:"active" godal:"is_active"`
+	Age     int               `json:"age" godal:"age,omitempty"`
+	Created time.Time         `json:"created" godal:"created_at"`
+	Address *rowMapperAddress `json:"address" godal:"addr,json"`
+	Tags    []string          `json:"tags"`
+	Ignored string            `json:"ignored" godal:"-"`
+}
+
+func TestStructRowMapper_Register(t *testing.T) {
+	name := "TestStructRowMapper_Register"
+	mapper := NewStructRowMapper(ColumnsListAll)
+	if err := mapper.Register("users", &rowMapperUser{}); err != nil {
+		t.Fatalf("%s failed: %e", name, err)
+	}
+	for _, prototype := range []interface{}{
+		"not a struct",
+		nil,
+		struct {
+			A string `godal:"col,unknown"`
+		}{},
+		struct {
+			A string `godal:"col"`
+			B string `godal:"col"`
+		}{},
+	} {
+		if err := mapper.Register("invalid", prototype); err == nil {
+			t.Fatalf("%s failed: %#v should be rejected", name, prototype)
+		}
+	}
+}
+
+func TestStructRowMapper_ColumnsList(t *testing.T) {
+	name := "TestStructRowMapper_ColumnsList"
+	testCases := map[ColumnsListMode][]string{
+		ColumnsListAll:      {"uid", "ugroup", "name", "is_active", "age", "created_at", "addr", "tags"},
+		ColumnsListKeys:     {"uid", "ugroup"},
+		ColumnsListWildcard: {"*"},
+	}
+	for mode, expected := range testCases {
+		mapper := NewStructRowMapper(mode)
+		mapper.Register("users", rowMapperUser{})
+		if cols := mapper.ColumnsList("users"); !reflect.DeepEqual(cols, expected) {
+			t.Fatalf("%s failed: mode %d, expected %#v but received %#v", name, mode, expected, cols)
+		}
+		if cols := mapper.ColumnsList("unknown"); !reflect.DeepEqual(cols, []string{"*"}) {
+			t.Fatalf("%s failed: %#v", name, cols)
+		}
+	}
+}
+
+func TestStructRowMapper_ToDbColName(t *testing.T) {
+	name := "TestStructRowMapper_ToDbColName"
+	mapper := NewStructRowMapper(ColumnsListAll)
+	mapper.Register("users", rowMapperUser{})
+	testCases := [][2]string{{"id", "uid"}, {"active", "is_active"}, {"address.city", "addr.city"}, {"tags[0]", "tags[0]"}, {"other", "other"}, {"ignored", "ignored"}}
+	for _, tc := range testCases {
+		if col := mapper.ToDbColName("users", tc[0]); col != tc[1] {
+			t.Fatalf("%s failed: expected %#v but received %#v", name, tc[1], col)
+		}
+		if field := mapper.ToBoFieldName("users", tc[1]); field != tc[0] {
+			t.Fatalf("%s failed: expected %#v but received %#v", name, tc[0], field)
+		}
+	}
+	if col := mapper.ToDbColName("unknown", "id"); col != "id" {
+		t.Fatalf("%s failed: %#v", name, col)
+	}
+}
+
+func TestStructRowMapper_ToRow(t *testing.T) {
+	name := "TestStructRowMapper_ToRow"
+	mapper := NewStructRowMapper(ColumnsListAll)
+	mapper.Register("users", rowMapperUser{})
+	now := time.Now()
+	bo := NewGenericBo()
+	bo.GboImportViaMap(map[string]interface{}{
+		"id": "1", "name": "Thanh", "active": "true", "age": 0, "created": now,
+		"address": map[string]interface{}{"city": "HCM"}, "tags": []interface{}{"a"}, "ignored": "x", "other": "y",
+	})
+	row, err := mapper.ToRow("users", bo)
+	expected := map[string]interface{}{
+		"uid": "1", "name": "Thanh", "is_active": true, "created_at": now, "addr": `{"city":"HCM"}`, "tags": []interface{}{"a"},
+	}
+	if err != nil || !reflect.DeepEqual(row, expected) {
+		t.Fatalf("%s failed: expected %#v but received %#v / %#v", name, expected, row, err)
+	}
+
+	bo.GboSetAttr("age", "not a number")
+	if _, err := mapper.ToRow("users", bo); err == nil {
+		t.Fatalf("%s failed: conversion error should be reported", name)
+	}
+	if _, err := mapper.ToRow("unknown", bo); err == nil {
+		t.Fatalf("%s failed: unregistered storage should be reported", name)
+	}
+	if row, err := mapper.ToRow("users", nil); row != nil || err != nil {
+		t.Fatalf("%s failed: %#v / %#v", name, row, err)
+	}
+}
+
+func TestStructRowMapper_ToBo(t *testing.T) {
+	name := "TestStructRowMapper_ToBo"
+	mapper := NewStructRowMapper(ColumnsListAll)
+	mapper.Register("users", rowMapperUser{})
+	now := time.Now()
+	row := map[string]interface{}{
+		"uid": []byte("1"), "NAME": "Thanh", "is_active": int64(1), "age": int64(30), "created_at": now,
+		"addr": `{"city":"HCM"}`, "tags": nil, "other": "y",
+	}
+	bo, err := mapper.ToBo("users", row)
+	if err != nil || bo == nil {
+		t.Fatalf("%s failed: %#v / %#v", name, bo, err)
+	}
+	data := make(map[string]interface{})
+	bo.GboIterate(func(_ reflect.Kind, field interface{}, value interface{}) { data[field.(string)] = value })
+	expected := map[string]interface{}{
+		"id": "1", "name": "Thanh", "active": true, "age": int64(30), "created": now,
+		"address": map[string]interface{}{"city": "HCM"}, "tags": nil,
+	}
+	if !reflect.DeepEqual(data, expected) {
+		t.Fatalf("%s failed: expected %#v but received %#v", name, expected, data)
+	}
+
+	// JSON input
+	bo, err = mapper.ToBo("users", []byte(`{"uid":"2","age":40}`))
+	if err != nil || bo.GboGetAttrUnsafe("id", nil) != "2" || bo.GboGetAttrUnsafe("age", nil) != int64(40) {
+		t.Fatalf("%s failed: %s / %#v", name, bo.GboToJsonUnsafe(), err)
+	}
+	if bo, err := mapper.ToBo("users", nil); bo != nil || err != nil {
+		t.Fatalf("%s failed: %#v / %#v", name, bo, err)
+	}
+	if _, err := mapper.ToBo("users", 1); err == nil {
+		t.Fatalf("%s failed: invalid input should be rejected", name)
+	}
+}
@@ -0,0 +1,539 @@
+package godal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/btnguyen2k/consu/reddo"
+)
+
+// IShardRouter maps a shard key to a shard.
+//
+// Available since v0.7.0
+type IShardRouter interface {
+	// Route returns index of the shard that holds the specified shard key.
+	Route(key interface{}) (int, error)
+}
+
+// compareShardValues compares two values, returning -1, 0 or 1.
+//   - nil is less than any other value.
+//   - numbers are compared numerically, time.Time values are compared chronologically, booleans: false < true.
+//   - other values are compared by their string representations.
+func compareShardValues(a, b interface{}) int {
+	if a == nil || b == nil {
+		switch {
+		case a == nil && b == nil:
+			return 0
+		case a == nil:
+			return -1
+		}
+		return 1
+	}
+	if isNumber(a) && isNumber(b) {
+		fa, _ := reddo.ToFloat(a)
+		fb, _ := reddo.ToFloat(b)
+		switch {
+		case fa < fb:
+			return -1
+		case fa > fb:
+			return 1
+		}
+		return 0
+	}
+	if ta, ok := a.(time.Time); ok {
+		if tb, ok := b.(time.Time); ok {
+			return ta.Compare(tb)
+		}
+	}
+	if ba, ok := a.(bool); ok {
+		if bb, ok := b.(bool); ok {
+			switch {
+			case ba == bb:
+				return 0
+			case bb:
+				return -1
+			}
+			return 1
+		}
+	}
+	return strings.Compare(fmt.Sprintf("%v", a), fmt.Sprintf("%v", b))
+}
+
+func isNumber(v interface{}) bool {
+	switch reflect.ValueOf(v).Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// shardKeyToString converts a shard key to string for hashing.
+// Integral floats (e.g. numbers parsed from JSON) produce the same string as the equivalent integers.
+func shardKeyToString(key interface{}) string {
+	switch v := key.(type) {
+	case string:
+		return v
+	case float32, float64:
+		f, _ := reddo.ToFloat(v)
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	return fmt.Sprintf("%v", key)
+}
+
+/*----------------------------------------------------------------------*/
+
+// NewConsistentHashRouter constructs a new ConsistentHashRouter.
+//
+//   - numShards: number of shards.
+//   - virtualNodes: number of points each shard occupies on the hash ring (value < 1 is treated as 100).
+//
+// Available since v0.7.0
+func NewConsistentHashRouter(numShards, virtualNodes int) *ConsistentHashRouter {
+	if virtualNodes < 1 {
+		virtualNodes = 100
+	}
+	r := &ConsistentHashRouter{}
+	for shard := 0; shard < numShards; shard++ {
+		for v := 0; v < virtualNodes; v++ {
+			h := crc32.ChecksumIEEE([]byte(fmt.Sprintf("shard-%d#%d", shard, v)))
+			r.ring = append(r.ring, hashRingPoint{hash: h, shard: shard})
+		}
+	}
+	sort.Slice(r.ring, func(i, j int) bool {
+		if r.ring[i].hash == r.ring[j].hash {
+			return r.ring[i].shard < r.ring[j].shard
+		}
+		return r.ring[i].hash < r.ring[j].hash
+	})
+	return r
+}
+
+type hashRingPoint struct {
+	hash  uint32
+	shard int
+}
+
+// ConsistentHashRouter is an IShardRouter that places shard keys on a consistent-hash ring.
+// When the number of shards grows from n to n+1, only about 1/(n+1) of the keys move to another shard.
+//
+// Available since v0.7.0
+type ConsistentHashRouter struct {
+	ring []hashRingPoint
+}
+
+// Route implements IShardRouter.Route.
+func (r *ConsistentHashRouter) Route(key interface{}) (int, error) {
+	if len(r.ring) == 0 {
+		return -1, fmt.Errorf("consistent-hash router has no shard")
+	}
+	h := crc32.ChecksumIEEE([]byte(shardKeyToString(key)))
+	i := sort.Search(len(r.ring), func(i int) bool { return r.ring[i].hash >= h })
+	if i >= len(r.ring) {
+		i = 0
+	}
+	return r.ring[i].shard, nil
+}
+
+// NewRangeShardRouter constructs a new RangeShardRouter from a sorted list of upper bounds:
+// shard #0 holds keys < bounds[0], shard #i holds keys in [bounds[i-1], bounds[i]), and the last shard
+// (#len(bounds)) holds keys >= bounds[len(bounds)-1].
+//
+// Available since v0.7.0
+func NewRangeShardRouter(bounds ...interface{}) *RangeShardRouter {
+	return &RangeShardRouter{bounds: bounds}
+}
+
+// RangeShardRouter is an IShardRouter that maps contiguous ranges of shard keys to shards.
+//
+// Available since v0.7.0
+type RangeShardRouter struct {
+	bounds []interface{}
+}
+
+// Route implements IShardRouter.Route.
+func (r *RangeShardRouter) Route(key interface{}) (int, error) {
+	if key == nil {
+		return -1, ErrGdaoShardKeyMissing
+	}
+	return sort.Search(len(r.bounds), func(i int) bool { return compareShardValues(key, r.bounds[i]) < 0 }), nil
+}
+
+/*----------------------------------------------------------------------*/
+
+// ShardKeyFunc extracts the shard key from a BO.
+//
+// Available since v0.7.0
+type ShardKeyFunc func(storageId string, bo IGenericBo) (interface{}, error)
+
+// NewShardedGenericDao constructs a new ShardedGenericDao.
+//
+//   - shardKeyField: name of the shard-key field. It is read from BOs and looked up in equality conditions of filters.
+//   - router: maps shard keys to indexes of shards.
+//   - shards: the underlying DAOs, one per shard. An error is returned if there is no shard.
+//
+// Available since v0.7.0
+func NewShardedGenericDao(shardKeyField string, router IShardRouter, shards ...IGenericDao) (*ShardedGenericDao, error) {
+	if len(shards) == 0 {
+		return nil, errors.New("at least one shard is required")
+	}
+	dao := &ShardedGenericDao{
+		shardKeyField:       shardKeyField,
+		filterShardKeyField: shardKeyField,
+		router:              router,
+		origShards:          shards,
+		shards:              make([]IGenericDaoWithContext, len(shards)),
+	}
+	for i, s := range shards {
+		dao.shards[i] = ToGenericDaoWithContext(s)
+	}
+	return dao, nil
+}
+
+// ShardedGenericDao is an IGenericDao that distributes BOs across several underlying DAOs (shards).
+//
+//   - GdaoCreate, GdaoUpdate, GdaoSave and GdaoDelete are routed by the shard key of the BO;
+//     ErrGdaoShardKeyMissing is returned if the BO has no shard key.
+//   - GdaoFetchOne, GdaoFetchMany and GdaoDeleteMany are routed by the equality condition on the shard-key field in the filter
+//     (either the filter itself or a direct member of a FilterOptAnd). If there is no such condition, the operation is
+//     scattered to all shards: GdaoFetchOne returns the first BO found, GdaoFetchMany merges results per SortingOpt
+//     (sorting field names are BO attributes) before applying startOffset and numItems, GdaoDeleteMany sums up deleted rows.
+//   - If a custom ShardKeyFunc is set, the shard key can not be derived from filters, hence GdaoFetchOne, GdaoFetchMany
+//     and GdaoDeleteMany are always scattered to all shards.
+//
+// GdaoCreateFilter and GetRowMapper are delegated to the first shard.
+//
+// Available since v0.7.0
+type ShardedGenericDao struct {
+	shardKeyField       string
+	filterShardKeyField string
+	shardKeyFunc        ShardKeyFunc
+	router              IShardRouter
+	origShards          []IGenericDao
+	shards              []IGenericDaoWithContext
+}
+
+// GetShards returns the underlying DAOs.
+func (dao *ShardedGenericDao) GetShards() []IGenericDao {
+	return dao.origShards
+}
+
+// GetRouter returns the shard router.
+func (dao *ShardedGenericDao) GetRouter() IShardRouter {
+	return dao.router
+}
+
+// GetShardKeyField returns name of the shard-key field.
+func (dao *ShardedGenericDao) GetShardKeyField() string {
+	return dao.shardKeyField
+}
+
+// GetFilterShardKeyField returns name of the shard-key field used in filters.
+func (dao *ShardedGenericDao) GetFilterShardKeyField() string {
+	return dao.filterShardKeyField
+}
+
+// SetFilterShardKeyField sets name of the shard-key field used in filters, if it differs from the BO field name
+// (e.g. BO attribute "userId" is stored in column "user_id").
+func (dao *ShardedGenericDao) SetFilterShardKeyField(field string) *ShardedGenericDao {
+	dao.filterShardKeyField = field
+	return dao
+}
+
+// GetShardKeyFunc returns the function that extracts the shard key from BOs.
+func (dao *ShardedGenericDao) GetShardKeyFunc() ShardKeyFunc {
+	return dao.shardKeyFunc
+}
+
+// SetShardKeyFunc sets the function that extracts the shard key from BOs.
+// If nil (default), value of the shard-key field is used.
+//
+// Filter-based operations (GdaoFetchOne, GdaoFetchMany and GdaoDeleteMany) are scattered to all shards if a custom
+// function is set, because the filter's shard-key condition may not route to the shard the BO was written to.
+func (dao *ShardedGenericDao) SetShardKeyFunc(f ShardKeyFunc) *ShardedGenericDao {
+	dao.shardKeyFunc = f
+	return dao
+}
+
+func (dao *ShardedGenericDao) route(key interface{}) (IGenericDaoWithContext, error) {
+	index, err := dao.router.Route(key)
+	if err != nil {
+		return nil, err
+	}
+	if index < 0 || index >= len(dao.shards) {
+		return nil, fmt.Errorf("shard key %#v is routed to shard #%d, which does not exist", key, index)
+	}
+	return dao.shards[index], nil
+}
+
+// shardForBo returns the shard that holds the BO.
+func (dao *ShardedGenericDao) shardForBo(storageId string, bo IGenericBo) (IGenericDaoWithContext, error) {
+	var key interface{}
+	var err error
+	if dao.shardKeyFunc != nil {
+		key, err = dao.shardKeyFunc(storageId, bo)
+	} else if bo != nil {
+		key, err = bo.GboGetAttr(dao.shardKeyField, nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		return nil, ErrGdaoShardKeyMissing
+	}
+	return dao.route(key)
+}
+
+// shardKeyFromFilter looks up the equality condition on the shard-key field in the filter.
+func (dao *ShardedGenericDao) shardKeyFromFilter(filter FilterOpt) (interface{}, bool) {
+	switch f := filter.(type) {
+	case FilterOptFieldOpValue:
+		return dao.shardKeyFromFilter(&f)
+	case *FilterOptFieldOpValue:
+		if f != nil && f.FieldName == dao.filterShardKeyField && f.Operator == FilterOpEqual && f.Value != nil {
+			return f.Value, true
+		}
+	case FilterOptAnd:
+		return dao.shardKeyFromFilter(&f)
+	case *FilterOptAnd:
+		if f != nil {
+			for _, child := range f.Filters {
+				if key, ok := dao.shardKeyFromFilter(child); ok {
+					return key, true
+				}
+			}
+		}
+	}
+	return nil, false
+}
+
+// shardForFilter returns the shard targeted by the filter, or nil if the filter targets all shards.
+//
+// Writes are routed by shardKeyFunc if set; its result can not be derived from the filter, so all shards are targeted.
+func (dao *ShardedGenericDao) shardForFilter(filter FilterOpt) (IGenericDaoWithContext, error) {
+	if dao.shardKeyFunc != nil {
+		return nil, nil
+	}
+	if key, ok := dao.shardKeyFromFilter(filter); ok {
+		return dao.route(key)
+	}
+	return nil, nil
+}
+
+// scatter calls the function on all shards concurrently and returns the first error, if any.
+func (dao *ShardedGenericDao) scatter(f func(index int, shard IGenericDaoWithContext) error) error {
+	errs := make([]error, len(dao.shards))
+	var wg sync.WaitGroup
+	wg.Add(len(dao.shards))
+	for i, shard := range dao.shards {
+		go func(i int, shard IGenericDaoWithContext) {
+			defer wg.Done()
+			errs[i] = f(i, shard)
+		}(i, shard)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mergeSorted merges BO lists fetched from shards per sorting options.
+func mergeSorted(lists [][]IGenericBo, sorting *SortingOpt) []IGenericBo {
+	result := make([]IGenericBo, 0)
+	for _, list := range lists {
+		result = append(result, list...)
+	}
+	if sorting == nil || len(sorting.Fields) == 0 {
+		return result
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		for _, field := range sorting.Fields {
+			vi, _ := result[i].GboGetAttr(field.FieldName, nil)
+			vj, _ := result[j].GboGetAttr(field.FieldName, nil)
+			c := compareShardValues(vi, vj)
+			if field.Descending {
+				c = -c
+			}
+			if c != 0 {
+				return c < 0
+			}
+		}
+		return false
+	})
+	return result
+}
+
+// GdaoCreateFilter implements IGenericDao.GdaoCreateFilter.
+func (dao *ShardedGenericDao) GdaoCreateFilter(storageId string, bo IGenericBo) FilterOpt {
+	return dao.shards[0].GdaoCreateFilter(storageId, bo)
+}
+
+// GetRowMapper implements IGenericDao.GetRowMapper.
+func (dao *ShardedGenericDao) GetRowMapper() IRowMapper {
+	return dao.shards[0].GetRowMapper()
+}
+
+// GdaoDelete implements IGenericDao.GdaoDelete.
+func (dao *ShardedGenericDao) GdaoDelete(storageId string, bo IGenericBo) (int, error) {
+	return dao.GdaoDeleteWithContext(nil, storageId, bo)
+}
+
+// GdaoDeleteWithContext implements IGenericDaoWithContext.GdaoDeleteWithContext.
+func (dao *ShardedGenericDao) GdaoDeleteWithContext(ctx context.Context, storageId string, bo IGenericBo) (int, error) {
+	shard, err := dao.shardForBo(storageId, bo)
+	if err != nil {
+		return 0, err
+	}
+	return shard.GdaoDeleteWithContext(ctx, storageId, bo)
+}
+
+// GdaoDeleteMany implements IGenericDao.GdaoDeleteMany.
+func (dao *ShardedGenericDao) GdaoDeleteMany(storageId string, filter FilterOpt) (int, error) {
+	return dao.GdaoDeleteManyWithContext(nil, storageId, filter)
+}
+
+// GdaoDeleteManyWithContext implements IGenericDaoWithContext.GdaoDeleteManyWithContext.
+func (dao *ShardedGenericDao) GdaoDeleteManyWithContext(ctx context.Context, storageId string, filter FilterOpt) (int, error) {
+	shard, err := dao.shardForFilter(filter)
+	if err != nil {
+		return 0, err
+	}
+	if shard != nil {
+		return shard.GdaoDeleteManyWithContext(ctx, storageId, filter)
+	}
+	counts := make([]int, len(dao.shards))
+	err = dao.scatter(func(i int, shard IGenericDaoWithContext) (err error) {
+		counts[i], err = shard.GdaoDeleteManyWithContext(ctx, storageId, filter)
+		return err
+	})
+	numRows := 0
+	for _, c := range counts {
+		numRows += c
+	}
+	return numRows, err
+}
+
+// GdaoFetchOne implements IGenericDao.GdaoFetchOne.
+func (dao *ShardedGenericDao) GdaoFetchOne(storageId string, filter FilterOpt) (IGenericBo, error) {
+	return dao.GdaoFetchOneWithContext(nil, storageId, filter)
+}
+
+// GdaoFetchOneWithContext implements IGenericDaoWithContext.GdaoFetchOneWithContext.
+func (dao *ShardedGenericDao) GdaoFetchOneWithContext(ctx context.Context, storageId string, filter FilterOpt) (IGenericBo, error) {
+	shard, err := dao.shardForFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+	if shard != nil {
+		return shard.GdaoFetchOneWithContext(ctx, storageId, filter)
+	}
+	results := make([]IGenericBo, len(dao.shards))
+	err = dao.scatter(func(i int, shard IGenericDaoWithContext) (err error) {
+		results[i], err = shard.GdaoFetchOneWithContext(ctx, storageId, filter)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, bo := range results {
+		if bo != nil {
+			return bo, nil
+		}
+	}
+	return nil, nil
+}
+
+// GdaoFetchMany implements IGenericDao.GdaoFetchMany.
+func (dao *ShardedGenericDao) GdaoFetchMany(storageId string, filter FilterOpt, sorting *SortingOpt, startOffset, numItems int) ([]IGenericBo, error) {
+	return dao.GdaoFetchManyWithContext(nil, storageId, filter, sorting, startOffset, numItems)
+}
+
+// GdaoFetchManyWithContext implements IGenericDaoWithContext.GdaoFetchManyWithContext.
+func (dao *ShardedGenericDao) GdaoFetchManyWithContext(ctx context.Context, storageId string, filter FilterOpt, sorting *SortingOpt, startOffset, numItems int) ([]IGenericBo, error) {
+	shard, err := dao.shardForFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+	if shard != nil {
+		return shard.GdaoFetchManyWithContext(ctx, storageId, filter, sorting, startOffset, numItems)
+	}
+	if startOffset < 0 {
+		startOffset = 0
+	}
+	// each shard returns its first (startOffset+numItems) items; offset and limit are applied after merging
+	shardNumItems := 0
+	if numItems > 0 {
+		shardNumItems = startOffset + numItems
+	}
+	lists := make([][]IGenericBo, len(dao.shards))
+	err = dao.scatter(func(i int, shard IGenericDaoWithContext) (err error) {
+		lists[i], err = shard.GdaoFetchManyWithContext(ctx, storageId, filter, sorting, 0, shardNumItems)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	result := mergeSorted(lists, sorting)
+	if startOffset >= len(result) {
+		return make([]IGenericBo, 0), nil
+	}
+	result = result[startOffset:]
+	if numItems > 0 && numItems < len(result) {
+		result = result[:numItems]
+	}
+	return result, nil
+}
+
+// GdaoCreate implements IGenericDao.GdaoCreate.
+func (dao *ShardedGenericDao) GdaoCreate(storageId string, bo IGenericBo) (int, error) {
+	return dao.GdaoCreateWithContext(nil, storageId, bo)
+}
+
+// GdaoCreateWithContext implements IGenericDaoWithContext.GdaoCreateWithContext.
+func (dao *ShardedGenericDao) GdaoCreateWithContext(ctx context.Context, storageId string, bo IGenericBo) (int, error) {
+	shard, err := dao.shardForBo(storageId, bo)
+	if err != nil {
+		return 0, err
+	}
+	return shard.GdaoCreateWithContext(ctx, storageId, bo)
+}
+
+// GdaoUpdate implements IGenericDao.GdaoUpdate.
+func (dao *ShardedGenericDao) GdaoUpdate(storageId string, bo IGenericBo) (int, error) {
+	return dao.GdaoUpdateWithContext(nil, storageId, bo)
+}
+
+// GdaoUpdateWithContext implements IGenericDaoWithContext.GdaoUpdateWithContext.
+func (dao *ShardedGenericDao) GdaoUpdateWithContext(ctx context.Context, storageId string, bo IGenericBo) (int, error) {
+	shard, err := dao.shardForBo(storageId, bo)
+	if err != nil {
+		return 0, err
+	}
+	return shard.GdaoUpdateWithContext(ctx, storageId, bo)
+}
+
+// GdaoSave implements IGenericDao.GdaoSave.
+func (dao *ShardedGenericDao) GdaoSave(storageId string, bo IGenericBo) (int, error) {
+	return dao.GdaoSaveWithContext(nil, storageId, bo)
+}
+
+// GdaoSaveWithContext implements IGenericDaoWithContext.GdaoSaveWithContext.
+func (dao *ShardedGenericDao) GdaoSaveWithContext(ctx context.Context, storageId string, bo IGenericBo) (int, error) {
+	shard, err := dao.shardForBo(storageId, bo)
+	if err != nil {
+		return 0, err
+	}
+	return shard.GdaoSaveWithContext(ctx, storageId, bo)
+}
@@ -0,0 +1,246 @@
+package godal
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/btnguyen2k/consu/reddo"
+)
+
+func TestCompareShardValues(t *testing.T) {
+	name := "TestCompareShardValues"
+	now := time.Now()
+	testCases := []struct {
+		a, b     interface{}
+		expected int
+	}{
+		{nil, nil, 0}, {nil, 1, -1}, {1, nil, 1},
+		{1, 2.5, -1}, {float64(10), int64(10), 0}, {uint(3), -1, 1},
+		{"10", "9", -1}, {"b", "a", 1},
+		{now, now.Add(time.Second), -1}, {now, now, 0},
+		{false, true, -1}, {true, true, 0}, {true, false, 1},
+	}
+	for _, tc := range testCases {
+		if c := compareShardValues(tc.a, tc.b); c != tc.expected {
+			t.Fatalf("%s failed: compare(%#v, %#v) expected %#v but received %#v", name, tc.a, tc.b, tc.expected, c)
+		}
+	}
+}
+
+func TestConsistentHashRouter(t *testing.T) {
+	name := "TestConsistentHashRouter"
+	if _, err := NewConsistentHashRouter(0, 10).Route("key"); err == nil {
+		t.Fatalf("%s failed: router without shard should return error", name)
+	}
+
+	r4 := NewConsistentHashRouter(4, 0)
+	counts := make([]int, 4)
+	numKeys := 10000
+	for i := 0; i < numKeys; i++ {
+		s, err := r4.Route(i)
+		if err != nil {
+			t.Fatalf("%s failed: %#v", name, err)
+		}
+		counts[s]++
+	}
+	for s, c := range counts {
+		if c < numKeys/8 {
+			t.Fatalf("%s failed: shard #%d receives too few keys: %#v", name, s, counts)
+		}
+	}
+
+	// integral float (e.g. parsed from JSON) is routed the same as the equivalent integer
+	s1, _ := r4.Route(12345)
+	s2, _ := r4.Route(float64(12345))
+	if s1 != s2 {
+		t.Fatalf("%s failed: 12345 and 12345.0 should be routed to the same shard", name)
+	}
+
+	// growing from 4 to 5 shards moves only a fraction of keys
+	r5 := NewConsistentHashRouter(5, 0)
+	moved := 0
+	for i := 0; i < numKeys; i++ {
+		s4, _ := r4.Route(i)
+		s5, _ := r5.Route(i)
+		if s4 != s5 {
+			moved++
+		}
+	}
+	if moved > numKeys/3 {
+		t.Fatalf("%s failed: too many keys moved: %d/%d", name, moved, numKeys)
+	}
+}
+
+func TestRangeShardRouter(t *testing.T) {
+	name := "TestRangeShardRouter"
+	r := NewRangeShardRouter(100, 200, 300)
+	testCases := map[interface{}]int{-1: 0, 99: 0, 100: 1, 199.5: 1, 200: 2, 300: 3, int64(1000): 3}
+	for key, expected := range testCases {
+		if s, err := r.Route(key); err != nil || s != expected {
+			t.Fatalf("%s failed: key %#v expected shard %#v but received %#v / %#v", name, key, expected, s, err)
+		}
+	}
+	if _, err := r.Route(nil); err != ErrGdaoShardKeyMissing {
+		t.Fatalf("%s failed: expected error %#v but received %#v", name, ErrGdaoShardKeyMissing, err)
+	}
+}
+
+func newShardedGenericDaoForTest(numShards int) (*ShardedGenericDao, []*mockGenericDao) {
+	mocks := make([]*mockGenericDao, numShards)
+	shards := make([]IGenericDao, numShards)
+	bounds := make([]interface{}, numShards-1)
+	for i := 0; i < numShards; i++ {
+		mocks[i] = newMockGenericDao()
+		shards[i] = mocks[i]
+		if i > 0 {
+			bounds[i-1] = i * 10
+		}
+	}
+	// shard #i holds values [i*10, (i+1)*10)
+	dao, _ := NewShardedGenericDao("value", NewRangeShardRouter(bounds...), shards...)
+	return dao, mocks
+}
+
+func TestNewShardedGenericDao_NoShard(t *testing.T) {
+	name := "TestNewShardedGenericDao_NoShard"
+	if dao, err := NewShardedGenericDao("value", NewRangeShardRouter()); dao != nil || err == nil {
+		t.Fatalf("%s failed: empty shard list should be rejected", name)
+	}
+}
+
+func TestShardedGenericDao_Writes(t *testing.T) {
+	name := "TestShardedGenericDao_Writes"
+	dao, mocks := newShardedGenericDaoForTest(3)
+	for i := 0; i < 30; i++ {
+		if numRows, err := dao.GdaoCreate("test", newMockBo(strconv.Itoa(i), i)); numRows != 1 || err != nil {
+			t.Fatalf("%s failed: %#v / %#v", name, numRows, err)
+		}
+	}
+	for i, mock := range mocks {
+		if c := mock.count("create"); c != 10 {
+			t.Fatalf("%s failed: expected %#v creates on shard #%d but received %#v", name, 10, i, c)
+		}
+	}
+
+	dao.GdaoUpdate("test", newMockBo("15", 15))
+	dao.GdaoSave("test", newMockBo("25", 25))
+	dao.GdaoDelete("test", newMockBo("5", 5))
+	for i, op := range []string{"delete", "update", "save"} {
+		if c := mocks[i].count(op); c != 1 {
+			t.Fatalf("%s failed: expected %#v %s on shard #%d but received %#v", name, 1, op, i, c)
+		}
+	}
+
+	if _, err := dao.GdaoCreate("test", newMockBo("x", nil)); err != ErrGdaoShardKeyMissing {
+		t.Fatalf("%s failed: expected error %#v but received %#v", name, ErrGdaoShardKeyMissing, err)
+	}
+}
+
+func TestShardedGenericDao_ShardKeyFunc(t *testing.T) {
+	name := "TestShardedGenericDao_ShardKeyFunc"
+	dao, mocks := newShardedGenericDaoForTest(3)
+	dao.SetShardKeyFunc(func(storageId string, bo IGenericBo) (interface{}, error) {
+		return bo.GboGetAttr("id", reddo.TypeInt)
+	})
+	dao.GdaoCreate("test", newMockBo("25", 1))
+	if c := mocks[2].count("create"); c != 1 {
+		t.Fatalf("%s failed: expected %#v create on shard #%d but received %#v", name, 1, 2, c)
+	}
+
+	// the filter's shard-key condition (value=1) routes to shard #0, hence reads and deletes must be scattered
+	filter := (&FilterOptAnd{}).
+		Add(&FilterOptFieldOpValue{FieldName: "id", Operator: FilterOpEqual, Value: "25"}).
+		Add(&FilterOptFieldOpValue{FieldName: "value", Operator: FilterOpEqual, Value: 1})
+	if bo, err := dao.GdaoFetchOne("test", filter); bo == nil || err != nil {
+		t.Fatalf("%s failed: %#v / %#v", name, bo, err)
+	}
+	if boList, err := dao.GdaoFetchMany("test", filter, nil, 0, 0); len(boList) != 1 || err != nil {
+		t.Fatalf("%s failed: %#v / %#v", name, boList, err)
+	}
+	if numRows, err := dao.GdaoDeleteMany("test", filter); numRows != 1 || err != nil {
+		t.Fatalf("%s failed: %#v / %#v", name, numRows, err)
+	}
+
+	errKey := errors.New("cannot extract shard key")
+	dao.SetShardKeyFunc(func(storageId string, bo IGenericBo) (interface{}, error) {
+		return nil, errKey
+	})
+	if _, err := dao.GdaoSave("test", newMockBo("1", 1)); err != errKey {
+		t.Fatalf("%s failed: expected error %#v but received %#v", name, errKey, err)
+	}
+}
+
+func TestShardedGenericDao_RouteByFilter(t *testing.T) {
+	name := "TestShardedGenericDao_RouteByFilter"
+	dao, mocks := newShardedGenericDaoForTest(3)
+	for i := 0; i < 30; i++ {
+		dao.GdaoCreate("test", newMockBo(strconv.Itoa(i), i))
+	}
+
+	filter := (&FilterOptAnd{}).Add(&FilterOptFieldOpValue{FieldName: "id", Operator: FilterOpEqual, Value: "15"}).
+		Add(FilterOptFieldOpValue{FieldName: "value", Operator: FilterOpEqual, Value: 15})
+	if bo, err := dao.GdaoFetchOne("test", filter); bo == nil || err != nil {
+		t.Fatalf("%s failed: %#v / %#v", name, bo, err)
+	}
+	dao.GdaoFetchMany("test", filter, nil, 0, 0)
+	if c := mocks[1].count("fetchOne") + mocks[1].count("fetchMany"); c != 2 {
+		t.Fatalf("%s failed: expected %#v reads on shard #1 but received %#v", name, 2, c)
+	}
+	if numRows, err := dao.GdaoDeleteMany("test", filter); numRows != 1 || err != nil {
+		t.Fatalf("%s failed: %#v / %#v", name, numRows, err)
+	}
+	for _, i := range []int{0, 2} {
+		if c := mocks[i].count("fetchOne") + mocks[i].count("fetchMany") + mocks[i].count("deleteMany"); c != 0 {
+			t.Fatalf("%s failed: expected no call on shard #%d but received %#v", name, i, c)
+		}
+	}
+}
+
+func TestShardedGenericDao_ScatterGather(t *testing.T) {
+	name := "TestShardedGenericDao_ScatterGather"
+	dao, mocks := newShardedGenericDaoForTest(3)
+	for i := 0; i < 30; i++ {
+		dao.GdaoCreate("test", newMockBo(strconv.Itoa(i), i))
+	}
+
+	// not routable: value > 5
+	filter := &FilterOptFieldOpValue{FieldName: "value", Operator: FilterOpGreater, Value: 5}
+	sorting := (&SortingField{FieldName: "value", Descending: true}).ToSortingOpt()
+	boList, err := dao.GdaoFetchMany("test", filter, sorting, 3, 5)
+	if err != nil || len(boList) != 5 {
+		t.Fatalf("%s failed: %#v / %#v", name, boList, err)
+	}
+	for i, bo := range boList {
+		expected := int64(26 - i)
+		if v := bo.GboGetAttrUnsafe("value", reddo.TypeInt); v != expected {
+			t.Fatalf("%s failed: expected item #%d to be %#v but received %#v", name, i, expected, v)
+		}
+	}
+	for i, mock := range mocks {
+		if c := mock.count("fetchMany"); c != 1 {
+			t.Fatalf("%s failed: expected %#v fetch on shard #%d but received %#v", name, 1, i, c)
+		}
+	}
+
+	if boList, _ := dao.GdaoFetchMany("test", filter, sorting, 100, 5); len(boList) != 0 {
+		t.Fatalf("%s failed: expected empty result but received %#v", name, boList)
+	}
+	if boList, _ := dao.GdaoFetchMany("test", nil, sorting, 0, 0); len(boList) != 30 {
+		t.Fatalf("%s failed: expected %#v items but received %#v", name, 30, len(boList))
+	}
+
+	if bo, err := dao.GdaoFetchOne("test", dao.GdaoCreateFilter("test", newMockBo("22", nil))); bo == nil || err != nil {
+		t.Fatalf("%s failed: %#v / %#v", name, bo, err)
+	}
+
+	if numRows, err := dao.GdaoDeleteMany("test", filter); numRows != 24 || err != nil {
+		t.Fatalf("%s failed: %#v / %#v", name, numRows, err)
+	}
+
+	mocks[1].errToThrow = errors.New("shard down")
+	if _, err := dao.GdaoFetchMany("test", nil, nil, 0, 0); err != mocks[1].errToThrow {
+		t.Fatalf("%s failed: expected error %#v but received %#v", name, mocks[1].errToThrow, err)
+	}
+}
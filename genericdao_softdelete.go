@@ -0,0 +1,334 @@
+package godal
+
+import (
+	"context"
+	"reflect"
+	"time"
+)
+
+// DefaultDeletedAtField is the default name of the field that marks soft-deleted BOs.
+//
+// Available since v0.7.0
+const DefaultDeletedAtField = "deleted_at"
+
+// NewSoftDeleteGenericDao constructs a new SoftDeleteGenericDao that wraps the specified DAO.
+// If deletedAtField is empty, DefaultDeletedAtField is used.
+//
+// Default settings: deletion time is stored as Unix time in milliseconds, active BOs have the deleted-at field set to null.
+//
+// Available since v0.7.0
+func NewSoftDeleteGenericDao(dao IGenericDao, deletedAtField string) *SoftDeleteGenericDao {
+	if deletedAtField == "" {
+		deletedAtField = DefaultDeletedAtField
+	}
+	return &SoftDeleteGenericDao{
+		origDao:              dao,
+		dao:                  ToGenericDaoWithContext(dao),
+		deletedAtField:       deletedAtField,
+		deletedAtFilterField: deletedAtField,
+		timeValueFunc:        func(t time.Time) interface{} { return t.UnixMilli() },
+		funcNow:              time.Now,
+	}
+}
+
+// SoftDeleteGenericDao is an IGenericDao wrapper that marks BOs as deleted instead of removing them.
+//
+//   - GdaoDelete and GdaoDeleteMany set the deleted-at field of matched BOs to the deletion time.
+//   - GdaoFetchMany and GdaoFetchOne add FilterOptFieldIsNull on the deleted-at field to the filter. With
+//     SetKeyOnlyFetchOne(true), GdaoFetchOne passes the filter as-is instead (some backends, such as DynamoDB, accept
+//     only key filters for this operation); in both cases it returns nil if the fetched BO is soft-deleted.
+//   - GdaoCreate and GdaoSave mark the BO as active. GdaoSave on a soft-deleted BO replaces and restores it.
+//   - GdaoUpdate does not touch soft-deleted BOs (returns 0).
+//   - GdaoFetchManyIncludingDeleted, GdaoRestore and GdaoPurge are additional operations.
+//
+// Representation of the deleted-at field must be chosen per backend:
+//   - GenericDaoSql: column of type BIGINT (default time value), or DATETIME/TIMESTAMP with
+//     SetTimeValueFunc(func(t time.Time) interface{} { return t }).
+//   - GenericDaoMongo and GenericDaoCosmosdb: defaults work as-is.
+//   - GenericDaoDynamodb: SetRemoveWhenActive(true), because FilterOptFieldIsNull is translated to "attribute_not_exists",
+//     and SetKeyOnlyFetchOne(true), because GdaoFetchOne reads items by key.
+//
+// GdaoDelete, GdaoDeleteMany, GdaoUpdate and GdaoRestore read the existing BOs before writing them back; the read and
+// the write are two separate calls to the underlying DAO.
+//
+// Available since v0.7.0
+type SoftDeleteGenericDao struct {
+	origDao              IGenericDao
+	dao                  IGenericDaoWithContext
+	deletedAtField       string
+	deletedAtFilterField string
+	removeWhenActive     bool
+	keyOnlyFetchOne      bool
+	timeValueFunc        func(t time.Time) interface{}
+	funcNow              func() time.Time
+}
+
+// GetDao returns the underlying DAO.
+func (dao *SoftDeleteGenericDao) GetDao() IGenericDao {
+	return dao.origDao
+}
+
+// GetDeletedAtField returns name of the BO field that marks soft-deleted BOs.
+func (dao *SoftDeleteGenericDao) GetDeletedAtField() string {
+	return dao.deletedAtField
+}
+
+// GetDeletedAtFilterField returns name of the deleted-at field used in filters.
+func (dao *SoftDeleteGenericDao) GetDeletedAtFilterField() string {
+	return dao.deletedAtFilterField
+}
+
+// SetDeletedAtFilterField sets name of the deleted-at field used in filters, if it differs from the BO field name
+// (e.g. BO attribute "deletedAt" is stored in column "deleted_at").
+func (dao *SoftDeleteGenericDao) SetDeletedAtFilterField(field string) *SoftDeleteGenericDao {
+	dao.deletedAtFilterField = field
+	return dao
+}
+
+// GetRemoveWhenActive returns 'true' if active BOs do not have the deleted-at field, 'false' if the field is set to null.
+func (dao *SoftDeleteGenericDao) GetRemoveWhenActive() bool {
+	return dao.removeWhenActive
+}
+
+// SetRemoveWhenActive specifies if active BOs do not have the deleted-at field ('true', required by DynamoDB) or have the
+// field set to null ('false', default).
+func (dao *SoftDeleteGenericDao) SetRemoveWhenActive(enabled bool) *SoftDeleteGenericDao {
+	dao.removeWhenActive = enabled
+	return dao
+}
+
+// GetKeyOnlyFetchOne returns 'true' if GdaoFetchOne passes the filter as-is to the underlying DAO, 'false' if the filter
+// is combined with the deleted-at condition.
+func (dao *SoftDeleteGenericDao) GetKeyOnlyFetchOne() bool {
+	return dao.keyOnlyFetchOne
+}
+
+// SetKeyOnlyFetchOne specifies if GdaoFetchOne passes the filter as-is to the underlying DAO ('true', required by
+// DynamoDB whose GdaoFetchOne accepts only key attributes) or combines it with the deleted-at condition ('false',
+// default). Either way, soft-deleted BOs are not returned; but with 'true', GdaoFetchOne with a non-key filter may
+// return nil if a soft-deleted BO is fetched while an active one also matches.
+func (dao *SoftDeleteGenericDao) SetKeyOnlyFetchOne(enabled bool) *SoftDeleteGenericDao {
+	dao.keyOnlyFetchOne = enabled
+	return dao
+}
+
+// SetTimeValueFunc sets the function that converts deletion time to the value stored in the deleted-at field.
+// Values must be comparable by the database store so that GdaoPurge can find old BOs. Default: Unix time in milliseconds.
+func (dao *SoftDeleteGenericDao) SetTimeValueFunc(f func(t time.Time) interface{}) *SoftDeleteGenericDao {
+	dao.timeValueFunc = f
+	return dao
+}
+
+// IsDeleted returns 'true' if the BO is soft-deleted.
+func (dao *SoftDeleteGenericDao) IsDeleted(bo IGenericBo) bool {
+	return bo != nil && bo.GboGetAttrUnsafe(dao.deletedAtField, nil) != nil
+}
+
+// markActive clears the deleted-at field of the BO.
+func (dao *SoftDeleteGenericDao) markActive(bo IGenericBo) error {
+	if !dao.removeWhenActive {
+		return bo.GboSetAttr(dao.deletedAtField, nil)
+	}
+	isMap := false
+	data := make(map[string]interface{})
+	bo.GboIterate(func(kind reflect.Kind, field interface{}, value interface{}) {
+		isMap = kind == reflect.Map
+		if k, ok := field.(string); ok && k != dao.deletedAtField {
+			data[k] = value
+		}
+	})
+	if !isMap {
+		return nil
+	}
+	return bo.GboImportViaMap(data)
+}
+
+// activeFilter combines the filter with condition "deleted-at IS NULL".
+func (dao *SoftDeleteGenericDao) activeFilter(filter FilterOpt) FilterOpt {
+	return (&FilterOptAnd{}).Add(filter).Add(&FilterOptFieldIsNull{FieldName: dao.deletedAtFilterField})
+}
+
+// fetchExisting fetches the existing BO with the same key as the input BO.
+func (dao *SoftDeleteGenericDao) fetchExisting(ctx context.Context, storageId string, bo IGenericBo) (IGenericBo, error) {
+	return dao.dao.GdaoFetchOneWithContext(ctx, storageId, dao.dao.GdaoCreateFilter(storageId, bo))
+}
+
+// softDelete marks an existing BO as deleted and writes it back.
+func (dao *SoftDeleteGenericDao) softDelete(ctx context.Context, storageId string, existing IGenericBo, now time.Time) (int, error) {
+	if err := existing.GboSetAttr(dao.deletedAtField, dao.timeValueFunc(now)); err != nil {
+		return 0, err
+	}
+	return dao.dao.GdaoUpdateWithContext(ctx, storageId, existing)
+}
+
+// GdaoCreateFilter implements IGenericDao.GdaoCreateFilter.
+func (dao *SoftDeleteGenericDao) GdaoCreateFilter(storageId string, bo IGenericBo) FilterOpt {
+	return dao.dao.GdaoCreateFilter(storageId, bo)
+}
+
+// GetRowMapper implements IGenericDao.GetRowMapper.
+func (dao *SoftDeleteGenericDao) GetRowMapper() IRowMapper {
+	return dao.dao.GetRowMapper()
+}
+
+// GdaoDelete implements IGenericDao.GdaoDelete.
+func (dao *SoftDeleteGenericDao) GdaoDelete(storageId string, bo IGenericBo) (int, error) {
+	return dao.GdaoDeleteWithContext(nil, storageId, bo)
+}
+
+// GdaoDeleteWithContext implements IGenericDaoWithContext.GdaoDeleteWithContext.
+//
+// The BO is marked as deleted instead of being removed. Deleting a non-existing or already-deleted BO returns 0.
+func (dao *SoftDeleteGenericDao) GdaoDeleteWithContext(ctx context.Context, storageId string, bo IGenericBo) (int, error) {
+	existing, err := dao.fetchExisting(ctx, storageId, bo)
+	if err != nil || existing == nil || dao.IsDeleted(existing) {
+		return 0, err
+	}
+	return dao.softDelete(ctx, storageId, existing, dao.funcNow())
+}
+
+// GdaoDeleteMany implements IGenericDao.GdaoDeleteMany.
+func (dao *SoftDeleteGenericDao) GdaoDeleteMany(storageId string, filter FilterOpt) (int, error) {
+	return dao.GdaoDeleteManyWithContext(nil, storageId, filter)
+}
+
+// GdaoDeleteManyWithContext implements IGenericDaoWithContext.GdaoDeleteManyWithContext.
+//
+// Matched active BOs are marked as deleted instead of being removed.
+func (dao *SoftDeleteGenericDao) GdaoDeleteManyWithContext(ctx context.Context, storageId string, filter FilterOpt) (int, error) {
+	boList, err := dao.dao.GdaoFetchManyWithContext(ctx, storageId, dao.activeFilter(filter), nil, 0, 0)
+	if err != nil {
+		return 0, err
+	}
+	now := dao.funcNow()
+	count := 0
+	for _, bo := range boList {
+		numRows, err := dao.softDelete(ctx, storageId, bo, now)
+		count += numRows
+		if err != nil {
+			return count, err
+		}
+	}
+	return count, nil
+}
+
+// GdaoFetchOne implements IGenericDao.GdaoFetchOne.
+func (dao *SoftDeleteGenericDao) GdaoFetchOne(storageId string, filter FilterOpt) (IGenericBo, error) {
+	return dao.GdaoFetchOneWithContext(nil, storageId, filter)
+}
+
+// GdaoFetchOneWithContext implements IGenericDaoWithContext.GdaoFetchOneWithContext.
+//
+// Soft-deleted BOs are excluded, see SetKeyOnlyFetchOne.
+func (dao *SoftDeleteGenericDao) GdaoFetchOneWithContext(ctx context.Context, storageId string, filter FilterOpt) (IGenericBo, error) {
+	if !dao.keyOnlyFetchOne {
+		filter = dao.activeFilter(filter)
+	}
+	bo, err := dao.dao.GdaoFetchOneWithContext(ctx, storageId, filter)
+	if err != nil || dao.IsDeleted(bo) {
+		return nil, err
+	}
+	return bo, nil
+}
+
+// GdaoFetchMany implements IGenericDao.GdaoFetchMany.
+func (dao *SoftDeleteGenericDao) GdaoFetchMany(storageId string, filter FilterOpt, sorting *SortingOpt, startOffset, numItems int) ([]IGenericBo, error) {
+	return dao.GdaoFetchManyWithContext(nil, storageId, filter, sorting, startOffset, numItems)
+}
+
+// GdaoFetchManyWithContext implements IGenericDaoWithContext.GdaoFetchManyWithContext.
+//
+// Soft-deleted BOs are excluded.
+func (dao *SoftDeleteGenericDao) GdaoFetchManyWithContext(ctx context.Context, storageId string, filter FilterOpt, sorting *SortingOpt, startOffset, numItems int) ([]IGenericBo, error) {
+	return dao.dao.GdaoFetchManyWithContext(ctx, storageId, dao.activeFilter(filter), sorting, startOffset, numItems)
+}
+
+// GdaoFetchManyIncludingDeleted is similar to GdaoFetchMany, but soft-deleted BOs are included.
+func (dao *SoftDeleteGenericDao) GdaoFetchManyIncludingDeleted(storageId string, filter FilterOpt, sorting *SortingOpt, startOffset, numItems int) ([]IGenericBo, error) {
+	return dao.GdaoFetchManyIncludingDeletedWithContext(nil, storageId, filter, sorting, startOffset, numItems)
+}
+
+// GdaoFetchManyIncludingDeletedWithContext is context-aware variant of GdaoFetchManyIncludingDeleted.
+func (dao *SoftDeleteGenericDao) GdaoFetchManyIncludingDeletedWithContext(ctx context.Context, storageId string, filter FilterOpt, sorting *SortingOpt, startOffset, numItems int) ([]IGenericBo, error) {
+	return dao.dao.GdaoFetchManyWithContext(ctx, storageId, filter, sorting, startOffset, numItems)
+}
+
+// GdaoCreate implements IGenericDao.GdaoCreate.
+func (dao *SoftDeleteGenericDao) GdaoCreate(storageId string, bo IGenericBo) (int, error) {
+	return dao.GdaoCreateWithContext(nil, storageId, bo)
+}
+
+// GdaoCreateWithContext implements IGenericDaoWithContext.GdaoCreateWithContext.
+//
+// Creating a BO whose key belongs to a soft-deleted BO returns ErrGdaoDuplicatedEntry (use GdaoRestore or GdaoSave instead).
+func (dao *SoftDeleteGenericDao) GdaoCreateWithContext(ctx context.Context, storageId string, bo IGenericBo) (int, error) {
+	if err := dao.markActive(bo); err != nil {
+		return 0, err
+	}
+	return dao.dao.GdaoCreateWithContext(ctx, storageId, bo)
+}
+
+// GdaoUpdate implements IGenericDao.GdaoUpdate.
+func (dao *SoftDeleteGenericDao) GdaoUpdate(storageId string, bo IGenericBo) (int, error) {
+	return dao.GdaoUpdateWithContext(nil, storageId, bo)
+}
+
+// GdaoUpdateWithContext implements IGenericDaoWithContext.GdaoUpdateWithContext.
+//
+// Soft-deleted BOs are treated as non-existing: (0, nil) is returned.
+func (dao *SoftDeleteGenericDao) GdaoUpdateWithContext(ctx context.Context, storageId string, bo IGenericBo) (int, error) {
+	existing, err := dao.fetchExisting(ctx, storageId, bo)
+	if err != nil || existing == nil || dao.IsDeleted(existing) {
+		return 0, err
+	}
+	if err := dao.markActive(bo); err != nil {
+		return 0, err
+	}
+	return dao.dao.GdaoUpdateWithContext(ctx, storageId, bo)
+}
+
+// GdaoSave implements IGenericDao.GdaoSave.
+func (dao *SoftDeleteGenericDao) GdaoSave(storageId string, bo IGenericBo) (int, error) {
+	return dao.GdaoSaveWithContext(nil, storageId, bo)
+}
+
+// GdaoSaveWithContext implements IGenericDaoWithContext.GdaoSaveWithContext.
+//
+// If the BO is soft-deleted, it is replaced and restored.
+func (dao *SoftDeleteGenericDao) GdaoSaveWithContext(ctx context.Context, storageId string, bo IGenericBo) (int, error) {
+	if err := dao.markActive(bo); err != nil {
+		return 0, err
+	}
+	return dao.dao.GdaoSaveWithContext(ctx, storageId, bo)
+}
+
+// GdaoRestore restores a soft-deleted BO and returns the number of restored items.
+// Restoring a non-existing or active BO returns 0.
+func (dao *SoftDeleteGenericDao) GdaoRestore(storageId string, bo IGenericBo) (int, error) {
+	return dao.GdaoRestoreWithContext(nil, storageId, bo)
+}
+
+// GdaoRestoreWithContext is context-aware variant of GdaoRestore.
+func (dao *SoftDeleteGenericDao) GdaoRestoreWithContext(ctx context.Context, storageId string, bo IGenericBo) (int, error) {
+	existing, err := dao.fetchExisting(ctx, storageId, bo)
+	if err != nil || existing == nil || !dao.IsDeleted(existing) {
+		return 0, err
+	}
+	if err := dao.markActive(existing); err != nil {
+		return 0, err
+	}
+	return dao.dao.GdaoUpdateWithContext(ctx, storageId, existing)
+}
+
+// GdaoPurge permanently removes BOs that were soft-deleted more than olderThan ago, and returns the number of removed items.
+func (dao *SoftDeleteGenericDao) GdaoPurge(storageId string, olderThan time.Duration) (int, error) {
+	return dao.GdaoPurgeWithContext(nil, storageId, olderThan)
+}
+
+// GdaoPurgeWithContext is context-aware variant of GdaoPurge.
+func (dao *SoftDeleteGenericDao) GdaoPurgeWithContext(ctx context.Context, storageId string, olderThan time.Duration) (int, error) {
+	filter := (&FilterOptAnd{}).
+		Add(&FilterOptFieldIsNotNull{FieldName: dao.deletedAtFilterField}).
+		Add(&FilterOptFieldOpValue{FieldName: dao.deletedAtFilterField, Operator: FilterOpLess, Value: dao.timeValueFunc(dao.funcNow().Add(-olderThan))})
+	return dao.dao.GdaoDeleteManyWithContext(ctx, storageId, filter)
+}
@@ -0,0 +1,191 @@
+package godal
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/btnguyen2k/consu/reddo"
+)
+
+func newSoftDeleteGenericDaoForTest() (*SoftDeleteGenericDao, *mockGenericDao, *time.Time) {
+	mock := newMockGenericDao()
+	now := time.Now()
+	dao := NewSoftDeleteGenericDao(mock, "")
+	dao.funcNow = func() time.Time { return now }
+	for i := 0; i < 5; i++ {
+		dao.GdaoCreate("test", newMockBo(strconv.Itoa(i), i))
+	}
+	return dao, mock, &now
+}
+
+func TestSoftDeleteGenericDao_GdaoDelete(t *testing.T) {
+	name := "TestSoftDeleteGenericDao_GdaoDelete"
+	dao, mock, now := newSoftDeleteGenericDaoForTest()
+	if dao.GetDeletedAtField() != DefaultDeletedAtField {
+		t.Fatalf("%s failed: expected field %#v but received %#v", name, DefaultDeletedAtField, dao.GetDeletedAtField())
+	}
+
+	if numRows, err := dao.GdaoDelete("test", newMockBo("1", nil)); numRows != 1 || err != nil {
+		t.Fatalf("%s failed: %#v / %#v", name, numRows, err)
+	}
+	if numRows, err := dao.GdaoDelete("test", newMockBo("1", nil)); numRows != 0 || err != nil {
+		t.Fatalf("%s failed: deleting an already-deleted BO should return 0: %#v / %#v", name, numRows, err)
+	}
+	if c := mock.count("delete"); c != 0 {
+		t.Fatalf("%s failed: underlying GdaoDelete should not be called", name)
+	}
+
+	filter := dao.GdaoCreateFilter("test", newMockBo("1", nil))
+	raw, _ := mock.GdaoFetchOne("test", filter)
+	if v := raw.GboGetAttrUnsafe(DefaultDeletedAtField, reddo.TypeInt); v != now.UnixMilli() {
+		t.Fatalf("%s failed: expected deleted-at %#v but received %#v", name, now.UnixMilli(), v)
+	}
+	if bo, err := dao.GdaoFetchOne("test", filter); bo != nil || err != nil {
+		t.Fatalf("%s failed: soft-deleted BO should not be returned: %#v / %#v", name, bo, err)
+	}
+	if boList, _ := dao.GdaoFetchMany("test", nil, nil, 0, 0); len(boList) != 4 {
+		t.Fatalf("%s failed: expected %#v BOs but received %#v", name, 4, len(boList))
+	}
+	if boList, _ := dao.GdaoFetchManyIncludingDeleted("test", nil, nil, 0, 0); len(boList) != 5 {
+		t.Fatalf("%s failed: expected %#v BOs but received %#v", name, 5, len(boList))
+	}
+
+	if numRows, err := dao.GdaoUpdate("test", newMockBo("1", 100)); numRows != 0 || err != nil {
+		t.Fatalf("%s failed: updating a soft-deleted BO should return 0: %#v / %#v", name, numRows, err)
+	}
+	if numRows, err := dao.GdaoUpdate("test", newMockBo("2", 100)); numRows != 1 || err != nil {
+		t.Fatalf("%s failed: %#v / %#v", name, numRows, err)
+	}
+	if _, err := dao.GdaoCreate("test", newMockBo("1", 100)); err != ErrGdaoDuplicatedEntry {
+		t.Fatalf("%s failed: expected error %#v but received %#v", name, ErrGdaoDuplicatedEntry, err)
+	}
+}
+
+func TestSoftDeleteGenericDao_GdaoDeleteMany(t *testing.T) {
+	name := "TestSoftDeleteGenericDao_GdaoDeleteMany"
+	dao, mock, _ := newSoftDeleteGenericDaoForTest()
+	filter := &FilterOptFieldOpValue{FieldName: "value", Operator: FilterOpGreaterOrEqual, Value: 2}
+	if numRows, err := dao.GdaoDeleteMany("test", filter); numRows != 3 || err != nil {
+		t.Fatalf("%s failed: %#v / %#v", name, numRows, err)
+	}
+	if numRows, err := dao.GdaoDeleteMany("test", filter); numRows != 0 || err != nil {
+		t.Fatalf("%s failed: %#v / %#v", name, numRows, err)
+	}
+	if c := mock.count("deleteMany"); c != 0 {
+		t.Fatalf("%s failed: underlying GdaoDeleteMany should not be called", name)
+	}
+	if boList, _ := dao.GdaoFetchMany("test", nil, nil, 0, 0); len(boList) != 2 {
+		t.Fatalf("%s failed: expected %#v BOs but received %#v", name, 2, len(boList))
+	}
+}
+
+func TestSoftDeleteGenericDao_GdaoFetchOne(t *testing.T) {
+	name := "TestSoftDeleteGenericDao_GdaoFetchOne"
+	dao, _, _ := newSoftDeleteGenericDaoForTest()
+	for i := 0; i < 4; i++ {
+		dao.GdaoDelete("test", newMockBo(strconv.Itoa(i), nil))
+	}
+	// soft-deleted BOs match the filter too, but must not hide the active one
+	for i := 0; i < 10; i++ {
+		bo, err := dao.GdaoFetchOne("test", nil)
+		if bo == nil || err != nil || bo.GboGetAttrUnsafe("id", reddo.TypeString) != "4" {
+			t.Fatalf("%s failed: %#v / %#v", name, bo, err)
+		}
+	}
+}
+
+func TestSoftDeleteGenericDao_GdaoRestore(t *testing.T) {
+	name := "TestSoftDeleteGenericDao_GdaoRestore"
+	dao, _, _ := newSoftDeleteGenericDaoForTest()
+	dao.GdaoDelete("test", newMockBo("1", nil))
+	if numRows, err := dao.GdaoRestore("test", newMockBo("1", nil)); numRows != 1 || err != nil {
+		t.Fatalf("%s failed: %#v / %#v", name, numRows, err)
+	}
+	bo, err := dao.GdaoFetchOne("test", dao.GdaoCreateFilter("test", newMockBo("1", nil)))
+	if bo == nil || err != nil || bo.GboGetAttrUnsafe("value", reddo.TypeInt) != int64(1) {
+		t.Fatalf("%s failed: %#v / %#v", name, bo, err)
+	}
+	if numRows, err := dao.GdaoRestore("test", newMockBo("1", nil)); numRows != 0 || err != nil {
+		t.Fatalf("%s failed: restoring an active BO should return 0: %#v / %#v", name, numRows, err)
+	}
+	if numRows, err := dao.GdaoRestore("test", newMockBo("100", nil)); numRows != 0 || err != nil {
+		t.Fatalf("%s failed: restoring a non-existing BO should return 0: %#v / %#v", name, numRows, err)
+	}
+
+	// GdaoSave restores a soft-deleted BO
+	dao.GdaoDelete("test", newMockBo("2", nil))
+	dao.GdaoSave("test", newMockBo("2", 200))
+	if bo, _ := dao.GdaoFetchOne("test", dao.GdaoCreateFilter("test", newMockBo("2", nil))); bo == nil {
+		t.Fatalf("%s failed: BO should have been restored by GdaoSave", name)
+	}
+}
+
+func TestSoftDeleteGenericDao_GdaoPurge(t *testing.T) {
+	name := "TestSoftDeleteGenericDao_GdaoPurge"
+	dao, mock, now := newSoftDeleteGenericDaoForTest()
+	dao.GdaoDelete("test", newMockBo("1", nil))
+	*now = now.Add(10 * 24 * time.Hour)
+	dao.GdaoDelete("test", newMockBo("2", nil))
+	*now = now.Add(25 * 24 * time.Hour)
+
+	if numRows, err := dao.GdaoPurge("test", 30*24*time.Hour); numRows != 1 || err != nil {
+		t.Fatalf("%s failed: %#v / %#v", name, numRows, err)
+	}
+	boList, _ := mock.GdaoFetchMany("test", nil, nil, 0, 0)
+	if len(boList) != 4 {
+		t.Fatalf("%s failed: expected %#v BOs but received %#v", name, 4, len(boList))
+	}
+	for _, bo := range boList {
+		if bo.GboGetAttrUnsafe("id", reddo.TypeString) == "1" {
+			t.Fatalf("%s failed: BO 1 should have been purged", name)
+		}
+	}
+}
+
+func TestSoftDeleteGenericDao_RemoveWhenActive(t *testing.T) {
+	name := "TestSoftDeleteGenericDao_RemoveWhenActive"
+	mock := newMockGenericDao()
+	dao := NewSoftDeleteGenericDao(mock, "deletedAt").SetRemoveWhenActive(true)
+	bo := newMockBo("1", 1)
+	bo.GboSetAttr("deletedAt", nil)
+	dao.GdaoCreate("test", bo)
+	if js := string(bo.GboToJsonUnsafe()); js != `{"id":"1","value":1}` {
+		t.Fatalf("%s failed: deleted-at field should have been removed: %s", name, js)
+	}
+
+	dao.GdaoDelete("test", bo)
+	dao.GdaoRestore("test", bo)
+	raw, _ := mock.GdaoFetchOne("test", dao.GdaoCreateFilter("test", bo))
+	if js := string(raw.GboToJsonUnsafe()); js != `{"id":"1","value":1}` {
+		t.Fatalf("%s failed: deleted-at field should have been removed: %s", name, js)
+	}
+}
+
+func TestSoftDeleteGenericDao_TimeValueFunc(t *testing.T) {
+	name := "TestSoftDeleteGenericDao_TimeValueFunc"
+	dao, mock, now := newSoftDeleteGenericDaoForTest()
+	layout := "2006-01-02T15:04:05.000Z"
+	dao.SetTimeValueFunc(func(t time.Time) interface{} { return t.UTC().Format(layout) })
+	dao.GdaoDelete("test", newMockBo("1", nil))
+	raw, _ := mock.GdaoFetchOne("test", dao.GdaoCreateFilter("test", newMockBo("1", nil)))
+	if v := raw.GboGetAttrUnsafe(DefaultDeletedAtField, nil); v != now.UTC().Format(layout) {
+		t.Fatalf("%s failed: expected %#v but received %#v", name, now.UTC().Format(layout), v)
+	}
+}
+
+func TestSoftDeleteGenericDao_GdaoFetchOne_KeyOnly(t *testing.T) {
+	name := "TestSoftDeleteGenericDao_GdaoFetchOne_KeyOnly"
+	dao, _, _ := newSoftDeleteGenericDaoForTest()
+	if dao.GetKeyOnlyFetchOne() {
+		t.Fatalf("%s failed: key-only fetch should be disabled by default", name)
+	}
+	dao.SetKeyOnlyFetchOne(true)
+	dao.GdaoDelete("test", newMockBo("1", nil))
+	if bo, err := dao.GdaoFetchOne("test", dao.GdaoCreateFilter("test", newMockBo("1", nil))); bo != nil || err != nil {
+		t.Fatalf("%s failed: soft-deleted BO should not be returned: %#v / %#v", name, bo, err)
+	}
+	if bo, err := dao.GdaoFetchOne("test", dao.GdaoCreateFilter("test", newMockBo("2", nil))); bo == nil || err != nil {
+		t.Fatalf("%s failed: active BO should be returned: %#v / %#v", name, bo, err)
+	}
+}
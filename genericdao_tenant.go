@@ -0,0 +1,414 @@
+package godal
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+
+	"github.com/btnguyen2k/consu/reddo"
+)
+
+type ctxKeyTenantId struct{}
+
+// WithTenantId returns a copy of the parent context that carries the tenant id.
+//
+// Available since v0.7.0
+func WithTenantId(ctx context.Context, tenantId string) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithValue(ctx, ctxKeyTenantId{}, tenantId)
+}
+
+// TenantIdFromContext returns the tenant id carried by the context.
+//
+// Available since v0.7.0
+func TenantIdFromContext(ctx context.Context) (string, bool) {
+	if ctx == nil {
+		return "", false
+	}
+	tenantId, ok := ctx.Value(ctxKeyTenantId{}).(string)
+	return tenantId, ok && tenantId != ""
+}
+
+// CrossTenantError is returned when an operation of one tenant touches a BO that belongs to another tenant.
+//
+// Available since v0.7.0
+type CrossTenantError struct {
+	StorageId     string // storage being accessed
+	TenantId      string // tenant carried by the context
+	OtherTenantId string // tenant the BO belongs to
+}
+
+// Error implements error.Error.
+func (e *CrossTenantError) Error() string {
+	return fmt.Sprintf("cross-tenant access: tenant %q attempted to access data of tenant %q in storage %q", e.TenantId, e.OtherTenantId, e.StorageId)
+}
+
+// TenancyStrategy specifies how MultiTenantGenericDao isolates tenants.
+//
+// Available since v0.7.0
+type TenancyStrategy int
+
+const (
+	// TenancyField stores all tenants in the same storages, BOs are tagged with a tenant field.
+	TenancyField TenancyStrategy = iota
+
+	// TenancyStorage stores each tenant in its own storages (e.g. a table or collection per tenant).
+	TenancyStorage
+
+	// TenancyDao stores each tenant in its own database, accessed via a per-tenant DAO.
+	TenancyDao
+)
+
+// TenantStorageIdFunc maps a storage-id to the tenant's own storage-id. MultiTenantGenericDao validates tenant ids
+// (see IsValidTenantId) before calling this function.
+//
+// Available since v0.7.0
+type TenantStorageIdFunc func(tenantId, storageId string) string
+
+// TenantDaoResolver returns the DAO that serves a tenant.
+//
+// Available since v0.7.0
+type TenantDaoResolver func(tenantId string) (IGenericDao, error)
+
+// tenantIdPattern restricts tenant ids to characters that are safe to embed in table/collection names.
+var tenantIdPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// IsValidTenantId returns 'true' if the tenant id consists of 1 to 64 letters, digits, '_' or '-' characters.
+// MultiTenantGenericDao rejects other tenant ids with ErrGdaoTenantIdInvalid, so that a tenant id can not change which
+// storage is addressed (e.g. by embedding a '.' or a quote in a table name).
+//
+// Available since v0.7.0
+func IsValidTenantId(tenantId string) bool {
+	return tenantIdPattern.MatchString(tenantId)
+}
+
+// DefaultTenantStorageId is the default TenantStorageIdFunc, which prefixes storage-id with tenant id: <tenant-id>_<storage-id>.
+//
+// Available since v0.7.0
+func DefaultTenantStorageId(tenantId, storageId string) string {
+	return tenantId + "_" + storageId
+}
+
+/*----------------------------------------------------------------------*/
+
+// NewTenantFieldGenericDao constructs a new MultiTenantGenericDao with strategy TenancyField.
+//
+//   - every filter is combined with condition "<tenantField> = <tenant-id>" via FilterOptAnd.
+//   - every BO written is stamped with the tenant id at tenantField.
+//   - GdaoUpdate, GdaoSave and GdaoDelete first check that the existing BO (if any) belongs to the tenant, then pass the
+//     tenant condition to the underlying DAO via WithWriteCondition, so that a BO that changes owner in between is not
+//     touched. The underlying DAO must honor the write condition (as the built-in DAOs do) for this guarantee.
+//
+// Available since v0.7.0
+func NewTenantFieldGenericDao(dao IGenericDao, tenantField string) *MultiTenantGenericDao {
+	return &MultiTenantGenericDao{
+		strategy:          TenancyField,
+		origDao:           dao,
+		dao:               ToGenericDaoWithContext(dao),
+		tenantField:       tenantField,
+		tenantFilterField: tenantField,
+	}
+}
+
+// NewTenantStorageGenericDao constructs a new MultiTenantGenericDao with strategy TenancyStorage.
+// storageIdFunc maps storage-ids to tenant's storage-ids; if nil, DefaultTenantStorageId is used.
+//
+// Available since v0.7.0
+func NewTenantStorageGenericDao(dao IGenericDao, storageIdFunc TenantStorageIdFunc) *MultiTenantGenericDao {
+	if storageIdFunc == nil {
+		storageIdFunc = DefaultTenantStorageId
+	}
+	return &MultiTenantGenericDao{
+		strategy:      TenancyStorage,
+		origDao:       dao,
+		dao:           ToGenericDaoWithContext(dao),
+		storageIdFunc: storageIdFunc,
+	}
+}
+
+// NewTenantDaoGenericDao constructs a new MultiTenantGenericDao with strategy TenancyDao.
+// templateDao serves GdaoCreateFilter and GetRowMapper, which do not carry a context; it is never used to access data.
+//
+// Available since v0.7.0
+func NewTenantDaoGenericDao(resolver TenantDaoResolver, templateDao IGenericDao) *MultiTenantGenericDao {
+	return &MultiTenantGenericDao{
+		strategy: TenancyDao,
+		origDao:  templateDao,
+		dao:      ToGenericDaoWithContext(templateDao),
+		resolver: resolver,
+	}
+}
+
+// MultiTenantGenericDao is an IGenericDao that isolates data of tenants. The tenant id is taken from the context
+// (see WithTenantId), so the context-aware GdaoXxxWithContext methods must be used: the plain GdaoXxx methods carry no
+// tenant id and always fail with ErrGdaoTenantMissing. Tenant ids that are not valid (see IsValidTenantId) are
+// rejected with ErrGdaoTenantIdInvalid. Cross-tenant access fails with *CrossTenantError.
+//
+// Available since v0.7.0
+type MultiTenantGenericDao struct {
+	strategy          TenancyStrategy
+	origDao           IGenericDao
+	dao               IGenericDaoWithContext
+	tenantField       string
+	tenantFilterField string
+	storageIdFunc     TenantStorageIdFunc
+	resolver          TenantDaoResolver
+}
+
+// GetDao returns the underlying DAO (or the template DAO for strategy TenancyDao).
+func (dao *MultiTenantGenericDao) GetDao() IGenericDao {
+	return dao.origDao
+}
+
+// GetStrategy returns the tenancy strategy.
+func (dao *MultiTenantGenericDao) GetStrategy() TenancyStrategy {
+	return dao.strategy
+}
+
+// GetTenantField returns the BO field that holds tenant id (strategy TenancyField).
+func (dao *MultiTenantGenericDao) GetTenantField() string {
+	return dao.tenantField
+}
+
+// GetTenantFilterField returns the field name of the tenant condition in filters (strategy TenancyField).
+func (dao *MultiTenantGenericDao) GetTenantFilterField() string {
+	return dao.tenantFilterField
+}
+
+// SetTenantFilterField sets the field name of the tenant condition in filters, if it differs from the BO field name
+// (e.g. BO attribute "tenantId" is stored in column "tenant_id").
+func (dao *MultiTenantGenericDao) SetTenantFilterField(field string) *MultiTenantGenericDao {
+	dao.tenantFilterField = field
+	return dao
+}
+
+// tenantDao returns the tenant id and the DAO & storage-id that serve the tenant.
+func (dao *MultiTenantGenericDao) tenantDao(ctx context.Context, storageId string) (string, IGenericDaoWithContext, string, error) {
+	tenantId, ok := TenantIdFromContext(ctx)
+	if !ok {
+		return "", nil, "", ErrGdaoTenantMissing
+	}
+	if !IsValidTenantId(tenantId) {
+		return "", nil, "", ErrGdaoTenantIdInvalid
+	}
+	switch dao.strategy {
+	case TenancyStorage:
+		return tenantId, dao.dao, dao.storageIdFunc(tenantId, storageId), nil
+	case TenancyDao:
+		d, err := dao.resolver(tenantId)
+		if err != nil {
+			return tenantId, nil, "", err
+		}
+		if d == nil {
+			return tenantId, nil, "", fmt.Errorf("no DAO for tenant %q", tenantId)
+		}
+		return tenantId, ToGenericDaoWithContext(d), storageId, nil
+	}
+	return tenantId, dao.dao, storageId, nil
+}
+
+// tenantCondition returns the condition that matches BOs of the tenant (strategy TenancyField).
+func (dao *MultiTenantGenericDao) tenantCondition(tenantId string) FilterOpt {
+	return &FilterOptFieldOpValue{FieldName: dao.tenantFilterField, Operator: FilterOpEqual, Value: tenantId}
+}
+
+// tenantFilter combines the filter with the tenant condition (strategy TenancyField).
+func (dao *MultiTenantGenericDao) tenantFilter(tenantId string, filter FilterOpt) FilterOpt {
+	if dao.strategy != TenancyField {
+		return filter
+	}
+	return (&FilterOptAnd{}).Add(dao.tenantCondition(tenantId)).Add(filter)
+}
+
+// tenantWriteContext returns a copy of the context that carries the tenant condition as write condition (strategy TenancyField).
+func (dao *MultiTenantGenericDao) tenantWriteContext(ctx context.Context, tenantId string) context.Context {
+	if dao.strategy != TenancyField {
+		return ctx
+	}
+	return WithWriteCondition(ctx, dao.tenantCondition(tenantId))
+}
+
+// checkOwner returns *CrossTenantError if the BO belongs to another tenant (strategy TenancyField).
+func (dao *MultiTenantGenericDao) checkOwner(storageId, tenantId string, bo IGenericBo) error {
+	if dao.strategy != TenancyField || bo == nil {
+		return nil
+	}
+	owner, err := bo.GboGetAttr(dao.tenantField, reddo.TypeString)
+	if err != nil {
+		return err
+	}
+	if owner != nil && owner.(string) != "" && owner.(string) != tenantId {
+		return &CrossTenantError{StorageId: storageId, TenantId: tenantId, OtherTenantId: owner.(string)}
+	}
+	return nil
+}
+
+// checkExistingOwner returns *CrossTenantError if the existing BO with the same key belongs to another tenant (strategy TenancyField).
+func (dao *MultiTenantGenericDao) checkExistingOwner(ctx context.Context, d IGenericDaoWithContext, storageId, tenantId string, bo IGenericBo) error {
+	if err := dao.checkOwner(storageId, tenantId, bo); err != nil {
+		return err
+	}
+	existing, err := d.GdaoFetchOneWithContext(ctx, storageId, d.GdaoCreateFilter(storageId, bo))
+	if err != nil {
+		return err
+	}
+	return dao.checkOwner(storageId, tenantId, existing)
+}
+
+// prepareWrite checks ownership of the BO to be written and stamps it with the tenant id (strategy TenancyField).
+// If checkExisting is true, the existing BO with the same key must also belong to the tenant.
+func (dao *MultiTenantGenericDao) prepareWrite(ctx context.Context, d IGenericDaoWithContext, storageId, tenantId string, bo IGenericBo, checkExisting bool) error {
+	if dao.strategy != TenancyField {
+		return nil
+	}
+	if checkExisting {
+		if err := dao.checkExistingOwner(ctx, d, storageId, tenantId, bo); err != nil {
+			return err
+		}
+	} else if err := dao.checkOwner(storageId, tenantId, bo); err != nil {
+		return err
+	}
+	return bo.GboSetAttr(dao.tenantField, tenantId)
+}
+
+// GdaoCreateFilter implements IGenericDao.GdaoCreateFilter.
+//
+// The returned filter does not include the tenant condition, which is added when the filter is used.
+func (dao *MultiTenantGenericDao) GdaoCreateFilter(storageId string, bo IGenericBo) FilterOpt {
+	return dao.dao.GdaoCreateFilter(storageId, bo)
+}
+
+// GetRowMapper implements IGenericDao.GetRowMapper.
+func (dao *MultiTenantGenericDao) GetRowMapper() IRowMapper {
+	return dao.dao.GetRowMapper()
+}
+
+// GdaoDelete implements IGenericDao.GdaoDelete.
+func (dao *MultiTenantGenericDao) GdaoDelete(storageId string, bo IGenericBo) (int, error) {
+	return dao.GdaoDeleteWithContext(nil, storageId, bo)
+}
+
+// GdaoDeleteWithContext implements IGenericDaoWithContext.GdaoDeleteWithContext.
+func (dao *MultiTenantGenericDao) GdaoDeleteWithContext(ctx context.Context, storageId string, bo IGenericBo) (int, error) {
+	tenantId, d, tenantStorageId, err := dao.tenantDao(ctx, storageId)
+	if err != nil {
+		return 0, err
+	}
+	if dao.strategy == TenancyField {
+		if err := dao.checkExistingOwner(ctx, d, tenantStorageId, tenantId, bo); err != nil {
+			return 0, err
+		}
+	}
+	return d.GdaoDeleteWithContext(dao.tenantWriteContext(ctx, tenantId), tenantStorageId, bo)
+}
+
+// GdaoDeleteMany implements IGenericDao.GdaoDeleteMany.
+func (dao *MultiTenantGenericDao) GdaoDeleteMany(storageId string, filter FilterOpt) (int, error) {
+	return dao.GdaoDeleteManyWithContext(nil, storageId, filter)
+}
+
+// GdaoDeleteManyWithContext implements IGenericDaoWithContext.GdaoDeleteManyWithContext.
+func (dao *MultiTenantGenericDao) GdaoDeleteManyWithContext(ctx context.Context, storageId string, filter FilterOpt) (int, error) {
+	tenantId, d, tenantStorageId, err := dao.tenantDao(ctx, storageId)
+	if err != nil {
+		return 0, err
+	}
+	return d.GdaoDeleteManyWithContext(ctx, tenantStorageId, dao.tenantFilter(tenantId, filter))
+}
+
+// GdaoFetchOne implements IGenericDao.GdaoFetchOne.
+func (dao *MultiTenantGenericDao) GdaoFetchOne(storageId string, filter FilterOpt) (IGenericBo, error) {
+	return dao.GdaoFetchOneWithContext(nil, storageId, filter)
+}
+
+// GdaoFetchOneWithContext implements IGenericDaoWithContext.GdaoFetchOneWithContext.
+func (dao *MultiTenantGenericDao) GdaoFetchOneWithContext(ctx context.Context, storageId string, filter FilterOpt) (IGenericBo, error) {
+	tenantId, d, tenantStorageId, err := dao.tenantDao(ctx, storageId)
+	if err != nil {
+		return nil, err
+	}
+	bo, err := d.GdaoFetchOneWithContext(ctx, tenantStorageId, dao.tenantFilter(tenantId, filter))
+	if err != nil {
+		return nil, err
+	}
+	if err := dao.checkOwner(storageId, tenantId, bo); err != nil {
+		return nil, err
+	}
+	return bo, nil
+}
+
+// GdaoFetchMany implements IGenericDao.GdaoFetchMany.
+func (dao *MultiTenantGenericDao) GdaoFetchMany(storageId string, filter FilterOpt, sorting *SortingOpt, startOffset, numItems int) ([]IGenericBo, error) {
+	return dao.GdaoFetchManyWithContext(nil, storageId, filter, sorting, startOffset, numItems)
+}
+
+// GdaoFetchManyWithContext implements IGenericDaoWithContext.GdaoFetchManyWithContext.
+func (dao *MultiTenantGenericDao) GdaoFetchManyWithContext(ctx context.Context, storageId string, filter FilterOpt, sorting *SortingOpt, startOffset, numItems int) ([]IGenericBo, error) {
+	tenantId, d, tenantStorageId, err := dao.tenantDao(ctx, storageId)
+	if err != nil {
+		return nil, err
+	}
+	boList, err := d.GdaoFetchManyWithContext(ctx, tenantStorageId, dao.tenantFilter(tenantId, filter), sorting, startOffset, numItems)
+	if err != nil {
+		return nil, err
+	}
+	for _, bo := range boList {
+		if err := dao.checkOwner(storageId, tenantId, bo); err != nil {
+			return nil, err
+		}
+	}
+	return boList, nil
+}
+
+// GdaoCreate implements IGenericDao.GdaoCreate.
+func (dao *MultiTenantGenericDao) GdaoCreate(storageId string, bo IGenericBo) (int, error) {
+	return dao.GdaoCreateWithContext(nil, storageId, bo)
+}
+
+// GdaoCreateWithContext implements IGenericDaoWithContext.GdaoCreateWithContext.
+func (dao *MultiTenantGenericDao) GdaoCreateWithContext(ctx context.Context, storageId string, bo IGenericBo) (int, error) {
+	tenantId, d, tenantStorageId, err := dao.tenantDao(ctx, storageId)
+	if err != nil {
+		return 0, err
+	}
+	if err := dao.prepareWrite(ctx, d, tenantStorageId, tenantId, bo, false); err != nil {
+		return 0, err
+	}
+	return d.GdaoCreateWithContext(ctx, tenantStorageId, bo)
+}
+
+// GdaoUpdate implements IGenericDao.GdaoUpdate.
+func (dao *MultiTenantGenericDao) GdaoUpdate(storageId string, bo IGenericBo) (int, error) {
+	return dao.GdaoUpdateWithContext(nil, storageId, bo)
+}
+
+// GdaoUpdateWithContext implements IGenericDaoWithContext.GdaoUpdateWithContext.
+func (dao *MultiTenantGenericDao) GdaoUpdateWithContext(ctx context.Context, storageId string, bo IGenericBo) (int, error) {
+	tenantId, d, tenantStorageId, err := dao.tenantDao(ctx, storageId)
+	if err != nil {
+		return 0, err
+	}
+	if err := dao.prepareWrite(ctx, d, tenantStorageId, tenantId, bo, true); err != nil {
+		return 0, err
+	}
+	return d.GdaoUpdateWithContext(dao.tenantWriteContext(ctx, tenantId), tenantStorageId, bo)
+}
+
+// GdaoSave implements IGenericDao.GdaoSave.
+func (dao *MultiTenantGenericDao) GdaoSave(storageId string, bo IGenericBo) (int, error) {
+	return dao.GdaoSaveWithContext(nil, storageId, bo)
+}
+
+// GdaoSaveWithContext implements IGenericDaoWithContext.GdaoSaveWithContext.
+func (dao *MultiTenantGenericDao) GdaoSaveWithContext(ctx context.Context, storageId string, bo IGenericBo) (int, error) {
+	tenantId, d, tenantStorageId, err := dao.tenantDao(ctx, storageId)
+	if err != nil {
+		return 0, err
+	}
+	if err := dao.prepareWrite(ctx, d, tenantStorageId, tenantId, bo, true); err != nil {
+		return 0, err
+	}
+	return d.GdaoSaveWithContext(dao.tenantWriteContext(ctx, tenantId), tenantStorageId, bo)
+}
@@ -0,0 +1,283 @@
+package godal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/btnguyen2k/consu/reddo"
+)
+
+func TestTenantIdFromContext(t *testing.T) {
+	name := "TestTenantIdFromContext"
+	if _, ok := TenantIdFromContext(nil); ok {
+		t.Fatalf("%s failed: nil context should not carry tenant id", name)
+	}
+	if _, ok := TenantIdFromContext(WithTenantId(nil, "")); ok {
+		t.Fatalf("%s failed: empty tenant id should be treated as missing", name)
+	}
+	if tenantId, ok := TenantIdFromContext(WithTenantId(context.Background(), "t1")); !ok || tenantId != "t1" {
+		t.Fatalf("%s failed: %#v / %#v", name, tenantId, ok)
+	}
+}
+
+func TestMultiTenantGenericDao_TenantMissing(t *testing.T) {
+	name := "TestMultiTenantGenericDao_TenantMissing"
+	mock := newMockGenericDao()
+	for _, dao := range []*MultiTenantGenericDao{
+		NewTenantFieldGenericDao(mock, "tenant"),
+		NewTenantStorageGenericDao(mock, nil),
+		NewTenantDaoGenericDao(func(string) (IGenericDao, error) { return mock, nil }, mock),
+	} {
+		if _, err := dao.GdaoCreate("test", newMockBo("1", 1)); err != ErrGdaoTenantMissing {
+			t.Fatalf("%s failed: expected error %#v but received %#v", name, ErrGdaoTenantMissing, err)
+		}
+		if _, err := dao.GdaoFetchMany("test", nil, nil, 0, 0); err != ErrGdaoTenantMissing {
+			t.Fatalf("%s failed: expected error %#v but received %#v", name, ErrGdaoTenantMissing, err)
+		}
+	}
+	if c := mock.count("create") + mock.count("fetchMany"); c != 0 {
+		t.Fatalf("%s failed: underlying DAO should not be called", name)
+	}
+}
+
+func TestMultiTenantGenericDao_Field(t *testing.T) {
+	name := "TestMultiTenantGenericDao_Field"
+	mock := newMockGenericDao()
+	dao := NewTenantFieldGenericDao(mock, "tenant")
+	ctx1, ctx2 := WithTenantId(nil, "t1"), WithTenantId(nil, "t2")
+
+	bo := newMockBo("1", 1)
+	if numRows, err := dao.GdaoCreateWithContext(ctx1, "test", bo); numRows != 1 || err != nil {
+		t.Fatalf("%s failed: %#v / %#v", name, numRows, err)
+	}
+	if v := bo.GboGetAttrUnsafe("tenant", reddo.TypeString); v != "t1" {
+		t.Fatalf("%s failed: BO should have been stamped with tenant id, received %#v", name, v)
+	}
+	dao.GdaoCreateWithContext(ctx2, "test", newMockBo("2", 2))
+
+	boList, err := dao.GdaoFetchManyWithContext(ctx1, "test", nil, nil, 0, 0)
+	if err != nil || len(boList) != 1 || boList[0].GboGetAttrUnsafe("id", reddo.TypeString) != "1" {
+		t.Fatalf("%s failed: %#v / %#v", name, boList, err)
+	}
+	filter := dao.GdaoCreateFilter("test", newMockBo("2", nil))
+	if bo, err := dao.GdaoFetchOneWithContext(ctx1, "test", filter); bo != nil || err != nil {
+		t.Fatalf("%s failed: tenant t1 should not see BO of tenant t2: %#v / %#v", name, bo, err)
+	}
+	if bo, err := dao.GdaoFetchOneWithContext(ctx2, "test", filter); bo == nil || err != nil {
+		t.Fatalf("%s failed: %#v / %#v", name, bo, err)
+	}
+
+	if numRows, err := dao.GdaoDeleteManyWithContext(ctx1, "test", nil); numRows != 1 || err != nil {
+		t.Fatalf("%s failed: %#v / %#v", name, numRows, err)
+	}
+	if bo, _ := mock.GdaoFetchOne("test", filter); bo == nil {
+		t.Fatalf("%s failed: BO of tenant t2 should not have been deleted", name)
+	}
+}
+
+func TestMultiTenantGenericDao_FieldCrossTenant(t *testing.T) {
+	name := "TestMultiTenantGenericDao_FieldCrossTenant"
+	mock := newMockGenericDao()
+	dao := NewTenantFieldGenericDao(mock, "tenant")
+	ctx1, ctx2 := WithTenantId(nil, "t1"), WithTenantId(nil, "t2")
+	dao.GdaoCreateWithContext(ctx2, "test", newMockBo("2", 2))
+
+	var crossTenantErr *CrossTenantError
+	bo := newMockBo("1", 1)
+	bo.GboSetAttr("tenant", "t2")
+	if _, err := dao.GdaoCreateWithContext(ctx1, "test", bo); !errors.As(err, &crossTenantErr) {
+		t.Fatalf("%s failed: expected CrossTenantError but received %#v", name, err)
+	}
+	if crossTenantErr.TenantId != "t1" || crossTenantErr.OtherTenantId != "t2" || crossTenantErr.StorageId != "test" {
+		t.Fatalf("%s failed: unexpected error %#v", name, crossTenantErr)
+	}
+
+	// overwriting/deleting BO of another tenant by key
+	for op, f := range map[string]func() (int, error){
+		"update": func() (int, error) { return dao.GdaoUpdateWithContext(ctx1, "test", newMockBo("2", 100)) },
+		"save":   func() (int, error) { return dao.GdaoSaveWithContext(ctx1, "test", newMockBo("2", 100)) },
+		"delete": func() (int, error) { return dao.GdaoDeleteWithContext(ctx1, "test", newMockBo("2", nil)) },
+	} {
+		if _, err := f(); !errors.As(err, &crossTenantErr) {
+			t.Fatalf("%s failed: expected CrossTenantError from %s but received %#v", name, op, err)
+		}
+		if c := mock.count(op); c != 0 {
+			t.Fatalf("%s failed: %s should not reach the underlying DAO", name, op)
+		}
+	}
+
+	// own BO can be updated, saved and deleted
+	dao.GdaoCreateWithContext(ctx1, "test", newMockBo("1", 1))
+	if numRows, err := dao.GdaoUpdateWithContext(ctx1, "test", newMockBo("1", 2)); numRows != 1 || err != nil {
+		t.Fatalf("%s failed: %#v / %#v", name, numRows, err)
+	}
+	if numRows, err := dao.GdaoSaveWithContext(ctx1, "test", newMockBo("3", 3)); numRows != 1 || err != nil {
+		t.Fatalf("%s failed: %#v / %#v", name, numRows, err)
+	}
+	if numRows, err := dao.GdaoDeleteWithContext(ctx1, "test", newMockBo("1", nil)); numRows != 1 || err != nil {
+		t.Fatalf("%s failed: %#v / %#v", name, numRows, err)
+	}
+}
+
+func TestMultiTenantGenericDao_Storage(t *testing.T) {
+	name := "TestMultiTenantGenericDao_Storage"
+	mock := newMockGenericDao()
+	dao := NewTenantStorageGenericDao(mock, nil)
+	ctx1, ctx2 := WithTenantId(nil, "t1"), WithTenantId(nil, "t2")
+	dao.GdaoCreateWithContext(ctx1, "test", newMockBo("1", 1))
+	dao.GdaoCreateWithContext(ctx2, "test", newMockBo("1", 2))
+
+	if boList, _ := mock.GdaoFetchMany("t1_test", nil, nil, 0, 0); len(boList) != 1 {
+		t.Fatalf("%s failed: expected %#v BO in storage t1_test but received %#v", name, 1, len(boList))
+	}
+	bo, err := dao.GdaoFetchOneWithContext(ctx2, "test", dao.GdaoCreateFilter("test", newMockBo("1", nil)))
+	if err != nil || bo == nil || bo.GboGetAttrUnsafe("value", reddo.TypeInt) != int64(2) {
+		t.Fatalf("%s failed: %#v / %#v", name, bo, err)
+	}
+	if bo.GboGetAttrUnsafe("tenant", nil) != nil {
+		t.Fatalf("%s failed: BO should not be stamped with tenant id", name)
+	}
+}
+
+func TestMultiTenantGenericDao_Dao(t *testing.T) {
+	name := "TestMultiTenantGenericDao_Dao"
+	mocks := map[string]*mockGenericDao{"t1": newMockGenericDao(), "t2": newMockGenericDao()}
+	dao := NewTenantDaoGenericDao(func(tenantId string) (IGenericDao, error) {
+		if mock, ok := mocks[tenantId]; ok {
+			return mock, nil
+		}
+		return nil, fmt.Errorf("unknown tenant %s", tenantId)
+	}, mocks["t1"])
+	dao.GdaoCreateWithContext(WithTenantId(nil, "t1"), "test", newMockBo("1", 1))
+	dao.GdaoSaveWithContext(WithTenantId(nil, "t2"), "test", newMockBo("1", 2))
+	for tenantId, mock := range mocks {
+		if c := mock.count("create") + mock.count("save"); c != 1 {
+			t.Fatalf("%s failed: expected %#v write on DAO of tenant %s but received %#v", name, 1, tenantId, c)
+		}
+	}
+	if _, err := dao.GdaoFetchOneWithContext(WithTenantId(nil, "t3"), "test", nil); err == nil {
+		t.Fatalf("%s failed: unknown tenant should fail", name)
+	}
+}
+
+func TestIsValidTenantId(t *testing.T) {
+	name := "TestIsValidTenantId"
+	for _, tenantId := range []string{"t1", "Tenant_01", "a-b", strings.Repeat("x", 64)} {
+		if !IsValidTenantId(tenantId) {
+			t.Fatalf("%s failed: %q should be valid", name, tenantId)
+		}
+	}
+	for _, tenantId := range []string{"", "a.b", "a'b", `a"b`, "a b", "a;drop", "ü", strings.Repeat("x", 65)} {
+		if IsValidTenantId(tenantId) {
+			t.Fatalf("%s failed: %q should be invalid", name, tenantId)
+		}
+	}
+}
+
+func TestMultiTenantGenericDao_TenantIdInvalid(t *testing.T) {
+	name := "TestMultiTenantGenericDao_TenantIdInvalid"
+	mock := newMockGenericDao()
+	ctx := WithTenantId(nil, "t1.users")
+	for _, dao := range []*MultiTenantGenericDao{
+		NewTenantFieldGenericDao(mock, "tenant"),
+		NewTenantStorageGenericDao(mock, nil),
+		NewTenantDaoGenericDao(func(string) (IGenericDao, error) { return mock, nil }, mock),
+	} {
+		if _, err := dao.GdaoCreateWithContext(ctx, "test", newMockBo("1", 1)); err != ErrGdaoTenantIdInvalid {
+			t.Fatalf("%s failed: expected error %#v but received %#v", name, ErrGdaoTenantIdInvalid, err)
+		}
+		if _, err := dao.GdaoFetchManyWithContext(ctx, "test", nil, nil, 0, 0); err != ErrGdaoTenantIdInvalid {
+			t.Fatalf("%s failed: expected error %#v but received %#v", name, ErrGdaoTenantIdInvalid, err)
+		}
+	}
+	if c := mock.count("create") + mock.count("fetchMany"); c != 0 {
+		t.Fatalf("%s failed: underlying DAO should not be called", name)
+	}
+}
+
+// writeConditionMockDao wraps mockGenericDao to honor the write condition carried by the context, and to run a
+// callback after each fetch.
+type writeConditionMockDao struct {
+	*mockGenericDao
+	afterFetch func()
+}
+
+// satisfied returns 'false' if the existing BO with the same key does not satisfy the write condition.
+func (dao *writeConditionMockDao) satisfied(ctx context.Context, storageId string, bo IGenericBo) bool {
+	existing, _ := dao.mockGenericDao.GdaoFetchOne(storageId, dao.GdaoCreateFilter(storageId, bo))
+	return existing == nil || mockMatchFilter(existing, WriteConditionFromContext(ctx))
+}
+
+func (dao *writeConditionMockDao) GdaoDeleteWithContext(ctx context.Context, storageId string, bo IGenericBo) (int, error) {
+	if !dao.satisfied(ctx, storageId, bo) {
+		return 0, nil
+	}
+	return dao.GdaoDelete(storageId, bo)
+}
+
+func (dao *writeConditionMockDao) GdaoDeleteManyWithContext(_ context.Context, storageId string, filter FilterOpt) (int, error) {
+	return dao.GdaoDeleteMany(storageId, filter)
+}
+
+func (dao *writeConditionMockDao) GdaoFetchOneWithContext(_ context.Context, storageId string, filter FilterOpt) (IGenericBo, error) {
+	defer func() {
+		if dao.afterFetch != nil {
+			dao.afterFetch()
+		}
+	}()
+	return dao.GdaoFetchOne(storageId, filter)
+}
+
+func (dao *writeConditionMockDao) GdaoFetchManyWithContext(_ context.Context, storageId string, filter FilterOpt, sorting *SortingOpt, startOffset, numItems int) ([]IGenericBo, error) {
+	return dao.GdaoFetchMany(storageId, filter, sorting, startOffset, numItems)
+}
+
+func (dao *writeConditionMockDao) GdaoCreateWithContext(_ context.Context, storageId string, bo IGenericBo) (int, error) {
+	return dao.GdaoCreate(storageId, bo)
+}
+
+func (dao *writeConditionMockDao) GdaoUpdateWithContext(ctx context.Context, storageId string, bo IGenericBo) (int, error) {
+	if !dao.satisfied(ctx, storageId, bo) {
+		return 0, nil
+	}
+	return dao.GdaoUpdate(storageId, bo)
+}
+
+func (dao *writeConditionMockDao) GdaoSaveWithContext(ctx context.Context, storageId string, bo IGenericBo) (int, error) {
+	if !dao.satisfied(ctx, storageId, bo) {
+		return 0, ErrGdaoDuplicatedEntry
+	}
+	return dao.GdaoSave(storageId, bo)
+}
+
+func TestMultiTenantGenericDao_FieldWriteCondition(t *testing.T) {
+	name := "TestMultiTenantGenericDao_FieldWriteCondition"
+	mock := &writeConditionMockDao{mockGenericDao: newMockGenericDao()}
+	dao := NewTenantFieldGenericDao(mock, "tenant")
+	ctx1, ctx2 := WithTenantId(nil, "t1"), WithTenantId(nil, "t2")
+
+	// the BO changes owner between the ownership check and the write
+	for op, f := range map[string]func() (int, error){
+		"update": func() (int, error) { return dao.GdaoUpdateWithContext(ctx1, "test", newMockBo("1", 100)) },
+		"save":   func() (int, error) { return dao.GdaoSaveWithContext(ctx1, "test", newMockBo("1", 100)) },
+		"delete": func() (int, error) { return dao.GdaoDeleteWithContext(ctx1, "test", newMockBo("1", nil)) },
+	} {
+		mock.GdaoDeleteMany("test", nil)
+		dao.GdaoCreateWithContext(ctx1, "test", newMockBo("1", 1))
+		mock.afterFetch = func() {
+			mock.afterFetch = nil
+			dao.GdaoDeleteWithContext(ctx1, "test", newMockBo("1", nil))
+			dao.GdaoCreateWithContext(ctx2, "test", newMockBo("1", 2))
+		}
+		if numRows, _ := f(); numRows != 0 {
+			t.Fatalf("%s failed: %s should not touch BO of another tenant", name, op)
+		}
+		bo, _ := dao.GdaoFetchOneWithContext(ctx2, "test", dao.GdaoCreateFilter("test", newMockBo("1", nil)))
+		if bo == nil || bo.GboGetAttrUnsafe("value", reddo.TypeInt) != int64(2) {
+			t.Fatalf("%s failed: BO of tenant t2 should be intact after %s, received %#v", name, op, bo)
+		}
+	}
+}
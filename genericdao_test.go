@@ -1,7 +1,14 @@
 package godal
 
 import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
 	"testing"
+
+	"github.com/btnguyen2k/consu/reddo"
 )
 
 func TestNewAbstractGenericDao(t *testing.T) {
@@ -47,3 +54,301 @@ func TestAbstractGenericDao_GetSetRowMapper(t *testing.T) {
 		t.Fatalf("%s failed: expected %p but received %p", name, rowMapper, v)
 	}
 }
+
+/*----------------------------------------------------------------------*/
+
+// mockGenericDao is an in-memory implementation of IGenericDao used to test DAO wrappers.
+// BOs are identified by the "id" field and stored as JSON.
+type mockGenericDao struct {
+	lock       sync.Mutex
+	storages   map[string]map[string][]byte
+	counters   map[string]int
+	errToThrow error
+}
+
+func newMockGenericDao() *mockGenericDao {
+	return &mockGenericDao{storages: make(map[string]map[string][]byte), counters: make(map[string]int)}
+}
+
+func (dao *mockGenericDao) count(op string) int {
+	dao.lock.Lock()
+	defer dao.lock.Unlock()
+	return dao.counters[op]
+}
+
+func (dao *mockGenericDao) begin(op string) error {
+	dao.counters[op]++
+	return dao.errToThrow
+}
+
+func (dao *mockGenericDao) storage(storageId string) map[string][]byte {
+	s, ok := dao.storages[storageId]
+	if !ok {
+		s = make(map[string][]byte)
+		dao.storages[storageId] = s
+	}
+	return s
+}
+
+func mockCompare(a, b interface{}) int {
+	fa, ea := reddo.ToFloat(a)
+	fb, eb := reddo.ToFloat(b)
+	if ea == nil && eb == nil {
+		if fa < fb {
+			return -1
+		} else if fa > fb {
+			return 1
+		}
+		return 0
+	}
+	return strings.Compare(fmt.Sprintf("%v", a), fmt.Sprintf("%v", b))
+}
+
+func mockMatchOperator(op FilterOperator, c int) bool {
+	switch op {
+	case FilterOpEqual:
+		return c == 0
+	case FilterOpNotEqual:
+		return c != 0
+	case FilterOpGreater:
+		return c > 0
+	case FilterOpGreaterOrEqual:
+		return c >= 0
+	case FilterOpLess:
+		return c < 0
+	case FilterOpLessOrEqual:
+		return c <= 0
+	}
+	return false
+}
+
+func mockMatchFilter(bo IGenericBo, filter FilterOpt) bool {
+	switch f := filter.(type) {
+	case nil:
+		return true
+	case *FilterOptFieldOpValue:
+		v := bo.GboGetAttrUnsafe(f.FieldName, nil)
+		return v != nil && mockMatchOperator(f.Operator, mockCompare(v, f.Value))
+	case FilterOptFieldOpValue:
+		return mockMatchFilter(bo, &f)
+	case *FilterOptFieldOpField:
+		l, r := bo.GboGetAttrUnsafe(f.FieldNameLeft, nil), bo.GboGetAttrUnsafe(f.FieldNameRight, nil)
+		return l != nil && r != nil && mockMatchOperator(f.Operator, mockCompare(l, r))
+	case *FilterOptFieldIsNull:
+		return bo.GboGetAttrUnsafe(f.FieldName, nil) == nil
+	case *FilterOptFieldIsNotNull:
+		return bo.GboGetAttrUnsafe(f.FieldName, nil) != nil
+	case *FilterOptAnd:
+		for _, inner := range f.Filters {
+			if !mockMatchFilter(bo, inner) {
+				return false
+			}
+		}
+		return true
+	case *FilterOptOr:
+		for _, inner := range f.Filters {
+			if mockMatchFilter(bo, inner) {
+				return true
+			}
+		}
+		return false
+	}
+	return false
+}
+
+func (dao *mockGenericDao) GdaoCreateFilter(_ string, bo IGenericBo) FilterOpt {
+	return &FilterOptFieldOpValue{FieldName: "id", Operator: FilterOpEqual, Value: bo.GboGetAttrUnsafe("id", nil)}
+}
+
+func (dao *mockGenericDao) GdaoDelete(storageId string, bo IGenericBo) (int, error) {
+	dao.lock.Lock()
+	defer dao.lock.Unlock()
+	if err := dao.begin("delete"); err != nil {
+		return 0, err
+	}
+	id := fmt.Sprintf("%v", bo.GboGetAttrUnsafe("id", nil))
+	if _, ok := dao.storage(storageId)[id]; !ok {
+		return 0, nil
+	}
+	delete(dao.storage(storageId), id)
+	return 1, nil
+}
+
+func (dao *mockGenericDao) GdaoDeleteMany(storageId string, filter FilterOpt) (int, error) {
+	dao.lock.Lock()
+	defer dao.lock.Unlock()
+	if err := dao.begin("deleteMany"); err != nil {
+		return 0, err
+	}
+	count := 0
+	for id, js := range dao.storage(storageId) {
+		bo := NewGenericBo()
+		_ = bo.GboFromJson(js)
+		if mockMatchFilter(bo, filter) {
+			delete(dao.storage(storageId), id)
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (dao *mockGenericDao) GdaoFetchOne(storageId string, filter FilterOpt) (IGenericBo, error) {
+	result, err := dao.fetch("fetchOne", storageId, filter, nil, 0, 1)
+	if err != nil || len(result) == 0 {
+		return nil, err
+	}
+	return result[0], nil
+}
+
+func (dao *mockGenericDao) GdaoFetchMany(storageId string, filter FilterOpt, sorting *SortingOpt, startOffset, numItems int) ([]IGenericBo, error) {
+	return dao.fetch("fetchMany", storageId, filter, sorting, startOffset, numItems)
+}
+
+func (dao *mockGenericDao) fetch(op, storageId string, filter FilterOpt, sorting *SortingOpt, startOffset, numItems int) ([]IGenericBo, error) {
+	dao.lock.Lock()
+	defer dao.lock.Unlock()
+	if err := dao.begin(op); err != nil {
+		return nil, err
+	}
+	result := make([]IGenericBo, 0)
+	for _, js := range dao.storage(storageId) {
+		bo := NewGenericBo()
+		_ = bo.GboFromJson(js)
+		if mockMatchFilter(bo, filter) {
+			result = append(result, bo)
+		}
+	}
+	if sorting == nil {
+		sorting = (&SortingField{FieldName: "id"}).ToSortingOpt()
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		for _, field := range sorting.Fields {
+			c := mockCompare(result[i].GboGetAttrUnsafe(field.FieldName, nil), result[j].GboGetAttrUnsafe(field.FieldName, nil))
+			if c != 0 {
+				return (c < 0) != field.Descending
+			}
+		}
+		return false
+	})
+	if startOffset > len(result) {
+		startOffset = len(result)
+	}
+	result = result[startOffset:]
+	if numItems > 0 && numItems < len(result) {
+		result = result[:numItems]
+	}
+	return result, nil
+}
+
+func (dao *mockGenericDao) GdaoCreate(storageId string, bo IGenericBo) (int, error) {
+	dao.lock.Lock()
+	defer dao.lock.Unlock()
+	if err := dao.begin("create"); err != nil {
+		return 0, err
+	}
+	id := fmt.Sprintf("%v", bo.GboGetAttrUnsafe("id", nil))
+	if _, ok := dao.storage(storageId)[id]; ok {
+		return 0, ErrGdaoDuplicatedEntry
+	}
+	dao.storage(storageId)[id] = bo.GboToJsonUnsafe()
+	return 1, nil
+}
+
+func (dao *mockGenericDao) GdaoUpdate(storageId string, bo IGenericBo) (int, error) {
+	dao.lock.Lock()
+	defer dao.lock.Unlock()
+	if err := dao.begin("update"); err != nil {
+		return 0, err
+	}
+	id := fmt.Sprintf("%v", bo.GboGetAttrUnsafe("id", nil))
+	if _, ok := dao.storage(storageId)[id]; !ok {
+		return 0, nil
+	}
+	dao.storage(storageId)[id] = bo.GboToJsonUnsafe()
+	return 1, nil
+}
+
+func (dao *mockGenericDao) GdaoSave(storageId string, bo IGenericBo) (int, error) {
+	dao.lock.Lock()
+	defer dao.lock.Unlock()
+	if err := dao.begin("save"); err != nil {
+		return 0, err
+	}
+	id := fmt.Sprintf("%v", bo.GboGetAttrUnsafe("id", nil))
+	dao.storage(storageId)[id] = bo.GboToJsonUnsafe()
+	return 1, nil
+}
+
+func (dao *mockGenericDao) GetRowMapper() IRowMapper {
+	return nil
+}
+
+func newMockBo(id string, value interface{}) IGenericBo {
+	bo := NewGenericBo()
+	_ = bo.GboSetAttr("id", id)
+	_ = bo.GboSetAttr("value", value)
+	return bo
+}
+
+func TestToGenericDaoWithContext(t *testing.T) {
+	name := "TestToGenericDaoWithContext"
+	if ToGenericDaoWithContext(nil) != nil {
+		t.Fatalf("%s failed: expected nil", name)
+	}
+	dao := newMockGenericDao()
+	ctxDao := ToGenericDaoWithContext(dao)
+	if ctxDao == nil {
+		t.Fatalf("%s failed: nil", name)
+	}
+	if ToGenericDaoWithContext(ctxDao) != ctxDao {
+		t.Fatalf("%s failed: IGenericDaoWithContext should be returned as-is", name)
+	}
+
+	ctx := context.Background()
+	if numRows, err := ctxDao.GdaoCreateWithContext(ctx, "test", newMockBo("1", 1)); numRows != 1 || err != nil {
+		t.Fatalf("%s failed: %#v / %#v", name+"/GdaoCreateWithContext", numRows, err)
+	}
+	if numRows, err := ctxDao.GdaoUpdateWithContext(ctx, "test", newMockBo("1", 2)); numRows != 1 || err != nil {
+		t.Fatalf("%s failed: %#v / %#v", name+"/GdaoUpdateWithContext", numRows, err)
+	}
+	if numRows, err := ctxDao.GdaoSaveWithContext(ctx, "test", newMockBo("2", 3)); numRows != 1 || err != nil {
+		t.Fatalf("%s failed: %#v / %#v", name+"/GdaoSaveWithContext", numRows, err)
+	}
+	if bo, err := ctxDao.GdaoFetchOneWithContext(ctx, "test", ctxDao.GdaoCreateFilter("test", newMockBo("1", nil))); bo == nil || err != nil {
+		t.Fatalf("%s failed: %#v / %#v", name+"/GdaoFetchOneWithContext", bo, err)
+	} else if v := bo.GboGetAttrUnsafe("value", reddo.TypeInt); v != int64(2) {
+		t.Fatalf("%s failed: expected %#v but received %#v", name+"/GdaoFetchOneWithContext", 2, v)
+	}
+	if boList, err := ctxDao.GdaoFetchManyWithContext(ctx, "test", nil, nil, 0, 0); len(boList) != 2 || err != nil {
+		t.Fatalf("%s failed: %#v / %#v", name+"/GdaoFetchManyWithContext", boList, err)
+	}
+	if numRows, err := ctxDao.GdaoDeleteWithContext(ctx, "test", newMockBo("1", nil)); numRows != 1 || err != nil {
+		t.Fatalf("%s failed: %#v / %#v", name+"/GdaoDeleteWithContext", numRows, err)
+	}
+	if numRows, err := ctxDao.GdaoDeleteManyWithContext(ctx, "test", nil); numRows != 1 || err != nil {
+		t.Fatalf("%s failed: %#v / %#v", name+"/GdaoDeleteManyWithContext", numRows, err)
+	}
+}
+
+func TestWithWriteCondition(t *testing.T) {
+	name := "TestWithWriteCondition"
+	if c := WriteConditionFromContext(nil); c != nil {
+		t.Fatalf("%s failed: expected nil but received %#v", name, c)
+	}
+	key := &FilterOptFieldOpValue{FieldName: "id", Operator: FilterOpEqual, Value: "1"}
+	if f := ApplyWriteCondition(context.Background(), key); f != key {
+		t.Fatalf("%s failed: filter should be returned as-is, received %#v", name, f)
+	}
+
+	c1 := &FilterOptFieldOpValue{FieldName: "tenant", Operator: FilterOpEqual, Value: "t1"}
+	c2 := &FilterOptFieldIsNull{FieldName: "deleted"}
+	ctx := WithWriteCondition(nil, c1)
+	if c := WriteConditionFromContext(ctx); c != c1 {
+		t.Fatalf("%s failed: expected %#v but received %#v", name, c1, c)
+	}
+	ctx = WithWriteCondition(ctx, c2)
+	expected := (&FilterOptAnd{}).Add(key).Add((&FilterOptAnd{}).Add(c1).Add(c2))
+	if f := ApplyWriteCondition(ctx, key); FilterToCacheKey(f) != FilterToCacheKey(expected) {
+		t.Fatalf("%s failed: expected %s but received %s", name, FilterToCacheKey(expected), FilterToCacheKey(f))
+	}
+}
@@ -0,0 +1,501 @@
+package godal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/btnguyen2k/consu/reddo"
+)
+
+// NewTypedDao constructs a new TypedDao that stores values of type T (which must be a struct type) to a storage via
+// the supplied DAO.
+//
+// Available since v0.7.0
+func NewTypedDao[T any](dao IGenericDao, storageId string) *TypedDao[T] {
+	return &TypedDao[T]{origDao: dao, dao: ToGenericDaoWithContext(dao), storageId: storageId}
+}
+
+// TypedDao wraps an IGenericDao and works with values of a Go struct type T instead of IGenericBo.
+//
+// Struct fields are mapped to BO fields the same way encoding/json does: exported fields only, names are taken from
+// the "json" tag if present (options "-" and "omitempty" are honored), fields of embedded structs are promoted.
+// Nested structs, slices and maps are mapped to nested BO values; time.Time and []byte values are kept as-is.
+// Conversion is reflection-based with field mappings cached per type; there is no JSON round-trip.
+//
+// Values written with Create, Update and Save receive the changes the DAO made to the BO (e.g. generated id and
+// auto-fields).
+//
+// Available since v0.7.0
+type TypedDao[T any] struct {
+	origDao   IGenericDao
+	dao       IGenericDaoWithContext
+	storageId string
+}
+
+// GetDao returns the wrapped DAO.
+func (d *TypedDao[T]) GetDao() IGenericDao {
+	return d.origDao
+}
+
+// GetStorageId returns the storage the values are stored to.
+func (d *TypedDao[T]) GetStorageId() string {
+	return d.storageId
+}
+
+// ToGbo converts a value to IGenericBo.
+func (d *TypedDao[T]) ToGbo(v *T) (IGenericBo, error) {
+	return StructToGbo(v)
+}
+
+// FromGbo converts an IGenericBo to a value, nil if the BO is nil.
+func (d *TypedDao[T]) FromGbo(bo IGenericBo) (*T, error) {
+	if bo == nil {
+		return nil, nil
+	}
+	v := new(T)
+	return v, GboToStruct(bo, v)
+}
+
+// Get fetches the value matching the filter, nil if not found. See IGenericDao.GdaoFetchOne.
+func (d *TypedDao[T]) Get(ctx context.Context, filter FilterOpt) (*T, error) {
+	bo, err := d.dao.GdaoFetchOneWithContext(ctx, d.storageId, filter)
+	if err != nil {
+		return nil, err
+	}
+	return d.FromGbo(bo)
+}
+
+// List fetches values matching the filter. See IGenericDao.GdaoFetchMany.
+func (d *TypedDao[T]) List(ctx context.Context, filter FilterOpt, sorting *SortingOpt, startOffset, numItems int) ([]*T, error) {
+	boList, err := d.dao.GdaoFetchManyWithContext(ctx, d.storageId, filter, sorting, startOffset, numItems)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*T, 0, len(boList))
+	for _, bo := range boList {
+		v, err := d.FromGbo(bo)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, v)
+	}
+	return result, nil
+}
+
+func (d *TypedDao[T]) write(ctx context.Context, v *T, f func(ctx context.Context, storageId string, bo IGenericBo) (int, error)) (int, error) {
+	bo, err := d.ToGbo(v)
+	if err != nil {
+		return 0, err
+	}
+	numRows, err := f(ctx, d.storageId, bo)
+	if err == nil {
+		err = GboToStruct(bo, v)
+	}
+	return numRows, err
+}
+
+// Create persists a new value and returns the number of saved items. See IGenericDao.GdaoCreate.
+func (d *TypedDao[T]) Create(ctx context.Context, v *T) (int, error) {
+	return d.write(ctx, v, d.dao.GdaoCreateWithContext)
+}
+
+// Update updates an existing value and returns the number of updated items. See IGenericDao.GdaoUpdate.
+func (d *TypedDao[T]) Update(ctx context.Context, v *T) (int, error) {
+	return d.write(ctx, v, d.dao.GdaoUpdateWithContext)
+}
+
+// Save creates or replaces a value and returns the number of saved items. See IGenericDao.GdaoSave.
+func (d *TypedDao[T]) Save(ctx context.Context, v *T) (int, error) {
+	return d.write(ctx, v, d.dao.GdaoSaveWithContext)
+}
+
+// Delete removes a value and returns the number of removed items. See IGenericDao.GdaoDelete.
+func (d *TypedDao[T]) Delete(ctx context.Context, v *T) (int, error) {
+	bo, err := d.ToGbo(v)
+	if err != nil {
+		return 0, err
+	}
+	return d.dao.GdaoDeleteWithContext(ctx, d.storageId, bo)
+}
+
+/*----------------------------------------------------------------------*/
+
+// StructToGbo converts a struct (or a pointer to struct) to IGenericBo, using the field mapping rules of TypedDao.
+//
+// Available since v0.7.0
+func StructToGbo(v interface{}) (IGenericBo, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("cannot convert value of type %T to BO: struct expected", v)
+	}
+	data, err := structToMap(rv)
+	if err != nil {
+		return nil, err
+	}
+	bo := NewGenericBo()
+	return bo, bo.GboImportViaMap(data)
+}
+
+// GboToStruct populates a struct (dest must be a pointer to struct) from an IGenericBo, using the field mapping rules
+// of TypedDao. Scalar values are converted to the fields' types (e.g. float64 to int, unix timestamp to time.Time).
+//
+// Available since v0.7.0
+func GboToStruct(bo IGenericBo, dest interface{}) error {
+	rv := reflect.ValueOf(dest)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("cannot convert BO to value of type %T: pointer to struct expected", dest)
+	}
+	data := make(map[string]interface{})
+	bo.GboIterate(func(kind reflect.Kind, field interface{}, value interface{}) {
+		if kind == reflect.Map {
+			data[fmt.Sprint(field)] = value
+		}
+	})
+	return mapToStruct(data, rv.Elem())
+}
+
+type structField struct {
+	name      string
+	index     []int
+	omitEmpty bool
+}
+
+// structCodecs caches field mappings of struct types: reflect.Type -> []structField
+var structCodecs sync.Map
+
+func structFields(typ reflect.Type) []structField {
+	if cached, ok := structCodecs.Load(typ); ok {
+		return cached.([]structField)
+	}
+	fields := collectStructFields(typ, nil, map[string]bool{})
+	structCodecs.Store(typ, fields)
+	return fields
+}
+
+// collectStructFields collects fields of a struct type; fields of embedded structs are promoted unless shadowed by
+// fields of the embedding struct.
+func collectStructFields(typ reflect.Type, index []int, seen map[string]bool) []structField {
+	var fields []structField
+	var embedded []reflect.StructField
+	for i := 0; i < typ.NumField(); i++ {
+		sf := typ.Field(i)
+		tag := sf.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		ft := sf.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if sf.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			embedded = append(embedded, sf)
+			continue
+		}
+		if !sf.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		fields = append(fields, structField{
+			name:      name,
+			index:     append(append([]int{}, index...), i),
+			omitEmpty: strings.Contains(","+opts+",", ",omitempty,"),
+		})
+	}
+	for _, sf := range embedded {
+		ft := sf.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		fields = append(fields, collectStructFields(ft, append(append([]int{}, index...), sf.Index[0]), seen)...)
+	}
+	return fields
+}
+
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64, reflect.Interface, reflect.Ptr:
+		return v.IsZero()
+	}
+	return false
+}
+
+func structToMap(v reflect.Value) (map[string]interface{}, error) {
+	result := make(map[string]interface{})
+	for _, f := range structFields(v.Type()) {
+		fv, err := v.FieldByIndexErr(f.index)
+		if err != nil {
+			// field of a nil embedded pointer
+			continue
+		}
+		if f.omitEmpty && isEmptyValue(fv) {
+			continue
+		}
+		value, err := toGboValue(fv)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", f.name, err)
+		}
+		result[f.name] = value
+	}
+	return result, nil
+}
+
+// toGboValue converts a Go value to a value to be stored in a BO.
+func toGboValue(v reflect.Value) (interface{}, error) {
+	switch v.Kind() {
+	case reflect.Invalid:
+		return nil, nil
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil, nil
+		}
+		return toGboValue(v.Elem())
+	case reflect.Struct:
+		if v.Type() == reddo.TypeTime {
+			return v.Interface(), nil
+		}
+		return structToMap(v)
+	case reflect.Slice:
+		if v.IsNil() {
+			return nil, nil
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return append([]byte{}, v.Bytes()...), nil
+		}
+		fallthrough
+	case reflect.Array:
+		result := make([]interface{}, v.Len())
+		for i := range result {
+			item, err := toGboValue(v.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			result[i] = item
+		}
+		return result, nil
+	case reflect.Map:
+		if v.IsNil() {
+			return nil, nil
+		}
+		result := make(map[string]interface{}, v.Len())
+		for iter := v.MapRange(); iter.Next(); {
+			item, err := toGboValue(iter.Value())
+			if err != nil {
+				return nil, err
+			}
+			result[fmt.Sprint(iter.Key().Interface())] = item
+		}
+		return result, nil
+	case reflect.Bool:
+		return v.Bool(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint(), nil
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), nil
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Chan, reflect.Func, reflect.UnsafePointer, reflect.Complex64, reflect.Complex128:
+		return nil, fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return v.Interface(), nil
+}
+
+func mapToStruct(data map[string]interface{}, v reflect.Value) error {
+	for _, f := range structFields(v.Type()) {
+		src, ok := data[f.name]
+		if !ok {
+			continue
+		}
+		fv := v
+		for i, idx := range f.index {
+			if i > 0 && fv.Kind() == reflect.Ptr {
+				if fv.IsNil() {
+					fv.Set(reflect.New(fv.Type().Elem()))
+				}
+				fv = fv.Elem()
+			}
+			fv = fv.Field(idx)
+		}
+		if err := fromGboValue(src, fv); err != nil {
+			return fmt.Errorf("field %s: %w", f.name, err)
+		}
+	}
+	return nil
+}
+
+// fromGboValue converts a value stored in a BO and assigns it to a Go value.
+func fromGboValue(src interface{}, dst reflect.Value) error {
+	if src == nil {
+		dst.Set(reflect.Zero(dst.Type()))
+		return nil
+	}
+	srcV := reflect.ValueOf(src)
+	if srcV.Type().AssignableTo(dst.Type()) && dst.Kind() != reflect.Map && dst.Kind() != reflect.Slice {
+		dst.Set(srcV)
+		return nil
+	}
+	switch dst.Kind() {
+	case reflect.Ptr:
+		elem := reflect.New(dst.Type().Elem())
+		if err := fromGboValue(src, elem.Elem()); err != nil {
+			return err
+		}
+		dst.Set(elem)
+		return nil
+	case reflect.Struct:
+		if dst.Type() == reddo.TypeTime {
+			t, err := toTime(src)
+			if err == nil {
+				dst.Set(reflect.ValueOf(t))
+			}
+			return err
+		}
+		if m, ok := src.(map[string]interface{}); ok {
+			return mapToStruct(m, dst)
+		}
+		return unmarshalJsonValue(src, dst)
+	case reflect.Slice, reflect.Array:
+		if dst.Kind() == reflect.Slice && dst.Type().Elem().Kind() == reflect.Uint8 {
+			switch s := src.(type) {
+			case []byte:
+				dst.SetBytes(append([]byte{}, s...))
+				return nil
+			case string:
+				dst.SetBytes([]byte(s))
+				return nil
+			}
+		}
+		if srcV.Kind() != reflect.Slice && srcV.Kind() != reflect.Array {
+			return unmarshalJsonValue(src, dst)
+		}
+		n := srcV.Len()
+		if dst.Kind() == reflect.Slice {
+			dst.Set(reflect.MakeSlice(dst.Type(), n, n))
+		} else if n > dst.Len() {
+			n = dst.Len()
+		}
+		for i := 0; i < n; i++ {
+			if err := fromGboValue(srcV.Index(i).Interface(), dst.Index(i)); err != nil {
+				return fmt.Errorf("index %d: %w", i, err)
+			}
+		}
+		return nil
+	case reflect.Map:
+		if srcV.Kind() != reflect.Map {
+			return unmarshalJsonValue(src, dst)
+		}
+		result := reflect.MakeMapWithSize(dst.Type(), srcV.Len())
+		for iter := srcV.MapRange(); iter.Next(); {
+			key := reflect.New(dst.Type().Key()).Elem()
+			if err := fromGboValue(iter.Key().Interface(), key); err != nil {
+				return fmt.Errorf("key %v: %w", iter.Key().Interface(), err)
+			}
+			value := reflect.New(dst.Type().Elem()).Elem()
+			if err := fromGboValue(iter.Value().Interface(), value); err != nil {
+				return fmt.Errorf("key %v: %w", iter.Key().Interface(), err)
+			}
+			result.SetMapIndex(key, value)
+		}
+		dst.Set(result)
+		return nil
+	case reflect.Bool:
+		b, err := reddo.ToBool(src)
+		if err == nil {
+			dst.SetBool(b)
+		}
+		return err
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := reddo.ToInt(src)
+		if err == nil && dst.OverflowInt(i) {
+			err = fmt.Errorf("value %v overflows %s", src, dst.Type())
+		}
+		if err == nil {
+			dst.SetInt(i)
+		}
+		return err
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		u, err := reddo.ToUint(src)
+		if err == nil && dst.OverflowUint(u) {
+			err = fmt.Errorf("value %v overflows %s", src, dst.Type())
+		}
+		if err == nil {
+			dst.SetUint(u)
+		}
+		return err
+	case reflect.Float32, reflect.Float64:
+		f, err := reddo.ToFloat(src)
+		if err == nil {
+			dst.SetFloat(f)
+		}
+		return err
+	case reflect.String:
+		if b, ok := src.([]byte); ok {
+			// some drivers return text columns as []byte
+			dst.SetString(string(b))
+			return nil
+		}
+		s, err := reddo.ToString(src)
+		if err == nil {
+			dst.SetString(s)
+		}
+		return err
+	case reflect.Interface:
+		if srcV.Type().Implements(dst.Type()) {
+			dst.Set(srcV)
+			return nil
+		}
+	}
+	if srcV.Type().ConvertibleTo(dst.Type()) {
+		dst.Set(srcV.Convert(dst.Type()))
+		return nil
+	}
+	return fmt.Errorf("cannot convert value of type %T to %s", src, dst.Type())
+}
+
+// toTime converts a value to time.Time; strings are parsed as RFC 3339 (the format encoding/json uses) before
+// falling back to reddo.ToTime.
+func toTime(src interface{}) (time.Time, error) {
+	switch v := src.(type) {
+	case *time.Time:
+		return *v, nil
+	case string:
+		if t, err := time.Parse(time.RFC3339Nano, v); err == nil {
+			return t, nil
+		}
+	}
+	return reddo.ToTime(src)
+}
+
+// unmarshalJsonValue handles nested values that the database store returns as JSON strings (e.g. a TEXT column).
+func unmarshalJsonValue(src interface{}, dst reflect.Value) error {
+	var js []byte
+	switch v := src.(type) {
+	case string:
+		js = []byte(v)
+	case []byte:
+		js = v
+	default:
+		return fmt.Errorf("cannot convert value of type %T to %s", src, dst.Type())
+	}
+	return json.Unmarshal(js, dst.Addr().Interface())
+}
@@ -0,0 +1,151 @@
+package godal
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+type typedAddress struct {
+	City string `json:"city"`
+	Zip  *int   `json:"zip,omitempty"`
+}
+
+type typedBase struct {
+	Id      string    `json:"id"`
+	Created time.Time `json:"created"`
+}
+
+type typedUser struct {
+	typedBase
+	Name     string         `json:"name"`
+	Age      uint8          `json:"age,omitempty"`
+	Score    float32        `json:"score"`
+	Active   bool           `json:"active"`
+	Tags     []string       `json:"tags"`
+	Labels   map[string]int `json:"labels"`
+	Address  *typedAddress  `json:"address"`
+	History  []typedAddress `json:"history,omitempty"`
+	Raw      []byte         `json:"raw"`
+	Any      interface{}    `json:"any"`
+	Secret   string         `json:"-"`
+	internal string
+	Extra    map[string]string `json:",omitempty"`
+}
+
+// idGeneratingDao generates the "id" field of created BOs.
+type idGeneratingDao struct {
+	*mockGenericDao
+}
+
+func (dao *idGeneratingDao) GdaoCreate(storageId string, bo IGenericBo) (int, error) {
+	if bo.GboGetAttrUnsafe("id", nil) == "" {
+		bo.GboSetAttr("id", "generated")
+	}
+	return dao.mockGenericDao.GdaoCreate(storageId, bo)
+}
+
+func TestStructToGbo(t *testing.T) {
+	name := "TestStructToGbo"
+	zip := 12345
+	now := time.Now()
+	u := &typedUser{
+		typedBase: typedBase{Id: "1", Created: now},
+		Name:      "Thanh", Tags: []string{"a", "b"}, Labels: map[string]int{"x": 1},
+		Address: &typedAddress{City: "HCM", Zip: &zip}, Raw: []byte("raw"), Secret: "s",
+	}
+	bo, err := StructToGbo(u)
+	if err != nil {
+		t.Fatalf("%s failed: %e", name, err)
+	}
+	expected := map[string]interface{}{
+		"id": "1", "created": now, "name": "Thanh", "score": float64(0), "active": false,
+		"tags": []interface{}{"a", "b"}, "labels": map[string]interface{}{"x": int64(1)},
+		"address": map[string]interface{}{"city": "HCM", "zip": int64(12345)},
+		"raw":     []byte("raw"), "any": nil,
+	}
+	data := make(map[string]interface{})
+	bo.GboIterate(func(_ reflect.Kind, field interface{}, value interface{}) { data[field.(string)] = value })
+	if !reflect.DeepEqual(data, expected) {
+		t.Fatalf("%s failed: expected %#v but received %#v", name, expected, data)
+	}
+	if _, err := StructToGbo("not a struct"); err == nil {
+		t.Fatalf("%s failed: non-struct value should be rejected", name)
+	}
+}
+
+func TestGboToStruct(t *testing.T) {
+	name := "TestGboToStruct"
+	bo := NewGenericBo()
+	bo.GboFromJson([]byte(`{"id":"1","created":"2024-01-02T03:04:05Z","name":"Thanh","age":30,"score":1.5,"active":true,
+		"tags":["a"],"labels":{"x":1},"address":{"city":"HCM","zip":123},"history":"[{\"city\":\"HN\"}]","raw":"raw","any":{"k":"v"},"Secret":"s"}`))
+	var u typedUser
+	if err := GboToStruct(bo, &u); err != nil {
+		t.Fatalf("%s failed: %e", name, err)
+	}
+	zip := 123
+	expected := typedUser{
+		typedBase: typedBase{Id: "1", Created: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)},
+		Name:      "Thanh", Age: 30, Score: 1.5, Active: true, Tags: []string{"a"}, Labels: map[string]int{"x": 1},
+		Address: &typedAddress{City: "HCM", Zip: &zip}, History: []typedAddress{{City: "HN"}}, Raw: []byte("raw"),
+		Any: map[string]interface{}{"k": "v"},
+	}
+	if !reflect.DeepEqual(u, expected) {
+		t.Fatalf("%s failed: expected %#v but received %#v", name, expected, u)
+	}
+
+	bo.GboSetAttr("age", 1000)
+	if err := GboToStruct(bo, &u); err == nil {
+		t.Fatalf("%s failed: overflow should be reported", name)
+	}
+	if err := GboToStruct(bo, u); err == nil {
+		t.Fatalf("%s failed: non-pointer destination should be rejected", name)
+	}
+}
+
+func TestTypedDao(t *testing.T) {
+	name := "TestTypedDao"
+	mock := newMockGenericDao()
+	dao := NewTypedDao[typedUser](&idGeneratingDao{mock}, "users")
+	if dao.GetDao() == nil || dao.GetStorageId() != "users" {
+		t.Fatalf("%s failed: %#v", name, dao)
+	}
+
+	u := &typedUser{typedBase: typedBase{Created: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)}, Name: "Thanh", Age: 30}
+	if numRows, err := dao.Create(nil, u); numRows != 1 || err != nil {
+		t.Fatalf("%s failed: %#v / %#v", name, numRows, err)
+	}
+	if u.Id != "generated" {
+		t.Fatalf("%s failed: changes made by the DAO should be reflected, received %#v", name, u.Id)
+	}
+
+	filter := &FilterOptFieldOpValue{FieldName: "id", Operator: FilterOpEqual, Value: "generated"}
+	fetched, err := dao.Get(nil, filter)
+	if err != nil || fetched == nil || fetched.Name != "Thanh" || fetched.Age != 30 || !fetched.Created.Equal(u.Created) {
+		t.Fatalf("%s failed: %#v / %#v", name, fetched, err)
+	}
+
+	u.Name = "Nguyen"
+	if numRows, err := dao.Update(nil, u); numRows != 1 || err != nil {
+		t.Fatalf("%s failed: %#v / %#v", name, numRows, err)
+	}
+	if numRows, err := dao.Save(nil, &typedUser{typedBase: typedBase{Id: "2"}, Name: "Other"}); numRows != 1 || err != nil {
+		t.Fatalf("%s failed: %#v / %#v", name, numRows, err)
+	}
+	list, err := dao.List(nil, nil, nil, 0, 0)
+	if err != nil || len(list) != 2 {
+		t.Fatalf("%s failed: %#v / %#v", name, list, err)
+	}
+	for _, item := range list {
+		if item.Id == "generated" && item.Name != "Nguyen" {
+			t.Fatalf("%s failed: %#v", name, item)
+		}
+	}
+
+	if numRows, err := dao.Delete(nil, u); numRows != 1 || err != nil {
+		t.Fatalf("%s failed: %#v / %#v", name, numRows, err)
+	}
+	if fetched, err := dao.Get(nil, filter); fetched != nil || err != nil {
+		t.Fatalf("%s failed: %#v / %#v", name, fetched, err)
+	}
+}
@@ -0,0 +1,339 @@
+package godal
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Data types of Schema.
+//
+// Available since v0.7.0
+const (
+	SchemaTypeString  = "string"
+	SchemaTypeNumber  = "number"
+	SchemaTypeInteger = "integer"
+	SchemaTypeBoolean = "boolean"
+	SchemaTypeObject  = "object"
+	SchemaTypeArray   = "array"
+)
+
+// Schema describes the rules a BO (or a value inside it) must satisfy. It is a practical subset of JSON Schema and can
+// be unmarshalled from JSON Schema documents using the same keywords.
+//
+// Available since v0.7.0
+type Schema struct {
+	Type     string        `json:"type,omitempty"`     // one of the SchemaTypeXXX constants, empty means any type
+	Nullable bool          `json:"nullable,omitempty"` // if true, null is accepted regardless of other rules
+	Enum     []interface{} `json:"enum,omitempty"`     // allowed values
+
+	MinLength *int   `json:"minLength,omitempty"` // minimum length of a string, in characters
+	MaxLength *int   `json:"maxLength,omitempty"` // maximum length of a string, in characters
+	Pattern   string `json:"pattern,omitempty"`   // regular expression a string must match
+
+	Minimum *float64 `json:"minimum,omitempty"` // minimum value of a number (inclusive)
+	Maximum *float64 `json:"maximum,omitempty"` // maximum value of a number (inclusive)
+
+	Required             []string           `json:"required,omitempty"`             // fields an object must have
+	Properties           map[string]*Schema `json:"properties,omitempty"`           // rules of an object's fields
+	AdditionalProperties *bool              `json:"additionalProperties,omitempty"` // if false, fields not listed in Properties are rejected
+
+	Items    *Schema `json:"items,omitempty"`    // rules of an array's elements
+	MinItems *int    `json:"minItems,omitempty"` // minimum number of array elements
+	MaxItems *int    `json:"maxItems,omitempty"` // maximum number of array elements
+
+	re *regexp.Regexp
+}
+
+// Compile checks the schema (and its nested schemas) for errors and pre-compiles regular expressions.
+// Validate compiles patterns lazily if needed, but is not safe to be called concurrently on a schema that has not
+// been compiled.
+func (s *Schema) Compile() error {
+	return s.compile("")
+}
+
+func (s *Schema) compile(path string) error {
+	switch s.Type {
+	case "", SchemaTypeString, SchemaTypeNumber, SchemaTypeInteger, SchemaTypeBoolean, SchemaTypeObject, SchemaTypeArray:
+	default:
+		return fmt.Errorf("schema at [%s]: invalid type %#v", path, s.Type)
+	}
+	if s.Pattern != "" && s.re == nil {
+		re, err := regexp.Compile(s.Pattern)
+		if err != nil {
+			return fmt.Errorf("schema at [%s]: invalid pattern: %w", path, err)
+		}
+		s.re = re
+	}
+	for name, prop := range s.Properties {
+		if prop == nil {
+			return fmt.Errorf("schema at [%s]: nil schema for property %#v", path, name)
+		}
+		if err := prop.compile(joinPath(path, name)); err != nil {
+			return err
+		}
+	}
+	if s.Items != nil {
+		return s.Items.compile(path + "[]")
+	}
+	return nil
+}
+
+// FieldViolation describes a rule violated by the value at a path.
+//
+// Available since v0.7.0
+type FieldViolation struct {
+	Path    string // path of the offending value, in the format accepted by IGenericBo.GboGetAttr (empty for the BO itself)
+	Rule    string // the violated rule, e.g. "required", "type", "pattern"
+	Message string // human-readable description
+}
+
+// ValidationError is returned by write operations when the BO does not satisfy the storage's schema.
+//
+// Available since v0.7.0
+type ValidationError struct {
+	StorageId  string
+	Violations []FieldViolation
+}
+
+// Error implements error.Error.
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		msgs[i] = fmt.Sprintf("[%s] %s", v.Path, v.Message)
+	}
+	return fmt.Sprintf("validation failed for storage [%s]: %s", e.StorageId, strings.Join(msgs, "; "))
+}
+
+// Paths returns the paths of offending values.
+func (e *ValidationError) Paths() []string {
+	paths := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		paths[i] = v.Path
+	}
+	return paths
+}
+
+func joinPath(path, field string) string {
+	if path == "" {
+		return field
+	}
+	return path + "." + field
+}
+
+// Validate validates a JSON-compatible value (as produced by encoding/json) against the schema and returns the list
+// of violations.
+func (s *Schema) Validate(data interface{}) []FieldViolation {
+	return s.validate("", data, nil)
+}
+
+func jsonType(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return SchemaTypeString
+	case float64:
+		return SchemaTypeNumber
+	case bool:
+		return SchemaTypeBoolean
+	case map[string]interface{}:
+		return SchemaTypeObject
+	case []interface{}:
+		return SchemaTypeArray
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+func (s *Schema) validate(path string, data interface{}, result []FieldViolation) []FieldViolation {
+	violate := func(rule, format string, args ...interface{}) {
+		result = append(result, FieldViolation{Path: path, Rule: rule, Message: fmt.Sprintf(format, args...)})
+	}
+	if data == nil {
+		if !s.Nullable && s.Type != "" {
+			violate("type", "expected %s but received null", s.Type)
+		}
+		return result
+	}
+
+	actualType := jsonType(data)
+	switch {
+	case s.Type == "" || s.Type == actualType:
+	case s.Type == SchemaTypeInteger && actualType == SchemaTypeNumber:
+		if f := data.(float64); f != math.Trunc(f) {
+			violate("type", "expected integer but received %v", f)
+			return result
+		}
+	default:
+		violate("type", "expected %s but received %s", s.Type, actualType)
+		return result
+	}
+
+	if len(s.Enum) > 0 {
+		found := false
+		for _, e := range s.Enum {
+			if enumEquals(e, data) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			violate("enum", "value %v is not one of %v", data, s.Enum)
+		}
+	}
+
+	switch v := data.(type) {
+	case string:
+		n := utf8.RuneCountInString(v)
+		if s.MinLength != nil && n < *s.MinLength {
+			violate("minLength", "length %d is less than %d", n, *s.MinLength)
+		}
+		if s.MaxLength != nil && n > *s.MaxLength {
+			violate("maxLength", "length %d is greater than %d", n, *s.MaxLength)
+		}
+		if s.Pattern != "" && s.re == nil {
+			if err := s.compile(path); err != nil {
+				violate("pattern", "%s", err)
+				break
+			}
+		}
+		if s.re != nil && !s.re.MatchString(v) {
+			violate("pattern", "value %#v does not match pattern %s", v, s.Pattern)
+		}
+	case float64:
+		if s.Minimum != nil && v < *s.Minimum {
+			violate("minimum", "value %v is less than %v", v, *s.Minimum)
+		}
+		if s.Maximum != nil && v > *s.Maximum {
+			violate("maximum", "value %v is greater than %v", v, *s.Maximum)
+		}
+	case map[string]interface{}:
+		for _, field := range s.Required {
+			if _, ok := v[field]; !ok {
+				result = append(result, FieldViolation{Path: joinPath(path, field), Rule: "required", Message: "field is required"})
+			}
+		}
+		fields := make([]string, 0, len(v))
+		for field := range v {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+		for _, field := range fields {
+			if prop, ok := s.Properties[field]; ok {
+				result = prop.validate(joinPath(path, field), v[field], result)
+			} else if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+				result = append(result, FieldViolation{Path: joinPath(path, field), Rule: "additionalProperties", Message: "field is not allowed"})
+			}
+		}
+	case []interface{}:
+		if s.MinItems != nil && len(v) < *s.MinItems {
+			violate("minItems", "number of items %d is less than %d", len(v), *s.MinItems)
+		}
+		if s.MaxItems != nil && len(v) > *s.MaxItems {
+			violate("maxItems", "number of items %d is greater than %d", len(v), *s.MaxItems)
+		}
+		if s.Items != nil {
+			for i, item := range v {
+				result = s.Items.validate(fmt.Sprintf("%s[%d]", path, i), item, result)
+			}
+		}
+	}
+	return result
+}
+
+// enumEquals compares an enum value (which may be of any Go type) with a JSON-compatible value.
+func enumEquals(enum, data interface{}) bool {
+	if js, err := json.Marshal(enum); err == nil {
+		var e interface{}
+		if json.Unmarshal(js, &e) == nil {
+			return reflect.DeepEqual(e, data)
+		}
+	}
+	return false
+}
+
+// ValidateBo validates a BO against a schema. It returns a *ValidationError if the BO violates the schema.
+//
+// Available since v0.7.0
+func ValidateBo(storageId string, schema *Schema, bo IGenericBo) error {
+	var data interface{}
+	if err := bo.GboTransferViaJson(&data); err != nil {
+		return err
+	}
+	if data == nil {
+		// an empty BO is an empty object
+		data = map[string]interface{}{}
+	}
+	if violations := schema.Validate(data); len(violations) > 0 {
+		return &ValidationError{StorageId: storageId, Violations: violations}
+	}
+	return nil
+}
+
+// GetSchema returns the schema attached to a storage, nil if none.
+//
+// Available since v0.7.0
+func (dao *AbstractGenericDao) GetSchema(storageId string) *Schema {
+	return dao.schemas[storageId]
+}
+
+// SetSchema attaches a schema to a storage: BOs written to the storage (GdaoCreate, GdaoUpdate and GdaoSave) are
+// validated against the schema after id generation and auto-fields are applied. Writes of invalid BOs are rejected
+// with a *ValidationError.
+//
+// Note: GdaoUpdate validates the supplied BO as a whole, hence partial BOs must also satisfy the schema.
+//
+// Supply a nil schema to detach the current one. Schemas should be attached once when the DAO is initialized; this
+// function is not safe to call concurrently with write operations.
+//
+// Available since v0.7.0
+func (dao *AbstractGenericDao) SetSchema(storageId string, schema *Schema) error {
+	if schema == nil {
+		delete(dao.schemas, storageId)
+		return nil
+	}
+	if err := schema.Compile(); err != nil {
+		return err
+	}
+	if dao.schemas == nil {
+		dao.schemas = make(map[string]*Schema)
+	}
+	dao.schemas[storageId] = schema
+	return nil
+}
+
+// applySchema validates the BO against the storage's schema; fields listed in notRequired may be absent.
+func (dao *AbstractGenericDao) applySchema(storageId string, bo IGenericBo, notRequired ...string) error {
+	schema, ok := dao.schemas[storageId]
+	if !ok {
+		return nil
+	}
+	err := ValidateBo(storageId, schema, bo)
+	if verr, ok := err.(*ValidationError); ok && len(notRequired) > 0 {
+		violations := make([]FieldViolation, 0, len(verr.Violations))
+		for _, v := range verr.Violations {
+			if v.Rule != "required" || !containsString(notRequired, v.Path) {
+				violations = append(violations, v)
+			}
+		}
+		if len(violations) == 0 {
+			return nil
+		}
+		verr.Violations = violations
+	}
+	return err
+}
+
+func containsString(list []string, s string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
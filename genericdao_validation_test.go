@@ -0,0 +1,172 @@
+package godal
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func intPtr(v int) *int { return &v }
+
+func floatPtr(v float64) *float64 { return &v }
+
+func boolPtr(v bool) *bool { return &v }
+
+func testSchema() *Schema {
+	return &Schema{
+		Type:     SchemaTypeObject,
+		Required: []string{"id", "email", "profile"},
+		Properties: map[string]*Schema{
+			"id":     {Type: SchemaTypeString, MinLength: intPtr(2), MaxLength: intPtr(8)},
+			"email":  {Type: SchemaTypeString, Pattern: `^[^@]+@[^@]+$`},
+			"age":    {Type: SchemaTypeInteger, Minimum: floatPtr(0), Maximum: floatPtr(150)},
+			"status": {Type: SchemaTypeString, Enum: []interface{}{"active", "disabled"}},
+			"note":   {Type: SchemaTypeString, Nullable: true},
+			"profile": {
+				Type:                 SchemaTypeObject,
+				Required:             []string{"name"},
+				Properties:           map[string]*Schema{"name": {Type: SchemaTypeString}, "score": {Type: SchemaTypeNumber}},
+				AdditionalProperties: boolPtr(false),
+			},
+			"tags": {Type: SchemaTypeArray, MaxItems: intPtr(2), Items: &Schema{Type: SchemaTypeString, MinLength: intPtr(1)}},
+		},
+	}
+}
+
+func TestSchema_Compile(t *testing.T) {
+	name := "TestSchema_Compile"
+	if err := testSchema().Compile(); err != nil {
+		t.Fatalf("%s failed: %e", name, err)
+	}
+	for _, s := range []*Schema{
+		{Type: "text"},
+		{Pattern: "(unclosed"},
+		{Properties: map[string]*Schema{"a": {Items: &Schema{Pattern: "["}}}},
+		{Properties: map[string]*Schema{"a": nil}},
+	} {
+		if err := s.Compile(); err == nil {
+			t.Fatalf("%s failed: schema %#v should be rejected", name, s)
+		}
+	}
+}
+
+func TestSchema_Validate(t *testing.T) {
+	name := "TestSchema_Validate"
+	schema := testSchema()
+	valid := map[string]interface{}{
+		"id": "u1", "email": "a@b", "age": 30.0, "status": "active", "note": nil,
+		"profile": map[string]interface{}{"name": "A", "score": 1.5},
+		"tags":    []interface{}{"x", "y"},
+	}
+	if v := schema.Validate(valid); len(v) != 0 {
+		t.Fatalf("%s failed: %#v", name, v)
+	}
+
+	testCases := []struct {
+		data  map[string]interface{}
+		paths []string
+		rules []string
+	}{
+		{map[string]interface{}{}, []string{"id", "email", "profile"}, []string{"required", "required", "required"}},
+		{map[string]interface{}{"id": "u", "email": "ab", "profile": map[string]interface{}{"name": "A"}}, []string{"email", "id"}, []string{"pattern", "minLength"}},
+		{map[string]interface{}{"id": 1.0, "email": "a@b", "profile": map[string]interface{}{"name": "A"}}, []string{"id"}, []string{"type"}},
+		{map[string]interface{}{"id": "u1", "email": "a@b", "age": 1.5, "profile": map[string]interface{}{"name": "A"}}, []string{"age"}, []string{"type"}},
+		{map[string]interface{}{"id": "u1", "email": "a@b", "age": 200.0, "profile": map[string]interface{}{"name": "A"}}, []string{"age"}, []string{"maximum"}},
+		{map[string]interface{}{"id": "u1", "email": "a@b", "status": "x", "profile": map[string]interface{}{"name": "A"}}, []string{"status"}, []string{"enum"}},
+		{map[string]interface{}{"id": "u1", "email": nil, "profile": map[string]interface{}{"name": "A"}}, []string{"email"}, []string{"type"}},
+		{map[string]interface{}{"id": "u1", "email": "a@b", "profile": map[string]interface{}{"extra": true}}, []string{"profile.name", "profile.extra"}, []string{"required", "additionalProperties"}},
+		{map[string]interface{}{"id": "u1", "email": "a@b", "profile": map[string]interface{}{"name": "A"}, "tags": []interface{}{"a", "", 1.0}},
+			[]string{"tags", "tags[1]", "tags[2]"}, []string{"maxItems", "minLength", "type"}},
+	}
+	for i, tc := range testCases {
+		violations := schema.Validate(tc.data)
+		var paths, rules []string
+		for _, v := range violations {
+			paths = append(paths, v.Path)
+			rules = append(rules, v.Rule)
+		}
+		if !reflect.DeepEqual(paths, tc.paths) || !reflect.DeepEqual(rules, tc.rules) {
+			t.Fatalf("%s failed: case %d, expected %v/%v but received %v/%v", name, i, tc.paths, tc.rules, paths, rules)
+		}
+	}
+
+	if v := (&Schema{Type: SchemaTypeObject}).Validate([]interface{}{}); len(v) != 1 || v[0].Path != "" {
+		t.Fatalf("%s failed: %#v", name, v)
+	}
+}
+
+func TestSchema_UnmarshalJson(t *testing.T) {
+	name := "TestSchema_UnmarshalJson"
+	js := `{"type":"object","required":["id"],"properties":{"id":{"type":"string","pattern":"^[a-z]+$"},"level":{"type":"integer","enum":[1,2,3]}}}`
+	var schema Schema
+	if err := json.Unmarshal([]byte(js), &schema); err != nil {
+		t.Fatalf("%s failed: %e", name, err)
+	}
+	if v := schema.Validate(map[string]interface{}{"id": "abc", "level": 2.0}); len(v) != 0 {
+		t.Fatalf("%s failed: %#v", name, v)
+	}
+	if v := schema.Validate(map[string]interface{}{"id": "ABC", "level": 4.0}); len(v) != 2 {
+		t.Fatalf("%s failed: %#v", name, v)
+	}
+}
+
+func TestValidateBo(t *testing.T) {
+	name := "TestValidateBo"
+	schema := &Schema{
+		Type:       SchemaTypeObject,
+		Required:   []string{"id", "created"},
+		Properties: map[string]*Schema{"id": {Type: SchemaTypeInteger}, "created": {Type: SchemaTypeString}},
+	}
+	bo := NewGenericBo()
+	bo.GboSetAttr("id", int64(1))
+	bo.GboSetAttr("created", time.Now())
+	if err := ValidateBo("test", schema, bo); err != nil {
+		t.Fatalf("%s failed: %e", name, err)
+	}
+
+	bo = NewGenericBo()
+	bo.GboSetAttr("id", "1")
+	err := ValidateBo("test", schema, bo)
+	var verr *ValidationError
+	if !errors.As(err, &verr) || verr.StorageId != "test" || !reflect.DeepEqual(verr.Paths(), []string{"created", "id"}) {
+		t.Fatalf("%s failed: %#v", name, err)
+	}
+	if verr.Error() == "" {
+		t.Fatalf("%s failed: empty error message", name)
+	}
+}
+
+func TestAbstractGenericDao_SetSchema(t *testing.T) {
+	name := "TestAbstractGenericDao_SetSchema"
+	dao := NewAbstractGenericDao(nil)
+	if err := dao.SetSchema("test", &Schema{Pattern: "("}); err == nil {
+		t.Fatalf("%s failed: invalid schema should be rejected", name)
+	}
+	schema := &Schema{
+		Type:       SchemaTypeObject,
+		Required:   []string{"id", "created"},
+		Properties: map[string]*Schema{"id": {Type: SchemaTypeString, MinLength: intPtr(26)}},
+	}
+	if err := dao.SetSchema("test", schema); err != nil || dao.GetSchema("test") != schema {
+		t.Fatalf("%s failed: %e", name, err)
+	}
+
+	// id and auto-fields are applied before validation
+	err := dao.PrepareBoForWrite(nil, "test", NewGenericBo(), WriteOpInsert)
+	var verr *ValidationError
+	if !errors.As(err, &verr) || !reflect.DeepEqual(verr.Paths(), []string{"id", "created"}) {
+		t.Fatalf("%s failed: %#v", name, err)
+	}
+	dao.SetIdGenerator("test", "id", IdGeneratorUlid)
+	dao.SetAutoFields("test", AutoFields{CreatedAt: "created"})
+	if err := dao.PrepareBoForWrite(nil, "test", NewGenericBo(), WriteOpInsert); err != nil {
+		t.Fatalf("%s failed: %e", name, err)
+	}
+
+	dao.SetSchema("test", nil)
+	if dao.GetSchema("test") != nil {
+		t.Fatalf("%s failed: schema should have been detached", name)
+	}
+}
@@ -113,13 +113,17 @@ package mongo
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"reflect"
 	"regexp"
+	"strconv"
+	"strings"
 
 	"github.com/btnguyen2k/consu/reddo"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	mongodrv "go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"go.mongodb.org/mongo-driver/mongo/readconcern"
@@ -132,10 +136,10 @@ import (
 // GenericRowMapperMongo is a generic implementation of godal.IRowMapper for MongoDB.
 //
 // Implementation rules:
-//   - ToRow        : transform godal.IGenericBo "as-is" to map[string]interface{}.
-//   - ToBo         : expect input is a map[string]interface{}, or JSON data (string or array/slice of bytes), transforms input to godal.IGenericBo via JSON unmarshalling.
+//   - ToRow        : transform godal.IGenericBo "as-is" to map[string]interface{}. Since v0.7.0, arbitrary-precision numbers (see godal.ExactNumberString) are written as Decimal128, except json.Number integers that fit int64.
+//   - ToBo         : expect input is a map[string]interface{}, or JSON data (string or array/slice of bytes), transforms input to godal.IGenericBo via JSON unmarshalling. Decimal128 values are read as {"$numberDecimal": "..."}, use GboGetDecimal to get their exact values.
 //   - ColumnsList  : return []string{"*"} (MongoDB is schema-free, hence column-list is not used).
-//   - ToDbColName  : return the input field name "as-is".
+//   - ToDbColName  : return the input field name "as-is", except that paths with array indexes or quoted segments are written in dot notation (e.g. "items[0].price" to "items.0.price").
 //   - ToBoFieldName: return the input column name "as-is".
 //
 // Available: since v0.0.2.
@@ -150,7 +154,20 @@ func (mapper *GenericRowMapperMongo) ToRow(collectionName string, bo godal.IGene
 		return nil, nil
 	}
 	result := make(map[string]interface{})
-	return result, bo.GboTransferViaJson(&result)
+	if err := bo.GboTransferViaJson(&result); err != nil {
+		return result, err
+	}
+	return result, godal.ReplaceExactNumbers(bo, result, toMongoNumber)
+}
+
+// toMongoNumber converts an arbitrary-precision number to Decimal128, or to int64 if it is a json.Number integer.
+func toMongoNumber(value interface{}, number string) (interface{}, error) {
+	if _, ok := value.(json.Number); ok {
+		if i, err := strconv.ParseInt(number, 10, 64); err == nil {
+			return i, nil
+		}
+	}
+	return primitive.ParseDecimal128(number)
 }
 
 // ToBo implements godal.IRowMapper.ToBo.
@@ -242,9 +259,26 @@ func (mapper *GenericRowMapperMongo) ColumnsList(_ string) []string {
 
 // ToDbColName implements godal.IRowMapper.ToDbColName.
 //
-// This function returns the input field name "as-is".
+// This function returns the input field name "as-is". Since v0.7.0, paths with array indexes or quoted segments are
+// written in MongoDB's dot notation, e.g. "items[0].price" to "items.0.price" and `labels["env"]` to "labels.env".
 func (mapper *GenericRowMapperMongo) ToDbColName(_, fieldName string) string {
-	return fieldName
+	if !strings.ContainsAny(fieldName, `["'`) {
+		return fieldName
+	}
+	p, err := godal.ParsePath(fieldName)
+	if err != nil {
+		return fieldName
+	}
+	segs := p.Segments()
+	parts := make([]string, len(segs))
+	for i, seg := range segs {
+		if seg.Index >= 0 {
+			parts[i] = strconv.Itoa(seg.Index)
+		} else {
+			parts[i] = seg.Key
+		}
+	}
+	return strings.Join(parts, ".")
 }
 
 // ToBoFieldName implements godal.IRowMapper.ToBoFieldName.
@@ -281,6 +315,8 @@ func NewGenericDaoMongo(mongoConnect *mongo.MongoConnect, agdao *godal.AbstractG
 // 	 - (y) GdaoCreate(collectionName string, bo godal.IGenericBo) (int, error)
 // 	 - (y) GdaoUpdate(collectionName string, bo godal.IGenericBo) (int, error)
 // 	 - (y) GdaoSave(collectionName string, bo godal.IGenericBo) (int, error)
+//
+// Since v0.7.0, update, save and delete honor the write condition carried by the context (see godal.WithWriteCondition).
 type GenericDaoMongo struct {
 	*godal.AbstractGenericDao
 	mongoConnect  *mongo.MongoConnect
@@ -344,10 +380,41 @@ func translateOperator(op godal.FilterOperator) (string, error) {
 	return "", fmt.Errorf("cannot translate operator \"%#v\"", op)
 }
 
+// fieldExpr returns an aggregation expression that reads the field at 'fieldName' if the field can not be referred
+// to in dot notation (i.e. a quoted member name contains "." or starts with "$"), nil otherwise.
+func fieldExpr(fieldName string) interface{} {
+	if !strings.ContainsAny(fieldName, `"'`) {
+		return nil
+	}
+	p, err := godal.ParsePath(fieldName)
+	if err != nil {
+		return nil
+	}
+	segs, needed := p.Segments(), false
+	for _, seg := range segs {
+		needed = needed || (seg.Index < 0 && (strings.Contains(seg.Key, ".") || strings.HasPrefix(seg.Key, "$")))
+	}
+	if !needed {
+		return nil
+	}
+	var expr interface{} = "$$ROOT"
+	for _, seg := range segs {
+		if seg.Index >= 0 {
+			expr = bson.M{"$arrayElemAt": bson.A{expr, seg.Index}}
+		} else {
+			expr = bson.M{"$getField": bson.M{"field": bson.M{"$literal": seg.Key}, "input": expr}}
+		}
+	}
+	return expr
+}
+
 // BuildFilter transforms a godal.FilterOpt to MongoDB-compatible filter map.
 //
 // See MongoDB query selector (https://docs.mongodb.com/manual/reference/operator/query/#query-selectors).
 //
+// Since v0.7.0, fields whose quoted member names contain "." or start with "$" (e.g. `labels["example.com"]`) are
+// filtered with $expr and $getField, which requires MongoDB v5.0+.
+//
 // Available since v0.5.0
 func (dao *GenericDaoMongo) BuildFilter(collectionName string, filter godal.FilterOpt) (bson.M, error) {
 	if filter == nil {
@@ -365,6 +432,9 @@ func (dao *GenericDaoMongo) BuildFilter(collectionName string, filter godal.Filt
 	case *godal.FilterOptFieldOpValue:
 		f := filter.(*godal.FilterOptFieldOpValue)
 		opStr, err := translateOperator(f.Operator)
+		if expr := fieldExpr(f.FieldName); expr != nil {
+			return bson.M{"$expr": bson.M{opStr: bson.A{expr, f.Value}}}, err
+		}
 		result := bson.M{rm.ToDbColName(collectionName, f.FieldName): bson.M{opStr: f.Value}}
 		return result, err
 	case godal.FilterOptFieldIsNull:
@@ -372,6 +442,10 @@ func (dao *GenericDaoMongo) BuildFilter(collectionName string, filter godal.Filt
 		return dao.BuildFilter(collectionName, &f)
 	case *godal.FilterOptFieldIsNull:
 		f := filter.(*godal.FilterOptFieldIsNull)
+		if expr := fieldExpr(f.FieldName); expr != nil {
+			// missing fields compare less than null
+			return bson.M{"$expr": bson.M{"$lte": bson.A{expr, nil}}}, nil
+		}
 		result := bson.M{rm.ToDbColName(collectionName, f.FieldName): bson.M{"$eq": nil}}
 		return result, nil
 	case godal.FilterOptFieldIsNotNull:
@@ -379,6 +453,9 @@ func (dao *GenericDaoMongo) BuildFilter(collectionName string, filter godal.Filt
 		return dao.BuildFilter(collectionName, &f)
 	case *godal.FilterOptFieldIsNotNull:
 		f := filter.(*godal.FilterOptFieldIsNotNull)
+		if expr := fieldExpr(f.FieldName); expr != nil {
+			return bson.M{"$expr": bson.M{"$gt": bson.A{expr, nil}}}, nil
+		}
 		result := bson.M{rm.ToDbColName(collectionName, f.FieldName): bson.M{"$ne": nil}}
 		return result, nil
 	case godal.FilterOptAnd:
@@ -449,10 +526,17 @@ func (dao *GenericDaoMongo) MongoFetchMany(ctx context.Context, collectionName s
 	if sorting != nil && len(sorting.Fields) > 0 {
 		sortingInfo := bson.D{}
 		for _, field := range sorting.Fields {
+			if fieldExpr(field.FieldName) != nil {
+				return nil, fmt.Errorf("cannot sort by field %q: member names containing \".\" or starting with \"$\" are not supported", field.FieldName)
+			}
+			key := field.FieldName
+			if rm := dao.GetRowMapper(); rm != nil {
+				key = rm.ToDbColName(collectionName, field.FieldName)
+			}
 			if field.Descending {
-				sortingInfo = append(sortingInfo, bson.E{Key: field.FieldName, Value: -1})
+				sortingInfo = append(sortingInfo, bson.E{Key: key, Value: -1})
 			} else {
-				sortingInfo = append(sortingInfo, bson.E{Key: field.FieldName, Value: 1})
+				sortingInfo = append(sortingInfo, bson.E{Key: key, Value: 1})
 			}
 		}
 		opt.SetSort(sortingInfo)
@@ -512,7 +596,7 @@ func (dao *GenericDaoMongo) GdaoDelete(collectionName string, bo godal.IGenericB
 //
 // Available: since v0.1.0
 func (dao *GenericDaoMongo) GdaoDeleteWithContext(ctx context.Context, collectionName string, bo godal.IGenericBo) (int, error) {
-	filter := dao.GdaoCreateFilter(collectionName, bo)
+	filter := godal.ApplyWriteCondition(ctx, dao.GdaoCreateFilter(collectionName, bo))
 	return dao.GdaoDeleteManyWithContext(ctx, collectionName, filter)
 }
 
@@ -597,6 +681,27 @@ func isErrorDuplicatedKey(err error) bool {
 		regexp.MustCompile(`\WConflictingOperationInProgress\W`).FindString(err.Error()) != "" // CosmosDB's MongoDB API duplicated key error
 }
 
+// IsErrorTransient checks if the error is transient and the operation can be retried.
+//
+// An error is transient if it carries one of the labels "RetryableWriteError", "TransientTransactionError" or
+// "NetworkError", or if it is a timeout error.
+//
+// This function can be used as godal.TransientErrorClassifier.
+//
+// Available since v0.7.0
+func IsErrorTransient(err error) bool {
+	if err == nil {
+		return false
+	}
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		if le, ok := e.(mongodrv.LabeledError); ok &&
+			(le.HasErrorLabel("RetryableWriteError") || le.HasErrorLabel("TransientTransactionError") || le.HasErrorLabel("NetworkError")) {
+			return true
+		}
+	}
+	return mongodrv.IsTimeout(err)
+}
+
 func (dao *GenericDaoMongo) insertIfNotExist(ctx context.Context, collectionName string, bo godal.IGenericBo) (bool, error) {
 	// first fetch existing document from storage
 	filter := dao.GdaoCreateFilter(collectionName, bo)
@@ -649,6 +754,9 @@ func (dao *GenericDaoMongo) GdaoCreate(collectionName string, bo godal.IGenericB
 //
 // Available: since v0.1.0
 func (dao *GenericDaoMongo) GdaoCreateWithContext(ctx context.Context, collectionName string, bo godal.IGenericBo) (int, error) {
+	if err := dao.PrepareBoForWrite(ctx, collectionName, bo, godal.WriteOpInsert); err != nil {
+		return 0, err
+	}
 	ctx = dao.mongoConnect.NewContextIfNil(ctx)
 	if dao.txModeOnWrite {
 		numRows := 0
@@ -685,11 +793,14 @@ func (dao *GenericDaoMongo) GdaoUpdate(collectionName string, bo godal.IGenericB
 //
 // Available: since v0.1.0
 func (dao *GenericDaoMongo) GdaoUpdateWithContext(ctx context.Context, collectionName string, bo godal.IGenericBo) (int, error) {
+	if err := dao.PrepareBoForWrite(ctx, collectionName, bo, godal.WriteOpUpdate); err != nil {
+		return 0, err
+	}
 	doc, err := dao.GetRowMapper().ToRow(collectionName, bo)
 	if err != nil {
 		return 0, err
 	}
-	filter := dao.GdaoCreateFilter(collectionName, bo)
+	filter := godal.ApplyWriteCondition(ctx, dao.GdaoCreateFilter(collectionName, bo))
 	result := dao.MongoUpdateOne(dao.mongoConnect.NewContextIfNil(ctx), collectionName, filter, doc)
 	if _, err := result.DecodeBytes(); err == mongodrv.ErrNoDocuments {
 		return 0, nil
@@ -709,11 +820,14 @@ func (dao *GenericDaoMongo) GdaoSave(collectionName string, bo godal.IGenericBo)
 //
 // Available: since v0.1.0
 func (dao *GenericDaoMongo) GdaoSaveWithContext(ctx context.Context, collectionName string, bo godal.IGenericBo) (int, error) {
+	if err := dao.PrepareBoForWrite(ctx, collectionName, bo, godal.WriteOpUpsert); err != nil {
+		return 0, err
+	}
 	doc, err := dao.GetRowMapper().ToRow(collectionName, bo)
 	if err != nil {
 		return 0, err
 	}
-	filter := dao.GdaoCreateFilter(collectionName, bo)
+	filter := godal.ApplyWriteCondition(ctx, dao.GdaoCreateFilter(collectionName, bo))
 	result := dao.MongoSaveOne(dao.mongoConnect.NewContextIfNil(ctx), collectionName, filter, doc)
 	if err = result.Err(); err == nil || err == mongodrv.ErrNoDocuments {
 		return 1, nil
@@ -723,3 +837,11 @@ func (dao *GenericDaoMongo) GdaoSaveWithContext(ctx context.Context, collectionN
 	}
 	return 1, err
 }
+
+// ApplyStorageDescriptors configures the DAO with the descriptors of backend godal.BackendMongo: schemas are attached
+// to collections. MongoDB is schema-free, hence GenericRowMapperMongo needs no configuration.
+//
+// Available since v0.7.0
+func (dao *GenericDaoMongo) ApplyStorageDescriptors(descs *godal.StorageDescriptors) error {
+	return descs.ApplySchemas(godal.BackendMongo, dao.AbstractGenericDao)
+}
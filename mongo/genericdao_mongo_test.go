@@ -1,6 +1,8 @@
 package mongo
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"reflect"
@@ -12,7 +14,10 @@ import (
 	"github.com/btnguyen2k/consu/reddo"
 	"github.com/btnguyen2k/godal"
 	"github.com/btnguyen2k/prom/mongo"
+	"github.com/shopspring/decimal"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	mongodrv "go.mongodb.org/mongo-driver/mongo"
 )
 
 func _createMongoConnect(t *testing.T, testName string) *mongo.MongoConnect {
@@ -268,6 +273,38 @@ func TestGenericRowMapperMongo_ToBo(t *testing.T) {
 	}
 }
 
+func TestGenericRowMapperMongo_ToRow_ExactNumbers(t *testing.T) {
+	name := "TestGenericRowMapperMongo_ToRow_ExactNumbers"
+	rowmapper := &GenericRowMapperMongo{}
+	bo := godal.NewGenericBo()
+	bo.(*godal.GenericBo).GboSetUseNumber(true).GboFromJson([]byte(`{"qty":3,"price":19.99,"items":[{"amount":12345678901234567890.12}]}`))
+	bo.GboSetAttr("dec", decimal.RequireFromString("0.10"))
+	bo.GboSetAttr("stored", map[string]interface{}{"$numberDecimal": "1.5"})
+	bo.GboSetAttr("float", 2.5)
+	row, err := rowmapper.ToRow("table", bo)
+	if err != nil {
+		t.Fatalf("%s failed: %s", name, err)
+	}
+	m := row.(map[string]interface{})
+	if m["qty"] != int64(3) || m["float"] != 2.5 {
+		t.Fatalf("%s failed: %#v", name, m)
+	}
+	for path, expected := range map[string]string{"price": "19.99", "dec": "0.1", "stored": "1.5"} {
+		if v, ok := m[path].(primitive.Decimal128); !ok || v.String() != expected {
+			t.Fatalf("%s failed: %s, expected %s but received %#v", name, path, expected, m[path])
+		}
+	}
+	amount := m["items"].([]interface{})[0].(map[string]interface{})["amount"]
+	if v, ok := amount.(primitive.Decimal128); !ok || v.String() != "12345678901234567890.12" {
+		t.Fatalf("%s failed: %#v", name, amount)
+	}
+
+	bo.GboSetAttr("dec", decimal.RequireFromString("1234567890123456789012345678901234567890"))
+	if _, err := rowmapper.ToRow("table", bo); err == nil {
+		t.Fatalf("%s failed: decimal with more than 34 digits should be rejected", name)
+	}
+}
+
 func TestGenericRowMapperMongo_ToRow(t *testing.T) {
 	name := "TestGenericRowMapperMongo_ToRow"
 	table := "table"
@@ -315,6 +352,54 @@ func TestGenericRowMapperMongo_ToDbColName(t *testing.T) {
 	}
 }
 
+func TestGenericRowMapperMongo_ToDbColName_Path(t *testing.T) {
+	name := "TestGenericRowMapperMongo_ToDbColName_Path"
+	rowMapper := &GenericRowMapperMongo{}
+	testCases := []struct {
+		fieldName, expected string
+	}{
+		{"a.b", "a.b"},
+		{"items[0].price", "items.0.price"},
+		{`labels["env"].value`, "labels.env.value"},
+		{godal.Path("a").Index(1).Key("b").String(), "a.1.b"},
+		{"a[x]", "a[x]"},
+	}
+	for _, tc := range testCases {
+		if fieldName := rowMapper.ToDbColName("table", tc.fieldName); fieldName != tc.expected {
+			t.Fatalf("%s failed, expect %#v but received %#v", name, tc.expected, fieldName)
+		}
+	}
+}
+
+func TestGenericDaoMongo_BuildFilter_QuotedPath(t *testing.T) {
+	name := "TestGenericDaoMongo_BuildFilter_QuotedPath"
+	dao := NewGenericDaoMongo(nil, godal.NewAbstractGenericDao(nil))
+	field := godal.Path("labels").Key("example.com").Index(0).String()
+	expr := bson.M{"$arrayElemAt": bson.A{
+		bson.M{"$getField": bson.M{"field": bson.M{"$literal": "example.com"}, "input": bson.M{"$getField": bson.M{"field": bson.M{"$literal": "labels"}, "input": "$$ROOT"}}}},
+		0,
+	}}
+	testCases := []struct {
+		filter   godal.FilterOpt
+		expected bson.M
+	}{
+		{&godal.FilterOptFieldOpValue{FieldName: field, Operator: godal.FilterOpGreater, Value: 1}, bson.M{"$expr": bson.M{"$gt": bson.A{expr, 1}}}},
+		{godal.FilterOptFieldIsNull{FieldName: field}, bson.M{"$expr": bson.M{"$lte": bson.A{expr, nil}}}},
+		{godal.FilterOptFieldIsNotNull{FieldName: field}, bson.M{"$expr": bson.M{"$gt": bson.A{expr, nil}}}},
+		{&godal.FilterOptFieldOpValue{FieldName: `labels["env"][0]`, Operator: godal.FilterOpEqual, Value: 1}, bson.M{"labels.env.0": bson.M{"$eq": 1}}},
+	}
+	for i, tc := range testCases {
+		if output, err := dao.BuildFilter("table", tc.filter); err != nil || !reflect.DeepEqual(output, tc.expected) {
+			t.Fatalf("%s failed: case %d, expected %#v but received %#v / %s", name, i, tc.expected, output, err)
+		}
+	}
+
+	sorting := &godal.SortingOpt{Fields: []*godal.SortingField{{FieldName: field}}}
+	if _, err := dao.MongoFetchMany(nil, "table", nil, sorting, 0, 0); err == nil {
+		t.Fatalf("%s failed: sorting by %s should be rejected", name, field)
+	}
+}
+
 func TestGenericRowMapperMongo_ToBoFieldName(t *testing.T) {
 	name := "TestGenericRowMapperMongo_ToBoFieldName"
 	table := "table"
@@ -902,3 +987,43 @@ func TestGenericDaoMongo_GdaoSaveDuplicated(t *testing.T) {
 		t.Fatalf("%s failed: num rows %#v / error: %s", testName, numRows, err)
 	}
 }
+
+func TestIsErrorTransient(t *testing.T) {
+	name := "TestIsErrorTransient"
+	if IsErrorTransient(nil) {
+		t.Fatalf("%s failed: nil error should not be transient", name)
+	}
+	if IsErrorTransient(errors.New("dummy")) {
+		t.Fatalf("%s failed: generic error should not be transient", name)
+	}
+	for _, label := range []string{"RetryableWriteError", "TransientTransactionError", "NetworkError"} {
+		err := mongodrv.CommandError{Code: 1, Message: "dummy", Labels: []string{label}}
+		if !IsErrorTransient(err) {
+			t.Fatalf("%s failed: error with label %s should be transient", name, label)
+		}
+		if !IsErrorTransient(fmt.Errorf("wrapped: %w", err)) {
+			t.Fatalf("%s failed: wrapped error with label %s should be transient", name, label)
+		}
+	}
+	if IsErrorTransient(mongodrv.CommandError{Code: 11000, Message: "E11000 duplicate key error"}) {
+		t.Fatalf("%s failed: duplicated key error should not be transient", name)
+	}
+	if !IsErrorTransient(context.DeadlineExceeded) {
+		t.Fatalf("%s failed: timeout error should be transient", name)
+	}
+}
+
+func TestGenericDaoMongo_ApplyStorageDescriptors(t *testing.T) {
+	name := "TestGenericDaoMongo_ApplyStorageDescriptors"
+	descs, err := godal.ParseStorageDescriptors([]byte(`{"storages":[{"id":"logs","backend":"mongo","schema":{"type":"object","required":["_id"]}}]}`))
+	if err != nil {
+		t.Fatalf("%s failed: %s", name, err)
+	}
+	dao := NewGenericDaoMongo(nil, godal.NewAbstractGenericDao(nil))
+	if err := dao.ApplyStorageDescriptors(descs); err != nil {
+		t.Fatalf("%s failed: %s", name, err)
+	}
+	if dao.GetSchema("logs") == nil {
+		t.Fatalf("%s failed: schema is not attached", name)
+	}
+}
@@ -0,0 +1,99 @@
+package sql
+
+import (
+	"fmt"
+
+	"github.com/btnguyen2k/godal"
+)
+
+var nameTransformations = map[string]NameTransformation{
+	"":                             NameTransfIntact,
+	godal.NameTransformationIntact: NameTransfIntact,
+	godal.NameTransformationUpper:  NameTransfUpperCase,
+	godal.NameTransformationLower:  NameTransfLowerCase,
+}
+
+// NewRowMapperFromDescriptors builds a GenericRowMapperSql from the descriptors of backend godal.BackendSql.
+//
+// GenericRowMapperSql applies the same name transformation to all tables, hence all descriptors must specify the
+// same name_transformation (or leave it empty).
+//
+// Available since v0.7.0
+func NewRowMapperFromDescriptors(descs *godal.StorageDescriptors) (*GenericRowMapperSql, error) {
+	return mergeRowMapperDescriptors(&GenericRowMapperSql{}, descs)
+}
+
+// mergeRowMapperDescriptors returns a copy of base with the mappings built from the descriptors of backend
+// godal.BackendSql: mappings of described tables are replaced, mappings of other tables are kept. If base already maps
+// tables, name_transformation of the descriptors must match base's name transformation.
+func mergeRowMapperDescriptors(base *GenericRowMapperSql, descs *godal.StorageDescriptors) (*GenericRowMapperSql, error) {
+	mapper := &GenericRowMapperSql{
+		NameTransformation:          base.NameTransformation,
+		GboFieldToColNameTranslator: make(map[string]map[string]interface{}),
+		ColNameToGboFieldTranslator: make(map[string]map[string]interface{}),
+		ColumnsListMap:              make(map[string][]string),
+		NestedFieldSeparator:        base.NestedFieldSeparator,
+	}
+	for k, v := range base.GboFieldToColNameTranslator {
+		mapper.GboFieldToColNameTranslator[k] = v
+	}
+	for k, v := range base.ColNameToGboFieldTranslator {
+		mapper.ColNameToGboFieldTranslator[k] = v
+	}
+	for k, v := range base.ColumnsListMap {
+		mapper.ColumnsListMap[k] = v
+	}
+	nameTransf := ""
+	for _, s := range descs.ForBackend(godal.BackendSql) {
+		if s.NameTransformation != "" {
+			if nameTransf != "" && nameTransf != s.NameTransformation {
+				return nil, fmt.Errorf("storage %s: name_transformation %#v conflicts with %#v of other storages", s.Id, s.NameTransformation, nameTransf)
+			}
+			nameTransf = s.NameTransformation
+		}
+	}
+	if nameTransf != "" {
+		mapsTables := len(mapper.GboFieldToColNameTranslator)+len(mapper.ColNameToGboFieldTranslator)+len(mapper.ColumnsListMap) > 0
+		if mapsTables && nameTransformations[nameTransf] != base.NameTransformation {
+			return nil, fmt.Errorf("name_transformation %#v conflicts with the name transformation of the row mapper", nameTransf)
+		}
+		mapper.NameTransformation = nameTransformations[nameTransf]
+	}
+	for _, s := range descs.ForBackend(godal.BackendSql) {
+		fieldToCol := make(map[string]interface{})
+		colToField := make(map[string]interface{})
+		for _, f := range s.Fields {
+			fieldToCol[mapper.transformName(f.Name)] = f.GetColumn()
+			colToField[mapper.transformName(f.GetColumn())] = f.Name
+		}
+		mapper.GboFieldToColNameTranslator[s.Id] = fieldToCol
+		mapper.ColNameToGboFieldTranslator[s.Id] = colToField
+		mapper.ColumnsListMap[s.Id] = s.ColumnsList()
+	}
+	return mapper, nil
+}
+
+// ApplyStorageDescriptors configures the DAO with the descriptors of backend godal.BackendSql: the mappings built from
+// the descriptors are merged into the current row mapper (a GenericRowMapperSql; mappings of tables that are not
+// described are kept), and schemas are attached to storages. The row mapper is replaced by a merged copy, the current
+// one is not modified.
+//
+// Available since v0.7.0
+func (dao *GenericDaoSql) ApplyStorageDescriptors(descs *godal.StorageDescriptors) error {
+	base := &GenericRowMapperSql{}
+	if current := dao.GetRowMapper(); current != nil {
+		var ok bool
+		if base, ok = current.(*GenericRowMapperSql); !ok {
+			return fmt.Errorf("row mapper %T can not be merged with storage descriptors", current)
+		}
+	}
+	rowMapper, err := mergeRowMapperDescriptors(base, descs)
+	if err != nil {
+		return err
+	}
+	if err := descs.ApplySchemas(godal.BackendSql, dao.AbstractGenericDao); err != nil {
+		return err
+	}
+	dao.SetRowMapper(rowMapper)
+	return nil
+}
@@ -0,0 +1,120 @@
+package sql
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/btnguyen2k/godal"
+)
+
+const testSqlDescriptorsYaml = `
+storages:
+  - id: users
+    backend: sql
+    name_transformation: lower
+    fields:
+      - {name: id, column: UID, key: true}
+      - {name: userName, column: uname}
+      - {name: age}
+    schema: {type: object, required: [id]}
+  - id: groups
+    backend: sql
+    fields: [{name: id, column: gid}]
+  - id: logs
+    backend: mongo
+`
+
+func TestNewRowMapperFromDescriptors(t *testing.T) {
+	testName := "TestNewRowMapperFromDescriptors"
+	descs, err := godal.ParseStorageDescriptors([]byte(testSqlDescriptorsYaml))
+	if err != nil {
+		t.Fatalf("%s failed: %s", testName, err)
+	}
+	mapper, err := NewRowMapperFromDescriptors(descs)
+	if err != nil {
+		t.Fatalf("%s failed: %s", testName, err)
+	}
+	if mapper.NameTransformation != NameTransfLowerCase {
+		t.Fatalf("%s failed: expected name transformation %#v but received %#v", testName, NameTransfLowerCase, mapper.NameTransformation)
+	}
+	if cols := mapper.ColumnsList("users"); !reflect.DeepEqual(cols, []string{"UID", "uname", "age"}) {
+		t.Fatalf("%s failed: %#v", testName, cols)
+	}
+	if cols := mapper.ColumnsList("logs"); !reflect.DeepEqual(cols, []string{"*"}) {
+		t.Fatalf("%s failed: %#v", testName, cols)
+	}
+	for field, col := range map[string]string{"id": "UID", "userName": "uname", "age": "age"} {
+		if v := mapper.ToDbColName("users", field); v != col {
+			t.Fatalf("%s failed: expected %#v but received %#v", testName, col, v)
+		}
+	}
+	row := map[string]interface{}{"uid": "1", "uname": "user1", "age": 30}
+	bo, err := mapper.ToBo("users", row)
+	if err != nil || bo.GboGetAttrUnsafe("id", nil) != "1" || bo.GboGetAttrUnsafe("userName", nil) != "user1" {
+		t.Fatalf("%s failed: %s / %s", testName, bo.GboToJsonUnsafe(), err)
+	}
+
+	conflict := strings.Replace(testSqlDescriptorsYaml, "fields: [{name: id, column: gid}]", "name_transformation: upper\n    fields: [{name: id}]", 1)
+	descs, err = godal.ParseStorageDescriptors([]byte(conflict))
+	if err != nil {
+		t.Fatalf("%s failed: %s", testName, err)
+	}
+	if _, err := NewRowMapperFromDescriptors(descs); err == nil {
+		t.Fatalf("%s failed: conflicting name transformations should be reported", testName)
+	}
+}
+
+func TestGenericDaoSql_ApplyStorageDescriptors(t *testing.T) {
+	testName := "TestGenericDaoSql_ApplyStorageDescriptors"
+	descs, err := godal.ParseStorageDescriptors([]byte(testSqlDescriptorsYaml))
+	if err != nil {
+		t.Fatalf("%s failed: %s", testName, err)
+	}
+	dao := &GenericDaoSql{AbstractGenericDao: godal.NewAbstractGenericDao(nil)}
+	if err := dao.ApplyStorageDescriptors(descs); err != nil {
+		t.Fatalf("%s failed: %s", testName, err)
+	}
+	if _, ok := dao.GetRowMapper().(*GenericRowMapperSql); !ok {
+		t.Fatalf("%s failed: row mapper is not set", testName)
+	}
+	if dao.GetSchema("users") == nil || dao.GetSchema("groups") != nil {
+		t.Fatalf("%s failed: schemas are not attached", testName)
+	}
+}
+
+func TestGenericDaoSql_ApplyStorageDescriptors_Merge(t *testing.T) {
+	testName := "TestGenericDaoSql_ApplyStorageDescriptors_Merge"
+	descs, err := godal.ParseStorageDescriptors([]byte(testSqlDescriptorsYaml))
+	if err != nil {
+		t.Fatalf("%s failed: %s", testName, err)
+	}
+	current := &GenericRowMapperSql{
+		NameTransformation:          NameTransfLowerCase,
+		GboFieldToColNameTranslator: map[string]map[string]interface{}{"orders": {"id": "oid"}, "users": {"id": "old"}},
+		ColNameToGboFieldTranslator: map[string]map[string]interface{}{"orders": {"oid": "id"}},
+		ColumnsListMap:              map[string][]string{"orders": {"oid"}},
+		NestedFieldSeparator:        "__",
+	}
+	dao := &GenericDaoSql{AbstractGenericDao: godal.NewAbstractGenericDao(nil)}
+	dao.SetRowMapper(current)
+	if err := dao.ApplyStorageDescriptors(descs); err != nil {
+		t.Fatalf("%s failed: %s", testName, err)
+	}
+	mapper := dao.GetRowMapper().(*GenericRowMapperSql)
+	if mapper.ToDbColName("orders", "id") != "oid" || !reflect.DeepEqual(mapper.ColumnsList("orders"), []string{"oid"}) || mapper.NestedFieldSeparator != "__" {
+		t.Fatalf("%s failed: mappings of other tables should be kept", testName)
+	}
+	if mapper.ToDbColName("users", "id") != "UID" || mapper.ToDbColName("groups", "id") != "gid" {
+		t.Fatalf("%s failed: mappings of described tables should be added", testName)
+	}
+	if current.ToDbColName("users", "id") != "old" || len(current.ColumnsListMap) != 1 {
+		t.Fatalf("%s failed: current row mapper should not be modified", testName)
+	}
+
+	// name transformation of the descriptors conflicts with that of the row mapper
+	dao.SetRowMapper(&GenericRowMapperSql{NameTransformation: NameTransfUpperCase, ColumnsListMap: map[string][]string{"orders": {"OID"}}})
+	if err := dao.ApplyStorageDescriptors(descs); err == nil {
+		t.Fatalf("%s failed: conflicting name transformations should be reported", testName)
+	}
+}
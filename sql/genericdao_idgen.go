@@ -0,0 +1,180 @@
+package sql
+
+import (
+	"context"
+	gosql "database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/btnguyen2k/prom/sql"
+
+	"github.com/btnguyen2k/godal"
+)
+
+type ctxKeyTx struct{}
+
+// contextWithTx returns a copy of the parent context that carries the transaction, or the parent context as-is if tx is nil.
+func contextWithTx(ctx context.Context, tx *gosql.Tx) context.Context {
+	if tx == nil {
+		return ctx
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithValue(ctx, ctxKeyTx{}, tx)
+}
+
+// TxFromContext returns the transaction of the write operation that a BO is being prepared for, nil if the operation
+// does not run within a transaction. GenericDaoSql passes the transaction to id generators (see godal.IIdGenerator)
+// through the context, so that they can run their statements within the same transaction.
+//
+// Available since v0.7.0
+func TxFromContext(ctx context.Context) *gosql.Tx {
+	if ctx == nil {
+		return nil
+	}
+	tx, _ := ctx.Value(ctxKeyTx{}).(*gosql.Tx)
+	return tx
+}
+
+// reSequenceName matches a (optionally schema-qualified) unquoted sequence name.
+var reSequenceName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// NewSequenceIdGenerator constructs a new SequenceIdGenerator that draws ids from a database sequence.
+//
+// sequenceName is embedded in SQL statements, hence it must be an unquoted identifier, optionally qualified by a
+// schema name (e.g. "seq_user" or "app.seq_user"); otherwise an error is returned.
+//
+// Available since v0.7.0
+func NewSequenceIdGenerator(dao IGenericDaoSql, sequenceName string) (*SequenceIdGenerator, error) {
+	if !reSequenceName.MatchString(sequenceName) {
+		return nil, fmt.Errorf("invalid sequence name %#v", sequenceName)
+	}
+	return &SequenceIdGenerator{dao: dao, sequenceName: sequenceName}, nil
+}
+
+// SequenceIdGenerator is a godal.IIdGenerator that draws ids from a database sequence. Supported flavors:
+//   - PostgreSQL: SELECT nextval('<sequence>')
+//   - Oracle: SELECT <sequence>.NEXTVAL FROM DUAL
+//   - MSSQL: SELECT NEXT VALUE FOR <sequence>
+//
+// If the write operation runs within a transaction (see TxFromContext), the sequence is queried within that transaction.
+//
+// Available since v0.7.0
+type SequenceIdGenerator struct {
+	dao          IGenericDaoSql
+	sequenceName string
+}
+
+// NextId implements godal.IIdGenerator.NextId.
+func (g *SequenceIdGenerator) NextId(ctx context.Context, _ string) (interface{}, error) {
+	var query string
+	switch g.dao.GetSqlFlavor() {
+	case sql.FlavorPgSql:
+		query = fmt.Sprintf("SELECT nextval('%s')", g.sequenceName)
+	case sql.FlavorOracle:
+		query = fmt.Sprintf("SELECT %s.NEXTVAL FROM DUAL", g.sequenceName)
+	case sql.FlavorMsSql:
+		query = fmt.Sprintf("SELECT NEXT VALUE FOR %s", g.sequenceName)
+	default:
+		return nil, fmt.Errorf("sequences are not supported by database flavor %#v", g.dao.GetSqlFlavor())
+	}
+	dbRows, err := g.dao.SqlQuery(ctx, TxFromContext(ctx), query)
+	if dbRows != nil {
+		defer func() { _ = dbRows.Close() }()
+	}
+	if err != nil {
+		return nil, err
+	}
+	var id int64
+	if !dbRows.Next() {
+		if err = dbRows.Err(); err == nil {
+			err = fmt.Errorf("sequence %s returned no value", g.sequenceName)
+		}
+		return nil, err
+	}
+	err = dbRows.Scan(&id)
+	return id, err
+}
+
+/*----------------------------------------------------------------------*/
+
+// IdGeneratorIdentity marks a storage's id as generated by the database when the row is inserted (IDENTITY or
+// auto-increment column). GenericDaoSql omits the id column from the INSERT statement and reads the generated value
+// back into the BO:
+//   - MSSQL: INSERT ... OUTPUT INSERTED.<column> ...
+//   - PostgreSQL: INSERT ... RETURNING <column>
+//   - MySQL, SQLite: sql.Result.LastInsertId()
+//
+// Oracle is not supported, use SequenceIdGenerator instead.
+//
+// Available since v0.7.0
+var IdGeneratorIdentity godal.IIdGenerator = identityIdGenerator{}
+
+type identityIdGenerator struct{}
+
+// NextId implements godal.IIdGenerator.NextId.
+func (identityIdGenerator) NextId(context.Context, string) (interface{}, error) {
+	return nil, nil
+}
+
+// identityField returns the BO field (and its column) that should be read back after insert, empty if none.
+func (dao *GenericDaoSql) identityField(tableName string, bo godal.IGenericBo) (string, string) {
+	fieldPath, gen := dao.GetIdGenerator(tableName)
+	if _, ok := gen.(identityIdGenerator); !ok || bo.GboGetAttrUnsafe(fieldPath, nil) != nil {
+		return "", ""
+	}
+	return fieldPath, dao.GetRowMapper().ToDbColName(tableName, fieldPath)
+}
+
+// sqlInsertBo inserts a row and, if the table's id is generated by the database, reads the generated id back into the BO.
+func (dao *GenericDaoSql) sqlInsertBo(ctx context.Context, tx *gosql.Tx, tableName string, bo godal.IGenericBo, colsAndVals map[string]interface{}) (int, error) {
+	fieldPath, colName := dao.identityField(tableName, bo)
+	if fieldPath == "" {
+		result, err := dao.SqlInsert(ctx, tx, tableName, colsAndVals)
+		if err != nil {
+			return 0, err
+		}
+		numRows, err := result.RowsAffected()
+		return int(numRows), err
+	}
+
+	delete(colsAndVals, colName)
+	sqlStm, values := dao.SqlBuildInsertEx(nil, tableName, colsAndVals)
+	var id interface{}
+	switch dao.GetSqlFlavor() {
+	case sql.FlavorMySql, sql.FlavorSqlite:
+		result, err := dao.SqlExecute(ctx, tx, sqlStm, values...)
+		if err != nil {
+			return 0, err
+		}
+		if id, err = result.LastInsertId(); err != nil {
+			return 0, err
+		}
+	case sql.FlavorMsSql, sql.FlavorPgSql:
+		if dao.GetSqlFlavor() == sql.FlavorMsSql {
+			sqlStm = strings.Replace(sqlStm, ") VALUES (", ") OUTPUT INSERTED."+colName+" VALUES (", 1)
+		} else {
+			sqlStm += " RETURNING " + colName
+		}
+		dbRows, err := dao.SqlQuery(ctx, tx, sqlStm, values...)
+		if dbRows != nil {
+			defer func() { _ = dbRows.Close() }()
+		}
+		if err != nil {
+			return 0, err
+		}
+		if !dbRows.Next() {
+			return 0, dbRows.Err()
+		}
+		var v int64
+		if err = dbRows.Scan(&v); err != nil {
+			return 0, err
+		}
+		id = v
+	default:
+		return 0, fmt.Errorf("reading back generated id is not supported by database flavor %#v", dao.GetSqlFlavor())
+	}
+	return 1, bo.GboSetAttr(fieldPath, id)
+}
@@ -0,0 +1,161 @@
+package sql
+
+import (
+	"context"
+	gosql "database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/btnguyen2k/consu/reddo"
+	"github.com/btnguyen2k/prom/sql"
+
+	"github.com/btnguyen2k/godal"
+)
+
+const testIdentityTableName = "test_identity"
+
+func prepareIdentityTableSqlite(sqlc *sql.SqlConnect, table string) error {
+	os.MkdirAll(filepath.Dir(strings.Trim(os.Getenv(envSqliteUrl), "\"")), 0711)
+	sql := fmt.Sprintf("DROP TABLE IF EXISTS %s", table)
+	if _, err := sqlc.GetDB().Exec(sql); err != nil {
+		return err
+	}
+	sql = fmt.Sprintf("CREATE TABLE %s (%s INTEGER PRIMARY KEY AUTOINCREMENT, %s VARCHAR(64), %s TEXT, %s INT, %s REAL, %s VARCHAR(64), %s DATETIME)",
+		table, colSqlId, colSqlUsername, colSqlData, colSqlValPInt, colSqlValPFloat, colSqlValPString, colSqlValPTime)
+	_, err := sqlc.GetDB().Exec(sql)
+	return err
+}
+
+func TestSequenceIdGenerator_UnsupportedFlavor(t *testing.T) {
+	testName := "TestSequenceIdGenerator_UnsupportedFlavor"
+	for _, flavor := range []sql.DbFlavor{sql.FlavorMySql, sql.FlavorSqlite} {
+		dao := &GenericDaoSql{}
+		dao.SetSqlConnect(&sql.SqlConnect{})
+		dao.GetSqlConnect().SetDbFlavor(flavor)
+		gen, _ := NewSequenceIdGenerator(dao, "seq_test")
+		if _, err := gen.NextId(nil, "test"); err == nil {
+			t.Fatalf("%s failed: sequences should not be supported by flavor %#v", testName, flavor)
+		}
+	}
+}
+
+func TestNewSequenceIdGenerator(t *testing.T) {
+	testName := "TestNewSequenceIdGenerator"
+	for _, name := range []string{"seq_test", "SeqTest1", "app.seq_test", "_seq"} {
+		if gen, err := NewSequenceIdGenerator(nil, name); gen == nil || err != nil {
+			t.Fatalf("%s failed: %#v should be a valid sequence name: %e", testName, name, err)
+		}
+	}
+	for _, name := range []string{"", "1seq", "seq-test", "seq test", "seq'); DROP TABLE t; --", "a.b.c", "seq.", `"seq"`} {
+		if gen, err := NewSequenceIdGenerator(nil, name); gen != nil || err == nil {
+			t.Fatalf("%s failed: %#v should not be a valid sequence name", testName, name)
+		}
+	}
+}
+
+func TestGenericDaoSqlite_IdGeneratorTx(t *testing.T) {
+	testName := "TestGenericDaoSqlite_IdGeneratorTx"
+	dao := _initDao(os.Getenv(envSqliteDriver), os.Getenv(envSqliteUrl), testTableName, sql.FlavorSqlite)
+	if dao == nil {
+		t.SkipNow()
+	}
+	defer dao.sqlConnect.Close()
+	if err := prepareTableSqlite(dao.GetSqlConnect(), dao.tableName); err != nil {
+		t.Fatalf("%s failed: %e", testName+"/prepareTableSqlite", err)
+	}
+	var txList []*gosql.Tx
+	dao.SetIdGenerator(dao.tableName, fieldGboId, godal.IdGeneratorFunc(func(ctx context.Context, _ string) (interface{}, error) {
+		txList = append(txList, TxFromContext(ctx))
+		return fmt.Sprintf("%d", len(txList)), nil
+	}))
+
+	tx, err := dao.StartTx(nil)
+	if err != nil {
+		t.Fatalf("%s failed: %e", testName+"/StartTx", err)
+	}
+	defer tx.Rollback()
+	for i, f := range []func(bo godal.IGenericBo) (int, error){
+		func(bo godal.IGenericBo) (int, error) { return dao.GdaoCreateWithTx(nil, tx, dao.tableName, bo) },
+		func(bo godal.IGenericBo) (int, error) { return dao.GdaoSaveWithTx(nil, tx, dao.tableName, bo) },
+	} {
+		bo := godal.NewGenericBo()
+		bo.GboImportViaMap(map[string]interface{}{fieldGboUsername: fmt.Sprintf("user%d", i), fieldGboData: "{}"})
+		if numRows, err := f(bo); numRows != 1 || err != nil {
+			t.Fatalf("%s failed: %#v / %#v", testName, numRows, err)
+		}
+	}
+	if len(txList) != 2 || txList[0] != tx || txList[1] != tx {
+		t.Fatalf("%s failed: id generator should run within the write's transaction: %#v", testName, txList)
+	}
+	if TxFromContext(nil) != nil || TxFromContext(context.Background()) != nil {
+		t.Fatalf("%s failed: context should not carry a transaction", testName)
+	}
+}
+
+func TestGenericDaoSqlite_IdGeneratorIdentity(t *testing.T) {
+	testName := "TestGenericDaoSqlite_IdGeneratorIdentity"
+	dao := _initDao(os.Getenv(envSqliteDriver), os.Getenv(envSqliteUrl), testIdentityTableName, sql.FlavorSqlite)
+	if dao == nil {
+		t.SkipNow()
+	}
+	defer dao.sqlConnect.Close()
+	if err := prepareIdentityTableSqlite(dao.GetSqlConnect(), dao.tableName); err != nil {
+		t.Fatalf("%s failed: %e", testName+"/prepareIdentityTableSqlite", err)
+	}
+	dao.SetIdGenerator(dao.tableName, fieldGboId, IdGeneratorIdentity)
+
+	newBo := func(username string) godal.IGenericBo {
+		bo := godal.NewGenericBo()
+		bo.GboImportViaMap(map[string]interface{}{fieldGboUsername: username, fieldGboData: "{}"})
+		return bo
+	}
+	for i, f := range []func(bo godal.IGenericBo) (int, error){
+		func(bo godal.IGenericBo) (int, error) { return dao.GdaoCreate(dao.tableName, bo) },
+		func(bo godal.IGenericBo) (int, error) { return dao.GdaoCreate(dao.tableName, bo) },
+		func(bo godal.IGenericBo) (int, error) { return dao.GdaoSave(dao.tableName, bo) },
+	} {
+		bo := newBo(fmt.Sprintf("user%d", i))
+		if numRows, err := f(bo); numRows != 1 || err != nil {
+			t.Fatalf("%s failed: %#v / %#v", testName, numRows, err)
+		}
+		if id := bo.GboGetAttrUnsafe(fieldGboId, reddo.TypeInt); id != int64(i+1) {
+			t.Fatalf("%s failed: expected generated id %#v but received %#v", testName, i+1, id)
+		}
+		if fetched, err := dao.GdaoFetchOne(dao.tableName, dao.GdaoCreateFilter(dao.tableName, bo)); err != nil || fetched == nil {
+			t.Fatalf("%s failed: %#v / %#v", testName, fetched, err)
+		}
+	}
+
+	// existing id is kept
+	bo := newBo("user100")
+	bo.GboSetAttr(fieldGboId, 100)
+	dao.GdaoCreate(dao.tableName, bo)
+	if id := bo.GboGetAttrUnsafe(fieldGboId, reddo.TypeInt); id != int64(100) {
+		t.Fatalf("%s failed: expected id %#v but received %#v", testName, 100, id)
+	}
+}
+
+func TestGenericDaoSqlite_IdGeneratorUlid(t *testing.T) {
+	testName := "TestGenericDaoSqlite_IdGeneratorUlid"
+	dao := _initDao(os.Getenv(envSqliteDriver), os.Getenv(envSqliteUrl), testTableName, sql.FlavorSqlite)
+	if dao == nil {
+		t.SkipNow()
+	}
+	defer dao.sqlConnect.Close()
+	if err := prepareTableSqlite(dao.GetSqlConnect(), dao.tableName); err != nil {
+		t.Fatalf("%s failed: %e", testName+"/prepareTableSqlite", err)
+	}
+	dao.SetIdGenerator(dao.tableName, fieldGboId, godal.IdGeneratorUlid)
+
+	bo := godal.NewGenericBo()
+	bo.GboImportViaMap(map[string]interface{}{fieldGboUsername: "user1", fieldGboData: "{}"})
+	if numRows, err := dao.GdaoCreate(dao.tableName, bo); numRows != 1 || err != nil {
+		t.Fatalf("%s failed: %#v / %#v", testName, numRows, err)
+	}
+	if id := bo.GboGetAttrUnsafe(fieldGboId, reddo.TypeString); len(id.(string)) != 26 {
+		t.Fatalf("%s failed: expected ULID but received %#v", testName, id)
+	}
+}
@@ -232,3 +232,62 @@ func TestGenericDaoMssql_FilterNotNull(t *testing.T) {
 	dotestGenericDaoSqlGdaoFilterNotNull(t, testName, dao)
 }
 
+func TestGenericDaoMssql_AutoFields(t *testing.T) {
+	testName := "TestGenericDaoMssql_AutoFields"
+	dao := _initDao(os.Getenv(envMssqlDriver), os.Getenv(envMssqlUrl), testTableName, sql.FlavorMsSql)
+	if dao == nil {
+		t.SkipNow()
+	}
+	defer dao.sqlConnect.Close()
+	
+	err := prepareTableMssql(dao.GetSqlConnect(), dao.tableName)
+	if err != nil {
+		t.Fatalf("%s failed: %e", testName+"/prepareTableMssql", err)
+	}
+	dotestGenericDaoSqlAutoFields(t, testName, dao)
+}
+
+func TestGenericDaoMssql_Schema(t *testing.T) {
+	testName := "TestGenericDaoMssql_Schema"
+	dao := _initDao(os.Getenv(envMssqlDriver), os.Getenv(envMssqlUrl), testTableName, sql.FlavorMsSql)
+	if dao == nil {
+		t.SkipNow()
+	}
+	defer dao.sqlConnect.Close()
+	
+	err := prepareTableMssql(dao.GetSqlConnect(), dao.tableName)
+	if err != nil {
+		t.Fatalf("%s failed: %e", testName+"/prepareTableMssql", err)
+	}
+	dotestGenericDaoSqlSchema(t, testName, dao)
+}
+
+func TestGenericDaoMssql_StructRowMapper(t *testing.T) {
+	testName := "TestGenericDaoMssql_StructRowMapper"
+	dao := _initDao(os.Getenv(envMssqlDriver), os.Getenv(envMssqlUrl), testTableName, sql.FlavorMsSql)
+	if dao == nil {
+		t.SkipNow()
+	}
+	defer dao.sqlConnect.Close()
+	
+	err := prepareTableMssql(dao.GetSqlConnect(), dao.tableName)
+	if err != nil {
+		t.Fatalf("%s failed: %e", testName+"/prepareTableMssql", err)
+	}
+	dotestGenericDaoSqlStructRowMapper(t, testName, dao)
+}
+
+func TestGenericDaoMssql_UpdateChangedOnly(t *testing.T) {
+	testName := "TestGenericDaoMssql_UpdateChangedOnly"
+	dao := _initDao(os.Getenv(envMssqlDriver), os.Getenv(envMssqlUrl), testTableName, sql.FlavorMsSql)
+	if dao == nil {
+		t.SkipNow()
+	}
+	defer dao.sqlConnect.Close()
+	
+	err := prepareTableMssql(dao.GetSqlConnect(), dao.tableName)
+	if err != nil {
+		t.Fatalf("%s failed: %e", testName+"/prepareTableMssql", err)
+	}
+	dotestGenericDaoSqlUpdateChangedOnly(t, testName, dao)
+}
@@ -231,3 +231,58 @@ func TestGenericDaoMysql_FilterNotNull(t *testing.T) {
 	dotestGenericDaoSqlGdaoFilterNotNull(t, testName, dao)
 }
 
+func TestGenericDaoMysql_AutoFields(t *testing.T) {
+	testName := "TestGenericDaoMysql_AutoFields"
+	dao := _initDao(os.Getenv(envMysqlDriver), os.Getenv(envMysqlUrl), testTableName, sql.FlavorMySql)
+	if dao == nil {
+		t.SkipNow()
+	}
+	defer dao.sqlConnect.Close()
+	err := prepareTableMysql(dao.GetSqlConnect(), dao.tableName)
+	if err != nil {
+		t.Fatalf("%s failed: %e", testName+"/prepareTableMysql", err)
+	}
+	dotestGenericDaoSqlAutoFields(t, testName, dao)
+}
+
+func TestGenericDaoMysql_Schema(t *testing.T) {
+	testName := "TestGenericDaoMysql_Schema"
+	dao := _initDao(os.Getenv(envMysqlDriver), os.Getenv(envMysqlUrl), testTableName, sql.FlavorMySql)
+	if dao == nil {
+		t.SkipNow()
+	}
+	defer dao.sqlConnect.Close()
+	err := prepareTableMysql(dao.GetSqlConnect(), dao.tableName)
+	if err != nil {
+		t.Fatalf("%s failed: %e", testName+"/prepareTableMysql", err)
+	}
+	dotestGenericDaoSqlSchema(t, testName, dao)
+}
+
+func TestGenericDaoMysql_StructRowMapper(t *testing.T) {
+	testName := "TestGenericDaoMysql_StructRowMapper"
+	dao := _initDao(os.Getenv(envMysqlDriver), os.Getenv(envMysqlUrl), testTableName, sql.FlavorMySql)
+	if dao == nil {
+		t.SkipNow()
+	}
+	defer dao.sqlConnect.Close()
+	err := prepareTableMysql(dao.GetSqlConnect(), dao.tableName)
+	if err != nil {
+		t.Fatalf("%s failed: %e", testName+"/prepareTableMysql", err)
+	}
+	dotestGenericDaoSqlStructRowMapper(t, testName, dao)
+}
+
+func TestGenericDaoMysql_UpdateChangedOnly(t *testing.T) {
+	testName := "TestGenericDaoMysql_UpdateChangedOnly"
+	dao := _initDao(os.Getenv(envMysqlDriver), os.Getenv(envMysqlUrl), testTableName, sql.FlavorMySql)
+	if dao == nil {
+		t.SkipNow()
+	}
+	defer dao.sqlConnect.Close()
+	err := prepareTableMysql(dao.GetSqlConnect(), dao.tableName)
+	if err != nil {
+		t.Fatalf("%s failed: %e", testName+"/prepareTableMysql", err)
+	}
+	dotestGenericDaoSqlUpdateChangedOnly(t, testName, dao)
+}
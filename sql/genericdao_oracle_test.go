@@ -231,3 +231,58 @@ func TestGenericDaoOracle_FilterNotNull(t *testing.T) {
 	dotestGenericDaoSqlGdaoFilterNotNull(t, testName, dao)
 }
 
+func TestGenericDaoOracle_AutoFields(t *testing.T) {
+	testName := "TestGenericDaoOracle_AutoFields"
+	dao := _initDao(os.Getenv(envOracleDriver), os.Getenv(envOracleUrl), testTableName, sql.FlavorOracle)
+	if dao == nil {
+		t.SkipNow()
+	}
+	defer dao.sqlConnect.Close()
+	err := prepareTableOracle(dao.GetSqlConnect(), dao.tableName)
+	if err != nil {
+		t.Fatalf("%s failed: %e", testName+"/prepareTableOracle", err)
+	}
+	dotestGenericDaoSqlAutoFields(t, testName, dao)
+}
+
+func TestGenericDaoOracle_Schema(t *testing.T) {
+	testName := "TestGenericDaoOracle_Schema"
+	dao := _initDao(os.Getenv(envOracleDriver), os.Getenv(envOracleUrl), testTableName, sql.FlavorOracle)
+	if dao == nil {
+		t.SkipNow()
+	}
+	defer dao.sqlConnect.Close()
+	err := prepareTableOracle(dao.GetSqlConnect(), dao.tableName)
+	if err != nil {
+		t.Fatalf("%s failed: %e", testName+"/prepareTableOracle", err)
+	}
+	dotestGenericDaoSqlSchema(t, testName, dao)
+}
+
+func TestGenericDaoOracle_StructRowMapper(t *testing.T) {
+	testName := "TestGenericDaoOracle_StructRowMapper"
+	dao := _initDao(os.Getenv(envOracleDriver), os.Getenv(envOracleUrl), testTableName, sql.FlavorOracle)
+	if dao == nil {
+		t.SkipNow()
+	}
+	defer dao.sqlConnect.Close()
+	err := prepareTableOracle(dao.GetSqlConnect(), dao.tableName)
+	if err != nil {
+		t.Fatalf("%s failed: %e", testName+"/prepareTableOracle", err)
+	}
+	dotestGenericDaoSqlStructRowMapper(t, testName, dao)
+}
+
+func TestGenericDaoOracle_UpdateChangedOnly(t *testing.T) {
+	testName := "TestGenericDaoOracle_UpdateChangedOnly"
+	dao := _initDao(os.Getenv(envOracleDriver), os.Getenv(envOracleUrl), testTableName, sql.FlavorOracle)
+	if dao == nil {
+		t.SkipNow()
+	}
+	defer dao.sqlConnect.Close()
+	err := prepareTableOracle(dao.GetSqlConnect(), dao.tableName)
+	if err != nil {
+		t.Fatalf("%s failed: %e", testName+"/prepareTableOracle", err)
+	}
+	dotestGenericDaoSqlUpdateChangedOnly(t, testName, dao)
+}
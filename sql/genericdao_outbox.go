@@ -0,0 +1,434 @@
+package sql
+
+import (
+	"context"
+	"crypto/rand"
+	gosql "database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/btnguyen2k/godal"
+)
+
+var (
+	// ErrOutboxConflict is returned by write operations when another writer has recorded an event of the same key
+	// concurrently, i.e. the unique index on (storage_id, event_key, event_seq) is violated. The write is rolled back
+	// and can be retried: IsErrorTransient returns 'true' for this error.
+	//
+	// Available since v0.7.0
+	ErrOutboxConflict = errors.New("outbox: event of the same key recorded concurrently")
+)
+
+// Operations recorded in outbox events.
+//
+// Available since v0.7.0
+const (
+	OutboxOpCreate = "create"
+	OutboxOpUpdate = "update"
+	OutboxOpSave   = "save"
+	OutboxOpDelete = "delete"
+)
+
+// Columns of the outbox table.
+//
+// event_key is built from GdaoCreateFilter of the BO (see godal.FilterToCacheKey), numbers are represented by value
+// regardless of their Go types. event_seq is the per-key version of the event: it is computed from the latest event of
+// the same (storage_id, event_key) within the write's transaction; OutboxRelay.PurgeDelivered keeps the latest event
+// of each key so that versions keep increasing. The unique index on (storage_id, event_key, event_seq) makes concurrent
+// writers of the same key that compute the same version fail with ErrOutboxConflict (and roll back) instead of
+// publishing events out of order.
+//
+// Sample table definition (MySQL):
+//
+//	CREATE TABLE outbox (
+//	  event_id     VARCHAR(64) NOT NULL PRIMARY KEY,
+//	  event_seq    BIGINT NOT NULL,
+//	  storage_id   VARCHAR(255) NOT NULL,
+//	  op           VARCHAR(16) NOT NULL,
+//	  event_key    VARCHAR(255) NOT NULL,
+//	  payload      TEXT,
+//	  created_at   BIGINT NOT NULL,
+//	  delivered_at BIGINT NULL,
+//	  UNIQUE INDEX uidx_outbox_key (storage_id, event_key, event_seq),
+//	  INDEX idx_outbox_pending (delivered_at, event_seq, created_at)
+//	)
+//
+// Available since v0.7.0
+const (
+	OutboxColEventId     = "event_id"
+	OutboxColEventSeq    = "event_seq"
+	OutboxColStorageId   = "storage_id"
+	OutboxColOperation   = "op"
+	OutboxColKey         = "event_key"
+	OutboxColPayload     = "payload"
+	OutboxColCreatedAt   = "created_at"
+	OutboxColDeliveredAt = "delivered_at"
+)
+
+// GetOutboxTable returns the name of the outbox table, empty if the outbox is disabled.
+//
+// Available since v0.7.0
+func (dao *GenericDaoSql) GetOutboxTable() string {
+	return dao.outboxTable
+}
+
+// SetOutboxTable enables the transactional outbox: GdaoCreate, GdaoUpdate, GdaoSave and GdaoDelete (and their
+// WithTx variants) also insert an event row into the outbox table within the same transaction. If no transaction is
+// supplied, a new one is started to wrap both the operation and the event. No event is written if no row is affected.
+//
+// Supply an empty string to disable the outbox. Events are delivered to downstream services by an OutboxRelay.
+//
+// Available since v0.7.0
+func (dao *GenericDaoSql) SetOutboxTable(tableName string) IGenericDaoSql {
+	dao.outboxTable = tableName
+	return dao
+}
+
+// outboxWrite performs a write operation and inserts the corresponding outbox event within the same transaction.
+func (dao *GenericDaoSql) outboxWrite(ctx context.Context, tx *gosql.Tx, tableName, op string, bo godal.IGenericBo,
+	f func(ctx context.Context, tx *gosql.Tx, tableName string, bo godal.IGenericBo) (int, error)) (int, error) {
+	if tx == nil {
+		var numRows int
+		err := dao.WrapTransaction(ctx, func(ctx context.Context, tx *gosql.Tx) error {
+			var e error
+			numRows, e = dao.outboxWrite(ctx, tx, tableName, op, bo, f)
+			return e
+		})
+		if err != nil {
+			return 0, err
+		}
+		return numRows, nil
+	}
+	numRows, err := f(ctx, tx, tableName, bo)
+	if err != nil || numRows == 0 {
+		return numRows, err
+	}
+	return numRows, dao.insertOutboxEvent(ctx, tx, tableName, op, bo)
+}
+
+func (dao *GenericDaoSql) insertOutboxEvent(ctx context.Context, tx *gosql.Tx, tableName, op string, bo godal.IGenericBo) error {
+	payload, err := bo.GboToJson()
+	if err != nil {
+		return err
+	}
+	key := godal.FilterToCacheKey(dao.GdaoCreateFilter(tableName, bo))
+	seq, err := lastOutboxSeq(ctx, dao, tx, tableName, key)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	random := make([]byte, 8)
+	_, _ = rand.Read(random)
+	_, err = dao.SqlInsert(ctx, tx, dao.outboxTable, map[string]interface{}{
+		OutboxColEventId:   fmt.Sprintf("%016x%s", now.UnixNano(), hex.EncodeToString(random)),
+		OutboxColEventSeq:  seq + 1,
+		OutboxColStorageId: tableName,
+		OutboxColOperation: op,
+		OutboxColKey:       key,
+		OutboxColPayload:   string(payload),
+		OutboxColCreatedAt: now.UnixMilli(),
+	})
+	if err != nil && dao.IsErrorDuplicatedEntry(err) {
+		return fmt.Errorf("%w: %s", ErrOutboxConflict, err)
+	}
+	return err
+}
+
+// lastOutboxSeq returns the sequence number of the latest event of a key, 0 if the key has no event.
+func lastOutboxSeq(ctx context.Context, dao IGenericDaoSql, tx *gosql.Tx, storageId, key string) (int64, error) {
+	filter := (&FilterAnd{}).
+		Add(&FilterFieldValue{Field: OutboxColStorageId, Operator: "=", Value: storageId}).
+		Add(&FilterFieldValue{Field: OutboxColKey, Operator: "=", Value: key})
+	sorting := (&GenericSorting{Flavor: dao.GetSqlFlavor()}).Add(OutboxColEventSeq + ":-1")
+	dbRows, err := dao.SqlSelect(ctx, tx, dao.GetOutboxTable(), []string{OutboxColEventSeq}, filter, sorting, 0, 1)
+	if dbRows != nil {
+		defer func() { _ = dbRows.Close() }()
+	}
+	if err != nil {
+		return 0, err
+	}
+	var seq int64
+	if dbRows.Next() {
+		if err := dbRows.Scan(&seq); err != nil {
+			return 0, err
+		}
+	}
+	return seq, dbRows.Err()
+}
+
+/*----------------------------------------------------------------------*/
+
+// OutboxEvent is a change event read from the outbox table.
+//
+// Available since v0.7.0
+type OutboxEvent struct {
+	Id        string    // unique id of the event
+	Seq       int64     // per-key version, events of the same key are delivered in ascending order of Seq
+	StorageId string    // table where the change happened
+	Operation string    // one of OutboxOpCreate, OutboxOpUpdate, OutboxOpSave or OutboxOpDelete
+	Key       string    // key of the changed BO (built from GdaoCreateFilter)
+	Payload   []byte    // JSON-encoded BO
+	CreatedAt time.Time // time the event was recorded
+}
+
+// IOutboxPublisher publishes outbox events to downstream services.
+//
+// Delivery is at-least-once: Publish may receive the same event more than once, publishers (or consumers) should
+// de-duplicate by OutboxEvent.Id if needed.
+//
+// Available since v0.7.0
+type IOutboxPublisher interface {
+	// Publish publishes an event. Returning an error marks the event (and subsequent events of the same key) for retry.
+	Publish(ctx context.Context, event *OutboxEvent) error
+}
+
+// OutboxPublisherFunc is a function that implements IOutboxPublisher.
+//
+// Available since v0.7.0
+type OutboxPublisherFunc func(ctx context.Context, event *OutboxEvent) error
+
+// Publish implements IOutboxPublisher.Publish.
+func (f OutboxPublisherFunc) Publish(ctx context.Context, event *OutboxEvent) error {
+	return f(ctx, event)
+}
+
+// NewOutboxRelay constructs a new OutboxRelay that delivers events from the DAO's outbox table (see
+// IGenericDaoSql.SetOutboxTable) through the supplied publisher.
+//
+// Default settings: batch size 100, poll interval 1s, retry interval 10s.
+//
+// Available since v0.7.0
+func NewOutboxRelay(dao IGenericDaoSql, publisher IOutboxPublisher) *OutboxRelay {
+	return &OutboxRelay{
+		dao:           dao,
+		publisher:     publisher,
+		batchSize:     100,
+		pollInterval:  time.Second,
+		retryInterval: 10 * time.Second,
+		blockedKeys:   make(map[outboxKey]time.Time),
+		funcNow:       time.Now,
+	}
+}
+
+// outboxKey identifies the BO an outbox event belongs to.
+type outboxKey struct {
+	storageId, key string
+}
+
+// OutboxRelay polls the outbox table for pending events, publishes them and marks them as delivered.
+//
+//   - Events of the same key are published in order of their sequence number. If publishing an event fails, the
+//     key is blocked: its remaining events are held back, and excluded from subsequent polls until the retry
+//     interval has elapsed, so that per-key ordering is preserved and events of other keys are not held up.
+//   - Delivery is at-least-once: an event is marked as delivered only after it has been published successfully.
+//   - Only one relay should poll an outbox table at a time, otherwise per-key ordering is not guaranteed.
+//
+// Available since v0.7.0
+type OutboxRelay struct {
+	dao           IGenericDaoSql
+	publisher     IOutboxPublisher
+	batchSize     int
+	pollInterval  time.Duration
+	retryInterval time.Duration
+	errorCallback func(err error)
+	funcNow       func() time.Time
+	lock          sync.Mutex
+	blockedKeys   map[outboxKey]time.Time // blocked keys and the time they are retried
+}
+
+// SetBatchSize sets the maximum number of events fetched per poll.
+func (r *OutboxRelay) SetBatchSize(batchSize int) *OutboxRelay {
+	if batchSize > 0 {
+		r.batchSize = batchSize
+	}
+	return r
+}
+
+// SetPollInterval sets the wait time between polls when there is no more pending event.
+func (r *OutboxRelay) SetPollInterval(pollInterval time.Duration) *OutboxRelay {
+	if pollInterval > 0 {
+		r.pollInterval = pollInterval
+	}
+	return r
+}
+
+// SetRetryInterval sets the wait time before events of a key are retried after publishing one of them failed.
+func (r *OutboxRelay) SetRetryInterval(retryInterval time.Duration) *OutboxRelay {
+	if retryInterval >= 0 {
+		r.retryInterval = retryInterval
+	}
+	return r
+}
+
+// SetErrorCallback sets the function that receives errors occurred while Run is polling.
+func (r *OutboxRelay) SetErrorCallback(f func(err error)) *OutboxRelay {
+	r.errorCallback = f
+	return r
+}
+
+// FetchPending fetches up to batchSize pending (not yet delivered) events of keys that are not blocked, in ascending
+// order of sequence number.
+func (r *OutboxRelay) FetchPending(ctx context.Context) ([]*OutboxEvent, error) {
+	filter := (&FilterAnd{}).Add(&FilterIsNull{FilterFieldValue: FilterFieldValue{Field: OutboxColDeliveredAt}})
+	for _, k := range r.blocked() {
+		filter.Add((&FilterOr{}).
+			Add(&FilterFieldValue{Field: OutboxColStorageId, Operator: "<>", Value: k.storageId}).
+			Add(&FilterFieldValue{Field: OutboxColKey, Operator: "<>", Value: k.key}))
+	}
+	sorting := (&GenericSorting{Flavor: r.dao.GetSqlFlavor()}).
+		Add(OutboxColEventSeq + ":1").Add(OutboxColCreatedAt + ":1").Add(OutboxColEventId + ":1")
+	columns := []string{OutboxColEventId, OutboxColEventSeq, OutboxColStorageId, OutboxColOperation, OutboxColKey, OutboxColPayload, OutboxColCreatedAt}
+	dbRows, err := r.dao.SqlSelect(ctx, nil, r.dao.GetOutboxTable(), columns, filter, sorting, 0, r.batchSize)
+	if dbRows != nil {
+		defer func() { _ = dbRows.Close() }()
+	}
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*OutboxEvent, 0)
+	for dbRows.Next() {
+		event := &OutboxEvent{}
+		var payload gosql.NullString
+		var createdAt int64
+		if err := dbRows.Scan(&event.Id, &event.Seq, &event.StorageId, &event.Operation, &event.Key, &payload, &createdAt); err != nil {
+			return nil, err
+		}
+		event.Payload = []byte(payload.String)
+		event.CreatedAt = time.UnixMilli(createdAt)
+		result = append(result, event)
+	}
+	return result, dbRows.Err()
+}
+
+// RelayOnce fetches a batch of pending events, publishes them and marks them as delivered.
+// It returns the number of delivered events; publish errors are joined and returned along with the number.
+func (r *OutboxRelay) RelayOnce(ctx context.Context) (int, error) {
+	events, err := r.FetchPending(ctx)
+	if err != nil {
+		return 0, err
+	}
+	numDelivered := 0
+	blockedKeys := make(map[outboxKey]bool)
+	var publishErrs []error
+	for _, event := range events {
+		k := outboxKey{storageId: event.StorageId, key: event.Key}
+		if blockedKeys[k] {
+			continue
+		}
+		if err := r.publisher.Publish(ctx, event); err != nil {
+			blockedKeys[k] = true
+			r.block(k)
+			publishErrs = append(publishErrs, fmt.Errorf("publishing event %s: %w", event.Id, err))
+			continue
+		}
+		filter := &FilterFieldValue{Field: OutboxColEventId, Operator: "=", Value: event.Id}
+		colsAndVals := map[string]interface{}{OutboxColDeliveredAt: r.funcNow().UnixMilli()}
+		if _, err := r.dao.SqlUpdate(ctx, nil, r.dao.GetOutboxTable(), colsAndVals, filter); err != nil {
+			return numDelivered, err
+		}
+		numDelivered++
+	}
+	return numDelivered, errors.Join(publishErrs...)
+}
+
+// blocked returns the keys that are blocked from being relayed, and releases the keys whose retry time has come.
+func (r *OutboxRelay) blocked() []outboxKey {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	now := r.funcNow()
+	result := make([]outboxKey, 0, len(r.blockedKeys))
+	for k, retryAt := range r.blockedKeys {
+		if now.Before(retryAt) {
+			result = append(result, k)
+		} else {
+			delete(r.blockedKeys, k)
+		}
+	}
+	return result
+}
+
+// block blocks a key from being relayed until the retry interval has elapsed.
+func (r *OutboxRelay) block(k outboxKey) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.blockedKeys[k] = r.funcNow().Add(r.retryInterval)
+}
+
+// Run polls and relays events until ctx is cancelled. Errors are reported to the error callback (if any).
+func (r *OutboxRelay) Run(ctx context.Context) error {
+	for {
+		numDelivered, err := r.RelayOnce(ctx)
+		if err != nil && r.errorCallback != nil {
+			r.errorCallback(err)
+		}
+		if numDelivered < r.batchSize || err != nil {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(r.pollInterval):
+			}
+		} else if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+}
+
+// PurgeDelivered removes events that were delivered longer than olderThan ago.
+//
+// The latest event of each key is kept (even if delivered): it is the key's high-water mark from which the versions of
+// the key's next events are computed, so that consumers de-duplicating by version do not drop them.
+func (r *OutboxRelay) PurgeDelivered(ctx context.Context, olderThan time.Duration) (int, error) {
+	deliveredBefore := &FilterFieldValue{Field: OutboxColDeliveredAt, Operator: "<", Value: r.funcNow().Add(-olderThan).UnixMilli()}
+	keys, err := r.keysOf(ctx, deliveredBefore)
+	if err != nil {
+		return 0, err
+	}
+	numPurged := 0
+	for _, k := range keys {
+		seq, err := lastOutboxSeq(ctx, r.dao, nil, k.storageId, k.key)
+		if err != nil {
+			return numPurged, err
+		}
+		filter := (&FilterAnd{}).Add(deliveredBefore).
+			Add(&FilterFieldValue{Field: OutboxColStorageId, Operator: "=", Value: k.storageId}).
+			Add(&FilterFieldValue{Field: OutboxColKey, Operator: "=", Value: k.key}).
+			Add(&FilterFieldValue{Field: OutboxColEventSeq, Operator: "<", Value: seq})
+		result, err := r.dao.SqlDelete(ctx, nil, r.dao.GetOutboxTable(), filter)
+		if err != nil {
+			return numPurged, err
+		}
+		numRows, err := result.RowsAffected()
+		numPurged += int(numRows)
+		if err != nil {
+			return numPurged, err
+		}
+	}
+	return numPurged, nil
+}
+
+// keysOf returns the distinct keys of events matching the filter.
+func (r *OutboxRelay) keysOf(ctx context.Context, filter IFilter) ([]outboxKey, error) {
+	dbRows, err := r.dao.SqlSelect(ctx, nil, r.dao.GetOutboxTable(), []string{OutboxColStorageId, OutboxColKey}, filter, nil, 0, 0)
+	if dbRows != nil {
+		defer func() { _ = dbRows.Close() }()
+	}
+	if err != nil {
+		return nil, err
+	}
+	found := make(map[outboxKey]bool)
+	result := make([]outboxKey, 0)
+	for dbRows.Next() {
+		var k outboxKey
+		if err := dbRows.Scan(&k.storageId, &k.key); err != nil {
+			return nil, err
+		}
+		if !found[k] {
+			found[k] = true
+			result = append(result, k)
+		}
+	}
+	return result, dbRows.Err()
+}
@@ -0,0 +1,323 @@
+package sql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/btnguyen2k/godal"
+	"github.com/btnguyen2k/prom/sql"
+)
+
+const testOutboxTableName = "test_outbox"
+
+func prepareOutboxTableSqlite(sqlc *sql.SqlConnect, table string) error {
+	sql := fmt.Sprintf("DROP TABLE IF EXISTS %s", table)
+	if _, err := sqlc.GetDB().Exec(sql); err != nil {
+		return err
+	}
+	sql = fmt.Sprintf("CREATE TABLE %s (%s VARCHAR(64), %s BIGINT, %s VARCHAR(255), %s VARCHAR(16), %s VARCHAR(255), %s TEXT, %s BIGINT, %s BIGINT, PRIMARY KEY (%s), UNIQUE (%s, %s, %s))",
+		table, OutboxColEventId, OutboxColEventSeq, OutboxColStorageId, OutboxColOperation, OutboxColKey, OutboxColPayload,
+		OutboxColCreatedAt, OutboxColDeliveredAt, OutboxColEventId, OutboxColStorageId, OutboxColKey, OutboxColEventSeq)
+	_, err := sqlc.GetDB().Exec(sql)
+	return err
+}
+
+func _initOutboxDaoSqlite(t *testing.T, testName string) *UserDaoSql {
+	dao := _initDao(os.Getenv(envSqliteDriver), os.Getenv(envSqliteUrl), testTableName, sql.FlavorSqlite)
+	if dao == nil {
+		t.SkipNow()
+	}
+	if err := prepareTableSqlite(dao.GetSqlConnect(), dao.tableName); err != nil {
+		t.Fatalf("%s failed: %e", testName+"/prepareTableSqlite", err)
+	}
+	if err := prepareOutboxTableSqlite(dao.GetSqlConnect(), testOutboxTableName); err != nil {
+		t.Fatalf("%s failed: %e", testName+"/prepareOutboxTableSqlite", err)
+	}
+	dao.SetOutboxTable(testOutboxTableName)
+	return dao
+}
+
+func TestGenericDaoSql_SetGetOutboxTable(t *testing.T) {
+	testName := "TestGenericDaoSql_SetGetOutboxTable"
+	dao := &GenericDaoSql{}
+	if dao.GetOutboxTable() != "" {
+		t.Fatalf("%s failed: outbox should be disabled by default", testName)
+	}
+	dao.SetOutboxTable(testOutboxTableName)
+	if dao.GetOutboxTable() != testOutboxTableName {
+		t.Fatalf("%s failed: expected %#v but received %#v", testName, testOutboxTableName, dao.GetOutboxTable())
+	}
+}
+
+func TestGenericDaoSqlite_Outbox(t *testing.T) {
+	testName := "TestGenericDaoSqlite_Outbox"
+	dao := _initOutboxDaoSqlite(t, testName)
+	defer dao.sqlConnect.Close()
+
+	user := &UserBoSql{Id: "1", Username: "btnguyen2k", Name: "Thanh Nguyen", Created: time.Now()}
+	if numRows, err := dao.GdaoCreate(dao.tableName, dao.toGbo(user)); numRows != 1 || err != nil {
+		t.Fatalf("%s failed: %#v / %#v", testName+"/GdaoCreate", numRows, err)
+	}
+	user.Name = "Nguyen Ba Thanh"
+	if numRows, err := dao.GdaoUpdate(dao.tableName, dao.toGbo(user)); numRows != 1 || err != nil {
+		t.Fatalf("%s failed: %#v / %#v", testName+"/GdaoUpdate", numRows, err)
+	}
+	if numRows, err := dao.GdaoSave(dao.tableName, dao.toGbo(&UserBoSql{Id: "2", Username: "user2"})); numRows != 1 || err != nil {
+		t.Fatalf("%s failed: %#v / %#v", testName+"/GdaoSave", numRows, err)
+	}
+	if numRows, err := dao.GdaoDelete(dao.tableName, dao.toGbo(user)); numRows != 1 || err != nil {
+		t.Fatalf("%s failed: %#v / %#v", testName+"/GdaoDelete", numRows, err)
+	}
+	// no event is written if no row is affected
+	dao.GdaoUpdate(dao.tableName, dao.toGbo(&UserBoSql{Id: "100"}))
+
+	relay := NewOutboxRelay(dao, nil)
+	events, err := relay.FetchPending(nil)
+	if err != nil || len(events) != 4 {
+		t.Fatalf("%s failed: expected %#v pending events but received %#v / %#v", testName, 4, len(events), err)
+	}
+	// events are versioned per key
+	eventsOfKey := make(map[string][]*OutboxEvent)
+	for i, event := range events {
+		if event.StorageId != dao.tableName || event.CreatedAt.IsZero() {
+			t.Fatalf("%s failed: unexpected event %#v", testName, event)
+		}
+		if i > 0 && event.Seq < events[i-1].Seq {
+			t.Fatalf("%s failed: events should be ordered by sequence", testName)
+		}
+		eventsOfKey[event.Key] = append(eventsOfKey[event.Key], event)
+	}
+	if len(eventsOfKey) != 2 {
+		t.Fatalf("%s failed: expected %#v keys but received %#v", testName, 2, len(eventsOfKey))
+	}
+	for _, list := range eventsOfKey {
+		expectedOps := []string{OutboxOpSave}
+		if len(list) > 1 {
+			expectedOps = []string{OutboxOpCreate, OutboxOpUpdate, OutboxOpDelete}
+			if string(list[1].Payload) != string(dao.toGbo(user).GboToJsonUnsafe()) {
+				t.Fatalf("%s failed: unexpected payload %s", testName, list[1].Payload)
+			}
+		}
+		if len(list) != len(expectedOps) {
+			t.Fatalf("%s failed: expected %#v events but received %#v", testName, len(expectedOps), len(list))
+		}
+		for i, op := range expectedOps {
+			if list[i].Operation != op || list[i].Seq != int64(i+1) {
+				t.Fatalf("%s failed: unexpected event %#v", testName, list[i])
+			}
+		}
+	}
+}
+
+func TestGenericDaoSqlite_OutboxRollback(t *testing.T) {
+	testName := "TestGenericDaoSqlite_OutboxRollback"
+	dao := _initOutboxDaoSqlite(t, testName)
+	defer dao.sqlConnect.Close()
+
+	tx, _ := dao.StartTx(nil)
+	dao.GdaoCreateWithTx(nil, tx, dao.tableName, dao.toGbo(&UserBoSql{Id: "1", Username: "user1"}))
+	tx.Rollback()
+	if events, err := NewOutboxRelay(dao, nil).FetchPending(nil); err != nil || len(events) != 0 {
+		t.Fatalf("%s failed: event should have been rolled back: %#v / %#v", testName, events, err)
+	}
+
+	// event insertion fails: the write is rolled back too
+	dao.SetOutboxTable("table_not_exists")
+	if _, err := dao.GdaoCreate(dao.tableName, dao.toGbo(&UserBoSql{Id: "1", Username: "user1"})); err == nil {
+		t.Fatalf("%s failed: GdaoCreate should fail", testName)
+	}
+	if bo, _ := dao.GdaoFetchOne(dao.tableName, dao.GdaoCreateFilter(dao.tableName, dao.toGbo(&UserBoSql{Id: "1"}))); bo != nil {
+		t.Fatalf("%s failed: write should have been rolled back", testName)
+	}
+}
+
+func TestGenericDaoSqlite_OutboxRelay(t *testing.T) {
+	testName := "TestGenericDaoSqlite_OutboxRelay"
+	dao := _initOutboxDaoSqlite(t, testName)
+	defer dao.sqlConnect.Close()
+
+	dao.GdaoCreate(dao.tableName, dao.toGbo(&UserBoSql{Id: "1", Username: "user1"}))
+	dao.GdaoCreate(dao.tableName, dao.toGbo(&UserBoSql{Id: "2", Username: "user2"}))
+	dao.GdaoUpdate(dao.tableName, dao.toGbo(&UserBoSql{Id: "1", Username: "user1", Name: "User 1"}))
+
+	published := make([]*OutboxEvent, 0)
+	failKey := ""
+	relay := NewOutboxRelay(dao, OutboxPublisherFunc(func(_ context.Context, event *OutboxEvent) error {
+		if event.Key == failKey {
+			return errors.New("publish failed")
+		}
+		published = append(published, event)
+		return nil
+	})).SetRetryInterval(0)
+	events, _ := relay.FetchPending(nil)
+	failKey = events[0].Key
+
+	// events of the failed key are held back, other keys are delivered
+	if numDelivered, err := relay.RelayOnce(nil); numDelivered != 1 || err == nil {
+		t.Fatalf("%s failed: %#v / %#v", testName, numDelivered, err)
+	}
+	if len(published) != 1 || published[0].Id != events[1].Id {
+		t.Fatalf("%s failed: only event of the other key should have been published", testName)
+	}
+
+	// retry delivers remaining events in order
+	failKey = ""
+	if numDelivered, err := relay.RelayOnce(nil); numDelivered != 2 || err != nil {
+		t.Fatalf("%s failed: %#v / %#v", testName, numDelivered, err)
+	}
+	if len(published) != 3 || published[1].Id != events[0].Id || published[2].Id != events[2].Id {
+		t.Fatalf("%s failed: events of the same key should be published in order", testName)
+	}
+	if numDelivered, err := relay.RelayOnce(nil); numDelivered != 0 || err != nil {
+		t.Fatalf("%s failed: %#v / %#v", testName, numDelivered, err)
+	}
+
+	if numRows, err := relay.PurgeDelivered(nil, time.Hour); numRows != 0 || err != nil {
+		t.Fatalf("%s failed: %#v / %#v", testName, numRows, err)
+	}
+	relay.funcNow = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	// the latest event of each key is kept as the key's high-water mark
+	if numRows, err := relay.PurgeDelivered(nil, time.Hour); numRows != 1 || err != nil {
+		t.Fatalf("%s failed: %#v / %#v", testName, numRows, err)
+	}
+	if numRows, err := relay.PurgeDelivered(nil, time.Hour); numRows != 0 || err != nil {
+		t.Fatalf("%s failed: %#v / %#v", testName, numRows, err)
+	}
+	dao.GdaoUpdate(dao.tableName, dao.toGbo(&UserBoSql{Id: "1", Username: "user1", Name: "User One"}))
+	events, _ = relay.FetchPending(nil)
+	if len(events) != 1 || events[0].Seq != 3 {
+		t.Fatalf("%s failed: versions should keep increasing after purge: %#v", testName, events)
+	}
+}
+
+// userDaoSqlRawId uses the id as-is (without converting it to string) in GdaoCreateFilter.
+type userDaoSqlRawId struct {
+	*UserDaoSql
+}
+
+// GdaoCreateFilter implements godal.IGenericDao.GdaoCreateFilter.
+func (dao *userDaoSqlRawId) GdaoCreateFilter(_ string, bo godal.IGenericBo) godal.FilterOpt {
+	return &godal.FilterOptFieldOpValue{FieldName: fieldGboId, Operator: godal.FilterOpEqual, Value: bo.GboGetAttrUnsafe(fieldGboId, nil)}
+}
+
+func TestGenericDaoSqlite_OutboxKeyNumberTypes(t *testing.T) {
+	testName := "TestGenericDaoSqlite_OutboxKeyNumberTypes"
+	userDao := _initOutboxDaoSqlite(t, testName)
+	defer userDao.sqlConnect.Close()
+	rowMapper := userDao.GetRowMapper()
+	dao := &userDaoSqlRawId{UserDaoSql: userDao}
+	dao.GenericDaoSql = NewGenericDaoSql(userDao.GetSqlConnect(), godal.NewAbstractGenericDao(dao))
+	dao.SetSqlFlavor(sql.FlavorSqlite).SetRowMapper(rowMapper)
+	dao.SetOutboxTable(testOutboxTableName)
+
+	for _, id := range []interface{}{int64(1), float64(1)} {
+		bo := godal.NewGenericBo()
+		bo.GboSetAttr(fieldGboId, id)
+		bo.GboSetAttr(fieldGboUsername, fmt.Sprintf("%T", id))
+		if _, err := dao.GdaoSave(dao.tableName, bo); err != nil {
+			t.Fatalf("%s failed: %#v / %e", testName, id, err)
+		}
+	}
+	events, err := NewOutboxRelay(dao, nil).FetchPending(nil)
+	if err != nil || len(events) != 2 {
+		t.Fatalf("%s failed: expected %#v pending events but received %#v / %#v", testName, 2, len(events), err)
+	}
+	if events[0].Key != events[1].Key || events[0].Seq != 1 || events[1].Seq != 2 {
+		t.Fatalf("%s failed: events of the same id should share the key: %#v / %#v", testName, events[0], events[1])
+	}
+}
+
+func TestGenericDaoSqlite_OutboxConflict(t *testing.T) {
+	testName := "TestGenericDaoSqlite_OutboxConflict"
+	dao := _initOutboxDaoSqlite(t, testName)
+	defer dao.sqlConnect.Close()
+
+	// simulate a concurrent writer: every event of a key after the first one violates the unique index
+	sqlStm := fmt.Sprintf("CREATE UNIQUE INDEX uidx_%s_key ON %s (%s, %s)", testOutboxTableName, testOutboxTableName, OutboxColStorageId, OutboxColKey)
+	if _, err := dao.GetSqlConnect().GetDB().Exec(sqlStm); err != nil {
+		t.Fatalf("%s failed: %e", testName, err)
+	}
+	dao.GdaoCreate(dao.tableName, dao.toGbo(&UserBoSql{Id: "1", Username: "user1"}))
+	_, err := dao.GdaoUpdate(dao.tableName, dao.toGbo(&UserBoSql{Id: "1", Username: "user1", Name: "User 1"}))
+	if !errors.Is(err, ErrOutboxConflict) || errors.Is(err, godal.ErrGdaoDuplicatedEntry) {
+		t.Fatalf("%s failed: expected ErrOutboxConflict but received %#v", testName, err)
+	}
+	if !dao.IsErrorTransient(err) {
+		t.Fatalf("%s failed: ErrOutboxConflict should be transient", testName)
+	}
+	if bo, _ := dao.GdaoFetchOne(dao.tableName, dao.GdaoCreateFilter(dao.tableName, dao.toGbo(&UserBoSql{Id: "1"}))); dao.toUser(bo).Name != "" {
+		t.Fatalf("%s failed: write should have been rolled back", testName)
+	}
+}
+
+func TestGenericDaoSqlite_OutboxRelayBlockedKey(t *testing.T) {
+	testName := "TestGenericDaoSqlite_OutboxRelayBlockedKey"
+	dao := _initOutboxDaoSqlite(t, testName)
+	defer dao.sqlConnect.Close()
+
+	for i := 0; i < 3; i++ {
+		dao.GdaoSave(dao.tableName, dao.toGbo(&UserBoSql{Id: "1", Username: fmt.Sprintf("user1-%d", i)}))
+	}
+	for i := 0; i < 5; i++ {
+		dao.GdaoSave(dao.tableName, dao.toGbo(&UserBoSql{Id: "2", Username: fmt.Sprintf("user2-%d", i)}))
+	}
+
+	published := make([]*OutboxEvent, 0)
+	failKey := dao.toGbo(&UserBoSql{Id: "1"})
+	relay := NewOutboxRelay(dao, OutboxPublisherFunc(func(_ context.Context, event *OutboxEvent) error {
+		if event.Key == godal.FilterToCacheKey(dao.GdaoCreateFilter(dao.tableName, failKey)) {
+			return errors.New("publish failed")
+		}
+		published = append(published, event)
+		return nil
+	})).SetBatchSize(2).SetRetryInterval(time.Hour)
+
+	// the failed key does not hold up events of other keys in subsequent polls
+	for {
+		if numDelivered, _ := relay.RelayOnce(nil); numDelivered == 0 {
+			break
+		}
+	}
+	if len(published) != 5 {
+		t.Fatalf("%s failed: expected %#v published events but received %#v", testName, 5, len(published))
+	}
+	for i, event := range published {
+		if event.Seq != int64(i+1) {
+			t.Fatalf("%s failed: events should be published in order, received %#v", testName, event)
+		}
+	}
+
+	// the failed key is retried after the retry interval
+	failKey = dao.toGbo(&UserBoSql{Id: "100"})
+	relay.funcNow = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	if numDelivered, err := relay.RelayOnce(nil); numDelivered != 2 || err != nil {
+		t.Fatalf("%s failed: %#v / %#v", testName, numDelivered, err)
+	}
+}
+
+func TestOutboxRelay_Run(t *testing.T) {
+	testName := "TestOutboxRelay_Run"
+	dao := _initOutboxDaoSqlite(t, testName)
+	defer dao.sqlConnect.Close()
+
+	numPublished := 0
+	relay := NewOutboxRelay(dao, OutboxPublisherFunc(func(_ context.Context, _ *OutboxEvent) error {
+		numPublished++
+		return nil
+	})).SetBatchSize(2).SetPollInterval(10 * time.Millisecond)
+	for i := 0; i < 5; i++ {
+		dao.GdaoCreate(dao.tableName, dao.toGbo(&UserBoSql{Id: fmt.Sprintf("%d", i), Username: fmt.Sprintf("user%d", i)}))
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	if err := relay.Run(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("%s failed: %#v", testName, err)
+	}
+	if numPublished != 5 {
+		t.Fatalf("%s failed: expected %#v published events but received %#v", testName, 5, numPublished)
+	}
+}
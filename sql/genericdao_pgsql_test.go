@@ -231,3 +231,63 @@ func TestGenericDaoPgsql_FilterNotNull(t *testing.T) {
 	}
 	dotestGenericDaoSqlGdaoFilterNotNull(t, testName, dao)
 }
+
+func TestGenericDaoPgsql_AutoFields(t *testing.T) {
+	testName := "TestGenericDaoPgsql_AutoFields"
+	dao := _initDao(os.Getenv(envPgsqlDriver), os.Getenv(envPgsqlUrl), testTableName, sql.FlavorPgSql)
+	if dao == nil {
+		t.SkipNow()
+	}
+	defer dao.sqlConnect.Close()
+
+	err := prepareTablePgsql(dao.GetSqlConnect(), dao.tableName)
+	if err != nil {
+		t.Fatalf("%s failed: %e", testName+"/prepareTablePgsql", err)
+	}
+	dotestGenericDaoSqlAutoFields(t, testName, dao)
+}
+
+func TestGenericDaoPgsql_Schema(t *testing.T) {
+	testName := "TestGenericDaoPgsql_Schema"
+	dao := _initDao(os.Getenv(envPgsqlDriver), os.Getenv(envPgsqlUrl), testTableName, sql.FlavorPgSql)
+	if dao == nil {
+		t.SkipNow()
+	}
+	defer dao.sqlConnect.Close()
+
+	err := prepareTablePgsql(dao.GetSqlConnect(), dao.tableName)
+	if err != nil {
+		t.Fatalf("%s failed: %e", testName+"/prepareTablePgsql", err)
+	}
+	dotestGenericDaoSqlSchema(t, testName, dao)
+}
+
+func TestGenericDaoPgsql_StructRowMapper(t *testing.T) {
+	testName := "TestGenericDaoPgsql_StructRowMapper"
+	dao := _initDao(os.Getenv(envPgsqlDriver), os.Getenv(envPgsqlUrl), testTableName, sql.FlavorPgSql)
+	if dao == nil {
+		t.SkipNow()
+	}
+	defer dao.sqlConnect.Close()
+
+	err := prepareTablePgsql(dao.GetSqlConnect(), dao.tableName)
+	if err != nil {
+		t.Fatalf("%s failed: %e", testName+"/prepareTablePgsql", err)
+	}
+	dotestGenericDaoSqlStructRowMapper(t, testName, dao)
+}
+
+func TestGenericDaoPgsql_UpdateChangedOnly(t *testing.T) {
+	testName := "TestGenericDaoPgsql_UpdateChangedOnly"
+	dao := _initDao(os.Getenv(envPgsqlDriver), os.Getenv(envPgsqlUrl), testTableName, sql.FlavorPgSql)
+	if dao == nil {
+		t.SkipNow()
+	}
+	defer dao.sqlConnect.Close()
+
+	err := prepareTablePgsql(dao.GetSqlConnect(), dao.tableName)
+	if err != nil {
+		t.Fatalf("%s failed: %e", testName+"/prepareTablePgsql", err)
+	}
+	dotestGenericDaoSqlUpdateChangedOnly(t, testName, dao)
+}
@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"reflect"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -31,11 +33,14 @@ const (
 //
 // Implementation rules:
 //   - ToRow: transform godal.IGenericBo to map[string]interface{}.
-//     - Only top level fields are converted. Column/Field name transformation: see below.
+//     - Only top level fields are converted, unless NestedFieldSeparator is set. Column/Field name transformation: see below.
 // 	   - If field is bool or string or time.Time: its value is converted as-is
 // 	   - If field is int (int8 to int64): its value is converted to int64
 // 	   - If field is uint (uint8 to uint64): its value is converted to uint64
 // 	   - If field is float32 or float64: its value is converted to float64
+// 	   - If field is an arbitrary-precision number (json.Number, decimal.Decimal, *big.Int or *big.Float, see
+// 	     godal.ExactNumberString): its value is converted to its exact decimal string, e.g. for PostgreSQL NUMERIC or
+// 	     MySQL DECIMAL columns (since v0.7.0)
 // 	   - Field is one of other types: its value is converted to JSON string
 //   - ToBo: expect input is a map[string]interface{}, or JSON data (string or array/slice of bytes), transform it to godal.IGenericBo. Column/Field name transformation: see below.
 //   - ColumnsList: lookup column-list from a 'columns-list map', returns []string{"*"} if not found
@@ -57,6 +62,14 @@ type GenericRowMapperSql struct {
 
 	// ColumnsListMap holds mappings of {table-name:[list of column names]}
 	ColumnsListMap map[string][]string
+
+	// NestedFieldSeparator, if not empty, makes ToRow map nested objects to prefixed columns, e.g. with separator "__"
+	// field "address" {"city":"HCM"} is mapped to column "address__city"; ToBo nests such columns back. Choose a
+	// separator that does not appear in column names (e.g. "__" rather than "_"). Default value: "" (nested objects
+	// are converted to JSON strings).
+	//
+	// Available since v0.7.0
+	NestedFieldSeparator string
 }
 
 var (
@@ -139,44 +152,85 @@ func (mapper *GenericRowMapperSql) ToRow(tableName string, gbo godal.IGenericBo)
 		if fieldName, err = reddo.ToString(field); err != nil {
 			return
 		}
-		colName := mapper.translateGboFieldToColName(tableName, mapper.transformName(fieldName))
-		v := reflect.ValueOf(value)
-		if value == nil || (v.Kind() == reflect.Ptr && v.IsNil()) {
-			row[colName] = nil
-			return
-		}
-		for ; v.Kind() == reflect.Ptr; v = v.Elem() {
-			// unwrap if pointer
-		}
-		k := v.Kind()
-		switch k {
-		case reflect.Bool:
-			row[colName] = v.Bool()
-		case reflect.String:
-			row[colName] = v.String()
-		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-			row[colName] = v.Int()
-		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-			row[colName] = v.Uint()
-		case reflect.Float32, reflect.Float64:
-			row[colName] = v.Float()
-		default:
-			if nullableTypes[k] && v.IsNil() {
-				row[colName] = nil
-			} else if v.Type() == typeTime {
-				row[colName] = v.Interface().(time.Time)
-			} else {
-				if js, e := json.Marshal(v.Interface()); e != nil {
-					err = e
-				} else {
-					row[colName] = string(js)
+		if mapper.NestedFieldSeparator != "" {
+			mapper.flattenNested(fieldName, value, func(fieldName string, value interface{}) {
+				if err == nil {
+					err = mapper.toColumn(row, tableName, fieldName, value)
 				}
-			}
+			})
+			return
 		}
+		err = mapper.toColumn(row, tableName, fieldName, value)
 	})
 	return row, err
 }
 
+// flattenNested calls 'emit' for each non-object value nested in 'value', with field names prefixed by parent names
+// and NestedFieldSeparator (e.g. "address__city").
+func (mapper *GenericRowMapperSql) flattenNested(fieldName string, value interface{}, emit func(fieldName string, value interface{})) {
+	v := reflect.ValueOf(value)
+	for ; v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface; v = v.Elem() {
+		// unwrap if pointer
+	}
+	if v.Kind() != reflect.Map || v.Type().Key().Kind() != reflect.String || v.Len() == 0 {
+		emit(fieldName, value)
+		return
+	}
+	keys := make([]string, 0, v.Len())
+	for _, k := range v.MapKeys() {
+		keys = append(keys, k.String())
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		nested := v.MapIndex(reflect.ValueOf(k).Convert(v.Type().Key())).Interface()
+		mapper.flattenNested(fieldName+mapper.NestedFieldSeparator+k, nested, emit)
+	}
+}
+
+// toColumn converts a BO field to a column value, see GenericRowMapperSql for conversion rules.
+func (mapper *GenericRowMapperSql) toColumn(row map[string]interface{}, tableName, fieldName string, value interface{}) error {
+	colName := mapper.translateGboFieldToColName(tableName, mapper.transformName(fieldName))
+	v := reflect.ValueOf(value)
+	if value == nil || (v.Kind() == reflect.Ptr && v.IsNil()) {
+		row[colName] = nil
+		return nil
+	}
+	if number, ok := godal.ExactNumberString(value); ok {
+		// exact decimal string, accepted by NUMERIC/DECIMAL columns (float64 would lose precision)
+		row[colName] = number
+		return nil
+	}
+	for ; v.Kind() == reflect.Ptr; v = v.Elem() {
+		// unwrap if pointer
+	}
+	k := v.Kind()
+	switch k {
+	case reflect.Bool:
+		row[colName] = v.Bool()
+	case reflect.String:
+		row[colName] = v.String()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		row[colName] = v.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		row[colName] = v.Uint()
+	case reflect.Float32, reflect.Float64:
+		row[colName] = v.Float()
+	default:
+		if nullableTypes[k] && v.IsNil() {
+			row[colName] = nil
+		} else if v.Type() == typeTime {
+			row[colName] = v.Interface().(time.Time)
+		} else {
+			js, err := json.Marshal(v.Interface())
+			if err != nil {
+				return err
+			}
+			row[colName] = string(js)
+		}
+	}
+	return nil
+}
+
 // ToBo implements godal.IRowMapper.ToBo.
 //
 // This function expects input to be a map[string]interface{}, or JSON data (string or array/slice of bytes), transforms it to godal.IGenericBo.
@@ -193,11 +247,11 @@ func (mapper *GenericRowMapperSql) ToBo(tableName string, row interface{}) (goda
 		}
 		return mapper.ToBo(tableName, *m)
 	case map[string]interface{}:
-		bo := godal.NewGenericBo()
+		fields := make(map[string]interface{})
 		for colName, v := range row.(map[string]interface{}) {
-			bo.GboSetAttr(mapper.translateColNameToGboField(tableName, mapper.transformName(colName)), v)
+			fields[mapper.translateColNameToGboField(tableName, mapper.transformName(colName))] = v
 		}
-		return bo, nil
+		return mapper.fieldsToBo(fields)
 	case string:
 		var data interface{}
 		json.Unmarshal([]byte(row.(string)), &data)
@@ -231,12 +285,12 @@ func (mapper *GenericRowMapperSql) ToBo(tableName string, row interface{}) (goda
 	}
 	switch v.Kind() {
 	case reflect.Map:
-		bo := godal.NewGenericBo()
+		fields := make(map[string]interface{})
 		for iter := v.MapRange(); iter.Next(); {
 			colName, _ := reddo.ToString(iter.Key().Interface())
-			bo.GboSetAttr(mapper.translateColNameToGboField(tableName, mapper.transformName(colName)), iter.Value().Interface())
+			fields[mapper.translateColNameToGboField(tableName, mapper.transformName(colName))] = iter.Value().Interface()
 		}
-		return bo, nil
+		return mapper.fieldsToBo(fields)
 	case reflect.String:
 		var data interface{}
 		json.Unmarshal([]byte(v.Interface().(string)), &data)
@@ -261,6 +315,19 @@ func (mapper *GenericRowMapperSql) ToBo(tableName string, row interface{}) (goda
 	return nil, fmt.Errorf("cannot construct godal.IGenericBo from input %v", row)
 }
 
+// fieldsToBo builds a BO from {field-name: value}. If NestedFieldSeparator is set, fields whose names contain the
+// separator are nested back (e.g. "address__city" becomes "address.city").
+func (mapper *GenericRowMapperSql) fieldsToBo(fields map[string]interface{}) (godal.IGenericBo, error) {
+	if mapper.NestedFieldSeparator != "" {
+		return godal.NewGenericBoFromFlat(fields, mapper.NestedFieldSeparator)
+	}
+	bo := godal.NewGenericBo()
+	for field, v := range fields {
+		bo.GboSetAttr(field, v)
+	}
+	return bo, nil
+}
+
 var allColumns = []string{"*"}
 
 // ColumnsList implements godal.IRowMapper.ColumnsList.
@@ -279,10 +346,40 @@ func (mapper *GenericRowMapperSql) ColumnsList(tableName string) []string {
 // ToDbColName implements godal.IRowMapper.ToDbColName.
 //   - firstly, field-name is transformed to new-field-name based on NameTransformation setting
 //   - then, new-field-name is feed to GboFieldToColNameTranslator to look up for the database-column-name
+//
+// If NestedFieldSeparator is set, nested field paths are mapped to prefixed columns, e.g. "address.city" to
+// "address__city" (since v0.7.0). Quoted path segments are unquoted, e.g. `["first.name"]` is mapped to column
+// "first.name" (since v0.7.0).
 func (mapper *GenericRowMapperSql) ToDbColName(tableName, fieldName string) string {
+	if mapper.NestedFieldSeparator != "" || strings.ContainsAny(fieldName, `"'`) {
+		fieldName = mapper.pathToColName(fieldName)
+	}
 	return mapper.translateGboFieldToColName(tableName, mapper.transformName(fieldName))
 }
 
+// pathToColName joins segments of a field path with NestedFieldSeparator (or "." if not set). Paths that can not
+// be parsed are returned as-is.
+func (mapper *GenericRowMapperSql) pathToColName(path string) string {
+	p, err := godal.ParsePath(path)
+	if err != nil {
+		return path
+	}
+	sep := mapper.NestedFieldSeparator
+	if sep == "" {
+		sep = "."
+	}
+	segs := p.Segments()
+	parts := make([]string, len(segs))
+	for i, seg := range segs {
+		if seg.Index >= 0 {
+			parts[i] = strconv.Itoa(seg.Index)
+		} else {
+			parts[i] = seg.Key
+		}
+	}
+	return strings.Join(parts, sep)
+}
+
 // ToBoFieldName implements godal.IRowMapper.ToBoFieldName.
 //   - firstly, database-column-name is transform to new-database-column-name based on NameTransformation setting
 //   - then, new database-column-name is feed to ColNameToGboFieldTranslator to look up for the field-name
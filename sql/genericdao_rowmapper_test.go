@@ -3,10 +3,13 @@ package sql
 import (
 	"encoding/json"
 	"fmt"
+	"math/big"
+	"reflect"
 	"testing"
 	"time"
 
 	"github.com/btnguyen2k/consu/reddo"
+	"github.com/shopspring/decimal"
 
 	"github.com/btnguyen2k/godal"
 )
@@ -207,6 +210,22 @@ func TestGenericRowMapperSql_ToRow(t *testing.T) {
 	}
 }
 
+func TestGenericRowMapperSql_ToRow_ExactNumbers(t *testing.T) {
+	name := "TestGenericRowMapperSql_ToRow_ExactNumbers"
+	rm := &GenericRowMapperSql{NestedFieldSeparator: "__"}
+	gbo := godal.NewGenericBo()
+	gbo.(*godal.GenericBo).GboSetUseNumber(true).GboFromJson([]byte(`{"amount":12345678901234567890.12,"price":{"net":0.10}}`))
+	gbo.GboSetAttr("dec", decimal.RequireFromString("-1.5"))
+	gbo.GboSetAttr("bigint", new(big.Int).Lsh(big.NewInt(1), 70))
+	gbo.GboSetAttr("float", 2.5)
+	row, err := rm.ToRow("*", gbo)
+	expected := map[string]interface{}{"amount": "12345678901234567890.12", "price__net": "0.10", "dec": "-1.5",
+		"bigint": "1180591620717411303424", "float": 2.5}
+	if err != nil || !reflect.DeepEqual(row, expected) {
+		t.Fatalf("%s failed: expected %#v but received %#v / %s", name, expected, row, err)
+	}
+}
+
 func TestGenericRowMapperSql_ToRow_Nil(t *testing.T) {
 	name := "TestGenericRowMapperSql_ToRow_Nil"
 	rm := &GenericRowMapperSql{}
@@ -875,3 +894,52 @@ func TestGenericRowMapperSql_ToBoFieldName_UpperCase(t *testing.T) {
 		t.Fatalf("%s failed: expected %#v but received %#v", name, expected, fieldName)
 	}
 }
+
+func TestGenericRowMapperSql_NestedFieldSeparator(t *testing.T) {
+	name := "TestGenericRowMapperSql_NestedFieldSeparator"
+	rm := &GenericRowMapperSql{NameTransformation: NameTransfLowerCase, NestedFieldSeparator: "__"}
+	gbo := godal.NewGenericBo()
+	gbo.GboFromJson([]byte(`{"id":"1","address":{"city":"HCM","geo":{"lat":10.8,"lng":106.6}},"tags":["a"],"meta":{}}`))
+	row, err := rm.ToRow("table", gbo)
+	if err != nil {
+		t.Fatalf("%s failed: %s", name, err)
+	}
+	expected := map[string]interface{}{"id": "1", "address__city": "HCM", "address__geo__lat": 10.8, "address__geo__lng": 106.6,
+		"tags": `["a"]`, "meta": "{}"}
+	if !reflect.DeepEqual(row, expected) {
+		t.Fatalf("%s failed: expected %#v but received %#v", name, expected, row)
+	}
+
+	gbo, err = rm.ToBo("table", map[string]interface{}{"ID": "1", "ADDRESS__CITY": "HCM", "ADDRESS__GEO__LAT": 10.8, "TAGS": `["a"]`})
+	if err != nil {
+		t.Fatalf("%s failed: %s", name, err)
+	}
+	if js := string(gbo.GboToJsonUnsafe()); js != `{"address":{"city":"HCM","geo":{"lat":10.8}},"id":"1","tags":"[\"a\"]"}` {
+		t.Fatalf("%s failed: %s", name, js)
+	}
+	if _, err = rm.ToBo("table", map[string]interface{}{"a": 1, "a__b": 2}); err == nil {
+		t.Fatalf("%s failed: conflicting columns should be rejected", name)
+	}
+}
+
+func TestGenericRowMapperSql_ToDbColName_Nested(t *testing.T) {
+	name := "TestGenericRowMapperSql_ToDbColName_Nested"
+	rm := &GenericRowMapperSql{NestedFieldSeparator: "__"}
+	if colName := rm.ToDbColName("table", "address.geo.lat"); colName != "address__geo__lat" {
+		t.Fatalf("%s failed: %#v", name, colName)
+	}
+	rm.NestedFieldSeparator = ""
+	if colName := rm.ToDbColName("table", "address.geo.lat"); colName != "address.geo.lat" {
+		t.Fatalf("%s failed: %#v", name, colName)
+	}
+	if colName := rm.ToDbColName("table", `["first.name"]`); colName != "first.name" {
+		t.Fatalf("%s failed: %#v", name, colName)
+	}
+	rm.NestedFieldSeparator = "__"
+	if colName := rm.ToDbColName("table", godal.Path("labels").Key("example.com").Index(1).String()); colName != "labels__example.com__1" {
+		t.Fatalf("%s failed: %#v", name, colName)
+	}
+	if colName := rm.ToDbColName("table", "a..b"); colName != "a..b" {
+		t.Fatalf("%s failed: %#v", name, colName)
+	}
+}
@@ -300,10 +300,42 @@ type IGenericDaoSql interface {
 	// IsErrorDuplicatedEntry checks if the error was caused by conflicting in database table entries.
 	IsErrorDuplicatedEntry(err error) bool
 
+	// IsErrorTransient checks if the error is transient (e.g. deadlock or serialization failure) and the operation can be retried.
+	//
+	// Available since v0.7.0
+	IsErrorTransient(err error) bool
+
 	// WrapTransaction wraps a function inside a transaction.
 	//
 	// txFunc: the function to wrap. If the function returns error, the transaction will be aborted, otherwise transaction is committed.
 	WrapTransaction(ctx context.Context, txFunc func(ctx context.Context, tx *gosql.Tx) error) error
+
+	// PrepareBoForWrite prepares a BO before it is written to a table (see godal.AbstractGenericDao.PrepareBoForWrite).
+	//
+	// Available since v0.7.0
+	PrepareBoForWrite(ctx context.Context, tableName string, bo godal.IGenericBo, op godal.WriteOp) error
+
+	// GetOutboxTable returns the name of the outbox table, empty if the outbox is disabled.
+	//
+	// Available since v0.7.0
+	GetOutboxTable() string
+
+	// SetOutboxTable enables the transactional outbox: create/update/save/delete operations also insert an event row
+	// into the outbox table within the same transaction. Supply an empty string to disable the outbox.
+	//
+	// Available since v0.7.0
+	SetOutboxTable(tableName string) IGenericDaoSql
+
+	// GetUpdateChangedOnly returns 'true' if update operations write only changed columns, 'false' otherwise.
+	//
+	// Available since v0.7.0
+	GetUpdateChangedOnly() bool
+
+	// SetUpdateChangedOnly enables/disables writing only columns of fields that have been changed since BOs were loaded
+	// (see godal.IDirtyTrackingBo) on update operations.
+	//
+	// Available since v0.7.0
+	SetUpdateChangedOnly(enabled bool) IGenericDaoSql
 }
 
 // FilterOperatorTranslator takes a godal.FilterOperator and translates to database-compatible operator string.
@@ -353,6 +385,9 @@ func DefaultFilterOperatorTranslator(op godal.FilterOperator) (string, error) {
 //   - (y) GdaoUpdate(tableName string, bo godal.IGenericBo) (int, error)
 //   - (y) GdaoSave(tableName string, bo godal.IGenericBo) (int, error)
 //
+// Since v0.7.0, GenericDaoSql also implements godal.IGenericDaoWithContext; update, save and delete honor the write
+// condition carried by the context (see godal.WithWriteCondition).
+//
 // Note: IGenericDaoSql and GenericDaoSql should be in sync.
 type GenericDaoSql struct {
 	*godal.AbstractGenericDao
@@ -361,6 +396,8 @@ type GenericDaoSql struct {
 	txIsolationLevel             gosql.IsolationLevel
 	funcFilterOperatorTranslator FilterOperatorTranslator
 	funcNewPlaceholderGenerator  NewPlaceholderGenerator
+	outboxTable                  string
+	updateChangedOnly            bool
 }
 
 // SetRowMapper attaches an IRowMapper to the DAO for latter use.
@@ -431,6 +468,28 @@ func (dao *GenericDaoSql) SetTxModeOnWrite(enabled bool) IGenericDaoSql {
 	return dao
 }
 
+// GetUpdateChangedOnly returns 'true' if update operations write only changed columns, 'false' otherwise.
+//
+// Available since v0.7.0
+func (dao *GenericDaoSql) GetUpdateChangedOnly() bool {
+	return dao.updateChangedOnly
+}
+
+// SetUpdateChangedOnly enables/disables writing only changed columns on update operations (GdaoUpdate and the update
+// step of GdaoSave).
+//
+// If enabled, BOs fetched by the DAO track changes (see godal.IDirtyTrackingBo, implemented by godal.GenericBo), and
+// the UPDATE statement sets only columns of top-level fields that have been added or changed since the BO was fetched;
+// BOs that do not track changes, or have no change, are written in full. Fields removed from the BO are not written (same as
+// when the option is disabled). The BO's snapshot is not reset after writing: call GboResetDirty once the write (and
+// its transaction) succeeds.
+//
+// Available since v0.7.0
+func (dao *GenericDaoSql) SetUpdateChangedOnly(enabled bool) IGenericDaoSql {
+	dao.updateChangedOnly = enabled
+	return dao
+}
+
 // GetTxIsolationLevel returns current transaction isolation level setting.
 //
 // Available: since v0.1.0
@@ -733,6 +792,14 @@ func (dao *GenericDaoSql) SqlUpdateEx(ctx context.Context, builder ISqlBuilder,
 	return dao.SqlExecute(ctx, tx, query, values...)
 }
 
+// trackChanges turns change tracking on for a fetched BO if only changed columns are written on update, see
+// SetUpdateChangedOnly.
+func (dao *GenericDaoSql) trackChanges(bo godal.IGenericBo) {
+	if tracker, ok := bo.(godal.IDirtyTrackingBo); ok && dao.updateChangedOnly {
+		tracker.GboResetDirty()
+	}
+}
+
 /*----------------------------------------------------------------------*/
 
 // FetchOne fetches a row from `sql.Rows` and transforms it to godal.IGenericBo.
@@ -744,6 +811,7 @@ func (dao *GenericDaoSql) FetchOne(tableName string, dbRows *gosql.Rows) (godal.
 	e := dao.sqlConnect.FetchRowsCallback(dbRows, func(row map[string]interface{}, e error) bool {
 		if e == nil {
 			bo, err = dao.GetRowMapper().ToBo(tableName, row)
+			dao.trackChanges(bo)
 		} else {
 			err = e
 		}
@@ -771,6 +839,7 @@ func (dao *GenericDaoSql) FetchAll(tableName string, dbRows *gosql.Rows) ([]goda
 			err = e
 			return false
 		}
+		dao.trackChanges(bo)
 		boList = append(boList, bo)
 		return true
 	})
@@ -789,11 +858,25 @@ func (dao *GenericDaoSql) GdaoDelete(tableName string, bo godal.IGenericBo) (int
 	return dao.GdaoDeleteWithTx(nil, nil, tableName, bo)
 }
 
+// GdaoDeleteWithContext implements godal.IGenericDaoWithContext.GdaoDeleteWithContext.
+//
+// Available since v0.7.0
+func (dao *GenericDaoSql) GdaoDeleteWithContext(ctx context.Context, tableName string, bo godal.IGenericBo) (int, error) {
+	return dao.GdaoDeleteWithTx(ctx, nil, tableName, bo)
+}
+
 // GdaoDeleteWithTx is database/sql variant of GdaoDelete.
 //
 // Available: since v0.1.0
 func (dao *GenericDaoSql) GdaoDeleteWithTx(ctx context.Context, tx *gosql.Tx, tableName string, bo godal.IGenericBo) (int, error) {
-	filter := dao.GdaoCreateFilter(tableName, bo)
+	if dao.outboxTable != "" {
+		return dao.outboxWrite(ctx, tx, tableName, OutboxOpDelete, bo, dao.gdaoDeleteWithTx)
+	}
+	return dao.gdaoDeleteWithTx(ctx, tx, tableName, bo)
+}
+
+func (dao *GenericDaoSql) gdaoDeleteWithTx(ctx context.Context, tx *gosql.Tx, tableName string, bo godal.IGenericBo) (int, error) {
+	filter := godal.ApplyWriteCondition(ctx, dao.GdaoCreateFilter(tableName, bo))
 	return dao.GdaoDeleteManyWithTx(ctx, tx, tableName, filter)
 }
 
@@ -802,6 +885,13 @@ func (dao *GenericDaoSql) GdaoDeleteMany(tableName string, filter godal.FilterOp
 	return dao.GdaoDeleteManyWithTx(nil, nil, tableName, filter)
 }
 
+// GdaoDeleteManyWithContext implements godal.IGenericDaoWithContext.GdaoDeleteManyWithContext.
+//
+// Available since v0.7.0
+func (dao *GenericDaoSql) GdaoDeleteManyWithContext(ctx context.Context, tableName string, filter godal.FilterOpt) (int, error) {
+	return dao.GdaoDeleteManyWithTx(ctx, nil, tableName, filter)
+}
+
 // GdaoDeleteManyWithTx is database/sql variant of GdaoDeleteMany.
 //
 // Available: since v0.1.0
@@ -821,6 +911,13 @@ func (dao *GenericDaoSql) GdaoFetchOne(tableName string, filter godal.FilterOpt)
 	return dao.GdaoFetchOneWithTx(nil, nil, tableName, filter)
 }
 
+// GdaoFetchOneWithContext implements godal.IGenericDaoWithContext.GdaoFetchOneWithContext.
+//
+// Available since v0.7.0
+func (dao *GenericDaoSql) GdaoFetchOneWithContext(ctx context.Context, tableName string, filter godal.FilterOpt) (godal.IGenericBo, error) {
+	return dao.GdaoFetchOneWithTx(ctx, nil, tableName, filter)
+}
+
 // GdaoFetchOneWithTx is database/sql variant of GdaoFetchOne.
 //
 // Available: since v0.1.0
@@ -845,6 +942,13 @@ func (dao *GenericDaoSql) GdaoFetchMany(tableName string, filter godal.FilterOpt
 	return dao.GdaoFetchManyWithTx(nil, nil, tableName, filter, sorting, fromOffset, numRows)
 }
 
+// GdaoFetchManyWithContext implements godal.IGenericDaoWithContext.GdaoFetchManyWithContext.
+//
+// Available since v0.7.0
+func (dao *GenericDaoSql) GdaoFetchManyWithContext(ctx context.Context, tableName string, filter godal.FilterOpt, sorting *godal.SortingOpt, fromOffset, numRows int) ([]godal.IGenericBo, error) {
+	return dao.GdaoFetchManyWithTx(ctx, nil, tableName, filter, sorting, fromOffset, numRows)
+}
+
 // GdaoFetchManyWithTx is database/sql variant of GdaoFetchMany.
 //
 // Available: since v0.1.0
@@ -891,27 +995,76 @@ func (dao *GenericDaoSql) IsErrorDuplicatedEntry(err error) bool {
 	return false
 }
 
+// IsErrorTransient checks if the error is transient and the operation can be retried.
+//   - MySQL: 1213 (deadlock found) and 1205 (lock wait timeout exceeded).
+//   - PostgreSQL: 40001 (serialization failure) and 40P01 (deadlock detected).
+//   - MSSQL: 1205 (deadlock victim) and 3960 (snapshot isolation update conflict).
+//   - Oracle: ORA-00060 (deadlock detected) and ORA-08177 (can't serialize access).
+//   - SQLite: SQLITE_BUSY (5) and SQLITE_LOCKED (6).
+//   - ErrOutboxConflict (concurrent writes of the same key with the outbox enabled).
+//
+// This function can be used as godal.TransientErrorClassifier.
+//
+// Available since v0.7.0
+func (dao *GenericDaoSql) IsErrorTransient(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, ErrOutboxConflict) {
+		return true
+	}
+	switch dao.GetSqlFlavor() {
+	case sql.FlavorMySql:
+		return regexp.MustCompile(`\W1213\W|\W1205\W`).FindString(err.Error()) != ""
+	case sql.FlavorPgSql:
+		return regexp.MustCompile(`\W40001\W|\W40P01\W`).FindString(fmt.Sprintf("%e", err)) != ""
+	case sql.FlavorMsSql:
+		return regexp.MustCompile(`\W1205\W|\W3960\W`).FindString(fmt.Sprintf("%e", err)) != ""
+	case sql.FlavorOracle:
+		return regexp.MustCompile(`\WORA\-00060\W|\WORA\-08177\W`).FindString(fmt.Sprintf("%e", err)) != ""
+	case sql.FlavorSqlite:
+		return regexp.MustCompile(`\WErrNo=5\W|\WErrNo=6\W`).FindString(fmt.Sprintf("%e", err)) != ""
+	}
+	return false
+}
+
 // GdaoCreate implements godal.IGenericDao.GdaoCreate.
 func (dao *GenericDaoSql) GdaoCreate(tableName string, bo godal.IGenericBo) (int, error) {
 	return dao.GdaoCreateWithTx(nil, nil, tableName, bo)
 }
 
+// GdaoCreateWithContext implements godal.IGenericDaoWithContext.GdaoCreateWithContext.
+//
+// Available since v0.7.0
+func (dao *GenericDaoSql) GdaoCreateWithContext(ctx context.Context, tableName string, bo godal.IGenericBo) (int, error) {
+	return dao.GdaoCreateWithTx(ctx, nil, tableName, bo)
+}
+
 // GdaoCreateWithTx is database/sql variant of GdaoCreate.
 //
 // Available: since v0.1.0
 func (dao *GenericDaoSql) GdaoCreateWithTx(ctx context.Context, tx *gosql.Tx, tableName string, bo godal.IGenericBo) (int, error) {
+	if dao.outboxTable != "" {
+		return dao.outboxWrite(ctx, tx, tableName, OutboxOpCreate, bo, dao.gdaoCreateWithTx)
+	}
+	return dao.gdaoCreateWithTx(ctx, tx, tableName, bo)
+}
+
+func (dao *GenericDaoSql) gdaoCreateWithTx(ctx context.Context, tx *gosql.Tx, tableName string, bo godal.IGenericBo) (int, error) {
+	if err := dao.PrepareBoForWrite(contextWithTx(ctx, tx), tableName, bo, godal.WriteOpInsert); err != nil {
+		return 0, err
+	}
 	if row, err := dao.GetRowMapper().ToRow(tableName, bo); err != nil {
 		return 0, err
 	} else if colsAndVals, err := reddo.ToMap(row, typeMap); err != nil {
 		return 0, err
-	} else if result, err := dao.SqlInsert(ctx, tx, tableName, colsAndVals.(map[string]interface{})); err != nil {
+	} else if numRows, err := dao.sqlInsertBo(ctx, tx, tableName, bo, colsAndVals.(map[string]interface{})); err != nil {
 		if dao.IsErrorDuplicatedEntry(err) {
 			return 0, godal.ErrGdaoDuplicatedEntry
 		}
 		return 0, err
 	} else {
-		numRows, err := result.RowsAffected()
-		return int(numRows), err
+		return numRows, nil
 	}
 }
 
@@ -920,23 +1073,36 @@ func (dao *GenericDaoSql) GdaoUpdate(tableName string, bo godal.IGenericBo) (int
 	return dao.GdaoUpdateWithTx(nil, nil, tableName, bo)
 }
 
+// GdaoUpdateWithContext implements godal.IGenericDaoWithContext.GdaoUpdateWithContext.
+//
+// Available since v0.7.0
+func (dao *GenericDaoSql) GdaoUpdateWithContext(ctx context.Context, tableName string, bo godal.IGenericBo) (int, error) {
+	return dao.GdaoUpdateWithTx(ctx, nil, tableName, bo)
+}
+
 // GdaoUpdateWithTx is database/sql variant of GdaoUpdate.
 //
 // Available: since v0.1.0
 func (dao *GenericDaoSql) GdaoUpdateWithTx(ctx context.Context, tx *gosql.Tx, tableName string, bo godal.IGenericBo) (int, error) {
-	filter, err := dao.BuildFilter(tableName, dao.GdaoCreateFilter(tableName, bo))
-	if err != nil {
+	if dao.outboxTable != "" {
+		return dao.outboxWrite(ctx, tx, tableName, OutboxOpUpdate, bo, dao.gdaoUpdateWithTx)
+	}
+	return dao.gdaoUpdateWithTx(ctx, tx, tableName, bo)
+}
+
+func (dao *GenericDaoSql) gdaoUpdateWithTx(ctx context.Context, tx *gosql.Tx, tableName string, bo godal.IGenericBo) (int, error) {
+	if err := dao.PrepareBoForWrite(ctx, tableName, bo, godal.WriteOpUpdate); err != nil {
 		return 0, err
 	}
-	row, err := dao.GetRowMapper().ToRow(tableName, bo)
+	filter, err := dao.BuildFilter(tableName, godal.ApplyWriteCondition(ctx, dao.GdaoCreateFilter(tableName, bo)))
 	if err != nil {
 		return 0, err
 	}
-	colsAndVals, err := reddo.ToMap(row, typeMap)
+	colsAndVals, err := dao.toColsAndValsForUpdate(tableName, bo)
 	if err != nil {
 		return 0, err
 	}
-	result, err := dao.SqlUpdate(ctx, tx, tableName, colsAndVals.(map[string]interface{}), filter)
+	result, err := dao.SqlUpdate(ctx, tx, tableName, colsAndVals, filter)
 	if err != nil {
 		if dao.IsErrorDuplicatedEntry(err) {
 			return 0, godal.ErrGdaoDuplicatedEntry
@@ -947,18 +1113,43 @@ func (dao *GenericDaoSql) GdaoUpdateWithTx(ctx context.Context, tx *gosql.Tx, ta
 	return int(numRows), err
 }
 
+// toColsAndValsForUpdate transforms a BO to columns and values for the UPDATE statement, honoring updateChangedOnly.
+func (dao *GenericDaoSql) toColsAndValsForUpdate(tableName string, bo godal.IGenericBo) (map[string]interface{}, error) {
+	if dao.updateChangedOnly {
+		if changed := godal.ChangedFieldsBo(bo); changed != nil {
+			bo = changed
+		}
+	}
+	row, err := dao.GetRowMapper().ToRow(tableName, bo)
+	if err != nil {
+		return nil, err
+	}
+	colsAndVals, err := reddo.ToMap(row, typeMap)
+	if err != nil {
+		return nil, err
+	}
+	return colsAndVals.(map[string]interface{}), nil
+}
+
 // GdaoSave implements godal.IGenericDao.GdaoSave.
 func (dao *GenericDaoSql) GdaoSave(tableName string, bo godal.IGenericBo) (int, error) {
+	return dao.GdaoSaveWithContext(nil, tableName, bo)
+}
+
+// GdaoSaveWithContext implements godal.IGenericDaoWithContext.GdaoSaveWithContext.
+//
+// Available since v0.7.0
+func (dao *GenericDaoSql) GdaoSaveWithContext(ctx context.Context, tableName string, bo godal.IGenericBo) (int, error) {
 	var numRows int
 	var err error
 	if dao.txModeOnWrite {
-		err = dao.WrapTransaction(nil, func(ctx context.Context, tx *gosql.Tx) error {
+		err = dao.WrapTransaction(ctx, func(ctx context.Context, tx *gosql.Tx) error {
 			var e error
 			numRows, e = dao.GdaoSaveWithTx(ctx, tx, tableName, bo)
 			return e
 		})
 	} else {
-		numRows, err = dao.GdaoSaveWithTx(nil, nil, tableName, bo)
+		numRows, err = dao.GdaoSaveWithTx(ctx, nil, tableName, bo)
 	}
 	return numRows, err
 }
@@ -967,21 +1158,27 @@ func (dao *GenericDaoSql) GdaoSave(tableName string, bo godal.IGenericBo) (int,
 //
 // Available: since v0.1.0
 func (dao *GenericDaoSql) GdaoSaveWithTx(ctx context.Context, tx *gosql.Tx, tableName string, bo godal.IGenericBo) (int, error) {
-	filter, err := dao.BuildFilter(tableName, dao.GdaoCreateFilter(tableName, bo))
-	if err != nil {
+	if dao.outboxTable != "" {
+		return dao.outboxWrite(ctx, tx, tableName, OutboxOpSave, bo, dao.gdaoSaveWithTx)
+	}
+	return dao.gdaoSaveWithTx(ctx, tx, tableName, bo)
+}
+
+func (dao *GenericDaoSql) gdaoSaveWithTx(ctx context.Context, tx *gosql.Tx, tableName string, bo godal.IGenericBo) (int, error) {
+	if err := dao.PrepareBoForWrite(contextWithTx(ctx, tx), tableName, bo, godal.WriteOpUpdateThenInsert); err != nil {
 		return 0, err
 	}
-	row, err := dao.GetRowMapper().ToRow(tableName, bo)
+	filter, err := dao.BuildFilter(tableName, godal.ApplyWriteCondition(ctx, dao.GdaoCreateFilter(tableName, bo)))
 	if err != nil {
 		return 0, err
 	}
-	colsAndVals, err := reddo.ToMap(row, typeMap)
+	colsAndVals, err := dao.toColsAndValsForUpdate(tableName, bo)
 	if err != nil {
 		return 0, err
 	}
 
 	// firstly: try to update row
-	if result, err := dao.SqlUpdate(ctx, tx, tableName, colsAndVals.(map[string]interface{}), filter); err != nil {
+	if result, err := dao.SqlUpdate(ctx, tx, tableName, colsAndVals, filter); err != nil {
 		if dao.IsErrorDuplicatedEntry(err) {
 			return 0, godal.ErrGdaoDuplicatedEntry
 		}
@@ -990,15 +1187,26 @@ func (dao *GenericDaoSql) GdaoSaveWithTx(ctx context.Context, tx *gosql.Tx, tabl
 		return int(numRows), err
 	} else {
 		// secondly: no row updated, try insert row
-		result, err := dao.SqlInsert(ctx, tx, tableName, colsAndVals.(map[string]interface{}))
+		// the BO has been prepared for the update already, only the insert-only fields are filled and validated
+		if err := dao.PrepareBoForInsert(contextWithTx(ctx, tx), tableName, bo); err != nil {
+			return 0, err
+		}
+		row, err := dao.GetRowMapper().ToRow(tableName, bo)
+		if err != nil {
+			return 0, err
+		}
+		colsAndVals, err := reddo.ToMap(row, typeMap)
+		if err != nil {
+			return 0, err
+		}
+		numRows, err := dao.sqlInsertBo(ctx, tx, tableName, bo, colsAndVals.(map[string]interface{}))
 		if err != nil {
 			if dao.IsErrorDuplicatedEntry(err) {
 				return 0, godal.ErrGdaoDuplicatedEntry
 			}
 			return 0, err
 		}
-		numRows, err := result.RowsAffected()
-		return int(numRows), err
+		return numRows, nil
 	}
 }
 
@@ -71,6 +71,14 @@ func _initDao(driver, url, tableName string, flavor sql.DbFlavor) *UserDaoSql {
 	return _createDaoSql(sqlc, tableName)
 }
 
+func TestGenericDaoSql_IGenericDaoWithTx(t *testing.T) {
+	testName := "TestGenericDaoSql_IGenericDaoWithTx"
+	var dao interface{} = &GenericDaoSql{}
+	if _, ok := dao.(godal.IGenericDaoWithTx); !ok {
+		t.Fatalf("%s failed: GenericDaoSql should implement godal.IGenericDaoWithTx", testName)
+	}
+}
+
 func TestGenericDaoSql_TxMode(t *testing.T) {
 	testName := "TestGenericDaoSql_TxMode"
 	dao := _initDao("mysql", "test:test@tcp(localhost:3306)/test", testTableName, sql.FlavorMySql)
@@ -107,6 +115,39 @@ func TestGenericDaoSql_SetGetFuncNewPlaceholderGenerator(t *testing.T) {
 	}
 }
 
+func TestGenericDaoSql_IsErrorTransient(t *testing.T) {
+	testName := "TestGenericDaoSql_IsErrorTransient"
+	testCases := []struct {
+		flavor       sql.DbFlavor
+		transient    []string
+		nonTransient []string
+	}{
+		{sql.FlavorMySql, []string{"Error 1213: Deadlock found", "Error 1205: Lock wait timeout exceeded"}, []string{"Error 1062: Duplicate entry"}},
+		{sql.FlavorPgSql, []string{"ERROR: could not serialize access (SQLSTATE 40001)", "ERROR: deadlock detected (SQLSTATE 40P01)"}, []string{"ERROR: duplicate key (SQLSTATE 23505)"}},
+		{sql.FlavorMsSql, []string{"mssql: error 1205: deadlocked", "mssql: error 3960: snapshot isolation"}, []string{"mssql: error 2627: violation"}},
+		{sql.FlavorOracle, []string{"ORA-00060: deadlock detected", "ORA-08177: can't serialize access"}, []string{"ORA-00001: unique constraint violated"}},
+		{sql.FlavorSqlite, []string{"{ErrNo=5 ErrNoExtended=5}", "{ErrNo=6 ErrNoExtended=6}"}, []string{"{ErrNo=19 ErrNoExtended=1555}"}},
+		{sql.FlavorDefault, nil, []string{"Error 1213: Deadlock found"}},
+	}
+	for _, testCase := range testCases {
+		dao := _initDao("mysql", "test:test@tcp(localhost:3306)/test", testTableName, testCase.flavor)
+		if dao.IsErrorTransient(nil) {
+			t.Fatalf("%s failed: nil error should not be transient", testName)
+		}
+		for _, msg := range testCase.transient {
+			if !dao.IsErrorTransient(errors.New(" " + msg + " ")) {
+				t.Fatalf("%s failed: error %#v should be transient for flavor %#v", testName, msg, testCase.flavor)
+			}
+		}
+		for _, msg := range testCase.nonTransient {
+			if dao.IsErrorTransient(errors.New(" " + msg + " ")) {
+				t.Fatalf("%s failed: error %#v should not be transient for flavor %#v", testName, msg, testCase.flavor)
+			}
+		}
+		dao.sqlConnect.Close()
+	}
+}
+
 func TestGenericDaoSql_BuildFilter(t *testing.T) {
 	testName := "TestGenericDaoSql_BuildFilter"
 	dao := _initDao("mysql", "test:test@tcp(localhost:3306)/test", testTableName, sql.FlavorMySql)
@@ -1012,3 +1053,234 @@ func dotestGenericDaoSqlGdaoFilterNotNull(t *testing.T, name string, dao *UserDa
 		}
 	}
 }
+
+func dotestGenericDaoSqlAutoFields(t *testing.T, name string, dao *UserDaoSql) {
+	now := time.Now().Round(time.Second)
+	dao.SetClock(func() time.Time { return now })
+	dao.SetAutoFields(dao.tableName, godal.AutoFields{CreatedAt: fieldGboValPTime, CreatedBy: fieldGboValPString})
+	defer dao.RemoveAutoFields(dao.tableName)
+	newBo := func(id string) godal.IGenericBo {
+		bo := godal.NewGenericBo()
+		bo.GboImportViaMap(map[string]interface{}{fieldGboId: id, fieldGboUsername: "user" + id, fieldGboData: "{}"})
+		return bo
+	}
+	checkCreated := func(id string, expectedTime time.Time, expectedActor string) {
+		bo, err := dao.GdaoFetchOne(dao.tableName, dao.GdaoCreateFilter(dao.tableName, newBo(id)))
+		if err != nil || bo == nil {
+			t.Fatalf("%s failed: %#v / %#v", name, bo, err)
+		}
+		if v, err := bo.GboGetAttr(fieldGboValPTime, reddo.TypeTime); err != nil || !v.(time.Time).Equal(expectedTime) {
+			t.Fatalf("%s failed: expected created-at %#v but received %#v / %#v", name, expectedTime, v, err)
+		}
+		if v := bo.GboGetAttrUnsafe(fieldGboValPString, reddo.TypeString); v != expectedActor {
+			t.Fatalf("%s failed: expected created-by %#v but received %#v", name, expectedActor, v)
+		}
+	}
+
+	bo := newBo("1")
+	if numRows, err := dao.GdaoCreateWithTx(godal.WithActor(nil, "alice"), nil, dao.tableName, bo); numRows != 1 || err != nil {
+		t.Fatalf("%s failed: %#v / %#v", name+"/GdaoCreate", numRows, err)
+	}
+	if v := bo.GboGetAttrUnsafe(fieldGboValPString, reddo.TypeString); v != "alice" {
+		t.Fatalf("%s failed: BO should have been stamped, received %#v", name, v)
+	}
+	checkCreated("1", now, "alice")
+
+	// save an existing row: created-fields are not touched
+	createdAt := now
+	now = now.Add(time.Hour)
+	if numRows, err := dao.GdaoSaveWithTx(godal.WithActor(nil, "bob"), nil, dao.tableName, newBo("1")); numRows != 1 || err != nil {
+		t.Fatalf("%s failed: %#v / %#v", name+"/GdaoSave", numRows, err)
+	}
+	checkCreated("1", createdAt, "alice")
+
+	// save a new row: created-fields are set
+	if numRows, err := dao.GdaoSaveWithTx(godal.WithActor(nil, "bob"), nil, dao.tableName, newBo("2")); numRows != 1 || err != nil {
+		t.Fatalf("%s failed: %#v / %#v", name+"/GdaoSave", numRows, err)
+	}
+	checkCreated("2", now, "bob")
+
+	// a schema may require created-fields: they are filled before the inserted BO is validated
+	schema := &godal.Schema{Type: godal.SchemaTypeObject, Required: []string{fieldGboValPTime, fieldGboValPString}}
+	if err := dao.SetSchema(dao.tableName, schema); err != nil {
+		t.Fatalf("%s failed: %e", name, err)
+	}
+	defer dao.SetSchema(dao.tableName, nil)
+	if numRows, err := dao.GdaoSaveWithTx(godal.WithActor(nil, "carol"), nil, dao.tableName, newBo("3")); numRows != 1 || err != nil {
+		t.Fatalf("%s failed: %#v / %#v", name+"/GdaoSave", numRows, err)
+	}
+	checkCreated("3", now, "carol")
+	var verr *godal.ValidationError
+	if _, err := dao.GdaoSaveWithTx(nil, nil, dao.tableName, newBo("4")); !errors.As(err, &verr) || !reflect.DeepEqual(verr.Paths(), []string{fieldGboValPString}) {
+		t.Fatalf("%s failed: inserted BO without created-by should be rejected, received %#v", name+"/GdaoSave", err)
+	}
+}
+
+func dotestGenericDaoSqlSchema(t *testing.T, name string, dao *UserDaoSql) {
+	schema := &godal.Schema{
+		Type:     godal.SchemaTypeObject,
+		Required: []string{fieldGboId, fieldGboUsername},
+		Properties: map[string]*godal.Schema{
+			fieldGboUsername: {Type: godal.SchemaTypeString, Pattern: "^user[0-9]+$"},
+			fieldGboValPInt:  {Type: godal.SchemaTypeInteger, Minimum: new(float64)},
+		},
+	}
+	if err := dao.SetSchema(dao.tableName, schema); err != nil {
+		t.Fatalf("%s failed: %e", name, err)
+	}
+	defer dao.SetSchema(dao.tableName, nil)
+
+	bo := godal.NewGenericBo()
+	bo.GboImportViaMap(map[string]interface{}{fieldGboId: "1", fieldGboUsername: "admin", fieldGboValPInt: -1, fieldGboData: "{}"})
+	for _, f := range []func(bo godal.IGenericBo) (int, error){
+		func(bo godal.IGenericBo) (int, error) { return dao.GdaoCreate(dao.tableName, bo) },
+		func(bo godal.IGenericBo) (int, error) { return dao.GdaoUpdate(dao.tableName, bo) },
+		func(bo godal.IGenericBo) (int, error) { return dao.GdaoSave(dao.tableName, bo) },
+	} {
+		numRows, err := f(bo)
+		verr, ok := err.(*godal.ValidationError)
+		if numRows != 0 || !ok || !reflect.DeepEqual(verr.Paths(), []string{fieldGboValPInt, fieldGboUsername}) {
+			t.Fatalf("%s failed: %#v / %#v", name, numRows, err)
+		}
+	}
+	if fetched, err := dao.GdaoFetchOne(dao.tableName, dao.GdaoCreateFilter(dao.tableName, bo)); err != nil || fetched != nil {
+		t.Fatalf("%s failed: invalid BO should not have been written: %#v / %#v", name, fetched, err)
+	}
+
+	bo.GboSetAttr(fieldGboUsername, "user1")
+	bo.GboSetAttr(fieldGboValPInt, 1)
+	if numRows, err := dao.GdaoCreate(dao.tableName, bo); numRows != 1 || err != nil {
+		t.Fatalf("%s failed: %#v / %#v", name, numRows, err)
+	}
+}
+
+func dotestGenericDaoSqlUpdateChangedOnly(t *testing.T, name string, dao *UserDaoSql) {
+	bo := godal.NewGenericBo()
+	bo.GboImportViaMap(map[string]interface{}{fieldGboId: "1", fieldGboUsername: "user1", fieldGboValPInt: 1, fieldGboValPString: "s1"})
+	if numRows, err := dao.GdaoCreate(dao.tableName, bo); numRows != 1 || err != nil {
+		t.Fatalf("%s failed: %#v / %#v", name+"/GdaoCreate", numRows, err)
+	}
+	filter := dao.GdaoCreateFilter(dao.tableName, bo)
+	dao.SetUpdateChangedOnly(true)
+	fetched, err := dao.GdaoFetchOne(dao.tableName, filter)
+	if err != nil || fetched == nil {
+		t.Fatalf("%s failed: %#v / %#v", name+"/GdaoFetchOne", fetched, err)
+	}
+
+	// the row is modified by someone else
+	query := fmt.Sprintf("UPDATE %s SET %s='external' WHERE %s='1'", dao.tableName, colSqlValPString, colSqlId)
+	if _, err := dao.SqlExecute(nil, nil, query); err != nil {
+		t.Fatalf("%s failed: %e", name+"/SqlExecute", err)
+	}
+
+	fetched.GboSetAttr(fieldGboValPInt, 2)
+	if numRows, err := dao.GdaoUpdate(dao.tableName, fetched); numRows != 1 || err != nil {
+		t.Fatalf("%s failed: %#v / %#v", name+"/GdaoUpdate", numRows, err)
+	}
+	check, _ := dao.GdaoFetchOne(dao.tableName, filter)
+	if pint := check.GboGetAttrUnsafe(fieldGboValPInt, reddo.TypeInt); pint != int64(2) {
+		t.Fatalf("%s failed: expected %#v but received %#v", name, int64(2), pint)
+	}
+	if pstring := check.GboGetAttrUnsafe(fieldGboValPString, reddo.TypeString); pstring != "external" {
+		t.Fatalf("%s failed: unchanged column should not be written, expected %#v but received %#v", name, "external", pstring)
+	}
+
+	// all columns are written when the option is disabled
+	dao.SetUpdateChangedOnly(false)
+	fetched.GboSetAttr(fieldGboValPInt, 3)
+	if numRows, err := dao.GdaoUpdate(dao.tableName, fetched); numRows != 1 || err != nil {
+		t.Fatalf("%s failed: %#v / %#v", name+"/GdaoUpdate", numRows, err)
+	}
+	check, _ = dao.GdaoFetchOne(dao.tableName, filter)
+	if pstring := check.GboGetAttrUnsafe(fieldGboValPString, reddo.TypeString); pstring != "s1" {
+		t.Fatalf("%s failed: expected %#v but received %#v", name, "s1", pstring)
+	}
+	if paths := check.(godal.IDirtyTrackingBo).GboChangedPaths(); len(paths) == 0 {
+		t.Fatalf("%s failed: BO fetched while the option is disabled should not track changes", name)
+	}
+}
+
+func dotestGenericDaoSqlWriteCondition(t *testing.T, name string, dao *UserDaoSql) {
+	bo := godal.NewGenericBo()
+	bo.GboImportViaMap(map[string]interface{}{fieldGboId: "1", fieldGboUsername: "user1", fieldGboValPInt: 1})
+	if numRows, err := dao.GdaoCreate(dao.tableName, bo); numRows != 1 || err != nil {
+		t.Fatalf("%s failed: %#v / %#v", name+"/GdaoCreate", numRows, err)
+	}
+	filter := dao.GdaoCreateFilter(dao.tableName, bo)
+
+	// the condition does not match: nothing is written
+	ctx := godal.WithWriteCondition(context.Background(), &godal.FilterOptFieldOpValue{FieldName: fieldGboUsername, Operator: godal.FilterOpEqual, Value: "user2"})
+	bo.GboSetAttr(fieldGboValPInt, 2)
+	if numRows, err := dao.GdaoUpdateWithContext(ctx, dao.tableName, bo); numRows != 0 || err != nil {
+		t.Fatalf("%s failed: %#v / %#v", name+"/GdaoUpdateWithContext", numRows, err)
+	}
+	if numRows, err := dao.GdaoDeleteWithContext(ctx, dao.tableName, bo); numRows != 0 || err != nil {
+		t.Fatalf("%s failed: %#v / %#v", name+"/GdaoDeleteWithContext", numRows, err)
+	}
+	check, _ := dao.GdaoFetchOne(dao.tableName, filter)
+	if check == nil {
+		t.Fatalf("%s failed: row should not be deleted", name)
+	}
+	if pint := check.GboGetAttrUnsafe(fieldGboValPInt, reddo.TypeInt); pint != int64(1) {
+		t.Fatalf("%s failed: expected %#v but received %#v", name, int64(1), pint)
+	}
+
+	// the condition matches
+	ctx = godal.WithWriteCondition(context.Background(), &godal.FilterOptFieldOpValue{FieldName: fieldGboUsername, Operator: godal.FilterOpEqual, Value: "user1"})
+	if numRows, err := dao.GdaoUpdateWithContext(ctx, dao.tableName, bo); numRows != 1 || err != nil {
+		t.Fatalf("%s failed: %#v / %#v", name+"/GdaoUpdateWithContext", numRows, err)
+	}
+	check, _ = dao.GdaoFetchOne(dao.tableName, filter)
+	if pint := check.GboGetAttrUnsafe(fieldGboValPInt, reddo.TypeInt); pint != int64(2) {
+		t.Fatalf("%s failed: expected %#v but received %#v", name, int64(2), pint)
+	}
+	if numRows, err := dao.GdaoDeleteWithContext(ctx, dao.tableName, bo); numRows != 1 || err != nil {
+		t.Fatalf("%s failed: %#v / %#v", name+"/GdaoDeleteWithContext", numRows, err)
+	}
+}
+
+type structRowMapperUser struct {
+	Id       string                 `json:"id" godal:"userid,pk"`
+	Username string                 `json:"username" godal:"uusername"`
+	Data     map[string]interface{} `json:"data" godal:"udata,json"`
+	PInt     int                    `json:"pint" godal:"pint,omitempty"`
+	PFloat   float64                `json:"pfloat" godal:"pfloat"`
+	PString  string                 `json:"pstring" godal:"pstring"`
+	PTime    time.Time              `json:"ptime" godal:"ptime"`
+}
+
+func dotestGenericDaoSqlStructRowMapper(t *testing.T, name string, dao *UserDaoSql) {
+	rowMapper := godal.NewStructRowMapper(godal.ColumnsListAll)
+	if err := rowMapper.Register(dao.tableName, structRowMapperUser{}); err != nil {
+		t.Fatalf("%s failed: %e", name, err)
+	}
+	origRowMapper := dao.GetRowMapper()
+	dao.SetRowMapper(rowMapper)
+	defer dao.SetRowMapper(origRowMapper)
+
+	now := time.Now().Round(time.Second)
+	typedDao := godal.NewTypedDao[structRowMapperUser](dao, dao.tableName)
+	u := &structRowMapperUser{Id: "1", Username: "user1", Data: map[string]interface{}{"k": "v"}, PInt: 10, PFloat: 1.5, PString: "s", PTime: now}
+	if numRows, err := typedDao.Create(nil, u); numRows != 1 || err != nil {
+		t.Fatalf("%s failed: %#v / %#v", name+"/Create", numRows, err)
+	}
+	filter := &godal.FilterOptFieldOpValue{FieldName: fieldGboUsername, Operator: godal.FilterOpEqual, Value: "user1"}
+	fetched, err := typedDao.Get(nil, filter)
+	if err != nil || fetched == nil {
+		t.Fatalf("%s failed: %#v / %#v", name+"/Get", fetched, err)
+	}
+	if fetched.Id != u.Id || fetched.PInt != u.PInt || fetched.PFloat != u.PFloat || fetched.PString != u.PString ||
+		!fetched.PTime.Equal(now) || !reflect.DeepEqual(fetched.Data, u.Data) {
+		t.Fatalf("%s failed: expected %#v but received %#v", name+"/Get", u, fetched)
+	}
+
+	u.PInt = 20
+	if numRows, err := typedDao.Update(nil, u); numRows != 1 || err != nil {
+		t.Fatalf("%s failed: %#v / %#v", name+"/Update", numRows, err)
+	}
+	sorting := (&godal.SortingOpt{}).Add(&godal.SortingField{FieldName: fieldGboValPInt})
+	list, err := typedDao.List(nil, nil, sorting, 0, 0)
+	if err != nil || len(list) != 1 || list[0].PInt != 20 {
+		t.Fatalf("%s failed: %#v / %#v", name+"/List", list, err)
+	}
+}
@@ -240,3 +240,78 @@ func TestGenericDaoSqlite_FilterNotNull(t *testing.T) {
 	}
 	dotestGenericDaoSqlGdaoFilterNotNull(t, testName, dao)
 }
+
+func TestGenericDaoSqlite_AutoFields(t *testing.T) {
+	testName := "TestGenericDaoSqlite_AutoFields"
+	dao := _initDao(os.Getenv(envSqliteDriver), os.Getenv(envSqliteUrl), testTableName, sql.FlavorSqlite)
+	if dao == nil {
+		t.SkipNow()
+	}
+	defer dao.sqlConnect.Close()
+
+	err := prepareTableSqlite(dao.GetSqlConnect(), dao.tableName)
+	if err != nil {
+		t.Fatalf("%s failed: %e", testName+"/prepareTableSqlite", err)
+	}
+	dotestGenericDaoSqlAutoFields(t, testName, dao)
+}
+
+func TestGenericDaoSqlite_Schema(t *testing.T) {
+	testName := "TestGenericDaoSqlite_Schema"
+	dao := _initDao(os.Getenv(envSqliteDriver), os.Getenv(envSqliteUrl), testTableName, sql.FlavorSqlite)
+	if dao == nil {
+		t.SkipNow()
+	}
+	defer dao.sqlConnect.Close()
+
+	err := prepareTableSqlite(dao.GetSqlConnect(), dao.tableName)
+	if err != nil {
+		t.Fatalf("%s failed: %e", testName+"/prepareTableSqlite", err)
+	}
+	dotestGenericDaoSqlSchema(t, testName, dao)
+}
+
+func TestGenericDaoSqlite_StructRowMapper(t *testing.T) {
+	testName := "TestGenericDaoSqlite_StructRowMapper"
+	dao := _initDao(os.Getenv(envSqliteDriver), os.Getenv(envSqliteUrl), testTableName, sql.FlavorSqlite)
+	if dao == nil {
+		t.SkipNow()
+	}
+	defer dao.sqlConnect.Close()
+
+	err := prepareTableSqlite(dao.GetSqlConnect(), dao.tableName)
+	if err != nil {
+		t.Fatalf("%s failed: %e", testName+"/prepareTableSqlite", err)
+	}
+	dotestGenericDaoSqlStructRowMapper(t, testName, dao)
+}
+
+func TestGenericDaoSqlite_UpdateChangedOnly(t *testing.T) {
+	testName := "TestGenericDaoSqlite_UpdateChangedOnly"
+	dao := _initDao(os.Getenv(envSqliteDriver), os.Getenv(envSqliteUrl), testTableName, sql.FlavorSqlite)
+	if dao == nil {
+		t.SkipNow()
+	}
+	defer dao.sqlConnect.Close()
+
+	err := prepareTableSqlite(dao.GetSqlConnect(), dao.tableName)
+	if err != nil {
+		t.Fatalf("%s failed: %e", testName+"/prepareTableSqlite", err)
+	}
+	dotestGenericDaoSqlUpdateChangedOnly(t, testName, dao)
+}
+
+func TestGenericDaoSqlite_WriteCondition(t *testing.T) {
+	testName := "TestGenericDaoSqlite_WriteCondition"
+	dao := _initDao(os.Getenv(envSqliteDriver), os.Getenv(envSqliteUrl), testTableName, sql.FlavorSqlite)
+	if dao == nil {
+		t.SkipNow()
+	}
+	defer dao.sqlConnect.Close()
+
+	err := prepareTableSqlite(dao.GetSqlConnect(), dao.tableName)
+	if err != nil {
+		t.Fatalf("%s failed: %e", testName+"/prepareTableSqlite", err)
+	}
+	dotestGenericDaoSqlWriteCondition(t, testName, dao)
+}